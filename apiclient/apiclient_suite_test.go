@@ -0,0 +1,13 @@
+package apiclient_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestAPIClient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "APIClient")
+}
@@ -0,0 +1,262 @@
+// Package apiclient is a client for the billing API served by apiserver,
+// returning the same eventio types that the API is built from
+package apiclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+var (
+	_ eventio.VATRateReader         = &Client{}
+	_ eventio.CurrencyRateReader    = &Client{}
+	_ eventio.PricingPlanReader     = &Client{}
+	_ eventio.TotalCostReader       = &Client{}
+	_ eventio.BillableSummaryReader = &Client{}
+	_ eventio.ProjectionReader      = &Client{}
+	_ eventio.AnomalyReader         = &Client{}
+	_ eventio.BudgetReader          = &Client{}
+	_ eventio.BudgetWriter          = &Client{}
+)
+
+type Config struct {
+	// URL is the address of the API, e.g. https://billing.example.com (required)
+	URL string
+	// Token is a Cloud Foundry access token for the user or client calling
+	// the API, without the "bearer " prefix
+	Token string
+	// HTTPClient is used to make the requests, http.DefaultClient is used if
+	// it is nil
+	HTTPClient *http.Client
+}
+
+// Client calls the billing API
+type Client struct {
+	cfg Config
+}
+
+func New(cfg Config) *Client {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	return &Client{cfg: cfg}
+}
+
+// Error is returned when the API responds with an error status. Message is
+// the error returned by the API.
+type Error struct {
+	StatusCode int
+	Message    string
+	// Problems is every problem found with the request body, if the API
+	// checked it all at once
+	Problems []Problem
+}
+
+// Problem is a problem with a value in a request, at a path such as
+// events[1].plan_guid
+type Problem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("billing api returned %d: %s", e.StatusCode, e.Message)
+}
+
+func (c *Client) GetVATRates(filter eventio.TimeRangeFilter) ([]eventio.VATRate, error) {
+	rates := []eventio.VATRate{}
+	return rates, c.get("/vat_rates", timeRangeQuery(filter), &rates)
+}
+
+func (c *Client) GetCurrencyRates(filter eventio.TimeRangeFilter) ([]eventio.CurrencyRate, error) {
+	rates := []eventio.CurrencyRate{}
+	return rates, c.get("/currency_rates", timeRangeQuery(filter), &rates)
+}
+
+func (c *Client) GetPricingPlans(filter eventio.TimeRangeFilter) ([]eventio.PricingPlan, error) {
+	plans := []eventio.PricingPlan{}
+	return plans, c.get("/pricing_plans", timeRangeQuery(filter), &plans)
+}
+
+func (c *Client) GetMonthEndProjections(filter eventio.ProjectionFilter) ([]eventio.Projection, error) {
+	query := url.Values{"org_guid": filter.OrgGUIDs}
+	if filter.Currency != "" {
+		query.Set("currency", filter.Currency)
+	}
+	projections := []eventio.Projection{}
+	return projections, c.get("/month_end_projections", query, &projections)
+}
+
+func (c *Client) GetTotalCost() ([]eventio.TotalCost, error) {
+	totals := []eventio.TotalCost{}
+	return totals, c.get("/totals", url.Values{}, &totals)
+}
+
+// GetUsageEvents returns every usage event for the filter. After and Limit
+// are ignored, as pages are selected with the cursor returned by the API.
+func (c *Client) GetUsageEvents(filter eventio.EventFilter) ([]eventio.UsageEvent, error) {
+	events := []eventio.UsageEvent{}
+	return events, c.get("/usage_events", eventQuery(filter), &events)
+}
+
+// GetBillableEvents returns every billable event for the filter. After and
+// Limit are ignored, as pages are selected with the cursor returned by the
+// API.
+func (c *Client) GetBillableEvents(filter eventio.EventFilter) ([]eventio.BillableEvent, error) {
+	events := []eventio.BillableEvent{}
+	return events, c.get("/billable_events", eventQuery(filter), &events)
+}
+
+func (c *Client) GetBillableSummary(filter eventio.BillableSummaryFilter) ([]eventio.BillableSummary, error) {
+	query := eventQuery(filter.EventFilter)
+	for _, group := range filter.GroupBy {
+		query.Add("group_by", string(group))
+	}
+	summaries := []eventio.BillableSummary{}
+	return summaries, c.get("/billable_summary", query, &summaries)
+}
+
+// ForecastBillableEvents prices usage events that have not happened
+func (c *Client) ForecastBillableEvents(events []eventio.UsageEvent, filter eventio.EventFilter) ([]eventio.BillableEvent, error) {
+	b, err := json.Marshal(events)
+	if err != nil {
+		return nil, err
+	}
+	billableEvents := []eventio.BillableEvent{}
+	return billableEvents, c.do(http.MethodPost, "/forecast_events", eventQuery(filter), bytes.NewReader(b), &billableEvents)
+}
+
+// ForecastScenario prices the real usage of the orgs in the filter with the
+// changes in the scenario made to it
+func (c *Client) ForecastScenario(scenario eventio.Scenario, filter eventio.EventFilter) ([]eventio.BillableEvent, error) {
+	b, err := json.Marshal(scenario)
+	if err != nil {
+		return nil, err
+	}
+	billableEvents := []eventio.BillableEvent{}
+	return billableEvents, c.do(http.MethodPost, "/forecast_scenario", eventQuery(filter), bytes.NewReader(b), &billableEvents)
+}
+
+func (c *Client) GetInvoices(filter eventio.EventFilter) ([]eventio.Invoice, error) {
+	invoices := []eventio.Invoice{}
+	return invoices, c.get("/invoices", eventQuery(filter), &invoices)
+}
+
+func (c *Client) GetInvoice(number int64) (eventio.Invoice, error) {
+	var invoice eventio.Invoice
+	return invoice, c.get("/invoices/"+strconv.FormatInt(number, 10), url.Values{}, &invoice)
+}
+
+func (c *Client) GetAnomalies(filter eventio.EventFilter) ([]eventio.Anomaly, error) {
+	anomalies := []eventio.Anomaly{}
+	return anomalies, c.get("/anomalies", eventQuery(filter), &anomalies)
+}
+
+func (c *Client) GetBudgets(orgGUIDs []string) ([]eventio.Budget, error) {
+	budgets := []eventio.Budget{}
+	return budgets, c.get("/budgets", url.Values{"org_guid": orgGUIDs}, &budgets)
+}
+
+func (c *Client) SetBudget(budget eventio.Budget) (eventio.Budget, error) {
+	b, err := json.Marshal(budget)
+	if err != nil {
+		return eventio.Budget{}, err
+	}
+	var set eventio.Budget
+	return set, c.do(http.MethodPut, "/budgets/"+url.PathEscape(budget.OrgGUID), url.Values{}, bytes.NewReader(b), &set)
+}
+
+func (c *Client) DeleteBudget(orgGUID string) error {
+	return c.do(http.MethodDelete, "/budgets/"+url.PathEscape(orgGUID), url.Values{}, nil, nil)
+}
+
+func timeRangeQuery(filter eventio.TimeRangeFilter) url.Values {
+	return url.Values{
+		"range_start": {filter.RangeStart},
+		"range_stop":  {filter.RangeStop},
+	}
+}
+
+func eventQuery(filter eventio.EventFilter) url.Values {
+	query := url.Values{
+		"range_start": {filter.RangeStart},
+		"range_stop":  {filter.RangeStop},
+	}
+	params := []struct {
+		name   string
+		values []string
+	}{
+		{"org_guid", filter.OrgGUIDs},
+		{"space_guid", filter.SpaceGUIDs},
+		{"resource_type", filter.ResourceTypes},
+		{"plan_guid", filter.PlanGUIDs},
+		{"resource_guid", filter.ResourceGUIDs},
+	}
+	for _, param := range params {
+		for _, value := range param.values {
+			query.Add(param.name, value)
+		}
+	}
+	if filter.Currency != "" {
+		query.Set("currency", filter.Currency)
+	}
+	return query
+}
+
+// get requests path with the query and decodes the JSON response into v
+func (c *Client) get(path string, query url.Values, v interface{}) error {
+	return c.do(http.MethodGet, path, query, nil, v)
+}
+
+// do makes a request with a JSON body, if body is not nil, and decodes the
+// JSON response into v
+func (c *Client) do(method string, path string, query url.Values, body io.Reader, v interface{}) error {
+	req, err := http.NewRequest(method, c.cfg.URL+path+"?"+query.Encode(), body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.Token != "" {
+		req.Header.Set("Authorization", "bearer "+c.cfg.Token)
+	}
+	res, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return responseError(res)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func responseError(res *http.Response) error {
+	b, err := ioutil.ReadAll(io.LimitReader(res.Body, 64*1024))
+	if err != nil {
+		return err
+	}
+	var body struct {
+		Error  string    `json:"error"`
+		Errors []Problem `json:"errors"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil || body.Error == "" {
+		body.Error = strings.TrimSpace(string(b))
+	}
+	return &Error{StatusCode: res.StatusCode, Message: body.Error, Problems: body.Errors}
+}
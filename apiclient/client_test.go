@@ -0,0 +1,164 @@
+package apiclient_test
+
+import (
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/apiserver"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/fakes"
+
+	. "github.com/alphagov/paas-billing/apiclient"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server            *httptest.Server
+		client            *Client
+		fakeAuthenticator *fakes.FakeAuthenticator
+		fakeAuthorizer    *fakes.FakeAuthorizer
+		fakeStore         *fakes.FakeEventStore
+		orgGUID           = "f5f32499-db32-4ab7-a314-20cbe3e49080"
+	)
+
+	BeforeEach(func() {
+		fakeStore = &fakes.FakeEventStore{}
+		fakeAuthenticator = &fakes.FakeAuthenticator{}
+		fakeAuthorizer = &fakes.FakeAuthorizer{}
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(true, nil)
+		server = httptest.NewServer(apiserver.New(apiserver.Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}))
+		client = New(Config{URL: server.URL + "/", Token: "ACCESS_GRANTED_TOKEN"})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should get the VAT rates", func() {
+		fakeStore.GetVATRatesReturns([]eventio.VATRate{
+			{Code: "Standard", ValidFrom: "epoch", Rate: eventio.MustParseDecimalNumber("0.2")},
+		}, nil)
+
+		rates, err := client.GetVATRates(eventio.TimeRangeFilter{RangeStart: "2001-01-01", RangeStop: "2001-02-01"})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rates).To(HaveLen(1))
+		Expect(rates[0].Code).To(Equal("Standard"))
+		Expect(fakeStore.GetVATRatesArgsForCall(0)).To(Equal(eventio.TimeRangeFilter{RangeStart: "2001-01-01", RangeStop: "2001-02-01"}))
+	})
+
+	It("should get the billable events with the token and filter", func() {
+		fakeRows := &fakes.FakeBillableEventRows{}
+		fakeRows.NextReturnsOnCall(0, true)
+		fakeRows.EventJSONReturns([]byte(`{"event_guid": "2d4e9f26-3f0c-4bd6-8f11-7c3ab7b0e3fb", "org_guid": "`+orgGUID+`", "price": {"ex_vat": "1.5", "inc_vat": "1.8", "details": []}}`), nil)
+		fakeStore.GetBillableEventRowsReturns(fakeRows, nil)
+
+		events, err := client.GetBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+			OrgGUIDs:   []string{orgGUID},
+			Currency:   "USD",
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Price.ExVAT.Equal(eventio.MustParseDecimal("1.5"))).To(BeTrue())
+		Expect(fakeAuthenticator.NewAuthorizerArgsForCall(0)).To(Equal("ACCESS_GRANTED_TOKEN"))
+		_, filter := fakeStore.GetBillableEventRowsArgsForCall(0)
+		Expect(filter).To(Equal(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+			OrgGUIDs:   []string{orgGUID},
+			Currency:   "USD",
+		}))
+	})
+
+	It("should get the billable summary", func() {
+		fakeStore.GetBillableSummaryReturns([]eventio.BillableSummary{
+			{OrgGUID: orgGUID, CurrencyCode: "GBP", ExVAT: eventio.MustParseDecimal("10"), IncVAT: eventio.MustParseDecimal("12")},
+		}, nil)
+
+		summaries, err := client.GetBillableSummary(eventio.BillableSummaryFilter{
+			EventFilter: eventio.EventFilter{RangeStart: "2001-01-01", RangeStop: "2001-02-01", OrgGUIDs: []string{orgGUID}},
+			GroupBy:     []eventio.SummaryGroup{eventio.GroupByOrg, eventio.GroupByMonth},
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(summaries).To(HaveLen(1))
+		Expect(summaries[0].OrgGUID).To(Equal(orgGUID))
+		Expect(fakeStore.GetBillableSummaryArgsForCall(0).GroupBy).To(Equal([]eventio.SummaryGroup{eventio.GroupByOrg, eventio.GroupByMonth}))
+	})
+
+	It("should return the error from the API", func() {
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+		_, err := client.GetUsageEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+			OrgGUIDs:   []string{orgGUID},
+		})
+
+		Expect(err).To(MatchError(&Error{
+			StatusCode: 401,
+			Message:    "you need to be billing_manager or an administrator to retrieve the billing data",
+		}))
+	})
+
+	It("should forecast events by posting them", func() {
+		fakeStore.GetPricingPlansReturns([]eventio.PricingPlan{{PlanGUID: eventstore.ComputePlanGUID}}, nil)
+		fakeRows := &fakes.FakeBillableEventRows{}
+		fakeRows.NextReturnsOnCall(0, true)
+		fakeRows.EventJSONReturns([]byte(`{"event_guid": "00000000-0000-0000-0000-000000000001"}`), nil)
+		fakeStore.ForecastBillableEventRowsReturns(fakeRows, nil)
+		inputEvent := eventio.UsageEvent{
+			EventGUID:    "00000000-0000-0000-0000-000000000001",
+			ResourceGUID: "00000000-0000-0000-0001-000000000001",
+			OrgGUID:      eventstore.DummyOrgGUID,
+			SpaceGUID:    eventstore.DummySpaceGUID,
+			EventStart:   "2001-01-01T00:00:00Z",
+			EventStop:    "2001-01-01T01:00:00Z",
+			PlanGUID:     eventstore.ComputePlanGUID,
+		}
+		filter := eventio.EventFilter{RangeStart: "2001-01-01", RangeStop: "2001-02-01"}
+
+		events, err := client.ForecastBillableEvents([]eventio.UsageEvent{inputEvent}, filter)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(1))
+		_, requestedEvents, _ := fakeStore.ForecastBillableEventRowsArgsForCall(0)
+		Expect(requestedEvents).To(Equal([]eventio.UsageEvent{inputEvent}))
+
+		inputEvent.MemoryInMB = -1
+		_, err = client.ForecastBillableEvents([]eventio.UsageEvent{inputEvent}, filter)
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*Error).Problems).To(Equal([]Problem{
+			{Path: "events[0].memory_in_mb", Message: "must not be negative - got -1"},
+		}))
+	})
+
+	It("should set and delete a budget", func() {
+		fakeStore.SetBudgetStub = func(budget eventio.Budget) (eventio.Budget, error) {
+			return budget, nil
+		}
+
+		budget, err := client.SetBudget(eventio.Budget{OrgGUID: orgGUID, Amount: eventio.MustParseDecimal("500")})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(budget.OrgGUID).To(Equal(orgGUID))
+		Expect(budget.Thresholds).To(Equal(eventio.DefaultBudgetThresholds))
+
+		Expect(client.DeleteBudget(orgGUID)).To(Succeed())
+		Expect(fakeStore.DeleteBudgetArgsForCall(0)).To(Equal(orgGUID))
+	})
+})
@@ -1,6 +1,14 @@
 package auth
 
 type Authorizer interface {
+	// Admin reports whether the token has any of the operator scopes,
+	// including the read only ones
 	Admin() (bool, error)
+	// FullAdmin reports whether the token has the cloud_controller.admin
+	// scope, which is needed to make changes
+	FullAdmin() (bool, error)
 	HasBillingAccess([]string) (bool, error)
+	// User returns who the token was issued to, for recording who made a
+	// change
+	User() (string, error)
 }
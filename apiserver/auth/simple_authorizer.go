@@ -12,6 +12,7 @@ var FakeBearerToken = "Bearer FAKE_TOKEN"
 type SimpleAuthorizer struct {
 	admin              bool
 	authorizedOrgGUIDs []string
+	user               string
 }
 
 func (sa *SimpleAuthorizer) HasBillingAccess(orgs []string) (bool, error) {
@@ -26,10 +27,19 @@ func (sa *SimpleAuthorizer) Admin() (bool, error) {
 	return sa.admin, nil
 }
 
+func (sa *SimpleAuthorizer) FullAdmin() (bool, error) {
+	return sa.admin, nil
+}
+
+func (sa *SimpleAuthorizer) User() (string, error) {
+	return sa.user, nil
+}
+
 type SimpleAuthenticator struct {
 	admin              bool
 	authorizedOrgGUIDs []string
 	authorizationError error
+	user               string
 }
 
 func (sa *SimpleAuthenticator) Authorize(c echo.Context) error {
@@ -48,11 +58,13 @@ func (sa *SimpleAuthenticator) NewAuthorizer(token string) (Authorizer, error) {
 	return &SimpleAuthorizer{
 		authorizedOrgGUIDs: sa.authorizedOrgGUIDs,
 		admin:              sa.admin,
+		user:               sa.user,
 	}, nil
 }
 
 var AuthenticatedNonAdmin = &SimpleAuthenticator{
 	admin: false,
+	user:  "non-admin",
 	authorizedOrgGUIDs: []string{
 		"org_guid",
 		"org_guid1",
@@ -71,6 +83,7 @@ var AuthenticatedNonAdmin = &SimpleAuthenticator{
 var AuthenticatedAdmin = &SimpleAuthenticator{
 	admin:              true,
 	authorizedOrgGUIDs: []string{},
+	user:               "admin",
 }
 
 var NonAuthenticated = &SimpleAuthenticator{
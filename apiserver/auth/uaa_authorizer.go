@@ -44,6 +44,7 @@ func (uaa *UAA) NewAuthorizer(token string) (Authorizer, error) {
 
 type UAAClaims struct {
 	UserID    string   `json:"user_id"`
+	ClientID  string   `json:"client_id"`
 	Scope     []string `json:"scope"`
 	Email     string   `json:"email"`
 	UserName  string   `json:"user_name"`
@@ -121,6 +122,25 @@ func (a *ClientAuthorizer) Admin() (bool, error) {
 	return false, nil
 }
 
+func (a *ClientAuthorizer) FullAdmin() (bool, error) {
+	return a.hasScope("cloud_controller.admin")
+}
+
+// User returns the user name the token was issued to, or the client id for
+// tokens issued to a client rather than a user
+func (a *ClientAuthorizer) User() (string, error) {
+	if err := a.composeClaims(); err != nil {
+		return "", err
+	}
+	if a.claims.UserName != "" {
+		return a.claims.UserName, nil
+	}
+	if a.claims.ClientID != "" {
+		return a.claims.ClientID, nil
+	}
+	return "", errors.New("token has no user_name or client_id")
+}
+
 func (a *ClientAuthorizer) hasScope(scope string) (bool, error) {
 	if a.scopes == nil {
 		var err error
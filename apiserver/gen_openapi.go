@@ -0,0 +1,47 @@
+//go:build ignore
+// +build ignore
+
+// gen_openapi writes openapi_document.go, which holds openapi.json as the
+// openAPIDocument constant so that the document is built into the binary.
+// Run it with go generate after changing openapi.json.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/format"
+	"io/ioutil"
+	"log"
+)
+
+func main() {
+	document, err := ioutil.ReadFile("openapi.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !json.Valid(document) {
+		log.Fatal("openapi.json is not valid JSON")
+	}
+	if bytes.ContainsRune(document, '`') {
+		log.Fatal("openapi.json must not contain a backquote")
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by go run gen_openapi.go; DO NOT EDIT.\n\n")
+	src.WriteString("package apiserver\n\n")
+	src.WriteString("// openAPIDocument is openapi.json, which describes every route in New.\n")
+	src.WriteString("// Requests are checked against the parameters and request bodies it\n")
+	src.WriteString("// documents by validateRequest, so it must be kept up to date as routes are\n")
+	src.WriteString("// added or changed.\n")
+	src.WriteString("const openAPIDocument = `")
+	src.Write(document)
+	src.WriteString("`\n")
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("openapi_document.go", formatted, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,439 @@
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/labstack/echo"
+	uuid "github.com/satori/go.uuid"
+)
+
+// openAPIDocument is generated from openapi.json
+//go:generate go run gen_openapi.go
+
+// openAPISpec is the part of an OpenAPI document that requests are
+// validated against
+type openAPISpec struct {
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Parameters map[string]openAPIParameter `json:"parameters"`
+		Schemas    map[string]openAPISchema    `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	Parameters  []openAPIParameter  `json:"parameters"`
+	RequestBody *openAPIRequestBody `json:"requestBody"`
+	Security    *[]json.RawMessage  `json:"security"`
+}
+
+type openAPIParameter struct {
+	Ref      string        `json:"$ref"`
+	Name     string        `json:"name"`
+	In       string        `json:"in"`
+	Required bool          `json:"required"`
+	Schema   openAPISchema `json:"schema"`
+}
+
+// openAPIRequestBody is a documented request body. Name is the x-name
+// extension, which problems with the body are reported under, such as
+// events for events[1].plan_guid.
+type openAPIRequestBody struct {
+	Name    string `json:"x-name"`
+	Content map[string]struct {
+		Schema openAPISchema `json:"schema"`
+	} `json:"content"`
+}
+
+type openAPISchema struct {
+	Ref        string                   `json:"$ref"`
+	Type       string                   `json:"type"`
+	Format     string                   `json:"format"`
+	Enum       []string                 `json:"enum"`
+	Minimum    *int64                   `json:"minimum"`
+	Maximum    *int64                   `json:"maximum"`
+	Items      *openAPISchema           `json:"items"`
+	Properties map[string]openAPISchema `json:"properties"`
+	Required   []string                 `json:"required"`
+	OneOf      []openAPISchema          `json:"oneOf"`
+}
+
+// openAPIFormats are the string formats that values are checked against,
+// with what a value of each is called in errors. Other formats are only
+// descriptive.
+var openAPIFormats = map[string]struct {
+	name  string
+	valid func(string) bool
+}{
+	"uuid": {"guid", func(s string) bool {
+		_, err := uuid.FromString(s)
+		return err == nil
+	}},
+	"date": {"date", func(s string) bool {
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	}},
+	"date-time": {"RFC3339 timestamp", func(s string) bool {
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	}},
+	"uri": {"url", func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.IsAbs()
+	}},
+}
+
+var openAPI = mustParseOpenAPI(openAPIDocument)
+
+func mustParseOpenAPI(document string) openAPISpec {
+	var spec openAPISpec
+	if err := json.Unmarshal([]byte(document), &spec); err != nil {
+		panic(fmt.Sprintf("the OpenAPI document is not valid: %s", err))
+	}
+	return spec
+}
+
+// operation returns the documented operation for an echo route, such as GET
+// /statements/:org_guid/:month
+func (spec openAPISpec) operation(method string, route string) (openAPIOperation, bool) {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	op, ok := spec.Paths[strings.Join(segments, "/")][strings.ToLower(method)]
+	return op, ok
+}
+
+// parameters returns the parameters of the operation with their references
+// resolved
+func (spec openAPISpec) parameters(op openAPIOperation) []openAPIParameter {
+	params := []openAPIParameter{}
+	for _, param := range op.Parameters {
+		if param.Ref != "" {
+			param = spec.Components.Parameters[strings.TrimPrefix(param.Ref, "#/components/parameters/")]
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// validateRequest checks the query and path parameters of each request
+// against those documented for its route, returning a 400 for the first that
+// is missing or not valid, and then its JSON body, returning a 400 with every
+// problem found. Values are checked for their type, bounds, allowed values
+// and format, what they mean is left to the handlers.
+//
+// Only the parameters of operations that need no credentials are checked
+// here. The rest are checked when the handler calls validateParams once the
+// request is authorized, so that a request without a valid token is
+// rejected with a 401 whatever its parameters are.
+func validateRequest(spec openAPISpec) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			op, ok := spec.operation(c.Request().Method, c.Path())
+			if !ok {
+				return next(c)
+			}
+			if op.isPublic() {
+				if err := spec.validate(c, op); err != nil {
+					return err
+				}
+				return next(c)
+			}
+			c.Set(validateParamsKey, func() error {
+				return spec.validate(c, op)
+			})
+			return next(c)
+		}
+	}
+}
+
+const validateParamsKey = "validate-params"
+
+// validateParams checks the parameters of an authorized request, as
+// validateRequest does for requests that need no credentials
+func validateParams(c echo.Context) error {
+	if validate, ok := c.Get(validateParamsKey).(func() error); ok {
+		return validate()
+	}
+	return nil
+}
+
+// isPublic reports whether the operation overrides the default security
+// requirement with none
+func (op openAPIOperation) isPublic() bool {
+	return op.Security != nil && len(*op.Security) == 0
+}
+
+func (spec openAPISpec) validate(c echo.Context, op openAPIOperation) error {
+	for _, param := range spec.parameters(op) {
+		var values []string
+		switch param.In {
+		case "query":
+			values = c.QueryParams()[param.Name]
+		case "path":
+			if value := c.Param(param.Name); value != "" {
+				values = []string{value}
+			}
+		default:
+			continue
+		}
+		if len(values) == 0 {
+			if param.Required {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s param is required", param.Name))
+			}
+			continue
+		}
+		schema := param.Schema
+		if schema.Type == "array" && schema.Items != nil {
+			schema = *schema.Items
+		} else if len(values) > 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s param must only be given once", param.Name))
+		}
+		for _, value := range values {
+			if err := schema.validate(param.Name, value); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+		}
+	}
+	return spec.validateBody(c, op)
+}
+
+func (schema openAPISchema) validate(name string, value string) error {
+	if len(schema.Enum) > 0 {
+		for _, allowed := range schema.Enum {
+			if value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown %s '%s', must be one of %s", name, value, joinOr(schema.Enum))
+	}
+	if format, ok := openAPIFormats[schema.Format]; ok && !format.valid(value) {
+		return fmt.Errorf("%s must be a %s - got %s", name, format.name, value)
+	}
+	if schema.Type == "integer" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		outOfRange := err != nil ||
+			(schema.Minimum != nil && n < *schema.Minimum) ||
+			(schema.Maximum != nil && n > *schema.Maximum)
+		if !outOfRange {
+			return nil
+		}
+		switch {
+		case schema.Minimum != nil && schema.Maximum != nil:
+			return fmt.Errorf("%s must be a whole number from %d to %d", name, *schema.Minimum, *schema.Maximum)
+		case schema.Minimum != nil:
+			return fmt.Errorf("%s must be a whole number of at least %d", name, *schema.Minimum)
+		default:
+			return fmt.Errorf("%s must be a whole number", name)
+		}
+	}
+	return nil
+}
+
+// validateBody checks a JSON request body against the schema documented for
+// it, returning every problem found at a path such as changes[1].plan_guid.
+// A body that is empty, too large, not JSON or not even of the documented
+// type, such as an object instead of an array, is left for the handler to
+// reject as it can say what was expected. The body is put back afterwards so
+// that the handler can read it.
+func (spec openAPISpec) validateBody(c echo.Context, op openAPIOperation) error {
+	if op.RequestBody == nil {
+		return nil
+	}
+	content, ok := op.RequestBody.Content[echo.MIMEApplicationJSON]
+	if !ok {
+		return nil
+	}
+	req := c.Request()
+	b, err := ioutil.ReadAll(io.LimitReader(req.Body, maxForecastBodySize+1))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("the request body could not be read: %s", err))
+	}
+	req.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(b), req.Body))
+	if len(b) > maxForecastBodySize {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var body interface{}
+	if err := decoder.Decode(&body); err != nil {
+		return nil
+	}
+	if schema := spec.resolve(content.Schema); schema.Type != "" && !matchesType(schema.Type, body) {
+		return nil
+	}
+	errs := eventstore.ValidationErrors{}
+	spec.validateValue(&errs, content.Schema, op.RequestBody.Name, body)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateValue adds the problems with a value decoded from a JSON body to
+// errs. A null is treated as if the value was not given, as it is by the
+// handlers. A string that is empty is not checked against its format, as
+// an empty string is how the API leaves out a guid, such as the
+// service_guid of an app.
+func (spec openAPISpec) validateValue(errs *eventstore.ValidationErrors, schema openAPISchema, path string, value interface{}) {
+	schema = spec.resolve(schema)
+	if value == nil {
+		return
+	}
+	add := func(format string, args ...interface{}) {
+		*errs = append(*errs, eventstore.ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(schema.OneOf) > 0 {
+		names := []string{}
+		for _, alternative := range schema.OneOf {
+			alternativeErrs := eventstore.ValidationErrors{}
+			spec.validateValue(&alternativeErrs, alternative, path, value)
+			if len(alternativeErrs) == 0 {
+				return
+			}
+			names = append(names, schemaTypeName(spec.resolve(alternative)))
+		}
+		add("must be %s - got %s", joinOr(names), valueTypeName(value))
+		return
+	}
+	if schema.Type != "" && !matchesType(schema.Type, value) {
+		add("must be %s - got %s", schemaTypeName(schema), valueTypeName(value))
+		return
+	}
+
+	switch v := value.(type) {
+	case string:
+		if len(schema.Enum) > 0 && !contains(schema.Enum, v) {
+			add("must be one of %s - got %s", joinOr(schema.Enum), v)
+		} else if format, ok := openAPIFormats[schema.Format]; ok && v != "" && !format.valid(v) {
+			add("'%s' is not a valid %s", v, format.name)
+		}
+	case json.Number:
+		n, err := v.Int64()
+		switch {
+		case err != nil:
+		case schema.Minimum != nil && schema.Maximum != nil && (n < *schema.Minimum || n > *schema.Maximum):
+			add("must be from %d to %d - got %d", *schema.Minimum, *schema.Maximum, n)
+		case schema.Minimum != nil && n < *schema.Minimum:
+			add("must be at least %d - got %d", *schema.Minimum, n)
+		case schema.Maximum != nil && n > *schema.Maximum:
+			add("must be at most %d - got %d", *schema.Maximum, n)
+		}
+	case []interface{}:
+		if schema.Items != nil {
+			for i, item := range v {
+				spec.validateValue(errs, *schema.Items, fmt.Sprintf("%s[%d]", path, i), item)
+			}
+		}
+	case map[string]interface{}:
+		prefix := ""
+		if path != "" {
+			prefix = path + "."
+		}
+		for _, name := range schema.Required {
+			if v[name] == nil {
+				*errs = append(*errs, eventstore.ValidationError{Path: prefix + name, Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			spec.validateValue(errs, schema.Properties[name], prefix+name, v[name])
+		}
+	}
+}
+
+// resolve returns the schema that a schema refers to, if it is a reference
+func (spec openAPISpec) resolve(schema openAPISchema) openAPISchema {
+	if schema.Ref != "" {
+		return spec.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	return schema
+}
+
+// matchesType reports whether a value decoded from JSON is of a schema type
+func matchesType(schemaType string, value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return schemaType == "string"
+	case bool:
+		return schemaType == "boolean"
+	case json.Number:
+		if schemaType == "integer" {
+			_, err := v.Int64()
+			return err == nil
+		}
+		return schemaType == "number"
+	case []interface{}:
+		return schemaType == "array"
+	case map[string]interface{}:
+		return schemaType == "object"
+	}
+	return false
+}
+
+// schemaTypeName is what values of a schema are called in errors
+func schemaTypeName(schema openAPISchema) string {
+	if schema.Type == "integer" {
+		return "a whole number"
+	}
+	return jsonTypeName(schema.Type)
+}
+
+// valueTypeName is what a value decoded from JSON is called in errors
+func valueTypeName(value interface{}) string {
+	switch value.(type) {
+	case bool:
+		return jsonTypeName("boolean")
+	case json.Number:
+		return jsonTypeName("number")
+	case []interface{}:
+		return jsonTypeName("array")
+	case map[string]interface{}:
+		return jsonTypeName("object")
+	default:
+		return jsonTypeName("string")
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// joinOr joins values as a list such as "a, b or c"
+func joinOr(values []string) string {
+	if len(values) < 2 {
+		return strings.Join(values, "")
+	}
+	return strings.Join(values[:len(values)-1], ", ") + " or " + values[len(values)-1]
+}
+
+// OpenAPIHandler serves the OpenAPI document describing the API
+func OpenAPIHandler() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, []byte(openAPIDocument))
+	}
+}
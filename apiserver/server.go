@@ -41,13 +41,43 @@ func New(cfg Config) *echo.Echo {
 		}))
 	}
 
+	e.Use(validateRequest(openAPI))
+
+	e.GET("/openapi.json", OpenAPIHandler())
 	e.GET("/vat_rates", VATRatesHandler(cfg.Store))
 	e.GET("/currency_rates", CurrencyRatesHandler(cfg.Store))
 	e.GET("/pricing_plans", PricingPlansHandler(cfg.Store))
-	e.GET("/forecast_events", ForecastEventsHandler(cfg.Store))
+	e.GET("/forecast_events", ForecastEventsHandler(cfg.Store, cfg.Store))
+	e.POST("/forecast_events", ForecastEventsHandler(cfg.Store, cfg.Store))
+	e.POST("/forecast_scenario", ForecastScenarioHandler(cfg.Store, cfg.Store, cfg.Authenticator))
 	e.GET("/usage_events", UsageEventsHandler(cfg.Store, cfg.Authenticator))
 	e.GET("/billable_events", BillableEventsHandler(cfg.Store, cfg.Store, cfg.Authenticator))
+	e.GET("/billable_summary", BillableSummaryHandler(cfg.Store, cfg.Authenticator))
+	e.GET("/month_end_projections", MonthEndProjectionsHandler(cfg.Store, cfg.Authenticator))
 	e.GET("/totals", TotalCostHandler(cfg.Store))
+	e.GET("/statements/:org_guid/:month", StatementHandler(cfg.Store, cfg.Store, cfg.Authenticator))
+	e.GET("/invoices", InvoicesHandler(cfg.Store, cfg.Authenticator))
+	e.GET("/invoices/:number", InvoiceHandler(cfg.Store, cfg.Authenticator))
+	e.POST("/invoices/:number/issue", IssueInvoiceHandler(cfg.Store, cfg.Authenticator))
+	e.POST("/invoices/:number/credit", CreditInvoiceHandler(cfg.Store, cfg.Authenticator))
+	e.GET("/anomalies", AnomaliesHandler(cfg.Store, cfg.Authenticator))
+	e.GET("/budgets", BudgetsHandler(cfg.Store, cfg.Authenticator))
+	e.PUT("/budgets/:org_guid", SetBudgetHandler(cfg.Store, cfg.Authenticator))
+	e.DELETE("/budgets/:org_guid", DeleteBudgetHandler(cfg.Store, cfg.Authenticator))
+
+	e.GET("/admin/pricing_plans", PricingPlanVersionsHandler(cfg.Store, cfg.Authenticator))
+	e.POST("/admin/pricing_plans", AddPricingPlanVersionHandler(cfg.Store, cfg.Authenticator))
+	e.GET("/admin/vat_rates", VATRateVersionsHandler(cfg.Store, cfg.Authenticator))
+	e.POST("/admin/vat_rates", AddVATRateVersionHandler(cfg.Store, cfg.Authenticator))
+	e.GET("/admin/currency_rates", CurrencyRateVersionsHandler(cfg.Store, cfg.Authenticator))
+	e.POST("/admin/currency_rates", AddCurrencyRateVersionHandler(cfg.Store, cfg.Authenticator))
+	e.GET("/admin/org_tax_treatments", OrgTaxTreatmentVersionsHandler(cfg.Store, cfg.Authenticator))
+	e.POST("/admin/org_tax_treatments", AddOrgTaxTreatmentVersionHandler(cfg.Store, cfg.Authenticator))
+	e.GET("/admin/webhooks", WebhooksHandler(cfg.Store, cfg.Authenticator))
+	e.POST("/admin/webhooks", AddWebhookHandler(cfg.Store, cfg.Authenticator))
+	e.DELETE("/admin/webhooks/:id", DeleteWebhookHandler(cfg.Store, cfg.Authenticator))
+	e.GET("/admin/webhooks/:id/deliveries", WebhookDeliveriesHandler(cfg.Store, cfg.Authenticator))
+	e.POST("/admin/webhooks/:id/deliveries/:delivery_id/retry", RetryWebhookDeliveryHandler(cfg.Store, cfg.Authenticator))
 
 	e.GET("/", status)
 
@@ -0,0 +1,37 @@
+package apiserver
+
+import (
+	"net/http"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo"
+)
+
+// AnomaliesHandler returns the unusually high daily costs found for the orgs
+func AnomaliesHandler(store eventio.AnomalyReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestedOrgs := c.Request().URL.Query()["org_guid"]
+		if ok, err := authorize(c, uaa, requestedOrgs); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		filter := eventio.EventFilter{
+			RangeStart: c.QueryParam("range_start"),
+			RangeStop:  c.QueryParam("range_stop"),
+			OrgGUIDs:   requestedOrgs,
+		}
+		if err := filter.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		anomalies, err := store.GetAnomalies(filter)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, anomalies)
+	}
+}
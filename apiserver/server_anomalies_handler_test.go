@@ -0,0 +1,111 @@
+package apiserver_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/fakes"
+	"github.com/labstack/echo"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AnomaliesHandler", func() {
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *fakes.FakeAuthenticator
+		fakeAuthorizer    *fakes.FakeAuthorizer
+		fakeStore         *fakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		orgGUID           = "f5f32499-db32-4ab7-a314-20cbe3e49080"
+		spaceGUID         = "276f4886-ac40-492d-a8cd-b2646637ba76"
+	)
+
+	BeforeEach(func() {
+		fakeStore = &fakes.FakeEventStore{}
+		fakeAuthenticator = &fakes.FakeAuthenticator{}
+		fakeAuthorizer = &fakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(true, nil)
+		fakeStore.GetAnomaliesReturns([]eventio.Anomaly{
+			{
+				ID:           7,
+				Day:          "2001-01-10",
+				Level:        eventio.AnomalySpace,
+				OrgGUID:      orgGUID,
+				OrgName:      "my-org",
+				SpaceGUID:    spaceGUID,
+				SpaceName:    "my-space",
+				Cost:         eventio.MustParseDecimal("120"),
+				Baseline:     eventio.MustParseDecimal("10"),
+				CurrencyCode: "GBP",
+				DetectedAt:   time.Date(2001, 1, 11, 0, 30, 0, 0, time.UTC),
+			},
+		}, nil)
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	var serve = func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.GET, path, nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	It("should return the anomalies of the orgs", func() {
+		res := serve("/anomalies?range_start=2001-01-01&range_stop=2001-02-01&org_guid=" + orgGUID)
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Body).To(MatchJSON(`[{
+			"id": 7,
+			"day": "2001-01-10",
+			"level": "space",
+			"org_guid": "` + orgGUID + `",
+			"org_name": "my-org",
+			"space_guid": "` + spaceGUID + `",
+			"space_name": "my-space",
+			"cost": "120",
+			"baseline": "10",
+			"currency_code": "GBP",
+			"detected_at": "2001-01-11T00:30:00Z"
+		}]`))
+
+		Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(Equal([]string{orgGUID}))
+		Expect(fakeStore.GetAnomaliesArgsForCall(0)).To(Equal(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+			OrgGUIDs:   []string{orgGUID},
+		}))
+	})
+
+	It("should not return anomalies for orgs the user can not see", func() {
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+		res := serve("/anomalies?range_start=2001-01-01&range_stop=2001-02-01&org_guid=" + orgGUID)
+
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeStore.GetAnomaliesCallCount()).To(Equal(0))
+	})
+})
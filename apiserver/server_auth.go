@@ -3,6 +3,7 @@ package apiserver
 import (
 	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/alphagov/paas-billing/apiserver/auth"
 	"github.com/labstack/echo"
@@ -40,3 +41,34 @@ func authorize(c echo.Context, uaa auth.Authenticator, orgs []string) (bool, err
 	}
 	return false, errors.New("you need to be billing_manager or an administrator to retrieve the billing data")
 }
+
+// authorizeAdmin checks that there is a token in the request with an operator
+// scope and returns who it was issued to. If write is true then the token
+// must have the cloud_controller.admin scope, the read only operator scopes
+// are not enough. what is the data being managed, for the error message.
+func authorizeAdmin(c echo.Context, uaa auth.Authenticator, write bool, what string) (string, error) {
+	token, err := auth.GetTokenFromRequest(c)
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, err)
+	}
+	authorizer, err := uaa.NewAuthorizer(token)
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, err)
+	}
+
+	isAdmin := authorizer.Admin
+	if write {
+		isAdmin = authorizer.FullAdmin
+	}
+	if ok, err := isAdmin(); err != nil {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, fmt.Errorf("invalid credentials: %s", err))
+	} else if !ok {
+		return "", echo.NewHTTPError(http.StatusForbidden, "you need to be an administrator to manage the "+what)
+	}
+
+	user, err := authorizer.User()
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, fmt.Errorf("invalid credentials: %s", err))
+	}
+	return user, nil
+}
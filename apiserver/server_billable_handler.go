@@ -22,15 +22,31 @@ func BillableEventsHandler(store eventio.BillableEventReader, consolidatedStore
 		} else if !ok {
 			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
 		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
 		// parse params
 		filter := eventio.EventFilter{
-			RangeStart: c.QueryParam("range_start"),
-			RangeStop:  c.QueryParam("range_stop"),
-			OrgGUIDs:   requestedOrgs,
+			RangeStart:    c.QueryParam("range_start"),
+			RangeStop:     c.QueryParam("range_stop"),
+			OrgGUIDs:      requestedOrgs,
+			SpaceGUIDs:    c.Request().URL.Query()["space_guid"],
+			ResourceTypes: c.Request().URL.Query()["resource_type"],
+			PlanGUIDs:     c.Request().URL.Query()["plan_guid"],
+			ResourceGUIDs: c.Request().URL.Query()["resource_guid"],
+			Currency:      c.QueryParam("currency"),
 		}
 		if err := filter.Validate(); err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
 		}
+		format, err := requestedFormat(c)
+		if err != nil {
+			return err
+		}
+		after, limit, err := requestedPage(c)
+		if err != nil {
+			return err
+		}
 
 		storeCtx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -39,10 +55,19 @@ func BillableEventsHandler(store eventio.BillableEventReader, consolidatedStore
 		rowOfRows := RowOfRows{}
 		defer rowOfRows.Close()
 
-		months, err := filter.SplitByMonth()
+		// split by the billing period of the orgs so that consolidated
+		// periods can be read from the consolidated events
+		period, err := consolidatedStore.GetBillingPeriod(requestedOrgs)
+		if err != nil {
+			return err
+		}
+		months, err := period.Split(filter)
 		if err != nil {
 			return err
 		}
+		if limit > 0 {
+			return writeBillableEventPage(storeCtx, c, store, consolidatedStore, format, months, after, limit)
+		}
 		for _, monthFilter := range months {
 			isConsolidated, err := consolidatedStore.IsRangeConsolidated(monthFilter)
 			if err != nil {
@@ -61,43 +86,86 @@ func BillableEventsHandler(store eventio.BillableEventReader, consolidatedStore
 		}
 
 		// stream response to client
-		c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
-		c.Response().WriteHeader(http.StatusOK)
-		return WriteRowsAsJson(c.Response(), c.Response(), &rowOfRows)
+		return streamEvents(c, format, BillableEventStream(&rowOfRows))
 	}
 }
 
-func WriteRowsAsJson(writer io.Writer, flusher http.Flusher, rows eventio.BillableEventRows) error {
-	if _, err := writer.Write([]byte("[\n")); err != nil {
-		return err
+// writeBillableEventPage writes the first limit events from the months,
+// starting after the cursor if there is one, with a link to the next page if
+// there are more events
+func writeBillableEventPage(
+	ctx context.Context,
+	c echo.Context,
+	store eventio.BillableEventReader,
+	consolidatedStore eventio.ConsolidatedBillableEventReader,
+	format Format,
+	months []eventio.EventFilter,
+	after *cursor,
+	limit int,
+) error {
+	if after != nil {
+		start := -1
+		for i, monthFilter := range months {
+			if monthFilter.RangeStart == after.Month {
+				start = i
+			}
+		}
+		if start < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "cursor is not valid for this range_start and range_stop")
+		}
+		months = months[start:]
+		months[0].After = &after.EventKey
 	}
-	flusher.Flush()
-	next := rows.Next()
-	delim := "\n"
-	for next {
-		b, err := rows.EventJSON()
-		if err != nil {
-			return err
+
+	page := &billableEventPage{}
+	var last, next *cursor
+	for _, monthFilter := range months {
+		if next != nil {
+			break
 		}
-		if _, err := writer.Write(b); err != nil {
+		// one more event than fits shows whether there is a next page
+		monthFilter.Limit = limit - len(page.events) + 1
+		isConsolidated, err := consolidatedStore.IsRangeConsolidated(monthFilter)
+		if err != nil {
 			return err
 		}
-		next = rows.Next()
-		if next {
-			delim = ",\n"
+		var rows eventio.BillableEventRows
+		if isConsolidated {
+			rows, err = consolidatedStore.GetConsolidatedBillableEventRows(ctx, monthFilter)
 		} else {
-			delim = "\n"
+			rows, err = store.GetBillableEventRows(ctx, monthFilter)
+		}
+		if err != nil {
+			return err
 		}
-		if _, err := writer.Write([]byte(delim)); err != nil {
+		err = func() error {
+			defer rows.Close()
+			for rows.Next() {
+				if len(page.events) == limit {
+					next = last
+					break
+				}
+				key, err := page.add(rows)
+				if err != nil {
+					return err
+				}
+				last = &cursor{Month: monthFilter.RangeStart, EventKey: key}
+			}
+			return rows.Err()
+		}()
+		if err != nil {
 			return err
 		}
-		flusher.Flush()
 	}
-	if _, err := writer.Write([]byte("]\n")); err != nil {
-		return err
+	if next != nil {
+		setNextPage(c, *next)
 	}
-	flusher.Flush()
-	return rows.Err()
+	return streamEvents(c, format, BillableEventStream(page))
+}
+
+// WriteRowsAsJson writes rows as a JSON array, flushing after each event
+func WriteRowsAsJson(writer io.Writer, flusher http.Flusher, rows eventio.BillableEventRows) error {
+	return WriteEvents(writer, flusher, FormatJSON, BillableEventStream(rows))
 }
 
 type RowOfRows struct {
@@ -226,6 +226,115 @@ var _ = Describe("BillableEventsHandler", func() {
 		Expect(res.Header().Get("Content-Type")).To(Equal("application/json; charset=UTF-8"))
 	})
 
+	It("should pass the requested currency to the store", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeRows := &fakes.FakeBillableEventRows{}
+		fakeStore.GetBillableEventRowsReturns(fakeRows, nil)
+
+		u := url.URL{}
+		u.Path = "/billable_events"
+		q := u.Query()
+		q.Set("org_guid", orgGUID1)
+		q.Set("range_start", "2001-01-01")
+		q.Set("range_stop", "2001-01-02")
+		q.Set("currency", "USD")
+		u.RawQuery = q.Encode()
+		req := httptest.NewRequest(echo.GET, u.String(), nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Code).To(Equal(200))
+		Expect(fakeStore.GetBillableEventRowsCallCount()).To(Equal(1))
+		_, filter := fakeStore.GetBillableEventRowsArgsForCall(0)
+		Expect(filter.Currency).To(Equal("USD"))
+	})
+
+	It("should pass the requested spaces, resource types, plans and resources to the store", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeRows := &fakes.FakeBillableEventRows{}
+		fakeStore.GetBillableEventRowsReturns(fakeRows, nil)
+
+		u := url.URL{}
+		u.Path = "/billable_events"
+		q := u.Query()
+		q.Set("org_guid", orgGUID1)
+		q.Set("range_start", "2001-01-01")
+		q.Set("range_stop", "2001-01-02")
+		q.Add("space_guid", "3f8b3c3e-5c3a-4a4c-9d0e-0c3a1d6e8f11")
+		q.Add("space_guid", "9f5a5d6a-93c2-4f6b-a8b4-5ad3c2e2b6f4")
+		q.Add("resource_type", "postgres")
+		q.Add("plan_guid", "f4d4b95a-f55e-4593-8d54-3364c25798c4")
+		q.Add("resource_guid", "c85e98f0-6d1b-4f45-9368-ea58263165a0")
+		u.RawQuery = q.Encode()
+		req := httptest.NewRequest(echo.GET, u.String(), nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Code).To(Equal(200))
+		Expect(fakeStore.GetBillableEventRowsCallCount()).To(Equal(1))
+		_, filter := fakeStore.GetBillableEventRowsArgsForCall(0)
+		Expect(filter.SpaceGUIDs).To(Equal([]string{"3f8b3c3e-5c3a-4a4c-9d0e-0c3a1d6e8f11", "9f5a5d6a-93c2-4f6b-a8b4-5ad3c2e2b6f4"}))
+		Expect(filter.ResourceTypes).To(Equal([]string{"postgres"}))
+		Expect(filter.PlanGUIDs).To(Equal([]string{"f4d4b95a-f55e-4593-8d54-3364c25798c4"}))
+		Expect(filter.ResourceGUIDs).To(Equal([]string{"c85e98f0-6d1b-4f45-9368-ea58263165a0"}))
+	})
+
+	It("should return a bad request for a space_guid that is not a guid", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+
+		req := httptest.NewRequest(echo.GET, "/billable_events?range_start=2001-01-01&range_stop=2001-01-02&space_guid=my-space", nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Code).To(Equal(400))
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "space_guid must be a guid - got my-space"
+		}`))
+		Expect(fakeStore.GetBillableEventRowsCallCount()).To(Equal(0))
+	})
+
+	It("should return a bad request for an unknown currency", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+
+		u := url.URL{}
+		u.Path = "/billable_events"
+		q := u.Query()
+		q.Set("org_guid", orgGUID1)
+		q.Set("range_start", "2001-01-01")
+		q.Set("range_stop", "2001-01-02")
+		q.Set("currency", "UKP")
+		u.RawQuery = q.Encode()
+		req := httptest.NewRequest(echo.GET, u.String(), nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Code).To(Equal(400))
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "unknown currency code 'UKP', must be an ISO 4217 code such as GBP or USD"
+		}`))
+		Expect(fakeStore.GetBillableEventRowsCallCount()).To(Equal(0))
+	})
+
 	It("should fetch ConsolidatedBillableEvents for whole months which have been consolidated and billable events otherwise", func() {
 		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
 		fakeAuthorizer.AdminReturns(false, nil)
@@ -277,6 +386,80 @@ var _ = Describe("BillableEventsHandler", func() {
 		Expect(res.Code).To(Equal(200))
 		Expect(res.Header().Get("Content-Type")).To(Equal("application/json; charset=UTF-8"))
 	})
+	It("should only check whole months for consolidation when the range is given as timestamps", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeRows := &fakes.FakeBillableEventRows{}
+		fakeRows.NextReturns(false)
+		fakeStore.IsRangeConsolidatedReturnsOnCall(0, false, nil)
+		fakeStore.IsRangeConsolidatedReturnsOnCall(1, true, nil)
+		fakeStore.IsRangeConsolidatedReturnsOnCall(2, false, nil)
+		fakeStore.GetBillableEventRowsReturns(fakeRows, nil)
+		fakeStore.GetConsolidatedBillableEventRowsReturns(fakeRows, nil)
+
+		u := url.URL{}
+		u.Path = "/billable_events"
+		q := u.Query()
+		q.Set("range_start", "2001-01-31T18:00:00Z")
+		q.Set("range_stop", "2001-03-01T06:00:00+01:00")
+		u.RawQuery = q.Encode()
+		req := httptest.NewRequest(echo.GET, u.String(), nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Code).To(Equal(200))
+		Expect(fakeStore.IsRangeConsolidatedCallCount()).To(Equal(3))
+		Expect(fakeStore.IsRangeConsolidatedArgsForCall(1)).To(Equal(eventio.EventFilter{
+			RangeStart: "2001-02-01",
+			RangeStop:  "2001-03-01",
+		}))
+		Expect(fakeStore.GetBillableEventRowsCallCount()).To(Equal(2))
+		_, partialStart := fakeStore.GetBillableEventRowsArgsForCall(0)
+		Expect(partialStart.RangeStart).To(Equal("2001-01-31T18:00:00Z"))
+		Expect(partialStart.RangeStop).To(Equal("2001-02-01"))
+		_, partialStop := fakeStore.GetBillableEventRowsArgsForCall(1)
+		Expect(partialStop.RangeStart).To(Equal("2001-03-01"))
+		Expect(partialStop.RangeStop).To(Equal("2001-03-01T05:00:00Z"))
+	})
+
+	It("should split the range by the billing period of the requested orgs", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeRows := &fakes.FakeBillableEventRows{}
+		fakeRows.NextReturns(false)
+		fakeStore.GetBillingPeriodReturns(eventio.BillingPeriod{StartDay: 15}, nil)
+		fakeStore.IsRangeConsolidatedReturns(false, nil)
+		fakeStore.GetBillableEventRowsReturns(fakeRows, nil)
+
+		u := url.URL{}
+		u.Path = "/billable_events"
+		q := u.Query()
+		q.Set("org_guid", orgGUID1)
+		q.Set("range_start", "2001-01-01")
+		q.Set("range_stop", "2001-03-01")
+		u.RawQuery = q.Encode()
+		req := httptest.NewRequest(echo.GET, u.String(), nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Code).To(Equal(200))
+		Expect(fakeStore.GetBillingPeriodArgsForCall(0)).To(Equal([]string{orgGUID1}))
+		Expect(fakeStore.IsRangeConsolidatedCallCount()).To(Equal(3))
+		Expect(fakeStore.IsRangeConsolidatedArgsForCall(1)).To(Equal(eventio.EventFilter{
+			RangeStart: "2001-01-15",
+			RangeStop:  "2001-02-15",
+			OrgGUIDs:   []string{orgGUID1},
+		}))
+	})
+
 	It("should fetch ConsolidatedBillableEvents when the filter range has been consolidated", func() {
 		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
 		fakeAuthorizer.AdminReturns(false, nil)
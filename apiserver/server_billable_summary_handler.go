@@ -0,0 +1,58 @@
+package apiserver
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo"
+)
+
+// BillableSummaryHandler returns the total prices of the billable events
+// grouped by the group_by parameters, which can be repeated or comma
+// separated, e.g. ?group_by=org,month
+func BillableSummaryHandler(store eventio.BillableSummaryReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestedOrgs := c.Request().URL.Query()["org_guid"]
+		if ok, err := authorize(c, uaa, requestedOrgs); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		// parse params
+		groupBy := []eventio.SummaryGroup{}
+		for _, param := range c.Request().URL.Query()["group_by"] {
+			for _, group := range strings.Split(param, ",") {
+				if group = strings.TrimSpace(group); group != "" {
+					groupBy = append(groupBy, eventio.SummaryGroup(group))
+				}
+			}
+		}
+		filter := eventio.BillableSummaryFilter{
+			EventFilter: eventio.EventFilter{
+				RangeStart:    c.QueryParam("range_start"),
+				RangeStop:     c.QueryParam("range_stop"),
+				OrgGUIDs:      requestedOrgs,
+				SpaceGUIDs:    c.Request().URL.Query()["space_guid"],
+				ResourceTypes: c.Request().URL.Query()["resource_type"],
+				PlanGUIDs:     c.Request().URL.Query()["plan_guid"],
+				ResourceGUIDs: c.Request().URL.Query()["resource_guid"],
+				Currency:      c.QueryParam("currency"),
+			},
+			GroupBy: groupBy,
+		}
+		if err := filter.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		summaries, err := store.GetBillableSummary(filter)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, summaries)
+	}
+}
@@ -0,0 +1,120 @@
+package apiserver_test
+
+import (
+	"context"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/fakes"
+	"github.com/labstack/echo"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BillableSummaryHandler", func() {
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *fakes.FakeAuthenticator
+		fakeAuthorizer    *fakes.FakeAuthorizer
+		fakeStore         *fakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		orgGUID           = "f5f32499-db32-4ab7-a314-20cbe3e49080"
+	)
+
+	BeforeEach(func() {
+		fakeStore = &fakes.FakeEventStore{}
+		fakeAuthenticator = &fakes.FakeAuthenticator{}
+		fakeAuthorizer = &fakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(true, nil)
+		fakeStore.GetBillableSummaryReturns([]eventio.BillableSummary{
+			{
+				OrgGUID:      orgGUID,
+				OrgName:      "my-org",
+				Month:        "2001-01",
+				CurrencyCode: "GBP",
+				ExVAT:        eventio.MustParseDecimal("10.5"),
+				IncVAT:       eventio.MustParseDecimal("12.6"),
+			},
+		}, nil)
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	var serve = func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.GET, path, nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	It("should return the totals for each group", func() {
+		res := serve("/billable_summary?range_start=2001-01-01&range_stop=2001-03-01&org_guid=" + orgGUID + "&group_by=org,month&group_by=space")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Body).To(MatchJSON(`[{
+			"org_guid": "` + orgGUID + `",
+			"org_name": "my-org",
+			"month": "2001-01",
+			"currency_code": "GBP",
+			"ex_vat": "10.5",
+			"inc_vat": "12.6"
+		}]`))
+
+		Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(Equal([]string{orgGUID}))
+		Expect(fakeStore.GetBillableSummaryCallCount()).To(Equal(1))
+		Expect(fakeStore.GetBillableSummaryArgsForCall(0)).To(Equal(eventio.BillableSummaryFilter{
+			EventFilter: eventio.EventFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-03-01",
+				OrgGUIDs:   []string{orgGUID},
+			},
+			GroupBy: []eventio.SummaryGroup{eventio.GroupByOrg, eventio.GroupByMonth, eventio.GroupBySpace},
+		}))
+	})
+
+	It("should not return totals for orgs the user can not see", func() {
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+		res := serve("/billable_summary?range_start=2001-01-01&range_stop=2001-02-01&org_guid=" + orgGUID)
+
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeStore.GetBillableSummaryCallCount()).To(Equal(0))
+	})
+
+	It("should reject an unknown group", func() {
+		res := serve("/billable_summary?range_start=2001-01-01&range_stop=2001-02-01&org_guid=" + orgGUID + "&group_by=org,colour")
+
+		Expect(res.Code).To(Equal(400))
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "unknown group_by value 'colour', must be one of org, space, plan, resource_type, resource, month, day"
+		}`))
+		Expect(fakeStore.GetBillableSummaryCallCount()).To(Equal(0))
+	})
+
+	It("should reject a group given more than once", func() {
+		res := serve("/billable_summary?range_start=2001-01-01&range_stop=2001-02-01&org_guid=" + orgGUID + "&group_by=org&group_by=org")
+
+		Expect(res.Code).To(Equal(400))
+		Expect(res.Body).To(MatchJSON(`{"error": "group_by value 'org' is given more than once"}`))
+	})
+})
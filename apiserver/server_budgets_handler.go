@@ -0,0 +1,82 @@
+package apiserver
+
+import (
+	"net/http"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo"
+)
+
+func BudgetsHandler(store eventio.BudgetReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestedOrgs := c.Request().URL.Query()["org_guid"]
+		if ok, err := authorize(c, uaa, requestedOrgs); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		filter := eventio.ProjectionFilter{
+			OrgGUIDs: requestedOrgs,
+		}
+		if err := filter.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		budgets, err := store.GetBudgets(requestedOrgs)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, budgets)
+	}
+}
+
+// SetBudgetHandler creates or replaces the budget of the org in the path
+func SetBudgetHandler(store eventio.BudgetWriter, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		orgGUID := c.Param("org_guid")
+		if ok, err := authorize(c, uaa, []string{orgGUID}); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		var budget eventio.Budget
+		if err := c.Bind(&budget); err != nil {
+			return err
+		}
+		budget.OrgGUID = orgGUID
+		if err := budget.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		budget, err := store.SetBudget(budget)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, budget)
+	}
+}
+
+func DeleteBudgetHandler(store eventio.BudgetWriter, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		orgGUID := c.Param("org_guid")
+		if ok, err := authorize(c, uaa, []string{orgGUID}); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		if err := store.DeleteBudget(orgGUID); err == eventio.ErrBudgetNotFound {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		} else if err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
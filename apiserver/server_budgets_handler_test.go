@@ -0,0 +1,133 @@
+package apiserver_test
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/fakes"
+	"github.com/labstack/echo"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BudgetsHandler", func() {
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *fakes.FakeAuthenticator
+		fakeAuthorizer    *fakes.FakeAuthorizer
+		fakeStore         *fakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		orgGUID           = "f5f32499-db32-4ab7-a314-20cbe3e49080"
+		budget            = eventio.Budget{
+			OrgGUID:    orgGUID,
+			Amount:     eventio.MustParseDecimal("1000"),
+			Thresholds: []int64{50, 80, 100},
+			UpdatedAt:  time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+	)
+
+	BeforeEach(func() {
+		fakeStore = &fakes.FakeEventStore{}
+		fakeAuthenticator = &fakes.FakeAuthenticator{}
+		fakeAuthorizer = &fakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(true, nil)
+		fakeStore.GetBudgetsReturns([]eventio.Budget{budget}, nil)
+		fakeStore.SetBudgetReturns(budget, nil)
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	var serve = func(method string, path string, body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, body)
+		req.Header.Set("Authorization", "bearer "+token)
+		if body != nil {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	It("should return the budgets of the orgs", func() {
+		res := serve(echo.GET, "/budgets?org_guid="+orgGUID, nil)
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Body).To(MatchJSON(`[{
+			"org_guid": "` + orgGUID + `",
+			"amount": "1000",
+			"thresholds": [50, 80, 100],
+			"updated_at": "2001-01-01T00:00:00Z"
+		}]`))
+		Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(Equal([]string{orgGUID}))
+		Expect(fakeStore.GetBudgetsArgsForCall(0)).To(Equal([]string{orgGUID}))
+	})
+
+	It("should set the budget of an org", func() {
+		res := serve(echo.PUT, "/budgets/"+orgGUID, strings.NewReader(`{"amount": "1000"}`))
+
+		Expect(res.Code).To(Equal(200))
+		Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(Equal([]string{orgGUID}))
+		Expect(fakeStore.SetBudgetCallCount()).To(Equal(1))
+		set := fakeStore.SetBudgetArgsForCall(0)
+		Expect(set.OrgGUID).To(Equal(orgGUID))
+		Expect(set.Amount.Equal(eventio.MustParseDecimal("1000"))).To(BeTrue())
+		Expect(set.Thresholds).To(Equal(eventio.DefaultBudgetThresholds))
+	})
+
+	It("should reject an invalid budget", func() {
+		res := serve(echo.PUT, "/budgets/"+orgGUID, strings.NewReader(`{"amount": "100", "thresholds": [80, 80]}`))
+
+		Expect(res.Code).To(Equal(400))
+		Expect(res.Body).To(MatchJSON(`{"error": "thresholds must not repeat - got 80 more than once"}`))
+		Expect(fakeStore.SetBudgetCallCount()).To(Equal(0))
+	})
+
+	It("should delete the budget of an org", func() {
+		res := serve(echo.DELETE, "/budgets/"+orgGUID, nil)
+
+		Expect(res.Code).To(Equal(204))
+		Expect(fakeStore.DeleteBudgetArgsForCall(0)).To(Equal(orgGUID))
+	})
+
+	It("should return 404 when deleting a budget that does not exist", func() {
+		fakeStore.DeleteBudgetReturns(eventio.ErrBudgetNotFound)
+
+		res := serve(echo.DELETE, "/budgets/"+orgGUID, nil)
+
+		Expect(res.Code).To(Equal(404))
+	})
+
+	It("should not let a user manage the budgets of orgs they are not a billing manager of", func() {
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+		Expect(serve(echo.GET, "/budgets?org_guid="+orgGUID, nil).Code).To(Equal(401))
+		Expect(serve(echo.PUT, "/budgets/"+orgGUID, strings.NewReader(`{"amount": "1000"}`)).Code).To(Equal(401))
+		Expect(serve(echo.DELETE, "/budgets/"+orgGUID, nil).Code).To(Equal(401))
+		Expect(fakeStore.GetBudgetsCallCount()).To(Equal(0))
+		Expect(fakeStore.SetBudgetCallCount()).To(Equal(0))
+		Expect(fakeStore.DeleteBudgetCallCount()).To(Equal(0))
+	})
+})
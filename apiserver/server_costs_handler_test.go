@@ -48,15 +48,15 @@ var _ = Describe("TotalCostHandler", func() {
 		fakeStore.GetTotalCostReturns([]eventio.TotalCost{
 			{
 				PlanGUID: "b1341aba-63f9-4747-9abd-d48313483044",
-				Cost:     45.23,
+				Cost:     eventio.MustParseDecimalNumber("45.23"),
 			},
 			{
 				PlanGUID: "f1019263-081c-4776-bd9e-b056e4a32e31",
-				Cost:     543,
+				Cost:     eventio.MustParseDecimalNumber("543"),
 			},
 			{
 				PlanGUID: "f19ac069-ed93-47c9-98ff-c23945b56cb9",
-				Cost:     6.23,
+				Cost:     eventio.MustParseDecimalNumber("6.23"),
 			},
 		}, nil)
 
@@ -50,12 +50,12 @@ var _ = Describe("CurrencyRatesHandler", func() {
 			{
 				Code:      "GBP",
 				ValidFrom: "2001-01-01",
-				Rate:      1.0,
+				Rate:      eventio.MustParseDecimalNumber("1.0"),
 			},
 			{
 				Code:      "USD",
 				ValidFrom: "2002-01-01",
-				Rate:      0.8,
+				Rate:      eventio.MustParseDecimalNumber("0.8"),
 			},
 		}, nil)
 		rangeStart := "2001-01-01"
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
 	"github.com/labstack/echo"
 	"github.com/lib/pq"
 )
@@ -11,6 +13,9 @@ import (
 type ErrorResponse struct {
 	Error      string `json:"error"`
 	Constraint string `json:"constraint,omitempty"`
+	// Errors is every problem found with the values in the request, if
+	// they were checked all at once
+	Errors eventstore.ValidationErrors `json:"errors,omitempty"`
 }
 
 func errorHandler(err error, c echo.Context) {
@@ -23,6 +28,16 @@ func errorHandler(err error, c echo.Context) {
 	case *echo.HTTPError:
 		code = v.Code
 		resp.Error = fmt.Sprintf("%s", v.Message)
+	case eventstore.ValidationErrors:
+		code = http.StatusBadRequest
+		resp.Error = v[0].Error()
+		if len(v) > 1 {
+			resp.Error = fmt.Sprintf("%s (and %d more problems)", resp.Error, len(v)-1)
+		}
+		resp.Errors = v
+	case *eventio.FilterError:
+		code = http.StatusBadRequest
+		resp.Error = v.Reason
 	case *pq.Error:
 		if v.Code.Name() == "check_violation" {
 			code = http.StatusBadRequest
@@ -9,6 +9,7 @@ import (
 
 	"code.cloudfoundry.org/lager"
 
+	"github.com/alphagov/paas-billing/eventio"
 	"github.com/labstack/echo"
 
 	. "github.com/alphagov/paas-billing/apiserver"
@@ -83,4 +84,15 @@ var _ = Describe("Errors", func() {
 		Expect(res.Body.String()).To(MatchJSON(`{"error":"friendly-error-message"}`))
 	})
 
+	It("should return errors of type eventio.FilterError as a bad request", func() {
+		e.GET("/filter-error", func(c echo.Context) error {
+			return &eventio.FilterError{Reason: "there is no currency_rate for 'JPY' in effect from 2001-01-01"}
+		})
+		req := httptest.NewRequest(echo.GET, "/filter-error", nil)
+		res := httptest.NewRecorder()
+		e.ServeHTTP(res, req)
+		Expect(res.Code).To(Equal(http.StatusBadRequest))
+		Expect(res.Body.String()).To(MatchJSON(`{"error":"there is no currency_rate for 'JPY' in effect from 2001-01-01"}`))
+	})
+
 })
@@ -1,18 +1,28 @@
 package apiserver
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"reflect"
+	"strings"
 
+	"github.com/alphagov/paas-billing/apiserver/auth"
 	"github.com/alphagov/paas-billing/eventio"
 	"github.com/alphagov/paas-billing/eventstore"
 	"github.com/labstack/echo"
 )
 
-func ForecastEventsHandler(store eventio.BillableEventForecaster) echo.HandlerFunc {
+// maxForecastBodySize is the largest request body accepted by
+// POST /forecast_events
+const maxForecastBodySize = 10 * 1024 * 1024
+
+func ForecastEventsHandler(store eventio.BillableEventForecaster, plans eventio.PricingPlanReader) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		requestedOrgGUIDs := c.Request().URL.Query()["org_guid"]
 		for _, guid := range requestedOrgGUIDs {
@@ -29,13 +39,23 @@ func ForecastEventsHandler(store eventio.BillableEventForecaster) echo.HandlerFu
 		if err := filter.Validate(); err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
 		}
-		inputEventData := c.QueryParam("events")
-		if inputEventData == "" {
-			return echo.NewHTTPError(http.StatusBadRequest, errors.New("events param is required"))
+		format, err := requestedFormat(c)
+		if err != nil {
+			return err
 		}
-		var inputEvents []eventio.UsageEvent
-		if err := json.Unmarshal([]byte(inputEventData), &inputEvents); err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, err)
+		inputEvents, err := requestedForecastEvents(c)
+		if err != nil {
+			return err
+		}
+		knownPlans, err := plans.GetPricingPlans(eventio.TimeRangeFilter{
+			RangeStart: filter.RangeStart,
+			RangeStop:  filter.RangeStop,
+		})
+		if err != nil {
+			return err
+		}
+		if errs := eventstore.ValidateForecastEvents(inputEvents, knownPlans); len(errs) > 0 {
+			return errs
 		}
 
 		storeCtx, cancel := context.WithCancel(context.Background())
@@ -48,38 +68,175 @@ func ForecastEventsHandler(store eventio.BillableEventForecaster) echo.HandlerFu
 		}
 		defer rows.Close()
 		// stream response to client
-		c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
-		c.Response().WriteHeader(http.StatusOK)
-		if _, err := c.Response().Write([]byte("[\n")); err != nil {
+		return streamEvents(c, format, BillableEventStream(rows))
+	}
+}
+
+// ForecastScenarioHandler forecasts the billable events of the orgs if the
+// changes in the scenario given as the request body were made to their real
+// usage events for the range
+func ForecastScenarioHandler(store eventio.BillableEventForecaster, plans eventio.PricingPlanReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestedOrgs := c.Request().URL.Query()["org_guid"]
+		if ok, err := authorize(c, uaa, requestedOrgs); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		if err := validateParams(c); err != nil {
 			return err
 		}
-		c.Response().Flush()
-		next := rows.Next()
-		delim := "\n"
-		for next {
-			b, err := rows.EventJSON()
-			if err != nil {
-				return err
-			}
-			if _, err := c.Response().Write(b); err != nil {
-				return err
-			}
-			next = rows.Next()
-			if next {
-				delim = ",\n"
-			} else {
-				delim = "\n"
-			}
-			if _, err := c.Response().Write([]byte(delim)); err != nil {
-				return err
-			}
-			c.Response().Flush()
+		// a scenario changes the usage of specific orgs, so unlike other
+		// requests an admin cannot leave the orgs out to mean every org
+		if len(requestedOrgs) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, errors.New("org_guid param is required"))
+		}
+		// parse params
+		filter := eventio.EventFilter{
+			RangeStart:    c.QueryParam("range_start"),
+			RangeStop:     c.QueryParam("range_stop"),
+			OrgGUIDs:      requestedOrgs,
+			SpaceGUIDs:    c.Request().URL.Query()["space_guid"],
+			ResourceTypes: c.Request().URL.Query()["resource_type"],
+			PlanGUIDs:     c.Request().URL.Query()["plan_guid"],
+			ResourceGUIDs: c.Request().URL.Query()["resource_guid"],
+			Currency:      c.QueryParam("currency"),
 		}
-		if _, err := c.Response().Write([]byte("]\n")); err != nil {
+		if err := filter.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		format, err := requestedFormat(c)
+		if err != nil {
 			return err
 		}
-		c.Response().Flush()
-		return rows.Err()
+		body, err := readForecastBody(c)
+		if err != nil {
+			return err
+		}
+		var scenario eventio.Scenario
+		if err := json.Unmarshal(body, &scenario); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, errors.New("the body must be a JSON scenario"))
+		}
+		forecastRange := scenario.Range(filter)
+		knownPlans, err := plans.GetPricingPlans(eventio.TimeRangeFilter{
+			RangeStart: forecastRange.RangeStart,
+			RangeStop:  forecastRange.RangeStop,
+		})
+		if err != nil {
+			return err
+		}
+		if errs := eventstore.ValidateScenario(scenario, filter, knownPlans); len(errs) > 0 {
+			return errs
+		}
 
+		storeCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// query the store
+		rows, err := store.ForecastScenarioBillableEventRows(storeCtx, scenario, filter)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		// stream response to client
+		return streamEvents(c, format, BillableEventStream(rows))
+	}
+}
+
+// requestedForecastEvents reads the usage events to forecast from the JSON
+// body of a POST, or from the events param of a GET
+func requestedForecastEvents(c echo.Context) ([]eventio.UsageEvent, error) {
+	var data []byte
+	if c.Request().Method == http.MethodPost {
+		b, err := readForecastBody(c)
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	} else {
+		data = []byte(c.QueryParam("events"))
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errors.New("events are required"))
+	}
+	return decodeForecastEvents(data)
+}
+
+// readForecastBody reads the request body, which must be at most
+// maxForecastBodySize bytes. A larger body is rejected with a 413, any other
+// failure to read it with a 400.
+func readForecastBody(c echo.Context) ([]byte, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(c.Request().Body, maxForecastBodySize+1))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("the request body could not be read: %s", err))
+	}
+	if len(b) > maxForecastBodySize {
+		return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Errorf("the request body must be at most %d bytes", maxForecastBodySize))
+	}
+	return b, nil
+}
+
+// decodeForecastEvents decodes a JSON array of usage events one at a time,
+// so that a value of the wrong type is reported with the index of its event
+func decodeForecastEvents(data []byte) ([]eventio.UsageEvent, error) {
+	var rawEvents []json.RawMessage
+	if err := json.Unmarshal(data, &rawEvents); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errors.New("events must be a JSON array of usage events"))
+	}
+	events := make([]eventio.UsageEvent, len(rawEvents))
+	errs := eventstore.ValidationErrors{}
+	for i, raw := range rawEvents {
+		path := fmt.Sprintf("events[%d]", i)
+		if err := json.Unmarshal(raw, &events[i]); err != nil {
+			if typeErr, ok := err.(*json.UnmarshalTypeError); ok && typeErr.Field != "" {
+				errs = append(errs, eventstore.ValidationError{
+					Path:    path + "." + typeErr.Field,
+					Message: fmt.Sprintf("must be %s - got %s", jsonTypeOf(typeErr.Type), jsonTypeName(typeErr.Value)),
+				})
+			} else {
+				errs = append(errs, eventstore.ValidationError{
+					Path:    path,
+					Message: "must be a usage event object",
+				})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return events, nil
+}
+
+// jsonTypeOf is the JSON type, with an article, that values of t are
+// decoded from, so that errors do not show Go type names
+func jsonTypeOf(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return jsonTypeName("boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return jsonTypeName("number")
+	case reflect.Slice, reflect.Array:
+		return jsonTypeName("array")
+	case reflect.Map, reflect.Struct:
+		return jsonTypeName("object")
+	default:
+		return jsonTypeName("string")
+	}
+}
+
+// jsonTypeName gives a JSON type, such as the Value of a
+// json.UnmarshalTypeError, with an article
+func jsonTypeName(value string) string {
+	name := strings.Fields(value + " ")[0]
+	if name == "bool" {
+		name = "boolean"
+	}
+	switch name {
+	case "array", "object":
+		return "an " + name
+	default:
+		return "a " + name
 	}
 }
@@ -0,0 +1,142 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo"
+)
+
+func InvoicesHandler(store eventio.InvoiceReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestedOrgs := c.Request().URL.Query()["org_guid"]
+		if ok, err := authorize(c, uaa, requestedOrgs); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		filter := eventio.EventFilter{
+			RangeStart: c.QueryParam("range_start"),
+			RangeStop:  c.QueryParam("range_stop"),
+			OrgGUIDs:   requestedOrgs,
+		}
+		if err := filter.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		invoices, err := store.GetInvoices(filter)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, invoices)
+	}
+}
+
+// InvoiceHandler returns an invoice to an admin or a billing manager of its
+// org. The credentials are checked before the invoice is looked up, and an
+// invoice of another org is reported as not found, so that nothing about
+// which invoice numbers are in use is revealed.
+func InvoiceHandler(store eventio.InvoiceReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token, err := auth.GetTokenFromRequest(c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		}
+		authorizer, err := uaa.NewAuthorizer(token)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		}
+		isAdmin, err := authorizer.Admin()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, fmt.Errorf("invalid credentials: %s", err))
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		number, err := invoiceNumber(c)
+		if err != nil {
+			return err
+		}
+		invoice, err := store.GetInvoice(number)
+		if err != nil {
+			return invoiceError(err)
+		}
+		if !isAdmin {
+			hasBillingAccess, err := authorizer.HasBillingAccess([]string{invoice.OrgGUID})
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, fmt.Errorf("invalid credentials: %s", err))
+			}
+			if !hasBillingAccess {
+				return invoiceError(eventio.ErrInvoiceNotFound)
+			}
+		}
+		return c.JSON(http.StatusOK, invoice)
+	}
+}
+
+func IssueInvoiceHandler(store eventio.InvoiceWriter, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, err := authorizeAdmin(c, uaa, true, "invoices")
+		if err != nil {
+			return err
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		number, err := invoiceNumber(c)
+		if err != nil {
+			return err
+		}
+		invoice, err := store.IssueInvoice(number, user)
+		if err != nil {
+			return invoiceError(err)
+		}
+		return c.JSON(http.StatusOK, invoice)
+	}
+}
+
+func CreditInvoiceHandler(store eventio.InvoiceWriter, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, err := authorizeAdmin(c, uaa, true, "invoices")
+		if err != nil {
+			return err
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		number, err := invoiceNumber(c)
+		if err != nil {
+			return err
+		}
+		invoice, err := store.CreditInvoice(number, user)
+		if err != nil {
+			return invoiceError(err)
+		}
+		return c.JSON(http.StatusOK, invoice)
+	}
+}
+
+func invoiceNumber(c echo.Context) (int64, error) {
+	number, err := strconv.ParseInt(c.Param("number"), 10, 64)
+	if err != nil || number < 1 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invoice number must be a positive integer")
+	}
+	return number, nil
+}
+
+// invoiceError turns the errors for a missing invoice or a status that can not
+// be changed into client errors
+func invoiceError(err error) error {
+	if err == eventio.ErrInvoiceNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+	if statusErr, ok := err.(*eventio.InvoiceStatusError); ok {
+		return echo.NewHTTPError(http.StatusConflict, statusErr.Reason)
+	}
+	return err
+}
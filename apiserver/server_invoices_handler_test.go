@@ -0,0 +1,236 @@
+package apiserver_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/fakes"
+	"github.com/labstack/echo"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("InvoiceHandlers", func() {
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *fakes.FakeAuthenticator
+		fakeAuthorizer    *fakes.FakeAuthorizer
+		fakeStore         *fakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		orgGUID           = "f5f32499-db32-4ab7-a314-20cbe3e49080"
+		createdAt         = time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+		invoice           eventio.Invoice
+	)
+
+	BeforeEach(func() {
+		fakeStore = &fakes.FakeEventStore{}
+		fakeAuthenticator = &fakes.FakeAuthenticator{}
+		fakeAuthorizer = &fakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeAuthorizer.FullAdminReturns(true, nil)
+		fakeAuthorizer.UserReturns("jeff@example.com", nil)
+		invoice = eventio.Invoice{
+			Number:       7,
+			OrgGUID:      orgGUID,
+			OrgName:      "my-org",
+			PeriodStart:  "2001-01-01",
+			PeriodStop:   "2001-02-01",
+			CurrencyCode: "GBP",
+			Status:       eventio.InvoiceDraft,
+			Lines: []eventio.InvoiceLine{
+				{
+					SpaceGUID: "276f4886-ac40-492d-a8cd-b2646637ba76",
+					SpaceName: "my-space",
+					PlanGUID:  "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+					PlanName:  "PLAN1",
+					VATCode:   "Standard",
+					VATRate:   eventio.MustParseDecimal("0.2"),
+					ExVAT:     eventio.MustParseDecimal("10.01"),
+				},
+			},
+			VATSummary: []eventio.InvoiceVATSummary{
+				{
+					VATCode: "Standard",
+					VATRate: eventio.MustParseDecimal("0.2"),
+					ExVAT:   eventio.MustParseDecimal("10.01"),
+					VAT:     eventio.MustParseDecimal("2.00"),
+				},
+			},
+			TotalExVAT:  eventio.MustParseDecimal("10.01"),
+			TotalVAT:    eventio.MustParseDecimal("2.00"),
+			TotalIncVAT: eventio.MustParseDecimal("12.01"),
+			CreatedAt:   createdAt,
+		}
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	var serve = func(method string, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	It("should list the invoices for the requested orgs and range", func() {
+		fakeStore.GetInvoicesReturns([]eventio.Invoice{invoice}, nil)
+
+		res := serve(echo.GET, "/invoices?range_start=2001-01-01&range_stop=2001-02-01&org_guid="+orgGUID)
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Body).To(MatchJSON(`[{
+			"number": 7,
+			"org_guid": "f5f32499-db32-4ab7-a314-20cbe3e49080",
+			"org_name": "my-org",
+			"period_start": "2001-01-01",
+			"period_stop": "2001-02-01",
+			"currency_code": "GBP",
+			"status": "draft",
+			"lines": [{
+				"space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76",
+				"space_name": "my-space",
+				"plan_guid": "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+				"plan_name": "PLAN1",
+				"vat_code": "Standard",
+				"vat_rate": "0.2",
+				"ex_vat": "10.01"
+			}],
+			"vat_summary": [{
+				"vat_code": "Standard",
+				"vat_rate": "0.2",
+				"ex_vat": "10.01",
+				"vat": "2.00"
+			}],
+			"total_ex_vat": "10.01",
+			"total_vat": "2.00",
+			"total_inc_vat": "12.01",
+			"created_at": "2001-02-03T04:05:06Z",
+			"issued_at": null,
+			"credited_at": null
+		}]`))
+		Expect(fakeStore.GetInvoicesCallCount()).To(Equal(1))
+		Expect(fakeStore.GetInvoicesArgsForCall(0)).To(Equal(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+			OrgGUIDs:   []string{orgGUID},
+		}))
+	})
+
+	It("should only list invoices for orgs the user can see", func() {
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+		res := serve(echo.GET, "/invoices?range_start=2001-01-01&range_stop=2001-02-01&org_guid="+orgGUID)
+
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeStore.GetInvoicesCallCount()).To(Equal(0))
+	})
+
+	It("should return an invoice for a billing manager of its org", func() {
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(true, nil)
+		fakeStore.GetInvoiceReturns(invoice, nil)
+
+		res := serve(echo.GET, "/invoices/7")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(fakeStore.GetInvoiceArgsForCall(0)).To(Equal(int64(7)))
+		Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(Equal([]string{orgGUID}))
+	})
+
+	It("should not return an invoice to someone who cannot see its org", func() {
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+		fakeStore.GetInvoiceReturns(invoice, nil)
+
+		res := serve(echo.GET, "/invoices/7")
+
+		Expect(res.Code).To(Equal(404))
+		Expect(res.Body).To(MatchJSON(`{"error": "invoice not found"}`))
+	})
+
+	It("should check the credentials before looking up an invoice", func() {
+		fakeAuthorizer.AdminReturns(false, errors.New("invalid token"))
+
+		res := serve(echo.GET, "/invoices/7")
+
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeStore.GetInvoiceCallCount()).To(Equal(0))
+	})
+
+	It("should return 404 for an invoice that does not exist", func() {
+		fakeStore.GetInvoiceReturns(eventio.Invoice{}, eventio.ErrInvoiceNotFound)
+
+		res := serve(echo.GET, "/invoices/8")
+
+		Expect(res.Code).To(Equal(404))
+		Expect(res.Body).To(MatchJSON(`{"error": "invoice not found"}`))
+	})
+
+	It("should reject an invoice number that is not a number", func() {
+		res := serve(echo.GET, "/invoices/seven")
+
+		Expect(res.Code).To(Equal(400))
+		Expect(fakeStore.GetInvoiceCallCount()).To(Equal(0))
+	})
+
+	It("should issue an invoice as the user", func() {
+		invoice.Status = eventio.InvoiceIssued
+		fakeStore.IssueInvoiceReturns(invoice, nil)
+
+		res := serve(echo.POST, "/invoices/7/issue")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(fakeStore.IssueInvoiceCallCount()).To(Equal(1))
+		number, by := fakeStore.IssueInvoiceArgsForCall(0)
+		Expect(number).To(Equal(int64(7)))
+		Expect(by).To(Equal("jeff@example.com"))
+	})
+
+	It("should require the full admin scope to change an invoice's status", func() {
+		fakeAuthorizer.FullAdminReturns(false, nil)
+
+		res := serve(echo.POST, "/invoices/7/credit")
+
+		Expect(res.Code).To(Equal(403))
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "you need to be an administrator to manage the invoices"
+		}`))
+		Expect(fakeStore.CreditInvoiceCallCount()).To(Equal(0))
+	})
+
+	It("should return 409 when the invoice cannot be credited", func() {
+		fakeStore.CreditInvoiceReturns(eventio.Invoice{}, &eventio.InvoiceStatusError{
+			Reason: "invoice 7 is draft, only issued invoices can be credited",
+		})
+
+		res := serve(echo.POST, "/invoices/7/credit")
+
+		Expect(res.Code).To(Equal(409))
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "invoice 7 is draft, only issued invoices can be credited"
+		}`))
+	})
+})
@@ -170,7 +170,10 @@ func (l *Logger) Write(p []byte) (int, error) {
 	logMessage := map[string]interface{}{}
 	err := json.Unmarshal(p, &logMessage)
 	if err != nil {
-		return 0, err
+		// the request logger does not escape error messages so a message
+		// containing quotes is not valid json, log it as it is
+		l.lager.Info("logger-middleware", lager.Data{"message": string(p)})
+		return len(p), nil
 	}
 	l.lager.Info("logger-middleware", logMessage)
 	return len(p), nil
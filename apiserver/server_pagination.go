@@ -0,0 +1,156 @@
+package apiserver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo"
+)
+
+const (
+	// DefaultPageLimit is the number of events in a page when a cursor is
+	// given without a limit
+	DefaultPageLimit = 1000
+	// MaxPageLimit is the most events that can be asked for in a page. Pages
+	// are read into memory before they are written.
+	MaxPageLimit = 10000
+	// HeaderNextCursor is the response header with the cursor for the next
+	// page of events, if there is one
+	HeaderNextCursor = "X-Next-Cursor"
+)
+
+// cursor is where a page of events starts. Billable events are read a
+// month at a time, so the cursor has the start of the month that the last
+// event was in as well as the key of the last event. Usage events are not
+// split by month and have no month.
+type cursor struct {
+	Month string `json:"month,omitempty"`
+	eventio.EventKey
+}
+
+func (c cursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// requestedPage returns the cursor and limit for the page of events that was
+// asked for with the cursor and limit query parameters. The cursor is nil
+// for the first page, and the limit is 0 if a page was not asked for.
+func requestedPage(c echo.Context) (*cursor, int, error) {
+	limit := 0
+	if s := c.QueryParam("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > MaxPageLimit {
+			return nil, 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("limit must be a whole number from 1 to %d", MaxPageLimit))
+		}
+		limit = n
+	}
+	s := c.QueryParam("cursor")
+	if s == "" {
+		return nil, limit, nil
+	}
+	if limit == 0 {
+		limit = DefaultPageLimit
+	}
+	invalid := echo.NewHTTPError(http.StatusBadRequest, "cursor is not valid, it must be the cursor returned for the previous page")
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, 0, invalid
+	}
+	var page cursor
+	if err := json.Unmarshal(b, &page); err != nil {
+		return nil, 0, invalid
+	}
+	if err := page.EventKey.Validate(); err != nil {
+		return nil, 0, invalid
+	}
+	return &page, limit, nil
+}
+
+// setNextPage adds the Link and X-Next-Cursor headers for the next page to
+// the response
+func setNextPage(c echo.Context, next cursor) {
+	u := *c.Request().URL
+	q := u.Query()
+	q.Set("cursor", next.String())
+	u.RawQuery = q.Encode()
+	c.Response().Header().Set(HeaderNextCursor, next.String())
+	c.Response().Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, u.String()))
+}
+
+// eventPage is a page of events read into memory, so that the connection to
+// the database is not held while it is written to the client
+type eventPage struct {
+	events []json.RawMessage
+	index  int
+}
+
+// add appends the event in rows, returning its key
+func (p *eventPage) add(rows interface{ EventJSON() ([]byte, error) }) (eventio.EventKey, error) {
+	var key eventio.EventKey
+	b, err := rows.EventJSON()
+	if err != nil {
+		return key, err
+	}
+	if err := json.Unmarshal(b, &key); err != nil {
+		return key, err
+	}
+	p.events = append(p.events, json.RawMessage(b))
+	return key, nil
+}
+
+func (p *eventPage) Next() bool {
+	p.index++
+	return p.index <= len(p.events)
+}
+
+func (p *eventPage) Err() error {
+	return nil
+}
+
+func (p *eventPage) Close() error {
+	return nil
+}
+
+func (p *eventPage) EventJSON() ([]byte, error) {
+	if p.index < 1 || p.index > len(p.events) {
+		return nil, fmt.Errorf("no more events in page")
+	}
+	return p.events[p.index-1], nil
+}
+
+type billableEventPage struct {
+	eventPage
+}
+
+func (p *billableEventPage) Event() (*eventio.BillableEvent, error) {
+	b, err := p.EventJSON()
+	if err != nil {
+		return nil, err
+	}
+	var event eventio.BillableEvent
+	if err := json.Unmarshal(b, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+type usageEventPage struct {
+	eventPage
+}
+
+func (p *usageEventPage) Event() (*eventio.UsageEvent, error) {
+	b, err := p.EventJSON()
+	if err != nil {
+		return nil, err
+	}
+	var event eventio.UsageEvent
+	if err := json.Unmarshal(b, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
@@ -72,6 +72,7 @@ var _ = Describe("PricingPlansHandler", func() {
 				MemoryInMB:    264,
 				StorageInMB:   265,
 				NumberOfNodes: 2,
+				DiskInMB:      266,
 				Components: []eventio.PricingPlanComponent{
 					{
 						Name:         "PLAN2COMPONENT1",
@@ -126,7 +127,8 @@ var _ = Describe("PricingPlansHandler", func() {
 				],
 				"memory_in_mb": 164,
 				"storage_in_mb": 165,
-				"number_of_nodes": 1
+				"number_of_nodes": 1,
+				"disk_in_mb": 0
 			},
 			{
 				"name": "PLAN2",
@@ -148,7 +150,8 @@ var _ = Describe("PricingPlansHandler", func() {
 				],
 				"memory_in_mb": 264,
 				"storage_in_mb": 265,
-				"number_of_nodes": 2
+				"number_of_nodes": 2,
+				"disk_in_mb": 266
 			}
 		]`))
 		Expect(res.Code).To(Equal(200))
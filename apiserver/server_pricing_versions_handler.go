@@ -0,0 +1,165 @@
+package apiserver
+
+import (
+	"net/http"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo"
+)
+
+func PricingPlanVersionsHandler(store eventio.PricingVersionReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, err := authorizeAdmin(c, uaa, false, "pricing data"); err != nil {
+			return err
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		versions, err := store.GetPricingPlanVersions(c.QueryParam("plan_guid"))
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, versions)
+	}
+}
+
+func AddPricingPlanVersionHandler(store eventio.PricingVersionWriter, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, err := authorizeAdmin(c, uaa, true, "pricing data")
+		if err != nil {
+			return err
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		var plan eventio.PricingPlan
+		if err := c.Bind(&plan); err != nil {
+			return err
+		}
+		version, err := store.AddPricingPlanVersion(plan, user)
+		if err != nil {
+			return versionError(err)
+		}
+		return c.JSON(http.StatusCreated, version)
+	}
+}
+
+func VATRateVersionsHandler(store eventio.PricingVersionReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, err := authorizeAdmin(c, uaa, false, "pricing data"); err != nil {
+			return err
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		versions, err := store.GetVATRateVersions(c.QueryParam("code"))
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, versions)
+	}
+}
+
+func AddVATRateVersionHandler(store eventio.PricingVersionWriter, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, err := authorizeAdmin(c, uaa, true, "pricing data")
+		if err != nil {
+			return err
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		var rate eventio.VATRate
+		if err := c.Bind(&rate); err != nil {
+			return err
+		}
+		version, err := store.AddVATRateVersion(rate, user)
+		if err != nil {
+			return versionError(err)
+		}
+		return c.JSON(http.StatusCreated, version)
+	}
+}
+
+func CurrencyRateVersionsHandler(store eventio.PricingVersionReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, err := authorizeAdmin(c, uaa, false, "pricing data"); err != nil {
+			return err
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		versions, err := store.GetCurrencyRateVersions(c.QueryParam("code"))
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, versions)
+	}
+}
+
+func AddCurrencyRateVersionHandler(store eventio.PricingVersionWriter, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, err := authorizeAdmin(c, uaa, true, "pricing data")
+		if err != nil {
+			return err
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		var rate eventio.CurrencyRate
+		if err := c.Bind(&rate); err != nil {
+			return err
+		}
+		version, err := store.AddCurrencyRateVersion(rate, user)
+		if err != nil {
+			return versionError(err)
+		}
+		return c.JSON(http.StatusCreated, version)
+	}
+}
+
+func OrgTaxTreatmentVersionsHandler(store eventio.PricingVersionReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, err := authorizeAdmin(c, uaa, false, "pricing data"); err != nil {
+			return err
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		versions, err := store.GetOrgTaxTreatmentVersions(c.QueryParam("org_guid"))
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, versions)
+	}
+}
+
+func AddOrgTaxTreatmentVersionHandler(store eventio.PricingVersionWriter, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, err := authorizeAdmin(c, uaa, true, "pricing data")
+		if err != nil {
+			return err
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		var treatment eventio.OrgTaxTreatment
+		if err := c.Bind(&treatment); err != nil {
+			return err
+		}
+		version, err := store.AddOrgTaxTreatmentVersion(treatment, user)
+		if err != nil {
+			return versionError(err)
+		}
+		return c.JSON(http.StatusCreated, version)
+	}
+}
+
+// versionError reports a rejected version as a bad request
+func versionError(err error) error {
+	if rejected, ok := err.(*eventio.VersionRejectedError); ok {
+		return echo.NewHTTPError(http.StatusBadRequest, rejected.Reason)
+	}
+	return err
+}
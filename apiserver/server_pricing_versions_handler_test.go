@@ -0,0 +1,282 @@
+package apiserver_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/fakes"
+	"github.com/labstack/echo"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PricingVersionHandlers", func() {
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *fakes.FakeAuthenticator
+		fakeAuthorizer    *fakes.FakeAuthorizer
+		fakeStore         *fakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		createdAt         = time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		fakeStore = &fakes.FakeEventStore{}
+		fakeAuthenticator = &fakes.FakeAuthenticator{}
+		fakeAuthorizer = &fakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeAuthorizer.FullAdminReturns(true, nil)
+		fakeAuthorizer.UserReturns("jeff@example.com", nil)
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	var serve = func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "bearer "+token)
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	It("should return error if no token in request", func() {
+		req := httptest.NewRequest(echo.GET, "/admin/pricing_plans", nil)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "no access_token in request"
+		}`))
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeStore.GetPricingPlanVersionsCallCount()).To(Equal(0))
+	})
+
+	It("should require an admin scope to list versions", func() {
+		fakeAuthorizer.AdminReturns(false, nil)
+
+		res := serve(echo.GET, "/admin/vat_rates", "")
+
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "you need to be an administrator to manage the pricing data"
+		}`))
+		Expect(res.Code).To(Equal(403))
+		Expect(fakeStore.GetVATRateVersionsCallCount()).To(Equal(0))
+	})
+
+	It("should require the full admin scope to add a version", func() {
+		fakeAuthorizer.FullAdminReturns(false, nil)
+
+		res := serve(echo.POST, "/admin/currency_rates", `{"code": "USD", "valid_from": "2001-02-01", "rate": 0.8}`)
+
+		Expect(res.Code).To(Equal(403))
+		Expect(fakeStore.AddCurrencyRateVersionCallCount()).To(Equal(0))
+	})
+
+	It("should list the versions of a pricing plan", func() {
+		fakeStore.GetPricingPlanVersionsReturns([]eventio.PricingPlanVersion{
+			{
+				PricingPlan: eventio.PricingPlan{
+					PlanGUID:  "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+					ValidFrom: "2001-01-01T00:00:00+00:00",
+					Name:      "PLAN1",
+					Components: []eventio.PricingPlanComponent{
+						{
+							Name:         "compute",
+							Formula:      "$time_in_seconds * 0.01",
+							VATCode:      "Standard",
+							CurrencyCode: "GBP",
+						},
+					},
+				},
+				Version:   1,
+				CreatedAt: createdAt,
+				CreatedBy: "config.json",
+			},
+		}, nil)
+
+		res := serve(echo.GET, "/admin/pricing_plans?plan_guid=f4d4b95a-f55e-4593-8d54-3364c25798c4", "")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(fakeStore.GetPricingPlanVersionsCallCount()).To(Equal(1))
+		Expect(fakeStore.GetPricingPlanVersionsArgsForCall(0)).To(Equal("f4d4b95a-f55e-4593-8d54-3364c25798c4"))
+		Expect(res.Body).To(MatchJSON(`[
+			{
+				"version": 1,
+				"plan_guid": "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+				"valid_from": "2001-01-01T00:00:00+00:00",
+				"name": "PLAN1",
+				"memory_in_mb": 0,
+				"storage_in_mb": 0,
+				"number_of_nodes": 0,
+				"disk_in_mb": 0,
+				"components": [
+					{
+						"name": "compute",
+						"formula": "$time_in_seconds * 0.01",
+						"vat_code": "Standard",
+						"currency_code": "GBP"
+					}
+				],
+				"created_at": "2001-02-03T04:05:06Z",
+				"created_by": "config.json"
+			}
+		]`))
+	})
+
+	It("should add a pricing plan version recording who added it", func() {
+		fakeStore.AddPricingPlanVersionReturns(eventio.PricingPlanVersion{
+			Version:   2,
+			CreatedAt: createdAt,
+			CreatedBy: "jeff@example.com",
+		}, nil)
+
+		res := serve(echo.POST, "/admin/pricing_plans", `{
+			"plan_guid": "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+			"valid_from": "2001-02-01",
+			"name": "PLAN1",
+			"components": [
+				{
+					"name": "compute",
+					"formula": "$time_in_seconds * 0.02",
+					"vat_code": "Standard",
+					"currency_code": "GBP"
+				}
+			]
+		}`)
+
+		Expect(res.Code).To(Equal(201))
+		Expect(fakeStore.AddPricingPlanVersionCallCount()).To(Equal(1))
+		plan, createdBy := fakeStore.AddPricingPlanVersionArgsForCall(0)
+		Expect(createdBy).To(Equal("jeff@example.com"))
+		Expect(plan).To(Equal(eventio.PricingPlan{
+			PlanGUID:  "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+			ValidFrom: "2001-02-01",
+			Name:      "PLAN1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "compute",
+					Formula:      "$time_in_seconds * 0.02",
+					VATCode:      "Standard",
+					CurrencyCode: "GBP",
+				},
+			},
+		}))
+		Expect(res.Body).To(ContainSubstring(`"version":2`))
+	})
+
+	It("should add a currency rate version", func() {
+		fakeStore.AddCurrencyRateVersionReturns(eventio.CurrencyRateVersion{
+			CurrencyRate: eventio.CurrencyRate{
+				Code:      "USD",
+				ValidFrom: "2001-02-01T00:00:00+00:00",
+				Rate:      eventio.MustParseDecimalNumber("0.8"),
+			},
+			Version:   3,
+			CreatedAt: createdAt,
+			CreatedBy: "jeff@example.com",
+		}, nil)
+
+		res := serve(echo.POST, "/admin/currency_rates", `{"code": "USD", "valid_from": "2001-02-01", "rate": 0.8}`)
+
+		Expect(res.Code).To(Equal(201))
+		rate, createdBy := fakeStore.AddCurrencyRateVersionArgsForCall(0)
+		Expect(rate).To(Equal(eventio.CurrencyRate{
+			Code:      "USD",
+			ValidFrom: "2001-02-01",
+			Rate:      eventio.MustParseDecimalNumber("0.8"),
+		}))
+		Expect(createdBy).To(Equal("jeff@example.com"))
+		Expect(res.Body).To(MatchJSON(`{
+			"version": 3,
+			"code": "USD",
+			"valid_from": "2001-02-01T00:00:00+00:00",
+			"rate": 0.8,
+			"created_at": "2001-02-03T04:05:06Z",
+			"created_by": "jeff@example.com"
+		}`))
+	})
+
+	It("should add an org tax treatment version", func() {
+		fakeStore.AddOrgTaxTreatmentVersionReturns(eventio.OrgTaxTreatmentVersion{
+			OrgTaxTreatment: eventio.OrgTaxTreatment{
+				OrgGUID:   "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+				ValidFrom: "2001-02-01T00:00:00+00:00",
+				VATCode:   "Exempt",
+			},
+			Version:   4,
+			CreatedAt: createdAt,
+			CreatedBy: "jeff@example.com",
+		}, nil)
+
+		res := serve(echo.POST, "/admin/org_tax_treatments", `{
+			"org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+			"valid_from": "2001-02-01",
+			"vat_code": "Exempt"
+		}`)
+
+		Expect(res.Code).To(Equal(201))
+		treatment, createdBy := fakeStore.AddOrgTaxTreatmentVersionArgsForCall(0)
+		Expect(treatment).To(Equal(eventio.OrgTaxTreatment{
+			OrgGUID:   "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+			ValidFrom: "2001-02-01",
+			VATCode:   "Exempt",
+		}))
+		Expect(createdBy).To(Equal("jeff@example.com"))
+		Expect(res.Body).To(MatchJSON(`{
+			"version": 4,
+			"org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+			"valid_from": "2001-02-01T00:00:00+00:00",
+			"vat_code": "Exempt",
+			"created_at": "2001-02-03T04:05:06Z",
+			"created_by": "jeff@example.com"
+		}`))
+	})
+
+	It("should list the org tax treatments of an org", func() {
+		res := serve(echo.GET, "/admin/org_tax_treatments?org_guid=51ba75ef-edc0-47ad-a633-a8f6e8770944", "")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(fakeStore.GetOrgTaxTreatmentVersionsCallCount()).To(Equal(1))
+		Expect(fakeStore.GetOrgTaxTreatmentVersionsArgsForCall(0)).To(Equal("51ba75ef-edc0-47ad-a633-a8f6e8770944"))
+	})
+
+	It("should return a bad request if the version is rejected", func() {
+		fakeStore.AddVATRateVersionReturns(eventio.VATRateVersion{}, &eventio.VersionRejectedError{
+			Reason: `invalid vat-rate: new row for relation "vat_rates" violates check constraint "valid_from_start_of_month"`,
+		})
+
+		res := serve(echo.POST, "/admin/vat_rates", `{"code": "Standard", "valid_from": "2001-02-02", "rate": 0.2}`)
+
+		Expect(res.Code).To(Equal(400))
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "invalid vat-rate: new row for relation \"vat_rates\" violates check constraint \"valid_from_start_of_month\""
+		}`))
+	})
+})
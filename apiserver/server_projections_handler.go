@@ -0,0 +1,39 @@
+package apiserver
+
+import (
+	"net/http"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo"
+)
+
+// MonthEndProjectionsHandler returns the month to date and projected month
+// end totals of each space of the orgs
+func MonthEndProjectionsHandler(store eventio.ProjectionReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestedOrgs := c.Request().URL.Query()["org_guid"]
+		if ok, err := authorize(c, uaa, requestedOrgs); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		// parse params
+		filter := eventio.ProjectionFilter{
+			OrgGUIDs: requestedOrgs,
+			Currency: c.QueryParam("currency"),
+		}
+		if err := filter.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		projections, err := store.GetMonthEndProjections(filter)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, projections)
+	}
+}
@@ -0,0 +1,119 @@
+package apiserver_test
+
+import (
+	"context"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/fakes"
+	"github.com/labstack/echo"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MonthEndProjectionsHandler", func() {
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *fakes.FakeAuthenticator
+		fakeAuthorizer    *fakes.FakeAuthorizer
+		fakeStore         *fakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		orgGUID           = "f5f32499-db32-4ab7-a314-20cbe3e49080"
+		spaceGUID         = "276f4886-ac40-492d-a8cd-b2646637ba76"
+	)
+
+	BeforeEach(func() {
+		fakeStore = &fakes.FakeEventStore{}
+		fakeAuthenticator = &fakes.FakeAuthenticator{}
+		fakeAuthorizer = &fakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(true, nil)
+		fakeStore.GetMonthEndProjectionsReturns([]eventio.Projection{
+			{
+				OrgGUID:           orgGUID,
+				OrgName:           "my-org",
+				SpaceGUID:         spaceGUID,
+				SpaceName:         "my-space",
+				PeriodStart:       "2001-01-01",
+				PeriodStop:        "2001-02-01",
+				AsOf:              "2001-01-10T12:00:00Z",
+				CurrencyCode:      "USD",
+				MonthToDateExVAT:  eventio.MustParseDecimal("10"),
+				MonthToDateIncVAT: eventio.MustParseDecimal("12"),
+				ProjectedExVAT:    eventio.MustParseDecimal("31"),
+				ProjectedIncVAT:   eventio.MustParseDecimal("37.2"),
+			},
+		}, nil)
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	var serve = func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.GET, path, nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	It("should return the projections for each space", func() {
+		res := serve("/month_end_projections?org_guid=" + orgGUID + "&currency=USD")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Body).To(MatchJSON(`[{
+			"org_guid": "` + orgGUID + `",
+			"org_name": "my-org",
+			"space_guid": "` + spaceGUID + `",
+			"space_name": "my-space",
+			"period_start": "2001-01-01",
+			"period_stop": "2001-02-01",
+			"as_of": "2001-01-10T12:00:00Z",
+			"currency_code": "USD",
+			"month_to_date_ex_vat": "10",
+			"month_to_date_inc_vat": "12",
+			"projected_ex_vat": "31",
+			"projected_inc_vat": "37.2"
+		}]`))
+
+		Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(Equal([]string{orgGUID}))
+		Expect(fakeStore.GetMonthEndProjectionsArgsForCall(0)).To(Equal(eventio.ProjectionFilter{
+			OrgGUIDs: []string{orgGUID},
+			Currency: "USD",
+		}))
+	})
+
+	It("should not return projections for orgs the user can not see", func() {
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+		res := serve("/month_end_projections?org_guid=" + orgGUID)
+
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeStore.GetMonthEndProjectionsCallCount()).To(Equal(0))
+	})
+
+	It("should reject an invalid org", func() {
+		res := serve("/month_end_projections?org_guid=my-org")
+
+		Expect(res.Code).To(Equal(400))
+		Expect(res.Body).To(MatchJSON(`{"error": "org_guid must be a guid - got my-org"}`))
+		Expect(fakeStore.GetMonthEndProjectionsCallCount()).To(Equal(0))
+	})
+})
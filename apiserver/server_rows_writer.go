@@ -0,0 +1,348 @@
+package apiserver
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo"
+)
+
+const MIMETextCSVCharsetUTF8 = "text/csv; charset=UTF-8"
+const MIMEApplicationNDJSON = "application/x-ndjson"
+
+// Format is how a stream of events is written to the client
+type Format string
+
+const (
+	// FormatJSON writes a JSON array of events
+	FormatJSON Format = "json"
+	// FormatNDJSON writes each event as JSON on a line of its own
+	FormatNDJSON Format = "ndjson"
+	// FormatCSV writes a header and then one row per event, or for billable
+	// events one row per price component
+	FormatCSV Format = "csv"
+)
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatNDJSON:
+		return MIMEApplicationNDJSON
+	case FormatCSV:
+		return MIMETextCSVCharsetUTF8
+	}
+	return echo.MIMEApplicationJSONCharsetUTF8
+}
+
+// requestedFormat picks the format from the format query parameter, or else
+// from the first media type in the Accept header that a format is available
+// for. The default is JSON.
+func requestedFormat(c echo.Context) (Format, error) {
+	if format := c.QueryParam("format"); format != "" {
+		switch Format(format) {
+		case FormatJSON, FormatNDJSON, FormatCSV:
+			return Format(format), nil
+		}
+		return "", echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown format '%s', must be one of json, ndjson or csv", format))
+	}
+	accept := c.Request().Header.Get(echo.HeaderAccept)
+	if strings.TrimSpace(accept) == "" {
+		return FormatJSON, nil
+	}
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(mediaRange)
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/json", "application/*", "*/*":
+			return FormatJSON, nil
+		case "application/x-ndjson", "application/ndjson":
+			return FormatNDJSON, nil
+		case "text/csv", "text/*":
+			return FormatCSV, nil
+		}
+	}
+	return "", echo.NewHTTPError(http.StatusNotAcceptable, "events can only be returned as application/json, application/x-ndjson or text/csv")
+}
+
+// EventStream is a stream of events that can be written in any Format
+type EventStream interface {
+	Next() bool
+	Err() error
+	EventJSON() ([]byte, error)
+	// CSVHeader is the names of the columns of CSVRecords
+	CSVHeader() []string
+	// CSVRecords is the current event as CSV rows
+	CSVRecords() ([][]string, error)
+}
+
+// BillableEventStream streams billable events. In CSV each price component
+// is a row of its own with the event's columns repeated.
+func BillableEventStream(rows eventio.BillableEventRows) EventStream {
+	return &billableEventStream{rows}
+}
+
+type billableEventStream struct {
+	eventio.BillableEventRows
+}
+
+var billableEventCSVHeader = []string{
+	"event_guid",
+	"event_start",
+	"event_stop",
+	"resource_guid",
+	"resource_name",
+	"resource_type",
+	"org_guid",
+	"org_name",
+	"space_guid",
+	"space_name",
+	"plan_guid",
+	"plan_name",
+	"quota_definition_guid",
+	"number_of_nodes",
+	"memory_in_mb",
+	"storage_in_mb",
+	"inc_vat",
+	"ex_vat",
+	"component_name",
+	"component_plan_name",
+	"component_start",
+	"component_stop",
+	"component_vat_code",
+	"component_vat_rate",
+	"component_currency_code",
+	"component_inc_vat",
+	"component_ex_vat",
+}
+
+func (s *billableEventStream) CSVHeader() []string {
+	return billableEventCSVHeader
+}
+
+func (s *billableEventStream) CSVRecords() ([][]string, error) {
+	ev, err := s.Event()
+	if err != nil {
+		return nil, err
+	}
+	event := []string{
+		ev.EventGUID,
+		ev.EventStart,
+		ev.EventStop,
+		ev.ResourceGUID,
+		ev.ResourceName,
+		ev.ResourceType,
+		ev.OrgGUID,
+		ev.OrgName,
+		ev.SpaceGUID,
+		ev.SpaceName,
+		ev.PlanGUID,
+		ev.PlanName,
+		ev.QuotaDefinitionGUID,
+		strconv.FormatInt(ev.NumberOfNodes, 10),
+		strconv.FormatInt(ev.MemoryInMB, 10),
+		strconv.FormatInt(ev.StorageInMB, 10),
+		ev.Price.IncVAT.String(),
+		ev.Price.ExVAT.String(),
+	}
+	if len(ev.Price.Details) == 0 {
+		return [][]string{append(event, make([]string, 9)...)}, nil
+	}
+	records := [][]string{}
+	for _, detail := range ev.Price.Details {
+		record := append([]string{}, event...)
+		records = append(records, append(record,
+			detail.Name,
+			detail.PlanName,
+			detail.Start,
+			detail.Stop,
+			detail.VatCode,
+			detail.VatRate.String(),
+			detail.CurrencyCode,
+			detail.IncVAT.String(),
+			detail.ExVAT.String(),
+		))
+	}
+	return records, nil
+}
+
+// UsageEventStream streams usage events
+func UsageEventStream(rows eventio.UsageEventRows) EventStream {
+	return &usageEventStream{rows}
+}
+
+type usageEventStream struct {
+	eventio.UsageEventRows
+}
+
+var usageEventCSVHeader = []string{
+	"event_guid",
+	"event_start",
+	"event_stop",
+	"resource_guid",
+	"resource_name",
+	"resource_type",
+	"org_guid",
+	"org_name",
+	"space_guid",
+	"space_name",
+	"plan_guid",
+	"plan_name",
+	"service_guid",
+	"service_name",
+	"number_of_nodes",
+	"memory_in_mb",
+	"storage_in_mb",
+	"disk_in_mb",
+}
+
+func (s *usageEventStream) CSVHeader() []string {
+	return usageEventCSVHeader
+}
+
+func (s *usageEventStream) CSVRecords() ([][]string, error) {
+	ev, err := s.Event()
+	if err != nil {
+		return nil, err
+	}
+	return [][]string{{
+		ev.EventGUID,
+		ev.EventStart,
+		ev.EventStop,
+		ev.ResourceGUID,
+		ev.ResourceName,
+		ev.ResourceType,
+		ev.OrgGUID,
+		ev.OrgName,
+		ev.SpaceGUID,
+		ev.SpaceName,
+		ev.PlanGUID,
+		ev.PlanName,
+		ev.ServiceGUID,
+		ev.ServiceName,
+		strconv.FormatInt(ev.NumberOfNodes, 10),
+		strconv.FormatInt(ev.MemoryInMB, 10),
+		strconv.FormatInt(ev.StorageInMB, 10),
+		strconv.FormatInt(ev.DiskInMB, 10),
+	}}, nil
+}
+
+// streamEvents writes a 200 response with the events in the requested
+// format, flushing after each event
+func streamEvents(c echo.Context, format Format, events EventStream) error {
+	c.Response().Header().Set(echo.HeaderContentType, format.ContentType())
+	c.Response().WriteHeader(http.StatusOK)
+	return WriteEvents(c.Response(), c.Response(), format, events)
+}
+
+// WriteEvents writes events to writer in format, flushing after each event
+func WriteEvents(writer io.Writer, flusher http.Flusher, format Format, events EventStream) error {
+	switch format {
+	case FormatNDJSON:
+		return writeNDJSON(writer, flusher, events)
+	case FormatCSV:
+		return writeCSV(writer, flusher, events)
+	}
+	return writeJSON(writer, flusher, events)
+}
+
+func writeJSON(writer io.Writer, flusher http.Flusher, events EventStream) error {
+	if _, err := writer.Write([]byte("[\n")); err != nil {
+		return err
+	}
+	flusher.Flush()
+	next := events.Next()
+	delim := "\n"
+	for next {
+		b, err := events.EventJSON()
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(b); err != nil {
+			return err
+		}
+		next = events.Next()
+		if next {
+			delim = ",\n"
+		} else {
+			delim = "\n"
+		}
+		if _, err := writer.Write([]byte(delim)); err != nil {
+			return err
+		}
+		flusher.Flush()
+	}
+	if _, err := writer.Write([]byte("]\n")); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return events.Err()
+}
+
+func writeNDJSON(writer io.Writer, flusher http.Flusher, events EventStream) error {
+	line := &bytes.Buffer{}
+	for events.Next() {
+		b, err := events.EventJSON()
+		if err != nil {
+			return err
+		}
+		// each event must be on one line
+		line.Reset()
+		if err := json.Compact(line, b); err != nil {
+			return err
+		}
+		line.WriteByte('\n')
+		if _, err := line.WriteTo(writer); err != nil {
+			return err
+		}
+		flusher.Flush()
+	}
+	return events.Err()
+}
+
+func writeCSV(writer io.Writer, flusher http.Flusher, events EventStream) error {
+	w := csv.NewWriter(writer)
+	if err := w.Write(events.CSVHeader()); err != nil {
+		return err
+	}
+	for events.Next() {
+		records, err := events.CSVRecords()
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := w.Write(escapeCSVRecord(record)); err != nil {
+				return err
+			}
+		}
+		flusher.Flush()
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return events.Err()
+}
+
+// escapeCSVRecord prefixes each cell that a spreadsheet would read as a
+// formula with a single quote, so that names chosen by tenants cannot run
+// formulas when the CSV is opened.
+func escapeCSVRecord(record []string) []string {
+	escaped := make([]string, len(record))
+	for i, cell := range record {
+		if cell != "" && strings.ContainsRune("=+-@", rune(cell[0])) {
+			cell = "'" + cell
+		}
+		escaped[i] = cell
+	}
+	return escaped
+}
@@ -0,0 +1,91 @@
+package apiserver
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/statement"
+	"github.com/labstack/echo"
+	uuid "github.com/satori/go.uuid"
+)
+
+const MIMEApplicationPDF = "application/pdf"
+
+// StatementHandler renders an org's billable events for a month as a PDF, or
+// as CSV if the month ends in ".csv", e.g. /statements/ORG_GUID/2018-01.csv
+func StatementHandler(store eventio.BillableEventReader, consolidatedStore eventio.ConsolidatedBillableEventReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		orgGUID := c.Param("org_guid")
+		if _, err := uuid.FromString(orgGUID); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "org_guid must be a guid")
+		}
+		if ok, err := authorize(c, uaa, []string{orgGUID}); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+
+		month, format := c.Param("month"), "pdf"
+		if i := strings.LastIndex(month, "."); i >= 0 {
+			month, format = month[:i], month[i+1:]
+		}
+		if format != "pdf" && format != "csv" {
+			return echo.NewHTTPError(http.StatusBadRequest, "statement format must be pdf or csv")
+		}
+		start, err := time.Parse("2006-01", month)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("a valid month is required - expected format 2006-01 - got %s", month))
+		}
+		// the statement is for the org's billing period starting in the month
+		period, err := consolidatedStore.GetBillingPeriod([]string{orgGUID})
+		if err != nil {
+			return err
+		}
+		filter, err := period.PeriodStartingIn(start.Year(), start.Month())
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		filter.OrgGUIDs = []string{orgGUID}
+
+		isConsolidated, err := consolidatedStore.IsRangeConsolidated(filter)
+		if err != nil {
+			return err
+		}
+		var events []eventio.BillableEvent
+		if isConsolidated {
+			events, err = consolidatedStore.GetConsolidatedBillableEvents(filter)
+		} else {
+			events, err = store.GetBillableEvents(filter)
+		}
+		if err != nil {
+			return err
+		}
+		s := statement.New(orgGUID, filter.RangeStart, filter.RangeStop, events)
+
+		// render before writing the headers so that errors can still be
+		// returned to the client
+		buf := &bytes.Buffer{}
+		contentType := MIMEApplicationPDF
+		if format == "csv" {
+			contentType = MIMETextCSVCharsetUTF8
+			err = statement.WriteCSV(buf, s)
+		} else {
+			err = statement.WritePDF(buf, s)
+		}
+		if err != nil {
+			return err
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(
+			`attachment; filename="statement-%s-%s.%s"`, orgGUID, month, format,
+		))
+		return c.Blob(http.StatusOK, contentType, buf.Bytes())
+	}
+}
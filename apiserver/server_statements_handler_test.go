@@ -0,0 +1,161 @@
+package apiserver_test
+
+import (
+	"context"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/fakes"
+	"github.com/labstack/echo"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StatementHandler", func() {
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *fakes.FakeAuthenticator
+		fakeAuthorizer    *fakes.FakeAuthorizer
+		fakeStore         *fakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		orgGUID           = "f5f32499-db32-4ab7-a314-20cbe3e49080"
+		events            = []eventio.BillableEvent{
+			{
+				EventGUID:    "aa30fa3c-725d-4272-9052-c7186d4968a6",
+				ResourceGUID: "c85e98f0-6d1b-4f45-9368-ea58263165a0",
+				ResourceName: "APP1",
+				ResourceType: "app",
+				OrgGUID:      orgGUID,
+				OrgName:      "my-org",
+				SpaceGUID:    "276f4886-ac40-492d-a8cd-b2646637ba76",
+				SpaceName:    "my-space",
+				PlanGUID:     "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+				Price: eventio.Price{
+					ExVAT:  eventio.MustParseDecimal("0.01"),
+					IncVAT: eventio.MustParseDecimal("0.012"),
+					Details: []eventio.PriceComponent{
+						{
+							Name:     "compute",
+							PlanName: "PLAN1",
+							VatCode:  "Standard",
+							VatRate:  eventio.MustParseDecimal("0.2"),
+							ExVAT:    eventio.MustParseDecimal("0.01"),
+							IncVAT:   eventio.MustParseDecimal("0.012"),
+						},
+					},
+				},
+			},
+		}
+	)
+
+	BeforeEach(func() {
+		fakeStore = &fakes.FakeEventStore{}
+		fakeAuthenticator = &fakes.FakeAuthenticator{}
+		fakeAuthorizer = &fakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(true, nil)
+		fakeStore.GetBillableEventsReturns(events, nil)
+		fakeStore.GetConsolidatedBillableEventsReturns(events, nil)
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	var serve = func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.GET, path, nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	It("should render a PDF statement for a billing manager of the org", func() {
+		res := serve("/statements/" + orgGUID + "/2001-01")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Header().Get("Content-Type")).To(Equal("application/pdf"))
+		Expect(res.Header().Get("Content-Disposition")).To(Equal(`attachment; filename="statement-` + orgGUID + `-2001-01.pdf"`))
+		Expect(res.Body.String()).To(HavePrefix("%PDF-1.4"))
+
+		Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(Equal([]string{orgGUID}))
+		Expect(fakeStore.GetBillableEventsCallCount()).To(Equal(1))
+		Expect(fakeStore.GetBillableEventsArgsForCall(0)).To(Equal(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+			OrgGUIDs:   []string{orgGUID},
+		}))
+	})
+
+	It("should render a CSV statement from consolidated events", func() {
+		fakeStore.IsRangeConsolidatedReturns(true, nil)
+
+		res := serve("/statements/" + orgGUID + "/2001-12.csv")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Header().Get("Content-Type")).To(Equal("text/csv; charset=UTF-8"))
+		Expect(res.Body.String()).To(ContainSubstring("component,276f4886-ac40-492d-a8cd-b2646637ba76,my-space"))
+		Expect(fakeStore.GetBillableEventsCallCount()).To(Equal(0))
+		Expect(fakeStore.GetConsolidatedBillableEventsArgsForCall(0)).To(Equal(eventio.EventFilter{
+			RangeStart: "2001-12-01",
+			RangeStop:  "2002-01-01",
+			OrgGUIDs:   []string{orgGUID},
+		}))
+	})
+
+	It("should render a statement for the org's billing period starting in the month", func() {
+		fakeStore.GetBillingPeriodReturns(eventio.BillingPeriod{StartDay: 15, Months: 3}, nil)
+
+		res := serve("/statements/" + orgGUID + "/2001-04")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(fakeStore.GetBillingPeriodArgsForCall(0)).To(Equal([]string{orgGUID}))
+		Expect(fakeStore.GetBillableEventsArgsForCall(0)).To(Equal(eventio.EventFilter{
+			RangeStart: "2001-04-15",
+			RangeStop:  "2001-07-15",
+			OrgGUIDs:   []string{orgGUID},
+		}))
+
+		res = serve("/statements/" + orgGUID + "/2001-05")
+
+		Expect(res.Code).To(Equal(400))
+		Expect(res.Body).To(MatchJSON(`{"error": "no billing period starts in 2001-05"}`))
+	})
+
+	It("should not render a statement for someone who cannot see the org", func() {
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+		res := serve("/statements/" + orgGUID + "/2001-01")
+
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeStore.GetBillableEventsCallCount()).To(Equal(0))
+	})
+
+	It("should reject an invalid month or format", func() {
+		res := serve("/statements/" + orgGUID + "/2001-13")
+		Expect(res.Code).To(Equal(400))
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "a valid month is required - expected format 2006-01 - got 2001-13"
+		}`))
+
+		res = serve("/statements/" + orgGUID + "/2001-01.xls")
+		Expect(res.Code).To(Equal(400))
+		Expect(fakeStore.GetBillableEventsCallCount()).To(Equal(0))
+	})
+})
@@ -16,15 +16,33 @@ func UsageEventsHandler(store eventio.UsageEventReader, uaa auth.Authenticator)
 		} else if !ok {
 			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
 		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
 		// parse params
 		filter := eventio.EventFilter{
-			RangeStart: c.QueryParam("range_start"),
-			RangeStop:  c.QueryParam("range_stop"),
-			OrgGUIDs:   requestedOrgs,
+			RangeStart:    c.QueryParam("range_start"),
+			RangeStop:     c.QueryParam("range_stop"),
+			OrgGUIDs:      requestedOrgs,
+			SpaceGUIDs:    c.Request().URL.Query()["space_guid"],
+			ResourceTypes: c.Request().URL.Query()["resource_type"],
+			PlanGUIDs:     c.Request().URL.Query()["plan_guid"],
+			ResourceGUIDs: c.Request().URL.Query()["resource_guid"],
 		}
 		if err := filter.Validate(); err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
 		}
+		format, err := requestedFormat(c)
+		if err != nil {
+			return err
+		}
+		after, limit, err := requestedPage(c)
+		if err != nil {
+			return err
+		}
+		if limit > 0 {
+			return writeUsageEventPage(c, store, format, filter, after, limit)
+		}
 		// query the store
 		rows, err := store.GetUsageEventRows(filter)
 		if err != nil {
@@ -32,37 +50,45 @@ func UsageEventsHandler(store eventio.UsageEventReader, uaa auth.Authenticator)
 		}
 		defer rows.Close()
 		// stream response to client
-		c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
-		c.Response().WriteHeader(http.StatusOK)
-		if _, err := c.Response().Write([]byte("[\n")); err != nil {
-			return err
-		}
-		c.Response().Flush()
-		next := rows.Next()
-		delim := "\n"
-		for next {
-			b, err := rows.EventJSON()
-			if err != nil {
-				return err
-			}
-			if _, err := c.Response().Write(b); err != nil {
-				return err
-			}
-			next = rows.Next()
-			if next {
-				delim = ",\n"
-			} else {
-				delim = "\n"
-			}
-			if _, err := c.Response().Write([]byte(delim)); err != nil {
-				return err
-			}
-			c.Response().Flush()
+		return streamEvents(c, format, UsageEventStream(rows))
+	}
+}
+
+// writeUsageEventPage writes the first limit events for the filter, starting
+// after the cursor if there is one, with a link to the next page if there are
+// more events
+func writeUsageEventPage(c echo.Context, store eventio.UsageEventReader, format Format, filter eventio.EventFilter, after *cursor, limit int) error {
+	if after != nil {
+		filter.After = &after.EventKey
+	}
+	// one more event than fits shows whether there is a next page
+	filter.Limit = limit + 1
+	rows, err := store.GetUsageEventRows(filter)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	page := &usageEventPage{}
+	var last, next *cursor
+	for rows.Next() {
+		if len(page.events) == limit {
+			next = last
+			break
 		}
-		if _, err := c.Response().Write([]byte("]\n")); err != nil {
+		key, err := page.add(rows)
+		if err != nil {
 			return err
 		}
-		c.Response().Flush()
-		return rows.Err()
+		last = &cursor{EventKey: key}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	if next != nil {
+		setNextPage(c, *next)
 	}
+	return streamEvents(c, format, UsageEventStream(page))
 }
@@ -50,17 +50,17 @@ var _ = Describe("VATRatesHandler", func() {
 			{
 				Code:      "Standard",
 				ValidFrom: "2001-01-01",
-				Rate:      0.2,
+				Rate:      eventio.MustParseDecimalNumber("0.2"),
 			},
 			{
 				Code:      "Reduced",
 				ValidFrom: "2001-07-01",
-				Rate:      0.05,
+				Rate:      eventio.MustParseDecimalNumber("0.05"),
 			},
 			{
 				Code:      "Zero",
 				ValidFrom: "2002-01-01",
-				Rate:      0.0,
+				Rate:      eventio.MustParseDecimalNumber("0.0"),
 			},
 		}, nil)
 		rangeStart := "2001-01-01"
@@ -0,0 +1,144 @@
+package apiserver
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo"
+)
+
+func WebhooksHandler(store eventio.WebhookReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, err := authorizeAdmin(c, uaa, false, "webhooks"); err != nil {
+			return err
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		webhooks, err := store.GetWebhooks()
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, webhooks)
+	}
+}
+
+// AddWebhookHandler adds a webhook, the response is the only time that its
+// secret is returned
+func AddWebhookHandler(store eventio.WebhookWriter, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, err := authorizeAdmin(c, uaa, true, "webhooks")
+		if err != nil {
+			return err
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		var webhook eventio.Webhook
+		if err := c.Bind(&webhook); err != nil {
+			return err
+		}
+		if err := webhook.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		webhook, err = store.AddWebhook(webhook, user)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusCreated, webhook)
+	}
+}
+
+func DeleteWebhookHandler(store eventio.WebhookWriter, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, err := authorizeAdmin(c, uaa, true, "webhooks"); err != nil {
+			return err
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		id, err := webhookID(c, "id")
+		if err != nil {
+			return err
+		}
+		if err := store.DeleteWebhook(id); err != nil {
+			return webhookError(err)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func WebhookDeliveriesHandler(store eventio.WebhookReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, err := authorizeAdmin(c, uaa, false, "webhooks"); err != nil {
+			return err
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		id, err := webhookID(c, "id")
+		if err != nil {
+			return err
+		}
+		filter := eventio.WebhookDeliveryFilter{
+			WebhookID: id,
+			Status:    eventio.WebhookDeliveryStatus(c.QueryParam("status")),
+		}
+		if limit := c.QueryParam("limit"); limit != "" {
+			if filter.Limit, err = strconv.Atoi(limit); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "limit must be a whole number")
+			}
+		}
+		if err := filter.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		deliveries, err := store.GetWebhookDeliveries(filter)
+		if err != nil {
+			return webhookError(err)
+		}
+		return c.JSON(http.StatusOK, deliveries)
+	}
+}
+
+func RetryWebhookDeliveryHandler(store eventio.WebhookWriter, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, err := authorizeAdmin(c, uaa, true, "webhooks"); err != nil {
+			return err
+		}
+		if err := validateParams(c); err != nil {
+			return err
+		}
+		id, err := webhookID(c, "id")
+		if err != nil {
+			return err
+		}
+		deliveryID, err := webhookID(c, "delivery_id")
+		if err != nil {
+			return err
+		}
+		delivery, err := store.RetryWebhookDelivery(id, deliveryID)
+		if err != nil {
+			return webhookError(err)
+		}
+		return c.JSON(http.StatusOK, delivery)
+	}
+}
+
+func webhookID(c echo.Context, param string) (int64, error) {
+	id, err := strconv.ParseInt(c.Param(param), 10, 64)
+	if err != nil || id < 1 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, param+" must be a positive integer")
+	}
+	return id, nil
+}
+
+// webhookError turns the errors for a missing webhook or delivery into not
+// found responses
+func webhookError(err error) error {
+	if err == eventio.ErrWebhookNotFound || err == eventio.ErrWebhookDeliveryNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+	return err
+}
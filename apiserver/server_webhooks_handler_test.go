@@ -0,0 +1,225 @@
+package apiserver_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/fakes"
+	"github.com/labstack/echo"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WebhookHandlers", func() {
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *fakes.FakeAuthenticator
+		fakeAuthorizer    *fakes.FakeAuthorizer
+		fakeStore         *fakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		createdAt         = time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+		webhook           = eventio.Webhook{
+			ID:        3,
+			URL:       "https://ledger.example.com/billing",
+			Events:    []eventio.WebhookEventType{eventio.MonthConsolidated},
+			CreatedBy: "jeff@example.com",
+			CreatedAt: createdAt,
+		}
+		delivery = eventio.WebhookDelivery{
+			ID:             7,
+			WebhookID:      3,
+			EventID:        "month.consolidated:2001-01-01/2001-02-01",
+			EventType:      eventio.MonthConsolidated,
+			Payload:        []byte(`{"id": "month.consolidated:2001-01-01/2001-02-01"}`),
+			Status:         eventio.WebhookDeliveryFailed,
+			Attempts:       10,
+			ResponseStatus: 500,
+			Error:          "webhook responded with 500 Internal Server Error",
+			LastAttemptAt:  &createdAt,
+			CreatedAt:      createdAt,
+		}
+	)
+
+	BeforeEach(func() {
+		fakeStore = &fakes.FakeEventStore{}
+		fakeAuthenticator = &fakes.FakeAuthenticator{}
+		fakeAuthorizer = &fakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeAuthorizer.FullAdminReturns(true, nil)
+		fakeAuthorizer.UserReturns("jeff@example.com", nil)
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	var serve = func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "bearer "+token)
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	It("should require an admin scope to list the webhooks", func() {
+		fakeAuthorizer.AdminReturns(false, nil)
+
+		res := serve(echo.GET, "/admin/webhooks", "")
+
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "you need to be an administrator to manage the webhooks"
+		}`))
+		Expect(res.Code).To(Equal(403))
+		Expect(fakeStore.GetWebhooksCallCount()).To(Equal(0))
+	})
+
+	It("should list the webhooks", func() {
+		fakeStore.GetWebhooksReturns([]eventio.Webhook{webhook}, nil)
+
+		res := serve(echo.GET, "/admin/webhooks", "")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Body).To(MatchJSON(`[{
+			"id": 3,
+			"url": "https://ledger.example.com/billing",
+			"events": ["month.consolidated"],
+			"created_by": "jeff@example.com",
+			"created_at": "2001-02-03T04:05:06Z"
+		}]`))
+	})
+
+	It("should require the full admin scope to add a webhook", func() {
+		fakeAuthorizer.FullAdminReturns(false, nil)
+
+		res := serve(echo.POST, "/admin/webhooks", `{"url": "https://ledger.example.com/billing"}`)
+
+		Expect(res.Code).To(Equal(403))
+		Expect(fakeStore.AddWebhookCallCount()).To(Equal(0))
+	})
+
+	It("should add a webhook and return its secret", func() {
+		added := webhook
+		added.Secret = "0123456789abcdef0123456789abcdef"
+		fakeStore.AddWebhookReturns(added, nil)
+
+		res := serve(echo.POST, "/admin/webhooks", `{"url": "https://ledger.example.com/billing", "events": ["month.consolidated"]}`)
+
+		Expect(res.Code).To(Equal(201))
+		Expect(fakeStore.AddWebhookCallCount()).To(Equal(1))
+		requested, createdBy := fakeStore.AddWebhookArgsForCall(0)
+		Expect(requested.URL).To(Equal("https://ledger.example.com/billing"))
+		Expect(requested.Events).To(Equal([]eventio.WebhookEventType{eventio.MonthConsolidated}))
+		Expect(createdBy).To(Equal("jeff@example.com"))
+		Expect(res.Body).To(MatchJSON(`{
+			"id": 3,
+			"url": "https://ledger.example.com/billing",
+			"secret": "0123456789abcdef0123456789abcdef",
+			"events": ["month.consolidated"],
+			"created_by": "jeff@example.com",
+			"created_at": "2001-02-03T04:05:06Z"
+		}`))
+	})
+
+	It("should not add a webhook for an unknown event type", func() {
+		res := serve(echo.POST, "/admin/webhooks", `{"url": "https://ledger.example.com/billing", "events": ["invoice.paid"]}`)
+
+		Expect(res.Code).To(Equal(400))
+		Expect(res.Body.String()).To(ContainSubstring(`"events[0]: must be one of month.consolidated, refresh.completed, refresh.failed or plan.detected - got invoice.paid"`))
+		Expect(fakeStore.AddWebhookCallCount()).To(Equal(0))
+	})
+
+	It("should delete a webhook", func() {
+		res := serve(echo.DELETE, "/admin/webhooks/3", "")
+
+		Expect(res.Code).To(Equal(204))
+		Expect(fakeStore.DeleteWebhookArgsForCall(0)).To(Equal(int64(3)))
+	})
+
+	It("should return a 404 when deleting a webhook that does not exist", func() {
+		fakeStore.DeleteWebhookReturns(eventio.ErrWebhookNotFound)
+
+		res := serve(echo.DELETE, "/admin/webhooks/3", "")
+
+		Expect(res.Code).To(Equal(404))
+	})
+
+	It("should list the deliveries of a webhook", func() {
+		fakeStore.GetWebhookDeliveriesReturns([]eventio.WebhookDelivery{delivery}, nil)
+
+		res := serve(echo.GET, "/admin/webhooks/3/deliveries?status=failed&limit=10", "")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(fakeStore.GetWebhookDeliveriesArgsForCall(0)).To(Equal(eventio.WebhookDeliveryFilter{
+			WebhookID: 3,
+			Status:    eventio.WebhookDeliveryFailed,
+			Limit:     10,
+		}))
+		Expect(res.Body).To(MatchJSON(`[{
+			"id": 7,
+			"webhook_id": 3,
+			"event_id": "month.consolidated:2001-01-01/2001-02-01",
+			"event_type": "month.consolidated",
+			"payload": {"id": "month.consolidated:2001-01-01/2001-02-01"},
+			"status": "failed",
+			"attempts": 10,
+			"response_status": 500,
+			"error": "webhook responded with 500 Internal Server Error",
+			"next_attempt_at": null,
+			"last_attempt_at": "2001-02-03T04:05:06Z",
+			"delivered_at": null,
+			"created_at": "2001-02-03T04:05:06Z"
+		}]`))
+	})
+
+	It("should not list deliveries with an unknown status", func() {
+		res := serve(echo.GET, "/admin/webhooks/3/deliveries?status=lost", "")
+
+		Expect(res.Code).To(Equal(400))
+		Expect(fakeStore.GetWebhookDeliveriesCallCount()).To(Equal(0))
+	})
+
+	It("should retry a delivery", func() {
+		retried := delivery
+		retried.Status = eventio.WebhookDeliveryPending
+		retried.Attempts = 0
+		fakeStore.RetryWebhookDeliveryReturns(retried, nil)
+
+		res := serve(echo.POST, "/admin/webhooks/3/deliveries/7/retry", "")
+
+		Expect(res.Code).To(Equal(200))
+		webhookID, deliveryID := fakeStore.RetryWebhookDeliveryArgsForCall(0)
+		Expect(webhookID).To(Equal(int64(3)))
+		Expect(deliveryID).To(Equal(int64(7)))
+		Expect(res.Body.String()).To(ContainSubstring(`"status":"pending"`))
+	})
+
+	It("should return a 404 when retrying a delivery that does not exist", func() {
+		fakeStore.RetryWebhookDeliveryReturns(eventio.WebhookDelivery{}, eventio.ErrWebhookDeliveryNotFound)
+
+		res := serve(echo.POST, "/admin/webhooks/3/deliveries/8/retry", "")
+
+		Expect(res.Code).To(Equal(404))
+	})
+})
@@ -0,0 +1,225 @@
+package eventio
+
+import (
+	"fmt"
+	"time"
+)
+
+// BillingPeriod is how an org's usage is cut into periods for consolidation,
+// invoices and statements. A period starts at midnight on StartDay in
+// TimeZone and lasts Months months. Periods longer than a month start in
+// AnchorMonth and every Months months after it, so a quarterly period
+// anchored in April starts in January, April, July and October.
+//
+// Any field left as its zero value takes the value in DefaultBillingPeriod.
+type BillingPeriod struct {
+	OrgGUID     string `json:"org_guid,omitempty"`
+	StartDay    int    `json:"start_day"`
+	Months      int    `json:"months"`
+	AnchorMonth int    `json:"anchor_month"`
+	TimeZone    string `json:"time_zone"`
+}
+
+// DefaultBillingPeriod is the UTC calendar month, which is the billing
+// period of every org without one of its own
+var DefaultBillingPeriod = BillingPeriod{
+	StartDay:    1,
+	Months:      1,
+	AnchorMonth: 1,
+	TimeZone:    "UTC",
+}
+
+// billingPeriodMonths are the lengths of period allowed, they divide a year
+// so that periods always start in the same months of every year
+var billingPeriodMonths = []int{1, 2, 3, 4, 6, 12}
+
+func (p BillingPeriod) normalised() BillingPeriod {
+	if p.StartDay == 0 {
+		p.StartDay = DefaultBillingPeriod.StartDay
+	}
+	if p.Months == 0 {
+		p.Months = DefaultBillingPeriod.Months
+	}
+	if p.AnchorMonth == 0 {
+		p.AnchorMonth = DefaultBillingPeriod.AnchorMonth
+	}
+	if p.TimeZone == "" {
+		p.TimeZone = DefaultBillingPeriod.TimeZone
+	}
+	return p
+}
+
+func (p BillingPeriod) Validate() error {
+	p = p.normalised()
+	if p.StartDay < 1 || p.StartDay > 28 {
+		return fmt.Errorf("start_day must be from 1 to 28 - got %d", p.StartDay)
+	}
+	validMonths := false
+	for _, months := range billingPeriodMonths {
+		if p.Months == months {
+			validMonths = true
+		}
+	}
+	if !validMonths {
+		return fmt.Errorf("months must be one of 1, 2, 3, 4, 6 or 12 - got %d", p.Months)
+	}
+	if p.AnchorMonth < 1 || p.AnchorMonth > 12 {
+		return fmt.Errorf("anchor_month must be from 1 to 12 - got %d", p.AnchorMonth)
+	}
+	if _, err := time.LoadLocation(p.TimeZone); err != nil {
+		return fmt.Errorf("time_zone must be an IANA time zone such as Europe/London - got %s", p.TimeZone)
+	}
+	return nil
+}
+
+// Equal reports whether both billing periods cut time into the same periods,
+// whichever orgs they are for
+func (p BillingPeriod) Equal(other BillingPeriod) bool {
+	p, other = p.normalised(), other.normalised()
+	p.OrgGUID, other.OrgGUID = "", ""
+	return p == other
+}
+
+func (p BillingPeriod) location() (*time.Location, error) {
+	return time.LoadLocation(p.normalised().TimeZone)
+}
+
+// periodStart returns the start of the period that t is in
+func (p BillingPeriod) periodStart(t time.Time, loc *time.Location) time.Time {
+	p = p.normalised()
+	t = t.In(loc)
+	month := t.Year()*12 + int(t.Month()) - 1
+	offset := (month - (p.AnchorMonth - 1)) % p.Months
+	if offset < 0 {
+		offset += p.Months
+	}
+	start := p.monthStart(month-offset, loc)
+	if start.After(t) {
+		start = p.monthStart(month-offset-p.Months, loc)
+	}
+	return start
+}
+
+// monthStart returns midnight on StartDay of a month counted from year 0
+func (p BillingPeriod) monthStart(month int, loc *time.Location) time.Time {
+	return time.Date(month/12, time.Month(month%12+1), p.StartDay, 0, 0, 0, 0, loc)
+}
+
+// periodStop returns the end of the period starting at start
+func (p BillingPeriod) periodStop(start time.Time) time.Time {
+	p = p.normalised()
+	return time.Date(start.Year(), start.Month()+time.Month(p.Months), p.StartDay, 0, 0, 0, 0, start.Location())
+}
+
+// Split returns a filter for each period in the range of filter. The first
+// and last are partial periods if the range does not start or end on a
+// period boundary.
+func (p BillingPeriod) Split(filter EventFilter) ([]EventFilter, error) {
+	loc, err := p.location()
+	if err != nil {
+		return nil, err
+	}
+	start, err := ParseRangeTime(filter.RangeStart)
+	if err != nil {
+		return nil, err
+	}
+	end, err := ParseRangeTime(filter.RangeStop)
+	if err != nil {
+		return nil, err
+	}
+
+	periods := []EventFilter{}
+	for t := start; t.Before(end); {
+		next := p.periodStop(p.periodStart(t, loc))
+		period := filter
+		period.RangeStart = FormatRangeTime(t)
+		period.RangeStop = FormatRangeTime(minDate(end, next))
+		periods = append(periods, period)
+		t = next
+	}
+	return periods, nil
+}
+
+// FullPeriods returns a filter for each whole period from the start of the
+// period containing startAt up to endAt
+func (p BillingPeriod) FullPeriods(startAt string, endAt string) ([]EventFilter, error) {
+	loc, err := p.location()
+	if err != nil {
+		return nil, err
+	}
+	start, err := ParseRangeTime(startAt)
+	if err != nil {
+		return nil, err
+	}
+	end, err := ParseRangeTime(endAt)
+	if err != nil {
+		return nil, err
+	}
+
+	periods := []EventFilter{}
+	for t := p.periodStart(start, loc); ; {
+		next := p.periodStop(t)
+		if next.After(end) {
+			break
+		}
+		periods = append(periods, EventFilter{
+			RangeStart: FormatRangeTime(t),
+			RangeStop:  FormatRangeTime(next),
+		})
+		t = next
+	}
+	return periods, nil
+}
+
+// PeriodStartingIn returns a filter for the period that starts in the given
+// month, which is an error if no period starts in that month
+func (p BillingPeriod) PeriodStartingIn(year int, month time.Month) (EventFilter, error) {
+	loc, err := p.location()
+	if err != nil {
+		return EventFilter{}, err
+	}
+	start := time.Date(year, month, p.normalised().StartDay, 0, 0, 0, 0, loc)
+	if !p.periodStart(start, loc).Equal(start) {
+		return EventFilter{}, fmt.Errorf("no billing period starts in %04d-%02d", year, month)
+	}
+	return EventFilter{
+		RangeStart: FormatRangeTime(start),
+		RangeStop:  FormatRangeTime(p.periodStop(start)),
+	}, nil
+}
+
+// PeriodContaining returns a filter for the period that t is in
+func (p BillingPeriod) PeriodContaining(t time.Time) (EventFilter, error) {
+	loc, err := p.location()
+	if err != nil {
+		return EventFilter{}, err
+	}
+	start := p.periodStart(t, loc)
+	return EventFilter{
+		RangeStart: FormatRangeTime(start),
+		RangeStop:  FormatRangeTime(p.periodStop(start)),
+	}, nil
+}
+
+// IsPeriod reports whether the range of filter is exactly one period
+func (p BillingPeriod) IsPeriod(filter EventFilter) bool {
+	loc, err := p.location()
+	if err != nil {
+		return false
+	}
+	start, err := ParseRangeTime(filter.RangeStart)
+	if err != nil {
+		return false
+	}
+	end, err := ParseRangeTime(filter.RangeStop)
+	if err != nil {
+		return false
+	}
+	periodStart := p.periodStart(start, loc)
+	return periodStart.Equal(start) && p.periodStop(periodStart).Equal(end)
+}
+
+// BillingPeriodReader gives the billing period of orgs
+type BillingPeriodReader interface {
+	GetBillingPeriod(orgGUIDs []string) (BillingPeriod, error)
+}
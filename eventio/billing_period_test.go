@@ -0,0 +1,137 @@
+package eventio_test
+
+import (
+	"time"
+
+	. "github.com/alphagov/paas-billing/eventio"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BillingPeriod", func() {
+	var (
+		fifteenth = BillingPeriod{StartDay: 15}
+		quarterly = BillingPeriod{Months: 3, AnchorMonth: 2}
+		london    = BillingPeriod{TimeZone: "Europe/London"}
+	)
+
+	table.DescribeTable(
+		"Split should return a list of filters split by period",
+		func(period BillingPeriod, filter EventFilter, expected []EventFilter) {
+			result, err := period.Split(filter)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(expected))
+		},
+		table.Entry(
+			"The default period should split by calendar month",
+			DefaultBillingPeriod,
+			EventFilter{RangeStart: "2018-01-15", RangeStop: "2018-03-01", OrgGUIDs: []string{"org"}},
+			[]EventFilter{
+				{RangeStart: "2018-01-15", RangeStop: "2018-02-01", OrgGUIDs: []string{"org"}},
+				{RangeStart: "2018-02-01", RangeStop: "2018-03-01", OrgGUIDs: []string{"org"}},
+			},
+		),
+		table.Entry(
+			"A zero period should split by calendar month",
+			BillingPeriod{},
+			EventFilter{RangeStart: "2018-01-01", RangeStop: "2018-02-01"},
+			[]EventFilter{{RangeStart: "2018-01-01", RangeStop: "2018-02-01"}},
+		),
+		table.Entry(
+			"Periods starting mid month should split on the start day",
+			fifteenth,
+			EventFilter{RangeStart: "2018-01-01", RangeStop: "2018-03-01"},
+			[]EventFilter{
+				{RangeStart: "2018-01-01", RangeStop: "2018-01-15"},
+				{RangeStart: "2018-01-15", RangeStop: "2018-02-15"},
+				{RangeStart: "2018-02-15", RangeStop: "2018-03-01"},
+			},
+		),
+		table.Entry(
+			"Quarterly periods should split on the quarters from the anchor month",
+			quarterly,
+			EventFilter{RangeStart: "2018-01-01", RangeStop: "2018-09-01"},
+			[]EventFilter{
+				{RangeStart: "2018-01-01", RangeStop: "2018-02-01"},
+				{RangeStart: "2018-02-01", RangeStop: "2018-05-01"},
+				{RangeStart: "2018-05-01", RangeStop: "2018-08-01"},
+				{RangeStart: "2018-08-01", RangeStop: "2018-09-01"},
+			},
+		),
+		table.Entry(
+			"Periods in a time zone should split at midnight in that time zone",
+			london,
+			EventFilter{RangeStart: "2018-05-01", RangeStop: "2018-07-01"},
+			[]EventFilter{
+				{RangeStart: "2018-05-01", RangeStop: "2018-05-31T23:00:00Z"},
+				{RangeStart: "2018-05-31T23:00:00Z", RangeStop: "2018-06-30T23:00:00Z"},
+				{RangeStart: "2018-06-30T23:00:00Z", RangeStop: "2018-07-01"},
+			},
+		),
+	)
+
+	table.DescribeTable(
+		"IsPeriod should report whether a range is exactly one period",
+		func(period BillingPeriod, filter EventFilter, expected bool) {
+			Expect(period.IsPeriod(filter)).To(Equal(expected))
+		},
+		table.Entry("calendar month", DefaultBillingPeriod, EventFilter{RangeStart: "2018-01-01", RangeStop: "2018-02-01"}, true),
+		table.Entry("two calendar months", DefaultBillingPeriod, EventFilter{RangeStart: "2018-01-01", RangeStop: "2018-03-01"}, false),
+		table.Entry("part of a calendar month", DefaultBillingPeriod, EventFilter{RangeStart: "2018-01-15", RangeStop: "2018-02-01"}, false),
+		table.Entry("mid month period", fifteenth, EventFilter{RangeStart: "2018-01-15", RangeStop: "2018-02-15"}, true),
+		table.Entry("calendar month for mid month period", fifteenth, EventFilter{RangeStart: "2018-01-01", RangeStop: "2018-02-01"}, false),
+		table.Entry("quarter", quarterly, EventFilter{RangeStart: "2018-11-01", RangeStop: "2019-02-01"}, true),
+		table.Entry("quarter not from the anchor month", quarterly, EventFilter{RangeStart: "2018-01-01", RangeStop: "2018-04-01"}, false),
+		table.Entry("month in a time zone", london, EventFilter{RangeStart: "2018-05-31T23:00:00Z", RangeStop: "2018-06-30T23:00:00Z"}, true),
+	)
+
+	It("FullPeriods should return the whole periods up to the end", func() {
+		periods, err := fifteenth.FullPeriods("2018-01-20", "2018-03-20")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(periods).To(Equal([]EventFilter{
+			{RangeStart: "2018-01-15", RangeStop: "2018-02-15"},
+			{RangeStart: "2018-02-15", RangeStop: "2018-03-15"},
+		}))
+	})
+
+	It("PeriodStartingIn should return the period that starts in a month", func() {
+		period, err := quarterly.PeriodStartingIn(2018, 5)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(period).To(Equal(EventFilter{RangeStart: "2018-05-01", RangeStop: "2018-08-01"}))
+
+		_, err = quarterly.PeriodStartingIn(2018, 6)
+		Expect(err).To(MatchError("no billing period starts in 2018-06"))
+	})
+
+	It("PeriodContaining should return the period that a time is in", func() {
+		period, err := quarterly.PeriodContaining(time.Date(2018, 7, 31, 23, 0, 0, 0, time.UTC))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(period).To(Equal(EventFilter{RangeStart: "2018-05-01", RangeStop: "2018-08-01"}))
+
+		period, err = fifteenth.PeriodContaining(time.Date(2018, 1, 14, 0, 0, 0, 0, time.UTC))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(period).To(Equal(EventFilter{RangeStart: "2017-12-15", RangeStop: "2018-01-15"}))
+
+		period, err = london.PeriodContaining(time.Date(2018, 6, 30, 23, 30, 0, 0, time.UTC))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(period).To(Equal(EventFilter{RangeStart: "2018-06-30T23:00:00Z", RangeStop: "2018-07-31T23:00:00Z"}))
+	})
+
+	It("Equal should compare periods whatever org they are for", func() {
+		Expect(BillingPeriod{OrgGUID: "org1"}.Equal(DefaultBillingPeriod)).To(BeTrue())
+		Expect(BillingPeriod{OrgGUID: "org1", StartDay: 15}.Equal(BillingPeriod{OrgGUID: "org2", StartDay: 15})).To(BeTrue())
+		Expect(fifteenth.Equal(DefaultBillingPeriod)).To(BeFalse())
+	})
+
+	table.DescribeTable(
+		"Validate should reject periods that can not be used",
+		func(period BillingPeriod, expected string) {
+			Expect(period.Validate()).To(MatchError(expected))
+		},
+		table.Entry("start day", BillingPeriod{StartDay: 31}, "start_day must be from 1 to 28 - got 31"),
+		table.Entry("months", BillingPeriod{Months: 5}, "months must be one of 1, 2, 3, 4, 6 or 12 - got 5"),
+		table.Entry("anchor month", BillingPeriod{AnchorMonth: 13}, "anchor_month must be from 1 to 12 - got 13"),
+		table.Entry("time zone", BillingPeriod{TimeZone: "Mars/Olympus_Mons"}, "time_zone must be an IANA time zone such as Europe/London - got Mars/Olympus_Mons"),
+	)
+})
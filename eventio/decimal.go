@@ -0,0 +1,308 @@
+package eventio
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MoneyPlaces is the number of decimal places that amounts of money are
+// rounded to when they are invoiced. The prices of events are never rounded,
+// only the totals that are invoiced, so that rounding errors do not add up.
+const MoneyPlaces = 2
+
+// maxExponent limits the size of numbers given in exponent notation, which
+// would otherwise allow a short string to use a huge amount of memory
+const maxExponent = 1000
+
+// Decimal is an exact decimal number, such as a price or a rate. Unlike a
+// float it holds amounts like 0.1 exactly, and unlike a string it can be
+// compared and added up. It keeps the decimal places it was given, so a price
+// read from the database is written out exactly as it was calculated. The
+// zero value is 0.
+//
+// A Decimal is written to JSON as a string, as prices have always been, and
+// can be read from either a JSON string or number.
+type Decimal struct {
+	// s is the number in its canonical form: an optional minus sign,
+	// integer digits without leading zeros and optional fractional digits.
+	// It is empty for 0, so that 0 is always equal to the zero value.
+	s string
+}
+
+// DecimalNumber is a Decimal that is written to JSON as a number, for values
+// such as rates that have always been numbers.
+type DecimalNumber struct {
+	Decimal
+}
+
+// ParseDecimal parses a decimal number such as "12", "-0.012" or "1.5e-3"
+func ParseDecimal(s string) (Decimal, error) {
+	coef, exp, err := parseDecimal(strings.TrimSpace(s))
+	if err != nil {
+		return Decimal{}, err
+	}
+	return newDecimal(coef, exp), nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics if s is not a number
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// MustParseDecimalNumber is like MustParseDecimal but returns a DecimalNumber
+func MustParseDecimalNumber(s string) DecimalNumber {
+	return DecimalNumber{MustParseDecimal(s)}
+}
+
+// NewDecimalFromInt returns i as a Decimal
+func NewDecimalFromInt(i int64) Decimal {
+	return newDecimal(big.NewInt(i), 0)
+}
+
+func parseDecimal(s string) (*big.Int, int, error) {
+	invalid := fmt.Errorf("'%s' is not a decimal number", s)
+	mantissa, exp := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil || e > maxExponent || e < -maxExponent {
+			return nil, 0, invalid
+		}
+		mantissa, exp = s[:i], e
+	}
+	sign := ""
+	if strings.HasPrefix(mantissa, "-") || strings.HasPrefix(mantissa, "+") {
+		sign, mantissa = mantissa[:1], mantissa[1:]
+	}
+	digits := mantissa
+	if i := strings.Index(mantissa, "."); i >= 0 {
+		digits = mantissa[:i] + mantissa[i+1:]
+		exp -= len(mantissa) - i - 1
+	}
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return nil, 0, invalid
+	}
+	coef, ok := new(big.Int).SetString(sign+digits, 10)
+	if !ok {
+		return nil, 0, invalid
+	}
+	return coef, exp, nil
+}
+
+// newDecimal returns coef * 10^exp, keeping -exp decimal places
+func newDecimal(coef *big.Int, exp int) Decimal {
+	if exp > 0 {
+		coef = new(big.Int).Mul(coef, pow10(exp))
+		exp = 0
+	}
+	digits := new(big.Int).Abs(coef).String()
+	places := -exp
+	if len(digits) <= places {
+		digits = strings.Repeat("0", places-len(digits)+1) + digits
+	}
+	s := digits
+	if places > 0 {
+		s = digits[:len(digits)-places] + "." + digits[len(digits)-places:]
+	}
+	if coef.Sign() < 0 {
+		s = "-" + s
+	}
+	if s == "0" {
+		s = ""
+	}
+	return Decimal{s: s}
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// parts returns d as coef * 10^-places
+func (d Decimal) parts() (*big.Int, int) {
+	if d.s == "" {
+		return new(big.Int), 0
+	}
+	coef, exp, err := parseDecimal(d.s)
+	if err != nil {
+		panic(err) // s is always canonical
+	}
+	return coef, -exp
+}
+
+// aligned returns the coefficients of d and o scaled to the same number of
+// decimal places
+func (d Decimal) aligned(o Decimal) (*big.Int, *big.Int, int) {
+	a, ap := d.parts()
+	b, bp := o.parts()
+	switch {
+	case ap < bp:
+		a.Mul(a, pow10(bp-ap))
+		return a, b, bp
+	case bp < ap:
+		b.Mul(b, pow10(ap-bp))
+	}
+	return a, b, ap
+}
+
+func (d Decimal) String() string {
+	if d.s == "" {
+		return "0"
+	}
+	return d.s
+}
+
+// Places is the number of decimal places that d has
+func (d Decimal) Places() int {
+	_, places := d.parts()
+	return places
+}
+
+func (d Decimal) Add(o Decimal) Decimal {
+	a, b, places := d.aligned(o)
+	return newDecimal(a.Add(a, b), -places)
+}
+
+func (d Decimal) Sub(o Decimal) Decimal {
+	a, b, places := d.aligned(o)
+	return newDecimal(a.Sub(a, b), -places)
+}
+
+func (d Decimal) Mul(o Decimal) Decimal {
+	a, ap := d.parts()
+	b, bp := o.parts()
+	return newDecimal(a.Mul(a, b), -(ap + bp))
+}
+
+func (d Decimal) Neg() Decimal {
+	a, places := d.parts()
+	return newDecimal(a.Neg(a), -places)
+}
+
+// Cmp returns -1, 0 or +1 if d is less than, equal to or greater than o
+func (d Decimal) Cmp(o Decimal) int {
+	a, b, _ := d.aligned(o)
+	return a.Cmp(b)
+}
+
+// Equal reports whether d and o are the same number, whatever their number
+// of decimal places
+func (d Decimal) Equal(o Decimal) bool {
+	return d.Cmp(o) == 0
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of d
+func (d Decimal) Sign() int {
+	a, _ := d.parts()
+	return a.Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Round returns d rounded to places decimal places, with halves rounded away
+// from zero in the same way as postgres' round(numeric, int). A d with fewer
+// places is padded with zeros.
+func (d Decimal) Round(places int) Decimal {
+	a, ap := d.parts()
+	if ap <= places {
+		return newDecimal(a.Mul(a, pow10(places-ap)), -places)
+	}
+	div := pow10(ap - places)
+	q, r := new(big.Int).QuoRem(a, div, new(big.Int))
+	// |r| * 2 >= div means the discarded part is at least a half
+	if r.Abs(r).Lsh(r, 1).Cmp(div) >= 0 {
+		if a.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return newDecimal(q, -places)
+}
+
+// RoundMoney rounds d to MoneyPlaces
+func (d Decimal) RoundMoney() Decimal {
+	return d.Round(MoneyPlaces)
+}
+
+// Float64 returns the nearest float64 to d, for when an exact value is not
+// needed
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		*d = Decimal{}
+		return nil
+	}
+	s := string(b)
+	if len(b) > 0 && b[0] == '"' {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		// prices used to be plain strings which were empty when unset
+		if s == "" {
+			*d = Decimal{}
+			return nil
+		}
+	}
+	v, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// Scan reads a numeric column from the database
+func (d *Decimal) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Decimal{}
+		return nil
+	case []byte:
+		return d.scanString(string(v))
+	case string:
+		return d.scanString(v)
+	case int64:
+		*d = NewDecimalFromInt(v)
+		return nil
+	case float64:
+		return d.scanString(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	return fmt.Errorf("cannot Scan into Decimal with: %T", src)
+}
+
+func (d *Decimal) scanString(s string) error {
+	v, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// Value writes d to the database as text, which postgres casts to numeric
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+func (d DecimalNumber) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
@@ -0,0 +1,106 @@
+package eventio_test
+
+import (
+	"encoding/json"
+
+	. "github.com/alphagov/paas-billing/eventio"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Decimal", func() {
+	table.DescribeTable("should keep the decimal places it was given",
+		func(s string, expected string) {
+			d, err := ParseDecimal(s)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(d.String()).To(Equal(expected))
+		},
+		table.Entry("integer", "12", "12"),
+		table.Entry("trailing zeros", "96.00", "96.00"),
+		table.Entry("leading zeros", "007.50", "7.50"),
+		table.Entry("fraction", "-.5", "-0.5"),
+		table.Entry("exponent", "1.5e-3", "0.0015"),
+		table.Entry("positive exponent", "1.5E3", "1500"),
+		table.Entry("zero", "0", "0"),
+		table.Entry("postgres division", "1.00000000000000000000", "1.00000000000000000000"),
+	)
+
+	table.DescribeTable("should reject things that are not decimal numbers",
+		func(s string) {
+			_, err := ParseDecimal(s)
+			Expect(err).To(MatchError(ContainSubstring("is not a decimal number")))
+		},
+		table.Entry("empty", ""),
+		table.Entry("letters", "ten"),
+		table.Entry("two points", "1.2.3"),
+		table.Entry("huge exponent", "1e1000000000"),
+		table.Entry("NaN", "NaN"),
+	)
+
+	It("should add, subtract and multiply exactly", func() {
+		a := MustParseDecimal("0.1")
+		b := MustParseDecimal("0.2")
+		Expect(a.Add(b).String()).To(Equal("0.3"))
+		Expect(a.Sub(b).String()).To(Equal("-0.1"))
+		Expect(a.Mul(b).String()).To(Equal("0.02"))
+		Expect(MustParseDecimal("0.012").Add(MustParseDecimal("1")).String()).To(Equal("1.012"))
+	})
+
+	It("should compare numbers whatever their decimal places", func() {
+		Expect(MustParseDecimal("1.0").Equal(MustParseDecimal("1"))).To(BeTrue())
+		Expect(MustParseDecimal("1.01").Cmp(MustParseDecimal("1.1"))).To(Equal(-1))
+		Expect(MustParseDecimal("-2").Sign()).To(Equal(-1))
+		Expect(MustParseDecimal("0.00").IsZero()).To(BeTrue())
+		Expect(MustParseDecimal("0")).To(Equal(Decimal{}))
+	})
+
+	table.DescribeTable("should round halves away from zero",
+		func(s string, places int, expected string) {
+			Expect(MustParseDecimal(s).Round(places).String()).To(Equal(expected))
+		},
+		table.Entry("down", "1.234", 2, "1.23"),
+		table.Entry("half up", "1.235", 2, "1.24"),
+		table.Entry("negative half", "-1.235", 2, "-1.24"),
+		table.Entry("pad", "1.2", 2, "1.20"),
+		table.Entry("whole", "2.5", 0, "3"),
+		table.Entry("tiny", "0.004999", 2, "0.00"),
+	)
+
+	It("should be written to JSON as a string and read from a string or number", func() {
+		var v struct {
+			Price Decimal `json:"price"`
+			Other Decimal `json:"other"`
+			Unset Decimal `json:"unset"`
+		}
+		Expect(json.Unmarshal([]byte(`{"price": "0.012", "other": 0.20, "unset": ""}`), &v)).To(Succeed())
+		Expect(v.Price.String()).To(Equal("0.012"))
+		Expect(v.Other.String()).To(Equal("0.20"))
+		Expect(v.Unset.IsZero()).To(BeTrue())
+
+		b, err := json.Marshal(v)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(MatchJSON(`{"price": "0.012", "other": "0.20", "unset": "0"}`))
+	})
+
+	It("should write a DecimalNumber to JSON as a number", func() {
+		v := CurrencyRate{Code: "USD", ValidFrom: "2001-01-01", Rate: MustParseDecimalNumber("0.8")}
+		b, err := json.Marshal(v)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(MatchJSON(`{"code": "USD", "valid_from": "2001-01-01", "rate": 0.8}`))
+
+		var read CurrencyRate
+		Expect(json.Unmarshal(b, &read)).To(Succeed())
+		Expect(read).To(Equal(v))
+	})
+
+	It("should scan numeric columns", func() {
+		var d Decimal
+		Expect(d.Scan([]byte("45.23"))).To(Succeed())
+		Expect(d.String()).To(Equal("45.23"))
+		Expect(d.Scan(int64(3))).To(Succeed())
+		Expect(d.String()).To(Equal("3"))
+		Expect(d.Scan(nil)).To(Succeed())
+		Expect(d.IsZero()).To(BeTrue())
+	})
+})
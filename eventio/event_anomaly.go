@@ -0,0 +1,94 @@
+package eventio
+
+import (
+	"fmt"
+	"time"
+)
+
+// AnomalyLevel is what the cost of an anomaly is the cost of
+type AnomalyLevel string
+
+const (
+	AnomalyOrg      AnomalyLevel = "org"
+	AnomalySpace    AnomalyLevel = "space"
+	AnomalyResource AnomalyLevel = "resource"
+)
+
+type AnomalyReader interface {
+	GetAnomalies(filter EventFilter) ([]Anomaly, error)
+}
+
+// AnomalyDetector finds the orgs, spaces and resources whose cost was
+// unusually high on each whole UTC day since the last day that was checked,
+// up to the last refresh, records them, and returns the anomalies that had
+// not been found before. GetUnnotifiedAnomalies returns every anomaly that
+// has not been sent yet, and SetAnomalyNotified is called once an anomaly
+// has been delivered so that it is not returned again.
+type AnomalyDetector interface {
+	DetectAnomalies(cfg AnomalyConfig) ([]Anomaly, error)
+	GetUnnotifiedAnomalies() ([]Anomaly, error)
+	SetAnomalyNotified(id int64) error
+}
+
+// AnomalyConfig is how unusual a day's cost must be to be an anomaly. The
+// cost of an org, space or resource on a day is compared against its
+// baseline, which is its mean daily cost over the days it had a cost in the
+// BaselineDays days before.
+type AnomalyConfig struct {
+	BaselineDays int
+	// MinHistoryDays is how many of the BaselineDays days an org, space or
+	// resource must have had a cost on for its baseline to be trusted, so
+	// that new ones are not reported
+	MinHistoryDays int
+	// Factor is how many times its baseline a cost must be at least
+	Factor Decimal
+	// MinIncrease is how much a cost must be above its baseline at least,
+	// ex VAT in BaseCurrency, so that rises in small costs are not reported
+	MinIncrease Decimal
+}
+
+// DefaultAnomalyConfig reports a cost that is at least three times the mean
+// of the two weeks before it, and at least 10 more, once there are at least
+// seven days to compare it with
+var DefaultAnomalyConfig = AnomalyConfig{
+	BaselineDays:   14,
+	MinHistoryDays: 7,
+	Factor:         MustParseDecimal("3"),
+	MinIncrease:    MustParseDecimal("10"),
+}
+
+func (cfg AnomalyConfig) Validate() error {
+	if cfg.BaselineDays < 1 || cfg.BaselineDays > 366 {
+		return fmt.Errorf("the anomaly baseline must be from 1 to 366 days - got %d", cfg.BaselineDays)
+	}
+	if cfg.MinHistoryDays < 1 || cfg.MinHistoryDays > cfg.BaselineDays {
+		return fmt.Errorf("the anomaly minimum history must be from 1 to %d days - got %d", cfg.BaselineDays, cfg.MinHistoryDays)
+	}
+	if cfg.Factor.Cmp(NewDecimalFromInt(1)) <= 0 {
+		return fmt.Errorf("the anomaly factor must be greater than 1 - got %s", cfg.Factor)
+	}
+	if cfg.MinIncrease.Sign() < 0 {
+		return fmt.Errorf("the anomaly minimum increase must not be negative - got %s", cfg.MinIncrease)
+	}
+	return nil
+}
+
+// Anomaly is a day on which the cost of an org, space or resource, ex VAT,
+// was unusually high compared to its Baseline. The space and resource are
+// only set for the levels they apply to.
+type Anomaly struct {
+	ID           int64        `json:"id"`
+	Day          string       `json:"day"`
+	Level        AnomalyLevel `json:"level"`
+	OrgGUID      string       `json:"org_guid"`
+	OrgName      string       `json:"org_name"`
+	SpaceGUID    string       `json:"space_guid,omitempty"`
+	SpaceName    string       `json:"space_name,omitempty"`
+	ResourceGUID string       `json:"resource_guid,omitempty"`
+	ResourceName string       `json:"resource_name,omitempty"`
+	ResourceType string       `json:"resource_type,omitempty"`
+	Cost         Decimal      `json:"cost"`
+	Baseline     Decimal      `json:"baseline"`
+	CurrencyCode string       `json:"currency_code"`
+	DetectedAt   time.Time    `json:"detected_at"`
+}
@@ -0,0 +1,31 @@
+package eventio_test
+
+import (
+	. "github.com/alphagov/paas-billing/eventio"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AnomalyConfig", func() {
+	It("should accept the default config", func() {
+		Expect(DefaultAnomalyConfig.Validate()).To(Succeed())
+	})
+
+	It("should reject a config that would report every cost", func() {
+		cfg := DefaultAnomalyConfig
+		cfg.BaselineDays = 0
+		Expect(cfg.Validate()).To(MatchError("the anomaly baseline must be from 1 to 366 days - got 0"))
+
+		cfg = DefaultAnomalyConfig
+		cfg.MinHistoryDays = 15
+		Expect(cfg.Validate()).To(MatchError("the anomaly minimum history must be from 1 to 14 days - got 15"))
+
+		cfg = DefaultAnomalyConfig
+		cfg.Factor = MustParseDecimal("1")
+		Expect(cfg.Validate()).To(MatchError("the anomaly factor must be greater than 1 - got 1"))
+
+		cfg = DefaultAnomalyConfig
+		cfg.MinIncrease = MustParseDecimal("-1")
+		Expect(cfg.Validate()).To(MatchError("the anomaly minimum increase must not be negative - got -1"))
+	})
+})
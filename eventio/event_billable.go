@@ -15,6 +15,7 @@ type ConsolidatedBillableEventReader interface {
 	GetConsolidatedBillableEventRows(ctx context.Context, filter EventFilter) (BillableEventRows, error)
 	GetConsolidatedBillableEvents(filter EventFilter) ([]BillableEvent, error)
 	IsRangeConsolidated(filter EventFilter) (bool, error)
+	BillingPeriodReader
 }
 
 type BillableEventConsolidator interface {
@@ -26,6 +27,8 @@ type BillableEventConsolidator interface {
 type BillableEventForecaster interface {
 	ForecastBillableEventRows(ctx context.Context, events []UsageEvent, filter EventFilter) (BillableEventRows, error)
 	ForecastBillableEvents(events []UsageEvent, filter EventFilter) ([]BillableEvent, error)
+	ForecastScenarioBillableEventRows(ctx context.Context, scenario Scenario, filter EventFilter) (BillableEventRows, error)
+	ForecastScenarioBillableEvents(scenario Scenario, filter EventFilter) ([]BillableEvent, error)
 }
 
 type BillableEventRows interface {
@@ -37,20 +40,20 @@ type BillableEventRows interface {
 }
 
 type PriceComponent struct {
-	Name         string `json:"name"`
-	PlanName     string `json:"plan_name"`
-	Start        string `json:"start"`
-	Stop         string `json:"stop"`
-	VatRate      string `json:"vat_rate"`
-	VatCode      string `json:"vat_code"`
-	CurrencyCode string `json:"currency_code"`
-	IncVAT       string `json:"inc_vat"`
-	ExVAT        string `json:"ex_vat"`
+	Name         string  `json:"name"`
+	PlanName     string  `json:"plan_name"`
+	Start        string  `json:"start"`
+	Stop         string  `json:"stop"`
+	VatRate      Decimal `json:"vat_rate"`
+	VatCode      string  `json:"vat_code"`
+	CurrencyCode string  `json:"currency_code"`
+	IncVAT       Decimal `json:"inc_vat"`
+	ExVAT        Decimal `json:"ex_vat"`
 }
 
 type Price struct {
-	IncVAT  string           `json:"inc_vat"`
-	ExVAT   string           `json:"ex_vat"`
+	IncVAT  Decimal          `json:"inc_vat"`
+	ExVAT   Decimal          `json:"ex_vat"`
 	Details []PriceComponent `json:"details"`
 }
 
@@ -0,0 +1,86 @@
+package eventio
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// DefaultBudgetThresholds are the percentages of a budget that an alert is
+// sent at when a budget is set without any
+var DefaultBudgetThresholds = []int64{50, 80, 100}
+
+// ErrBudgetNotFound is returned when an org does not have a budget
+var ErrBudgetNotFound = errors.New("budget not found")
+
+type BudgetReader interface {
+	GetBudgets(orgGUIDs []string) ([]Budget, error)
+}
+
+type BudgetWriter interface {
+	SetBudget(budget Budget) (Budget, error)
+	DeleteBudget(orgGUID string) error
+}
+
+// BudgetAlerter checks the orgs' spending against their budgets.
+// EvaluateBudgets records an alert the first time in a billing period that
+// an org's spend reaches each threshold of its budget, and returns the alerts that
+// have not been sent yet. SetBudgetAlertSent is called once an alert has
+// been delivered so that it is not returned again.
+type BudgetAlerter interface {
+	EvaluateBudgets() ([]BudgetAlert, error)
+	SetBudgetAlertSent(id int64) error
+}
+
+// Budget is how much an org expects to spend ex VAT, in BaseCurrency, each
+// billing period of the org, see BillingPeriod. Thresholds are the percentages of the Amount that an
+// alert is sent at.
+type Budget struct {
+	OrgGUID    string    `json:"org_guid"`
+	Amount     Decimal   `json:"amount"`
+	Thresholds []int64   `json:"thresholds"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Validate checks the budget can be set, Thresholds is set to
+// DefaultBudgetThresholds if it is empty
+func (b *Budget) Validate() error {
+	if _, err := uuid.FromString(b.OrgGUID); err != nil {
+		return fmt.Errorf("org_guid must be a guid - got %s", b.OrgGUID)
+	}
+	if b.Amount.Sign() <= 0 {
+		return fmt.Errorf("amount must be greater than zero - got %s", b.Amount)
+	}
+	if len(b.Thresholds) == 0 {
+		b.Thresholds = append([]int64{}, DefaultBudgetThresholds...)
+	}
+	seen := map[int64]bool{}
+	for _, threshold := range b.Thresholds {
+		if threshold < 1 || threshold > 1000 {
+			return fmt.Errorf("thresholds must be percentages from 1 to 1000 - got %d", threshold)
+		}
+		if seen[threshold] {
+			return fmt.Errorf("thresholds must not repeat - got %d more than once", threshold)
+		}
+		seen[threshold] = true
+	}
+	return nil
+}
+
+// BudgetAlert is raised when an org's spend ex VAT for its billing period
+// starting on PeriodStart, the date in the form 2006-01-02 in the org's time
+// zone, reaches Threshold percent of its budget. Spent is the period to date spend when the alert was raised.
+type BudgetAlert struct {
+	ID           int64      `json:"id"`
+	OrgGUID      string     `json:"org_guid"`
+	OrgName      string     `json:"org_name"`
+	PeriodStart  string     `json:"period_start"`
+	Threshold    int64      `json:"threshold"`
+	Amount       Decimal    `json:"amount"`
+	Spent        Decimal    `json:"spent"`
+	CurrencyCode string     `json:"currency_code"`
+	CreatedAt    time.Time  `json:"created_at"`
+	SentAt       *time.Time `json:"sent_at"`
+}
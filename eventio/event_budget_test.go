@@ -0,0 +1,44 @@
+package eventio_test
+
+import (
+	. "github.com/alphagov/paas-billing/eventio"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Budget", func() {
+	var budget Budget
+
+	BeforeEach(func() {
+		budget = Budget{
+			OrgGUID: "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+			Amount:  MustParseDecimal("1000"),
+		}
+	})
+
+	It("should alert at the default thresholds if none are given", func() {
+		Expect(budget.Validate()).To(Succeed())
+		Expect(budget.Thresholds).To(Equal([]int64{50, 80, 100}))
+
+		budget.Thresholds[0] = 10
+		Expect(DefaultBudgetThresholds).To(Equal([]int64{50, 80, 100}))
+	})
+
+	It("should require a guid for the org", func() {
+		budget.OrgGUID = "my-org"
+		Expect(budget.Validate()).To(MatchError("org_guid must be a guid - got my-org"))
+	})
+
+	It("should require a positive amount", func() {
+		budget.Amount = MustParseDecimal("0")
+		Expect(budget.Validate()).To(MatchError("amount must be greater than zero - got 0"))
+	})
+
+	It("should require thresholds to be percentages that do not repeat", func() {
+		budget.Thresholds = []int64{0}
+		Expect(budget.Validate()).To(MatchError("thresholds must be percentages from 1 to 1000 - got 0"))
+
+		budget.Thresholds = []int64{50, 50}
+		Expect(budget.Validate()).To(MatchError("thresholds must not repeat - got 50 more than once"))
+	})
+})
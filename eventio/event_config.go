@@ -1,5 +1,7 @@
 package eventio
 
+import "time"
+
 type PricingPlan struct {
 	Name          string                 `json:"name"`
 	PlanGUID      string                 `json:"plan_guid"`
@@ -8,6 +10,7 @@ type PricingPlan struct {
 	MemoryInMB    uint                   `json:"memory_in_mb"`
 	StorageInMB   uint                   `json:"storage_in_mb"`
 	NumberOfNodes uint                   `json:"number_of_nodes"`
+	DiskInMB      uint                   `json:"disk_in_mb"`
 }
 
 type PricingPlanComponent struct {
@@ -18,13 +21,69 @@ type PricingPlanComponent struct {
 }
 
 type VATRate struct {
-	Code      string  `json:"code"`
-	ValidFrom string  `json:"valid_from"`
-	Rate      float64 `json:"rate"`
+	Code      string        `json:"code"`
+	ValidFrom string        `json:"valid_from"`
+	Rate      DecimalNumber `json:"rate"`
 }
 
 type CurrencyRate struct {
-	Code      string  `json:"code"`
-	ValidFrom string  `json:"valid_from"`
-	Rate      float64 `json:"rate"`
+	Code      string        `json:"code"`
+	ValidFrom string        `json:"valid_from"`
+	Rate      DecimalNumber `json:"rate"`
+}
+
+// OrgTaxTreatment is how an org is taxed from ValidFrom onwards. VATCode
+// replaces the VAT code of every pricing component used by the org, for
+// example to zero rate an org that is exempt from VAT. An empty VATCode
+// means the org is taxed using the VAT code of each component.
+type OrgTaxTreatment struct {
+	OrgGUID   string `json:"org_guid"`
+	ValidFrom string `json:"valid_from"`
+	VATCode   string `json:"vat_code"`
+}
+
+// PricingPlanVersion is a pricing plan as it was recorded at a point in time,
+// along with who recorded it. The latest version of a plan for each
+// valid_from is the one that is used.
+type PricingPlanVersion struct {
+	PricingPlan
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+}
+
+// VATRateVersion is a VAT rate as it was recorded at a point in time
+type VATRateVersion struct {
+	VATRate
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+}
+
+// CurrencyRateVersion is a currency rate as it was recorded at a point in time
+type CurrencyRateVersion struct {
+	CurrencyRate
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+}
+
+// OrgTaxTreatmentVersion is an org tax treatment as it was recorded at a
+// point in time
+type OrgTaxTreatmentVersion struct {
+	OrgTaxTreatment
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+}
+
+// VersionRejectedError is returned when a new pricing plan, VAT rate,
+// currency rate or org tax treatment version is not valid, either on its own
+// or because of the effect it would have on the other pricing data
+type VersionRejectedError struct {
+	Reason string
+}
+
+func (e *VersionRejectedError) Error() string {
+	return e.Reason
 }
@@ -5,6 +5,6 @@ type TotalCostReader interface {
 }
 
 type TotalCost struct {
-	PlanGUID string  `json:"plan_guid"`
-	Cost     float32 `json:"cost"`
+	PlanGUID string        `json:"plan_guid"`
+	Cost     DecimalNumber `json:"cost"`
 }
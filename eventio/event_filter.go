@@ -3,22 +3,66 @@ package eventio
 import (
 	"fmt"
 	"time"
+
+	uuid "github.com/satori/go.uuid"
 )
 
+// FilterError is returned when a filter is valid on its own but can not be
+// applied to the stored data, for example asking for prices in a currency
+// that there is no rate for
+type FilterError struct {
+	Reason string
+}
+
+func (e *FilterError) Error() string {
+	return e.Reason
+}
+
 type EventFilter struct {
 	RangeStart string
 	RangeStop  string
 	OrgGUIDs   []string
+	// SpaceGUIDs, ResourceTypes, PlanGUIDs and ResourceGUIDs each limit the
+	// events to those with one of the given values, if any are given
+	SpaceGUIDs    []string
+	ResourceTypes []string
+	PlanGUIDs     []string
+	ResourceGUIDs []string
+	// Currency is the ISO 4217 code of the currency to give prices in,
+	// converted using the currency rates. Prices are in BaseCurrency if it
+	// is empty.
+	Currency string
+	// After and Limit select a page of events. Events are ordered by their
+	// EventKey when either is set, After skips the events up to and
+	// including the given one, and Limit is the most events to return.
+	After *EventKey
+	Limit int
 }
 
-func (filter *EventFilter) SplitByMonth() ([]EventFilter, error) {
-	dateFormat := "2006-01-02"
+// EventKey identifies a billable or usage event in a page of events
+type EventKey struct {
+	EventGUID string `json:"event_guid"`
+	PlanGUID  string `json:"plan_guid"`
+}
+
+func (key EventKey) Validate() error {
+	if err := validateGUIDs("event_guid", []string{key.EventGUID}); err != nil {
+		return err
+	}
+	return validateGUIDs("plan_guid", []string{key.PlanGUID})
+}
+
+// Paginated reports whether the filter selects a page of events
+func (filter *EventFilter) Paginated() bool {
+	return filter.After != nil || filter.Limit > 0
+}
 
-	start, err := time.Parse(dateFormat, filter.RangeStart)
+func (filter *EventFilter) SplitByMonth() ([]EventFilter, error) {
+	start, err := ParseRangeTime(filter.RangeStart)
 	if err != nil {
 		return nil, err
 	}
-	end, err := time.Parse(dateFormat, filter.RangeStop)
+	end, err := ParseRangeTime(filter.RangeStop)
 	if err != nil {
 		return nil, err
 	}
@@ -27,29 +71,52 @@ func (filter *EventFilter) SplitByMonth() ([]EventFilter, error) {
 }
 
 func (filter *EventFilter) recursiveSplitByMonth(t1, t2 time.Time) []EventFilter {
-	dateFormat := "2006-01-02"
-
 	if !t1.Before(t2) {
 		return []EventFilter{}
 	} else {
-		next := truncateMonth(t1.AddDate(0, 1, 0))
+		next := truncateMonth(t1).AddDate(0, 1, 0)
+		month := *filter
+		month.RangeStart = FormatRangeTime(t1)
+		month.RangeStop = FormatRangeTime(minDate(t2, next))
 		return append(
-			[]EventFilter{
-				{
-					RangeStart: t1.Format(dateFormat),
-					RangeStop:  minDate(t2, next).Format(dateFormat),
-					OrgGUIDs:   filter.OrgGUIDs,
-				},
-			},
+			[]EventFilter{month},
 			filter.recursiveSplitByMonth(next, t2)...,
 		)
 	}
 }
 
+// SplitByDay returns a filter for each day in the range. The first and last
+// days are partial days if the range does not start or end at midnight UTC.
+func (filter *EventFilter) SplitByDay() ([]EventFilter, error) {
+	start, err := ParseRangeTime(filter.RangeStart)
+	if err != nil {
+		return nil, err
+	}
+	end, err := ParseRangeTime(filter.RangeStop)
+	if err != nil {
+		return nil, err
+	}
+
+	days := []EventFilter{}
+	for day := start; day.Before(end); {
+		next := truncateDay(day).AddDate(0, 0, 1)
+		dayFilter := *filter
+		dayFilter.RangeStart = FormatRangeTime(day)
+		dayFilter.RangeStop = FormatRangeTime(minDate(end, next))
+		days = append(days, dayFilter)
+		day = next
+	}
+	return days, nil
+}
+
 func truncateMonth(t time.Time) time.Time {
 	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
 }
 
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
 func minDate(t1 time.Time, t2 time.Time) time.Time {
 	if t1.Before(t2) {
 		return t1
@@ -59,20 +126,19 @@ func minDate(t1 time.Time, t2 time.Time) time.Time {
 }
 
 func (filter *EventFilter) TruncateMonth() (EventFilter, error) {
-	start, err := time.Parse("2006-01-02", filter.RangeStart)
+	start, err := ParseRangeTime(filter.RangeStart)
 	if err != nil {
 		return *filter, err
 	}
-	stop, err := time.Parse("2006-01-02", filter.RangeStop)
+	stop, err := ParseRangeTime(filter.RangeStop)
 	if err != nil {
 		return *filter, err
 	}
 
-	return EventFilter{
-		RangeStart: truncateMonth(start).Format("2006-01-02"),
-		RangeStop:  truncateMonth(stop).Format("2006-01-02"),
-		OrgGUIDs:   filter.OrgGUIDs,
-	}, nil
+	truncated := *filter
+	truncated.RangeStart = FormatRangeTime(truncateMonth(start))
+	truncated.RangeStop = FormatRangeTime(truncateMonth(stop))
+	return truncated, nil
 }
 
 func (filter *EventFilter) Validate() error {
@@ -82,6 +148,37 @@ func (filter *EventFilter) Validate() error {
 	if err := validateDateString("end", filter.RangeStop); err != nil {
 		return err
 	}
+	if filter.Currency != "" {
+		if err := ValidateCurrencyCode(filter.Currency); err != nil {
+			return err
+		}
+	}
+	if err := validateGUIDs("space_guid", filter.SpaceGUIDs); err != nil {
+		return err
+	}
+	if err := validateGUIDs("plan_guid", filter.PlanGUIDs); err != nil {
+		return err
+	}
+	if err := validateGUIDs("resource_guid", filter.ResourceGUIDs); err != nil {
+		return err
+	}
+	if filter.After != nil {
+		if err := filter.After.Validate(); err != nil {
+			return err
+		}
+	}
+	if filter.Limit < 0 {
+		return fmt.Errorf("limit must not be negative - got %d", filter.Limit)
+	}
+	return nil
+}
+
+func validateGUIDs(name string, values []string) error {
+	for _, value := range values {
+		if _, err := uuid.FromString(value); err != nil {
+			return fmt.Errorf("a valid %s filter value is required - expected a guid - got %s", name, value)
+		}
+	}
 	return nil
 }
 
@@ -101,11 +198,35 @@ func (filter *TimeRangeFilter) Validate() error {
 }
 
 func validateDateString(name string, value string) error {
-	if _, err := time.Parse("2006-01-02", value); err != nil {
+	if _, err := ParseRangeTime(value); err != nil {
 		return fmt.Errorf(
-			`a valid range %s filter value is required - expected format 2006-01-02 - got %s`,
+			`a valid range %s filter value is required - expected format 2006-01-02 or RFC3339 - got %s`,
 			name, value,
 		)
 	}
 	return nil
 }
+
+// ParseRangeTime parses the start or end of a range, which is either a date
+// such as 2006-01-02, meaning midnight UTC, or an RFC3339 timestamp such as
+// 2006-01-02T15:04:05+01:00. The time is returned in UTC.
+func ParseRangeTime(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, err
+	}
+	return t.UTC(), nil
+}
+
+// FormatRangeTime formats the start or end of a range as a date if it is
+// midnight UTC, or otherwise as an RFC3339 timestamp in UTC
+func FormatRangeTime(t time.Time) string {
+	t = t.UTC()
+	if t.Equal(truncateDay(t)) {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339Nano)
+}
@@ -93,6 +93,46 @@ var _ = Describe("EventFilter", func() {
 				},
 			},
 		),
+		table.Entry(
+			"Should maintain the other filters",
+			EventFilter{
+				RangeStart:    "2017-01-15",
+				RangeStop:     "2017-02-15",
+				SpaceGUIDs:    []string{"space-guid"},
+				ResourceTypes: []string{"app"},
+				PlanGUIDs:     []string{"plan-guid"},
+				ResourceGUIDs: []string{"resource-guid"},
+			},
+			[]EventFilter{
+				{RangeStart: "2017-01-15", RangeStop: "2017-02-01", SpaceGUIDs: []string{"space-guid"}, ResourceTypes: []string{"app"}, PlanGUIDs: []string{"plan-guid"}, ResourceGUIDs: []string{"resource-guid"}},
+				{RangeStart: "2017-02-01", RangeStop: "2017-02-15", SpaceGUIDs: []string{"space-guid"}, ResourceTypes: []string{"app"}, PlanGUIDs: []string{"plan-guid"}, ResourceGUIDs: []string{"resource-guid"}},
+			},
+		),
+		table.Entry(
+			"Should maintain the currency",
+			EventFilter{RangeStart: "2017-01-15", RangeStop: "2017-02-15", Currency: "USD"},
+			[]EventFilter{
+				{RangeStart: "2017-01-15", RangeStop: "2017-02-01", Currency: "USD"},
+				{RangeStart: "2017-02-01", RangeStop: "2017-02-15", Currency: "USD"},
+			},
+		),
+		table.Entry(
+			"Range starting at the end of a long month should not skip the next month",
+			EventFilter{RangeStart: "2018-01-31", RangeStop: "2018-03-02"},
+			[]EventFilter{
+				{RangeStart: "2018-01-31", RangeStop: "2018-02-01"},
+				{RangeStart: "2018-02-01", RangeStop: "2018-03-01"},
+				{RangeStart: "2018-03-01", RangeStop: "2018-03-02"},
+			},
+		),
+		table.Entry(
+			"Timestamps should split at the start of the month in UTC",
+			EventFilter{RangeStart: "2018-01-31T18:30:00Z", RangeStop: "2018-02-01T06:00:00+01:00"},
+			[]EventFilter{
+				{RangeStart: "2018-01-31T18:30:00Z", RangeStop: "2018-02-01"},
+				{RangeStart: "2018-02-01", RangeStop: "2018-02-01T05:00:00Z"},
+			},
+		),
 		table.Entry(
 			"Multi-year range should return all months",
 			EventFilter{RangeStart: "2016-11-12", RangeStop: "2018-01-05"},
@@ -116,6 +156,51 @@ var _ = Describe("EventFilter", func() {
 		),
 	)
 
+	table.DescribeTable(
+		"SplitByDay should return a filter for each day",
+		func(filter EventFilter, expected []EventFilter) {
+			result, err := filter.SplitByDay()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(expected))
+		},
+		table.Entry(
+			"Empty range should return empty slice",
+			EventFilter{RangeStart: "2018-01-01", RangeStop: "2018-01-01"},
+			[]EventFilter{},
+		),
+		table.Entry(
+			"Range across the end of a month should return each day",
+			EventFilter{RangeStart: "2018-02-27", RangeStop: "2018-03-02", OrgGUIDs: []string{"org-guid"}, Currency: "USD"},
+			[]EventFilter{
+				{RangeStart: "2018-02-27", RangeStop: "2018-02-28", OrgGUIDs: []string{"org-guid"}, Currency: "USD"},
+				{RangeStart: "2018-02-28", RangeStop: "2018-03-01", OrgGUIDs: []string{"org-guid"}, Currency: "USD"},
+				{RangeStart: "2018-03-01", RangeStop: "2018-03-02", OrgGUIDs: []string{"org-guid"}, Currency: "USD"},
+			},
+		),
+	)
+
+	table.DescribeTable(
+		"SplitByDay should return partial days for timestamps",
+		func(filter EventFilter, expected []EventFilter) {
+			result, err := filter.SplitByDay()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(expected))
+		},
+		table.Entry(
+			"Within a day",
+			EventFilter{RangeStart: "2018-01-01T06:00:00Z", RangeStop: "2018-01-01T12:00:00Z"},
+			[]EventFilter{{RangeStart: "2018-01-01T06:00:00Z", RangeStop: "2018-01-01T12:00:00Z"}},
+		),
+		table.Entry(
+			"Across midnight",
+			EventFilter{RangeStart: "2018-01-01T18:00:00Z", RangeStop: "2018-01-02T06:00:00Z"},
+			[]EventFilter{
+				{RangeStart: "2018-01-01T18:00:00Z", RangeStop: "2018-01-02"},
+				{RangeStart: "2018-01-02", RangeStop: "2018-01-02T06:00:00Z"},
+			},
+		),
+	)
+
 	table.DescribeTable(
 		"TruncateMonth truncate to one month both start and end",
 		func(filter EventFilter, expected EventFilter) {
@@ -149,5 +234,66 @@ var _ = Describe("EventFilter", func() {
 			EventFilter{RangeStart: "2018-01-15", RangeStop: "2018-02-15", OrgGUIDs: []string{"org-guid"}},
 			EventFilter{RangeStart: "2018-01-01", RangeStop: "2018-02-01", OrgGUIDs: []string{"org-guid"}},
 		),
+		table.Entry(
+			"Timestamps",
+			EventFilter{RangeStart: "2018-01-15T12:00:00Z", RangeStop: "2018-03-01T00:30:00+01:00"},
+			EventFilter{RangeStart: "2018-01-01", RangeStop: "2018-02-01"},
+		),
+		table.Entry(
+			"Perserves the other filters",
+			EventFilter{RangeStart: "2018-01-15", RangeStop: "2018-02-15", SpaceGUIDs: []string{"space-guid"}, ResourceTypes: []string{"app"}},
+			EventFilter{RangeStart: "2018-01-01", RangeStop: "2018-02-01", SpaceGUIDs: []string{"space-guid"}, ResourceTypes: []string{"app"}},
+		),
+	)
+
+	table.DescribeTable(
+		"Validate should check the currency",
+		func(currency string, expectedErr string) {
+			filter := EventFilter{RangeStart: "2018-01-01", RangeStop: "2018-02-01", Currency: currency}
+			err := filter.Validate()
+			if expectedErr == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(expectedErr))
+			}
+		},
+		table.Entry("no currency", "", ""),
+		table.Entry("pounds", "GBP", ""),
+		table.Entry("yen", "JPY", ""),
+		table.Entry("lowercase", "usd", "unknown currency code 'usd', must be an ISO 4217 code such as GBP or USD"),
+		table.Entry("not a currency", "UKP", "unknown currency code 'UKP', must be an ISO 4217 code such as GBP or USD"),
+		table.Entry("no currency involved", "XXX", "unknown currency code 'XXX', must be an ISO 4217 code such as GBP or USD"),
+	)
+
+	It("Validate should check the guids that events are filtered by", func() {
+		filter := EventFilter{
+			RangeStart:    "2018-01-01",
+			RangeStop:     "2018-02-01",
+			SpaceGUIDs:    []string{"3f8b3c3e-5c3a-4a4c-9d0e-0c3a1d6e8f11"},
+			ResourceTypes: []string{"app"},
+			PlanGUIDs:     []string{"f4d4b95a-f55e-4593-8d54-3364c25798c4"},
+		}
+		Expect(filter.Validate()).To(Succeed())
+
+		filter.ResourceGUIDs = []string{"not-a-guid"}
+		Expect(filter.Validate()).To(MatchError("a valid resource_guid filter value is required - expected a guid - got not-a-guid"))
+	})
+
+	table.DescribeTable(
+		"Validate should accept dates and RFC3339 timestamps",
+		func(value string, valid bool) {
+			filter := EventFilter{RangeStart: value, RangeStop: "2018-02-01"}
+			if valid {
+				Expect(filter.Validate()).To(Succeed())
+			} else {
+				Expect(filter.Validate()).To(MatchError("a valid range start filter value is required - expected format 2006-01-02 or RFC3339 - got " + value))
+			}
+		},
+		table.Entry("date", "2018-01-01", true),
+		table.Entry("UTC timestamp", "2018-01-01T06:00:00Z", true),
+		table.Entry("timestamp with an offset", "2018-01-01T06:00:00-05:00", true),
+		table.Entry("fractional seconds", "2018-01-01T06:00:00.5Z", true),
+		table.Entry("timestamp without a zone", "2018-01-01T06:00:00", false),
+		table.Entry("month", "2018-01", false),
 	)
 })
@@ -0,0 +1,85 @@
+package eventio
+
+import (
+	"errors"
+	"time"
+)
+
+// InvoiceStatus is where an invoice is in its lifecycle. Invoices are
+// generated as drafts, can then be issued, and once issued can be credited.
+type InvoiceStatus string
+
+const (
+	InvoiceDraft    InvoiceStatus = "draft"
+	InvoiceIssued   InvoiceStatus = "issued"
+	InvoiceCredited InvoiceStatus = "credited"
+)
+
+// ErrInvoiceNotFound is returned when there is no invoice with the requested
+// number
+var ErrInvoiceNotFound = errors.New("invoice not found")
+
+type InvoiceReader interface {
+	GetInvoices(filter EventFilter) ([]Invoice, error)
+	GetInvoice(number int64) (Invoice, error)
+}
+
+// InvoiceWriter moves invoices through their lifecycle. by is who made the
+// change.
+type InvoiceWriter interface {
+	IssueInvoice(number int64, by string) (Invoice, error)
+	CreditInvoice(number int64, by string) (Invoice, error)
+}
+
+// InvoiceLine is the cost of a plan used in a space during the invoiced
+// month, for one VAT code. ExVAT is rounded to MoneyPlaces.
+type InvoiceLine struct {
+	SpaceGUID string  `json:"space_guid"`
+	SpaceName string  `json:"space_name"`
+	PlanGUID  string  `json:"plan_guid"`
+	PlanName  string  `json:"plan_name"`
+	VATCode   string  `json:"vat_code"`
+	VATRate   Decimal `json:"vat_rate"`
+	ExVAT     Decimal `json:"ex_vat"`
+}
+
+// InvoiceVATSummary is the VAT due for the lines with one VAT code. VAT is
+// calculated on the total of the lines and rounded to MoneyPlaces.
+type InvoiceVATSummary struct {
+	VATCode string  `json:"vat_code"`
+	VATRate Decimal `json:"vat_rate"`
+	ExVAT   Decimal `json:"ex_vat"`
+	VAT     Decimal `json:"vat"`
+}
+
+// Invoice is what an org owes for a consolidated month. Apart from its
+// status an invoice never changes once it has been generated.
+type Invoice struct {
+	Number       int64               `json:"number"`
+	OrgGUID      string              `json:"org_guid"`
+	OrgName      string              `json:"org_name"`
+	PeriodStart  string              `json:"period_start"`
+	PeriodStop   string              `json:"period_stop"`
+	CurrencyCode string              `json:"currency_code"`
+	Status       InvoiceStatus       `json:"status"`
+	Lines        []InvoiceLine       `json:"lines"`
+	VATSummary   []InvoiceVATSummary `json:"vat_summary"`
+	TotalExVAT   Decimal             `json:"total_ex_vat"`
+	TotalVAT     Decimal             `json:"total_vat"`
+	TotalIncVAT  Decimal             `json:"total_inc_vat"`
+	CreatedAt    time.Time           `json:"created_at"`
+	IssuedAt     *time.Time          `json:"issued_at"`
+	IssuedBy     string              `json:"issued_by,omitempty"`
+	CreditedAt   *time.Time          `json:"credited_at"`
+	CreditedBy   string              `json:"credited_by,omitempty"`
+}
+
+// InvoiceStatusError is returned when an invoice can not be moved to the
+// requested status, for example crediting a draft
+type InvoiceStatusError struct {
+	Reason string
+}
+
+func (e *InvoiceStatusError) Error() string {
+	return e.Reason
+}
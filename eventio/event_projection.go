@@ -0,0 +1,48 @@
+package eventio
+
+type ProjectionReader interface {
+	GetMonthEndProjections(filter ProjectionFilter) ([]Projection, error)
+}
+
+// ProjectionFilter selects the orgs to project the period end totals of. All
+// orgs are projected if there are no OrgGUIDs.
+type ProjectionFilter struct {
+	OrgGUIDs []string
+	// Currency is the ISO 4217 code of the currency to give totals in,
+	// they are in BaseCurrency if it is empty
+	Currency string
+}
+
+func (filter *ProjectionFilter) Validate() error {
+	if err := validateGUIDs("org_guid", filter.OrgGUIDs); err != nil {
+		return err
+	}
+	if filter.Currency != "" {
+		if err := ValidateCurrencyCode(filter.Currency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Projection is what a space is expected to be billed for the billing
+// period of its org that contains AsOf, the last time that the events were
+// refreshed, see BillingPeriod. The month to date totals are the prices of
+// the space's events from PeriodStart up to AsOf. The projected totals add
+// the price of the resources that were running at AsOf, as if they keep
+// running until PeriodStop. PeriodStart and PeriodStop are dates in the
+// org's time zone, PeriodStop is the day after the period ends.
+type Projection struct {
+	OrgGUID           string  `json:"org_guid"`
+	OrgName           string  `json:"org_name"`
+	SpaceGUID         string  `json:"space_guid"`
+	SpaceName         string  `json:"space_name"`
+	PeriodStart       string  `json:"period_start"`
+	PeriodStop        string  `json:"period_stop"`
+	AsOf              string  `json:"as_of"`
+	CurrencyCode      string  `json:"currency_code"`
+	MonthToDateExVAT  Decimal `json:"month_to_date_ex_vat"`
+	MonthToDateIncVAT Decimal `json:"month_to_date_inc_vat"`
+	ProjectedExVAT    Decimal `json:"projected_ex_vat"`
+	ProjectedIncVAT   Decimal `json:"projected_inc_vat"`
+}
@@ -20,18 +20,53 @@ type VATRateReader interface {
 	GetVATRates(filter TimeRangeFilter) ([]VATRate, error)
 }
 
+// PricingVersionWriter records new versions of the pricing data. createdBy
+// is who made the change.
+type PricingVersionWriter interface {
+	AddPricingPlanVersion(plan PricingPlan, createdBy string) (PricingPlanVersion, error)
+	AddVATRateVersion(rate VATRate, createdBy string) (VATRateVersion, error)
+	AddCurrencyRateVersion(rate CurrencyRate, createdBy string) (CurrencyRateVersion, error)
+	AddOrgTaxTreatmentVersion(treatment OrgTaxTreatment, createdBy string) (OrgTaxTreatmentVersion, error)
+}
+
+// PricingVersionReader lists every recorded version of the pricing data,
+// oldest first. An empty planGUID, code or orgGUID returns the versions of
+// them all.
+type PricingVersionReader interface {
+	GetPricingPlanVersions(planGUID string) ([]PricingPlanVersion, error)
+	GetVATRateVersions(code string) ([]VATRateVersion, error)
+	GetCurrencyRateVersions(code string) ([]CurrencyRateVersion, error)
+	GetOrgTaxTreatmentVersions(orgGUID string) ([]OrgTaxTreatmentVersion, error)
+}
+
 type EventStore interface {
 	Init() error
 	Refresh() error
+	Rebuild() error
 	PricingPlanReader
 	CurrencyRateReader
 	VATRateReader
+	PricingVersionWriter
+	PricingVersionReader
 	RawEventWriter
 	RawEventReader
 	UsageEventReader
 	TotalCostReader
 	BillableEventReader
+	BillableSummaryReader
 	BillableEventForecaster
+	ProjectionReader
 	ConsolidatedBillableEventReader
 	BillableEventConsolidator
+	InvoiceReader
+	InvoiceWriter
+	BudgetReader
+	BudgetWriter
+	BudgetAlerter
+	AnomalyReader
+	AnomalyDetector
+	WebhookReader
+	WebhookWriter
+	WebhookEmitter
+	WebhookDeliverer
 }
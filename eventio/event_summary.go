@@ -0,0 +1,102 @@
+package eventio
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SummaryGroup is something that billable event prices can be totalled by
+type SummaryGroup string
+
+const (
+	GroupByOrg          SummaryGroup = "org"
+	GroupBySpace        SummaryGroup = "space"
+	GroupByPlan         SummaryGroup = "plan"
+	GroupByResourceType SummaryGroup = "resource_type"
+	GroupByResource     SummaryGroup = "resource"
+	GroupByMonth        SummaryGroup = "month"
+	GroupByDay          SummaryGroup = "day"
+)
+
+// SummaryGroups are all the groups, in the order their fields are sorted by
+var SummaryGroups = []SummaryGroup{
+	GroupByOrg,
+	GroupBySpace,
+	GroupByPlan,
+	GroupByResourceType,
+	GroupByResource,
+	GroupByMonth,
+	GroupByDay,
+}
+
+type BillableSummaryReader interface {
+	GetBillableSummary(filter BillableSummaryFilter) ([]BillableSummary, error)
+}
+
+// BillableSummaryFilter selects billable events like an EventFilter and
+// totals their prices for each distinct combination of the GroupBy groups.
+// With no groups there is a single total.
+type BillableSummaryFilter struct {
+	EventFilter
+	GroupBy []SummaryGroup
+}
+
+func (filter *BillableSummaryFilter) Validate() error {
+	if err := filter.EventFilter.Validate(); err != nil {
+		return err
+	}
+	seen := map[SummaryGroup]bool{}
+	for _, group := range filter.GroupBy {
+		if !group.valid() {
+			names := []string{}
+			for _, g := range SummaryGroups {
+				names = append(names, string(g))
+			}
+			return fmt.Errorf("unknown group_by value '%s', must be one of %s", group, strings.Join(names, ", "))
+		}
+		if seen[group] {
+			return fmt.Errorf("group_by value '%s' is given more than once", group)
+		}
+		seen[group] = true
+	}
+	return nil
+}
+
+// Has reports whether the summary is grouped by group
+func (filter *BillableSummaryFilter) Has(group SummaryGroup) bool {
+	for _, g := range filter.GroupBy {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+func (group SummaryGroup) valid() bool {
+	for _, g := range SummaryGroups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// BillableSummary is the total price of the billable events in one group.
+// Only the fields of the groups that were asked for are set. Where a name
+// has changed during the range the latest name is given.
+type BillableSummary struct {
+	OrgGUID      string  `json:"org_guid,omitempty"`
+	OrgName      string  `json:"org_name,omitempty"`
+	SpaceGUID    string  `json:"space_guid,omitempty"`
+	SpaceName    string  `json:"space_name,omitempty"`
+	PlanGUID     string  `json:"plan_guid,omitempty"`
+	PlanName     string  `json:"plan_name,omitempty"`
+	ResourceType string  `json:"resource_type,omitempty"`
+	ResourceGUID string  `json:"resource_guid,omitempty"`
+	ResourceName string  `json:"resource_name,omitempty"`
+	Month        string  `json:"month,omitempty"`
+	Day          string  `json:"day,omitempty"`
+	CurrencyCode string  `json:"currency_code"`
+	ExVAT        Decimal `json:"ex_vat"`
+	IncVAT       Decimal `json:"inc_vat"`
+}
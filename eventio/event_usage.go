@@ -23,6 +23,7 @@ type UsageEvent struct {
 	NumberOfNodes int64  `json:"number_of_nodes"`
 	MemoryInMB    int64  `json:"memory_in_mb"`
 	StorageInMB   int64  `json:"storage_in_mb"`
+	DiskInMB      int64  `json:"disk_in_mb"`
 }
 
 type UsageEventRows interface {
@@ -0,0 +1,247 @@
+package eventio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// WebhookEventType is the kind of billing lifecycle event that a webhook can
+// be sent for
+type WebhookEventType string
+
+const (
+	// MonthConsolidated is sent when the billable events of a billing period
+	// have been consolidated, the data is a MonthConsolidatedData
+	MonthConsolidated WebhookEventType = "month.consolidated"
+	// RefreshCompleted and RefreshFailed are sent after each refresh of the
+	// billable events by the processor, the data is a RefreshData
+	RefreshCompleted WebhookEventType = "refresh.completed"
+	RefreshFailed    WebhookEventType = "refresh.failed"
+	// PlanDetected is sent when a plan is found in the usage events that does
+	// not have a pricing plan, the data is a PlanDetectedData
+	PlanDetected WebhookEventType = "plan.detected"
+)
+
+// WebhookEventTypes are every type of event that can be subscribed to
+var WebhookEventTypes = []WebhookEventType{
+	MonthConsolidated,
+	RefreshCompleted,
+	RefreshFailed,
+	PlanDetected,
+}
+
+// DefaultWebhookDeliveryLimit is how many deliveries are listed if no limit
+// is given
+const DefaultWebhookDeliveryLimit = 100
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+var ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+
+type WebhookReader interface {
+	GetWebhooks() ([]Webhook, error)
+	GetWebhookDeliveries(filter WebhookDeliveryFilter) ([]WebhookDelivery, error)
+}
+
+// WebhookWriter manages the webhooks. AddWebhook returns the webhook with its
+// Secret, which is not returned by GetWebhooks. RetryWebhookDelivery sends a
+// delivery again as soon as possible, with its attempts reset.
+type WebhookWriter interface {
+	AddWebhook(webhook Webhook, createdBy string) (Webhook, error)
+	DeleteWebhook(id int64) error
+	RetryWebhookDelivery(webhookID int64, deliveryID int64) (WebhookDelivery, error)
+}
+
+// WebhookEmitter records a delivery of an event to each webhook subscribed to
+// its type. An event is only delivered to a webhook once however many times
+// it is emitted, so events that may be emitted again, such as on each failed
+// refresh, should have an ID that is the same each time.
+type WebhookEmitter interface {
+	EmitWebhookEvent(event WebhookEvent) error
+}
+
+// WebhookDeliverer is used to send the deliveries. GetDueWebhookDeliveries
+// claims up to limit deliveries that are due to be attempted, they are not
+// returned again until lease has passed so that they are not sent twice at
+// once. RecordWebhookAttempt is then called with the outcome of each.
+type WebhookDeliverer interface {
+	GetDueWebhookDeliveries(limit int, lease time.Duration) ([]DueWebhookDelivery, error)
+	RecordWebhookAttempt(id int64, attempt WebhookAttempt) error
+}
+
+// Webhook is a URL that the events of the given types are posted to, signed
+// with Secret. It is sent every type of event if Events is empty.
+type Webhook struct {
+	ID        int64              `json:"id"`
+	URL       string             `json:"url"`
+	Secret    string             `json:"secret,omitempty"`
+	Events    []WebhookEventType `json:"events"`
+	CreatedBy string             `json:"created_by"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// Validate checks the webhook can be added, an empty Secret is allowed as
+// one is generated when the webhook is added
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https url - got %s", w.URL)
+	}
+	if w.Secret != "" && len(w.Secret) < 16 {
+		return errors.New("secret must be at least 16 characters")
+	}
+	if w.Events == nil {
+		w.Events = []WebhookEventType{}
+	}
+	seen := map[WebhookEventType]bool{}
+	for _, eventType := range w.Events {
+		if !isWebhookEventType(eventType) {
+			return fmt.Errorf("events must be %s - got %s", joinWebhookEventTypes(), eventType)
+		}
+		if seen[eventType] {
+			return fmt.Errorf("events must not repeat - got %s more than once", eventType)
+		}
+		seen[eventType] = true
+	}
+	return nil
+}
+
+func isWebhookEventType(eventType WebhookEventType) bool {
+	for _, t := range WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func joinWebhookEventTypes() string {
+	s := ""
+	for i, t := range WebhookEventTypes {
+		switch {
+		case i == 0:
+		case i == len(WebhookEventTypes)-1:
+			s += " or "
+		default:
+			s += ", "
+		}
+		s += string(t)
+	}
+	return s
+}
+
+// WebhookEvent is the body posted to a webhook. ID identifies the event to
+// the receiver, which may be sent it more than once.
+type WebhookEvent struct {
+	ID        string           `json:"id"`
+	Type      WebhookEventType `json:"type"`
+	CreatedAt time.Time        `json:"created_at"`
+	Data      json.RawMessage  `json:"data"`
+}
+
+// NewWebhookEvent creates an event of the type with data as its JSON data
+func NewWebhookEvent(eventType WebhookEventType, id string, data interface{}) (WebhookEvent, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return WebhookEvent{}, err
+	}
+	return WebhookEvent{
+		ID:        id,
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      b,
+	}, nil
+}
+
+// MonthConsolidatedData is the data of a MonthConsolidated event
+type MonthConsolidatedData struct {
+	RangeStart string `json:"range_start"`
+	RangeStop  string `json:"range_stop"`
+}
+
+// RefreshData is the data of a RefreshCompleted or RefreshFailed event,
+// Error is why it failed
+type RefreshData struct {
+	FullRebuild bool   `json:"full_rebuild"`
+	Error       string `json:"error,omitempty"`
+}
+
+// PlanDetectedData is the data of a PlanDetected event. Placeholder is true
+// if a pricing plan costing nothing was added for it, otherwise the refresh
+// fails until the plan is priced.
+type PlanDetectedData struct {
+	PlanGUID     string `json:"plan_guid"`
+	PlanName     string `json:"plan_name"`
+	ResourceType string `json:"resource_type"`
+	Placeholder  bool   `json:"placeholder"`
+}
+
+// WebhookDeliveryStatus is where a delivery has got to, it is pending until
+// it is delivered or has run out of attempts
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is the record of sending an event to a webhook. The
+// ResponseStatus and Error are of the last attempt.
+type WebhookDelivery struct {
+	ID             int64                 `json:"id"`
+	WebhookID      int64                 `json:"webhook_id"`
+	EventID        string                `json:"event_id"`
+	EventType      WebhookEventType      `json:"event_type"`
+	Payload        json.RawMessage       `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	ResponseStatus int                   `json:"response_status,omitempty"`
+	Error          string                `json:"error,omitempty"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time            `json:"last_attempt_at"`
+	DeliveredAt    *time.Time            `json:"delivered_at"`
+	CreatedAt      time.Time             `json:"created_at"`
+}
+
+// DueWebhookDelivery is a delivery with where to send it
+type DueWebhookDelivery struct {
+	WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// WebhookAttempt is the outcome of an attempt to send a delivery. If it was
+// not Delivered it is tried again at NextAttemptAt, or marked as failed if
+// that is nil.
+type WebhookAttempt struct {
+	Delivered      bool
+	ResponseStatus int
+	Error          string
+	NextAttemptAt  *time.Time
+}
+
+type WebhookDeliveryFilter struct {
+	WebhookID int64
+	Status    WebhookDeliveryStatus
+	Limit     int
+}
+
+// Validate checks the filter, Limit is set to DefaultWebhookDeliveryLimit if
+// it is not set
+func (f *WebhookDeliveryFilter) Validate() error {
+	switch f.Status {
+	case "", WebhookDeliveryPending, WebhookDeliveryDelivered, WebhookDeliveryFailed:
+	default:
+		return fmt.Errorf("status must be pending, delivered or failed - got %s", f.Status)
+	}
+	if f.Limit == 0 {
+		f.Limit = DefaultWebhookDeliveryLimit
+	}
+	if f.Limit < 0 {
+		return fmt.Errorf("limit must be positive - got %d", f.Limit)
+	}
+	return nil
+}
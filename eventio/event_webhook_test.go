@@ -0,0 +1,87 @@
+package eventio_test
+
+import (
+	"encoding/json"
+
+	. "github.com/alphagov/paas-billing/eventio"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Webhook", func() {
+	var webhook Webhook
+
+	BeforeEach(func() {
+		webhook = Webhook{
+			URL: "https://ledger.example.com/billing",
+		}
+	})
+
+	It("should send every type of event if none are given", func() {
+		Expect(webhook.Validate()).To(Succeed())
+		Expect(webhook.Events).To(Equal([]WebhookEventType{}))
+	})
+
+	It("should accept the known event types", func() {
+		webhook.Events = []WebhookEventType{MonthConsolidated, PlanDetected}
+		Expect(webhook.Validate()).To(Succeed())
+	})
+
+	It("should require an absolute http or https url", func() {
+		for _, u := range []string{"", "ledger.example.com/billing", "ftp://ledger.example.com", "https://"} {
+			webhook.URL = u
+			Expect(webhook.Validate()).To(MatchError("url must be an absolute http or https url - got "+u), u)
+		}
+	})
+
+	It("should not allow a short secret", func() {
+		webhook.Secret = "hunter2"
+		Expect(webhook.Validate()).To(MatchError("secret must be at least 16 characters"))
+	})
+
+	It("should not allow an unknown event type", func() {
+		webhook.Events = []WebhookEventType{"invoice.paid"}
+		Expect(webhook.Validate()).To(MatchError("events must be month.consolidated, refresh.completed, refresh.failed or plan.detected - got invoice.paid"))
+	})
+
+	It("should not allow an event type more than once", func() {
+		webhook.Events = []WebhookEventType{RefreshFailed, RefreshFailed}
+		Expect(webhook.Validate()).To(MatchError("events must not repeat - got refresh.failed more than once"))
+	})
+})
+
+var _ = Describe("NewWebhookEvent", func() {
+	It("should encode the data as JSON", func() {
+		event, err := NewWebhookEvent(MonthConsolidated, "month.consolidated:2018-01-01", MonthConsolidatedData{
+			RangeStart: "2018-01-01",
+			RangeStop:  "2018-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(event.ID).To(Equal("month.consolidated:2018-01-01"))
+		Expect(event.Type).To(Equal(MonthConsolidated))
+		Expect(event.CreatedAt).ToNot(BeZero())
+		Expect(event.Data).To(MatchJSON(`{"range_start": "2018-01-01", "range_stop": "2018-02-01"}`))
+
+		b, err := json.Marshal(event)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(b)).To(ContainSubstring(`"data":{"range_start":"2018-01-01","range_stop":"2018-02-01"}`))
+	})
+})
+
+var _ = Describe("WebhookDeliveryFilter", func() {
+	It("should default the limit", func() {
+		filter := WebhookDeliveryFilter{WebhookID: 1}
+		Expect(filter.Validate()).To(Succeed())
+		Expect(filter.Limit).To(Equal(DefaultWebhookDeliveryLimit))
+	})
+
+	It("should not allow an unknown status", func() {
+		filter := WebhookDeliveryFilter{WebhookID: 1, Status: "lost"}
+		Expect(filter.Validate()).To(MatchError("status must be pending, delivered or failed - got lost"))
+	})
+
+	It("should not allow a negative limit", func() {
+		filter := WebhookDeliveryFilter{WebhookID: 1, Limit: -1}
+		Expect(filter.Validate()).To(MatchError("limit must be positive - got -1"))
+	})
+})
@@ -0,0 +1,108 @@
+package eventio
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Scenario is a set of changes to an org's real usage, used to forecast what
+// the org would be billed if its usage were different. It is applied to the
+// usage events of a range with Apply.
+type Scenario struct {
+	// Changes are made to every event of the resource they name
+	Changes []ResourceChange `json:"changes"`
+	// ExtendTo, if set, is when the events still running at the end of the
+	// range are assumed to stop, such as the end of the month. It is a date
+	// or RFC3339 timestamp like the range.
+	ExtendTo string `json:"extend_to,omitempty"`
+}
+
+// ResourceChange is a change to a resource in a Scenario. Sizes and a plan
+// that are not set are left as they are.
+type ResourceChange struct {
+	ResourceGUID string `json:"resource_guid"`
+	// Remove removes the resource, any other changes are ignored
+	Remove        bool   `json:"remove,omitempty"`
+	PlanGUID      string `json:"plan_guid,omitempty"`
+	NumberOfNodes *int64 `json:"number_of_nodes,omitempty"`
+	MemoryInMB    *int64 `json:"memory_in_mb,omitempty"`
+	StorageInMB   *int64 `json:"storage_in_mb,omitempty"`
+	DiskInMB      *int64 `json:"disk_in_mb,omitempty"`
+}
+
+// Range returns the filter with its range extended to ExtendTo, which is
+// the range that the scenario's events are forecast for
+func (s Scenario) Range(filter EventFilter) EventFilter {
+	if s.ExtendTo != "" {
+		filter.RangeStop = s.ExtendTo
+	}
+	return filter
+}
+
+// Apply returns the usage events with the scenario's changes made. The
+// events must be the usage events of a range that stops at rangeStop, as
+// returned by a UsageEventReader, so that the events still running at the
+// end of the range can be extended.
+func (s Scenario) Apply(events []UsageEvent, rangeStop string) ([]UsageEvent, error) {
+	stop, err := ParseRangeTime(rangeStop)
+	if err != nil {
+		return nil, err
+	}
+	var extendTo time.Time
+	if s.ExtendTo != "" {
+		extendTo, err = ParseRangeTime(s.ExtendTo)
+		if err != nil {
+			return nil, fmt.Errorf("extend_to must be a date or RFC3339 timestamp - got %s", s.ExtendTo)
+		}
+		if !extendTo.After(stop) {
+			return nil, fmt.Errorf("extend_to must be after the end of the range - got %s", s.ExtendTo)
+		}
+	}
+
+	changes := map[string]ResourceChange{}
+	for _, change := range s.Changes {
+		changes[strings.ToLower(change.ResourceGUID)] = change
+	}
+
+	applied := []UsageEvent{}
+	for _, ev := range events {
+		change, ok := changes[strings.ToLower(ev.ResourceGUID)]
+		if ok && change.Remove {
+			continue
+		}
+		if ok {
+			ev = change.apply(ev)
+		}
+		if s.ExtendTo != "" {
+			eventStop, err := time.Parse(time.RFC3339Nano, ev.EventStop)
+			if err != nil {
+				return nil, fmt.Errorf("event %s has an invalid event_stop: %s", ev.EventGUID, ev.EventStop)
+			}
+			if !eventStop.Before(stop) {
+				ev.EventStop = FormatRangeTime(extendTo)
+			}
+		}
+		applied = append(applied, ev)
+	}
+	return applied, nil
+}
+
+func (change ResourceChange) apply(ev UsageEvent) UsageEvent {
+	if change.PlanGUID != "" {
+		ev.PlanGUID = change.PlanGUID
+	}
+	if change.NumberOfNodes != nil {
+		ev.NumberOfNodes = *change.NumberOfNodes
+	}
+	if change.MemoryInMB != nil {
+		ev.MemoryInMB = *change.MemoryInMB
+	}
+	if change.StorageInMB != nil {
+		ev.StorageInMB = *change.StorageInMB
+	}
+	if change.DiskInMB != nil {
+		ev.DiskInMB = *change.DiskInMB
+	}
+	return ev
+}
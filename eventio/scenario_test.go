@@ -0,0 +1,98 @@
+package eventio_test
+
+import (
+	. "github.com/alphagov/paas-billing/eventio"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scenario", func() {
+	var (
+		app1 = UsageEvent{
+			EventGUID:     "00000000-0000-0000-0000-000000000001",
+			ResourceGUID:  "00000000-0000-0000-0001-000000000001",
+			EventStart:    "2001-01-01T00:00:00+00:00",
+			EventStop:     "2001-01-10T00:00:00+00:00",
+			PlanGUID:      "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+			NumberOfNodes: 1,
+			MemoryInMB:    64,
+		}
+		app1Restarted = UsageEvent{
+			EventGUID:     "00000000-0000-0000-0000-000000000002",
+			ResourceGUID:  "00000000-0000-0000-0001-000000000001",
+			EventStart:    "2001-01-10T00:00:00+00:00",
+			EventStop:     "2001-01-15T00:00:00+00:00",
+			PlanGUID:      "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+			NumberOfNodes: 2,
+			MemoryInMB:    64,
+		}
+		db1 = UsageEvent{
+			EventGUID:    "00000000-0000-0000-0000-000000000003",
+			ResourceGUID: "00000000-0000-0000-0002-000000000001",
+			EventStart:   "2001-01-01T00:00:00+00:00",
+			EventStop:    "2001-01-15T00:00:00+00:00",
+			PlanGUID:     "b2a1e9d4-4c3b-4b2a-9e8f-0d1c2b3a4f5e",
+		}
+		events = []UsageEvent{app1, app1Restarted, db1}
+	)
+
+	It("should return the events unchanged for an empty scenario", func() {
+		applied, err := Scenario{}.Apply(events, "2001-01-15")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(applied).To(Equal(events))
+	})
+
+	It("should resize and change the plan of every event of a resource", func() {
+		nodes := int64(4)
+		memory := int64(1024)
+		scenario := Scenario{Changes: []ResourceChange{{
+			ResourceGUID:  app1.ResourceGUID,
+			PlanGUID:      "9a2b3c4d-0000-4000-8000-000000000001",
+			NumberOfNodes: &nodes,
+			MemoryInMB:    &memory,
+		}}}
+
+		applied, err := scenario.Apply(events, "2001-01-15")
+
+		Expect(err).ToNot(HaveOccurred())
+		expectedApp1, expectedApp1Restarted := app1, app1Restarted
+		for _, ev := range []*UsageEvent{&expectedApp1, &expectedApp1Restarted} {
+			ev.PlanGUID = "9a2b3c4d-0000-4000-8000-000000000001"
+			ev.NumberOfNodes = 4
+			ev.MemoryInMB = 1024
+		}
+		Expect(applied).To(Equal([]UsageEvent{expectedApp1, expectedApp1Restarted, db1}))
+	})
+
+	It("should remove a resource", func() {
+		scenario := Scenario{Changes: []ResourceChange{{ResourceGUID: db1.ResourceGUID, Remove: true}}}
+
+		applied, err := scenario.Apply(events, "2001-01-15")
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(applied).To(Equal([]UsageEvent{app1, app1Restarted}))
+	})
+
+	It("should extend the events running at the end of the range", func() {
+		scenario := Scenario{ExtendTo: "2001-02-01"}
+
+		applied, err := scenario.Apply(events, "2001-01-15")
+
+		Expect(err).ToNot(HaveOccurred())
+		extendedApp1Restarted, extendedDB1 := app1Restarted, db1
+		extendedApp1Restarted.EventStop = "2001-02-01"
+		extendedDB1.EventStop = "2001-02-01"
+		Expect(applied).To(Equal([]UsageEvent{app1, extendedApp1Restarted, extendedDB1}))
+		Expect(scenario.Range(EventFilter{RangeStart: "2001-01-01", RangeStop: "2001-01-15"})).To(Equal(
+			EventFilter{RangeStart: "2001-01-01", RangeStop: "2001-02-01"},
+		))
+	})
+
+	It("should only extend to after the end of the range", func() {
+		_, err := Scenario{ExtendTo: "2001-01-10"}.Apply(events, "2001-01-15")
+		Expect(err).To(MatchError("extend_to must be after the end of the range - got 2001-01-10"))
+
+		_, err = Scenario{ExtendTo: "end of month"}.Apply(events, "2001-01-15")
+		Expect(err).To(MatchError("extend_to must be a date or RFC3339 timestamp - got end of month"))
+	})
+})
@@ -0,0 +1,18 @@
+package eventio
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var vatCodePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,31}$`)
+
+// ValidateVATCode returns an error if code can not be used as a VAT code.
+// Any code can be configured, such as Standard, Zero, Exempt or
+// ReverseCharge, as long as it has a VAT rate in effect where it is used.
+func ValidateVATCode(code string) error {
+	if !vatCodePattern.MatchString(code) {
+		return fmt.Errorf("invalid vat code '%s', must be a letter followed by up to 31 letters, digits, '-' or '_'", code)
+	}
+	return nil
+}
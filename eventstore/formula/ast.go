@@ -0,0 +1,384 @@
+package formula
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// node is an expression in the formula syntax tree. Every node can be
+// evaluated directly in Go or written out as an equivalent SQL expression.
+type node interface {
+	pos() Pos
+	// kind is the type the expression will have, which can be determined
+	// without evaluating it
+	kind() kind
+	eval(vars Vars) (value, error)
+	writeSQL(b *strings.Builder)
+}
+
+type numberNode struct {
+	at   Pos
+	text string
+}
+
+func (n *numberNode) pos() Pos { return n.at }
+
+func (n *numberNode) kind() kind {
+	v, err := literalValue(n.text)
+	if err != nil {
+		return kindNumeric
+	}
+	return v.kind
+}
+
+func (n *numberNode) eval(vars Vars) (value, error) {
+	v, err := literalValue(n.text)
+	if err != nil {
+		return value{}, newError(n.at, "%s", err)
+	}
+	return v, nil
+}
+
+func (n *numberNode) writeSQL(b *strings.Builder) {
+	if strings.HasPrefix(n.text, ".") {
+		b.WriteString("0")
+	}
+	b.WriteString(n.text)
+}
+
+type variableNode struct {
+	at  Pos
+	def *variable
+}
+
+func (n *variableNode) pos() Pos { return n.at }
+
+func (n *variableNode) kind() kind { return kindNumeric }
+
+func (n *variableNode) eval(vars Vars) (value, error) {
+	r := n.def.get(vars)
+	if r == nil {
+		r = new(big.Rat)
+	}
+	return numericValue(r), nil
+}
+
+func (n *variableNode) writeSQL(b *strings.Builder) {
+	fmt.Fprintf(b, "($%d::numeric)", n.def.param)
+}
+
+type unaryNode struct {
+	at      Pos
+	op      string
+	operand node
+}
+
+func (n *unaryNode) pos() Pos { return n.at }
+
+func (n *unaryNode) kind() kind { return n.operand.kind() }
+
+func (n *unaryNode) eval(vars Vars) (value, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return value{}, err
+	}
+	if n.op == "+" {
+		return v, nil
+	}
+	v, err = negate(v)
+	if err != nil {
+		return value{}, newError(n.at, "%s", err)
+	}
+	return v, nil
+}
+
+func (n *unaryNode) writeSQL(b *strings.Builder) {
+	b.WriteString("(")
+	b.WriteString(n.op)
+	n.operand.writeSQL(b)
+	b.WriteString(")")
+}
+
+type binaryNode struct {
+	at    Pos
+	op    string
+	left  node
+	right node
+}
+
+func (n *binaryNode) pos() Pos { return n.at }
+
+func (n *binaryNode) kind() kind {
+	if n.op == "^" {
+		return powerKind(n.left.kind(), n.right.kind())
+	}
+	return commonKind(n.left.kind(), n.right.kind())
+}
+
+func (n *binaryNode) eval(vars Vars) (value, error) {
+	a, err := n.left.eval(vars)
+	if err != nil {
+		return value{}, err
+	}
+	b, err := n.right.eval(vars)
+	if err != nil {
+		return value{}, err
+	}
+	v, err := arithmetic(n.op, a, b)
+	if err != nil {
+		return value{}, newError(n.at, "%s", err)
+	}
+	return v, nil
+}
+
+func (n *binaryNode) writeSQL(b *strings.Builder) {
+	b.WriteString("(")
+	n.left.writeSQL(b)
+	b.WriteString(" ")
+	b.WriteString(n.op)
+	b.WriteString(" ")
+	n.right.writeSQL(b)
+	b.WriteString(")")
+}
+
+type castNode struct {
+	at       Pos
+	operand  node
+	typeName string
+}
+
+func (n *castNode) pos() Pos { return n.at }
+
+func (n *castNode) kind() kind { return castKinds[n.typeName] }
+
+func (n *castNode) eval(vars Vars) (value, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return value{}, err
+	}
+	v, err = cast(v, n.typeName)
+	if err != nil {
+		return value{}, newError(n.at, "%s", err)
+	}
+	return v, nil
+}
+
+func (n *castNode) writeSQL(b *strings.Builder) {
+	b.WriteString("(")
+	n.operand.writeSQL(b)
+	b.WriteString(")::")
+	b.WriteString(n.typeName)
+}
+
+type callNode struct {
+	at   Pos
+	def  *function
+	args []node
+}
+
+func (n *callNode) pos() Pos { return n.at }
+
+func (n *callNode) kind() kind {
+	kinds := make([]kind, len(n.args))
+	for i, arg := range n.args {
+		kinds[i] = arg.kind()
+	}
+	return n.def.kind(kinds)
+}
+
+func (n *callNode) eval(vars Vars) (value, error) {
+	args := make([]value, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return value{}, err
+		}
+		args[i] = v
+	}
+	v, err := n.def.eval(args)
+	if err != nil {
+		return value{}, newError(n.at, "%s", err)
+	}
+	return v, nil
+}
+
+func (n *callNode) writeSQL(b *strings.Builder) {
+	if n.def.writeSQL != nil {
+		n.def.writeSQL(b, n.args)
+		return
+	}
+	writeCall(b, n.def.name, n.args)
+}
+
+func writeCall(b *strings.Builder, name string, args []node) {
+	b.WriteString(name)
+	b.WriteString("(")
+	for i, arg := range args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		arg.writeSQL(b)
+	}
+	b.WriteString(")")
+}
+
+// whenClause is a single "when ... then ..." branch of a case expression
+type whenClause struct {
+	cond   condition
+	result node
+}
+
+// caseNode picks the result of the first when clause whose condition is
+// true, or the else result if none are
+type caseNode struct {
+	at     Pos
+	whens  []whenClause
+	orElse node
+}
+
+func (n *caseNode) pos() Pos { return n.at }
+
+func (n *caseNode) kind() kind {
+	k := n.orElse.kind()
+	for _, w := range n.whens {
+		k = commonKind(k, w.result.kind())
+	}
+	return k
+}
+
+func (n *caseNode) eval(vars Vars) (value, error) {
+	result := n.orElse
+	for _, w := range n.whens {
+		ok, err := w.cond.evalBool(vars)
+		if err != nil {
+			return value{}, err
+		}
+		if ok {
+			result = w.result
+			break
+		}
+	}
+	v, err := result.eval(vars)
+	if err != nil {
+		return value{}, err
+	}
+	// every branch has the same type in postgres
+	v, err = coerce(v, n.kind())
+	if err != nil {
+		return value{}, newError(n.at, "%s", err)
+	}
+	return v, nil
+}
+
+func (n *caseNode) writeSQL(b *strings.Builder) {
+	b.WriteString("(case")
+	for _, w := range n.whens {
+		b.WriteString(" when ")
+		w.cond.writeSQL(b)
+		b.WriteString(" then ")
+		w.result.writeSQL(b)
+	}
+	b.WriteString(" else ")
+	n.orElse.writeSQL(b)
+	b.WriteString(" end)")
+}
+
+// condition is a boolean expression, which can only appear in the when
+// clause of a case expression
+type condition interface {
+	evalBool(vars Vars) (bool, error)
+	writeSQL(b *strings.Builder)
+}
+
+type comparisonNode struct {
+	at    Pos
+	op    string
+	left  node
+	right node
+}
+
+func (n *comparisonNode) evalBool(vars Vars) (bool, error) {
+	a, err := n.left.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, err := n.right.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	c, err := compare(a, b)
+	if err != nil {
+		return false, newError(n.at, "%s", err)
+	}
+	switch n.op {
+	case "=":
+		return c == 0, nil
+	case "<>":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return false, newError(n.at, "unknown comparison: %s", n.op)
+}
+
+func (n *comparisonNode) writeSQL(b *strings.Builder) {
+	b.WriteString("(")
+	n.left.writeSQL(b)
+	b.WriteString(" ")
+	b.WriteString(n.op)
+	b.WriteString(" ")
+	n.right.writeSQL(b)
+	b.WriteString(")")
+}
+
+type logicalNode struct {
+	op    string
+	left  condition
+	right condition
+}
+
+func (n *logicalNode) evalBool(vars Vars) (bool, error) {
+	a, err := n.left.evalBool(vars)
+	if err != nil {
+		return false, err
+	}
+	if n.op == "and" && !a {
+		return false, nil
+	}
+	if n.op == "or" && a {
+		return true, nil
+	}
+	return n.right.evalBool(vars)
+}
+
+func (n *logicalNode) writeSQL(b *strings.Builder) {
+	b.WriteString("(")
+	n.left.writeSQL(b)
+	b.WriteString(" ")
+	b.WriteString(n.op)
+	b.WriteString(" ")
+	n.right.writeSQL(b)
+	b.WriteString(")")
+}
+
+type notNode struct {
+	operand condition
+}
+
+func (n *notNode) evalBool(vars Vars) (bool, error) {
+	v, err := n.operand.evalBool(vars)
+	return !v, err
+}
+
+func (n *notNode) writeSQL(b *strings.Builder) {
+	b.WriteString("(not ")
+	n.operand.writeSQL(b)
+	b.WriteString(")")
+}
@@ -0,0 +1,163 @@
+// Package formula implements the small expression language used to price
+// the components of a pricing plan.
+//
+// A formula is parsed into a syntax tree which can either be evaluated
+// directly in Go (for example to price a forecast) or compiled to an
+// equivalent SQL expression that the event store evaluates with
+// eval_formula. Only the constructs understood by the parser can ever
+// appear in the generated SQL.
+//
+// Evaluation follows the postgres typing rules for each operation so that
+// both evaluators agree: integer literals are integers (and integer
+// division truncates), decimal literals and variables are numeric, and
+// results are returned as a numeric.
+package formula
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Vars are the values of the variables available to a formula. A nil
+// value is treated as zero.
+type Vars struct {
+	MemoryInMB    *big.Rat
+	StorageInMB   *big.Rat
+	NumberOfNodes *big.Rat
+	TimeInSeconds *big.Rat
+	DiskInMB      *big.Rat
+	// MonthSeconds is the length of the UTC calendar month containing the
+	// start of the time being priced, see MonthSeconds
+	MonthSeconds *big.Rat
+	// EventCount is 1 when the time being priced includes the start of
+	// the event and 0 otherwise, so that a charge can be made once per
+	// event
+	EventCount *big.Rat
+}
+
+type variable struct {
+	name  string
+	param int
+	get   func(Vars) *big.Rat
+}
+
+// variables maps each variable to the positional parameter that the
+// compiled SQL uses to refer to it. eval_formula passes the parameters in
+// this order.
+var variables = []*variable{
+	{name: "$memory_in_mb", param: 1, get: func(v Vars) *big.Rat { return v.MemoryInMB }},
+	{name: "$storage_in_mb", param: 2, get: func(v Vars) *big.Rat { return v.StorageInMB }},
+	{name: "$number_of_nodes", param: 3, get: func(v Vars) *big.Rat { return v.NumberOfNodes }},
+	{name: "$time_in_seconds", param: 4, get: func(v Vars) *big.Rat { return v.TimeInSeconds }},
+	{name: "$disk_in_mb", param: 5, get: func(v Vars) *big.Rat { return v.DiskInMB }},
+	{name: "$month_seconds", param: 6, get: func(v Vars) *big.Rat { return v.MonthSeconds }},
+	{name: "$event_count", param: 7, get: func(v Vars) *big.Rat { return v.EventCount }},
+}
+
+// MonthSeconds returns the number of seconds in the UTC calendar month
+// containing t, as used for the $month_seconds variable. It is always the UTC
+// calendar month, whatever the billing period of the org being priced, so a
+// monthly fee prorated by $month_seconds is only prorated by the length of
+// the billing period for orgs billed by calendar month.
+func MonthSeconds(t time.Time) *big.Rat {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	return big.NewRat(int64(end.Sub(start)/time.Second), 1)
+}
+
+func lookupVariable(name string) (*variable, bool) {
+	for _, v := range variables {
+		if v.name == name {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Error is a problem with a formula at a particular position
+type Error struct {
+	Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+func newError(pos Pos, format string, args ...interface{}) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Formula is a parsed pricing formula
+type Formula struct {
+	source string
+	root   node
+}
+
+// Parse parses and checks the given formula source. As well as syntax
+// errors, it rejects formulas that cannot be evaluated for the edge case
+// inputs of all zeros and all ones (for example division by a variable
+// other than $month_seconds).
+func Parse(source string) (*Formula, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, newError(Pos{Line: 1, Column: 1}, "formula can not be empty")
+	}
+	p := &parser{toks: newLexer(source).tokens()}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	f := &Formula{source: source, root: root}
+	// a month is never empty so $month_seconds is always a real length
+	zero, one := new(big.Rat), big.NewRat(1, 1)
+	month := MonthSeconds(time.Date(2000, 4, 1, 0, 0, 0, 0, time.UTC))
+	for _, vars := range []Vars{
+		{MemoryInMB: zero, StorageInMB: zero, NumberOfNodes: zero, TimeInSeconds: zero, DiskInMB: zero, MonthSeconds: month, EventCount: zero},
+		{MemoryInMB: one, StorageInMB: one, NumberOfNodes: one, TimeInSeconds: one, DiskInMB: one, MonthSeconds: month, EventCount: one},
+	} {
+		if _, err := f.Eval(vars); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// MustParse is like Parse but panics if the formula is invalid
+func MustParse(source string) *Formula {
+	f, err := Parse(source)
+	if err != nil {
+		panic(fmt.Sprintf("formula %q: %s", source, err))
+	}
+	return f
+}
+
+// String returns the source of the formula
+func (f *Formula) String() string {
+	return f.source
+}
+
+// Eval evaluates the formula with the given variables
+func (f *Formula) Eval(vars Vars) (*big.Rat, error) {
+	v, err := f.root.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	r, err := v.numeric()
+	if err != nil {
+		return nil, newError(f.root.pos(), "%s", err)
+	}
+	return new(big.Rat).Set(r), nil
+}
+
+// SQL returns the formula compiled to a postgres expression. Variables are
+// referenced as the positional parameters $1 to $7 in the order memory,
+// storage, number of nodes, time in seconds, disk, month seconds and event
+// count.
+func (f *Formula) SQL() string {
+	var b strings.Builder
+	f.root.writeSQL(&b)
+	return b.String()
+}
@@ -0,0 +1,164 @@
+package formula_test
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventstore/formula"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Formula", func() {
+
+	vars := formula.Vars{
+		MemoryInMB:    big.NewRat(64, 1),
+		StorageInMB:   big.NewRat(128, 1),
+		NumberOfNodes: big.NewRat(2, 1),
+		TimeInSeconds: big.NewRat(60, 1),
+		DiskInMB:      big.NewRat(256, 1),
+		MonthSeconds:  big.NewRat(2592000, 1),
+		EventCount:    big.NewRat(1, 1),
+	}
+
+	DescribeTable("Eval",
+		func(source string, expected string) {
+			f, err := formula.Parse(source)
+			Expect(err).ToNot(HaveOccurred())
+			out, err := f.Eval(vars)
+			Expect(err).ToNot(HaveOccurred())
+			want, ok := new(big.Rat).SetString(expected)
+			Expect(ok).To(BeTrue())
+			Expect(out.Cmp(want)).To(Equal(0), "got %s, want %s", out.RatString(), want.RatString())
+		},
+		Entry("integer arithmetic", "((2 * 2::integer) + 1 - 1) / 1", "4"),
+		Entry("integer division truncates", "5 / 3", "1"),
+		Entry("negative integer division truncates towards zero", "-5 / 3", "-1"),
+		Entry("numeric division does not truncate", "5.0 / 2", "2.5"),
+		Entry("bigint arithmetic", "12147483647 * (2)::bigint", "24294967294"),
+		Entry("numeric literals", "1.5 * 2", "3"),
+		Entry("leading dot numeric literals", ".5 * 2", "1"),
+		Entry("$memory_in_mb", "$memory_in_mb * 2", "128"),
+		Entry("$storage_in_mb", "$storage_in_mb * 2", "256"),
+		Entry("$number_of_nodes", "$number_of_nodes * 2", "4"),
+		Entry("$time_in_seconds", "$time_in_seconds * 2", "120"),
+		Entry("$disk_in_mb", "$disk_in_mb * 2", "512"),
+		Entry("$month_seconds", "$month_seconds", "2592000"),
+		Entry("$event_count", "$event_count * 5", "5"),
+		Entry("prorated monthly fee", "$time_in_seconds / $month_seconds * 10", "1/4320"),
+		Entry("variables are numeric", "$time_in_seconds / 3600 * 2", "1/30"),
+		Entry("variables are case insensitive", "$Memory_In_MB", "64"),
+		Entry("power", "2^2", "4"),
+		Entry("power is left associative", "2^3^2", "64"),
+		Entry("power binds tighter than multiplication", "2 * 3^2", "18"),
+		Entry("unary minus binds tighter than power", "-2^2", "4"),
+		Entry("numeric power", "1.5^2", "2.25"),
+		Entry("ceil", "ceil(5.0/3.0)", "2"),
+		Entry("ceil with a variable", "ceil($time_in_seconds / 3600) * 10", "10"),
+		Entry("ceil is case insensitive", "CEIL(0.1)", "1"),
+		Entry("numeric to integer casts round", "2.5::integer + (-2.5)::integer", "0"),
+		Entry("casting to numeric", "5::numeric / 2", "2.5"),
+		Entry("multi-line formulas", "1 +\n\t2 *\n\t3", "7"),
+		Entry("floor", "floor(5.0/3.0)", "1"),
+		Entry("floor of negative numbers", "floor(-0.5)", "-1"),
+		Entry("round", "round(2.5) + round(-2.5)", "0"),
+		Entry("round of a double rounds half to even", "round(5 * 2^-1) + round(2^-1)", "2"),
+		Entry("round to decimal places", "round(1.23456, 2)", "1.23"),
+		Entry("round to negative decimal places", "round(1250, -2)", "1300"),
+		Entry("greatest", "greatest(1, 3.5, $number_of_nodes)", "3.5"),
+		Entry("least", "least(1, 3.5, $number_of_nodes)", "1"),
+		Entry("max", "max($memory_in_mb, 100)", "100"),
+		Entry("min", "min($memory_in_mb, 100)", "64"),
+		Entry("minimum charges", "greatest($time_in_seconds * 0.001, 1)", "1"),
+		Entry("tier below the lower bound", "tier($memory_in_mb, 100)", "0"),
+		Entry("tier above the lower bound", "tier($storage_in_mb, 100)", "28"),
+		Entry("tier within the bounds", "tier($storage_in_mb, 100, 200)", "28"),
+		Entry("tier above the upper bound", "tier($storage_in_mb, 100, 110)", "10"),
+		Entry("volume tiers", "tier($storage_in_mb, 0, 100) * 2 + tier($storage_in_mb, 100) * 1", "228"),
+		Entry("first 100 MB free", "tier($storage_in_mb, 100) * 0.5", "14"),
+		Entry("case", "case when $number_of_nodes > 1 then 10 else 20 end", "10"),
+		Entry("case else", "case when $number_of_nodes > 2 then 10 else 20 end", "20"),
+		Entry("case with multiple whens", "case when $memory_in_mb < 64 then 1 when $memory_in_mb <= 64 then 2 else 3 end", "2"),
+		Entry("case branches have a common type", "case when 1 = 1 then 5 else 2.5 end / 2", "2.5"),
+		Entry("case with and", "case when $memory_in_mb = 64 and $storage_in_mb <> 64 then 1 else 0 end", "1"),
+		Entry("case with or", "case when $memory_in_mb != 64 or $storage_in_mb >= 129 then 1 else 0 end", "0"),
+		Entry("case with not", "case when not $memory_in_mb > 64 then 1 else 0 end", "1"),
+		Entry("case with nested conditions", "case when ($memory_in_mb > 64 or $storage_in_mb > 64) and not ($number_of_nodes = 3) then 1 else 0 end", "1"),
+		Entry("case with parenthesised expressions", "case when ($memory_in_mb + 1) * 2 > 64 then 1 else 0 end", "1"),
+		Entry("case is case insensitive", "CASE WHEN 1 > 0 THEN 1 ELSE 0 END", "1"),
+		Entry("case does not evaluate other branches", "case when $number_of_nodes = 0 then 0 else 1 / $number_of_nodes end", "1/2"),
+	)
+
+	DescribeTable("Parse errors",
+		func(source string, expected string) {
+			_, err := formula.Parse(source)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(expected))
+		},
+		Entry("empty formula", "  ", "line 1, column 1: formula can not be empty"),
+		Entry("semicolons", "1+1;", "line 1, column 4: illegal token in formula: ;"),
+		Entry("sql keywords", "select", "line 1, column 1: illegal token in formula: select"),
+		Entry("unknown variables", "1 + $unknown", "line 1, column 5: illegal token in formula: $unknown"),
+		Entry("unknown casts", "1::text", "line 1, column 4: illegal token in formula: text"),
+		Entry("unclosed function calls", "ceil(5", "line 1, column 7: unexpected end of formula, expected ')'"),
+		Entry("functions without arguments", "ceil", "line 1, column 5: unexpected end of formula, expected '(' after ceil"),
+		Entry("too many arguments", "ceil(1, 2)", "line 1, column 1: ceil expects 1 argument, got 2"),
+		Entry("missing operands", "1 +", "line 1, column 4: unexpected end of formula, expected a number, variable or function"),
+		Entry("missing operators", "1 2", "line 1, column 3: unexpected '2', expected an operator or end of formula"),
+		Entry("errors on later lines", "1 +\n  2 +\n  ;", "line 3, column 3: illegal token in formula: ;"),
+		Entry("division by zero", "1 / (2 - 2)", "line 1, column 3: division by zero"),
+		Entry("division by a variable that may be zero", "1 / $number_of_nodes", "line 1, column 3: division by zero"),
+		Entry("integer overflow", "2147483647 + 1", "line 1, column 12: integer out of range"),
+		Entry("round to fractional places", "round(1.5, 0.5)", "line 1, column 1: round expects a whole number of decimal places"),
+		Entry("tier with too few arguments", "tier(1)", "line 1, column 1: tier expects between 2 and 3 arguments, got 1"),
+		Entry("case without else", "case when 1 > 0 then 1 end", "line 1, column 24: unexpected 'end', expected 'when' or 'else' (case must have an else)"),
+		Entry("case without when", "case else 1 end", "line 1, column 6: unexpected 'else', expected 'when'"),
+		Entry("case without comparison", "case when 1 then 1 else 0 end", "line 1, column 13: unexpected 'then', expected a comparison"),
+		Entry("case without end", "case when 1 > 0 then 1 else 0", "line 1, column 30: unexpected end of formula, expected 'end'"),
+		Entry("comparisons outside case", "1 > 0", "line 1, column 3: unexpected '>', expected an operator or end of formula"),
+		Entry("keywords as values", "1 + then", "line 1, column 5: unexpected 'then', expected a number, variable or function"),
+	)
+
+	It("should treat nil variables as zero", func() {
+		f := formula.MustParse("$memory_in_mb + $storage_in_mb + $number_of_nodes + $time_in_seconds + $disk_in_mb + $event_count + 1")
+		out, err := f.Eval(formula.Vars{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out.Cmp(big.NewRat(1, 1))).To(Equal(0))
+	})
+
+	DescribeTable("MonthSeconds",
+		func(t string, days int64) {
+			at, err := time.Parse(time.RFC3339, t)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(formula.MonthSeconds(at).Cmp(big.NewRat(days*24*60*60, 1))).To(Equal(0))
+		},
+		Entry("january", "2001-01-31T23:59:59Z", int64(31)),
+		Entry("february", "2001-02-01T00:00:00Z", int64(28)),
+		Entry("february in a leap year", "2004-02-15T00:00:00Z", int64(29)),
+		Entry("april", "2001-04-10T00:00:00Z", int64(30)),
+		Entry("uses the UTC month", "2001-05-01T00:30:00+01:00", int64(30)),
+	)
+
+	DescribeTable("SQL",
+		func(source string, expected string) {
+			Expect(formula.MustParse(source).SQL()).To(Equal(expected))
+		},
+		Entry("variables become positional parameters", "$memory_in_mb + $storage_in_mb + $number_of_nodes + $time_in_seconds",
+			"(((($1::numeric) + ($2::numeric)) + ($3::numeric)) + ($4::numeric))"),
+		Entry("newer variables become positional parameters", "$disk_in_mb + $month_seconds + $event_count",
+			"((($5::numeric) + ($6::numeric)) + ($7::numeric))"),
+		Entry("casts", "2.5::integer", "(2.5)::integer"),
+		Entry("functions", "CEIL($time_in_seconds / 3600)", "ceil((($4::numeric) / 3600))"),
+		Entry("unary minus can not form comments", "1--2", "(1 - (-2))"),
+		Entry("precedence is made explicit", "-2^2 * 3", "(((-2) ^ 2) * 3)"),
+		Entry("leading dot numeric literals", ".5", "0.5"),
+		Entry("min and max become least and greatest", "min(1, max(2, 3))", "least(1, greatest(2, 3))"),
+		Entry("round to decimal places casts its arguments", "round(1.234, 2)", "round((1.234)::numeric, (2)::integer)"),
+		Entry("tier", "tier($storage_in_mb, 1024)", "greatest((($2::numeric) - 1024), 0)"),
+		Entry("tier with upper bound", "tier($storage_in_mb, 1024, 2048)", "greatest((least(($2::numeric), 2048) - 1024), 0)"),
+		Entry("case", "case when $memory_in_mb > 1 and not (1 != 2 or 1 <= 2) then 1 else 0 end",
+			"(case when ((($1::numeric) > 1) and (not ((1 <> 2) or (1 <= 2)))) then 1 else 0 end)"),
+	)
+})
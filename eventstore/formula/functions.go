@@ -0,0 +1,236 @@
+package formula
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// function is a function that can be called from a formula
+type function struct {
+	name    string
+	minArgs int
+	// maxArgs is -1 for functions that take any number of arguments
+	maxArgs int
+	// check optionally validates the arguments when the formula is parsed
+	check func(args []node) error
+	kind  func(args []kind) kind
+	eval  func(args []value) (value, error)
+	// writeSQL optionally overrides writing the function as a SQL
+	// function call of the same name
+	writeSQL func(b *strings.Builder, args []node)
+}
+
+var functions = map[string]*function{}
+
+func init() {
+	for _, f := range []*function{
+		{
+			name:    "ceil",
+			minArgs: 1,
+			maxArgs: 1,
+			kind:    roundingKind,
+			eval:    evalRounding(ratCeil, math.Ceil),
+		},
+		{
+			name:    "floor",
+			minArgs: 1,
+			maxArgs: 1,
+			kind:    roundingKind,
+			eval:    evalRounding(ratFloor, math.Floor),
+		},
+		{
+			name:     "round",
+			minArgs:  1,
+			maxArgs:  2,
+			check:    checkRound,
+			kind:     roundKind,
+			eval:     evalRound,
+			writeSQL: writeRound,
+		},
+		{
+			name:    "greatest",
+			minArgs: 1,
+			maxArgs: -1,
+			kind:    commonArgsKind,
+			eval:    evalExtreme(1),
+		},
+		{
+			name:    "least",
+			minArgs: 1,
+			maxArgs: -1,
+			kind:    commonArgsKind,
+			eval:    evalExtreme(-1),
+		},
+		{
+			name:     "max",
+			minArgs:  1,
+			maxArgs:  -1,
+			kind:     commonArgsKind,
+			eval:     evalExtreme(1),
+			writeSQL: writeAlias("greatest"),
+		},
+		{
+			name:     "min",
+			minArgs:  1,
+			maxArgs:  -1,
+			kind:     commonArgsKind,
+			eval:     evalExtreme(-1),
+			writeSQL: writeAlias("least"),
+		},
+		{
+			name:     "tier",
+			minArgs:  2,
+			maxArgs:  3,
+			kind:     commonArgsKind,
+			eval:     evalTier,
+			writeSQL: writeTier,
+		},
+	} {
+		functions[f.name] = f
+	}
+}
+
+// roundingKind follows postgres in resolving functions such as ceil of an
+// integer to the double precision version of the function
+func roundingKind(args []kind) kind {
+	if args[0] == kindNumeric {
+		return kindNumeric
+	}
+	return kindDouble
+}
+
+func commonArgsKind(args []kind) kind {
+	return commonKind(args...)
+}
+
+func evalRounding(numeric func(*big.Rat) *big.Int, double func(float64) float64) func([]value) (value, error) {
+	return func(args []value) (value, error) {
+		v := args[0]
+		if v.kind == kindNumeric {
+			return numericValue(new(big.Rat).SetInt(numeric(v.r))), nil
+		}
+		return doubleValue(double(v.double()))
+	}
+}
+
+func checkRound(args []node) error {
+	if len(args) == 2 && args[1].kind() > kindBigint {
+		return errors.New("round expects a whole number of decimal places")
+	}
+	return nil
+}
+
+func roundKind(args []kind) kind {
+	if len(args) == 2 {
+		return kindNumeric
+	}
+	return roundingKind(args)
+}
+
+// evalRound rounds halves away from zero for numerics, but to the nearest
+// even number for doubles. With two arguments the value is always rounded
+// as a numeric to the given number of decimal places.
+func evalRound(args []value) (value, error) {
+	if len(args) == 1 {
+		return evalRounding(ratRound, math.RoundToEven)(args)
+	}
+	v, err := cast(args[0], "numeric")
+	if err != nil {
+		return value{}, err
+	}
+	places, err := cast(args[1], "integer")
+	if err != nil {
+		return value{}, err
+	}
+	n := places.r.Num().Int64()
+	if n > maxExactExponent || n < -maxExactExponent {
+		return value{}, errors.New("round can not use more than 1000 decimal places")
+	}
+	scale := ratPow(big.NewRat(10, 1), n)
+	rounded := new(big.Rat).SetInt(ratRound(new(big.Rat).Mul(v.r, scale)))
+	return numericValue(rounded.Quo(rounded, scale)), nil
+}
+
+// writeRound casts the arguments of the two argument form, postgres only
+// has a round(numeric, integer) function
+func writeRound(b *strings.Builder, args []node) {
+	if len(args) == 1 {
+		writeCall(b, "round", args)
+		return
+	}
+	b.WriteString("round((")
+	args[0].writeSQL(b)
+	b.WriteString(")::numeric, (")
+	args[1].writeSQL(b)
+	b.WriteString(")::integer)")
+}
+
+// evalExtreme returns the greatest (sign 1) or least (sign -1) argument
+func evalExtreme(sign int) func([]value) (value, error) {
+	return func(args []value) (value, error) {
+		kinds := make([]kind, len(args))
+		for i, arg := range args {
+			kinds[i] = arg.kind
+		}
+		k := commonKind(kinds...)
+		best, err := coerce(args[0], k)
+		if err != nil {
+			return value{}, err
+		}
+		for _, arg := range args[1:] {
+			v, err := coerce(arg, k)
+			if err != nil {
+				return value{}, err
+			}
+			c, err := compare(v, best)
+			if err != nil {
+				return value{}, err
+			}
+			if c == sign {
+				best = v
+			}
+		}
+		return best, nil
+	}
+}
+
+func writeAlias(name string) func(*strings.Builder, []node) {
+	return func(b *strings.Builder, args []node) {
+		writeCall(b, name, args)
+	}
+}
+
+// evalTier returns the part of the first argument that lies between the
+// second and (if given) the third, for example tier(15, 10, 20) is 5 and
+// tier(25, 10, 20) is 10. It is written in SQL as
+// greatest(least(x, upper) - lower, 0).
+func evalTier(args []value) (value, error) {
+	v := args[0]
+	if len(args) == 3 {
+		var err error
+		v, err = evalExtreme(-1)([]value{v, args[2]})
+		if err != nil {
+			return value{}, err
+		}
+	}
+	v, err := arithmetic("-", v, args[1])
+	if err != nil {
+		return value{}, err
+	}
+	zero, _ := literalValue("0")
+	return evalExtreme(1)([]value{v, zero})
+}
+
+func writeTier(b *strings.Builder, args []node) {
+	b.WriteString("greatest((")
+	if len(args) == 3 {
+		writeCall(b, "least", []node{args[0], args[2]})
+	} else {
+		args[0].writeSQL(b)
+	}
+	b.WriteString(" - ")
+	args[1].writeSQL(b)
+	b.WriteString("), 0)")
+}
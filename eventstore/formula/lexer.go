@@ -0,0 +1,187 @@
+package formula
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenVariable
+	tokenIdent
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+	tokenCast
+	tokenComparison
+	tokenIllegal
+)
+
+// Pos is a position within the source of a formula. Lines and columns
+// are counted from 1.
+type Pos struct {
+	Line   int
+	Column int
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  Pos
+}
+
+// describe returns a human readable name for the token for use in error
+// messages
+func (t token) describe() string {
+	if t.kind == tokenEOF {
+		return "end of formula"
+	}
+	return "'" + t.text + "'"
+}
+
+type lexer struct {
+	src  []rune
+	off  int
+	line int
+	col  int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{
+		src:  []rune(src),
+		line: 1,
+		col:  1,
+	}
+}
+
+func (l *lexer) peekRune(n int) rune {
+	if l.off+n >= len(l.src) {
+		return 0
+	}
+	return l.src[l.off+n]
+}
+
+func (l *lexer) advance() rune {
+	r := l.src[l.off]
+	l.off++
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+func (l *lexer) pos() Pos {
+	return Pos{Line: l.line, Column: l.col}
+}
+
+// tokens splits the whole source into tokens, the last token is always
+// tokenEOF
+func (l *lexer) tokens() []token {
+	toks := []token{}
+	for {
+		tok := l.next()
+		toks = append(toks, tok)
+		if tok.kind == tokenEOF {
+			return toks
+		}
+	}
+}
+
+func (l *lexer) next() token {
+	for l.off < len(l.src) && unicode.IsSpace(l.src[l.off]) {
+		l.advance()
+	}
+	pos := l.pos()
+	if l.off >= len(l.src) {
+		return token{kind: tokenEOF, pos: pos}
+	}
+	r := l.peekRune(0)
+	switch {
+	case isDigit(r) || (r == '.' && isDigit(l.peekRune(1))):
+		return token{kind: tokenNumber, text: l.readNumber(), pos: pos}
+	case r == '$' && isIdentRune(l.peekRune(1)):
+		l.advance()
+		return token{kind: tokenVariable, text: "$" + l.readIdent(), pos: pos}
+	case isIdentStart(r):
+		return token{kind: tokenIdent, text: l.readIdent(), pos: pos}
+	case r == ':' && l.peekRune(1) == ':':
+		l.advance()
+		l.advance()
+		return token{kind: tokenCast, text: "::", pos: pos}
+	case r == '<' || r == '>' || r == '=' || (r == '!' && l.peekRune(1) == '='):
+		return token{kind: tokenComparison, text: l.readComparison(), pos: pos}
+	case strings.ContainsRune("+-*/^", r):
+		l.advance()
+		return token{kind: tokenOperator, text: string(r), pos: pos}
+	case r == '(':
+		l.advance()
+		return token{kind: tokenLeftParen, text: "(", pos: pos}
+	case r == ')':
+		l.advance()
+		return token{kind: tokenRightParen, text: ")", pos: pos}
+	case r == ',':
+		l.advance()
+		return token{kind: tokenComma, text: ",", pos: pos}
+	}
+	l.advance()
+	return token{kind: tokenIllegal, text: string(r), pos: pos}
+}
+
+func (l *lexer) readNumber() string {
+	start := l.off
+	for l.off < len(l.src) && isDigit(l.src[l.off]) {
+		l.advance()
+	}
+	if l.peekRune(0) == '.' && isDigit(l.peekRune(1)) {
+		l.advance()
+		for l.off < len(l.src) && isDigit(l.src[l.off]) {
+			l.advance()
+		}
+	}
+	return string(l.src[start:l.off])
+}
+
+// readComparison reads a comparison operator, != is returned as the
+// equivalent <>
+func (l *lexer) readComparison() string {
+	op := string(l.advance())
+	switch next := l.peekRune(0); {
+	case next == '=' && op != "=":
+		op += string(l.advance())
+	case next == '>' && op == "<":
+		op += string(l.advance())
+	}
+	if op == "!=" {
+		return "<>"
+	}
+	return op
+}
+
+// readIdent reads an identifier, formulas are case insensitive so it is
+// returned in lower case
+func (l *lexer) readIdent() string {
+	start := l.off
+	for l.off < len(l.src) && isIdentRune(l.src[l.off]) {
+		l.advance()
+	}
+	return strings.ToLower(string(l.src[start:l.off]))
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isIdentStart(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_'
+}
+
+func isIdentRune(r rune) bool {
+	return isIdentStart(r) || isDigit(r)
+}
@@ -0,0 +1,363 @@
+package formula
+
+import (
+	"strconv"
+	"strings"
+)
+
+// castTypes are the types that a formula can be cast to with ::
+var castTypes = map[string]bool{
+	"integer": true,
+	"bigint":  true,
+	"numeric": true,
+}
+
+// keywords can not be used as function names
+var keywords = map[string]bool{
+	"case": true,
+	"when": true,
+	"then": true,
+	"else": true,
+	"end":  true,
+	"and":  true,
+	"or":   true,
+	"not":  true,
+}
+
+// parser is a recursive descent parser for the formula grammar. Operator
+// precedence follows postgres so that a formula means the same thing
+// whether it is evaluated in Go or by the database:
+//
+//	expr       = term { ("+" | "-") term }
+//	term       = power { ("*" | "/") power }
+//	power      = unary { "^" unary }
+//	unary      = ("+" | "-") unary | postfix
+//	postfix    = primary { "::" type }
+//	primary    = number | variable | function "(" expr { "," expr } ")" | case | "(" expr ")"
+//	case       = "case" when { when } "else" expr "end"
+//	when       = "when" cond "then" expr
+//	cond       = and { "or" and }
+//	and        = not { "and" not }
+//	not        = "not" not | "(" cond ")" | comparison
+//	comparison = expr ("=" | "<>" | "!=" | "<" | "<=" | ">" | ">=") expr
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.toks[p.i]
+	if tok.kind != tokenEOF {
+		p.i++
+	}
+	return tok
+}
+
+func (p *parser) isOperator(ops string) bool {
+	tok := p.peek()
+	return tok.kind == tokenOperator && strings.Contains(ops, tok.text)
+}
+
+func (p *parser) expect(kind tokenKind, want string) (token, error) {
+	tok := p.next()
+	if tok.kind == tokenIllegal {
+		return tok, illegalToken(tok)
+	}
+	if tok.kind != kind {
+		return tok, newError(tok.pos, "unexpected %s, expected %s", tok.describe(), want)
+	}
+	return tok, nil
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		if tok.kind == tokenIllegal {
+			return nil, illegalToken(tok)
+		}
+		return nil, newError(tok.pos, "unexpected %s, expected an operator or end of formula", tok.describe())
+	}
+	return n, nil
+}
+
+func (p *parser) parseBinary(ops string, operand func() (node, error)) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator(ops) {
+		op := p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{at: op.pos, op: op.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseExpr() (node, error) {
+	return p.parseBinary("+-", p.parseTerm)
+}
+
+func (p *parser) parseTerm() (node, error) {
+	return p.parseBinary("*/", p.parsePower)
+}
+
+func (p *parser) parsePower() (node, error) {
+	return p.parseBinary("^", p.parseUnary)
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOperator("+-") {
+		op := p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{at: op.pos, op: op.text, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenCast {
+		op := p.next()
+		typeName, err := p.expect(tokenIdent, "a type name")
+		if err != nil {
+			return nil, err
+		}
+		if !castTypes[typeName.text] {
+			return nil, illegalToken(typeName)
+		}
+		n = &castNode{at: op.pos, operand: n, typeName: typeName.text}
+	}
+	return n, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		return &numberNode{at: tok.pos, text: tok.text}, nil
+	case tokenVariable:
+		def, ok := lookupVariable(tok.text)
+		if !ok {
+			return nil, illegalToken(tok)
+		}
+		return &variableNode{at: tok.pos, def: def}, nil
+	case tokenIdent:
+		if tok.text == "case" {
+			return p.parseCase(tok)
+		}
+		if keywords[tok.text] {
+			return nil, newError(tok.pos, "unexpected %s, expected a number, variable or function", tok.describe())
+		}
+		def, ok := functions[tok.text]
+		if !ok {
+			return nil, illegalToken(tok)
+		}
+		return p.parseCall(tok, def)
+	case tokenLeftParen:
+		n, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRightParen, "')'"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case tokenIllegal:
+		return nil, illegalToken(tok)
+	}
+	return nil, newError(tok.pos, "unexpected %s, expected a number, variable or function", tok.describe())
+}
+
+func (p *parser) parseCall(name token, def *function) (node, error) {
+	if _, err := p.expect(tokenLeftParen, "'(' after "+def.name); err != nil {
+		return nil, err
+	}
+	args := []node{}
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.peek().kind != tokenComma {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(tokenRightParen, "')'"); err != nil {
+		return nil, err
+	}
+	if len(args) < def.minArgs || (def.maxArgs >= 0 && len(args) > def.maxArgs) {
+		return nil, newError(name.pos, "%s expects %s, got %d", def.name, describeArity(def), len(args))
+	}
+	if def.check != nil {
+		if err := def.check(args); err != nil {
+			return nil, newError(name.pos, "%s", err)
+		}
+	}
+	return &callNode{at: name.pos, def: def, args: args}, nil
+}
+
+func (p *parser) isKeyword(word string) bool {
+	tok := p.peek()
+	return tok.kind == tokenIdent && tok.text == word
+}
+
+func (p *parser) expectKeyword(word string) error {
+	tok := p.next()
+	if tok.kind == tokenIllegal {
+		return illegalToken(tok)
+	}
+	if tok.kind != tokenIdent || tok.text != word {
+		return newError(tok.pos, "unexpected %s, expected '%s'", tok.describe(), word)
+	}
+	return nil
+}
+
+func (p *parser) parseCase(start token) (node, error) {
+	n := &caseNode{at: start.pos}
+	if !p.isKeyword("when") {
+		tok := p.next()
+		return nil, newError(tok.pos, "unexpected %s, expected 'when'", tok.describe())
+	}
+	for p.isKeyword("when") {
+		p.next()
+		cond, err := p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("then"); err != nil {
+			return nil, err
+		}
+		result, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		n.whens = append(n.whens, whenClause{cond: cond, result: result})
+	}
+	if !p.isKeyword("else") {
+		tok := p.next()
+		return nil, newError(tok.pos, "unexpected %s, expected 'when' or 'else' (case must have an else)", tok.describe())
+	}
+	p.next()
+	orElse, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	n.orElse = orElse
+	if err := p.expectKeyword("end"); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (p *parser) parseCondition() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (condition, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (condition, error) {
+	if p.isKeyword("not") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	if p.peek().kind == tokenLeftParen {
+		// a parenthesis could start either a nested condition or the
+		// expression on the left of a comparison, so try the condition
+		// first and backtrack if that is not what it is
+		start := p.i
+		p.next()
+		if cond, err := p.parseCondition(); err == nil && p.peek().kind == tokenRightParen {
+			p.next()
+			return cond, nil
+		}
+		p.i = start
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (condition, error) {
+	left, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	op := p.next()
+	if op.kind == tokenIllegal {
+		return nil, illegalToken(op)
+	}
+	if op.kind != tokenComparison {
+		return nil, newError(op.pos, "unexpected %s, expected a comparison", op.describe())
+	}
+	right, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &comparisonNode{at: op.pos, op: op.text, left: left, right: right}, nil
+}
+
+func describeArity(def *function) string {
+	plural := func(n int) string {
+		if n == 1 {
+			return "1 argument"
+		}
+		return strconv.Itoa(n) + " arguments"
+	}
+	switch {
+	case def.minArgs == def.maxArgs:
+		return plural(def.minArgs)
+	case def.maxArgs < 0:
+		return "at least " + plural(def.minArgs)
+	}
+	return "between " + strconv.Itoa(def.minArgs) + " and " + plural(def.maxArgs)
+}
+
+func illegalToken(tok token) error {
+	return newError(tok.pos, "illegal token in formula: %s", tok.text)
+}
@@ -0,0 +1,357 @@
+package formula
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// kind mirrors the postgres type that an expression would have when the
+// compiled formula is executed by the database. Keeping track of it allows
+// Eval to give the same answers as the SQL, for example integer division
+// truncating.
+type kind int
+
+const (
+	kindInteger kind = iota
+	kindBigint
+	kindNumeric
+	kindDouble
+)
+
+var (
+	minInteger = big.NewInt(math.MinInt32)
+	maxInteger = big.NewInt(math.MaxInt32)
+	minBigint  = big.NewInt(math.MinInt64)
+	maxBigint  = big.NewInt(math.MaxInt64)
+
+	errDivisionByZero    = errors.New("division by zero")
+	errIntegerRange      = errors.New("integer out of range")
+	errBigintRange       = errors.New("bigint out of range")
+	errDoubleRange       = errors.New("value out of range: overflow")
+	errZeroNegativePower = errors.New("zero raised to a negative power is undefined")
+	errComplexPower      = errors.New("a negative number raised to a non-integer power yields a complex result")
+)
+
+// maxExactExponent is the largest integer exponent that will be computed
+// exactly for numeric powers
+const maxExactExponent = 1000
+
+type value struct {
+	kind kind
+	// r holds the value for integer, bigint and numeric kinds
+	r *big.Rat
+	// f holds the value for the double kind
+	f float64
+}
+
+func integerValue(k kind, i *big.Int) (value, error) {
+	if err := checkIntegerRange(k, i); err != nil {
+		return value{}, err
+	}
+	return value{kind: k, r: new(big.Rat).SetInt(i)}, nil
+}
+
+func numericValue(r *big.Rat) value {
+	return value{kind: kindNumeric, r: r}
+}
+
+func doubleValue(f float64) (value, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return value{}, errDoubleRange
+	}
+	return value{kind: kindDouble, f: f}, nil
+}
+
+// literalValue returns the value of a numeric literal, typed the same way
+// that postgres types constants
+func literalValue(text string) (value, error) {
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return value{}, errors.New("invalid number: " + text)
+	}
+	if !r.IsInt() || containsDot(text) {
+		return numericValue(r), nil
+	}
+	i := r.Num()
+	if i.Cmp(minInteger) >= 0 && i.Cmp(maxInteger) <= 0 {
+		return value{kind: kindInteger, r: r}, nil
+	}
+	if i.Cmp(minBigint) >= 0 && i.Cmp(maxBigint) <= 0 {
+		return value{kind: kindBigint, r: r}, nil
+	}
+	return numericValue(r), nil
+}
+
+func containsDot(s string) bool {
+	for _, c := range s {
+		if c == '.' {
+			return true
+		}
+	}
+	return false
+}
+
+func (v value) double() float64 {
+	if v.kind == kindDouble {
+		return v.f
+	}
+	f, _ := v.r.Float64()
+	return f
+}
+
+// numeric converts the value to an exact rational. Doubles are converted
+// with 15 significant digits as postgres does.
+func (v value) numeric() (*big.Rat, error) {
+	if v.kind != kindDouble {
+		return v.r, nil
+	}
+	if math.IsInf(v.f, 0) || math.IsNaN(v.f) {
+		return nil, errDoubleRange
+	}
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(v.f, 'g', 15, 64))
+	if !ok {
+		return nil, errDoubleRange
+	}
+	return r, nil
+}
+
+func checkIntegerRange(k kind, i *big.Int) error {
+	switch k {
+	case kindInteger:
+		if i.Cmp(minInteger) < 0 || i.Cmp(maxInteger) > 0 {
+			return errIntegerRange
+		}
+	case kindBigint:
+		if i.Cmp(minBigint) < 0 || i.Cmp(maxBigint) > 0 {
+			return errBigintRange
+		}
+	}
+	return nil
+}
+
+// commonKind is the type postgres resolves a mix of types to, for example
+// for the arguments of greatest or the branches of a case
+func commonKind(kinds ...kind) kind {
+	k := kindInteger
+	for _, other := range kinds {
+		if other > k {
+			k = other
+		}
+	}
+	return k
+}
+
+func powerKind(a, b kind) kind {
+	if a == kindDouble || b == kindDouble || (a <= kindBigint && b <= kindBigint) {
+		return kindDouble
+	}
+	return kindNumeric
+}
+
+var castKinds = map[string]kind{
+	"integer": kindInteger,
+	"bigint":  kindBigint,
+	"numeric": kindNumeric,
+}
+
+// coerce implicitly converts a value to a wider type
+func coerce(v value, k kind) (value, error) {
+	switch {
+	case v.kind == k:
+		return v, nil
+	case k == kindDouble:
+		return doubleValue(v.double())
+	case v.kind < k:
+		return value{kind: k, r: v.r}, nil
+	}
+	return value{}, errors.New("can not implicitly convert to a narrower type")
+}
+
+// compare returns -1, 0 or 1 as a is less than, equal to or greater than b
+func compare(a, b value) (int, error) {
+	k := commonKind(a.kind, b.kind)
+	a, err := coerce(a, k)
+	if err != nil {
+		return 0, err
+	}
+	b, err = coerce(b, k)
+	if err != nil {
+		return 0, err
+	}
+	if k == kindDouble {
+		switch {
+		case a.f < b.f:
+			return -1, nil
+		case a.f > b.f:
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return a.r.Cmp(b.r), nil
+}
+
+func negate(v value) (value, error) {
+	switch v.kind {
+	case kindDouble:
+		return doubleValue(-v.f)
+	case kindNumeric:
+		return numericValue(new(big.Rat).Neg(v.r)), nil
+	}
+	return integerValue(v.kind, new(big.Int).Neg(v.r.Num()))
+}
+
+func arithmetic(op string, a, b value) (value, error) {
+	if op == "^" {
+		return power(a, b)
+	}
+	k := commonKind(a.kind, b.kind)
+	switch k {
+	case kindDouble:
+		x, y := a.double(), b.double()
+		switch op {
+		case "+":
+			return doubleValue(x + y)
+		case "-":
+			return doubleValue(x - y)
+		case "*":
+			return doubleValue(x * y)
+		case "/":
+			if y == 0 {
+				return value{}, errDivisionByZero
+			}
+			return doubleValue(x / y)
+		}
+	case kindNumeric:
+		x, y := a.r, b.r
+		switch op {
+		case "+":
+			return numericValue(new(big.Rat).Add(x, y)), nil
+		case "-":
+			return numericValue(new(big.Rat).Sub(x, y)), nil
+		case "*":
+			return numericValue(new(big.Rat).Mul(x, y)), nil
+		case "/":
+			if y.Sign() == 0 {
+				return value{}, errDivisionByZero
+			}
+			return numericValue(new(big.Rat).Quo(x, y)), nil
+		}
+	default:
+		x, y := a.r.Num(), b.r.Num()
+		switch op {
+		case "+":
+			return integerValue(k, new(big.Int).Add(x, y))
+		case "-":
+			return integerValue(k, new(big.Int).Sub(x, y))
+		case "*":
+			return integerValue(k, new(big.Int).Mul(x, y))
+		case "/":
+			if y.Sign() == 0 {
+				return value{}, errDivisionByZero
+			}
+			// integer division truncates towards zero
+			return integerValue(k, new(big.Int).Quo(x, y))
+		}
+	}
+	return value{}, errors.New("unknown operator: " + op)
+}
+
+// power follows postgres: there is no integer power operator so integers
+// are raised as doubles, numerics are raised exactly where possible.
+func power(a, b value) (value, error) {
+	if powerKind(a.kind, b.kind) == kindDouble {
+		x, y := a.double(), b.double()
+		if x == 0 && y < 0 {
+			return value{}, errZeroNegativePower
+		}
+		if x < 0 && y != math.Trunc(y) {
+			return value{}, errComplexPower
+		}
+		return doubleValue(math.Pow(x, y))
+	}
+	x, y := a.r, b.r
+	if x.Sign() == 0 && y.Sign() < 0 {
+		return value{}, errZeroNegativePower
+	}
+	if x.Sign() < 0 && !y.IsInt() {
+		return value{}, errComplexPower
+	}
+	if y.IsInt() && y.Num().IsInt64() {
+		n := y.Num().Int64()
+		if n >= -maxExactExponent && n <= maxExactExponent {
+			return numericValue(ratPow(x, n)), nil
+		}
+	}
+	fx, _ := x.Float64()
+	fy, _ := y.Float64()
+	f := math.Pow(fx, fy)
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return value{}, errors.New("value overflows numeric format")
+	}
+	return numericValue(new(big.Rat).SetFloat64(f)), nil
+}
+
+func ratPow(x *big.Rat, n int64) *big.Rat {
+	neg := n < 0
+	if neg {
+		n = -n
+	}
+	num := new(big.Int).Exp(x.Num(), big.NewInt(n), nil)
+	den := new(big.Int).Exp(x.Denom(), big.NewInt(n), nil)
+	if neg {
+		num, den = den, num
+	}
+	return new(big.Rat).SetFrac(num, den)
+}
+
+func ratFloor(r *big.Rat) *big.Int {
+	// Div is euclidean division, which for a positive denominator floors
+	return new(big.Int).Div(r.Num(), r.Denom())
+}
+
+func ratCeil(r *big.Rat) *big.Int {
+	return new(big.Int).Neg(ratFloor(new(big.Rat).Neg(r)))
+}
+
+// ratRound rounds half away from zero as postgres does for numerics
+func ratRound(r *big.Rat) *big.Int {
+	half := big.NewRat(1, 2)
+	if r.Sign() < 0 {
+		return new(big.Int).Neg(ratFloor(new(big.Rat).Add(new(big.Rat).Neg(r), half)))
+	}
+	return ratFloor(new(big.Rat).Add(r, half))
+}
+
+func cast(v value, typeName string) (value, error) {
+	switch typeName {
+	case "numeric":
+		r, err := v.numeric()
+		if err != nil {
+			return value{}, err
+		}
+		return numericValue(r), nil
+	case "integer", "bigint":
+		k := kindInteger
+		if typeName == "bigint" {
+			k = kindBigint
+		}
+		switch v.kind {
+		case kindDouble:
+			if math.IsInf(v.f, 0) || math.IsNaN(v.f) {
+				if k == kindBigint {
+					return value{}, errBigintRange
+				}
+				return value{}, errIntegerRange
+			}
+			// doubles are rounded to the nearest even integer
+			i, _ := new(big.Float).SetFloat64(math.RoundToEven(v.f)).Int(nil)
+			return integerValue(k, i)
+		case kindNumeric:
+			return integerValue(k, ratRound(v.r))
+		}
+		return integerValue(k, v.r.Num())
+	}
+	return value{}, errors.New("unknown type: " + typeName)
+}
@@ -0,0 +1,54 @@
+-- An anomaly is a day on which the cost of an org, space or resource was
+-- unusually high compared to the days before it. subject_guid is the guid
+-- of the org, space or resource, depending on the level, so that each is
+-- only recorded once for a day. notified_at is set once the anomaly has been
+-- sent, so that those that failed to send are sent after the next refresh.
+
+CREATE TABLE IF NOT EXISTS anomalies (
+	id bigserial PRIMARY KEY,
+	day date NOT NULL,
+	level text NOT NULL,
+	subject_guid uuid NOT NULL,
+	org_guid uuid NOT NULL,
+	org_name text NOT NULL,
+	space_guid uuid,
+	space_name text,
+	resource_guid uuid,
+	resource_name text,
+	resource_type text,
+	cost numeric NOT NULL,
+	baseline numeric NOT NULL,
+	currency_code currency_code NOT NULL,
+	detected_at timestamptz NOT NULL DEFAULT now(),
+	notified_at timestamptz,
+
+	UNIQUE (day, level, subject_guid),
+	CONSTRAINT level_must_be_valid CHECK (level in ('org', 'space', 'resource'))
+);
+
+-- anomalies found before they were notified at most once are not sent again
+DO $$
+  BEGIN
+    BEGIN
+      ALTER TABLE anomalies ADD COLUMN notified_at timestamptz;
+      UPDATE anomalies SET notified_at = detected_at;
+    EXCEPTION
+      WHEN duplicate_column THEN RAISE NOTICE 'column notified_at already exists in anomalies.';
+    END;
+  END;
+$$;
+
+CREATE INDEX IF NOT EXISTS anomalies_unnotified_idx ON anomalies (id) WHERE notified_at IS NULL;
+
+-- the last day that anomalies were detected for, which the next detection
+-- starts from whether or not any anomalies were found on it
+CREATE TABLE IF NOT EXISTS anomaly_detection_progress (
+	id boolean PRIMARY KEY DEFAULT true,
+	last_day date NOT NULL,
+
+	CONSTRAINT only_one_progress CHECK (id)
+);
+
+INSERT INTO anomaly_detection_progress (last_day)
+	SELECT max(day) FROM anomalies HAVING max(day) IS NOT NULL
+	ON CONFLICT (id) DO NOTHING;
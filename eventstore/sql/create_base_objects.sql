@@ -2,6 +2,7 @@ DROP TABLE IF EXISTS pricing_plan_components;
 DROP TABLE IF EXISTS pricing_plans;
 DROP TABLE IF EXISTS vat_rates;
 DROP TABLE IF EXISTS currency_rates;
+DROP TABLE IF EXISTS org_tax_treatments;
 
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
@@ -51,81 +52,57 @@ END; $$ LANGUAGE plpgsql;
 -- FIXME: END
 
 
--- compile formula into sql
-CREATE OR REPLACE FUNCTION compile_formula( formula text ) RETURNS text AS $$
-DECLARE
-	out text;
-BEGIN
-	out := coalesce(lower(formula), '0');
-	out := regexp_replace(out, '\$memory_in_mb', '($1::numeric)', 'g');
-	out := regexp_replace(out, '\$storage_in_mb', '($2::numeric)', 'g');
-	out := regexp_replace(out, '\$number_of_nodes', '($3::numeric)', 'g');
-	out := regexp_replace(out, '\$time_in_seconds', '($4::numeric)', 'g');
-	out := (select 'select (' || out || ')::numeric;');
-	return out;
-END; $$ LANGUAGE plpgsql;
-
--- validate formula whitelist's selected terms that can be evaluated in
--- "formulas" this should not be considered "safe" for untrusted input it is
--- intended as a technique to restrict formulas from becoming complex SQL
--- expressions
-CREATE OR REPLACE FUNCTION validate_formula() RETURNS trigger AS $$
-DECLARE
-	invalid_formula text;
-	illegal_token text;
-	dummy_price numeric;
-BEGIN
-	IF (NEW.formula = '') THEN
-		RAISE EXCEPTION 'formula can not be empty';
-	END IF;
-	invalid_formula := lower(NEW.formula);
-	invalid_formula := (select regexp_replace(invalid_formula, '::(integer|bigint|numeric)', '#', 'g'));
-	invalid_formula := (select regexp_replace(invalid_formula, '([0-9]+)?\.([0-9]+)', '#', 'g'));
-	invalid_formula := (select regexp_replace(invalid_formula, '([0-9]+)', '#', 'g'));
-	invalid_formula := (select regexp_replace(invalid_formula, '\$memory_in_mb', '#', 'g'));
-	invalid_formula := (select regexp_replace(invalid_formula, '\$storage_in_mb', '#', 'g'));
-	invalid_formula := (select regexp_replace(invalid_formula, '\$time_in_seconds', '#', 'g'));
-	invalid_formula := (select regexp_replace(invalid_formula, '\$number_of_nodes', '#', 'g'));
-	invalid_formula := (select regexp_replace(invalid_formula, 'ceil', '#', 'g'));
-	invalid_formula := (select regexp_replace(invalid_formula, '\(|\)', '#', 'g'));
-	invalid_formula := (select regexp_replace(invalid_formula, '\*', '#', 'g'));
-	invalid_formula := (select regexp_replace(invalid_formula, '\-', '#', 'g'));
-	invalid_formula := (select regexp_replace(invalid_formula, '\+', '#', 'g'));
-	invalid_formula := (select regexp_replace(invalid_formula, '\/', '#', 'g'));
-	invalid_formula := (select regexp_replace(invalid_formula, '\^', '#', 'g'));
-	invalid_formula := (select regexp_replace(invalid_formula, '\s+', '#', 'g'));
-	invalid_formula := (select regexp_replace(invalid_formula, '#+', '', 'g'));
-	IF (invalid_formula != '') THEN
-		illegal_token := (select * from regexp_split_to_table(invalid_formula, '\s+') limit 1);
-		RAISE EXCEPTION 'illegal token in formula: %', illegal_token;
-	END IF;
-	-- attempt to use the formula to ensure it works with common edge case inputs
-	dummy_price := (select eval_formula(0, 0, 0, tstzrange(now(), now()), NEW.formula));
-	dummy_price := (select eval_formula(1, 1, 1, tstzrange(now(), now() + '1 second'), NEW.formula));
-	dummy_price := (select eval_formula(null, null, null, null, NEW.formula));
-	RETURN NEW;
-END;
-$$ language plpgsql;
+DROP FUNCTION IF EXISTS validate_formula();
+DROP FUNCTION IF EXISTS compile_formula(text);
 
+DROP FUNCTION IF EXISTS eval_formula(numeric, numeric, integer, tstzrange, text);
 
+-- evaluate a formula that has been compiled to sql by the formula package,
+-- compiled formulas refer to their variables as positional parameters.
+-- $month_seconds is the length of the UTC calendar month containing the
+-- start of the duration, whatever the billing period of the org, see
+-- formula.MonthSeconds.
 CREATE OR REPLACE FUNCTION eval_formula(
 	memory_in_mb numeric,
 	storage_in_mb numeric,
 	number_of_nodes integer,
+	disk_in_mb numeric,
 	duration tstzrange,
-	formula text
+	event_count integer,
+	compiled_formula text
 ) returns numeric AS $$
 DECLARE
 	out numeric;
+	month_start timestamp := date_trunc('month', lower(duration) at time zone 'UTC');
 BEGIN
-	execute compile_formula(formula) into out using
-		coalesce(memory_in_mb, 0),
-		coalesce(storage_in_mb, 0),
-		coalesce(number_of_nodes, 0),
-		extract(epoch from (upper(duration) - lower(duration)));
+	execute 'select (' || compiled_formula || ')::numeric;' into out using
+		coalesce(memory_in_mb, 0)::numeric,
+		coalesce(storage_in_mb, 0)::numeric,
+		coalesce(number_of_nodes, 0)::numeric,
+		coalesce(extract(epoch from (upper(duration) - lower(duration))), 0)::numeric,
+		coalesce(disk_in_mb, 0)::numeric,
+		coalesce(extract(epoch from ((month_start + interval '1 month') - month_start)), 0)::numeric,
+		coalesce(event_count, 0)::numeric;
 	return out;
 END; $$ LANGUAGE plpgsql IMMUTABLE;
 
+-- divide every amount in a billable event price by rate, converting it from
+-- the base currency to currency_code
+CREATE OR REPLACE FUNCTION convert_price(price jsonb, rate numeric, currency_code text) RETURNS jsonb AS $$
+	select jsonb_build_object(
+		'ex_vat', ((price->>'ex_vat')::numeric / rate)::text,
+		'inc_vat', ((price->>'inc_vat')::numeric / rate)::text,
+		'details', coalesce((
+			select jsonb_agg(d.detail || jsonb_build_object(
+				'ex_vat', ((d.detail->>'ex_vat')::numeric / rate)::text,
+				'inc_vat', ((d.detail->>'inc_vat')::numeric / rate)::text,
+				'currency_code', currency_code
+			) order by d.n)
+			from jsonb_array_elements(price->'details') with ordinality as d(detail, n)
+		), '[]'::jsonb)
+	);
+$$ LANGUAGE SQL IMMUTABLE;
+
 -------------------------------------- SCHEMA
 
 CREATE TABLE pricing_plans (
@@ -135,6 +112,7 @@ CREATE TABLE pricing_plans (
 	memory_in_mb integer NOT NULL DEFAULT 0,
 	number_of_nodes integer NOT NULL DEFAULT 0,
 	storage_in_mb integer NOT NULL DEFAULT 0,
+	disk_in_mb integer NOT NULL DEFAULT 0,
 
 	PRIMARY KEY (plan_guid, valid_from),
 	CONSTRAINT name_must_not_be_blank CHECK (length(trim(name)) > 0),
@@ -177,17 +155,33 @@ CREATE TABLE IF NOT EXISTS vat_rates (
 	)
 );
 
+-- a null vat_code means the org is taxed using the vat_code of each component
+CREATE TABLE org_tax_treatments (
+	org_guid uuid NOT NULL,
+	valid_from timestamptz NOT NULL,
+	vat_code vat_code,
+
+	PRIMARY KEY (org_guid, valid_from),
+	CONSTRAINT valid_from_start_of_month CHECK (
+	  (extract (day from valid_from)) = 1 AND
+	  (extract (hour from valid_from)) = 0 AND
+	  (extract (minute from valid_from)) = 0 AND
+	  (extract (second from valid_from)) = 0
+	)
+);
+
 CREATE TABLE pricing_plan_components (
 	plan_guid uuid NOT NULL,
 	valid_from timestamptz NOT NULL,
 	name text NOT NULL,
 	formula text NOT NULL,
+	compiled_formula text NOT NULL,
 	vat_code vat_code NOT NULL, 
 	currency_code currency_code NOT NULL,
 
 	PRIMARY KEY (plan_guid, valid_from, name),
 	FOREIGN KEY (plan_guid, valid_from) REFERENCES pricing_plans (plan_guid, valid_from) ON DELETE CASCADE, 
 	CONSTRAINT name_must_not_be_blank CHECK (length(trim(name)) > 0),
-	CONSTRAINT formula_must_not_be_blank CHECK (length(trim(formula)) > 0)
+	CONSTRAINT formula_must_not_be_blank CHECK (length(trim(formula)) > 0),
+	CONSTRAINT compiled_formula_must_not_be_blank CHECK (length(trim(compiled_formula)) > 0)
 );
-CREATE TRIGGER tgr_ppc_validate_formula BEFORE INSERT OR UPDATE ON pricing_plan_components FOR EACH ROW EXECUTE PROCEDURE validate_formula();
@@ -1,4 +1,5 @@
 DROP FUNCTION IF EXISTS generate_billable_event_components();
+DROP FUNCTION IF EXISTS generate_billable_event_components(uuid[]);
 
 CREATE TABLE billable_event_components_temp (
 	event_guid uuid NOT NULL,
@@ -16,8 +17,11 @@ CREATE TABLE billable_event_components_temp (
 	number_of_nodes integer NOT NULL,
 	memory_in_mb numeric NOT NULL,
 	storage_in_mb numeric NOT NULL,
+	disk_in_mb numeric NOT NULL,
+	event_count integer NOT NULL,
 	component_name text NOT NULL,
 	component_formula text NOT NULL,
+	component_compiled_formula text NOT NULL,
 	currency_code currency_code NOT NULL,
 	currency_rate numeric NOT NULL,
 	vat_code vat_code NOT NULL,
@@ -28,7 +32,8 @@ CREATE TABLE billable_event_components_temp (
 	CONSTRAINT no_empty_duration CHECK (not isempty(duration))
 );
 
-CREATE OR REPLACE FUNCTION generate_billable_event_components() RETURNS SETOF billable_event_components_temp AS $$
+-- when resource_guids is given only the components for those resources are generated
+CREATE OR REPLACE FUNCTION generate_billable_event_components(resource_guids uuid[] DEFAULT NULL) RETURNS SETOF billable_event_components_temp AS $$
 	with
 	valid_pricing_plans as (
 		select
@@ -56,6 +61,26 @@ CREATE OR REPLACE FUNCTION generate_billable_event_components() RETURNS SETOF bi
 			)) as valid_for
 		from
 			vat_rates
+	),
+	valid_org_tax_treatments as (
+		select
+			*,
+			tstzrange(valid_from, lead(valid_from, 1, 'infinity') over (
+				partition by org_guid order by valid_from rows between current row and 1 following
+			)) as valid_for
+		from
+			org_tax_treatments
+		union all
+		-- before its first treatment an org is taxed using the vat_code of each component
+		select
+			org_guid,
+			'-infinity'::timestamptz,
+			null::vat_code,
+			tstzrange('-infinity', min(valid_from))
+		from
+			org_tax_treatments
+		group by
+			org_guid
 	)
 	select
 		ev.event_guid,
@@ -66,15 +91,22 @@ CREATE OR REPLACE FUNCTION generate_billable_event_components() RETURNS SETOF bi
 		ev.org_name,
 		ev.space_guid,
 		ev.space_name,
-		ev.duration * vpp.valid_for * vcr.valid_for * vvr.valid_for as duration,
+		ev.duration * vpp.valid_for * coalesce(vot.valid_for, '(,)') * vcr.valid_for * vvr.valid_for as duration,
 		vpp.plan_guid as plan_guid,
 		vpp.valid_from as plan_valid_from,
 		vpp.name as plan_name,
 		coalesce(ev.number_of_nodes, vpp.number_of_nodes)::integer as number_of_nodes,
 		coalesce(ev.memory_in_mb, vpp.memory_in_mb)::numeric as memory_in_mb,
 		coalesce(ev.storage_in_mb, vpp.storage_in_mb)::numeric as storage_in_mb,
+		coalesce(ev.disk_in_mb, vpp.disk_in_mb)::numeric as disk_in_mb,
+		-- only the component that covers the start of the event counts it
+		(case
+			when lower(ev.duration * vpp.valid_for * coalesce(vot.valid_for, '(,)') * vcr.valid_for * vvr.valid_for) = lower(ev.duration) then 1
+			else 0
+		end) as event_count,
 		ppc.name AS component_name,
 		ppc.formula as component_formula,
+		ppc.compiled_formula as component_compiled_formula,
 		vcr.code as currency_code,
 		vcr.rate as currency_rate,
 		vvr.code as vat_code,
@@ -83,8 +115,13 @@ CREATE OR REPLACE FUNCTION generate_billable_event_components() RETURNS SETOF bi
 			coalesce(ev.memory_in_mb, vpp.memory_in_mb)::numeric,
 			coalesce(ev.storage_in_mb, vpp.storage_in_mb)::numeric,
 			coalesce(ev.number_of_nodes, vpp.number_of_nodes)::integer,
-			ev.duration * vpp.valid_for * vcr.valid_for * vvr.valid_for,
-			ppc.formula
+			coalesce(ev.disk_in_mb, vpp.disk_in_mb)::numeric,
+			ev.duration * vpp.valid_for * coalesce(vot.valid_for, '(,)') * vcr.valid_for * vvr.valid_for,
+			(case
+				when lower(ev.duration * vpp.valid_for * coalesce(vot.valid_for, '(,)') * vcr.valid_for * vvr.valid_for) = lower(ev.duration) then 1
+				else 0
+			end),
+			ppc.compiled_formula
 		) * vcr.rate) as cost_for_duration
 	from
 		events ev
@@ -94,18 +131,26 @@ CREATE OR REPLACE FUNCTION generate_billable_event_components() RETURNS SETOF bi
 	left join
 		pricing_plan_components ppc on ppc.plan_guid = vpp.plan_guid
 		and ppc.valid_from = vpp.valid_from
+	left join
+		valid_org_tax_treatments vot on vot.org_guid = ev.org_guid
+		and vot.valid_for && (ev.duration * vpp.valid_for)
 	left join
 		valid_currency_rates vcr on vcr.code = ppc.currency_code
-		and vcr.valid_for && (ev.duration * vpp.valid_for)
+		and vcr.valid_for && (ev.duration * vpp.valid_for * coalesce(vot.valid_for, '(,)'))
 	left join
-		valid_vat_rates vvr on vvr.code = ppc.vat_code
-		and vvr.valid_for && (ev.duration * vpp.valid_for * vcr.valid_for)
+		valid_vat_rates vvr on vvr.code = coalesce(vot.vat_code, ppc.vat_code)
+		and vvr.valid_for && (ev.duration * vpp.valid_for * coalesce(vot.valid_for, '(,)') * vcr.valid_for)
+	where
+		resource_guids is null or ev.resource_guid = any(resource_guids)
 ; $$ LANGUAGE SQL;
 
 INSERT INTO billable_event_components_temp (select * from generate_billable_event_components());
 
 CREATE INDEX billable_event_components_temp_org_idx on billable_event_components_temp (org_guid);
 CREATE INDEX billable_event_components_temp_space_idx on billable_event_components_temp (space_guid);
+CREATE INDEX billable_event_components_temp_resource_idx on billable_event_components_temp (resource_guid);
+CREATE INDEX billable_event_components_temp_resource_type_idx on billable_event_components_temp (resource_type);
+CREATE INDEX billable_event_components_temp_plan_idx on billable_event_components_temp (plan_guid);
 CREATE INDEX billable_event_components_temp_duration_idx on billable_event_components_temp using gist (duration);
 
 DROP TABLE IF EXISTS billable_event_components;
@@ -113,4 +158,7 @@ ALTER TABLE billable_event_components_temp RENAME TO billable_event_components;
 ALTER INDEX billable_event_components_temp_pkey RENAME TO billable_event_components_pkey;
 ALTER INDEX billable_event_components_temp_org_idx RENAME TO billable_event_components_org_idx;
 ALTER INDEX billable_event_components_temp_space_idx RENAME TO billable_event_components_space_idx;
+ALTER INDEX billable_event_components_temp_resource_idx RENAME TO billable_event_components_resource_idx;
+ALTER INDEX billable_event_components_temp_resource_type_idx RENAME TO billable_event_components_resource_type_idx;
+ALTER INDEX billable_event_components_temp_plan_idx RENAME TO billable_event_components_plan_idx;
 ALTER INDEX billable_event_components_temp_duration_idx RENAME TO billable_event_components_duration_idx;
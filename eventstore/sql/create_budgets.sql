@@ -0,0 +1,30 @@
+-- A budget is how much an org expects to spend ex VAT each billing period.
+-- An alert is recorded the first time in a period that the org's spend
+-- reaches each threshold of its budget, and is marked as sent once it has
+-- been delivered. The month of an alert is the full date its billing period
+-- starts on in the org's time zone, which is not always the 1st.
+
+CREATE TABLE IF NOT EXISTS budgets (
+	org_guid uuid PRIMARY KEY,
+	amount numeric NOT NULL,
+	thresholds integer[] NOT NULL,
+	updated_at timestamptz NOT NULL DEFAULT now(),
+
+	CONSTRAINT amount_must_be_positive CHECK (amount > 0),
+	CONSTRAINT thresholds_must_be_set CHECK (cardinality(thresholds) > 0)
+);
+
+CREATE TABLE IF NOT EXISTS budget_alerts (
+	id bigserial PRIMARY KEY,
+	org_guid uuid NOT NULL,
+	org_name text NOT NULL,
+	month date NOT NULL,
+	threshold integer NOT NULL,
+	amount numeric NOT NULL,
+	spent numeric NOT NULL,
+	currency_code currency_code NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	sent_at timestamptz,
+
+	UNIQUE (org_guid, month, threshold)
+);
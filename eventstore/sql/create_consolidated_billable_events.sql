@@ -3,27 +3,21 @@ CREATE TABLE IF NOT EXISTS consolidation_history (
   created_at timestamptz NOT NULL,
 
   PRIMARY KEY (consolidated_range),
-  CONSTRAINT no_empty_consolidated_range CHECK (not isempty(consolidated_range)),
-
-  CONSTRAINT range_from_start_of_month CHECK (
-    (extract (day from lower(consolidated_range))) = 1 AND
-    (extract (hour from lower(consolidated_range))) = 0 AND
-    (extract (minute from lower(consolidated_range))) = 0 AND
-    (extract (second from lower(consolidated_range))) = 0
-  ),
-
-  CONSTRAINT range_to_end_of_month CHECK (
-    (extract (day from upper(consolidated_range))) = 1 AND
-    (extract (hour from upper(consolidated_range))) = 0 AND
-    (extract (minute from upper(consolidated_range))) = 0 AND
-    (extract (second from upper(consolidated_range))) = 0
-  ),
-
-  CONSTRAINT range_exactly_one_month CHECK (
-    (lower(consolidated_range) + interval '1 month') = (upper(consolidated_range))
-  )
+  CONSTRAINT no_empty_consolidated_range CHECK (not isempty(consolidated_range))
 );
 
+-- consolidated ranges are the billing periods of the orgs, which are not
+-- always UTC calendar months
+ALTER TABLE consolidation_history DROP CONSTRAINT IF EXISTS range_from_start_of_month;
+ALTER TABLE consolidation_history DROP CONSTRAINT IF EXISTS range_to_end_of_month;
+ALTER TABLE consolidation_history DROP CONSTRAINT IF EXISTS range_exactly_one_month;
+
+-- the orgs consolidated for each range, which are the orgs in org_guids or,
+-- if it is null, every org apart from those in excluded_org_guids. Ranges
+-- consolidated before these were recorded were for every org.
+ALTER TABLE consolidation_history ADD COLUMN IF NOT EXISTS org_guids uuid[];
+ALTER TABLE consolidation_history ADD COLUMN IF NOT EXISTS excluded_org_guids uuid[];
+
 CREATE TABLE IF NOT EXISTS consolidated_billable_events (
   consolidated_range tstzrange REFERENCES consolidation_history(consolidated_range) NOT NULL,
 
@@ -61,3 +55,9 @@ DO $$
     END;
   END;
 $$;
+
+CREATE INDEX IF NOT EXISTS consolidated_billable_events_org_idx ON consolidated_billable_events (org_guid);
+CREATE INDEX IF NOT EXISTS consolidated_billable_events_space_idx ON consolidated_billable_events (space_guid);
+CREATE INDEX IF NOT EXISTS consolidated_billable_events_resource_idx ON consolidated_billable_events (resource_guid);
+CREATE INDEX IF NOT EXISTS consolidated_billable_events_resource_type_idx ON consolidated_billable_events (resource_type);
+CREATE INDEX IF NOT EXISTS consolidated_billable_events_plan_idx ON consolidated_billable_events (plan_guid);
@@ -1,15 +1,48 @@
+-- vat_code and currency_code used to be enums of fixed sets of codes, any
+-- columns still using them are converted to the domains below. Codes are
+-- checked in more detail before they are stored, currency codes against the
+-- list of ISO 4217 currencies.
+DO $$ DECLARE
+	t text;
+BEGIN
+FOREACH t IN ARRAY ARRAY['vat_code', 'currency_code'] LOOP
+	IF EXISTS (SELECT 1 FROM pg_type WHERE typname = t AND typtype = 'e') THEN
+		EXECUTE format('ALTER TYPE %I RENAME TO %I', t, t || '_enum');
+	END IF;
+END LOOP;
+END $$;
+
 DO $$ BEGIN
-CREATE TYPE vat_code AS ENUM ('Standard', 'Reduced', 'Zero');
+CREATE DOMAIN vat_code AS text
+	CONSTRAINT vat_code_format CHECK (VALUE ~ '^[A-Za-z][A-Za-z0-9_-]{0,31}$');
 EXCEPTION
     WHEN duplicate_object THEN null;
 END $$;
 
 DO $$ BEGIN
-CREATE TYPE currency_code AS ENUM ('USD', 'GBP', 'EUR');
+CREATE DOMAIN currency_code AS text
+	CONSTRAINT currency_code_format CHECK (VALUE ~ '^[A-Z]{3}$');
 EXCEPTION
     WHEN duplicate_object THEN null;
 END $$;
 
+DO $$ DECLARE
+	col record;
+BEGIN
+FOR col IN
+	SELECT table_name, column_name, udt_name
+	FROM information_schema.columns
+	WHERE table_schema = current_schema() AND udt_name IN ('vat_code_enum', 'currency_code_enum')
+LOOP
+	EXECUTE format(
+		'ALTER TABLE %I ALTER COLUMN %I TYPE %I USING %I::text',
+		col.table_name, col.column_name, left(col.udt_name, -length('_enum')), col.column_name
+	);
+END LOOP;
+DROP TYPE IF EXISTS vat_code_enum;
+DROP TYPE IF EXISTS currency_code_enum;
+END $$;
+
 DO $$ BEGIN
 CREATE TYPE resource_state AS ENUM ('STARTED', 'STOPPED');
 EXCEPTION
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS event_watermarks (
+	source text PRIMARY KEY,
+	last_sequence integer NOT NULL,
+	processed_at timestamptz NOT NULL,
+
+	CONSTRAINT last_sequence_not_negative CHECK (last_sequence >= 0)
+);
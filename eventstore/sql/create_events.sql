@@ -1,3 +1,5 @@
+DROP FUNCTION IF EXISTS generate_events(uuid[]);
+
 CREATE TABLE events_temp (
 	event_guid uuid PRIMARY KEY NOT NULL,
 	resource_guid uuid NOT NULL,
@@ -15,6 +17,7 @@ CREATE TABLE events_temp (
 	number_of_nodes integer,
 	memory_in_mb integer,
 	storage_in_mb integer,
+	disk_in_mb integer,
 
 	CONSTRAINT duration_must_not_be_empty CHECK (not isempty(duration))
 );
@@ -22,7 +25,9 @@ CREATE TABLE events_temp (
 -- extract useful stuff from usage events
 -- we treat both apps and services as "resources" so normalize the fields
 -- we normalize states to just STARTED/STOPPED because we treat consecutive STARTED to mean "update"
-INSERT INTO events_temp with
+-- when resource_guids is given only the events for those resources are generated
+CREATE OR REPLACE FUNCTION generate_events(resource_guids uuid[]) RETURNS SETOF events_temp AS $$
+	with
 	raw_events as (
 		(
 			select
@@ -42,12 +47,14 @@ INSERT INTO events_temp with
 				coalesce(raw_message->>'instance_count', '1')::numeric as number_of_nodes,
 				coalesce(raw_message->>'memory_in_mb_per_instance', '0')::numeric as memory_in_mb,
 				'0'::numeric as storage_in_mb,
+				coalesce(raw_message->>'disk_in_mb_per_instance', '0')::numeric as disk_in_mb,
 				(raw_message->>'state')::resource_state as state
 			from
 				app_usage_events
 			where
 				(raw_message->>'state' = 'STARTED' or raw_message->>'state' = 'STOPPED')
 				and raw_message->>'space_name' !~ '^(SMOKE|ACC|CATS|PERF)-' -- FIXME: this is open to abuse
+				and (resource_guids is null or (raw_message->>'app_guid')::uuid = any(resource_guids))
 		) union all (
 			select
 				id as event_sequence,
@@ -66,6 +73,7 @@ INSERT INTO events_temp with
 				NULL::numeric as number_of_nodes,
 				NULL::numeric as memory_in_mb,
 				NULL::numeric as storage_in_mb,
+				NULL::numeric as disk_in_mb,
 				(case
 					when (raw_message->>'state') = 'CREATED' then 'STARTED'
 					when (raw_message->>'state') = 'DELETED' then 'STOPPED'
@@ -76,6 +84,7 @@ INSERT INTO events_temp with
 			where
 				raw_message->>'service_instance_type' = 'managed_service_instance'
 				and raw_message->>'space_name' !~ '^(SMOKE|ACC|CATS|PERF)-' -- FIXME: this is open to abuse
+				and (resource_guids is null or (raw_message->>'service_instance_guid')::uuid = any(resource_guids))
 		) union all (
 			select
 				id as event_sequence,
@@ -94,6 +103,7 @@ INSERT INTO events_temp with
 				coalesce(raw_message->>'instance_count', '1')::numeric as number_of_nodes,
 				coalesce(raw_message->>'memory_in_mb_per_instance', '0')::numeric as memory_in_mb,
 				'0'::numeric as storage_in_mb,
+				coalesce(raw_message->>'disk_in_mb_per_instance', '0')::numeric as disk_in_mb,
 				(case
 					when (raw_message->>'state') = 'TASK_STARTED' then 'STARTED'
 					when (raw_message->>'state') = 'TASK_STOPPED' then 'STOPPED'
@@ -103,6 +113,7 @@ INSERT INTO events_temp with
 			where
 				(raw_message->>'state' = 'TASK_STARTED' or raw_message->>'state' = 'TASK_STOPPED')
 				and raw_message->>'space_name' !~ '^(SMOKE|ACC|CATS|PERF)-' -- FIXME: this is open to abuse
+				and (resource_guids is null or (raw_message->>'task_guid')::uuid = any(resource_guids))
 		) union all (
 			select
 				id as event_sequence,
@@ -121,6 +132,7 @@ INSERT INTO events_temp with
 				'1'::numeric as number_of_nodes,
 				coalesce(raw_message->>'memory_in_mb_per_instance', '0')::numeric as memory_in_mb,
 				'0'::numeric as storage_in_mb,
+				coalesce(raw_message->>'disk_in_mb_per_instance', '0')::numeric as disk_in_mb,
 				(case
 					when (raw_message->>'state') = 'STAGING_STARTED' then 'STARTED'
 					when (raw_message->>'state') = 'STAGING_STOPPED' then 'STOPPED'
@@ -130,6 +142,7 @@ INSERT INTO events_temp with
 			where
 				(raw_message->>'state' = 'STAGING_STARTED' or raw_message->>'state' = 'STAGING_STOPPED')
 				and raw_message->>'space_name' !~ '^(SMOKE|ACC|CATS|PERF)-' -- FIXME: this is open to abuse
+				and (resource_guids is null or (raw_message->>'parent_app_guid')::uuid = any(resource_guids))
 		) union all (
 			select
 				s.id as event_sequence,
@@ -154,6 +167,7 @@ INSERT INTO events_temp with
 				NULL::numeric as number_of_nodes,
 				(pg_size_bytes(c.raw_message->'data'->>'memory') / 1024 / 1024)::numeric as memory_in_mb,
 				(pg_size_bytes(c.raw_message->'data'->>'storage') / 1024 / 1024)::numeric as storage_in_mb,
+				NULL::numeric as disk_in_mb,
 				'STARTED'::resource_state as state
 			from
 				compose_audit_events c
@@ -166,6 +180,10 @@ INSERT INTO events_temp with
 				) AND s.raw_message->>'state' = 'CREATED'
 			where
 				s.raw_message->>'space_name' !~ '^(SMOKE|ACC|CATS|PERF)-' -- FIXME: this is open to abuse
+				and (resource_guids is null or substring(
+					c.raw_message->'data'->>'deployment'
+					from '[a-zA-Z0-9]{8}-[a-zA-Z0-9]{4}-[a-zA-Z0-9]{4}-[a-zA-Z0-9]{4}-[a-zA-Z0-9]{12}$'
+				)::uuid = any(resource_guids))
 		)
 	),
 	raw_events_with_injected_values as (
@@ -201,6 +219,7 @@ INSERT INTO events_temp with
 					array_agg(storage_in_mb) over prev_events
 				, NULL))[1]
 			) as storage_in_mb,
+			disk_in_mb,
 			state
 		from
 			raw_events
@@ -288,7 +307,8 @@ INSERT INTO events_temp with
 		coalesce(vs.label, ev.service_name) as service_name,
 		number_of_nodes,
 		memory_in_mb,
-		storage_in_mb
+		storage_in_mb,
+		disk_in_mb
 	from
 		event_ranges ev
 	left join
@@ -308,13 +328,16 @@ INSERT INTO events_temp with
 		and not isempty(duration)
 	order by
 		event_sequence, event_guid
-;
+; $$ LANGUAGE SQL;
+
+INSERT INTO events_temp (select * from generate_events(NULL));
 
 CREATE INDEX events_org_temp_idx ON events_temp (org_guid);
 CREATE INDEX events_space_temp_idx ON events_temp (space_guid);
 CREATE INDEX events_resource_temp_idx ON events_temp (resource_guid);
 CREATE INDEX events_duration_temp_idx ON events_temp using gist (duration);
 CREATE INDEX events_plan_temp_idx ON events_temp (plan_guid);
+CREATE INDEX events_resource_type_temp_idx ON events_temp (resource_type);
 
 DROP TABLE IF EXISTS events;
 ALTER TABLE events_temp RENAME TO events;
@@ -324,3 +347,4 @@ ALTER INDEX events_space_temp_idx RENAME TO events_space_idx;
 ALTER INDEX events_resource_temp_idx RENAME TO events_resource_idx;
 ALTER INDEX events_duration_temp_idx RENAME TO events_duration_idx;
 ALTER INDEX events_plan_temp_idx RENAME TO events_plan_idx;
+ALTER INDEX events_resource_type_temp_idx RENAME TO events_resource_type_idx;
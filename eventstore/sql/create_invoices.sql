@@ -0,0 +1,103 @@
+-- An invoice is generated for each org with billable events in each
+-- consolidated month. Invoices, their lines and VAT summaries never change
+-- once generated, apart from the invoice moving from draft to issued and from
+-- issued to credited.
+
+CREATE TABLE IF NOT EXISTS invoices (
+	number bigint PRIMARY KEY,
+	org_guid uuid NOT NULL,
+	org_name text NOT NULL,
+	billing_period tstzrange NOT NULL REFERENCES consolidation_history (consolidated_range),
+	currency_code currency_code NOT NULL,
+	status text NOT NULL DEFAULT 'draft',
+	total_ex_vat numeric NOT NULL,
+	total_vat numeric NOT NULL,
+	total_inc_vat numeric NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	issued_at timestamptz,
+	issued_by text,
+	credited_at timestamptz,
+	credited_by text,
+
+	UNIQUE (org_guid, billing_period),
+	CONSTRAINT status_must_be_valid CHECK (status in ('draft', 'issued', 'credited')),
+	CONSTRAINT issued_must_be_recorded CHECK ((status = 'draft') = (issued_at is null)),
+	CONSTRAINT credited_must_be_recorded CHECK ((status = 'credited') = (credited_at is not null))
+);
+
+-- invoice numbers are allocated from a single counter row, which is locked
+-- until the transaction generating the invoice ends, so that there are no
+-- gaps in the numbers if it is rolled back. They were allocated from a
+-- sequence before, which is replaced.
+CREATE TABLE IF NOT EXISTS invoice_number_counter (
+	id boolean PRIMARY KEY DEFAULT true,
+	last_number bigint NOT NULL,
+
+	CONSTRAINT only_one_counter CHECK (id)
+);
+
+INSERT INTO invoice_number_counter (last_number)
+	SELECT coalesce(max(number), 0) FROM invoices
+	ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE invoices ALTER COLUMN number DROP DEFAULT;
+DROP SEQUENCE IF EXISTS invoice_number_seq;
+
+CREATE TABLE IF NOT EXISTS invoice_lines (
+	invoice_number bigint NOT NULL REFERENCES invoices (number),
+	space_guid uuid NOT NULL,
+	space_name text NOT NULL,
+	plan_guid uuid NOT NULL,
+	plan_name text NOT NULL,
+	vat_code vat_code NOT NULL,
+	vat_rate numeric NOT NULL,
+	ex_vat numeric NOT NULL,
+
+	PRIMARY KEY (invoice_number, space_guid, plan_guid, plan_name, vat_code, vat_rate)
+);
+
+CREATE TABLE IF NOT EXISTS invoice_vat_summaries (
+	invoice_number bigint NOT NULL REFERENCES invoices (number),
+	vat_code vat_code NOT NULL,
+	vat_rate numeric NOT NULL,
+	ex_vat numeric NOT NULL,
+	vat numeric NOT NULL,
+
+	PRIMARY KEY (invoice_number, vat_code, vat_rate)
+);
+
+CREATE OR REPLACE FUNCTION reject_invoice_changes() RETURNS trigger AS $$ BEGIN
+	IF TG_OP = 'UPDATE' AND TG_TABLE_NAME = 'invoices' THEN
+		IF (NEW.number, NEW.org_guid, NEW.org_name, NEW.billing_period, NEW.currency_code,
+			NEW.total_ex_vat, NEW.total_vat, NEW.total_inc_vat, NEW.created_at)
+			IS DISTINCT FROM
+			(OLD.number, OLD.org_guid, OLD.org_name, OLD.billing_period, OLD.currency_code,
+			OLD.total_ex_vat, OLD.total_vat, OLD.total_inc_vat, OLD.created_at) THEN
+			RAISE EXCEPTION 'invoice % can not be changed', OLD.number USING
+				hint = 'only the status of an invoice can change';
+		END IF;
+		IF NOT ((OLD.status = 'draft' AND NEW.status = 'issued') OR
+			(OLD.status = 'issued' AND NEW.status = 'credited')) THEN
+			RAISE EXCEPTION 'invoice % can not go from % to %', OLD.number, OLD.status, NEW.status;
+		END IF;
+		RETURN NEW;
+	END IF;
+	RAISE EXCEPTION '% can not be changed', TG_TABLE_NAME USING
+		hint = 'invoices are immutable, credit an issued invoice instead';
+END; $$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS immutable ON invoices;
+CREATE TRIGGER immutable BEFORE UPDATE OR DELETE ON invoices
+	FOR EACH ROW EXECUTE PROCEDURE reject_invoice_changes();
+
+DROP TRIGGER IF EXISTS immutable_truncate ON invoices;
+CREATE TRIGGER immutable_truncate BEFORE TRUNCATE ON invoices
+	FOR EACH STATEMENT EXECUTE PROCEDURE reject_invoice_changes();
+
+DROP TRIGGER IF EXISTS immutable ON invoice_lines;
+CREATE TRIGGER immutable BEFORE UPDATE OR DELETE OR TRUNCATE ON invoice_lines
+	FOR EACH STATEMENT EXECUTE PROCEDURE reject_invoice_changes();
+
+DROP TRIGGER IF EXISTS immutable ON invoice_vat_summaries;
+CREATE TRIGGER immutable BEFORE UPDATE OR DELETE OR TRUNCATE ON invoice_vat_summaries
+	FOR EACH STATEMENT EXECUTE PROCEDURE reject_invoice_changes();
@@ -0,0 +1,92 @@
+-- Pricing plans, VAT rates, currency rates and org tax treatments are
+-- recorded as append only versions along with who made each change and when.
+-- The latest version for each plan_guid/code/org_guid and valid_from is
+-- copied into the pricing_plans, pricing_plan_components, vat_rates,
+-- currency_rates and org_tax_treatments tables whenever a version is added,
+-- and it is those tables' constraints that validate them.
+
+CREATE TABLE IF NOT EXISTS pricing_plan_versions (
+	version serial PRIMARY KEY,
+	plan_guid uuid NOT NULL,
+	valid_from timestamptz NOT NULL,
+	name text NOT NULL,
+	memory_in_mb integer NOT NULL DEFAULT 0,
+	number_of_nodes integer NOT NULL DEFAULT 0,
+	storage_in_mb integer NOT NULL DEFAULT 0,
+	disk_in_mb integer NOT NULL DEFAULT 0,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	created_by text NOT NULL,
+
+	CONSTRAINT created_by_must_not_be_blank CHECK (length(trim(created_by)) > 0)
+);
+
+CREATE INDEX IF NOT EXISTS pricing_plan_versions_plan_guid_idx ON pricing_plan_versions (plan_guid, valid_from);
+
+CREATE TABLE IF NOT EXISTS pricing_plan_component_versions (
+	version integer NOT NULL REFERENCES pricing_plan_versions (version),
+	name text NOT NULL,
+	formula text NOT NULL,
+	compiled_formula text NOT NULL,
+	vat_code vat_code NOT NULL,
+	currency_code currency_code NOT NULL,
+
+	PRIMARY KEY (version, name)
+);
+
+CREATE TABLE IF NOT EXISTS vat_rate_versions (
+	version serial PRIMARY KEY,
+	code vat_code NOT NULL,
+	valid_from timestamptz NOT NULL,
+	rate numeric NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	created_by text NOT NULL,
+
+	CONSTRAINT created_by_must_not_be_blank CHECK (length(trim(created_by)) > 0)
+);
+
+CREATE TABLE IF NOT EXISTS currency_rate_versions (
+	version serial PRIMARY KEY,
+	code currency_code NOT NULL,
+	valid_from timestamptz NOT NULL,
+	rate numeric NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	created_by text NOT NULL,
+
+	CONSTRAINT created_by_must_not_be_blank CHECK (length(trim(created_by)) > 0)
+);
+
+CREATE TABLE IF NOT EXISTS org_tax_treatment_versions (
+	version serial PRIMARY KEY,
+	org_guid uuid NOT NULL,
+	valid_from timestamptz NOT NULL,
+	vat_code vat_code,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	created_by text NOT NULL,
+
+	CONSTRAINT created_by_must_not_be_blank CHECK (length(trim(created_by)) > 0)
+);
+
+CREATE OR REPLACE FUNCTION reject_version_changes() RETURNS trigger AS $$ BEGIN
+	RAISE EXCEPTION '% is append only', TG_TABLE_NAME USING
+		hint = 'add a new version instead of changing an existing one';
+END; $$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS append_only ON pricing_plan_versions;
+CREATE TRIGGER append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON pricing_plan_versions
+	FOR EACH STATEMENT EXECUTE PROCEDURE reject_version_changes();
+
+DROP TRIGGER IF EXISTS append_only ON pricing_plan_component_versions;
+CREATE TRIGGER append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON pricing_plan_component_versions
+	FOR EACH STATEMENT EXECUTE PROCEDURE reject_version_changes();
+
+DROP TRIGGER IF EXISTS append_only ON vat_rate_versions;
+CREATE TRIGGER append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON vat_rate_versions
+	FOR EACH STATEMENT EXECUTE PROCEDURE reject_version_changes();
+
+DROP TRIGGER IF EXISTS append_only ON currency_rate_versions;
+CREATE TRIGGER append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON currency_rate_versions
+	FOR EACH STATEMENT EXECUTE PROCEDURE reject_version_changes();
+
+DROP TRIGGER IF EXISTS append_only ON org_tax_treatment_versions;
+CREATE TRIGGER append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON org_tax_treatment_versions
+	FOR EACH STATEMENT EXECUTE PROCEDURE reject_version_changes();
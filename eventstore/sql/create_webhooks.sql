@@ -0,0 +1,37 @@
+-- A webhook is a URL that billing lifecycle events are posted to. events are
+-- the types of event it is sent, it is sent every type if it is empty.
+
+CREATE TABLE IF NOT EXISTS webhooks (
+	id bigserial PRIMARY KEY,
+	url text NOT NULL,
+	secret text NOT NULL,
+	events text[] NOT NULL DEFAULT '{}',
+	created_by text NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- A webhook delivery is an event to be posted to a webhook, and the outcome
+-- of the last attempt to post it. The payload is kept as text so that the
+-- body that is signed and sent is the same on every attempt. Deliveries are
+-- removed with their webhook.
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id bigserial PRIMARY KEY,
+	webhook_id bigint NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+	event_id text NOT NULL,
+	event_type text NOT NULL,
+	payload text NOT NULL,
+	status text NOT NULL DEFAULT 'pending',
+	attempts integer NOT NULL DEFAULT 0,
+	response_status integer,
+	error text,
+	next_attempt_at timestamptz DEFAULT now(),
+	last_attempt_at timestamptz,
+	delivered_at timestamptz,
+	created_at timestamptz NOT NULL DEFAULT now(),
+
+	UNIQUE (webhook_id, event_id),
+	CONSTRAINT status_must_be_valid CHECK (status in ('pending', 'delivered', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
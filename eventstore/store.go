@@ -14,12 +14,14 @@ import (
 	"code.cloudfoundry.org/lager"
 
 	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore/formula"
 	"github.com/lib/pq"
 )
 
 const (
 	AppUsageTableName     = "app_usage_events"
 	ServiceUsageTableName = "service_usage_events"
+	ComposeAuditTableName = "compose_audit_events"
 	ComputePlanGUID       = "f4d4b95a-f55e-4593-8d54-3364c25798c4"
 	ComputeServiceGUID    = "4f6f0a18-cdd4-4e51-8b6b-dc39b696e61b"
 	TaskPlanGUID          = "ebfa9453-ef66-450c-8c37-d53dfd931038"
@@ -56,9 +58,15 @@ func NewFromConfig(ctx context.Context, db *sql.DB, logger lager.Logger, filenam
 	return New(ctx, db, logger, cfg), nil
 }
 
-// Init initialises the database tables and functions
+// Init initialises the database tables and functions. The pricing plans, VAT
+// rates, currency rates and org tax treatments in the config are recorded as
+// the first version of any that have not been seen before, then the latest
+// versions are loaded.
 func (s *EventStore) Init() error {
 	s.logger.Info("initializing")
+	if err := s.checkBillingPeriods(); err != nil {
+		return fmt.Errorf("failed to init billing periods: %s", err)
+	}
 	ctx, cancel := context.WithTimeout(s.ctx, DefaultInitTimeout)
 	defer cancel()
 
@@ -73,6 +81,7 @@ func (s *EventStore) Init() error {
 		"create_services.sql",
 		"create_service_plans.sql",
 		"create_base_objects.sql",
+		"create_pricing_versions.sql",
 		"create_orgs.sql",
 		"create_spaces.sql",
 	}
@@ -92,6 +101,18 @@ func (s *EventStore) Init() error {
 	if err := s.initPlans(tx); err != nil {
 		return fmt.Errorf("failed to init plans: %s", err)
 	}
+	if err := s.initOrgTaxTreatments(tx); err != nil {
+		return fmt.Errorf("failed to init org tax treatments: %s", err)
+	}
+	if err := s.checkConfigVersions(tx); err != nil {
+		return err
+	}
+	if err := s.importPricingVersions(tx); err != nil {
+		return fmt.Errorf("failed to import pricing config: %s", err)
+	}
+	if err := loadPricingVersions(tx); err != nil {
+		return fmt.Errorf("failed to load pricing versions: %s", err)
+	}
 	if err := tx.Commit(); err != nil {
 		return err
 	}
@@ -101,7 +122,12 @@ func (s *EventStore) Init() error {
 		"create_app_usage_events.sql",
 		"create_service_usage_events.sql",
 		"create_compose_audit_events.sql",
+		"create_event_watermarks.sql",
 		"create_consolidated_billable_events.sql",
+		"create_invoices.sql",
+		"create_budgets.sql",
+		"create_anomalies.sql",
+		"create_webhooks.sql",
 	); err != nil {
 		return err
 	}
@@ -113,53 +139,6 @@ func (s *EventStore) Init() error {
 	return nil
 }
 
-// Refresh triggers regeneration of the cached normalized view of the event dat and rebuilds the
-// billable components. Ideally you should do this once a day
-func (s *EventStore) Refresh() error {
-	return s.regenerateEvents()
-}
-
-func (s *EventStore) regenerateEvents() error {
-	ctx, cancel := context.WithTimeout(s.ctx, DefaultRefreshTimeout)
-	defer cancel()
-
-	if err := s.runSQLFilesInTransaction(
-		ctx,
-		"create_events.sql",
-	); err != nil {
-		return err
-	}
-
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	if s.cfg.IgnoreMissingPlans {
-		if err := s.generateMissingPlans(tx); err != nil {
-			return err
-		}
-	}
-
-	if err := checkPlanConsistency(tx); err != nil {
-		return err
-	}
-
-	if err := tx.Commit(); err != nil {
-		return err
-	}
-
-	if err := s.runSQLFilesInTransaction(
-		ctx,
-		"create_billable_event_components.sql",
-	); err != nil {
-		return err
-	}
-
-	return nil
-}
-
 func (s *EventStore) initVATRates(tx *sql.Tx) error {
 	for _, vr := range s.cfg.VATRates {
 		s.logger.Info("configuring-vat-rate", lager.Data{
@@ -167,6 +146,9 @@ func (s *EventStore) initVATRates(tx *sql.Tx) error {
 			"valid_from": vr.ValidFrom,
 			"rate":       vr.Rate,
 		})
+		if err := eventio.ValidateVATCode(vr.Code); err != nil {
+			return fmt.Errorf("invalid vat rate: %s", err)
+		}
 		_, err := tx.Exec(`
 			insert into vat_rates (
 				code, valid_from, rate
@@ -188,6 +170,9 @@ func (s *EventStore) initCurrencyRates(tx *sql.Tx) error {
 			"valid_from": cr.ValidFrom,
 			"rate":       cr.Rate,
 		})
+		if err := eventio.ValidateCurrencyCode(cr.Code); err != nil {
+			return fmt.Errorf("invalid currency rate: %s", err)
+		}
 		_, err := tx.Exec(`
 			insert into currency_rates (
 				code, valid_from, rate
@@ -202,10 +187,9 @@ func (s *EventStore) initCurrencyRates(tx *sql.Tx) error {
 	return nil
 }
 
-// InitPlans destroys all existing plans and replaces them with those specified
-// by pricingPlans if the new set of plans does not satisfy the existing data
-// (for example if you are missing plans for services found in the events then
-// it will fail to update plans and rollback the transaction
+// InitPlans inserts the plans specified by pricingPlans and fails if they are
+// not valid, for example if a component is missing a VAT rate. The
+// transaction should be rolled back if it fails.
 func (s *EventStore) initPlans(tx *sql.Tx) (err error) {
 	for _, pp := range s.cfg.PricingPlans {
 		s.logger.Info("configuring-pricing-plan", lager.Data{
@@ -215,12 +199,12 @@ func (s *EventStore) initPlans(tx *sql.Tx) (err error) {
 		})
 		_, err := tx.Exec(`insert into pricing_plans (
 			plan_guid, valid_from, name,
-			memory_in_mb, storage_in_mb, number_of_nodes
+			memory_in_mb, storage_in_mb, number_of_nodes, disk_in_mb
 		) values (
 			$1, $2, $3,
-			$4, $5, $6
+			$4, $5, $6, $7
 		)`, pp.PlanGUID, pp.ValidFrom, pp.Name,
-			pp.MemoryInMB, pp.StorageInMB, pp.NumberOfNodes,
+			pp.MemoryInMB, pp.StorageInMB, pp.NumberOfNodes, pp.DiskInMB,
 		)
 		if err != nil {
 			return wrapPqError(err, "invalid pricing plan")
@@ -231,13 +215,23 @@ func (s *EventStore) initPlans(tx *sql.Tx) (err error) {
 				"name":       ppc.Name,
 				"valid_from": pp.ValidFrom,
 			})
-			_, err := tx.Exec(`insert into pricing_plan_components (
+			f, err := formula.Parse(ppc.Formula)
+			if err != nil {
+				return fmt.Errorf("invalid pricing plan component: formula for %s/%s/%s: %s", pp.PlanGUID, pp.ValidFrom, ppc.Name, err)
+			}
+			if err := eventio.ValidateVATCode(ppc.VATCode); err != nil {
+				return fmt.Errorf("invalid pricing plan component: %s/%s/%s: %s", pp.PlanGUID, pp.ValidFrom, ppc.Name, err)
+			}
+			if err := eventio.ValidateCurrencyCode(ppc.CurrencyCode); err != nil {
+				return fmt.Errorf("invalid pricing plan component: %s/%s/%s: %s", pp.PlanGUID, pp.ValidFrom, ppc.Name, err)
+			}
+			_, err = tx.Exec(`insert into pricing_plan_components (
 				plan_guid, valid_from, name,
-				formula, currency_code, vat_code
+				formula, compiled_formula, currency_code, vat_code
 			) values (
 				$1, $2, $3,
-				$4, $5, $6
-			)`, pp.PlanGUID, pp.ValidFrom, ppc.Name, ppc.Formula, ppc.CurrencyCode, ppc.VATCode)
+				$4, $5, $6, $7
+			)`, pp.PlanGUID, pp.ValidFrom, ppc.Name, ppc.Formula, f.SQL(), ppc.CurrencyCode, ppc.VATCode)
 			if err != nil {
 				return wrapPqError(err, "invalid pricing plan component")
 			}
@@ -259,6 +253,34 @@ func (s *EventStore) initPlans(tx *sql.Tx) (err error) {
 	return nil
 }
 
+// initOrgTaxTreatments inserts the org tax treatments in the config and
+// fails if any uses a VAT code without a rate
+func (s *EventStore) initOrgTaxTreatments(tx *sql.Tx) error {
+	for _, ot := range s.cfg.OrgTaxTreatments {
+		s.logger.Info("configuring-org-tax-treatment", lager.Data{
+			"org_guid":   ot.OrgGUID,
+			"valid_from": ot.ValidFrom,
+			"vat_code":   ot.VATCode,
+		})
+		if ot.VATCode != "" {
+			if err := eventio.ValidateVATCode(ot.VATCode); err != nil {
+				return fmt.Errorf("invalid org tax treatment: %s", err)
+			}
+		}
+		_, err := tx.Exec(`
+			insert into org_tax_treatments (
+				org_guid, valid_from, vat_code
+			) values (
+				$1, $2, nullif($3, '')
+			)
+		`, ot.OrgGUID, ot.ValidFrom, ot.VATCode)
+		if err != nil {
+			return wrapPqError(err, "invalid org tax treatment")
+		}
+	}
+	return checkOrgTaxTreatments(tx)
+}
+
 func (s *EventStore) StoreEvents(events []eventio.RawEvent) error {
 	ctx, cancel := context.WithTimeout(s.ctx, DefaultStoreTimeout)
 	defer cancel()
@@ -508,6 +530,41 @@ func checkCurrencyRates(tx *sql.Tx) error {
 	return rows.Err()
 }
 
+// checkOrgTaxTreatments checks that every VAT code an org is taxed with has
+// a rate from when the org starts being taxed with it
+func checkOrgTaxTreatments(tx *sql.Tx) error {
+	rows, err := tx.Query(`
+		select
+			vat_code,
+			valid_from,
+			org_guid
+		from
+			org_tax_treatments ott
+		where
+			ott.vat_code is not null
+			and ott.vat_code not in (
+				select code
+				from vat_rates vr
+				where vr.valid_from <= ott.valid_from
+			)
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var code string
+		var valid string
+		var guid string
+		if err := rows.Scan(&code, &valid, &guid); err != nil {
+			return err
+		}
+		return fmt.Errorf("missing vat_rate for '%s' for period '%s' required by org tax treatment for '%s'", code, valid, guid)
+	}
+
+	return rows.Err()
+}
+
 // generateMissingPlans creates dummy plans with 0 cost at the epoch time
 // for every single plan in events, unless there is already one.
 // Useful for getting the system up with an existing dataset without
@@ -539,6 +596,7 @@ func (s *EventStore) generateMissingPlans(tx *sql.Tx) error {
 		return wrapPqError(err, "generate-service-plan")
 	}
 	defer rows.Close()
+	generatedPlanGUIDs := []string{}
 	for rows.Next() {
 		var planGUID string
 		var planName string
@@ -552,17 +610,29 @@ func (s *EventStore) generateMissingPlans(tx *sql.Tx) error {
 			"plan_name":  planName,
 			"valid_from": "epoch",
 		})
+		generatedPlanGUIDs = append(generatedPlanGUIDs, planGUID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
 	}
+	rows.Close()
 	if _, err := tx.Exec(`
 		insert into pricing_plan_components (
-			plan_guid, valid_from, name, formula, vat_code, currency_code
+			plan_guid, valid_from, name, formula, compiled_formula, vat_code, currency_code
 		) (
 			select distinct
 				plan_guid,
 				'epoch'::timestamptz,
 				'pending',
 				'0',
-				'Standard'::vat_code,
+				'0',
+				-- the price is zero so any code in effect will do, but
+				-- prefer Standard if there is one
+				(
+					select code from vat_rates
+					order by valid_from, code = 'Standard' desc, code
+					limit 1
+				),
 				'GBP'::currency_code
 			from events
 			where plan_guid not in (
@@ -575,6 +645,47 @@ func (s *EventStore) generateMissingPlans(tx *sql.Tx) error {
 	); err != nil {
 		return wrapPqError(err, "generate-service-plan-component")
 	}
+	if len(generatedPlanGUIDs) > 0 {
+		return s.emitPlansDetected(tx, generatedPlanGUIDs)
+	}
+	return nil
+}
+
+// emitPlansDetected emits a PlanDetected event for each of the generated
+// plans, named after their latest usage
+func (s *EventStore) emitPlansDetected(tx *sql.Tx, planGUIDs []string) error {
+	rows, err := tx.Query(`
+		select distinct on (plan_guid)
+			plan_guid, plan_name, resource_type
+		from events
+		where plan_guid = any($1::uuid[])
+		order by plan_guid, lower(duration) desc
+	`, pq.Array(planGUIDs))
+	if err != nil {
+		return wrapPqError(err, "get-generated-plans")
+	}
+	defer rows.Close()
+	plans := []eventio.PlanDetectedData{}
+	for rows.Next() {
+		plan := eventio.PlanDetectedData{Placeholder: true}
+		if err := rows.Scan(&plan.PlanGUID, &plan.PlanName, &plan.ResourceType); err != nil {
+			return err
+		}
+		plans = append(plans, plan)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	for _, plan := range plans {
+		event, err := NewPlanDetectedEvent(plan)
+		if err != nil {
+			return err
+		}
+		if err := s.emitWebhookEvent(tx, event); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -653,11 +764,34 @@ func checkPlanConsistency(tx *sql.Tx) error {
 		if err := rows.Scan(&planGUID, &planName, &resourceType); err != nil {
 			return err
 		}
-		return fmt.Errorf("missing '%s' pricing plan configuration for '%s' (%s)", resourceType, planName, planGUID)
+		return &MissingPlanError{
+			PlanGUID:     planGUID,
+			PlanName:     planName,
+			ResourceType: resourceType,
+		}
 	}
 	return nil
 }
 
+// MissingPlanError is returned by checkPlanConsistency for a plan in the
+// events that has no pricing plan
+type MissingPlanError struct {
+	PlanGUID     string
+	PlanName     string
+	ResourceType string
+}
+
+func (e *MissingPlanError) Error() string {
+	return fmt.Sprintf("missing '%s' pricing plan configuration for '%s' (%s)", e.ResourceType, e.PlanName, e.PlanGUID)
+}
+
+// NewPlanDetectedEvent creates the PlanDetected event for a plan. The event
+// has the same ID each time it is created for the plan, so that it is only
+// sent once however many refreshes find it.
+func NewPlanDetectedEvent(plan eventio.PlanDetectedData) (eventio.WebhookEvent, error) {
+	return eventio.NewWebhookEvent(eventio.PlanDetected, fmt.Sprintf("%s:%s", eventio.PlanDetected, plan.PlanGUID), plan)
+}
+
 func (s *EventStore) runSQLFilesInTransaction(ctx context.Context, filenames ...string) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -704,6 +838,58 @@ func (s *EventStore) runSQLFile(tx *sql.Tx, filename string) error {
 	return nil
 }
 
+// eventFilterQuery returns the conditions on the orgs, spaces, resource
+// types, plans, resources and page of filter to add to a where clause, starting
+// with " and ", and args with their values appended. The column names are
+// prefixed with prefix, such as "cbe.".
+func eventFilterQuery(filter eventio.EventFilter, prefix string, args []interface{}) (string, []interface{}) {
+	filterConditions := []string{}
+	for _, values := range []struct {
+		column string
+		cast   string
+		values []string
+	}{
+		{"org_guid", "uuid", filter.OrgGUIDs},
+		{"space_guid", "uuid", filter.SpaceGUIDs},
+		{"resource_type", "text", filter.ResourceTypes},
+		{"plan_guid", "uuid", filter.PlanGUIDs},
+		{"resource_guid", "uuid", filter.ResourceGUIDs},
+	} {
+		placeholders := []string{}
+		for _, value := range values.values {
+			args = append(args, value)
+			placeholders = append(placeholders, fmt.Sprintf("($%d::%s)", len(args), values.cast)) // $N
+		}
+		if len(placeholders) > 0 {
+			filterConditions = append(filterConditions, fmt.Sprintf(
+				"%s%s = any (values %s)", prefix, values.column, strings.Join(placeholders, ","),
+			))
+		}
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.EventGUID, filter.After.PlanGUID)
+		filterConditions = append(filterConditions, fmt.Sprintf(
+			"(%sevent_guid, %splan_guid) > ($%d::uuid, $%d::uuid)", prefix, prefix, len(args)-1, len(args),
+		))
+	}
+	if len(filterConditions) == 0 {
+		return "", args
+	}
+	return " and " + strings.Join(filterConditions, " and "), args
+}
+
+// eventPageQuery returns the order by and limit clauses for a page of
+// events, or orderBy if the filter is not for a page
+func eventPageQuery(filter eventio.EventFilter, orderBy string) string {
+	if !filter.Paginated() {
+		return orderBy
+	}
+	if filter.Limit > 0 {
+		return fmt.Sprintf("order by event_guid, plan_guid limit %d", filter.Limit)
+	}
+	return "order by event_guid, plan_guid"
+}
+
 // queryJSON returns rows as a json blobs, which makes it easier to decode into structs.
 func queryJSON(tx *sql.Tx, q string, args ...interface{}) (*sql.Rows, error) {
 	return tx.Query(fmt.Sprintf(`
@@ -753,5 +939,8 @@ func LoadConfig(filename string) (Config, error) {
 	if err := json.Unmarshal(b, &cfg); err != nil {
 		return Config{}, err
 	}
+	if err := cfg.checkFormulas(); err != nil {
+		return Config{}, fmt.Errorf("%s: %s", filename, err)
+	}
 	return cfg, nil
 }
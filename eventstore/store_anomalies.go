@@ -0,0 +1,330 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/lib/pq"
+)
+
+var _ eventio.AnomalyReader = &EventStore{}
+var _ eventio.AnomalyDetector = &EventStore{}
+
+// GetAnomalies returns the anomalies of days that overlap the filter's
+// range, for the filter's orgs or for all orgs if it has none
+func (s *EventStore) GetAnomalies(filter eventio.EventFilter) ([]eventio.Anomaly, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	args := []interface{}{
+		fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop), // $1
+	}
+	where := "tstzrange(a.day::timestamp at time zone 'UTC', (a.day + 1)::timestamp at time zone 'UTC') && $1::tstzrange"
+	if len(filter.OrgGUIDs) > 0 {
+		args = append(args, pq.Array(filter.OrgGUIDs))
+		where += " and a.org_guid = any($2::uuid[])"
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return getAnomalies(tx, where, args...)
+}
+
+// DetectAnomalies prices the billable event components of each resource for
+// each day from the start of the baseline to the last whole UTC day before
+// the last refresh, in the same way as WithBillableEvents. The daily costs
+// are totalled for each space and org, and the cost of each org, space and
+// resource on every day from the last day checked by the previous detection,
+// going back at most BaselineDays days, is compared against the mean of the
+// days before it that it had a cost on. Those with a cost on fewer than
+// MinHistoryDays of the days are skipped. Each org, space or resource is only
+// recorded as an anomaly once a day.
+func (s *EventStore) DetectAnomalies(cfg eventio.AnomalyConfig) ([]eventio.Anomaly, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var asOf time.Time
+	if err := tx.QueryRow(`select coalesce(min(processed_at), now()) from event_watermarks`).Scan(&asOf); err != nil {
+		return nil, err
+	}
+	asOf = asOf.UTC()
+	lastDay := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	// the progress row is locked so that detections take turns. The last
+	// day checked is checked again as new events may have been refreshed
+	// since, but no more than a baseline of days are caught up on
+	firstDay := lastDay.AddDate(0, 0, -cfg.BaselineDays+1)
+	var lastChecked time.Time
+	err = tx.QueryRow(`
+		select last_day::timestamp at time zone 'UTC'
+		from anomaly_detection_progress
+		for update
+	`).Scan(&lastChecked)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, wrapPqError(err, "detect-anomalies-progress")
+	}
+	if err == nil && lastChecked.After(firstDay) {
+		firstDay = lastChecked.UTC()
+		if firstDay.After(lastDay) {
+			firstDay = lastDay
+		}
+	}
+	baselineStart := firstDay.AddDate(0, 0, -cfg.BaselineDays)
+
+	startTime := time.Now()
+	rows, err := tx.Query(fmt.Sprintf(`
+		with
+		days as (
+			select tstzrange(d, d + interval '1 day') as day
+			from generate_series($1::timestamptz, $2::timestamptz, interval '1 day') as d
+		),
+		resource_costs as (
+			select
+				lower(days.day) as day,
+				b.org_guid,
+				(array_agg(b.org_name order by upper(b.duration) desc))[1] as org_name,
+				b.space_guid,
+				(array_agg(b.space_name order by upper(b.duration) desc))[1] as space_name,
+				b.resource_guid,
+				(array_agg(b.resource_name order by upper(b.duration) desc))[1] as resource_name,
+				(array_agg(b.resource_type order by upper(b.duration) desc))[1] as resource_type,
+				sum(eval_formula(
+					b.memory_in_mb,
+					b.storage_in_mb,
+					b.number_of_nodes,
+					b.disk_in_mb,
+					b.duration * days.day,
+					-- the event is only counted on the day containing its start
+					(case when lower(b.duration) <@ days.day then b.event_count else 0 end),
+					b.component_compiled_formula
+				) * b.currency_rate) as cost
+			from
+				days
+				join billable_event_components b on b.duration && days.day
+			group by
+				lower(days.day), b.org_guid, b.space_guid, b.resource_guid
+		),
+		costs as (
+			select
+				day,
+				(case
+					when grouping(resource_guid) = 0 then 'resource'
+					when grouping(space_guid) = 0 then 'space'
+					else 'org'
+				end) as level,
+				coalesce(resource_guid, space_guid, org_guid) as subject_guid,
+				org_guid,
+				(array_agg(org_name order by space_guid, resource_guid))[1] as org_name,
+				space_guid,
+				(array_agg(space_name order by resource_guid))[1] as space_name,
+				resource_guid,
+				(array_agg(resource_name))[1] as resource_name,
+				(array_agg(resource_type))[1] as resource_type,
+				sum(cost) as cost
+			from
+				resource_costs
+			group by
+				day, grouping sets ((org_guid), (org_guid, space_guid), (org_guid, space_guid, resource_guid))
+		),
+		-- every day of every org, space and resource, so that the window
+		-- below is a number of days whether or not they had a cost
+		daily_costs as (
+			select
+				subjects.level,
+				subjects.subject_guid,
+				lower(days.day) as day,
+				c.org_guid,
+				c.org_name,
+				c.space_guid,
+				c.space_name,
+				c.resource_guid,
+				c.resource_name,
+				c.resource_type,
+				c.cost
+			from
+				(select distinct level, subject_guid from costs) as subjects
+				cross join days
+				left join costs c on
+					c.level = subjects.level
+					and c.subject_guid = subjects.subject_guid
+					and c.day = lower(days.day)
+		),
+		scored as (
+			select
+				daily_costs.*,
+				sum(cost) over baseline_days / nullif(count(cost) over baseline_days, 0) as baseline,
+				count(cost) over baseline_days as history_days
+			from
+				daily_costs
+			window
+				baseline_days as (
+					partition by level, subject_guid
+					order by day
+					rows between %d preceding and 1 preceding
+				)
+		)
+		insert into anomalies (
+			day, level, subject_guid,
+			org_guid, org_name, space_guid, space_name,
+			resource_guid, resource_name, resource_type,
+			cost, baseline, currency_code
+		)
+		select
+			(day at time zone 'UTC')::date,
+			level,
+			subject_guid,
+			org_guid,
+			org_name,
+			space_guid,
+			(case when level <> 'org' then space_name end),
+			resource_guid,
+			(case when level = 'resource' then resource_name end),
+			(case when level = 'resource' then resource_type end),
+			cost,
+			baseline,
+			$6
+		from
+			scored
+		where
+			day >= $3::timestamptz
+			and cost > 0
+			and history_days >= $7::integer
+			and cost >= baseline * $4::numeric
+			and cost - baseline >= $5::numeric
+		on conflict (day, level, subject_guid) do nothing
+		returning id
+	`, cfg.BaselineDays), baselineStart, lastDay, firstDay, cfg.Factor, cfg.MinIncrease, eventio.BaseCurrency, cfg.MinHistoryDays)
+	if err != nil {
+		return nil, wrapPqError(err, "detect-anomalies")
+	}
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`
+		insert into anomaly_detection_progress (last_day) values ($1::date)
+		on conflict (id) do update set last_day = excluded.last_day
+	`, lastDay.Format("2006-01-02")); err != nil {
+		return nil, wrapPqError(err, "detect-anomalies-progress")
+	}
+
+	anomalies, err := getAnomalies(tx, "a.id = any($1::bigint[])", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	s.logger.Info("detect-anomalies", lager.Data{
+		"first_day": firstDay.Format("2006-01-02"),
+		"last_day":  lastDay.Format("2006-01-02"),
+		"anomalies": len(anomalies),
+		"elapsed":   int64(time.Since(startTime)),
+	})
+	return anomalies, nil
+}
+
+// GetUnnotifiedAnomalies returns every anomaly that has not been notified
+func (s *EventStore) GetUnnotifiedAnomalies() ([]eventio.Anomaly, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return getAnomalies(tx, "a.notified_at is null")
+}
+
+func (s *EventStore) SetAnomalyNotified(id int64) error {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, `
+		update anomalies set notified_at = now()
+		where id = $1 and notified_at is null
+	`, id)
+	if err != nil {
+		return wrapPqError(err, "set-anomaly-notified")
+	}
+	return nil
+}
+
+func getAnomalies(tx *sql.Tx, where string, args ...interface{}) ([]eventio.Anomaly, error) {
+	rows, err := tx.Query(`
+		select
+			a.id,
+			to_char(a.day, 'YYYY-MM-DD'),
+			a.level,
+			a.org_guid,
+			a.org_name,
+			coalesce(a.space_guid::text, ''),
+			coalesce(a.space_name, ''),
+			coalesce(a.resource_guid::text, ''),
+			coalesce(a.resource_name, ''),
+			coalesce(a.resource_type, ''),
+			a.cost,
+			a.baseline,
+			a.currency_code,
+			a.detected_at
+		from
+			anomalies a
+		where
+			`+where+`
+		order by
+			a.day, a.org_name, a.org_guid, a.level, a.subject_guid
+	`, args...)
+	if err != nil {
+		return nil, wrapPqError(err, "get-anomalies")
+	}
+	defer rows.Close()
+	anomalies := []eventio.Anomaly{}
+	for rows.Next() {
+		var anomaly eventio.Anomaly
+		if err := rows.Scan(
+			&anomaly.ID,
+			&anomaly.Day,
+			&anomaly.Level,
+			&anomaly.OrgGUID,
+			&anomaly.OrgName,
+			&anomaly.SpaceGUID,
+			&anomaly.SpaceName,
+			&anomaly.ResourceGUID,
+			&anomaly.ResourceName,
+			&anomaly.ResourceType,
+			&anomaly.Cost,
+			&anomaly.Baseline,
+			&anomaly.CurrencyCode,
+			&anomaly.DetectedAt,
+		); err != nil {
+			return nil, err
+		}
+		anomalies = append(anomalies, anomaly)
+	}
+	return anomalies, rows.Err()
+}
@@ -0,0 +1,159 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DetectAnomalies", func() {
+
+	var (
+		cfg eventstore.Config
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  eventstore.ComputePlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "APP_PLAN_1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "compute",
+					Formula:      "$time_in_seconds * $number_of_nodes * 0.001",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+	})
+
+	/*-----------------------------------------------------------------------------------*
+	     20 days ago                               yesterday        today               .
+	         |                                         |              |                 .
+	 .   .   [=============== 1 instance ==============][==== 50 instances =====>       .
+	 .   .   |_____________ baseline ______|...........|_ checked _|                    .
+	*-----------------------------------------------------------------------------------*/
+	It("should record the day an app was scaled up as an anomaly for the app, its space and its org", func() {
+		db, err := testenv.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		store := db.Schema
+
+		orgGUID := "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		spaceGUID := "276f4886-ac40-492d-a8cd-b2646637ba76"
+		appGUID := "c85e98f0-6d1b-4f45-9368-ea58263165a0"
+		appEvent := func(guid string, instances int, createdAt time.Time) eventio.RawEvent {
+			return eventio.RawEvent{
+				GUID:       guid,
+				Kind:       "app",
+				CreatedAt:  createdAt,
+				RawMessage: json.RawMessage(`{"state": "STARTED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "SPACE1", "process_type": "web", "instance_count": ` + strconv.Itoa(instances) + `, "previous_state": "STARTED", "memory_in_mb_per_instance": 1000}`),
+			}
+		}
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		yesterday := today.AddDate(0, 0, -1)
+		Expect(store.StoreEvents([]eventio.RawEvent{
+			appEvent("ae28a572-f485-48e1-87d0-98b7b8b66dfa", 1, today.AddDate(0, 0, -20)),
+			appEvent("ae28a573-f485-48e1-87d0-98b7b8b66dfa", 50, yesterday),
+		})).To(Succeed())
+		Expect(store.Refresh()).To(Succeed())
+
+		anomalies, err := store.DetectAnomalies(eventio.DefaultAnomalyConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(anomalies).To(HaveLen(3))
+
+		levels := map[eventio.AnomalyLevel]eventio.Anomaly{}
+		for _, anomaly := range anomalies {
+			Expect(anomaly.Day).To(Equal(yesterday.Format("2006-01-02")))
+			Expect(anomaly.OrgGUID).To(Equal(orgGUID))
+			Expect(anomaly.OrgName).To(Equal(""))
+			Expect(anomaly.CurrencyCode).To(Equal("GBP"))
+			Expect(anomaly.Cost.Equal(eventio.MustParseDecimal("4320"))).To(BeTrue(), "expected %s to be 4320", anomaly.Cost)
+			Expect(anomaly.Baseline.Equal(eventio.MustParseDecimal("86.4"))).To(BeTrue(), "expected %s to be 86.4", anomaly.Baseline)
+			levels[anomaly.Level] = anomaly
+		}
+		Expect(levels).To(HaveKey(eventio.AnomalyOrg))
+		Expect(levels[eventio.AnomalyOrg].SpaceGUID).To(BeEmpty())
+		Expect(levels).To(HaveKey(eventio.AnomalySpace))
+		Expect(levels[eventio.AnomalySpace].SpaceGUID).To(Equal(spaceGUID))
+		Expect(levels[eventio.AnomalySpace].ResourceGUID).To(BeEmpty())
+		Expect(levels).To(HaveKey(eventio.AnomalyResource))
+		Expect(levels[eventio.AnomalyResource].ResourceGUID).To(Equal(appGUID))
+		Expect(levels[eventio.AnomalyResource].ResourceName).To(Equal("APP1"))
+
+		again, err := store.DetectAnomalies(eventio.DefaultAnomalyConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(again).To(BeEmpty(), "expected each anomaly to only be recorded once")
+
+		recorded, err := store.GetAnomalies(eventio.EventFilter{
+			RangeStart: yesterday.Format("2006-01-02"),
+			RangeStop:  today.Format("2006-01-02"),
+			OrgGUIDs:   []string{orgGUID},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorded).To(HaveLen(3))
+		Expect(db.Get(`select last_day::text from anomaly_detection_progress`)).To(Equal(yesterday.Format("2006-01-02")))
+
+		unnotified, err := store.GetUnnotifiedAnomalies()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unnotified).To(HaveLen(3))
+		Expect(store.SetAnomalyNotified(unnotified[0].ID)).To(Succeed())
+		unnotified, err = store.GetUnnotifiedAnomalies()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unnotified).To(HaveLen(2), "expected the notified anomaly not to be sent again")
+	})
+
+	/*-----------------------------------------------------------------------------------*
+	    30 days ago         5 days ago   3 days ago                 today               .
+	         |                   |           |                        |                 .
+	 org1    [=== 1 instance ===============][= 50 =][= 1 instance ==>                  .
+	 org2                        |           |      [= 50 instances =>                  .
+	 .   .   .   .   .   last checked        |_______ checked ______|                   .
+	*-----------------------------------------------------------------------------------*/
+	It("should check every day since the last day checked and skip those without enough history", func() {
+		db, err := testenv.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		store := db.Schema
+
+		org1GUID := "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		org2GUID := "61ba75ef-edc0-47ad-a633-a8f6e8770944"
+		appEvent := func(guid string, orgGUID string, appGUID string, instances int, createdAt time.Time) eventio.RawEvent {
+			return eventio.RawEvent{
+				GUID:       guid,
+				Kind:       "app",
+				CreatedAt:  createdAt,
+				RawMessage: json.RawMessage(`{"state": "STARTED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + orgGUID + `", "space_name": "SPACE1", "process_type": "web", "instance_count": ` + strconv.Itoa(instances) + `, "previous_state": "STARTED", "memory_in_mb_per_instance": 1000}`),
+			}
+		}
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		Expect(store.StoreEvents([]eventio.RawEvent{
+			appEvent("ae28a572-f485-48e1-87d0-98b7b8b66dfa", org1GUID, "c85e98f0-6d1b-4f45-9368-ea58263165a0", 1, today.AddDate(0, 0, -30)),
+			appEvent("ae28a573-f485-48e1-87d0-98b7b8b66dfa", org1GUID, "c85e98f0-6d1b-4f45-9368-ea58263165a0", 50, today.AddDate(0, 0, -3)),
+			appEvent("ae28a574-f485-48e1-87d0-98b7b8b66dfa", org1GUID, "c85e98f0-6d1b-4f45-9368-ea58263165a0", 1, today.AddDate(0, 0, -2)),
+			appEvent("ae28a575-f485-48e1-87d0-98b7b8b66dfa", org2GUID, "d85e98f0-6d1b-4f45-9368-ea58263165a0", 50, today.AddDate(0, 0, -2)),
+		})).To(Succeed())
+		Expect(store.Refresh()).To(Succeed())
+		Expect(db.Insert("anomaly_detection_progress", testenv.Row{
+			"last_day": today.AddDate(0, 0, -5).Format("2006-01-02"),
+		})).To(Succeed())
+
+		anomalies, err := store.DetectAnomalies(eventio.DefaultAnomalyConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(anomalies).To(HaveLen(3))
+		for _, anomaly := range anomalies {
+			Expect(anomaly.Day).To(Equal(today.AddDate(0, 0, -3).Format("2006-01-02")))
+			Expect(anomaly.OrgGUID).To(Equal(org1GUID))
+			Expect(anomaly.Baseline.Equal(eventio.MustParseDecimal("86.4"))).To(BeTrue(), "expected %s to be 86.4", anomaly.Baseline)
+		}
+	})
+
+})
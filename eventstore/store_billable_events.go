@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"strings"
 	"time"
 
 	"code.cloudfoundry.org/lager"
@@ -35,9 +34,12 @@ func (s *EventStore) getBillableEventRows(tx *sql.Tx, filter eventio.EventFilter
 	if err := filter.Validate(); err != nil {
 		return nil, err
 	}
+	if err := checkCurrencyRateFrom(tx, filter.Currency, filter.RangeStart); err != nil {
+		return nil, err
+	}
 
 	query, args, err := WithBillableEvents(
-		`select * from billable_events`,
+		`select * from billable_events `+eventPageQuery(filter, ""),
 		filter,
 	)
 	if err != nil {
@@ -139,6 +141,10 @@ func (ber *BillableEventRows) Event() (*eventio.BillableEvent, error) {
 // billable_events, containing the result of applying the given pricing
 // formula to the events for the given filter.
 //
+// Prices are given in filter.Currency, converted from the base currency
+// with the rate in effect at the start of each component, or in the base
+// currency if no currency is given.
+//
 // Other included tables are:
 //  - components_with_price: Components and formulas selected for this filter
 //  - filtered range: time range of the filter
@@ -149,18 +155,20 @@ func WithBillableEvents(query string, filter eventio.EventFilter, args ...interf
 	args = append(args, fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop)) // $1
 	durationArgPosition := len(args)
 
-	filterConditions := []string{}
-	orgPlaceholders := []string{}
-	for _, orgGUID := range filter.OrgGUIDs {
-		args = append(args, orgGUID)
-		orgPlaceholders = append(orgPlaceholders, fmt.Sprintf("($%d::uuid)", len(args))) // $N
-	}
-	if len(orgPlaceholders) > 0 {
-		filterConditions = append(filterConditions, fmt.Sprintf("org_guid = any (values %s)", strings.Join(orgPlaceholders, ",")))
-	}
-	filterQuery := ""
-	if len(filterConditions) > 0 {
-		filterQuery = " and " + strings.Join(filterConditions, " and ")
+	filterQuery, args := eventFilterQuery(filter, "", args)
+
+	currencyCode := fmt.Sprintf("'%s'", eventio.BaseCurrency)
+	currencyConversion := ""
+	if filter.Currency != "" {
+		args = append(args, filter.Currency)
+		currencyCode = fmt.Sprintf("$%d::text", len(args))
+		currencyConversion = fmt.Sprintf(` / (
+			select cr.rate
+			from currency_rates cr
+			where cr.code = $%d and cr.valid_from <= lower(b.duration * filtered_range)
+			order by cr.valid_from desc
+			limit 1
+		)`, len(args))
 	}
 
 	wrappedQuery := fmt.Sprintf(`
@@ -184,18 +192,23 @@ func WithBillableEvents(query string, filter eventio.EventFilter, args ...interf
 				b.number_of_nodes,
 				b.memory_in_mb,
 				b.storage_in_mb,
+				b.disk_in_mb,
 				b.component_name,
 				b.component_formula,
+				b.component_compiled_formula,
 				b.vat_code,
 				b.vat_rate,
-				'GBP' as currency_code,
+				%s as currency_code,
 				(eval_formula(
 					b.memory_in_mb,
 					b.storage_in_mb,
 					b.number_of_nodes,
+					b.disk_in_mb,
 					b.duration * filtered_range,
-					b.component_formula
-				) * b.currency_rate) as price_ex_vat
+					-- the event is only counted in the range containing its start
+					(case when lower(b.duration) <@ filtered_range then b.event_count else 0 end),
+					b.component_compiled_formula
+				) * b.currency_rate%s) as price_ex_vat
 			from
 			    filtered_range,
 				billable_event_components b
@@ -259,6 +272,8 @@ func WithBillableEvents(query string, filter eventio.EventFilter, args ...interf
 	  %s
 	  `,
 		durationArgPosition,
+		currencyCode,
+		currencyConversion,
 		filterQuery,
 		query,
 	)
@@ -3,7 +3,6 @@ package eventstore_test
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"time"
 
 	"github.com/alphagov/paas-billing/eventio"
@@ -95,19 +94,19 @@ var _ = Describe("GetBillableEvents", func() {
 			MemoryInMB:    1024,
 			StorageInMB:   0,
 			Price: eventio.Price{
-				IncVAT: "0.012",
-				ExVAT:  "0.01",
+				IncVAT: eventio.MustParseDecimal("0.012"),
+				ExVAT:  eventio.MustParseDecimal("0.01"),
 				Details: []eventio.PriceComponent{
 					{
 						Name:         "compute",
 						PlanName:     "STAGING_PLAN_1",
 						Start:        "2001-01-01T00:00:00+00:00",
 						Stop:         "2001-01-01T00:01:00+00:00",
-						VatRate:      "0.2",
+						VatRate:      eventio.MustParseDecimal("0.2"),
 						VatCode:      "Standard",
 						CurrencyCode: "GBP",
-						IncVAT:       "0.012",
-						ExVAT:        "0.01",
+						IncVAT:       eventio.MustParseDecimal("0.012"),
+						ExVAT:        eventio.MustParseDecimal("0.01"),
 					},
 				},
 			},
@@ -188,19 +187,19 @@ var _ = Describe("GetBillableEvents", func() {
 			MemoryInMB:    1024,
 			StorageInMB:   0,
 			Price: eventio.Price{
-				IncVAT: "0.012",
-				ExVAT:  "0.01",
+				IncVAT: eventio.MustParseDecimal("0.012"),
+				ExVAT:  eventio.MustParseDecimal("0.01"),
 				Details: []eventio.PriceComponent{
 					{
 						Name:         "compute",
 						PlanName:     "PLAN1",
 						Start:        "2001-01-01T00:00:00+00:00",
 						Stop:         "2001-01-01T01:00:00+00:00",
-						VatRate:      "0.2",
+						VatRate:      eventio.MustParseDecimal("0.2"),
 						VatCode:      "Standard",
 						CurrencyCode: "GBP",
-						IncVAT:       "0.012",
-						ExVAT:        "0.01",
+						IncVAT:       eventio.MustParseDecimal("0.012"),
+						ExVAT:        eventio.MustParseDecimal("0.01"),
 					},
 				},
 			},
@@ -281,19 +280,19 @@ var _ = Describe("GetBillableEvents", func() {
 			MemoryInMB:    1024,
 			StorageInMB:   0,
 			Price: eventio.Price{
-				IncVAT: "0.012",
-				ExVAT:  "0.01",
+				IncVAT: eventio.MustParseDecimal("0.012"),
+				ExVAT:  eventio.MustParseDecimal("0.01"),
 				Details: []eventio.PriceComponent{
 					{
 						Name:         "task",
 						PlanName:     "PLAN1",
 						Start:        "2001-01-01T00:00:00+00:00",
 						Stop:         "2001-01-01T01:00:00+00:00",
-						VatRate:      "0.2",
+						VatRate:      eventio.MustParseDecimal("0.2"),
 						VatCode:      "Standard",
 						CurrencyCode: "GBP",
-						IncVAT:       "0.012",
-						ExVAT:        "0.01",
+						IncVAT:       eventio.MustParseDecimal("0.012"),
+						ExVAT:        eventio.MustParseDecimal("0.01"),
 					},
 				},
 			},
@@ -378,19 +377,19 @@ var _ = Describe("GetBillableEvents", func() {
 			MemoryInMB:    1024,
 			StorageInMB:   0,
 			Price: eventio.Price{
-				IncVAT: "0.012",
-				ExVAT:  "0.01",
+				IncVAT: eventio.MustParseDecimal("0.012"),
+				ExVAT:  eventio.MustParseDecimal("0.01"),
 				Details: []eventio.PriceComponent{
 					{
 						Name:         "compute",
 						PlanName:     "PLAN1",
 						Start:        "2001-01-01T00:00:00+00:00",
 						Stop:         "2001-01-01T01:00:00+00:00",
-						VatRate:      "0.2",
+						VatRate:      eventio.MustParseDecimal("0.2"),
 						VatCode:      "Standard",
 						CurrencyCode: "GBP",
-						IncVAT:       "0.012",
-						ExVAT:        "0.01",
+						IncVAT:       eventio.MustParseDecimal("0.012"),
+						ExVAT:        eventio.MustParseDecimal("0.01"),
 					},
 				},
 			},
@@ -413,19 +412,19 @@ var _ = Describe("GetBillableEvents", func() {
 			MemoryInMB:    1024,
 			StorageInMB:   0,
 			Price: eventio.Price{
-				IncVAT: "0.012",
-				ExVAT:  "0.01",
+				IncVAT: eventio.MustParseDecimal("0.012"),
+				ExVAT:  eventio.MustParseDecimal("0.01"),
 				Details: []eventio.PriceComponent{
 					{
 						Name:         "compute",
 						PlanName:     "PLAN1",
 						Start:        "2001-01-01T01:00:00+00:00",
 						Stop:         "2001-01-01T02:00:00+00:00",
-						VatRate:      "0.2",
+						VatRate:      eventio.MustParseDecimal("0.2"),
 						VatCode:      "Standard",
 						CurrencyCode: "GBP",
-						IncVAT:       "0.012",
-						ExVAT:        "0.01",
+						IncVAT:       eventio.MustParseDecimal("0.012"),
+						ExVAT:        eventio.MustParseDecimal("0.01"),
 					},
 				},
 			},
@@ -627,30 +626,30 @@ var _ = Describe("GetBillableEvents", func() {
 		)
 
 		Expect(events[0].Price).To(Equal(eventio.Price{
-			IncVAT: "40.8",
-			ExVAT:  "34",
+			IncVAT: eventio.MustParseDecimal("40.8"),
+			ExVAT:  eventio.MustParseDecimal("34"),
 			Details: []eventio.PriceComponent{
 				{
 					Name:         "compute",
 					PlanName:     "PLAN1",
 					Start:        "2017-01-01T00:00:00+00:00",
 					Stop:         "2017-02-01T00:00:00+00:00",
-					VatRate:      "0.2",
+					VatRate:      eventio.MustParseDecimal("0.2"),
 					VatCode:      "Standard",
 					CurrencyCode: "GBP",
-					IncVAT:       "1.2",
-					ExVAT:        "1",
+					IncVAT:       eventio.MustParseDecimal("1.2"),
+					ExVAT:        eventio.MustParseDecimal("1"),
 				},
 				{
 					Name:         "compute",
 					PlanName:     "PLAN2",
 					Start:        "2017-02-01T00:00:00+00:00",
 					Stop:         "2017-03-01T00:00:00+00:00",
-					VatRate:      "0.2",
+					VatRate:      eventio.MustParseDecimal("0.2"),
 					VatCode:      "Standard",
 					CurrencyCode: "GBP",
-					IncVAT:       "39.6",
-					ExVAT:        "33",
+					IncVAT:       eventio.MustParseDecimal("39.6"),
+					ExVAT:        eventio.MustParseDecimal("33"),
 				},
 			},
 		}))
@@ -683,7 +682,7 @@ var _ = Describe("GetBillableEvents", func() {
 		})
 		cfg.AddVATRate(eventio.VATRate{
 			Code:      "Standard",
-			Rate:      0,
+			Rate:      eventio.MustParseDecimalNumber("0"),
 			ValidFrom: "2017-02-01",
 		})
 
@@ -724,30 +723,30 @@ var _ = Describe("GetBillableEvents", func() {
 		)
 
 		Expect(events[0].Price).To(Equal(eventio.Price{
-			IncVAT: "2.2",
-			ExVAT:  "2",
+			IncVAT: eventio.MustParseDecimal("2.2"),
+			ExVAT:  eventio.MustParseDecimal("2"),
 			Details: []eventio.PriceComponent{
 				{
 					Name:         "compute",
 					PlanName:     "PLAN1",
 					Start:        "2017-01-01T00:00:00+00:00",
 					Stop:         "2017-02-01T00:00:00+00:00",
-					VatRate:      "0.2",
+					VatRate:      eventio.MustParseDecimal("0.2"),
 					VatCode:      "Standard",
 					CurrencyCode: "GBP",
-					IncVAT:       "1.2",
-					ExVAT:        "1",
+					IncVAT:       eventio.MustParseDecimal("1.2"),
+					ExVAT:        eventio.MustParseDecimal("1"),
 				},
 				{
 					Name:         "compute",
 					PlanName:     "PLAN1",
 					Start:        "2017-02-01T00:00:00+00:00",
 					Stop:         "2017-03-01T00:00:00+00:00",
-					VatRate:      "0",
+					VatRate:      eventio.MustParseDecimal("0"),
 					VatCode:      "Standard",
 					CurrencyCode: "GBP",
-					IncVAT:       "1",
-					ExVAT:        "1",
+					IncVAT:       eventio.MustParseDecimal("1"),
+					ExVAT:        eventio.MustParseDecimal("1"),
 				},
 			},
 		}))
@@ -780,7 +779,7 @@ var _ = Describe("GetBillableEvents", func() {
 		})
 		cfg.AddCurrencyRate(eventio.CurrencyRate{
 			Code:      "GBP",
-			Rate:      2,
+			Rate:      eventio.MustParseDecimalNumber("2"),
 			ValidFrom: "2017-02-01",
 		})
 
@@ -821,30 +820,30 @@ var _ = Describe("GetBillableEvents", func() {
 		)
 
 		Expect(events[0].Price).To(Equal(eventio.Price{
-			IncVAT: "3.6",
-			ExVAT:  "3",
+			IncVAT: eventio.MustParseDecimal("3.6"),
+			ExVAT:  eventio.MustParseDecimal("3"),
 			Details: []eventio.PriceComponent{
 				{
 					Name:         "compute",
 					PlanName:     "PLAN1",
 					Start:        "2017-01-01T00:00:00+00:00",
 					Stop:         "2017-02-01T00:00:00+00:00",
-					VatRate:      "0.2",
+					VatRate:      eventio.MustParseDecimal("0.2"),
 					VatCode:      "Standard",
 					CurrencyCode: "GBP",
-					IncVAT:       "1.2",
-					ExVAT:        "1",
+					IncVAT:       eventio.MustParseDecimal("1.2"),
+					ExVAT:        eventio.MustParseDecimal("1"),
 				},
 				{
 					Name:         "compute",
 					PlanName:     "PLAN1",
 					Start:        "2017-02-01T00:00:00+00:00",
 					Stop:         "2017-03-01T00:00:00+00:00",
-					VatRate:      "0.2",
+					VatRate:      eventio.MustParseDecimal("0.2"),
 					VatCode:      "Standard",
 					CurrencyCode: "GBP",
-					IncVAT:       "2.4",
-					ExVAT:        "2",
+					IncVAT:       eventio.MustParseDecimal("2.4"),
+					ExVAT:        eventio.MustParseDecimal("2"),
 				},
 			},
 		}))
@@ -882,17 +881,17 @@ var _ = Describe("GetBillableEvents", func() {
 		})
 		cfg.AddVATRate(eventio.VATRate{
 			Code:      "Standard",
-			Rate:      0,
+			Rate:      eventio.MustParseDecimalNumber("0"),
 			ValidFrom: "2017-03-01",
 		})
 		cfg.AddCurrencyRate(eventio.CurrencyRate{
 			Code:      "GBP",
-			Rate:      2,
+			Rate:      eventio.MustParseDecimalNumber("2"),
 			ValidFrom: "2017-02-01",
 		})
 		cfg.AddCurrencyRate(eventio.CurrencyRate{
 			Code:      "GBP",
-			Rate:      4,
+			Rate:      eventio.MustParseDecimalNumber("4"),
 			ValidFrom: "2017-04-01",
 		})
 
@@ -933,52 +932,52 @@ var _ = Describe("GetBillableEvents", func() {
 		)
 
 		Expect(events[0].Price).To(Equal(eventio.Price{
-			IncVAT: "9.6",
-			ExVAT:  "9",
+			IncVAT: eventio.MustParseDecimal("9.6"),
+			ExVAT:  eventio.MustParseDecimal("9"),
 			Details: []eventio.PriceComponent{
 				{
 					Name:         "compute",
 					PlanName:     "PLAN1",
 					Start:        "2017-01-01T00:00:00+00:00",
 					Stop:         "2017-02-01T00:00:00+00:00",
-					VatRate:      "0.2",
+					VatRate:      eventio.MustParseDecimal("0.2"),
 					VatCode:      "Standard",
 					CurrencyCode: "GBP",
-					IncVAT:       "1.2",
-					ExVAT:        "1",
+					IncVAT:       eventio.MustParseDecimal("1.2"),
+					ExVAT:        eventio.MustParseDecimal("1"),
 				},
 				{
 					Name:         "compute",
 					PlanName:     "PLAN1",
 					Start:        "2017-02-01T00:00:00+00:00",
 					Stop:         "2017-03-01T00:00:00+00:00",
-					VatRate:      "0.2",
+					VatRate:      eventio.MustParseDecimal("0.2"),
 					VatCode:      "Standard",
 					CurrencyCode: "GBP",
-					IncVAT:       "2.4",
-					ExVAT:        "2",
+					IncVAT:       eventio.MustParseDecimal("2.4"),
+					ExVAT:        eventio.MustParseDecimal("2"),
 				},
 				{
 					Name:         "compute",
 					PlanName:     "PLAN1",
 					Start:        "2017-03-01T00:00:00+00:00",
 					Stop:         "2017-04-01T00:00:00+00:00",
-					VatRate:      "0",
+					VatRate:      eventio.MustParseDecimal("0"),
 					VatCode:      "Standard",
 					CurrencyCode: "GBP",
-					IncVAT:       "2",
-					ExVAT:        "2",
+					IncVAT:       eventio.MustParseDecimal("2"),
+					ExVAT:        eventio.MustParseDecimal("2"),
 				},
 				{
 					Name:         "compute",
 					PlanName:     "PLAN1",
 					Start:        "2017-04-01T00:00:00+00:00",
 					Stop:         "2017-05-01T00:00:00+00:00",
-					VatRate:      "0",
+					VatRate:      eventio.MustParseDecimal("0"),
 					VatCode:      "Standard",
 					CurrencyCode: "GBP",
-					IncVAT:       "4",
-					ExVAT:        "4",
+					IncVAT:       eventio.MustParseDecimal("4"),
+					ExVAT:        eventio.MustParseDecimal("4"),
 				},
 			},
 		}))
@@ -998,7 +997,7 @@ var _ = Describe("GetBillableEvents", func() {
 	It("Should include BillableEvent that represents the data from a compose scale event", func() {
 		cfg.AddVATRate(eventio.VATRate{
 			Code:      "Zero",
-			Rate:      0,
+			Rate:      eventio.MustParseDecimalNumber("0"),
 			ValidFrom: "epoch",
 		})
 		plan := eventio.PricingPlan{
@@ -1111,19 +1110,19 @@ var _ = Describe("GetBillableEvents", func() {
 			MemoryInMB:    1024,
 			StorageInMB:   2048,
 			Price: eventio.Price{
-				IncVAT: fmt.Sprintf("%d", expectedEvent1PriceIncVat),
-				ExVAT:  fmt.Sprintf("%d", expectedEvent1PriceExVat),
+				IncVAT: eventio.NewDecimalFromInt(int64(expectedEvent1PriceIncVat)),
+				ExVAT:  eventio.NewDecimalFromInt(int64(expectedEvent1PriceExVat)),
 				Details: []eventio.PriceComponent{
 					{
 						Name:         "compose",
 						PlanName:     "PLAN1",
 						Start:        "2001-01-01T00:00:00+00:00",
 						Stop:         "2001-01-01T01:00:00+00:00",
-						VatRate:      "0",
+						VatRate:      eventio.MustParseDecimal("0"),
 						VatCode:      "Zero",
 						CurrencyCode: "GBP",
-						IncVAT:       fmt.Sprintf("%d", expectedEvent1PriceIncVat),
-						ExVAT:        fmt.Sprintf("%d", expectedEvent1PriceExVat),
+						IncVAT:       eventio.NewDecimalFromInt(int64(expectedEvent1PriceIncVat)),
+						ExVAT:        eventio.NewDecimalFromInt(int64(expectedEvent1PriceExVat)),
 					},
 				},
 			},
@@ -1145,22 +1144,367 @@ var _ = Describe("GetBillableEvents", func() {
 			MemoryInMB:    2048,
 			StorageInMB:   4096,
 			Price: eventio.Price{
-				IncVAT: fmt.Sprintf("%d", expectedEvent2PriceIncVat),
-				ExVAT:  fmt.Sprintf("%d", expectedEvent2PriceExVat),
+				IncVAT: eventio.NewDecimalFromInt(int64(expectedEvent2PriceIncVat)),
+				ExVAT:  eventio.NewDecimalFromInt(int64(expectedEvent2PriceExVat)),
 				Details: []eventio.PriceComponent{
 					{
 						Name:         "compose",
 						PlanName:     "PLAN1",
 						Start:        "2001-01-01T01:00:00+00:00",
 						Stop:         "2001-01-01T02:00:00+00:00",
-						VatRate:      "0",
+						VatRate:      eventio.MustParseDecimal("0"),
 						VatCode:      "Zero",
 						CurrencyCode: "GBP",
-						IncVAT:       fmt.Sprintf("%d", expectedEvent2PriceIncVat),
-						ExVAT:        fmt.Sprintf("%d", expectedEvent2PriceExVat),
+						IncVAT:       eventio.NewDecimalFromInt(int64(expectedEvent2PriceIncVat)),
+						ExVAT:        eventio.NewDecimalFromInt(int64(expectedEvent2PriceExVat)),
 					},
 				},
 			},
 		}))
 	})
+
+	/*-----------------------------------------------------------------------------------*
+	.                                                                                     .
+	                 Jan 31 23:00   Feb 01 01:00                                          .
+	                     |              |                                                 .
+	 .   .   .   .   .   [=====app1=====]   .   .   .   .   .   .   .   .   .   .   .   .
+	 .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .
+	<============PLAN1 (jan)========><==============PLAN1 (feb)=========================>.
+	 .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .
+	*-----------------------------------------------------------------------------------*/
+	Describe("$disk_in_mb and $event_count", func() {
+		var db *testenv.TempDB
+
+		BeforeEach(func() {
+			for _, validFrom := range []string{"2001-01-01", "2001-02-01"} {
+				cfg.AddPlan(eventio.PricingPlan{
+					PlanGUID:  eventstore.ComputePlanGUID,
+					ValidFrom: validFrom,
+					Name:      "PLAN1",
+					Components: []eventio.PricingPlanComponent{
+						{
+							Name:         "setup",
+							Formula:      "$event_count * 10",
+							CurrencyCode: "GBP",
+							VATCode:      "Standard",
+						},
+						{
+							Name:         "disk",
+							Formula:      "ceil($time_in_seconds/3600) * $disk_in_mb",
+							CurrencyCode: "GBP",
+							VATCode:      "Standard",
+						},
+					},
+				})
+			}
+
+			var err error
+			db, err = testenv.Open(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			app1EventStart := testenv.Row{
+				"guid":        "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+				"created_at":  "2001-01-31T23:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "ORG1-SPACE1", "process_type": "web", "instance_count": 1, "previous_state": "STOPPED", "memory_in_mb_per_instance": 1024, "disk_in_mb_per_instance": 512}`),
+			}
+			app1EventStop := testenv.Row{
+				"guid":        "8d9036c5-8367-497d-bb56-94bfcac6621a",
+				"created_at":  "2001-02-01T01:00Z",
+				"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "ORG1-SPACE1", "process_type": "web", "instance_count": 1, "previous_state": "STARTED", "memory_in_mb_per_instance": 1024, "disk_in_mb_per_instance": 512}`),
+			}
+			Expect(db.Insert("app_usage_events", app1EventStart, app1EventStop)).To(Succeed())
+			Expect(db.Schema.Refresh()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			db.Close()
+		})
+
+		costs := func(rangeStart, rangeStop string) map[string][]string {
+			events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+				RangeStart: rangeStart,
+				RangeStop:  rangeStop,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(HaveLen(1))
+			byName := map[string][]string{}
+			for _, detail := range events[0].Price.Details {
+				byName[detail.Name] = append(byName[detail.Name], detail.ExVAT.String())
+			}
+			return byName
+		}
+
+		It("should only charge $event_count once, in the part of the event containing its start", func() {
+			Expect(costs("2001-01-01", "2001-03-01")).To(Equal(map[string][]string{
+				"setup": {"10", "0"},
+				"disk":  {"512", "512"},
+			}))
+		})
+
+		It("should not charge $event_count in a range that does not contain the start of the event", func() {
+			Expect(costs("2001-02-01", "2001-03-01")).To(Equal(map[string][]string{
+				"setup": {"0"},
+				"disk":  {"512"},
+			}))
+		})
+	})
+
+	Describe("in another currency", func() {
+		var db *testenv.TempDB
+
+		BeforeEach(func() {
+			cfg.AddPlan(eventio.PricingPlan{
+				PlanGUID:  eventstore.ComputePlanGUID,
+				ValidFrom: "2001-01-01",
+				Name:      "PLAN1",
+				Components: []eventio.PricingPlanComponent{
+					{
+						Name:         "compute",
+						Formula:      "ceil($time_in_seconds/3600) * 0.8",
+						CurrencyCode: "GBP",
+						VATCode:      "Standard",
+					},
+				},
+			})
+			cfg.AddCurrencyRate(eventio.CurrencyRate{
+				Code:      "USD",
+				ValidFrom: "2001-01-01",
+				Rate:      eventio.MustParseDecimalNumber("0.8"),
+			})
+
+			var err error
+			db, err = testenv.Open(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			app1EventStart := testenv.Row{
+				"guid":        "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+				"created_at":  "2001-01-01T00:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "ORG1-SPACE1", "process_type": "web", "instance_count": 1, "previous_state": "STOPPED", "memory_in_mb_per_instance": 1024}`),
+			}
+			app1EventStop := testenv.Row{
+				"guid":        "8d9036c5-8367-497d-bb56-94bfcac6621a",
+				"created_at":  "2001-01-01T01:00Z",
+				"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "ORG1-SPACE1", "process_type": "web", "instance_count": 1, "previous_state": "STARTED", "memory_in_mb_per_instance": 1024}`),
+			}
+			Expect(db.Insert("app_usage_events", app1EventStart, app1EventStop)).To(Succeed())
+			Expect(db.Schema.Refresh()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			db.Close()
+		})
+
+		It("should convert the price through the currency rate", func() {
+			events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-02-01",
+				Currency:   "USD",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(HaveLen(1))
+
+			Expect(events[0].Price.ExVAT.Equal(eventio.MustParseDecimal("1"))).To(BeTrue())
+			Expect(events[0].Price.IncVAT.Equal(eventio.MustParseDecimal("1.2"))).To(BeTrue())
+			Expect(events[0].Price.Details).To(HaveLen(1))
+			Expect(events[0].Price.Details[0].CurrencyCode).To(Equal("USD"))
+		})
+
+		It("should return a FilterError when the currency has no rate", func() {
+			_, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-02-01",
+				Currency:   "EUR",
+			})
+			Expect(err).To(BeAssignableToTypeOf(&eventio.FilterError{}))
+			Expect(err).To(MatchError(ContainSubstring("there is no currency_rate for 'EUR'")))
+		})
+	})
+
+	Describe("for an org with a tax treatment", func() {
+		var db *testenv.TempDB
+
+		BeforeEach(func() {
+			cfg.AddPlan(eventio.PricingPlan{
+				PlanGUID:  eventstore.ComputePlanGUID,
+				ValidFrom: "2001-01-01",
+				Name:      "PLAN1",
+				Components: []eventio.PricingPlanComponent{
+					{
+						Name:         "compute",
+						Formula:      "ceil($time_in_seconds/3600) * 1",
+						CurrencyCode: "GBP",
+						VATCode:      "Standard",
+					},
+				},
+			})
+			cfg.AddVATRate(eventio.VATRate{
+				Code:      "Exempt",
+				ValidFrom: "2001-01-01",
+				Rate:      eventio.MustParseDecimalNumber("0"),
+			})
+			cfg.AddOrgTaxTreatment(eventio.OrgTaxTreatment{
+				OrgGUID:   "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+				ValidFrom: "2001-02-01",
+				VATCode:   "Exempt",
+			})
+
+			var err error
+			db, err = testenv.Open(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			app1EventStart := testenv.Row{
+				"guid":        "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+				"created_at":  "2001-01-31T23:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "ORG1-SPACE1", "process_type": "web", "instance_count": 1, "previous_state": "STOPPED", "memory_in_mb_per_instance": 1024}`),
+			}
+			app1EventStop := testenv.Row{
+				"guid":        "8d9036c5-8367-497d-bb56-94bfcac6621a",
+				"created_at":  "2001-02-01T01:00Z",
+				"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "ORG1-SPACE1", "process_type": "web", "instance_count": 1, "previous_state": "STARTED", "memory_in_mb_per_instance": 1024}`),
+			}
+			Expect(db.Insert("app_usage_events", app1EventStart, app1EventStop)).To(Succeed())
+			Expect(db.Schema.Refresh()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			db.Close()
+		})
+
+		It("should use the VAT code of the org from when its treatment starts", func() {
+			events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-03-01",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Price.ExVAT).To(Equal(eventio.MustParseDecimal("2")))
+			Expect(events[0].Price.IncVAT).To(Equal(eventio.MustParseDecimal("2.2")))
+
+			details := events[0].Price.Details
+			Expect(details).To(HaveLen(2))
+			Expect(details[0].Start).To(Equal("2001-01-31T23:00:00+00:00"))
+			Expect(details[0].VatCode).To(Equal("Standard"))
+			Expect(details[0].IncVAT).To(Equal(eventio.MustParseDecimal("1.2")))
+			Expect(details[1].Start).To(Equal("2001-02-01T00:00:00+00:00"))
+			Expect(details[1].VatCode).To(Equal("Exempt"))
+			Expect(details[1].IncVAT).To(Equal(eventio.MustParseDecimal("1")))
+		})
+	})
+})
+
+var _ = Describe("Filtering events", func() {
+	var (
+		scenario *testenv.TestScenario
+		db       *testenv.TempDB
+		january  = eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		}
+	)
+
+	BeforeEach(func() {
+		scenario = testenv.NewTestScenario("2001-01-01T00:00")
+		scenario.AddComputePlan()
+		scenario.AppLifeCycle("org1", "space1", "app1",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+1h", State: "STOPPED"},
+		)
+		scenario.AppLifeCycle("org1", "space2", "app2",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+1h", State: "STOPPED"},
+		)
+
+		var err error
+		db, err = scenario.Open(testenv.BasicConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(db.Schema.Refresh()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("should filter billable, consolidated and usage events by space", func() {
+		filter := january
+		filter.SpaceGUIDs = []string{scenario.GetSpaceGUID("org1", "space2")}
+
+		billableEvents, err := db.Schema.GetBillableEvents(filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(billableEvents).To(HaveLen(1))
+		Expect(billableEvents[0].SpaceName).To(Equal("space2"))
+
+		usageEvents, err := db.Schema.GetUsageEvents(filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(usageEvents).To(HaveLen(1))
+		Expect(usageEvents[0].SpaceName).To(Equal("space2"))
+
+		Expect(db.Schema.Consolidate(january)).To(Succeed())
+		consolidatedEvents, err := db.Schema.GetConsolidatedBillableEvents(filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(consolidatedEvents).To(HaveLen(1))
+		Expect(consolidatedEvents[0].SpaceName).To(Equal("space2"))
+	})
+
+	It("should filter by resource type, plan and resource", func() {
+		filter := january
+		filter.ResourceTypes = []string{"app"}
+		filter.PlanGUIDs = []string{eventstore.ComputePlanGUID}
+		filter.ResourceGUIDs = []string{scenario.GetAppGUID("org1", "space1", "app1")}
+
+		billableEvents, err := db.Schema.GetBillableEvents(filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(billableEvents).To(HaveLen(1))
+		Expect(billableEvents[0].ResourceName).To(Equal("app1"))
+
+		filter.ResourceTypes = []string{"postgres"}
+		billableEvents, err = db.Schema.GetBillableEvents(filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(billableEvents).To(BeEmpty())
+	})
+
+	It("should return pages of billable, consolidated and usage events in event order", func() {
+		filter := january
+		filter.Limit = 1
+
+		first, err := db.Schema.GetBillableEvents(filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(first).To(HaveLen(1))
+
+		filter.After = &eventio.EventKey{EventGUID: first[0].EventGUID, PlanGUID: first[0].PlanGUID}
+		second, err := db.Schema.GetBillableEvents(filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second).To(HaveLen(1))
+		Expect(second[0].EventGUID > first[0].EventGUID).To(BeTrue())
+
+		usageEvents, err := db.Schema.GetUsageEvents(filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(usageEvents).To(HaveLen(1))
+		Expect(usageEvents[0].EventGUID).To(Equal(second[0].EventGUID))
+
+		Expect(db.Schema.Consolidate(january)).To(Succeed())
+		consolidatedEvents, err := db.Schema.GetConsolidatedBillableEvents(filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(consolidatedEvents).To(HaveLen(1))
+		Expect(consolidatedEvents[0].EventGUID).To(Equal(second[0].EventGUID))
+
+		filter.After = &eventio.EventKey{EventGUID: second[0].EventGUID, PlanGUID: second[0].PlanGUID}
+		last, err := db.Schema.GetBillableEvents(filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(last).To(BeEmpty())
+	})
+
+	It("should price the part of each event within a range given as timestamps", func() {
+		filter := eventio.EventFilter{
+			RangeStart: "2001-01-01T00:30:00Z",
+			RangeStop:  "2001-01-01T02:00:00+01:00",
+			SpaceGUIDs: []string{scenario.GetSpaceGUID("org1", "space1")},
+		}
+
+		billableEvents, err := db.Schema.GetBillableEvents(filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(billableEvents).To(HaveLen(1))
+		Expect(billableEvents[0].EventStart).To(Equal("2001-01-01T00:30:00+00:00"))
+		Expect(billableEvents[0].EventStop).To(Equal("2001-01-01T01:00:00+00:00"))
+		Expect(billableEvents[0].Price.ExVAT.Equal(eventio.MustParseDecimal("0.01"))).To(BeTrue())
+	})
 })
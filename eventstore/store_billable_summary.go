@@ -0,0 +1,305 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+var _ eventio.BillableSummaryReader = &EventStore{}
+
+// summaryColumns are the columns that the prices are grouped by for each
+// group, and the column with the name that goes with them if there is one
+var summaryColumns = []struct {
+	group eventio.SummaryGroup
+	id    string
+	name  string
+}{
+	{eventio.GroupByOrg, "org_guid", "org_name"},
+	{eventio.GroupBySpace, "space_guid", "space_name"},
+	{eventio.GroupByPlan, "plan_guid", "plan_name"},
+	{eventio.GroupByResourceType, "resource_type", ""},
+	{eventio.GroupByResource, "resource_guid", "resource_name"},
+}
+
+// GetBillableSummary totals the prices of the billable events for the
+// filter by the groups in filter.GroupBy. Consolidated months are totalled
+// from their consolidated events, everything else is priced with the same
+// query as GetBillableEventRows.
+//
+// Consolidated events can not be split into days, so when grouping by day
+// every day is priced from the current events, one day at a time.
+func (s *EventStore) GetBillableSummary(filter eventio.BillableSummaryFilter) ([]eventio.BillableSummary, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := checkCurrencyRateFrom(tx, filter.Currency, filter.RangeStart); err != nil {
+		return nil, err
+	}
+	months, err := filter.SplitByMonth()
+	if err != nil {
+		return nil, err
+	}
+	summaries := summaryTotals{}
+	for _, monthFilter := range months {
+		if filter.Has(eventio.GroupByDay) {
+			days, err := monthFilter.SplitByDay()
+			if err != nil {
+				return nil, err
+			}
+			for _, dayFilter := range days {
+				groups, err := s.getBillableSummary(tx, filter.GroupBy, dayFilter, false)
+				if err != nil {
+					return nil, err
+				}
+				summaries.add(filter, dayFilter, groups)
+			}
+			continue
+		}
+		isConsolidated, err := s.isRangeConsolidated(tx, monthFilter)
+		if err != nil {
+			return nil, err
+		}
+		groups, err := s.getBillableSummary(tx, filter.GroupBy, monthFilter, isConsolidated)
+		if err != nil {
+			return nil, err
+		}
+		summaries.add(filter, monthFilter, groups)
+	}
+	return summaries.sorted(), nil
+}
+
+// getBillableSummary totals the prices for a range within a single month by
+// every group apart from month and day
+func (s *EventStore) getBillableSummary(tx *sql.Tx, groupBy []eventio.SummaryGroup, filter eventio.EventFilter, consolidated bool) ([]eventio.BillableSummary, error) {
+	groupColumns := []string{}
+	selectColumns := []string{}
+	for _, column := range summaryColumns {
+		for _, group := range groupBy {
+			if group != column.group {
+				continue
+			}
+			groupColumns = append(groupColumns, column.id)
+			selectColumns = append(selectColumns, column.id)
+			if column.name != "" {
+				selectColumns = append(selectColumns, fmt.Sprintf(
+					"(array_agg(%s order by upper(duration) desc))[1] as %s", column.name, column.name,
+				))
+			}
+		}
+	}
+	selectColumns = append(selectColumns,
+		"coalesce(sum(ex_vat), 0)::text as ex_vat",
+		"coalesce(sum(inc_vat), 0)::text as inc_vat",
+	)
+	groupQuery := ""
+	if len(groupColumns) > 0 {
+		groupQuery = "group by " + strings.Join(groupColumns, ", ")
+	}
+	summaryQuery := `
+		select
+			%s
+		from (
+			%s
+		) as summary_components
+		%s
+	`
+
+	var query string
+	var args []interface{}
+	if consolidated {
+		components, componentArgs := consolidatedSummaryComponents(filter)
+		query, args = fmt.Sprintf(summaryQuery, strings.Join(selectColumns, ",\n"), components, groupQuery), componentArgs
+	} else {
+		var err error
+		query, args, err = WithBillableEvents(fmt.Sprintf(summaryQuery, strings.Join(selectColumns, ",\n"), `
+			select
+				org_guid,
+				org_name,
+				space_guid,
+				space_name,
+				plan_guid,
+				plan_name,
+				resource_type,
+				resource_guid,
+				resource_name,
+				duration,
+				price_ex_vat as ex_vat,
+				price_ex_vat * (1 + vat_rate) as inc_vat
+			from
+				components_with_price
+		`, groupQuery), filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	startTime := time.Now()
+	rows, err := queryJSON(tx, query, args...)
+	elapsed := time.Since(startTime)
+	if err != nil {
+		s.logger.Error("get-billable-summary-query", err, lager.Data{
+			"filter":       filter,
+			"group_by":     groupBy,
+			"consolidated": consolidated,
+			"elapsed":      int64(elapsed),
+		})
+		return nil, err
+	}
+	s.logger.Info("get-billable-summary-query", lager.Data{
+		"filter":       filter,
+		"group_by":     groupBy,
+		"consolidated": consolidated,
+		"elapsed":      int64(elapsed),
+	})
+	defer rows.Close()
+
+	summaries := []eventio.BillableSummary{}
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var summary eventio.BillableSummary
+		if err := json.Unmarshal(b, &summary); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// consolidatedSummaryComponents returns a query for the price components of
+// the consolidated events for a month, with the same columns as the
+// components of unconsolidated events
+func consolidatedSummaryComponents(filter eventio.EventFilter) (string, []interface{}) {
+	args := []interface{}{
+		fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop), // $1
+	}
+	filterQuery, args := eventFilterQuery(filter, "cbe.", args)
+
+	price := "cbe.price"
+	if filter.Currency != "" {
+		args = append(args, filter.Currency)
+		price = fmt.Sprintf(`convert_price(cbe.price, (
+			select cr.rate
+			from currency_rates cr
+			where cr.code = $%d and cr.valid_from <= lower(cbe.consolidated_range)
+			order by cr.valid_from desc
+			limit 1
+		), $%d)`, len(args), len(args))
+	}
+
+	return fmt.Sprintf(`
+		select
+			cbe.org_guid,
+			cbe.org_name,
+			cbe.space_guid,
+			cbe.space_name,
+			cbe.plan_guid,
+			d.detail->>'plan_name' as plan_name,
+			cbe.resource_type,
+			cbe.resource_guid,
+			cbe.resource_name,
+			cbe.duration,
+			(d.detail->>'ex_vat')::numeric as ex_vat,
+			(d.detail->>'inc_vat')::numeric as inc_vat
+		from
+			consolidated_billable_events cbe,
+			jsonb_array_elements((%s)->'details') as d(detail)
+		where
+			cbe.consolidated_range = $1::tstzrange
+			%s
+	`, price, filterQuery), args
+}
+
+// summaryTotals adds up the summaries of each month or day into the totals
+// for the whole range
+type summaryTotals struct {
+	keys   []string
+	totals map[string]*eventio.BillableSummary
+}
+
+func (t *summaryTotals) add(filter eventio.BillableSummaryFilter, rangeFilter eventio.EventFilter, summaries []eventio.BillableSummary) {
+	if t.totals == nil {
+		t.totals = map[string]*eventio.BillableSummary{}
+	}
+	for _, summary := range summaries {
+		if filter.Has(eventio.GroupByMonth) {
+			summary.Month = rangeFilter.RangeStart[:len("2006-01")]
+		}
+		if filter.Has(eventio.GroupByDay) {
+			summary.Day = rangeFilter.RangeStart[:len("2006-01-02")]
+		}
+		summary.CurrencyCode = filter.Currency
+		if summary.CurrencyCode == "" {
+			summary.CurrencyCode = eventio.BaseCurrency
+		}
+		key := strings.Join([]string{
+			summary.OrgGUID,
+			summary.SpaceGUID,
+			summary.PlanGUID,
+			summary.ResourceType,
+			summary.ResourceGUID,
+			summary.Month,
+			summary.Day,
+		}, "/")
+		total, ok := t.totals[key]
+		if !ok {
+			total := summary
+			t.keys = append(t.keys, key)
+			t.totals[key] = &total
+			continue
+		}
+		// ranges are added in order so the names are the latest ones
+		exVAT, incVAT := total.ExVAT.Add(summary.ExVAT), total.IncVAT.Add(summary.IncVAT)
+		*total = summary
+		total.ExVAT, total.IncVAT = exVAT, incVAT
+	}
+}
+
+// sorted returns the totals by month and day, then by name
+func (t *summaryTotals) sorted() []eventio.BillableSummary {
+	summaries := []eventio.BillableSummary{}
+	for _, key := range t.keys {
+		summaries = append(summaries, *t.totals[key])
+	}
+	sortKey := func(s eventio.BillableSummary) []string {
+		return []string{
+			s.Month, s.Day,
+			s.OrgName, s.OrgGUID,
+			s.SpaceName, s.SpaceGUID,
+			s.PlanName, s.PlanGUID,
+			s.ResourceType,
+			s.ResourceName, s.ResourceGUID,
+		}
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		a, b := sortKey(summaries[i]), sortKey(summaries[j])
+		for n := range a {
+			if a[n] != b[n] {
+				return a[n] < b[n]
+			}
+		}
+		return false
+	})
+	return summaries
+}
@@ -0,0 +1,110 @@
+package eventstore_test
+
+import (
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetBillableSummary", func() {
+	var (
+		cfg      eventstore.Config
+		scenario *testenv.TestScenario
+		db       *testenv.TempDB
+		january  = eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		}
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		scenario = testenv.NewTestScenario("2001-01-01T00:00")
+		scenario.AddComputePlan()
+		scenario.AppLifeCycle("org1", "space1", "app1",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+100h", State: "STOPPED"},
+		)
+		scenario.AppLifeCycle("org1", "space2", "app2",
+			testenv.EventInfo{Delta: "+1h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+6h", State: "STOPPED"},
+		)
+		scenario.AppLifeCycle("org2", "space3", "app3",
+			testenv.EventInfo{Delta: "+1h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+2h", State: "STOPPED"},
+		)
+
+		var err error
+		db, err = scenario.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(db.Schema.Refresh()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("should give a single total when there are no groups", func() {
+		summaries, err := db.Schema.GetBillableSummary(eventio.BillableSummaryFilter{EventFilter: january})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(summaries).To(HaveLen(1))
+		Expect(summaries[0].CurrencyCode).To(Equal("GBP"))
+		Expect(summaries[0].ExVAT.Equal(eventio.MustParseDecimal("1.06"))).To(BeTrue())
+		Expect(summaries[0].IncVAT.Equal(eventio.MustParseDecimal("1.272"))).To(BeTrue())
+	})
+
+	It("should total each org and space", func() {
+		summaries, err := db.Schema.GetBillableSummary(eventio.BillableSummaryFilter{
+			EventFilter: january,
+			GroupBy:     []eventio.SummaryGroup{eventio.GroupByOrg, eventio.GroupBySpace},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(summaries).To(HaveLen(3))
+		Expect(summaries[0].OrgName).To(Equal("org1"))
+		Expect(summaries[0].SpaceName).To(Equal("space1"))
+		Expect(summaries[0].ExVAT.Equal(eventio.MustParseDecimal("1"))).To(BeTrue())
+		Expect(summaries[1].SpaceName).To(Equal("space2"))
+		Expect(summaries[1].ExVAT.Equal(eventio.MustParseDecimal("0.05"))).To(BeTrue())
+		Expect(summaries[2].OrgGUID).To(Equal(scenario.GetOrgGUID("org2")))
+		Expect(summaries[2].ExVAT.Equal(eventio.MustParseDecimal("0.01"))).To(BeTrue())
+	})
+
+	It("should give the same totals once the month is consolidated", func() {
+		filter := eventio.BillableSummaryFilter{
+			EventFilter: eventio.EventFilter{RangeStart: "2001-01-01", RangeStop: "2001-03-01"},
+			GroupBy:     []eventio.SummaryGroup{eventio.GroupByOrg, eventio.GroupByMonth},
+		}
+		before, err := db.Schema.GetBillableSummary(filter)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(db.Schema.Consolidate(january)).To(Succeed())
+
+		after, err := db.Schema.GetBillableSummary(filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(after).To(HaveLen(2))
+		Expect(after[0].Month).To(Equal("2001-01"))
+		for i := range after {
+			Expect(after[i].OrgGUID).To(Equal(before[i].OrgGUID))
+			Expect(after[i].ExVAT.Equal(before[i].ExVAT)).To(BeTrue())
+		}
+	})
+
+	It("should total each day", func() {
+		summaries, err := db.Schema.GetBillableSummary(eventio.BillableSummaryFilter{
+			EventFilter: eventio.EventFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-01-06",
+				OrgGUIDs:   []string{scenario.GetOrgGUID("org1")},
+			},
+			GroupBy: []eventio.SummaryGroup{eventio.GroupByDay},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(summaries).To(HaveLen(5))
+		Expect(summaries[0].Day).To(Equal("2001-01-01"))
+		Expect(summaries[0].ExVAT.Equal(eventio.MustParseDecimal("0.29"))).To(BeTrue())
+		Expect(summaries[4].Day).To(Equal("2001-01-05"))
+		Expect(summaries[4].ExVAT.Equal(eventio.MustParseDecimal("0.04"))).To(BeTrue())
+	})
+})
@@ -0,0 +1,152 @@
+package eventstore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alphagov/paas-billing/eventio"
+	uuid "github.com/satori/go.uuid"
+)
+
+var _ eventio.BillingPeriodReader = &EventStore{}
+
+// checkBillingPeriods fails if any org billing period in the config is not
+// valid or if an org has more than one
+func (s *EventStore) checkBillingPeriods() error {
+	seen := map[string]bool{}
+	for _, bp := range s.cfg.OrgBillingPeriods {
+		if _, err := uuid.FromString(bp.OrgGUID); err != nil {
+			return fmt.Errorf("invalid org billing period: '%s' is not a valid guid", bp.OrgGUID)
+		}
+		if err := bp.Validate(); err != nil {
+			return fmt.Errorf("invalid org billing period for %s: %s", bp.OrgGUID, err)
+		}
+		if seen[strings.ToLower(bp.OrgGUID)] {
+			return fmt.Errorf("invalid org billing period: %s has more than one billing period", bp.OrgGUID)
+		}
+		seen[strings.ToLower(bp.OrgGUID)] = true
+	}
+	return nil
+}
+
+// orgBillingPeriods returns the orgs that are not billed by the default
+// billing period, keyed by lower case org guid
+func (s *EventStore) orgBillingPeriods() map[string]eventio.BillingPeriod {
+	periods := map[string]eventio.BillingPeriod{}
+	for _, bp := range s.cfg.OrgBillingPeriods {
+		if !bp.Equal(eventio.DefaultBillingPeriod) {
+			periods[strings.ToLower(bp.OrgGUID)] = bp
+		}
+	}
+	return periods
+}
+
+func (s *EventStore) billingPeriodOf(orgGUID string) eventio.BillingPeriod {
+	if bp, ok := s.orgBillingPeriods()[strings.ToLower(orgGUID)]; ok {
+		return bp
+	}
+	return eventio.DefaultBillingPeriod
+}
+
+// GetBillingPeriod returns the billing period shared by every one of the
+// orgs. The default billing period is returned if no orgs are given or if
+// they are billed by different periods.
+func (s *EventStore) GetBillingPeriod(orgGUIDs []string) (eventio.BillingPeriod, error) {
+	if len(orgGUIDs) == 0 {
+		return eventio.DefaultBillingPeriod, nil
+	}
+	period := s.billingPeriodOf(orgGUIDs[0])
+	for _, orgGUID := range orgGUIDs[1:] {
+		if !s.billingPeriodOf(orgGUID).Equal(period) {
+			return eventio.DefaultBillingPeriod, nil
+		}
+	}
+	period.OrgGUID = ""
+	return period, nil
+}
+
+// isBillingPeriodFor reports whether the range of the filter is exactly one
+// billing period of every org in the filter. Without an org filter the range
+// must be a billing period of every org.
+func (s *EventStore) isBillingPeriodFor(filter eventio.EventFilter) bool {
+	if len(filter.OrgGUIDs) == 0 {
+		for _, bp := range s.orgBillingPeriods() {
+			if !bp.IsPeriod(filter) {
+				return false
+			}
+		}
+		return eventio.DefaultBillingPeriod.IsPeriod(filter)
+	}
+	for _, orgGUID := range filter.OrgGUIDs {
+		if !s.billingPeriodOf(orgGUID).IsPeriod(filter) {
+			return false
+		}
+	}
+	return true
+}
+
+// consolidationOrgs returns which orgs are consolidated for the range of
+// the filter. When the range is a default billing period every org is
+// consolidated apart from those in exclude, otherwise only the orgs in
+// include are. An error is returned if the range is not a billing period of
+// any org.
+func (s *EventStore) consolidationOrgs(filter eventio.EventFilter) (include []string, exclude []string, err error) {
+	isDefaultPeriod := eventio.DefaultBillingPeriod.IsPeriod(filter)
+	for orgGUID, bp := range s.orgBillingPeriods() {
+		if bp.IsPeriod(filter) {
+			include = append(include, orgGUID)
+		} else {
+			exclude = append(exclude, orgGUID)
+		}
+	}
+	sort.Strings(include)
+	sort.Strings(exclude)
+	if isDefaultPeriod {
+		return nil, exclude, nil
+	}
+	if len(include) == 0 {
+		return nil, nil, fmt.Errorf(
+			"consolidation range [%s, %s) is not a billing period of any org",
+			filter.RangeStart, filter.RangeStop,
+		)
+	}
+	return include, nil, nil
+}
+
+// consolidationRanges returns every whole billing period of any org from
+// the start of the period containing startAt up to endAt, ordered by start
+// and then stop
+func (s *EventStore) consolidationRanges(startAt string, endAt string) ([]eventio.EventFilter, error) {
+	ranges, err := eventio.DefaultBillingPeriod.FullPeriods(startAt, endAt)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, filter := range ranges {
+		seen[filter.RangeStart+"/"+filter.RangeStop] = true
+	}
+	for _, bp := range s.orgBillingPeriods() {
+		periods, err := bp.FullPeriods(startAt, endAt)
+		if err != nil {
+			return nil, err
+		}
+		for _, filter := range periods {
+			if key := filter.RangeStart + "/" + filter.RangeStop; !seen[key] {
+				seen[key] = true
+				ranges = append(ranges, filter)
+			}
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		iStart, _ := eventio.ParseRangeTime(ranges[i].RangeStart)
+		jStart, _ := eventio.ParseRangeTime(ranges[j].RangeStart)
+		if !iStart.Equal(jStart) {
+			return iStart.Before(jStart)
+		}
+		iStop, _ := eventio.ParseRangeTime(ranges[i].RangeStop)
+		jStop, _ := eventio.ParseRangeTime(ranges[j].RangeStop)
+		return iStop.Before(jStop)
+	})
+	return ranges, nil
+}
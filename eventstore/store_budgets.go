@@ -0,0 +1,280 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/lib/pq"
+)
+
+var _ eventio.BudgetReader = &EventStore{}
+var _ eventio.BudgetWriter = &EventStore{}
+var _ eventio.BudgetAlerter = &EventStore{}
+
+// GetBudgets returns the budgets of the orgs, or of every org if no orgs are
+// given
+func (s *EventStore) GetBudgets(orgGUIDs []string) ([]eventio.Budget, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return getBudgets(tx, orgGUIDs)
+}
+
+// SetBudget creates or replaces the budget of an org
+func (s *EventStore) SetBudget(budget eventio.Budget) (eventio.Budget, error) {
+	if err := budget.Validate(); err != nil {
+		return eventio.Budget{}, err
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return eventio.Budget{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		insert into budgets (org_guid, amount, thresholds)
+		values ($1, $2, $3)
+		on conflict (org_guid) do update set
+			amount = excluded.amount,
+			thresholds = excluded.thresholds,
+			updated_at = now()
+	`, budget.OrgGUID, budget.Amount, pq.Array(budget.Thresholds))
+	if err != nil {
+		return eventio.Budget{}, wrapPqError(err, "set-budget")
+	}
+	budgets, err := getBudgets(tx, []string{budget.OrgGUID})
+	if err != nil {
+		return eventio.Budget{}, err
+	}
+	if len(budgets) != 1 {
+		return eventio.Budget{}, eventio.ErrBudgetNotFound
+	}
+	s.logger.Info("set-budget", lager.Data{
+		"org_guid":   budget.OrgGUID,
+		"amount":     budget.Amount,
+		"thresholds": budget.Thresholds,
+	})
+	return budgets[0], tx.Commit()
+}
+
+// DeleteBudget removes the budget of an org. Alerts that have already been
+// raised for the org are kept.
+func (s *EventStore) DeleteBudget(orgGUID string) error {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	result, err := s.db.ExecContext(ctx, `delete from budgets where org_guid = $1`, orgGUID)
+	if err != nil {
+		return wrapPqError(err, "delete-budget")
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return eventio.ErrBudgetNotFound
+	}
+	s.logger.Info("delete-budget", lager.Data{"org_guid": orgGUID})
+	return nil
+}
+
+// EvaluateBudgets compares the spend ex VAT of each org with a budget, for
+// the org's billing period containing the last refresh, against the
+// thresholds of its budget. An alert is recorded for each threshold reached
+// that has not already been reached this period. Every alert that has not
+// been sent is returned, oldest first, including any that failed to be sent
+// in earlier periods.
+func (s *EventStore) EvaluateBudgets() ([]eventio.BudgetAlert, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	startTime := time.Now()
+	budgets, err := getBudgets(tx, nil)
+	if err != nil {
+		return nil, err
+	}
+	var asOf time.Time
+	if err := tx.QueryRow(`select coalesce(min(processed_at), now()) from event_watermarks`).Scan(&asOf); err != nil {
+		return nil, err
+	}
+
+	// orgs are grouped by their current billing period so that the spend of
+	// each group can be summarised at once
+	type budgetPeriod struct {
+		filter eventio.EventFilter
+		month  time.Time
+	}
+	periods := []budgetPeriod{}
+	periodOf := map[string]int{}
+	seen := map[string]int{}
+	for _, budget := range budgets {
+		period, err := s.GetBillingPeriod([]string{budget.OrgGUID})
+		if err != nil {
+			return nil, err
+		}
+		filter, err := period.PeriodContaining(asOf)
+		if err != nil {
+			return nil, err
+		}
+		key := filter.RangeStart + "/" + filter.RangeStop
+		i, ok := seen[key]
+		if !ok {
+			// the alerts are keyed by the date the period starts in the
+			// org's time zone
+			loc, err := time.LoadLocation(period.TimeZone)
+			if err != nil {
+				return nil, err
+			}
+			start, err := eventio.ParseRangeTime(filter.RangeStart)
+			if err != nil {
+				return nil, err
+			}
+			start = start.In(loc)
+			periods = append(periods, budgetPeriod{
+				filter: filter,
+				month:  time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC),
+			})
+			i = len(periods) - 1
+			seen[key] = i
+		}
+		periods[i].filter.OrgGUIDs = append(periods[i].filter.OrgGUIDs, budget.OrgGUID)
+		periodOf[budget.OrgGUID] = i
+	}
+
+	spent := map[string]eventio.BillableSummary{}
+	for _, period := range periods {
+		summaries, err := s.getBillableSummary(tx, []eventio.SummaryGroup{eventio.GroupByOrg}, period.filter, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range summaries {
+			spent[summary.OrgGUID] = summary
+		}
+	}
+
+	raised := 0
+	percent := eventio.MustParseDecimal("0.01")
+	for _, budget := range budgets {
+		summary, ok := spent[budget.OrgGUID]
+		if !ok {
+			continue
+		}
+		for _, threshold := range budget.Thresholds {
+			limit := budget.Amount.Mul(eventio.NewDecimalFromInt(threshold)).Mul(percent)
+			if summary.ExVAT.Cmp(limit) < 0 {
+				continue
+			}
+			result, err := tx.Exec(`
+				insert into budget_alerts (
+					org_guid, org_name, month, threshold, amount, spent, currency_code
+				) values (
+					$1, $2, $3, $4, $5, $6, $7
+				) on conflict (org_guid, month, threshold) do nothing
+			`, budget.OrgGUID, summary.OrgName, periods[periodOf[budget.OrgGUID]].month, threshold, budget.Amount, summary.ExVAT, eventio.BaseCurrency)
+			if err != nil {
+				return nil, wrapPqError(err, "raise-budget-alert")
+			}
+			if n, err := result.RowsAffected(); err != nil {
+				return nil, err
+			} else if n > 0 {
+				raised++
+			}
+		}
+	}
+
+	alerts, err := getBudgetAlerts(tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	s.logger.Info("evaluate-budgets", lager.Data{
+		"budgets": len(budgets),
+		"raised":  raised,
+		"unsent":  len(alerts),
+		"elapsed": int64(time.Since(startTime)),
+	})
+	return alerts, nil
+}
+
+// SetBudgetAlertSent records that an alert has been delivered
+func (s *EventStore) SetBudgetAlertSent(id int64) error {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, `
+		update budget_alerts set sent_at = now()
+		where id = $1 and sent_at is null
+	`, id)
+	if err != nil {
+		return wrapPqError(err, "set-budget-alert-sent")
+	}
+	return nil
+}
+
+func getBudgets(tx *sql.Tx, orgGUIDs []string) ([]eventio.Budget, error) {
+	args := []interface{}{}
+	orgQuery := ""
+	if len(orgGUIDs) > 0 {
+		args = append(args, pq.Array(orgGUIDs))
+		orgQuery = "where org_guid = any($1::uuid[])"
+	}
+	rows, err := tx.Query(fmt.Sprintf(`
+		select org_guid, amount, thresholds, updated_at
+		from budgets
+		%s
+		order by org_guid
+	`, orgQuery), args...)
+	if err != nil {
+		return nil, wrapPqError(err, "get-budgets")
+	}
+	defer rows.Close()
+	budgets := []eventio.Budget{}
+	for rows.Next() {
+		var budget eventio.Budget
+		if err := rows.Scan(&budget.OrgGUID, &budget.Amount, pq.Array(&budget.Thresholds), &budget.UpdatedAt); err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, rows.Err()
+}
+
+func getBudgetAlerts(tx *sql.Tx) ([]eventio.BudgetAlert, error) {
+	rows, err := tx.Query(`
+		select
+			id, org_guid, org_name, to_char(month, 'YYYY-MM-DD'), threshold,
+			amount, spent, currency_code, created_at, sent_at
+		from budget_alerts
+		where sent_at is null
+		order by id
+	`)
+	if err != nil {
+		return nil, wrapPqError(err, "get-budget-alerts")
+	}
+	defer rows.Close()
+	alerts := []eventio.BudgetAlert{}
+	for rows.Next() {
+		var alert eventio.BudgetAlert
+		if err := rows.Scan(
+			&alert.ID, &alert.OrgGUID, &alert.OrgName, &alert.PeriodStart, &alert.Threshold,
+			&alert.Amount, &alert.Spent, &alert.CurrencyCode, &alert.CreatedAt, &alert.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
@@ -0,0 +1,161 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Budgets", func() {
+
+	var (
+		cfg eventstore.Config
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  eventstore.ComputePlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "APP_PLAN_1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "compute",
+					Formula:      "$time_in_seconds * $number_of_nodes * 0.01",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+	})
+
+	It("should set, replace and delete the budget of an org", func() {
+		db, err := testenv.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		store := db.Schema
+
+		orgGUID := "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		budget, err := store.SetBudget(eventio.Budget{OrgGUID: orgGUID, Amount: eventio.MustParseDecimal("100")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(budget.Thresholds).To(Equal([]int64{50, 80, 100}))
+
+		_, err = store.SetBudget(eventio.Budget{OrgGUID: orgGUID, Amount: eventio.MustParseDecimal("200"), Thresholds: []int64{90}})
+		Expect(err).ToNot(HaveOccurred())
+		budgets, err := store.GetBudgets([]string{orgGUID})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(budgets).To(HaveLen(1))
+		Expect(budgets[0].Amount.Equal(eventio.MustParseDecimal("200"))).To(BeTrue())
+		Expect(budgets[0].Thresholds).To(Equal([]int64{90}))
+
+		Expect(store.DeleteBudget(orgGUID)).To(Succeed())
+		Expect(store.DeleteBudget(orgGUID)).To(Equal(eventio.ErrBudgetNotFound))
+		Expect(store.GetBudgets(nil)).To(BeEmpty())
+	})
+
+	It("should raise an alert once a month for each threshold that an org's spend reaches", func() {
+		db, err := testenv.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		store := db.Schema
+
+		overspentOrgGUID := "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		underspentOrgGUID := "61ba75ef-edc0-47ad-a633-a8f6e8770944"
+		appEvent := func(guid string, orgGUID string, createdAt time.Time) eventio.RawEvent {
+			return eventio.RawEvent{
+				GUID:       guid,
+				Kind:       "app",
+				CreatedAt:  createdAt,
+				RawMessage: json.RawMessage(`{"state": "STARTED", "app_guid": "` + guid + `", "app_name": "APP", "org_guid": "` + orgGUID + `", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "SPACE1", "process_type": "web", "instance_count": 2, "previous_state": "STOPPED", "memory_in_mb_per_instance": 1000}`),
+			}
+		}
+		Expect(store.StoreEvents([]eventio.RawEvent{
+			appEvent("ae28a572-f485-48e1-87d0-98b7b8b66dfa", overspentOrgGUID, time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)),
+			appEvent("ae28a573-f485-48e1-87d0-98b7b8b66dfa", underspentOrgGUID, time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)),
+		})).To(Succeed())
+		Expect(store.Refresh()).To(Succeed())
+
+		_, err = store.SetBudget(eventio.Budget{OrgGUID: overspentOrgGUID, Amount: eventio.MustParseDecimal("0.01"), Thresholds: []int64{80, 100}})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = store.SetBudget(eventio.Budget{OrgGUID: underspentOrgGUID, Amount: eventio.MustParseDecimal("1000000000")})
+		Expect(err).ToNot(HaveOccurred())
+
+		alerts, err := store.EvaluateBudgets()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(alerts).To(HaveLen(2))
+		Expect(alerts[0].OrgGUID).To(Equal(overspentOrgGUID))
+		Expect(alerts[0].Threshold).To(Equal(int64(80)))
+		Expect(alerts[0].PeriodStart).To(Equal(time.Now().UTC().Format("2006-01") + "-01"))
+		Expect(alerts[0].CurrencyCode).To(Equal("GBP"))
+		Expect(alerts[0].Spent.Cmp(alerts[0].Amount)).To(Equal(1))
+		Expect(alerts[0].SentAt).To(BeNil())
+		Expect(alerts[1].Threshold).To(Equal(int64(100)))
+
+		Expect(store.SetBudgetAlertSent(alerts[0].ID)).To(Succeed())
+
+		unsent, err := store.EvaluateBudgets()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unsent).To(HaveLen(1), "expected the alerts not to be raised again and only the unsent alert to be returned")
+		Expect(unsent[0].ID).To(Equal(alerts[1].ID))
+	})
+
+	It("should raise alerts for each org's own billing period and return unsent alerts from earlier periods", func() {
+		orgGUID := "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		cfg.AddOrgBillingPeriod(eventio.BillingPeriod{
+			OrgGUID:  orgGUID,
+			StartDay: 15,
+			TimeZone: "Australia/Sydney",
+		})
+		db, err := testenv.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		store := db.Schema
+
+		Expect(store.StoreEvents([]eventio.RawEvent{
+			{
+				GUID:       "ae28a572-f485-48e1-87d0-98b7b8b66dfa",
+				Kind:       "app",
+				CreatedAt:  time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+				RawMessage: json.RawMessage(`{"state": "STARTED", "app_guid": "ae28a572-f485-48e1-87d0-98b7b8b66dfa", "app_name": "APP", "org_guid": "` + orgGUID + `", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "SPACE1", "process_type": "web", "instance_count": 2, "previous_state": "STOPPED", "memory_in_mb_per_instance": 1000}`),
+			},
+		})).To(Succeed())
+		Expect(store.Refresh()).To(Succeed())
+		Expect(db.Insert("budget_alerts", testenv.Row{
+			"org_guid":      orgGUID,
+			"org_name":      "ORG",
+			"month":         "2001-01-15",
+			"threshold":     100,
+			"amount":        1,
+			"spent":         2,
+			"currency_code": "GBP",
+		})).To(Succeed())
+
+		_, err = store.SetBudget(eventio.Budget{OrgGUID: orgGUID, Amount: eventio.MustParseDecimal("0.01"), Thresholds: []int64{100}})
+		Expect(err).ToNot(HaveOccurred())
+
+		alerts, err := store.EvaluateBudgets()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(alerts).To(HaveLen(2))
+		Expect(alerts[0].PeriodStart).To(Equal("2001-01-15"))
+
+		sydney, err := time.LoadLocation("Australia/Sydney")
+		Expect(err).ToNot(HaveOccurred())
+		now := time.Now().In(sydney)
+		periodStart := time.Date(now.Year(), now.Month(), 15, 0, 0, 0, 0, sydney)
+		if now.Before(periodStart) {
+			periodStart = periodStart.AddDate(0, -1, 0)
+		}
+		Expect(alerts[1].PeriodStart).To(Equal(periodStart.Format("2006-01-02")))
+		Expect(
+			db.Query(`select to_char(month, 'YYYY-MM-DD') as month from budget_alerts order by id`),
+		).To(MatchJSON(testenv.Rows{
+			{"month": "2001-01-15"},
+			{"month": periodStart.Format("2006-01-02")},
+		}))
+	})
+})
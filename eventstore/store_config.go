@@ -2,15 +2,19 @@ package eventstore
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore/formula"
 )
 
 type Config struct {
-	VATRates           []eventio.VATRate      `json:"vat_rates"`            // vat rate
-	CurrencyRates      []eventio.CurrencyRate `json:"currency_rates"`       // exchange rates
-	PricingPlans       []eventio.PricingPlan  `json:"pricing_plans"`        // dataset to generate prices from
-	IgnoreMissingPlans bool                   `json:"ignore_missing_plans"` // if true, will generate missing plans that emit "£0", useful for testing
+	VATRates           []eventio.VATRate         `json:"vat_rates"`            // vat rate
+	CurrencyRates      []eventio.CurrencyRate    `json:"currency_rates"`       // exchange rates
+	PricingPlans       []eventio.PricingPlan     `json:"pricing_plans"`        // dataset to generate prices from
+	OrgTaxTreatments   []eventio.OrgTaxTreatment `json:"org_tax_treatments"`   // orgs taxed differently to the plans
+	OrgBillingPeriods  []eventio.BillingPeriod   `json:"org_billing_periods"`  // orgs billed other than by UTC calendar month
+	IgnoreMissingPlans bool                      `json:"ignore_missing_plans"` // if true, will generate missing plans that emit "£0", useful for testing
 }
 
 func (cfg *Config) AddPlan(p eventio.PricingPlan) {
@@ -25,6 +29,27 @@ func (cfg *Config) AddCurrencyRate(c eventio.CurrencyRate) {
 	cfg.CurrencyRates = append(cfg.CurrencyRates, c)
 }
 
+func (cfg *Config) AddOrgTaxTreatment(t eventio.OrgTaxTreatment) {
+	cfg.OrgTaxTreatments = append(cfg.OrgTaxTreatments, t)
+}
+
+func (cfg *Config) AddOrgBillingPeriod(p eventio.BillingPeriod) {
+	cfg.OrgBillingPeriods = append(cfg.OrgBillingPeriods, p)
+}
+
+// checkFormulas parses every pricing plan component formula and returns the
+// first that is invalid, along with where it is in the config
+func (cfg *Config) checkFormulas() error {
+	for i, pp := range cfg.PricingPlans {
+		for j, ppc := range pp.Components {
+			if _, err := formula.Parse(ppc.Formula); err != nil {
+				return fmt.Errorf("pricing_plans[%d].components[%d].formula: %s", i, j, err)
+			}
+		}
+	}
+	return nil
+}
+
 var _ eventio.PricingPlanReader = &EventStore{}
 
 func (s *EventStore) GetPricingPlans(filter eventio.TimeRangeFilter) ([]eventio.PricingPlan, error) {
@@ -54,6 +79,7 @@ func (s *EventStore) GetPricingPlans(filter eventio.TimeRangeFilter) ([]eventio.
 			vpp.memory_in_mb,
 			vpp.number_of_nodes,
 			vpp.storage_in_mb,
+			vpp.disk_in_mb,
 			json_agg(json_build_object(
 				'plan_guid', ppc.plan_guid::text,
 				'name', ppc.name,
@@ -74,7 +100,8 @@ func (s *EventStore) GetPricingPlans(filter eventio.TimeRangeFilter) ([]eventio.
 			vpp.name,
 			vpp.memory_in_mb,
 			vpp.number_of_nodes,
-			vpp.storage_in_mb
+			vpp.storage_in_mb,
+			vpp.disk_in_mb
 		order by
 			valid_from
 	`, filter.RangeStart, filter.RangeStop)
@@ -0,0 +1,226 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+// previewTables are the tables that a config is loaded into. While
+// previewing a config they are shadowed by temporary tables of the same name
+// so that the real pricing data is neither changed nor locked.
+var previewTables = []string{
+	"vat_rates",
+	"currency_rates",
+	"pricing_plans",
+	"pricing_plan_components",
+	"org_tax_treatments",
+	"billable_event_components",
+}
+
+// PriceChange is the total cost, excluding VAT, of the events of an org or a
+// plan under the current and proposed configs
+type PriceChange struct {
+	GUID       string `json:"guid"`
+	Name       string `json:"name"`
+	Current    string `json:"current"`
+	Proposed   string `json:"proposed"`
+	Difference string `json:"difference"`
+}
+
+// ConfigPreview is the effect that changing config would have on the cost of
+// the events in a range
+type ConfigPreview struct {
+	Orgs  []PriceChange `json:"orgs"`
+	Plans []PriceChange `json:"plans"`
+}
+
+// PreviewConfig prices the stored events for the given filter with both the
+// current and proposed configs and returns the change in cost for each org
+// and plan. If current is nil the latest stored pricing versions are used in
+// its place, as they include the versions added through the admin API that
+// the events are really priced with. Nothing is written, the configs are
+// loaded into temporary tables that are discarded afterwards.
+func (s *EventStore) PreviewConfig(current *Config, proposed Config, filter eventio.EventFilter) (*ConfigPreview, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultRefreshTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	startTime := time.Now()
+	for _, table := range previewTables {
+		if _, err := tx.Exec(fmt.Sprintf(
+			`create temporary table %s (like %s including all) on commit drop`, table, table,
+		)); err != nil {
+			return nil, wrapPqError(err, "create-preview-tables")
+		}
+	}
+	if _, err := tx.Exec(`
+		create temporary table config_preview (
+			config text not null,
+			org_guid uuid not null,
+			org_name text not null,
+			plan_guid uuid not null,
+			plan_name text not null,
+			cost numeric not null
+		) on commit drop
+	`); err != nil {
+		return nil, wrapPqError(err, "create-preview-tables")
+	}
+
+	for _, c := range []struct {
+		name string
+		cfg  *Config
+	}{
+		{"current", current},
+		{"proposed", &proposed},
+	} {
+		if err := s.priceWithConfig(tx, c.name, c.cfg, filter); err != nil {
+			return nil, fmt.Errorf("%s config: %s", c.name, err)
+		}
+	}
+
+	orgs, err := queryPriceChanges(tx, "org_guid", "org_name")
+	if err != nil {
+		return nil, err
+	}
+	plans, err := queryPriceChanges(tx, "plan_guid", "plan_name")
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("preview-config", lager.Data{
+		"filter":  filter,
+		"elapsed": int64(time.Since(startTime)),
+	})
+	return &ConfigPreview{
+		Orgs:  orgs,
+		Plans: plans,
+	}, nil
+}
+
+// priceWithConfig replaces the contents of the temporary pricing tables with
+// cfg, or the latest stored pricing versions if cfg is nil, and records the
+// cost of each org and plan as the named config
+func (s *EventStore) priceWithConfig(tx *sql.Tx, name string, cfg *Config, filter eventio.EventFilter) error {
+	for _, table := range previewTables {
+		if _, err := tx.Exec(fmt.Sprintf(`delete from pg_temp.%s`, table)); err != nil {
+			return err
+		}
+	}
+
+	if cfg == nil {
+		// the versions tables are not shadowed, so they are read from the
+		// real tables and loaded into the temporary ones
+		if err := loadPricingVersions(tx); err != nil {
+			return fmt.Errorf("failed to load the stored pricing versions: %s", err)
+		}
+		if s.cfg.IgnoreMissingPlans {
+			if err := s.generateMissingPlans(tx); err != nil {
+				return err
+			}
+		}
+	} else {
+		preview := New(s.ctx, s.db, s.logger.Session("preview", lager.Data{"config": name}), *cfg)
+		if err := preview.initVATRates(tx); err != nil {
+			return fmt.Errorf("failed to init VAT rates: %s", err)
+		}
+		if err := preview.initCurrencyRates(tx); err != nil {
+			return fmt.Errorf("failed to init currency rates: %s", err)
+		}
+		if err := preview.initPlans(tx); err != nil {
+			return fmt.Errorf("failed to init plans: %s", err)
+		}
+		if err := preview.initOrgTaxTreatments(tx); err != nil {
+			return fmt.Errorf("failed to init org tax treatments: %s", err)
+		}
+	}
+	if err := checkPlanConsistency(tx); err != nil {
+		return err
+	}
+
+	// only the resources with events in the range need pricing
+	if _, err := tx.Exec(`
+		insert into pg_temp.billable_event_components (
+			select * from generate_billable_event_components(array(
+				select distinct resource_guid from events where duration && $1::tstzrange
+			))
+		)
+	`, fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop)); err != nil {
+		return wrapPqError(err, "generate-billable-event-components")
+	}
+
+	query, args, err := WithBillableEvents(`
+		insert into pg_temp.config_preview (
+			select
+				$1::text,
+				org_guid,
+				org_name,
+				plan_guid,
+				plan_name,
+				sum(price_ex_vat)
+			from
+				components_with_price
+			group by
+				org_guid, org_name, plan_guid, plan_name
+		)
+	`, filter, name)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return wrapPqError(err, "price-events")
+	}
+	return nil
+}
+
+// queryPriceChanges totals the previewed costs grouped by the given guid
+// column, largest increase first
+func queryPriceChanges(tx *sql.Tx, guidColumn string, nameColumn string) ([]PriceChange, error) {
+	rows, err := tx.Query(fmt.Sprintf(`
+		with totals as (
+			select
+				%s as guid,
+				max(%s) as name,
+				coalesce(sum(case when config = 'current' then cost end), 0) as current,
+				coalesce(sum(case when config = 'proposed' then cost end), 0) as proposed
+			from
+				pg_temp.config_preview
+			group by
+				%s
+		)
+		select
+			guid,
+			name,
+			round(current, 2)::text,
+			round(proposed, 2)::text,
+			round(proposed - current, 2)::text
+		from
+			totals
+		order by
+			(proposed - current) desc, guid
+	`, guidColumn, nameColumn, guidColumn))
+	if err != nil {
+		return nil, wrapPqError(err, "query-price-changes")
+	}
+	defer rows.Close()
+	changes := []PriceChange{}
+	for rows.Next() {
+		var c PriceChange
+		if err := rows.Scan(&c.GUID, &c.Name, &c.Current, &c.Proposed, &c.Difference); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
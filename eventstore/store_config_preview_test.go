@@ -0,0 +1,136 @@
+package eventstore_test
+
+import (
+	"context"
+	"encoding/json"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PreviewConfig", func() {
+
+	var (
+		db       *testenv.TempDB
+		store    *eventstore.EventStore
+		current  eventstore.Config
+		proposed eventstore.Config
+		filter   eventio.EventFilter
+	)
+
+	var computePlan = func(formula string) eventio.PricingPlan {
+		return eventio.PricingPlan{
+			PlanGUID:  eventstore.ComputePlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "PLAN1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "compute",
+					Formula:      formula,
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		current = testenv.BasicConfig
+		current.AddPlan(computePlan("ceil($time_in_seconds/3600) * 0.01"))
+		proposed = testenv.BasicConfig
+		proposed.AddPlan(computePlan("ceil($time_in_seconds/3600) * 0.015"))
+
+		var err error
+		db, err = testenv.Open(current)
+		Expect(err).ToNot(HaveOccurred())
+
+		app1EventStart := testenv.Row{
+			"guid":        "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+			"created_at":  "2001-01-01T00:00Z",
+			"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "ORG1-SPACE1", "process_type": "web", "instance_count": 1, "previous_state": "STARTED", "memory_in_mb_per_instance": 1024}`),
+		}
+		app1EventStop := testenv.Row{
+			"guid":        "8d9036c5-8367-497d-bb56-94bfcac6621a",
+			"created_at":  "2001-01-01T02:00Z",
+			"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "ORG1-SPACE1", "process_type": "web", "instance_count": 1, "previous_state": "STARTED", "memory_in_mb_per_instance": 1024}`),
+		}
+		Expect(db.Insert("app_usage_events", app1EventStart, app1EventStop)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		store = eventstore.New(context.Background(), db.Conn, lager.NewLogger("test"), current)
+		filter = eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("should return the change in cost for each org and plan", func() {
+		preview, err := store.PreviewConfig(&current, proposed, filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(preview.Orgs).To(Equal([]eventstore.PriceChange{
+			{
+				GUID:       "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+				Name:       "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+				Current:    "0.02",
+				Proposed:   "0.03",
+				Difference: "0.01",
+			},
+		}))
+		Expect(preview.Plans).To(Equal([]eventstore.PriceChange{
+			{
+				GUID:       eventstore.ComputePlanGUID,
+				Name:       "PLAN1",
+				Current:    "0.02",
+				Proposed:   "0.03",
+				Difference: "0.01",
+			},
+		}))
+	})
+
+	It("should not change the stored pricing data", func() {
+		_, err := store.PreviewConfig(&current, proposed, filter)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(
+			db.Query(`select formula from pricing_plan_components`),
+		).To(MatchJSON(testenv.Rows{
+			{"formula": "ceil($time_in_seconds/3600) * 0.01"},
+		}))
+		Expect(
+			db.Get(`select sum(cost_for_duration)::text from billable_event_components`),
+		).To(Equal("0.02"))
+	})
+
+	It("should compare with the latest stored pricing versions without a current config", func() {
+		_, err := store.AddPricingPlanVersion(computePlan("ceil($time_in_seconds/3600) * 0.02"), "admin")
+		Expect(err).ToNot(HaveOccurred())
+
+		preview, err := store.PreviewConfig(nil, proposed, filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(preview.Plans).To(Equal([]eventstore.PriceChange{
+			{
+				GUID:       eventstore.ComputePlanGUID,
+				Name:       "PLAN1",
+				Current:    "0.04",
+				Proposed:   "0.03",
+				Difference: "-0.01",
+			},
+		}))
+	})
+
+	It("should fail if the proposed config does not price every event", func() {
+		proposed = testenv.BasicConfig
+
+		_, err := store.PreviewConfig(&current, proposed, filter)
+		Expect(err).To(MatchError(HavePrefix("proposed config: missing 'app' pricing plan configuration")))
+	})
+})
@@ -1,11 +1,15 @@
 package eventstore_test
 
 import (
+	"io/ioutil"
+	"os"
+
 	"github.com/alphagov/paas-billing/eventio"
 	"github.com/alphagov/paas-billing/eventstore"
 	"github.com/alphagov/paas-billing/testenv"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -20,14 +24,14 @@ var _ = Describe("GetPricingPlans", func() {
 			VATRates: []eventio.VATRate{
 				{
 					Code:      "Standard",
-					Rate:      0.2,
+					Rate:      eventio.MustParseDecimalNumber("0.2"),
 					ValidFrom: "epoch",
 				},
 			},
 			CurrencyRates: []eventio.CurrencyRate{
 				{
 					Code:      "GBP",
-					Rate:      1,
+					Rate:      eventio.MustParseDecimalNumber("1"),
 					ValidFrom: "epoch",
 				},
 			},
@@ -90,3 +94,256 @@ var _ = Describe("GetPricingPlans", func() {
 	})
 
 })
+
+var _ = Describe("LoadConfig", func() {
+
+	var (
+		configFile string
+	)
+
+	BeforeEach(func() {
+		f, err := ioutil.TempFile("", "config.json")
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		configFile = f.Name()
+	})
+
+	AfterEach(func() {
+		os.Remove(configFile)
+	})
+
+	It("should load a config with valid formulas", func() {
+		Expect(ioutil.WriteFile(configFile, []byte(`{
+			"pricing_plans": [{
+				"name": "PLAN1",
+				"plan_guid": "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+				"valid_from": "2001-01-01",
+				"components": [{"name": "compute", "formula": "ceil($time_in_seconds / 3600) * 0.01"}]
+			}]
+		}`), 0644)).To(Succeed())
+
+		cfg, err := eventstore.LoadConfig(configFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cfg.PricingPlans[0].Components[0].Formula).To(Equal("ceil($time_in_seconds / 3600) * 0.01"))
+	})
+
+	It("should return the location of an invalid formula", func() {
+		Expect(ioutil.WriteFile(configFile, []byte(`{
+			"pricing_plans": [{
+				"name": "PLAN1",
+				"plan_guid": "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+				"valid_from": "2001-01-01",
+				"components": [
+					{"name": "compute", "formula": "1"},
+					{"name": "storage", "formula": "1 +\n ceil(1;"}
+				]
+			}]
+		}`), 0644)).To(Succeed())
+
+		_, err := eventstore.LoadConfig(configFile)
+		Expect(err).To(MatchError(configFile + ": pricing_plans[0].components[1].formula: line 2, column 8: illegal token in formula: ;"))
+	})
+})
+
+var _ = Describe("Config.Validate", func() {
+
+	var validConfig = func() eventstore.Config {
+		return eventstore.Config{
+			VATRates: []eventio.VATRate{
+				{Code: "Standard", Rate: eventio.MustParseDecimalNumber("0.2"), ValidFrom: "epoch"},
+			},
+			CurrencyRates: []eventio.CurrencyRate{
+				{Code: "GBP", Rate: eventio.MustParseDecimalNumber("1"), ValidFrom: "epoch"},
+				{Code: "USD", Rate: eventio.MustParseDecimalNumber("0.8"), ValidFrom: "2001-01-01"},
+			},
+			PricingPlans: []eventio.PricingPlan{
+				{
+					Name:      "PLAN1",
+					PlanGUID:  "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+					ValidFrom: "2001-01-01",
+					Components: []eventio.PricingPlanComponent{
+						{Name: "compute", Formula: "$time_in_seconds * 0.01", VATCode: "Standard", CurrencyCode: "USD"},
+						{Name: "storage", Formula: "$storage_in_mb * 0.01", VATCode: "Standard", CurrencyCode: "GBP"},
+					},
+				},
+			},
+		}
+	}
+
+	It("should find no problems with a valid config", func() {
+		cfg := validConfig()
+		Expect(cfg.Validate()).To(BeEmpty())
+	})
+
+	DescribeTable("problems",
+		func(modify func(*eventstore.Config), expected ...eventstore.ValidationError) {
+			cfg := validConfig()
+			modify(&cfg)
+			Expect(cfg.Validate()).To(ConsistOf(expected))
+		},
+		Entry("invalid formula",
+			func(cfg *eventstore.Config) { cfg.PricingPlans[0].Components[1].Formula = "1 +" },
+			eventstore.ValidationError{Path: "pricing_plans[0].components[1].formula", Message: "line 1, column 4: unexpected end of formula, expected a number, variable or function"},
+		),
+		Entry("plan valid_from not at the start of a month",
+			func(cfg *eventstore.Config) { cfg.PricingPlans[0].ValidFrom = "2001-01-02" },
+			eventstore.ValidationError{Path: "pricing_plans[0].valid_from", Message: "'2001-01-02' must be the start of a month"},
+		),
+		Entry("plan valid_from that is not a date",
+			func(cfg *eventstore.Config) { cfg.PricingPlans[0].ValidFrom = "January" },
+			eventstore.ValidationError{Path: "pricing_plans[0].valid_from", Message: "'January' is not a valid date, expected a date like 2001-01-01"},
+		),
+		Entry("rate valid_from not at the start of a month",
+			func(cfg *eventstore.Config) { cfg.VATRates[0].ValidFrom = "2000-12-01T12:00:00Z" },
+			eventstore.ValidationError{Path: "vat_rates[0].valid_from", Message: "'2000-12-01T12:00:00Z' must be the start of a month"},
+		),
+		Entry("blank plan name and invalid plan guid",
+			func(cfg *eventstore.Config) {
+				cfg.PricingPlans[0].Name = " "
+				cfg.PricingPlans[0].PlanGUID = "not-a-guid"
+			},
+			eventstore.ValidationError{Path: "pricing_plans[0].name", Message: "name must not be blank"},
+			eventstore.ValidationError{Path: "pricing_plans[0].plan_guid", Message: "'not-a-guid' is not a valid guid"},
+		),
+		Entry("plan without components",
+			func(cfg *eventstore.Config) { cfg.PricingPlans[0].Components = nil },
+			eventstore.ValidationError{Path: "pricing_plans[0].components", Message: "plan must have at least one component"},
+		),
+		Entry("duplicate plan",
+			func(cfg *eventstore.Config) {
+				cfg.PricingPlans = append(cfg.PricingPlans, cfg.PricingPlans[0])
+				cfg.PricingPlans[1].ValidFrom = "2001-01-01T00:00:00+00:00"
+			},
+			eventstore.ValidationError{Path: "pricing_plans[1]", Message: "duplicate of pricing_plans[0], plans must have a different plan_guid or valid_from"},
+		),
+		Entry("duplicate component name",
+			func(cfg *eventstore.Config) { cfg.PricingPlans[0].Components[1].Name = "compute" },
+			eventstore.ValidationError{Path: "pricing_plans[0].components[1].name", Message: "duplicate of pricing_plans[0].components[0].name"},
+		),
+		Entry("unknown codes",
+			func(cfg *eventstore.Config) {
+				cfg.PricingPlans[0].Components[0].VATCode = "Standard rate"
+				cfg.PricingPlans[0].Components[0].CurrencyCode = "UKP"
+			},
+			eventstore.ValidationError{Path: "pricing_plans[0].components[0].vat_code", Message: "invalid vat code 'Standard rate', must be a letter followed by up to 31 letters, digits, '-' or '_'"},
+			eventstore.ValidationError{Path: "pricing_plans[0].components[0].currency_code", Message: "unknown currency code 'UKP', must be an ISO 4217 code such as GBP or USD"},
+		),
+		Entry("currency without a rate",
+			func(cfg *eventstore.Config) { cfg.PricingPlans[0].Components[0].CurrencyCode = "JPY" },
+			eventstore.ValidationError{Path: "pricing_plans[0].components[0].currency_code", Message: "missing currency_rate for 'JPY' for period '2001-01-01'"},
+		),
+		Entry("missing vat rate",
+			func(cfg *eventstore.Config) { cfg.PricingPlans[0].Components[1].VATCode = "Zero" },
+			eventstore.ValidationError{Path: "pricing_plans[0].components[1].vat_code", Message: "missing vat_rate for 'Zero' for period '2001-01-01'"},
+		),
+		Entry("configured vat code without a rate",
+			func(cfg *eventstore.Config) { cfg.PricingPlans[0].Components[1].VATCode = "Exempt" },
+			eventstore.ValidationError{Path: "pricing_plans[0].components[1].vat_code", Message: "missing vat_rate for 'Exempt' for period '2001-01-01'"},
+		),
+		Entry("org tax treatment with a vat code without a rate",
+			func(cfg *eventstore.Config) {
+				cfg.OrgTaxTreatments = []eventio.OrgTaxTreatment{
+					{OrgGUID: "51ba75ef-edc0-47ad-a633-a8f6e8770944", ValidFrom: "2001-01-01", VATCode: "Exempt"},
+				}
+			},
+			eventstore.ValidationError{Path: "org_tax_treatments[0].vat_code", Message: "missing vat_rate for 'Exempt' for period '2001-01-01'"},
+		),
+		Entry("invalid and duplicate org tax treatments",
+			func(cfg *eventstore.Config) {
+				cfg.OrgTaxTreatments = []eventio.OrgTaxTreatment{
+					{OrgGUID: "51ba75ef-edc0-47ad-a633-a8f6e8770944", ValidFrom: "2001-01-01", VATCode: "Standard"},
+					{OrgGUID: "51ba75ef-edc0-47ad-a633-a8f6e8770944", ValidFrom: "2001-01-01", VATCode: ""},
+					{OrgGUID: "not-a-guid", ValidFrom: "2001-01-01", VATCode: "Standard"},
+				}
+			},
+			eventstore.ValidationError{Path: "org_tax_treatments[1]", Message: "duplicate of org_tax_treatments[0]"},
+			eventstore.ValidationError{Path: "org_tax_treatments[2].org_guid", Message: "'not-a-guid' is not a valid guid"},
+		),
+		Entry("invalid and duplicate org billing periods",
+			func(cfg *eventstore.Config) {
+				cfg.OrgBillingPeriods = []eventio.BillingPeriod{
+					{OrgGUID: "51ba75ef-edc0-47ad-a633-a8f6e8770944", StartDay: 15},
+					{OrgGUID: "51BA75EF-EDC0-47AD-A633-A8F6E8770944", Months: 3},
+					{OrgGUID: "not-a-guid"},
+					{OrgGUID: "0cbb4c73-0a32-45b1-bbc3-7e4c9e3a0d34", TimeZone: "Nowhere/Special"},
+				}
+			},
+			eventstore.ValidationError{Path: "org_billing_periods[1]", Message: "duplicate of org_billing_periods[0], an org can only have one billing period"},
+			eventstore.ValidationError{Path: "org_billing_periods[2].org_guid", Message: "'not-a-guid' is not a valid guid"},
+			eventstore.ValidationError{Path: "org_billing_periods[3]", Message: "time_zone must be an IANA time zone such as Europe/London - got Nowhere/Special"},
+		),
+		Entry("currency rate only valid after the plan",
+			func(cfg *eventstore.Config) { cfg.CurrencyRates[1].ValidFrom = "2001-02-01" },
+			eventstore.ValidationError{Path: "pricing_plans[0].components[0].currency_code", Message: "missing currency_rate for 'USD' for period '2001-01-01'"},
+		),
+		Entry("invalid rates",
+			func(cfg *eventstore.Config) {
+				cfg.VATRates[0].Rate = eventio.MustParseDecimalNumber("-0.1")
+				cfg.CurrencyRates[0].Rate = eventio.MustParseDecimalNumber("0")
+			},
+			eventstore.ValidationError{Path: "vat_rates[0].rate", Message: "rate must not be negative"},
+			eventstore.ValidationError{Path: "currency_rates[0].rate", Message: "rate must be greater than zero"},
+		),
+		Entry("duplicate rates",
+			func(cfg *eventstore.Config) {
+				cfg.VATRates = append(cfg.VATRates, cfg.VATRates[0])
+				cfg.CurrencyRates = append(cfg.CurrencyRates, cfg.CurrencyRates[1])
+			},
+			eventstore.ValidationError{Path: "vat_rates[1]", Message: "duplicate of vat_rates[0]"},
+			eventstore.ValidationError{Path: "currency_rates[2]", Message: "duplicate of currency_rates[1]"},
+		),
+	)
+
+	It("should report problems with their location", func() {
+		errs := eventstore.ValidationErrors{
+			{Path: "vat_rates[0].rate", Message: "rate must not be negative"},
+			{Path: "pricing_plans[1].name", Message: "name must not be blank"},
+		}
+		Expect(errs.Error()).To(Equal("vat_rates[0].rate: rate must not be negative\npricing_plans[1].name: name must not be blank"))
+	})
+})
+
+var _ = Describe("ValidateConfigFile", func() {
+
+	var (
+		configFile string
+	)
+
+	BeforeEach(func() {
+		f, err := ioutil.TempFile("", "config.json")
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		configFile = f.Name()
+	})
+
+	AfterEach(func() {
+		os.Remove(configFile)
+	})
+
+	It("should return every problem in the file", func() {
+		Expect(ioutil.WriteFile(configFile, []byte(`{
+			"vat_rates": [{"code": "Standard", "rate": 0.2, "valid_from": "epoch"}],
+			"currency_rates": [{"code": "GBP", "rate": 1, "valid_from": "epoch"}],
+			"pricing_plans": [{
+				"name": "PLAN1",
+				"plan_guid": "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+				"valid_from": "2001-01-15",
+				"components": [{"name": "compute", "formula": "1 / 0", "vat_code": "Standard", "currency_code": "GBP"}]
+			}]
+		}`), 0644)).To(Succeed())
+
+		errs, err := eventstore.ValidateConfigFile(configFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(errs).To(Equal(eventstore.ValidationErrors{
+			{Path: "pricing_plans[0].valid_from", Message: "'2001-01-15' must be the start of a month"},
+			{Path: "pricing_plans[0].components[0].formula", Message: "line 1, column 3: division by zero"},
+		}))
+	})
+
+	It("should return an error if the file is not valid JSON", func() {
+		Expect(ioutil.WriteFile(configFile, []byte(`{"pricing_plans": [}`), 0644)).To(Succeed())
+
+		_, err := eventstore.ValidateConfigFile(configFile)
+		Expect(err).To(MatchError(HavePrefix(configFile + ": invalid character")))
+	})
+})
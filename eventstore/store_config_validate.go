@@ -0,0 +1,243 @@
+package eventstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore/formula"
+	uuid "github.com/satori/go.uuid"
+)
+
+// validFromLayouts are the formats of valid_from dates that are accepted,
+// the database is more lenient but these cover what is used in practice
+var validFromLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+	"2006-01-02T15:04:05-07:00",
+	"2006-01-02 15:04:05-07",
+}
+
+// ValidationError is a problem with a config at a location given as a JSON
+// path, for example pricing_plans[0].components[1].formula
+type ValidationError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationErrors is every problem found with a config
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (errs *ValidationErrors) add(path string, format string, args ...interface{}) {
+	*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateConfigFile reads the config in filename and checks it with
+// Validate. An error is only returned if the file could not be read as a
+// config at all.
+func ValidateConfigFile(filename string) (ValidationErrors, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return cfg.Validate(), nil
+}
+
+// Validate checks the config without a database, applying the same rules
+// that Init would. Rather than stopping at the first problem it returns all
+// of them.
+func (cfg *Config) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	vatRates := map[string][]time.Time{}
+	seenVATRates := map[string]int{}
+	for i, vr := range cfg.VATRates {
+		path := fmt.Sprintf("vat_rates[%d]", i)
+		if err := eventio.ValidateVATCode(vr.Code); err != nil {
+			errs.add(path+".code", "%s", err)
+		}
+		if vr.Rate.Sign() < 0 {
+			errs.add(path+".rate", "rate must not be negative")
+		}
+		// a rate with a date that can not be parsed is treated as always
+		// valid so that the plans using it do not also report it missing
+		validFrom, ok := validateValidFrom(&errs, path+".valid_from", vr.ValidFrom)
+		if ok {
+			key := vr.Code + "/" + validFrom.String()
+			if j, ok := seenVATRates[key]; ok {
+				errs.add(path, "duplicate of vat_rates[%d]", j)
+				continue
+			}
+			seenVATRates[key] = i
+		}
+		vatRates[vr.Code] = append(vatRates[vr.Code], validFrom)
+	}
+
+	currencyRates := map[string][]time.Time{}
+	seenCurrencyRates := map[string]int{}
+	for i, cr := range cfg.CurrencyRates {
+		path := fmt.Sprintf("currency_rates[%d]", i)
+		if err := eventio.ValidateCurrencyCode(cr.Code); err != nil {
+			errs.add(path+".code", "%s", err)
+		}
+		if cr.Rate.Sign() <= 0 {
+			errs.add(path+".rate", "rate must be greater than zero")
+		}
+		validFrom, ok := validateValidFrom(&errs, path+".valid_from", cr.ValidFrom)
+		if ok {
+			key := cr.Code + "/" + validFrom.String()
+			if j, ok := seenCurrencyRates[key]; ok {
+				errs.add(path, "duplicate of currency_rates[%d]", j)
+				continue
+			}
+			seenCurrencyRates[key] = i
+		}
+		currencyRates[cr.Code] = append(currencyRates[cr.Code], validFrom)
+	}
+
+	seenPlans := map[string]int{}
+	for i, pp := range cfg.PricingPlans {
+		path := fmt.Sprintf("pricing_plans[%d]", i)
+		if strings.TrimSpace(pp.Name) == "" {
+			errs.add(path+".name", "name must not be blank")
+		}
+		if _, err := uuid.FromString(pp.PlanGUID); err != nil {
+			errs.add(path+".plan_guid", "'%s' is not a valid guid", pp.PlanGUID)
+		}
+		validFrom, validFromOK := validateValidFrom(&errs, path+".valid_from", pp.ValidFrom)
+		if validFromOK {
+			key := strings.ToLower(pp.PlanGUID) + "/" + validFrom.String()
+			if j, ok := seenPlans[key]; ok {
+				errs.add(path, "duplicate of pricing_plans[%d], plans must have a different plan_guid or valid_from", j)
+			}
+			seenPlans[key] = i
+		}
+		if len(pp.Components) == 0 {
+			errs.add(path+".components", "plan must have at least one component")
+		}
+
+		seenComponents := map[string]int{}
+		for j, ppc := range pp.Components {
+			componentPath := fmt.Sprintf("%s.components[%d]", path, j)
+			if strings.TrimSpace(ppc.Name) == "" {
+				errs.add(componentPath+".name", "name must not be blank")
+			} else if k, ok := seenComponents[ppc.Name]; ok {
+				errs.add(componentPath+".name", "duplicate of %s.components[%d].name", path, k)
+			}
+			seenComponents[ppc.Name] = j
+			if _, err := formula.Parse(ppc.Formula); err != nil {
+				errs.add(componentPath+".formula", "%s", err)
+			}
+			if err := eventio.ValidateVATCode(ppc.VATCode); err != nil {
+				errs.add(componentPath+".vat_code", "%s", err)
+			} else if validFromOK && !hasRateFrom(vatRates[ppc.VATCode], validFrom) {
+				errs.add(componentPath+".vat_code", "missing vat_rate for '%s' for period '%s'", ppc.VATCode, pp.ValidFrom)
+			}
+			if err := eventio.ValidateCurrencyCode(ppc.CurrencyCode); err != nil {
+				errs.add(componentPath+".currency_code", "%s", err)
+			} else if validFromOK && !hasRateFrom(currencyRates[ppc.CurrencyCode], validFrom) {
+				errs.add(componentPath+".currency_code", "missing currency_rate for '%s' for period '%s'", ppc.CurrencyCode, pp.ValidFrom)
+			}
+		}
+	}
+
+	seenOrgTaxTreatments := map[string]int{}
+	for i, ot := range cfg.OrgTaxTreatments {
+		path := fmt.Sprintf("org_tax_treatments[%d]", i)
+		if _, err := uuid.FromString(ot.OrgGUID); err != nil {
+			errs.add(path+".org_guid", "'%s' is not a valid guid", ot.OrgGUID)
+		}
+		validFrom, validFromOK := validateValidFrom(&errs, path+".valid_from", ot.ValidFrom)
+		if validFromOK {
+			key := strings.ToLower(ot.OrgGUID) + "/" + validFrom.String()
+			if j, ok := seenOrgTaxTreatments[key]; ok {
+				errs.add(path, "duplicate of org_tax_treatments[%d]", j)
+			}
+			seenOrgTaxTreatments[key] = i
+		}
+		if ot.VATCode == "" {
+			continue
+		}
+		if err := eventio.ValidateVATCode(ot.VATCode); err != nil {
+			errs.add(path+".vat_code", "%s", err)
+		} else if validFromOK && !hasRateFrom(vatRates[ot.VATCode], validFrom) {
+			errs.add(path+".vat_code", "missing vat_rate for '%s' for period '%s'", ot.VATCode, ot.ValidFrom)
+		}
+	}
+
+	seenOrgBillingPeriods := map[string]int{}
+	for i, bp := range cfg.OrgBillingPeriods {
+		path := fmt.Sprintf("org_billing_periods[%d]", i)
+		if _, err := uuid.FromString(bp.OrgGUID); err != nil {
+			errs.add(path+".org_guid", "'%s' is not a valid guid", bp.OrgGUID)
+		} else if j, ok := seenOrgBillingPeriods[strings.ToLower(bp.OrgGUID)]; ok {
+			errs.add(path, "duplicate of org_billing_periods[%d], an org can only have one billing period", j)
+		}
+		seenOrgBillingPeriods[strings.ToLower(bp.OrgGUID)] = i
+		if err := bp.Validate(); err != nil {
+			errs.add(path, "%s", err)
+		}
+	}
+
+	return errs
+}
+
+// validateValidFrom parses a valid_from date, which must be the start of a
+// month, adding a problem at path if it is not. The returned bool is false
+// if the date could not be parsed at all, in which case the zero time is
+// returned.
+func validateValidFrom(errs *ValidationErrors, path string, s string) (time.Time, bool) {
+	t, err := parseValidFrom(s)
+	if err != nil {
+		errs.add(path, "%s", err)
+		return time.Time{}, false
+	}
+	if t.Day() != 1 || t.Hour() != 0 || t.Minute() != 0 || t.Second() != 0 || t.Nanosecond() != 0 {
+		errs.add(path, "'%s' must be the start of a month", s)
+	}
+	return t, true
+}
+
+func parseValidFrom(s string) (time.Time, error) {
+	if s == "epoch" {
+		return time.Unix(0, 0).UTC(), nil
+	}
+	for _, layout := range validFromLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("'%s' is not a valid date, expected a date like 2001-01-01", s)
+}
+
+// hasRateFrom reports whether any of the rate dates is at or before t
+func hasRateFrom(rates []time.Time, t time.Time) bool {
+	for _, validFrom := range rates {
+		if !validFrom.After(t) {
+			return true
+		}
+	}
+	return false
+}
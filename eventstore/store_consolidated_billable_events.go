@@ -5,11 +5,11 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
-	"strings"
 	"time"
 
 	"code.cloudfoundry.org/lager"
 	"github.com/alphagov/paas-billing/eventio"
+	"github.com/lib/pq"
 )
 
 const (
@@ -33,29 +33,30 @@ func (e *EventStore) getConsolidatedBillableEventRows(tx *sql.Tx, filter eventio
 	if err := filter.Validate(); err != nil {
 		return nil, err
 	}
-	err := checkMonthBoundary(filter.RangeStart)
-	if err != nil {
-		return nil, err
+	if !e.isBillingPeriodFor(filter) {
+		return nil, fmt.Errorf("consolidation only works with ranges that are exactly one billing period of the orgs in the filter")
 	}
-	err = checkMonthBoundary(filter.RangeStop)
-	if err != nil {
+	if err := checkCurrencyRateFrom(tx, filter.Currency, filter.RangeStart); err != nil {
 		return nil, err
 	}
 	args := []interface{}{
 		fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop), // $1
 	}
-	filterConditions := []string{}
-	orgPlaceholders := []string{}
-	for _, orgGUID := range filter.OrgGUIDs {
-		args = append(args, orgGUID)
-		orgPlaceholders = append(orgPlaceholders, fmt.Sprintf("($%d::uuid)", len(args))) // $N
-	}
-	if len(orgPlaceholders) > 0 {
-		filterConditions = append(filterConditions, fmt.Sprintf("org_guid = any (values %s)", strings.Join(orgPlaceholders, ",")))
-	}
-	filterQuery := ""
-	if len(filterConditions) > 0 {
-		filterQuery = " and " + strings.Join(filterConditions, " and ")
+	filterQuery, args := eventFilterQuery(filter, "", args)
+
+	// consolidated prices are stored in the base currency, rates change at
+	// the start of a month so the rate at the start of the consolidated
+	// month applies to all of it
+	price := "price"
+	if filter.Currency != "" {
+		args = append(args, filter.Currency)
+		price = fmt.Sprintf(`convert_price(price, (
+			select cr.rate
+			from currency_rates cr
+			where cr.code = $%d and cr.valid_from <= lower(consolidated_range)
+			order by cr.valid_from desc
+			limit 1
+		), $%d)`, len(args), len(args))
 	}
 
 	startTime := time.Now()
@@ -76,14 +77,14 @@ func (e *EventStore) getConsolidatedBillableEventRows(tx *sql.Tx, filter eventio
 			number_of_nodes,
 			memory_in_mb,
 			storage_in_mb,
-			price
+			%s as price
 		from
 			consolidated_billable_events
  		where
-			consolidated_range && $1::tstzrange
+			consolidated_range = $1::tstzrange
 			%s
-		order by event_guid
-	`, filterQuery), args...)
+		%s
+	`, price, filterQuery, eventPageQuery(filter, "order by event_guid")), args...)
 	elapsed := time.Since(startTime)
 	if err != nil {
 		e.logger.Error("get-consolidated-billable-event-rows-query", err, lager.Data{
@@ -136,10 +137,20 @@ func (e *EventStore) IsRangeConsolidated(filter eventio.EventFilter) (bool, erro
 	return result, tx.Commit()
 }
 
+// isRangeConsolidated reports whether the range of the filter has been
+// consolidated and is a billing period of every org in the filter, so that
+// the consolidated events hold all of their events for the range
 func (e *EventStore) isRangeConsolidated(tx *sql.Tx, filter eventio.EventFilter) (bool, error) {
 	if err := filter.Validate(); err != nil {
 		return false, err
 	}
+	if !e.isBillingPeriodFor(filter) {
+		return false, nil
+	}
+	return e.hasConsolidationHistory(tx, filter)
+}
+
+func (e *EventStore) hasConsolidationHistory(tx *sql.Tx, filter eventio.EventFilter) (bool, error) {
 	startTime := time.Now()
 	rows, err := tx.Query(
 		"SELECT 1 FROM consolidation_history where consolidated_range=$1::tstzrange",
@@ -199,31 +210,42 @@ func (e *EventStore) ConsolidateFullMonths(startAt string, endAt string) error {
 	return tx.Commit()
 }
 
+// consolidateFullMonths consolidates every whole billing period of any org
+// from the start of the period containing startAt up to endAt that has not
+// been consolidated yet
 func (e *EventStore) consolidateFullMonths(tx *sql.Tx, startAt string, endAt string) error {
-	eventFilter := eventio.EventFilter{
-		RangeStart: startAt,
-		RangeStop:  endAt,
-	}
-	truncatedEventFilter, err := eventFilter.TruncateMonth()
-	if err != nil {
-		return err
-	}
-
 	e.logger.Info("consolidating-full-months", lager.Data{
-		"start": truncatedEventFilter.RangeStart,
-		"stop":  truncatedEventFilter.RangeStop,
+		"start": startAt,
+		"stop":  endAt,
 	})
 
-	monthFilters, err := truncatedEventFilter.SplitByMonth()
+	periodFilters, err := e.consolidationRanges(startAt, endAt)
 	if err != nil {
 		return err
 	}
-	for _, filter := range monthFilters {
-		isConsolidated, err := e.isRangeConsolidated(tx, filter)
+	for _, filter := range periodFilters {
+		isConsolidated, err := e.hasConsolidationHistory(tx, filter)
 		if err != nil {
 			return err
 		}
 		if !isConsolidated {
+			include, exclude, err := e.consolidationOrgs(filter)
+			if err != nil {
+				return err
+			}
+			consolidatedBefore, err := e.checkConsolidationOverlap(tx, filter, include, exclude)
+			if err != nil {
+				return err
+			}
+			if consolidatedBefore {
+				// the orgs were consolidated for this time by the billing
+				// periods they had before
+				e.logger.Info("skipping-consolidated-months", lager.Data{
+					"start": filter.RangeStart,
+					"stop":  filter.RangeStop,
+				})
+				continue
+			}
 			e.logger.Info("consolidating-months", lager.Data{
 				"start": filter.RangeStart,
 				"stop":  filter.RangeStop,
@@ -232,15 +254,29 @@ func (e *EventStore) consolidateFullMonths(tx *sql.Tx, startAt string, endAt str
 			if err != nil {
 				return err
 			}
+			event, err := eventio.NewWebhookEvent(
+				eventio.MonthConsolidated,
+				fmt.Sprintf("%s:%s/%s", eventio.MonthConsolidated, filter.RangeStart, filter.RangeStop),
+				eventio.MonthConsolidatedData{
+					RangeStart: filter.RangeStart,
+					RangeStop:  filter.RangeStop,
+				},
+			)
+			if err != nil {
+				return err
+			}
+			if err := e.emitWebhookEvent(tx, event); err != nil {
+				return err
+			}
 		}
 	}
 
 	e.logger.Info("consolidated-full-months", lager.Data{
-		"start": truncatedEventFilter.RangeStart,
-		"stop":  truncatedEventFilter.RangeStop,
+		"start": startAt,
+		"stop":  endAt,
 	})
 
-	return nil
+	return e.generateInvoices(tx)
 }
 
 func (e *EventStore) Consolidate(filter eventio.EventFilter) error {
@@ -249,6 +285,9 @@ func (e *EventStore) Consolidate(filter eventio.EventFilter) error {
 		return err
 	}
 	err = e.consolidate(tx, filter)
+	if err == nil {
+		err = e.generateInvoices(tx)
+	}
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -260,18 +299,38 @@ func (e *EventStore) consolidate(tx *sql.Tx, filter eventio.EventFilter) error {
 	if len(filter.OrgGUIDs) != 0 {
 		return fmt.Errorf("consolidate must be called without an organisations filter (i.e. for all orgs)")
 	}
+	// each org is consolidated for the ranges that are its billing periods
+	include, exclude, err := e.consolidationOrgs(filter)
+	if err != nil {
+		return err
+	}
+
+	consolidatedBefore, err := e.checkConsolidationOverlap(tx, filter, include, exclude)
+	if err != nil {
+		return err
+	}
+	if consolidatedBefore {
+		return fmt.Errorf(
+			"consolidation range [%s, %s) has already been consolidated for some of its orgs by other billing periods",
+			filter.RangeStart, filter.RangeStop,
+		)
+	}
 
 	startTime := time.Now()
-	_, err := tx.Exec(`
+	_, err = tx.Exec(`
 				insert into consolidation_history (
 					consolidated_range,
-					created_at
+					created_at,
+					org_guids,
+					excluded_org_guids
 				) values (
 					$1::tstzrange,
-					$2::timestamptz
+					$2::timestamptz,
+					$3::uuid[],
+					$4::uuid[]
 				)`,
 		fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop),
-		time.Now())
+		time.Now(), pq.Array(include), pq.Array(exclude))
 	elapsed := time.Since(startTime)
 	if err != nil {
 		e.logger.Error("consolidation-history-query", err, lager.Data{
@@ -285,6 +344,8 @@ func (e *EventStore) consolidate(tx *sql.Tx, filter eventio.EventFilter) error {
 		"elapsed": int64(elapsed),
 	})
 
+	orgsFilter := filter
+	orgsFilter.OrgGUIDs = include
 	query, args, err := WithBillableEvents(`
 			insert into consolidated_billable_events (
 				consolidated_range,
@@ -327,11 +388,18 @@ func (e *EventStore) consolidate(tx *sql.Tx, filter eventio.EventFilter) error {
 				billable_events,
 				filtered_range
 		`,
-		filter,
+		orgsFilter,
 	)
 	if err != nil {
 		return err
 	}
+	if len(exclude) > 0 {
+		args = append(args, pq.Array(exclude))
+		query += fmt.Sprintf(`
+			where
+				billable_events.org_guid <> all($%d::uuid[])
+		`, len(args))
+	}
 
 	startTime = time.Now()
 	_, err = tx.Exec(query, args...)
@@ -351,16 +419,49 @@ func (e *EventStore) consolidate(tx *sql.Tx, filter eventio.EventFilter) error {
 	return nil
 }
 
-func checkMonthBoundary(value string) error {
-	rangeStart, err := time.Parse("2006-01-02", value)
-	if err != nil {
-		return err
-	}
-	rangeStartMonth := time.Date(rangeStart.Year(), rangeStart.Month(), 1, 0, 0, 0, 0, rangeStart.Location())
-	if rangeStartMonth != rangeStart {
-		return fmt.Errorf("consolidation only works with ranges starting and ending on month boundaries")
-	}
-	return nil
+// checkConsolidationOverlap checks the range of filter against the ranges
+// already consolidated for any of the same orgs, which overlap it if an org's
+// billing period has been changed. The range has been consolidated before if
+// it ends within those ranges, so the orgs have already been billed for it by
+// their earlier billing periods. It is an error for the range to start inside
+// them and end after, as the orgs would be billed twice for part of it.
+func (e *EventStore) checkConsolidationOverlap(tx *sql.Tx, filter eventio.EventFilter, include []string, exclude []string) (bool, error) {
+	consolidatedRange := fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop)
+	var overlapStart, overlapStop time.Time
+	var endsWithin bool
+	err := tx.QueryRow(`
+		select
+			lower(h.consolidated_range),
+			upper(h.consolidated_range),
+			upper(h.consolidated_range) >= upper($1::tstzrange)
+		from
+			consolidation_history h
+		where
+			h.consolidated_range && $1::tstzrange
+			and h.consolidated_range <> $1::tstzrange
+			and (case
+				when h.org_guids is null and $2::uuid[] is null then true
+				when h.org_guids is null then not ($2::uuid[] <@ coalesce(h.excluded_org_guids, '{}'))
+				when $2::uuid[] is null then not (h.org_guids <@ coalesce($3::uuid[], '{}'))
+				else h.org_guids && $2::uuid[]
+			end)
+		order by
+			upper(h.consolidated_range) desc
+		limit 1
+	`, consolidatedRange, pq.Array(include), pq.Array(exclude)).Scan(&overlapStart, &overlapStop, &endsWithin)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, wrapPqError(err, "check-consolidation-overlap")
+	}
+	if endsWithin {
+		return true, nil
+	}
+	return false, fmt.Errorf(
+		"consolidation range %s starts inside the consolidated range [%s, %s) for some of the same orgs, "+
+			"an org's billing period can only be changed to one starting at the end of its consolidated ranges",
+		consolidatedRange, eventio.FormatRangeTime(overlapStart), eventio.FormatRangeTime(overlapStop),
+	)
 }
 
 type CachedBillableEventRows struct {
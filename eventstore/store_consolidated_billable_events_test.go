@@ -1,9 +1,11 @@
 package eventstore_test
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"code.cloudfoundry.org/lager"
 	"github.com/alphagov/paas-billing/eventio"
 	"github.com/alphagov/paas-billing/eventstore"
 	"github.com/alphagov/paas-billing/testenv"
@@ -27,17 +29,17 @@ var _ = Describe("GetConsolidatedBillableEvents", func() {
 
 		cfg.AddVATRate(eventio.VATRate{
 			Code:      "Standard",
-			Rate:      0,
+			Rate:      eventio.MustParseDecimalNumber("0"),
 			ValidFrom: "2017-03-01",
 		})
 		cfg.AddCurrencyRate(eventio.CurrencyRate{
 			Code:      "GBP",
-			Rate:      2,
+			Rate:      eventio.MustParseDecimalNumber("2"),
 			ValidFrom: "2017-02-01",
 		})
 		cfg.AddCurrencyRate(eventio.CurrencyRate{
 			Code:      "GBP",
-			Rate:      4,
+			Rate:      eventio.MustParseDecimalNumber("4"),
 			ValidFrom: "2017-04-01",
 		})
 
@@ -95,7 +97,7 @@ var _ = Describe("GetConsolidatedBillableEvents", func() {
 		}
 	})
 
-	It("Should fail to GetBillableEvents if query range is not one month", func() {
+	It("Should fail to GetBillableEvents if query range is not one billing period", func() {
 		db, err := scenario.Open(cfg)
 		Expect(err).ToNot(HaveOccurred())
 		defer db.Close()
@@ -110,13 +112,13 @@ var _ = Describe("GetConsolidatedBillableEvents", func() {
 			RangeStart: "2001-02-01",
 			RangeStop:  "2001-02-02",
 		})
-		Expect(err).To(MatchError("consolidation only works with ranges starting and ending on month boundaries"))
+		Expect(err).To(MatchError("consolidation only works with ranges that are exactly one billing period of the orgs in the filter"))
 
 		_, err = db.Schema.GetConsolidatedBillableEvents(eventio.EventFilter{
 			RangeStart: "2001-01-15",
 			RangeStop:  "2001-02-15",
 		})
-		Expect(err).To(MatchError("consolidation only works with ranges starting and ending on month boundaries"))
+		Expect(err).To(MatchError("consolidation only works with ranges that are exactly one billing period of the orgs in the filter"))
 	})
 })
 
@@ -146,7 +148,7 @@ var _ = Describe("Consolidate", func() {
 		))
 	})
 
-	It("Should fail to Consolidate if query range is not exactly one month", func() {
+	It("Should fail to Consolidate if query range is not exactly one billing period", func() {
 		db, err := scenario.Open(cfg)
 		Expect(err).ToNot(HaveOccurred())
 		defer db.Close()
@@ -156,7 +158,7 @@ var _ = Describe("Consolidate", func() {
 			RangeStop:  "2001-02-02",
 		})
 		Expect(err).To(MatchError(
-			MatchRegexp("violates check constraint"),
+			"consolidation range [2001-01-02, 2001-02-02) is not a billing period of any org",
 		))
 
 		err = db.Schema.Consolidate(eventio.EventFilter{
@@ -164,7 +166,7 @@ var _ = Describe("Consolidate", func() {
 			RangeStop:  "2001-07-01",
 		})
 		Expect(err).To(MatchError(
-			MatchRegexp("violates check constraint"),
+			"consolidation range [2001-01-01, 2001-07-01) is not a billing period of any org",
 		))
 	})
 
@@ -355,3 +357,161 @@ var _ = Describe("ConsolidateFullMonths", func() {
 		Expect(consolidatedEventsAfterTwoConsolidations).NotTo(Equal(billableEvents))
 	})
 })
+
+var _ = Describe("Consolidating orgs with their own billing periods", func() {
+	var (
+		cfg      eventstore.Config
+		scenario *testenv.TestScenario
+		db       *testenv.TempDB
+		org1     string
+		org2     string
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		scenario = testenv.NewTestScenario("2001-01-01T00:00")
+		scenario.AddComputePlan()
+		scenario.AppLifeCycle("org1", "space1", "app1",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+2000h", State: "STOPPED"},
+		)
+		scenario.AppLifeCycle("org2", "space2", "app2",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+2000h", State: "STOPPED"},
+		)
+		org1 = scenario.GetOrgGUID("org1")
+		org2 = scenario.GetOrgGUID("org2")
+		cfg.AddOrgBillingPeriod(eventio.BillingPeriod{OrgGUID: org2, StartDay: 15})
+
+		var err error
+		db, err = scenario.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(db.Schema.Refresh()).To(Succeed())
+		Expect(db.Schema.ConsolidateFullMonths("2001-01-01", "2001-03-01")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("should consolidate each org for its own billing periods", func() {
+		january := eventio.EventFilter{RangeStart: "2001-01-01", RangeStop: "2001-02-01", OrgGUIDs: []string{org1}}
+		isConsolidated, err := db.Schema.IsRangeConsolidated(january)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isConsolidated).To(BeTrue())
+
+		january.OrgGUIDs = []string{org2}
+		isConsolidated, err = db.Schema.IsRangeConsolidated(january)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isConsolidated).To(BeFalse())
+
+		midJanuary := eventio.EventFilter{RangeStart: "2001-01-15", RangeStop: "2001-02-15", OrgGUIDs: []string{org2}}
+		isConsolidated, err = db.Schema.IsRangeConsolidated(midJanuary)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isConsolidated).To(BeTrue())
+
+		By("not treating a range as consolidated for all orgs if some are billed by other periods")
+		isConsolidated, err = db.Schema.IsRangeConsolidated(eventio.EventFilter{RangeStart: "2001-01-01", RangeStop: "2001-02-01"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isConsolidated).To(BeFalse())
+	})
+
+	It("should only hold each org's events in the ranges that are its billing periods", func() {
+		january := eventio.EventFilter{RangeStart: "2001-01-01", RangeStop: "2001-02-01", OrgGUIDs: []string{org1}}
+		billableEvents, err := db.Schema.GetBillableEvents(january)
+		Expect(err).NotTo(HaveOccurred())
+		consolidatedEvents, err := db.Schema.GetConsolidatedBillableEvents(january)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(consolidatedEvents).To(Equal(billableEvents))
+
+		midJanuary := eventio.EventFilter{RangeStart: "2001-01-15", RangeStop: "2001-02-15", OrgGUIDs: []string{org2}}
+		billableEvents, err = db.Schema.GetBillableEvents(midJanuary)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(billableEvents).To(HaveLen(1))
+		consolidatedEvents, err = db.Schema.GetConsolidatedBillableEvents(midJanuary)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(consolidatedEvents).To(Equal(billableEvents))
+
+		_, err = db.Schema.GetConsolidatedBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+			OrgGUIDs:   []string{org2},
+		})
+		Expect(err).To(MatchError("consolidation only works with ranges that are exactly one billing period of the orgs in the filter"))
+	})
+
+	It("should give the billing period shared by the orgs", func() {
+		period, err := db.Schema.GetBillingPeriod([]string{org2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(period.StartDay).To(Equal(15))
+
+		period, err = db.Schema.GetBillingPeriod([]string{org1, org2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(period).To(Equal(eventio.DefaultBillingPeriod))
+	})
+})
+
+var _ = Describe("Changing the billing period of a consolidated org", func() {
+	var (
+		cfg      eventstore.Config
+		scenario *testenv.TestScenario
+		org2     string
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		scenario = testenv.NewTestScenario("2001-01-01T00:00")
+		scenario.AddComputePlan()
+		scenario.AppLifeCycle("org1", "space1", "app1",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+2000h", State: "STOPPED"},
+		)
+		scenario.AppLifeCycle("org2", "space2", "app2",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+2000h", State: "STOPPED"},
+		)
+		org2 = scenario.GetOrgGUID("org2")
+	})
+
+	It("should reject a billing period change that starts inside a consolidated range", func() {
+		db, err := scenario.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		Expect(db.Schema.Refresh()).To(Succeed())
+		Expect(db.Schema.ConsolidateFullMonths("2001-01-01", "2001-03-01")).To(Succeed())
+
+		By("changing org2 to a billing period that starts inside February")
+		cfg.AddOrgBillingPeriod(eventio.BillingPeriod{OrgGUID: org2, StartDay: 15})
+		store := eventstore.New(context.Background(), db.Conn, lager.NewLogger("test"), cfg)
+		Expect(store.Init()).To(Succeed())
+		err = store.ConsolidateFullMonths("2001-01-01", "2001-04-01")
+		Expect(err).To(MatchError(ContainSubstring(
+			"consolidation range [2001-02-15, 2001-03-15) starts inside the consolidated range [2001-02-01, 2001-03-01) for some of the same orgs",
+		)))
+		err = store.Consolidate(eventio.EventFilter{RangeStart: "2001-01-15", RangeStop: "2001-02-15"})
+		Expect(err).To(MatchError(
+			"consolidation range [2001-01-15, 2001-02-15) has already been consolidated for some of its orgs by other billing periods",
+		))
+
+		By("changing org2 to a billing period that starts at the end of the consolidated ranges")
+		cfg.OrgBillingPeriods = nil
+		cfg.AddOrgBillingPeriod(eventio.BillingPeriod{OrgGUID: org2, Months: 3, AnchorMonth: 3})
+		store = eventstore.New(context.Background(), db.Conn, lager.NewLogger("test"), cfg)
+		Expect(store.Init()).To(Succeed())
+		Expect(store.ConsolidateFullMonths("2001-01-01", "2001-06-01")).To(Succeed())
+
+		isConsolidated, err := store.IsRangeConsolidated(eventio.EventFilter{RangeStart: "2001-03-01", RangeStop: "2001-06-01", OrgGUIDs: []string{org2}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isConsolidated).To(BeTrue())
+		Expect(
+			db.Query(`select consolidated_range::text from consolidation_history order by consolidated_range`),
+		).To(MatchJSON(testenv.Rows{
+			{"consolidated_range": `["2001-01-01 00:00:00+00","2001-02-01 00:00:00+00")`},
+			{"consolidated_range": `["2001-02-01 00:00:00+00","2001-03-01 00:00:00+00")`},
+			{"consolidated_range": `["2001-03-01 00:00:00+00","2001-04-01 00:00:00+00")`},
+			{"consolidated_range": `["2001-03-01 00:00:00+00","2001-06-01 00:00:00+00")`},
+			{"consolidated_range": `["2001-04-01 00:00:00+00","2001-05-01 00:00:00+00")`},
+			{"consolidated_range": `["2001-05-01 00:00:00+00","2001-06-01 00:00:00+00")`},
+		}))
+	})
+})
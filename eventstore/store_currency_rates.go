@@ -1,7 +1,9 @@
 package eventstore
 
 import (
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"code.cloudfoundry.org/lager"
@@ -76,3 +78,27 @@ func (s *EventStore) GetCurrencyRates(filter eventio.TimeRangeFilter) ([]eventio
 	}
 	return currencyRates, nil
 }
+
+// checkCurrencyRateFrom returns a FilterError if prices can not be converted
+// to currency from t onwards. Rates apply until they are replaced so a rate
+// in effect at t is enough.
+func checkCurrencyRateFrom(tx *sql.Tx, currency string, t string) error {
+	if currency == "" {
+		return nil
+	}
+	var ok bool
+	err := tx.QueryRow(`
+		select exists (
+			select 1 from currency_rates where code = $1 and valid_from <= $2::timestamptz
+		)
+	`, currency, t).Scan(&ok)
+	if err != nil {
+		return wrapPqError(err, "check-currency-rate")
+	}
+	if !ok {
+		return &eventio.FilterError{
+			Reason: fmt.Sprintf("there is no currency_rate for '%s' in effect from %s", currency, t),
+		}
+	}
+	return nil
+}
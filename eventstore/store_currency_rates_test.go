@@ -19,19 +19,19 @@ var _ = Describe("GetCurrencyRates", func() {
 			VATRates: []eventio.VATRate{
 				{
 					Code:      "Standard",
-					Rate:      0.2,
+					Rate:      eventio.MustParseDecimalNumber("0.2"),
 					ValidFrom: "1970-01-01T00:00:00+00:00",
 				},
 			},
 			CurrencyRates: []eventio.CurrencyRate{
 				{
 					Code:      "USD",
-					Rate:      0.8,
+					Rate:      eventio.MustParseDecimalNumber("0.8"),
 					ValidFrom: "1970-01-01T00:00:00+00:00",
 				},
 				{
 					Code:      "GBP",
-					Rate:      1,
+					Rate:      eventio.MustParseDecimalNumber("1"),
 					ValidFrom: "1970-01-01T00:00:00+00:00",
 				},
 			},
@@ -44,14 +44,14 @@ var _ = Describe("Currency Conversion", func() {
 			VATRates: []eventio.VATRate{
 				{
 					Code:      "Standard",
-					Rate:      0.2,
+					Rate:      eventio.MustParseDecimalNumber("0.2"),
 					ValidFrom: "epoch",
 				},
 			},
 			CurrencyRates: []eventio.CurrencyRate{
 				{
 					Code:      "GBP",
-					Rate:      1,
+					Rate:      eventio.MustParseDecimalNumber("1"),
 					ValidFrom: "epoch",
 				},
 			},
@@ -91,8 +91,8 @@ var _ = Describe("Currency Conversion", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		Expect(len(events)).To(BeNumerically("==", 1), "expected a single event to be returned")
-		Expect(events[0].Price.ExVAT).To(Equal("1"))
-		Expect(events[0].Price.IncVAT).To(Equal("1.2"))
+		Expect(events[0].Price.ExVAT).To(Equal(eventio.MustParseDecimal("1")))
+		Expect(events[0].Price.IncVAT).To(Equal(eventio.MustParseDecimal("1.2")))
 		Expect(len(events[0].Price.Details)).To(BeNumerically("==", 1), "expected a single event component to be returned")
 	})
 
@@ -112,14 +112,14 @@ var _ = Describe("Currency Conversion", func() {
 			VATRates: []eventio.VATRate{
 				{
 					Code:      "Standard",
-					Rate:      0.2,
+					Rate:      eventio.MustParseDecimalNumber("0.2"),
 					ValidFrom: "epoch",
 				},
 			},
 			CurrencyRates: []eventio.CurrencyRate{
 				{
 					Code:      "USD",
-					Rate:      0.8,
+					Rate:      eventio.MustParseDecimalNumber("0.8"),
 					ValidFrom: "epoch",
 				},
 			},
@@ -159,8 +159,8 @@ var _ = Describe("Currency Conversion", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		Expect(len(events)).To(BeNumerically("==", 1), "expected a single event to be returned")
-		Expect(events[0].Price.ExVAT).To(Equal("80.0"))
-		Expect(events[0].Price.IncVAT).To(Equal("96.00"))
+		Expect(events[0].Price.ExVAT).To(Equal(eventio.MustParseDecimal("80.0")))
+		Expect(events[0].Price.IncVAT).To(Equal(eventio.MustParseDecimal("96.00")))
 		Expect(len(events[0].Price.Details)).To(BeNumerically("==", 1), "expected a single event component to be returned")
 	})
 
@@ -180,19 +180,19 @@ var _ = Describe("Currency Conversion", func() {
 			VATRates: []eventio.VATRate{
 				{
 					Code:      "Standard",
-					Rate:      0.2,
+					Rate:      eventio.MustParseDecimalNumber("0.2"),
 					ValidFrom: "epoch",
 				},
 			},
 			CurrencyRates: []eventio.CurrencyRate{
 				{
 					Code:      "USD",
-					Rate:      2,
+					Rate:      eventio.MustParseDecimalNumber("2"),
 					ValidFrom: "epoch",
 				},
 				{
 					Code:      "USD",
-					Rate:      4,
+					Rate:      eventio.MustParseDecimalNumber("4"),
 					ValidFrom: "2001-02-01",
 				},
 			},
@@ -232,14 +232,14 @@ var _ = Describe("Currency Conversion", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		Expect(len(events)).To(BeNumerically("==", 1), "expected a single event to be returned")
-		Expect(events[0].Price.ExVAT).To(Equal("6"))
-		Expect(events[0].Price.IncVAT).To(Equal("7.2"))
+		Expect(events[0].Price.ExVAT).To(Equal(eventio.MustParseDecimal("6")))
+		Expect(events[0].Price.IncVAT).To(Equal(eventio.MustParseDecimal("7.2")))
 
 		Expect(len(events[0].Price.Details)).To(BeNumerically("==", 2), "expected two event components to be returned")
-		Expect(events[0].Price.Details[0].ExVAT).To(Equal("2"))
-		Expect(events[0].Price.Details[0].IncVAT).To(Equal("2.4"))
-		Expect(events[0].Price.Details[1].ExVAT).To(Equal("4"))
-		Expect(events[0].Price.Details[1].IncVAT).To(Equal("4.8"))
+		Expect(events[0].Price.Details[0].ExVAT).To(Equal(eventio.MustParseDecimal("2")))
+		Expect(events[0].Price.Details[0].IncVAT).To(Equal(eventio.MustParseDecimal("2.4")))
+		Expect(events[0].Price.Details[1].ExVAT).To(Equal(eventio.MustParseDecimal("4")))
+		Expect(events[0].Price.Details[1].IncVAT).To(Equal(eventio.MustParseDecimal("4.8")))
 	})
 
 	/*---------------------------------------------------------------------------------------*
@@ -259,19 +259,19 @@ var _ = Describe("Currency Conversion", func() {
 			VATRates: []eventio.VATRate{
 				{
 					Code:      "Standard",
-					Rate:      0.2,
+					Rate:      eventio.MustParseDecimalNumber("0.2"),
 					ValidFrom: "epoch",
 				},
 			},
 			CurrencyRates: []eventio.CurrencyRate{
 				{
 					Code:      "GBP",
-					Rate:      1,
+					Rate:      eventio.MustParseDecimalNumber("1"),
 					ValidFrom: "2001-01-01",
 				},
 				{
 					Code:      "USD",
-					Rate:      2,
+					Rate:      eventio.MustParseDecimalNumber("2"),
 					ValidFrom: "2001-01-01",
 				},
 			},
@@ -319,11 +319,11 @@ var _ = Describe("Currency Conversion", func() {
 		Expect(len(events)).To(BeNumerically("==", 1), "expected a single event to be returned")
 		Expect(len(events[0].Price.Details)).To(BeNumerically("==", 2), "expected two event components to be returned")
 
-		Expect(events[0].Price.Details[0].ExVAT).To(Equal("1"))
-		Expect(events[0].Price.Details[0].IncVAT).To(Equal("1.2"))
+		Expect(events[0].Price.Details[0].ExVAT).To(Equal(eventio.MustParseDecimal("1")))
+		Expect(events[0].Price.Details[0].IncVAT).To(Equal(eventio.MustParseDecimal("1.2")))
 		Expect(events[0].Price.Details[0].CurrencyCode).To(Equal("GBP"))
-		Expect(events[0].Price.Details[1].ExVAT).To(Equal("200"))
-		Expect(events[0].Price.Details[1].IncVAT).To(Equal("240.0"))
+		Expect(events[0].Price.Details[1].ExVAT).To(Equal(eventio.MustParseDecimal("200")))
+		Expect(events[0].Price.Details[1].IncVAT).To(Equal(eventio.MustParseDecimal("240.0")))
 		Expect(events[0].Price.Details[1].CurrencyCode).To(Equal("GBP"))
 	})
 
@@ -3,9 +3,13 @@ package eventstore
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/alphagov/paas-billing/eventio"
 	"github.com/lib/pq"
+	uuid "github.com/satori/go.uuid"
 )
 
 var _ eventio.BillableEventForecaster = &EventStore{}
@@ -17,12 +21,32 @@ const (
 	DummySpaceName = "my-space"
 )
 
+// forecastTables are the tables that forecast events are priced in. While
+// forecasting they are shadowed by temporary tables of the same name so that
+// the real events are neither changed nor locked.
+var forecastTables = []string{
+	"events",
+	"billable_event_components",
+}
+
 func (s *EventStore) ForecastBillableEventRows(ctx context.Context, events []eventio.UsageEvent, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	rows, err := s.forecastBillableEventRows(tx, events, filter)
+	// forecast events are always given the same plan name and no service
+	simulatedEvents := make([]eventio.UsageEvent, len(events))
+	for i, ev := range events {
+		ev.PlanName = "simulated"
+		ev.ServiceGUID = ""
+		ev.ServiceName = ""
+		simulatedEvents[i] = ev
+	}
+	if err := shadowForecastTables(tx, "false"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	rows, err := s.forecastBillableEventRows(tx, simulatedEvents, filter)
 	if err != nil {
 		tx.Rollback()
 		return nil, err
@@ -30,44 +54,69 @@ func (s *EventStore) ForecastBillableEventRows(ctx context.Context, events []eve
 	return rows, nil
 }
 
+// shadowForecastTables creates the temporary forecastTables as copies of the
+// rows of the real tables that match the condition
+func shadowForecastTables(tx *sql.Tx, condition string, args ...interface{}) error {
+	// the copies are made before the temporary table exists so that the
+	// select reads from the real table
+	for _, table := range forecastTables {
+		if _, err := tx.Exec(fmt.Sprintf(`
+			create temporary table %s on commit drop as
+			select * from %s where %s
+		`, table, table, condition), args...); err != nil {
+			return wrapPqError(err, "create-forecast-tables")
+		}
+	}
+	return nil
+}
+
+// forecastBillableEventRows prices the events in the temporary
+// forecastTables, which must have been created in tx
 func (s *EventStore) forecastBillableEventRows(tx *sql.Tx, events []eventio.UsageEvent, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
 	eventGUIDs := []string{}
+	resourceGUIDs := []string{}
 	for _, ev := range events {
 		_, err := tx.Exec(`
-			insert into events (
+			insert into pg_temp.events (
 				event_guid,
 				resource_guid, resource_name, resource_type,
 				org_guid, org_name, space_guid, space_name,
 				duration,
 				plan_guid, plan_name,
-				number_of_nodes, memory_in_mb, storage_in_mb
+				service_guid, service_name,
+				number_of_nodes, memory_in_mb, storage_in_mb, disk_in_mb
 			) values (
 				$1::uuid,
 				$2::uuid, $3::text, $4::text,
 				$5::uuid, $6::text, $7::uuid, $8::text,
 				tstzrange($9::timestamptz, $10::timestamptz),
-				$11::uuid, 'simulated',
-				$12::numeric, $13::numeric, $14::numeric
+				$11::uuid, $12::text,
+				nullif($13::text, '')::uuid, nullif($14::text, ''),
+				$15::numeric, $16::numeric, $17::numeric, $18::numeric
 			)
 		`,
 			ev.EventGUID,
 			ev.ResourceGUID, ev.ResourceName, ev.ResourceType,
 			ev.OrgGUID, ev.OrgName, ev.SpaceGUID, ev.SpaceName,
 			ev.EventStart, ev.EventStop,
-			ev.PlanGUID,
-			ev.NumberOfNodes, ev.MemoryInMB, ev.StorageInMB,
+			ev.PlanGUID, ev.PlanName,
+			ev.ServiceGUID, ev.ServiceName,
+			ev.NumberOfNodes, ev.MemoryInMB, ev.StorageInMB, ev.DiskInMB,
 		)
 		if err != nil {
 			return nil, err
 		}
 		eventGUIDs = append(eventGUIDs, ev.EventGUID)
+		resourceGUIDs = append(resourceGUIDs, ev.ResourceGUID)
 	}
+	// generate_billable_event_components reads events unqualified, so it
+	// sees the forecast events in the temporary table
 	_, err := tx.Exec(`
-		insert into billable_event_components (
-			select * from generate_billable_event_components()
-			where event_guid = any($1)
+		insert into pg_temp.billable_event_components (
+			select * from generate_billable_event_components($1::uuid[])
+			where event_guid = any($2::uuid[])
 		)
-	`, pq.Array(eventGUIDs))
+	`, pq.Array(resourceGUIDs), pq.Array(eventGUIDs))
 	if err != nil {
 		return nil, err
 	}
@@ -97,3 +146,230 @@ func (s *EventStore) ForecastBillableEvents(input []eventio.UsageEvent, filter e
 	}
 	return events, nil
 }
+
+// ForecastScenarioBillableEventRows returns the billable events that the
+// orgs in the filter would have if the changes in the scenario were made to
+// their usage events for the filter. The billable events are for the range
+// extended to the scenario's ExtendTo, if it is set, and only come from the
+// changed events. Nothing is stored, the changes are made to temporary
+// tables that are discarded with the transaction when ctx is done.
+func (s *EventStore) ForecastScenarioBillableEventRows(ctx context.Context, scenario eventio.Scenario, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.forecastScenarioBillableEventRows(tx, scenario, filter)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *EventStore) forecastScenarioBillableEventRows(tx *sql.Tx, scenario eventio.Scenario, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
+	if len(filter.OrgGUIDs) == 0 {
+		return nil, fmt.Errorf("a scenario can only be forecast for an org")
+	}
+	usageRows, err := s.getUsageEventRows(tx, filter)
+	if err != nil {
+		return nil, err
+	}
+	// the rows are closed without Close, which would roll back tx
+	defer usageRows.rows.Close()
+	usageEvents := []eventio.UsageEvent{}
+	for usageRows.Next() {
+		ev, err := usageRows.Event()
+		if err != nil {
+			return nil, err
+		}
+		usageEvents = append(usageEvents, *ev)
+	}
+	if err := usageRows.Err(); err != nil {
+		return nil, err
+	}
+	usageRows.rows.Close()
+
+	events, err := scenario.Apply(usageEvents, filter.RangeStop)
+	if err != nil {
+		return nil, err
+	}
+
+	// the plan of an event may have been changed, so the scenario's events
+	// are only limited by the rest of the filter
+	forecastFilter := scenario.Range(filter)
+	forecastFilter.PlanGUIDs = nil
+
+	// copy the range without the orgs' real events, so that the forecast
+	// only prices the scenario's events for them
+	err = shadowForecastTables(tx, `
+		duration && tstzrange($1::timestamptz, $2::timestamptz)
+		and org_guid <> all($3::uuid[])
+	`, forecastFilter.RangeStart, forecastFilter.RangeStop, pq.Array(filter.OrgGUIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.forecastBillableEventRows(tx, events, forecastFilter)
+}
+
+func (s *EventStore) ForecastScenarioBillableEvents(scenario eventio.Scenario, filter eventio.EventFilter) ([]eventio.BillableEvent, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rows, err := s.ForecastScenarioBillableEventRows(ctx, scenario, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []eventio.BillableEvent{}
+	for rows.Next() {
+		ev, err := rows.Event()
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ValidateForecastEvents checks the usage events to be forecast without a
+// database, returning every problem found at a path such as
+// events[1].event_stop. A plan_guid is unknown if it is not the plan_guid of
+// one of plans.
+func ValidateForecastEvents(events []eventio.UsageEvent, plans []eventio.PricingPlan) ValidationErrors {
+	errs := ValidationErrors{}
+
+	knownPlans := planGUIDs(plans)
+
+	seenEvents := map[string]int{}
+	for i, ev := range events {
+		path := fmt.Sprintf("events[%d]", i)
+		guids := []struct {
+			name  string
+			value string
+		}{
+			{"event_guid", ev.EventGUID},
+			{"resource_guid", ev.ResourceGUID},
+			{"org_guid", ev.OrgGUID},
+			{"space_guid", ev.SpaceGUID},
+			{"plan_guid", ev.PlanGUID},
+		}
+		for _, guid := range guids {
+			if _, err := uuid.FromString(guid.value); err != nil {
+				errs.add(path+"."+guid.name, "'%s' is not a valid guid", guid.value)
+			}
+		}
+		if j, ok := seenEvents[strings.ToLower(ev.EventGUID)]; ok {
+			errs.add(path+".event_guid", "duplicate of events[%d].event_guid", j)
+		} else {
+			seenEvents[strings.ToLower(ev.EventGUID)] = i
+		}
+		if _, err := uuid.FromString(ev.PlanGUID); err == nil && !knownPlans[strings.ToLower(ev.PlanGUID)] {
+			errs.add(path+".plan_guid", "'%s' is not a known plan", ev.PlanGUID)
+		}
+
+		start, startErr := parseEventTime(ev.EventStart)
+		if startErr != nil {
+			errs.add(path+".event_start", "%s", startErr)
+		}
+		stop, stopErr := parseEventTime(ev.EventStop)
+		if stopErr != nil {
+			errs.add(path+".event_stop", "%s", stopErr)
+		}
+		if startErr == nil && stopErr == nil && !stop.After(start) {
+			errs.add(path+".event_stop", "'%s' must be after event_start", ev.EventStop)
+		}
+
+		sizes := []struct {
+			name  string
+			value int64
+		}{
+			{"number_of_nodes", ev.NumberOfNodes},
+			{"memory_in_mb", ev.MemoryInMB},
+			{"storage_in_mb", ev.StorageInMB},
+			{"disk_in_mb", ev.DiskInMB},
+		}
+		for _, size := range sizes {
+			if size.value < 0 {
+				errs.add(path+"."+size.name, "must not be negative - got %d", size.value)
+			}
+		}
+	}
+
+	return errs
+}
+
+// ValidateScenario checks a scenario to be forecast for the filter without a
+// database, returning every problem found at a path such as
+// changes[1].plan_guid. A plan_guid is unknown if it is not the plan_guid of
+// one of plans.
+func ValidateScenario(scenario eventio.Scenario, filter eventio.EventFilter, plans []eventio.PricingPlan) ValidationErrors {
+	errs := ValidationErrors{}
+
+	knownPlans := planGUIDs(plans)
+
+	seenResources := map[string]int{}
+	for i, change := range scenario.Changes {
+		path := fmt.Sprintf("changes[%d]", i)
+		if _, err := uuid.FromString(change.ResourceGUID); err != nil {
+			errs.add(path+".resource_guid", "'%s' is not a valid guid", change.ResourceGUID)
+		} else if j, ok := seenResources[strings.ToLower(change.ResourceGUID)]; ok {
+			errs.add(path, "duplicate of changes[%d], a resource can only be changed once", j)
+		} else {
+			seenResources[strings.ToLower(change.ResourceGUID)] = i
+		}
+		if change.PlanGUID != "" {
+			if _, err := uuid.FromString(change.PlanGUID); err != nil {
+				errs.add(path+".plan_guid", "'%s' is not a valid guid", change.PlanGUID)
+			} else if !knownPlans[strings.ToLower(change.PlanGUID)] {
+				errs.add(path+".plan_guid", "'%s' is not a known plan", change.PlanGUID)
+			}
+		}
+		sizes := []struct {
+			name  string
+			value *int64
+		}{
+			{"number_of_nodes", change.NumberOfNodes},
+			{"memory_in_mb", change.MemoryInMB},
+			{"storage_in_mb", change.StorageInMB},
+			{"disk_in_mb", change.DiskInMB},
+		}
+		for _, size := range sizes {
+			if size.value != nil && *size.value < 0 {
+				errs.add(path+"."+size.name, "must not be negative - got %d", *size.value)
+			}
+		}
+	}
+
+	if scenario.ExtendTo != "" {
+		extendTo, err := eventio.ParseRangeTime(scenario.ExtendTo)
+		if err != nil {
+			errs.add("extend_to", "'%s' is not a valid time, expected a date or RFC3339 timestamp", scenario.ExtendTo)
+		} else if stop, err := eventio.ParseRangeTime(filter.RangeStop); err == nil && !extendTo.After(stop) {
+			errs.add("extend_to", "'%s' must be after range_stop", scenario.ExtendTo)
+		}
+	}
+
+	return errs
+}
+
+// planGUIDs returns the set of the plans' lower case plan_guids
+func planGUIDs(plans []eventio.PricingPlan) map[string]bool {
+	guids := map[string]bool{}
+	for _, plan := range plans {
+		guids[strings.ToLower(plan.PlanGUID)] = true
+	}
+	return guids
+}
+
+func parseEventTime(s string) (time.Time, error) {
+	for _, layout := range validFromLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("'%s' is not a valid time, expected a time like 2001-01-01T00:00:00Z", s)
+}
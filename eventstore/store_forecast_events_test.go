@@ -1,10 +1,15 @@
 package eventstore_test
 
 import (
+	"context"
+	"encoding/json"
+	"time"
+
 	"github.com/alphagov/paas-billing/eventio"
 	"github.com/alphagov/paas-billing/eventstore"
 	"github.com/alphagov/paas-billing/testenv"
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -130,19 +135,19 @@ var _ = Describe("ForecastBillingEvents", func() {
 			MemoryInMB:    64,
 			StorageInMB:   0,
 			Price: eventio.Price{
-				IncVAT: "2.400000000000000000000",
-				ExVAT:  "2.00000000000000000000",
+				IncVAT: eventio.MustParseDecimal("2.400000000000000000000"),
+				ExVAT:  eventio.MustParseDecimal("2.00000000000000000000"),
 				Details: []eventio.PriceComponent{
 					{
 						Name:         "node-cost",
 						PlanName:     "APP-PLAN1",
 						Start:        "2001-01-01T00:00:00+00:00",
 						Stop:         "2001-01-01T01:00:00+00:00",
-						VatRate:      "0.2",
+						VatRate:      eventio.MustParseDecimal("0.2"),
 						VatCode:      "Standard",
 						CurrencyCode: "GBP",
-						IncVAT:       "2.400000000000000000000",
-						ExVAT:        "2.00000000000000000000",
+						IncVAT:       eventio.MustParseDecimal("2.400000000000000000000"),
+						ExVAT:        eventio.MustParseDecimal("2.00000000000000000000"),
 					},
 				},
 			},
@@ -164,19 +169,19 @@ var _ = Describe("ForecastBillingEvents", func() {
 			MemoryInMB:    0,
 			StorageInMB:   1024,
 			Price: eventio.Price{
-				IncVAT: "2457.60000000000000000",
-				ExVAT:  "2048.0000000000000000",
+				IncVAT: eventio.MustParseDecimal("2457.60000000000000000"),
+				ExVAT:  eventio.MustParseDecimal("2048.0000000000000000"),
 				Details: []eventio.PriceComponent{
 					{
 						Name:         "storage-cost",
 						PlanName:     "SRV-PLAN1",
 						Start:        "2001-01-01T01:00:00+00:00",
 						Stop:         "2001-01-01T03:00:00+00:00",
-						VatRate:      "0.2",
+						VatRate:      eventio.MustParseDecimal("0.2"),
 						VatCode:      "Standard",
 						CurrencyCode: "GBP",
-						IncVAT:       "2457.60000000000000000",
-						ExVAT:        "2048.0000000000000000",
+						IncVAT:       eventio.MustParseDecimal("2457.60000000000000000"),
+						ExVAT:        eventio.MustParseDecimal("2048.0000000000000000"),
 					},
 				},
 			},
@@ -236,3 +241,226 @@ var _ = Describe("ForecastBillingEvents", func() {
 	})
 
 })
+
+var _ = Describe("ForecastScenarioBillableEvents", func() {
+
+	var (
+		db      *testenv.TempDB
+		store   eventio.EventStore
+		orgGUID = "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		appGUID = "c85e98f0-6d1b-4f45-9368-ea58263165a0"
+		filter  = eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-01-01T10:00:00Z",
+			OrgGUIDs:   []string{orgGUID},
+		}
+		nodes    = int64(2)
+		scenario = eventio.Scenario{
+			Changes:  []eventio.ResourceChange{{ResourceGUID: appGUID, NumberOfNodes: &nodes}},
+			ExtendTo: "2001-01-01T20:00:00Z",
+		}
+	)
+
+	BeforeEach(func() {
+		cfg := testenv.BasicConfig
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  eventstore.ComputePlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "APP-PLAN1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "node-cost",
+					Formula:      "($time_in_seconds / 3600) * $number_of_nodes",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+
+		var err error
+		db, err = testenv.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		store = db.Schema
+
+		Expect(store.StoreEvents([]eventio.RawEvent{
+			{
+				GUID:       "ae28a572-f485-48e1-87d0-98b7b8b66dfa",
+				Kind:       "app",
+				CreatedAt:  time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+				RawMessage: json.RawMessage(`{"state": "STARTED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "ORG1-SPACE1", "process_type": "web", "instance_count": 10, "previous_state": "STOPPED", "memory_in_mb_per_instance": 1000}`),
+			},
+			{
+				GUID:       "bd9036c5-8367-497d-bb56-94bfcac6621a",
+				Kind:       "app",
+				CreatedAt:  time.Date(2001, 1, 1, 10, 0, 0, 0, time.UTC),
+				RawMessage: json.RawMessage(`{"state": "STOPPED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "ORG1-SPACE1", "process_type": "web", "instance_count": 10, "previous_state": "STARTED", "memory_in_mb_per_instance": 1000}`),
+			},
+		})).To(Succeed())
+		Expect(store.Refresh()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	/*-----------------------------------------------------------------------------------*
+	     2001-01-01                                                                      .
+	       00:00                   10:00                   20:00                         .
+	         |                       |                       |                           .
+	 .   .   [========APP1===========]   .   .   .   .   .   .   .   .   .   .   .   .   .
+	 .   .   [====APP1 x2 nodes======|=====extended==========]   .   .   .   .   .   .   .
+	 .   .   |____ request range ____|   .   .   .   .   .   .   .   .   .   .   .   .   .
+	*-----------------------------------------------------------------------------------*/
+	It("should forecast the org's real events with the scenario's changes", func() {
+		forecastEvents, err := store.ForecastScenarioBillableEvents(scenario, filter)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(forecastEvents).To(HaveLen(1))
+		Expect(forecastEvents[0].ResourceGUID).To(Equal(appGUID))
+		Expect(forecastEvents[0].EventStart).To(Equal("2001-01-01T00:00:00+00:00"))
+		Expect(forecastEvents[0].EventStop).To(Equal("2001-01-01T20:00:00+00:00"))
+		Expect(forecastEvents[0].NumberOfNodes).To(Equal(int64(2)))
+		Expect(forecastEvents[0].Price.ExVAT).To(Equal(eventio.MustParseDecimal("40")))
+
+		realEvents, err := store.GetBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-01-02",
+			OrgGUIDs:   []string{orgGUID},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(realEvents).To(HaveLen(1), "did not expect the scenario to change the real events")
+		Expect(realEvents[0].NumberOfNodes).To(Equal(int64(10)))
+		Expect(realEvents[0].EventStop).To(Equal("2001-01-01T10:00:00+00:00"))
+	})
+
+	It("should neither change nor lock the real events while the forecast is read", func() {
+		componentCount := db.Get(`select count(*) from billable_event_components`)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		rows, err := store.ForecastScenarioBillableEventRows(ctx, scenario, filter)
+		Expect(err).ToNot(HaveOccurred())
+		defer rows.Close()
+		Expect(rows.Next()).To(BeTrue())
+
+		Expect(db.Get(`select count(*) from billable_event_components`)).To(Equal(componentCount))
+		Expect(db.Get(`
+			select count(*) from (
+				select 1 from billable_event_components for update nowait
+			) locked
+		`)).To(Equal(componentCount), "did not expect the forecast to lock the real components")
+		Expect(db.Get(`
+			select count(*) from (
+				select 1 from events for update nowait
+			) locked
+		`)).To(BeEquivalentTo(1), "did not expect the forecast to lock the real events")
+	})
+
+})
+
+var _ = Describe("ValidateForecastEvents", func() {
+
+	validEvent := func() eventio.UsageEvent {
+		return eventio.UsageEvent{
+			EventGUID:     "00000000-0000-0000-0000-000000000001",
+			ResourceGUID:  "00000000-0000-0000-0001-000000000001",
+			ResourceType:  "app",
+			OrgGUID:       eventstore.DummyOrgGUID,
+			SpaceGUID:     eventstore.DummySpaceGUID,
+			EventStart:    "2001-01-01T00:00",
+			EventStop:     "2001-01-01T01:00",
+			PlanGUID:      eventstore.ComputePlanGUID,
+			NumberOfNodes: 1,
+			MemoryInMB:    64,
+		}
+	}
+
+	plans := []eventio.PricingPlan{{PlanGUID: eventstore.ComputePlanGUID, ValidFrom: "2001-01-01"}}
+
+	It("should find no problems with valid events", func() {
+		Expect(eventstore.ValidateForecastEvents([]eventio.UsageEvent{validEvent()}, plans)).To(BeEmpty())
+	})
+
+	DescribeTable("problems",
+		func(modify func(*eventio.UsageEvent), expected ...eventstore.ValidationError) {
+			ev := validEvent()
+			modify(&ev)
+			other := validEvent()
+			other.EventGUID = "00000000-0000-0000-0000-000000000002"
+			Expect(eventstore.ValidateForecastEvents([]eventio.UsageEvent{other, ev}, plans)).To(ConsistOf(expected))
+		},
+		Entry("invalid guids",
+			func(ev *eventio.UsageEvent) {
+				ev.ResourceGUID = "my-app"
+				ev.SpaceGUID = ""
+			},
+			eventstore.ValidationError{Path: "events[1].resource_guid", Message: "'my-app' is not a valid guid"},
+			eventstore.ValidationError{Path: "events[1].space_guid", Message: "'' is not a valid guid"},
+		),
+		Entry("duplicate event_guid",
+			func(ev *eventio.UsageEvent) { ev.EventGUID = "00000000-0000-0000-0000-000000000002" },
+			eventstore.ValidationError{Path: "events[1].event_guid", Message: "duplicate of events[0].event_guid"},
+		),
+		Entry("unknown plan",
+			func(ev *eventio.UsageEvent) { ev.PlanGUID = "dc0e3b0d-f8b6-4a9b-9e2f-5b1a5f0d6c4e" },
+			eventstore.ValidationError{Path: "events[1].plan_guid", Message: "'dc0e3b0d-f8b6-4a9b-9e2f-5b1a5f0d6c4e' is not a known plan"},
+		),
+		Entry("stop before start",
+			func(ev *eventio.UsageEvent) { ev.EventStop = "2000-12-31T23:00:00Z" },
+			eventstore.ValidationError{Path: "events[1].event_stop", Message: "'2000-12-31T23:00:00Z' must be after event_start"},
+		),
+		Entry("invalid start",
+			func(ev *eventio.UsageEvent) { ev.EventStart = "yesterday" },
+			eventstore.ValidationError{Path: "events[1].event_start", Message: "'yesterday' is not a valid time, expected a time like 2001-01-01T00:00:00Z"},
+		),
+		Entry("negative sizes",
+			func(ev *eventio.UsageEvent) {
+				ev.MemoryInMB = -64
+				ev.DiskInMB = -1
+			},
+			eventstore.ValidationError{Path: "events[1].memory_in_mb", Message: "must not be negative - got -64"},
+			eventstore.ValidationError{Path: "events[1].disk_in_mb", Message: "must not be negative - got -1"},
+		),
+	)
+
+})
+
+var _ = Describe("ValidateScenario", func() {
+
+	var (
+		filter = eventio.EventFilter{RangeStart: "2001-01-01", RangeStop: "2001-01-15"}
+		plans  = []eventio.PricingPlan{{PlanGUID: eventstore.ComputePlanGUID, ValidFrom: "2001-01-01"}}
+		nodes  = int64(2)
+	)
+
+	It("should find no problems with a valid scenario", func() {
+		scenario := eventio.Scenario{
+			Changes: []eventio.ResourceChange{
+				{ResourceGUID: "00000000-0000-0000-0001-000000000001", PlanGUID: eventstore.ComputePlanGUID, NumberOfNodes: &nodes},
+				{ResourceGUID: "00000000-0000-0000-0001-000000000002", Remove: true},
+			},
+			ExtendTo: "2001-02-01",
+		}
+		Expect(eventstore.ValidateScenario(scenario, filter, plans)).To(BeEmpty())
+	})
+
+	It("should find every problem", func() {
+		negative := int64(-1)
+		scenario := eventio.Scenario{
+			Changes: []eventio.ResourceChange{
+				{ResourceGUID: "00000000-0000-0000-0001-000000000001", PlanGUID: "dc0e3b0d-f8b6-4a9b-9e2f-5b1a5f0d6c4e"},
+				{ResourceGUID: "00000000-0000-0000-0001-000000000001", MemoryInMB: &negative},
+				{ResourceGUID: "my-app"},
+			},
+			ExtendTo: "2001-01-10",
+		}
+		Expect(eventstore.ValidateScenario(scenario, filter, plans)).To(ConsistOf(
+			eventstore.ValidationError{Path: "changes[0].plan_guid", Message: "'dc0e3b0d-f8b6-4a9b-9e2f-5b1a5f0d6c4e' is not a known plan"},
+			eventstore.ValidationError{Path: "changes[1]", Message: "duplicate of changes[0], a resource can only be changed once"},
+			eventstore.ValidationError{Path: "changes[1].memory_in_mb", Message: "must not be negative - got -1"},
+			eventstore.ValidationError{Path: "changes[2].resource_guid", Message: "'my-app' is not a valid guid"},
+			eventstore.ValidationError{Path: "extend_to", Message: "'2001-01-10' must be after range_stop"},
+		))
+	})
+
+})
@@ -2,12 +2,15 @@ package eventstore_test
 
 import (
 	"math"
+	"math/big"
 
 	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore/formula"
 	"github.com/alphagov/paas-billing/testenv"
 	uuid "github.com/satori/go.uuid"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -38,7 +41,7 @@ var _ = Describe("Pricing Formulae", func() {
 
 		return db.Conn.QueryRow(`
 			select
-				eval_formula(64, 128, 2, tstzrange(now(), now() + '60 seconds'), formula) as result
+				eval_formula(64, 128, 2, 256, tstzrange('2001-02-03 00:00:00+00', '2001-02-03 00:01:00+00'), 1, compiled_formula) as result
 			from
 				pricing_plan_components
 			where
@@ -116,6 +119,27 @@ var _ = Describe("Pricing Formulae", func() {
 		Expect(out).To(Equal(2 * 2))
 	})
 
+	It("Should allow $disk_in_mb variable", func() {
+		var out int
+		err := insert("$disk_in_mb * 2", &out)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal(256 * 2))
+	})
+
+	It("Should allow $month_seconds variable for the month containing the start of the duration", func() {
+		var out int
+		err := insert("$month_seconds", &out)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal(28 * 24 * 60 * 60))
+	})
+
+	It("Should allow $event_count variable", func() {
+		var out int
+		err := insert("$event_count * 5", &out)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal(5))
+	})
+
 	It("Should allow power of operator", func() {
 		var out float64
 		err := insert("2^2", &out)
@@ -163,4 +187,63 @@ var _ = Describe("Pricing Formulae", func() {
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(MatchRegexp(`illegal token in formula: \$unknown`))
 	})
+
+	DescribeTable("should give the same result when evaluated in Go",
+		func(source string) {
+			var sqlResult string
+			err := insert(source, &sqlResult)
+			Expect(err).ToNot(HaveOccurred())
+			expected, ok := new(big.Rat).SetString(sqlResult)
+			Expect(ok).To(BeTrue())
+
+			goResult, err := formula.MustParse(source).Eval(formula.Vars{
+				MemoryInMB:    big.NewRat(64, 1),
+				StorageInMB:   big.NewRat(128, 1),
+				NumberOfNodes: big.NewRat(2, 1),
+				TimeInSeconds: big.NewRat(60, 1),
+				DiskInMB:      big.NewRat(256, 1),
+				MonthSeconds:  big.NewRat(28*24*60*60, 1),
+				EventCount:    big.NewRat(1, 1),
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			// postgres rounds inexact numeric division to 20 or so
+			// significant digits where Go is exact
+			diff, _ := new(big.Rat).Sub(goResult, expected).Float64()
+			Expect(math.Abs(diff)).To(BeNumerically("<", 1e-12), "go=%s sql=%s", goResult.FloatString(20), sqlResult)
+		},
+		Entry("integer arithmetic", "((2 * 2::integer) + 1 - 1) / 1"),
+		Entry("integer division", "7 / 2"),
+		Entry("negative integer division", "-7 / 2"),
+		Entry("bigint arithmetic", "12147483647 * (2)::bigint"),
+		Entry("numeric arithmetic", "1.5 * 2 - 0.25"),
+		Entry("numeric division", "1 / 3.0"),
+		Entry("variable arithmetic", "$memory_in_mb * $number_of_nodes + $storage_in_mb - $time_in_seconds"),
+		Entry("variable division", "$time_in_seconds / 3600 * 2"),
+		Entry("integer power", "2^10"),
+		Entry("numeric power", "1.5^3"),
+		Entry("fractional power", "$memory_in_mb ^ 0.5"),
+		Entry("power precedence", "-2^2 * 3 + 2^3^2"),
+		Entry("ceil of integer", "ceil(5)"),
+		Entry("ceil of numeric", "ceil(5.0/3.0)"),
+		Entry("ceil of double", "ceil(2^0.5)"),
+		Entry("casting numeric to integer", "2.5::integer + (-2.5)::integer + 3.5::bigint"),
+		Entry("casting double to integer", "(2^-1)::integer + (1.5^1 * 1)::integer"),
+		Entry("casting integer to numeric", "5::numeric / 2"),
+		Entry("realistic compute formula", "ceil($time_in_seconds/3600) * 0.01 * $memory_in_mb / 1024 * $number_of_nodes"),
+		Entry("realistic storage formula", "($storage_in_mb / 1024) * 0.0001 * $time_in_seconds"),
+		Entry("floor", "floor(5.0/3.0) + floor(-0.5) + floor(2^0.5)"),
+		Entry("round", "round(2.5) + round(-2.5) + round(5 * 2^-1) + round(7)"),
+		Entry("round to decimal places", "round(1.23456, 2) + round(1250, -2) + round(2^0.5, 3)"),
+		Entry("greatest and least", "greatest(1, 3.5, $number_of_nodes) + least(1, 3.5, $number_of_nodes) + greatest(2^0.5, 1)"),
+		Entry("min and max", "max($memory_in_mb, 100) + min($memory_in_mb, 100)"),
+		Entry("minimum charge", "greatest($time_in_seconds * 0.001, 1)"),
+		Entry("tiers", "tier($storage_in_mb, 0, 100) * 2 + tier($storage_in_mb, 100) * 1 + tier($memory_in_mb, 100)"),
+		Entry("case", "case when $memory_in_mb < 64 then 1 when $memory_in_mb <= 64 then 2.5 else 3 end"),
+		Entry("case with conditions", "case when ($memory_in_mb > 64 or $storage_in_mb > 64) and not ($number_of_nodes = 3) then 1 else 0 end"),
+		Entry("disk", "$disk_in_mb / 1024 * 0.01"),
+		Entry("prorated monthly fee", "$time_in_seconds / $month_seconds * 10"),
+		Entry("one off fee per event", "$event_count * 0.5 + $time_in_seconds * 0.001"),
+		Entry("case with mixed types", "case when $number_of_nodes <> 2 then 2^0.5 else 5 end / 2"),
+	)
 })
@@ -0,0 +1,405 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+var _ eventio.InvoiceReader = &EventStore{}
+var _ eventio.InvoiceWriter = &EventStore{}
+
+// invoiceDetail is the total cost of one plan used in one space of an org
+// during a consolidated month, for one VAT code and rate
+type invoiceDetail struct {
+	BillingPeriod string          `json:"billing_period"`
+	OrgGUID       string          `json:"org_guid"`
+	OrgName       string          `json:"org_name"`
+	SpaceGUID     string          `json:"space_guid"`
+	SpaceName     string          `json:"space_name"`
+	PlanGUID      string          `json:"plan_guid"`
+	PlanName      string          `json:"plan_name"`
+	VATCode       string          `json:"vat_code"`
+	VATRate       eventio.Decimal `json:"vat_rate"`
+	ExVAT         eventio.Decimal `json:"ex_vat"`
+}
+
+// generateInvoices creates a draft invoice for each org that has billable
+// events in a consolidated month but does not have an invoice for it yet.
+// The lines are rounded to eventio.MoneyPlaces, and VAT is calculated on the
+// total of the rounded lines for each VAT code and rate.
+func (s *EventStore) generateInvoices(tx *sql.Tx) error {
+	startTime := time.Now()
+	rows, err := queryJSON(tx, `
+		with details as (
+			select
+				cbe.consolidated_range,
+				cbe.org_guid,
+				first_value(cbe.org_name) over org_events as org_name,
+				cbe.space_guid,
+				first_value(cbe.space_name) over space_events as space_name,
+				cbe.plan_guid,
+				d.detail->>'plan_name' as plan_name,
+				d.detail->>'vat_code' as vat_code,
+				(d.detail->>'vat_rate')::numeric as vat_rate,
+				(d.detail->>'ex_vat')::numeric as ex_vat
+			from
+				consolidated_billable_events cbe,
+				jsonb_array_elements(cbe.price->'details') as d(detail)
+			where
+				not exists (
+					select 1 from invoices i
+					where i.org_guid = cbe.org_guid
+					and i.billing_period = cbe.consolidated_range
+				)
+			window
+				org_events as (
+					partition by cbe.consolidated_range, cbe.org_guid
+					order by upper(cbe.duration) desc, cbe.event_guid
+				),
+				space_events as (
+					partition by cbe.consolidated_range, cbe.space_guid
+					order by upper(cbe.duration) desc, cbe.event_guid
+				)
+		)
+		select
+			consolidated_range::text as billing_period,
+			org_guid,
+			org_name,
+			space_guid,
+			space_name,
+			plan_guid,
+			plan_name,
+			vat_code,
+			vat_rate,
+			sum(ex_vat) as ex_vat
+		from
+			details
+		group by
+			consolidated_range, org_guid, org_name, space_guid, space_name,
+			plan_guid, plan_name, vat_code, vat_rate
+		order by
+			lower(consolidated_range), org_name, org_guid, space_name, space_guid,
+			plan_name, plan_guid, vat_code, vat_rate
+	`)
+	if err != nil {
+		return wrapPqError(err, "generate-invoices")
+	}
+	details := []invoiceDetail{}
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			rows.Close()
+			return err
+		}
+		var detail invoiceDetail
+		if err := json.Unmarshal(b, &detail); err != nil {
+			rows.Close()
+			return err
+		}
+		details = append(details, detail)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	count := 0
+	for len(details) > 0 {
+		n := 1
+		for n < len(details) && details[n].BillingPeriod == details[0].BillingPeriod && details[n].OrgGUID == details[0].OrgGUID {
+			n++
+		}
+		if err := s.insertInvoice(tx, details[:n]); err != nil {
+			return err
+		}
+		details = details[n:]
+		count++
+	}
+	s.logger.Info("generate-invoices", lager.Data{
+		"invoices": count,
+		"elapsed":  int64(time.Since(startTime)),
+	})
+	return nil
+}
+
+// insertInvoice records the invoice for details, which must all be for the
+// same org and month
+func (s *EventStore) insertInvoice(tx *sql.Tx, details []invoiceDetail) error {
+	lines := make([]eventio.InvoiceLine, len(details))
+	summaries := map[string]*eventio.InvoiceVATSummary{}
+	for i, detail := range details {
+		lines[i] = eventio.InvoiceLine{
+			SpaceGUID: detail.SpaceGUID,
+			SpaceName: detail.SpaceName,
+			PlanGUID:  detail.PlanGUID,
+			PlanName:  detail.PlanName,
+			VATCode:   detail.VATCode,
+			VATRate:   detail.VATRate,
+			ExVAT:     detail.ExVAT.RoundMoney(),
+		}
+		key := detail.VATCode + " " + detail.VATRate.String()
+		if summaries[key] == nil {
+			summaries[key] = &eventio.InvoiceVATSummary{
+				VATCode: detail.VATCode,
+				VATRate: detail.VATRate,
+				ExVAT:   eventio.Decimal{}.RoundMoney(),
+			}
+		}
+		summaries[key].ExVAT = summaries[key].ExVAT.Add(lines[i].ExVAT)
+	}
+	keys := []string{}
+	for key := range summaries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	totalExVAT := eventio.Decimal{}.RoundMoney()
+	totalVAT := eventio.Decimal{}.RoundMoney()
+	for _, key := range keys {
+		summary := summaries[key]
+		summary.VAT = summary.ExVAT.Mul(summary.VATRate).RoundMoney()
+		totalExVAT = totalExVAT.Add(summary.ExVAT)
+		totalVAT = totalVAT.Add(summary.VAT)
+	}
+
+	// the counter stays locked until tx ends, so another invoice can not be
+	// numbered until this one is committed or rolled back
+	var number int64
+	if err := tx.QueryRow(`select last_number + 1 from invoice_number_counter for update`).Scan(&number); err != nil {
+		return wrapPqError(err, "lock-invoice-number")
+	}
+	if _, err := tx.Exec(`update invoice_number_counter set last_number = $1`, number); err != nil {
+		return wrapPqError(err, "allocate-invoice-number")
+	}
+	_, err := tx.Exec(`
+		insert into invoices (
+			number, org_guid, org_name, billing_period, currency_code,
+			total_ex_vat, total_vat, total_inc_vat
+		) values (
+			$1, $2, $3, $4::tstzrange, $5, $6, $7, $8
+		)
+	`,
+		number,
+		details[0].OrgGUID,
+		details[0].OrgName,
+		details[0].BillingPeriod,
+		eventio.BaseCurrency,
+		totalExVAT,
+		totalVAT,
+		totalExVAT.Add(totalVAT),
+	)
+	if err != nil {
+		return wrapPqError(err, "insert-invoice")
+	}
+	for _, line := range lines {
+		_, err := tx.Exec(`
+			insert into invoice_lines (
+				invoice_number, space_guid, space_name, plan_guid, plan_name,
+				vat_code, vat_rate, ex_vat
+			) values (
+				$1, $2, $3, $4, $5, $6, $7, $8
+			)
+		`, number, line.SpaceGUID, line.SpaceName, line.PlanGUID, line.PlanName, line.VATCode, line.VATRate, line.ExVAT)
+		if err != nil {
+			return wrapPqError(err, "insert-invoice-line")
+		}
+	}
+	for _, key := range keys {
+		summary := summaries[key]
+		_, err := tx.Exec(`
+			insert into invoice_vat_summaries (
+				invoice_number, vat_code, vat_rate, ex_vat, vat
+			) values (
+				$1, $2, $3, $4, $5
+			)
+		`, number, summary.VATCode, summary.VATRate, summary.ExVAT, summary.VAT)
+		if err != nil {
+			return wrapPqError(err, "insert-invoice-vat-summary")
+		}
+	}
+	return nil
+}
+
+// GetInvoices returns the invoices for billing periods that overlap the
+// filter's range, for the filter's orgs or for all orgs if it has none
+func (s *EventStore) GetInvoices(filter eventio.EventFilter) ([]eventio.Invoice, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	args := []interface{}{
+		fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop), // $1
+	}
+	where := "i.billing_period && $1::tstzrange"
+	orgPlaceholders := []string{}
+	for _, orgGUID := range filter.OrgGUIDs {
+		args = append(args, orgGUID)
+		orgPlaceholders = append(orgPlaceholders, fmt.Sprintf("($%d::uuid)", len(args))) // $N
+	}
+	if len(orgPlaceholders) > 0 {
+		where += fmt.Sprintf(" and i.org_guid = any (values %s)", strings.Join(orgPlaceholders, ","))
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return getInvoices(tx, where, args...)
+}
+
+func (s *EventStore) GetInvoice(number int64) (eventio.Invoice, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return eventio.Invoice{}, err
+	}
+	defer tx.Rollback()
+	return getInvoice(tx, number)
+}
+
+// IssueInvoice marks a draft invoice as issued
+func (s *EventStore) IssueInvoice(number int64, by string) (eventio.Invoice, error) {
+	return s.setInvoiceStatus(number, eventio.InvoiceDraft, eventio.InvoiceIssued, `
+		update invoices set status = 'issued', issued_at = now(), issued_by = $2
+		where number = $1
+	`, by)
+}
+
+// CreditInvoice marks an issued invoice as credited, cancelling what was
+// owed
+func (s *EventStore) CreditInvoice(number int64, by string) (eventio.Invoice, error) {
+	return s.setInvoiceStatus(number, eventio.InvoiceIssued, eventio.InvoiceCredited, `
+		update invoices set status = 'credited', credited_at = now(), credited_by = $2
+		where number = $1
+	`, by)
+}
+
+func (s *EventStore) setInvoiceStatus(number int64, from eventio.InvoiceStatus, to eventio.InvoiceStatus, update string, by string) (eventio.Invoice, error) {
+	if strings.TrimSpace(by) == "" {
+		return eventio.Invoice{}, errors.New("the user changing an invoice's status must be given")
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return eventio.Invoice{}, err
+	}
+	defer tx.Rollback()
+
+	var status eventio.InvoiceStatus
+	err = tx.QueryRow(`select status from invoices where number = $1 for update`, number).Scan(&status)
+	if err == sql.ErrNoRows {
+		return eventio.Invoice{}, eventio.ErrInvoiceNotFound
+	}
+	if err != nil {
+		return eventio.Invoice{}, wrapPqError(err, "set-invoice-status")
+	}
+	if status != from {
+		return eventio.Invoice{}, &eventio.InvoiceStatusError{
+			Reason: fmt.Sprintf("invoice %d is %s, only %s invoices can be %s", number, status, from, to),
+		}
+	}
+	if _, err := tx.Exec(update, number, by); err != nil {
+		return eventio.Invoice{}, wrapPqError(err, "set-invoice-status")
+	}
+	invoice, err := getInvoice(tx, number)
+	if err != nil {
+		return eventio.Invoice{}, err
+	}
+	s.logger.Info("set-invoice-status", lager.Data{
+		"number": number,
+		"status": to,
+		"by":     by,
+	})
+	return invoice, tx.Commit()
+}
+
+func getInvoice(tx *sql.Tx, number int64) (eventio.Invoice, error) {
+	invoices, err := getInvoices(tx, "i.number = $1", number)
+	if err != nil {
+		return eventio.Invoice{}, err
+	}
+	if len(invoices) != 1 {
+		return eventio.Invoice{}, eventio.ErrInvoiceNotFound
+	}
+	return invoices[0], nil
+}
+
+func getInvoices(tx *sql.Tx, where string, args ...interface{}) ([]eventio.Invoice, error) {
+	rows, err := queryJSON(tx, `
+		select
+			i.number,
+			i.org_guid,
+			i.org_name,
+			to_char(lower(i.billing_period), 'YYYY-MM-DD') as period_start,
+			to_char(upper(i.billing_period), 'YYYY-MM-DD') as period_stop,
+			i.currency_code,
+			i.status,
+			coalesce((
+				select json_agg(json_build_object(
+					'space_guid', l.space_guid,
+					'space_name', l.space_name,
+					'plan_guid', l.plan_guid,
+					'plan_name', l.plan_name,
+					'vat_code', l.vat_code,
+					'vat_rate', l.vat_rate,
+					'ex_vat', l.ex_vat
+				) order by l.space_name, l.space_guid, l.plan_name, l.plan_guid, l.vat_code, l.vat_rate)
+				from invoice_lines l
+				where l.invoice_number = i.number
+			), '[]') as lines,
+			coalesce((
+				select json_agg(json_build_object(
+					'vat_code', v.vat_code,
+					'vat_rate', v.vat_rate,
+					'ex_vat', v.ex_vat,
+					'vat', v.vat
+				) order by v.vat_code, v.vat_rate)
+				from invoice_vat_summaries v
+				where v.invoice_number = i.number
+			), '[]') as vat_summary,
+			i.total_ex_vat,
+			i.total_vat,
+			i.total_inc_vat,
+			i.created_at,
+			i.issued_at,
+			i.issued_by,
+			i.credited_at,
+			i.credited_by
+		from
+			invoices i
+		where
+			`+where+`
+		order by
+			lower(i.billing_period), i.org_name, i.number
+	`, args...)
+	if err != nil {
+		return nil, wrapPqError(err, "get-invoices")
+	}
+	defer rows.Close()
+	invoices := []eventio.Invoice{}
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var invoice eventio.Invoice
+		if err := json.Unmarshal(b, &invoice); err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, invoice)
+	}
+	return invoices, rows.Err()
+}
@@ -0,0 +1,177 @@
+package eventstore_test
+
+import (
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Invoices", func() {
+	var (
+		cfg      eventstore.Config
+		scenario *testenv.TestScenario
+		db       *testenv.TempDB
+		january  = eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		}
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		scenario = testenv.NewTestScenario("2001-01-01T00:00")
+		scenario.AddComputePlan()
+		scenario.AppLifeCycle("org1", "space1", "app1",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+100h", State: "STOPPED"},
+		)
+		scenario.AppLifeCycle("org1", "space2", "app2",
+			testenv.EventInfo{Delta: "+1h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+6h", State: "STOPPED"},
+		)
+		scenario.AppLifeCycle("org2", "space3", "app3",
+			testenv.EventInfo{Delta: "+1h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+2h", State: "STOPPED"},
+		)
+
+		var err error
+		db, err = scenario.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(db.Schema.Refresh()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("should generate a draft invoice per org when a month is consolidated", func() {
+		Expect(db.Schema.Consolidate(january)).To(Succeed())
+
+		invoices, err := db.Schema.GetInvoices(january)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(invoices).To(HaveLen(2))
+
+		invoice := invoices[0]
+		Expect(invoice.OrgGUID).To(Equal(scenario.GetOrgGUID("org1")))
+		Expect(invoice.PeriodStart).To(Equal("2001-01-01"))
+		Expect(invoice.PeriodStop).To(Equal("2001-02-01"))
+		Expect(invoice.CurrencyCode).To(Equal("GBP"))
+		Expect(invoice.Status).To(Equal(eventio.InvoiceDraft))
+		Expect(invoice.Lines).To(Equal([]eventio.InvoiceLine{
+			{
+				SpaceGUID: scenario.GetSpaceGUID("org1", "space1"),
+				SpaceName: "space1",
+				PlanGUID:  eventstore.ComputePlanGUID,
+				PlanName:  "ComputePlan1",
+				VATCode:   "Standard",
+				VATRate:   eventio.MustParseDecimal("0.2"),
+				ExVAT:     eventio.MustParseDecimal("1.00"),
+			},
+			{
+				SpaceGUID: scenario.GetSpaceGUID("org1", "space2"),
+				SpaceName: "space2",
+				PlanGUID:  eventstore.ComputePlanGUID,
+				PlanName:  "ComputePlan1",
+				VATCode:   "Standard",
+				VATRate:   eventio.MustParseDecimal("0.2"),
+				ExVAT:     eventio.MustParseDecimal("0.05"),
+			},
+		}))
+		Expect(invoice.VATSummary).To(Equal([]eventio.InvoiceVATSummary{
+			{
+				VATCode: "Standard",
+				VATRate: eventio.MustParseDecimal("0.2"),
+				ExVAT:   eventio.MustParseDecimal("1.05"),
+				VAT:     eventio.MustParseDecimal("0.21"),
+			},
+		}))
+		Expect(invoice.TotalExVAT.String()).To(Equal("1.05"))
+		Expect(invoice.TotalVAT.String()).To(Equal("0.21"))
+		Expect(invoice.TotalIncVAT.String()).To(Equal("1.26"))
+		Expect(invoice.IssuedAt).To(BeNil())
+
+		Expect(invoices[1].OrgGUID).To(Equal(scenario.GetOrgGUID("org2")))
+		Expect(invoices[1].Number).ToNot(Equal(invoice.Number))
+	})
+
+	It("should only generate an invoice once", func() {
+		Expect(db.Schema.Consolidate(january)).To(Succeed())
+		Expect(db.Schema.ConsolidateFullMonths("2001-01-01", "2001-03-01")).To(Succeed())
+
+		Expect(db.Get(`select count(*) from invoices`)).To(BeEquivalentTo(2))
+	})
+
+	It("should number invoices without gaps when a transaction is rolled back", func() {
+		tx, err := db.Conn.Begin()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = tx.Exec(`update invoice_number_counter set last_number = last_number + 1`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tx.Rollback()).To(Succeed())
+
+		Expect(db.Schema.Consolidate(january)).To(Succeed())
+
+		Expect(
+			db.Query(`select number from invoices order by number`),
+		).To(MatchJSON(testenv.Rows{
+			{"number": 1},
+			{"number": 2},
+		}))
+		Expect(db.Get(`select last_number from invoice_number_counter`)).To(BeEquivalentTo(2))
+	})
+
+	It("should filter invoices by org", func() {
+		Expect(db.Schema.Consolidate(january)).To(Succeed())
+
+		filter := january
+		filter.OrgGUIDs = []string{scenario.GetOrgGUID("org2")}
+		invoices, err := db.Schema.GetInvoices(filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(invoices).To(HaveLen(1))
+		Expect(invoices[0].OrgGUID).To(Equal(scenario.GetOrgGUID("org2")))
+	})
+
+	It("should issue and then credit an invoice", func() {
+		Expect(db.Schema.Consolidate(january)).To(Succeed())
+		invoices, err := db.Schema.GetInvoices(january)
+		Expect(err).ToNot(HaveOccurred())
+		number := invoices[0].Number
+
+		_, err = db.Schema.CreditInvoice(number, "jeff@example.com")
+		Expect(err).To(MatchError(ContainSubstring("only issued invoices can be credited")))
+
+		issued, err := db.Schema.IssueInvoice(number, "jeff@example.com")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(issued.Status).To(Equal(eventio.InvoiceIssued))
+		Expect(issued.IssuedBy).To(Equal("jeff@example.com"))
+		Expect(issued.IssuedAt).ToNot(BeNil())
+		Expect(issued.TotalIncVAT).To(Equal(invoices[0].TotalIncVAT))
+
+		_, err = db.Schema.IssueInvoice(number, "jeff@example.com")
+		Expect(err).To(BeAssignableToTypeOf(&eventio.InvoiceStatusError{}))
+
+		credited, err := db.Schema.CreditInvoice(number, "bob@example.com")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(credited.Status).To(Equal(eventio.InvoiceCredited))
+		Expect(credited.CreditedBy).To(Equal("bob@example.com"))
+	})
+
+	It("should return ErrInvoiceNotFound for an unknown invoice", func() {
+		_, err := db.Schema.GetInvoice(12345)
+		Expect(err).To(Equal(eventio.ErrInvoiceNotFound))
+		_, err = db.Schema.IssueInvoice(12345, "jeff@example.com")
+		Expect(err).To(Equal(eventio.ErrInvoiceNotFound))
+	})
+
+	It("should not allow invoices to be changed or deleted", func() {
+		Expect(db.Schema.Consolidate(january)).To(Succeed())
+
+		_, err := db.Conn.Exec(`update invoices set total_ex_vat = 0`)
+		Expect(err).To(MatchError(ContainSubstring("can not be changed")))
+		_, err = db.Conn.Exec(`delete from invoice_lines`)
+		Expect(err).To(MatchError(ContainSubstring("can not be changed")))
+		_, err = db.Conn.Exec(`delete from invoices`)
+		Expect(err).To(MatchError(ContainSubstring("can not be changed")))
+	})
+})
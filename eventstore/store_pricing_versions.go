@@ -0,0 +1,758 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore/formula"
+	"github.com/lib/pq"
+)
+
+// ConfigImportAuthor is recorded as the creator of versions imported from
+// config.json
+const ConfigImportAuthor = "config.json"
+
+var _ eventio.PricingVersionWriter = &EventStore{}
+var _ eventio.PricingVersionReader = &EventStore{}
+
+// checkConfigVersions compares the config that has just been loaded into
+// the pricing tables with the latest version of each plan, VAT rate, currency
+// rate and org tax treatment that already has versions. An entry that has been
+// edited in the config since it was imported would be silently ignored by
+// importPricingVersions, so an error naming every such entry is returned. An
+// entry whose latest version was added through the admin API is only logged,
+// as the config has not caught up with the change.
+func (s *EventStore) checkConfigVersions(tx *sql.Tx) error {
+	edited := []string{}
+	for _, q := range []struct {
+		name  string
+		query string
+	}{
+		{"vat-rates", `
+			select
+				format('VAT rate %s from %s', vr.code, to_char(vr.valid_from at time zone 'UTC', 'YYYY-MM-DD')),
+				v.version, v.created_by
+			from
+				vat_rates vr
+			join lateral (
+				select * from vat_rate_versions v
+				where v.code = vr.code and v.valid_from = vr.valid_from
+				order by v.version desc
+				limit 1
+			) v on true
+			where v.rate <> vr.rate
+			order by vr.code, vr.valid_from
+		`},
+		{"currency-rates", `
+			select
+				format('currency rate %s from %s', cr.code, to_char(cr.valid_from at time zone 'UTC', 'YYYY-MM-DD')),
+				v.version, v.created_by
+			from
+				currency_rates cr
+			join lateral (
+				select * from currency_rate_versions v
+				where v.code = cr.code and v.valid_from = cr.valid_from
+				order by v.version desc
+				limit 1
+			) v on true
+			where v.rate <> cr.rate
+			order by cr.code, cr.valid_from
+		`},
+		{"org-tax-treatments", `
+			select
+				format('org tax treatment %s from %s', ott.org_guid, to_char(ott.valid_from at time zone 'UTC', 'YYYY-MM-DD')),
+				v.version, v.created_by
+			from
+				org_tax_treatments ott
+			join lateral (
+				select * from org_tax_treatment_versions v
+				where v.org_guid = ott.org_guid and v.valid_from = ott.valid_from
+				order by v.version desc
+				limit 1
+			) v on true
+			where v.vat_code is distinct from ott.vat_code
+			order by ott.org_guid, ott.valid_from
+		`},
+		{"pricing-plans", `
+			select
+				format('pricing plan %s (%s) from %s', pp.name, pp.plan_guid, to_char(pp.valid_from at time zone 'UTC', 'YYYY-MM-DD')),
+				v.version, v.created_by
+			from
+				pricing_plans pp
+			join lateral (
+				select * from pricing_plan_versions v
+				where v.plan_guid = pp.plan_guid and v.valid_from = pp.valid_from
+				order by v.version desc
+				limit 1
+			) v on true
+			where
+				(v.name, v.memory_in_mb, v.number_of_nodes, v.storage_in_mb, v.disk_in_mb)
+				<> (pp.name, pp.memory_in_mb, pp.number_of_nodes, pp.storage_in_mb, pp.disk_in_mb)
+				or array(
+					select row(ppc.name, ppc.formula, ppc.vat_code, ppc.currency_code)::text
+					from pricing_plan_components ppc
+					where ppc.plan_guid = pp.plan_guid and ppc.valid_from = pp.valid_from
+					order by ppc.name
+				) <> array(
+					select row(vc.name, vc.formula, vc.vat_code, vc.currency_code)::text
+					from pricing_plan_component_versions vc
+					where vc.version = v.version
+					order by vc.name
+				)
+			order by pp.plan_guid, pp.valid_from
+		`},
+	} {
+		rows, err := tx.Query(q.query)
+		if err != nil {
+			return wrapPqError(err, "check-"+q.name)
+		}
+		for rows.Next() {
+			var entry, createdBy string
+			var version int64
+			if err := rows.Scan(&entry, &version, &createdBy); err != nil {
+				rows.Close()
+				return err
+			}
+			if createdBy == ConfigImportAuthor {
+				edited = append(edited, fmt.Sprintf("%s (version %d)", entry, version))
+				continue
+			}
+			s.logger.Error("check-"+q.name, errors.New("config differs from the latest version"), lager.Data{
+				"entry":      entry,
+				"version":    version,
+				"created_by": createdBy,
+				"hint":       "update the config to match the version added through the admin API",
+			})
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	if len(edited) > 0 {
+		return fmt.Errorf(
+			"%d config entries differ from the versions imported before and would be ignored, add a version through the admin API instead:\n    %s",
+			len(edited), strings.Join(edited, "\n    "),
+		)
+	}
+	return nil
+}
+
+// importPricingVersions records the config that has just been loaded into the
+// pricing tables as the first version of each plan, VAT rate, currency rate
+// and org tax treatment that does not have any versions yet. Anything that already has a
+// version is left alone, after that it can only be changed through the admin
+// API.
+func (s *EventStore) importPricingVersions(tx *sql.Tx) error {
+	for _, q := range []struct {
+		name  string
+		query string
+	}{
+		{"vat-rates", `
+			insert into vat_rate_versions (
+				code, valid_from, rate, created_by
+			) (
+				select code, valid_from, rate, $1
+				from vat_rates vr
+				where not exists (
+					select 1 from vat_rate_versions v
+					where v.code = vr.code and v.valid_from = vr.valid_from
+				)
+				order by code, valid_from
+			)
+		`},
+		{"currency-rates", `
+			insert into currency_rate_versions (
+				code, valid_from, rate, created_by
+			) (
+				select code, valid_from, rate, $1
+				from currency_rates cr
+				where not exists (
+					select 1 from currency_rate_versions v
+					where v.code = cr.code and v.valid_from = cr.valid_from
+				)
+				order by code, valid_from
+			)
+		`},
+		{"org-tax-treatments", `
+			insert into org_tax_treatment_versions (
+				org_guid, valid_from, vat_code, created_by
+			) (
+				select org_guid, valid_from, vat_code, $1
+				from org_tax_treatments ott
+				where not exists (
+					select 1 from org_tax_treatment_versions v
+					where v.org_guid = ott.org_guid and v.valid_from = ott.valid_from
+				)
+				order by org_guid, valid_from
+			)
+		`},
+		{"pricing-plans", `
+			with imported as (
+				insert into pricing_plan_versions (
+					plan_guid, valid_from, name,
+					memory_in_mb, number_of_nodes, storage_in_mb, disk_in_mb,
+					created_by
+				) (
+					select
+						plan_guid, valid_from, name,
+						memory_in_mb, number_of_nodes, storage_in_mb, disk_in_mb,
+						$1
+					from pricing_plans pp
+					where not exists (
+						select 1 from pricing_plan_versions v
+						where v.plan_guid = pp.plan_guid and v.valid_from = pp.valid_from
+					)
+					order by plan_guid, valid_from
+				)
+				returning version, plan_guid, valid_from
+			)
+			insert into pricing_plan_component_versions (
+				version, name, formula, compiled_formula, vat_code, currency_code
+			) (
+				select
+					i.version, ppc.name, ppc.formula, ppc.compiled_formula, ppc.vat_code, ppc.currency_code
+				from
+					imported i
+				join
+					pricing_plan_components ppc on ppc.plan_guid = i.plan_guid
+					and ppc.valid_from = i.valid_from
+			)
+		`},
+	} {
+		res, err := tx.Exec(q.query, ConfigImportAuthor)
+		if err != nil {
+			return wrapPqError(err, "import-"+q.name)
+		}
+		if n, err := res.RowsAffected(); err == nil && n > 0 {
+			s.logger.Info("import-"+q.name, lager.Data{
+				"imported": n,
+			})
+		}
+	}
+	return nil
+}
+
+// loadPricingVersions replaces the contents of the pricing tables with the
+// latest version of each plan, VAT rate, currency rate and org tax treatment,
+// then checks that the result is consistent
+func loadPricingVersions(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+		delete from pricing_plan_components;
+		delete from pricing_plans;
+		delete from vat_rates;
+		delete from currency_rates;
+		delete from org_tax_treatments;
+	`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		insert into vat_rates (
+			code, valid_from, rate
+		) (
+			select distinct on (code, valid_from)
+				code, valid_from, rate
+			from vat_rate_versions
+			order by code, valid_from, version desc
+		)
+	`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		insert into currency_rates (
+			code, valid_from, rate
+		) (
+			select distinct on (code, valid_from)
+				code, valid_from, rate
+			from currency_rate_versions
+			order by code, valid_from, version desc
+		)
+	`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		insert into org_tax_treatments (
+			org_guid, valid_from, vat_code
+		) (
+			select distinct on (org_guid, valid_from)
+				org_guid, valid_from, vat_code
+			from org_tax_treatment_versions
+			order by org_guid, valid_from, version desc
+		)
+	`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		create temporary table latest_pricing_plan_versions on commit drop as (
+			select distinct on (plan_guid, valid_from)
+				*
+			from pricing_plan_versions
+			order by plan_guid, valid_from, version desc
+		);
+		insert into pricing_plans (
+			plan_guid, valid_from, name,
+			memory_in_mb, number_of_nodes, storage_in_mb, disk_in_mb
+		) (
+			select
+				plan_guid, valid_from, name,
+				memory_in_mb, number_of_nodes, storage_in_mb, disk_in_mb
+			from latest_pricing_plan_versions
+		);
+		insert into pricing_plan_components (
+			plan_guid, valid_from, name,
+			formula, compiled_formula, vat_code, currency_code
+		) (
+			select
+				pp.plan_guid, pp.valid_from, ppc.name,
+				ppc.formula, ppc.compiled_formula, ppc.vat_code, ppc.currency_code
+			from
+				latest_pricing_plan_versions pp
+			join
+				pricing_plan_component_versions ppc on ppc.version = pp.version
+		);
+		drop table latest_pricing_plan_versions;
+	`); err != nil {
+		return err
+	}
+
+	if err := checkPricingComponents(tx); err != nil {
+		return err
+	}
+	if err := checkVATRates(tx); err != nil {
+		return err
+	}
+	if err := checkCurrencyRates(tx); err != nil {
+		return err
+	}
+	return checkOrgTaxTreatments(tx)
+}
+
+// addPricingVersion runs insert, which should add a new version, and reloads
+// the pricing tables from the versions. Everything is rolled back if the new
+// version makes the pricing data invalid or leaves any existing event without
+// a plan. As the price of past events may have changed the recorded
+// watermarks are cleared so that the next Refresh reprices everything.
+func (s *EventStore) addPricingVersion(name string, insert func(tx *sql.Tx) (int64, error)) (int64, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultStoreTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// only one version can be added at a time, so that each is checked
+	// against all of those before it
+	if _, err := tx.Exec(`lock table pricing_plan_versions, vat_rate_versions, currency_rate_versions, org_tax_treatment_versions in exclusive mode`); err != nil {
+		return 0, err
+	}
+
+	version, err := insert(tx)
+	if err != nil {
+		return 0, rejectVersion(err, "invalid "+name)
+	}
+	if err := loadPricingVersions(tx); err != nil {
+		return 0, rejectVersion(err, "invalid "+name)
+	}
+	if s.cfg.IgnoreMissingPlans {
+		if err := s.generateMissingPlans(tx); err != nil {
+			return 0, err
+		}
+	}
+	if err := checkPlanConsistency(tx); err != nil {
+		return 0, rejectVersion(err, "invalid "+name)
+	}
+	if _, err := tx.Exec(`delete from event_watermarks`); err != nil {
+		return 0, wrapPqError(err, "clear-watermarks")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	s.logger.Info("add-"+name+"-version", lager.Data{
+		"version": version,
+	})
+	return version, nil
+}
+
+// rejectVersion turns an error caused by the content of a new version into a
+// VersionRejectedError. Other database errors, such as a lost connection, are
+// returned as they are.
+func rejectVersion(err error, prefix string) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code.Class() {
+		case "22", "23", "P0":
+		default:
+			return err
+		}
+	}
+	return &eventio.VersionRejectedError{Reason: wrapPqError(err, prefix).Error()}
+}
+
+// AddPricingPlanVersion records a new version of a pricing plan. If there is
+// already a plan with the same plan_guid and valid_from then this replaces
+// it, otherwise it is a new plan or a change to an existing plan from
+// valid_from onwards.
+func (s *EventStore) AddPricingPlanVersion(pp eventio.PricingPlan, createdBy string) (eventio.PricingPlanVersion, error) {
+	if len(pp.Components) == 0 {
+		return eventio.PricingPlanVersion{}, &eventio.VersionRejectedError{Reason: "invalid pricing plan: plan must have at least one component"}
+	}
+	compiled := make([]string, len(pp.Components))
+	for i, ppc := range pp.Components {
+		f, err := formula.Parse(ppc.Formula)
+		if err != nil {
+			return eventio.PricingPlanVersion{}, &eventio.VersionRejectedError{
+				Reason: fmt.Sprintf("invalid pricing plan component: formula for %s: %s", ppc.Name, err),
+			}
+		}
+		compiled[i] = f.SQL()
+		if err := eventio.ValidateVATCode(ppc.VATCode); err != nil {
+			return eventio.PricingPlanVersion{}, &eventio.VersionRejectedError{
+				Reason: fmt.Sprintf("invalid pricing plan component: %s: %s", ppc.Name, err),
+			}
+		}
+		if err := eventio.ValidateCurrencyCode(ppc.CurrencyCode); err != nil {
+			return eventio.PricingPlanVersion{}, &eventio.VersionRejectedError{
+				Reason: fmt.Sprintf("invalid pricing plan component: %s: %s", ppc.Name, err),
+			}
+		}
+	}
+
+	version, err := s.addPricingVersion("pricing-plan", func(tx *sql.Tx) (int64, error) {
+		var version int64
+		err := tx.QueryRow(`
+			insert into pricing_plan_versions (
+				plan_guid, valid_from, name,
+				memory_in_mb, storage_in_mb, number_of_nodes, disk_in_mb,
+				created_by
+			) values (
+				$1, $2, $3,
+				$4, $5, $6, $7,
+				$8
+			) returning version
+		`, pp.PlanGUID, pp.ValidFrom, pp.Name,
+			pp.MemoryInMB, pp.StorageInMB, pp.NumberOfNodes, pp.DiskInMB,
+			createdBy,
+		).Scan(&version)
+		if err != nil {
+			return 0, err
+		}
+		for i, ppc := range pp.Components {
+			if _, err := tx.Exec(`
+				insert into pricing_plan_component_versions (
+					version, name, formula, compiled_formula, vat_code, currency_code
+				) values (
+					$1, $2, $3, $4, $5, $6
+				)
+			`, version, ppc.Name, ppc.Formula, compiled[i], ppc.VATCode, ppc.CurrencyCode); err != nil {
+				return 0, err
+			}
+		}
+		return version, nil
+	})
+	if err != nil {
+		return eventio.PricingPlanVersion{}, err
+	}
+	versions, err := s.getPricingPlanVersions(`v.version = $1`, version)
+	if err != nil {
+		return eventio.PricingPlanVersion{}, err
+	}
+	if len(versions) != 1 {
+		return eventio.PricingPlanVersion{}, errors.New("pricing plan version was not recorded")
+	}
+	return versions[0], nil
+}
+
+// AddVATRateVersion records a VAT rate that applies from valid_from, or
+// replaces the rate if there is already one from that date
+func (s *EventStore) AddVATRateVersion(vr eventio.VATRate, createdBy string) (eventio.VATRateVersion, error) {
+	if err := eventio.ValidateVATCode(vr.Code); err != nil {
+		return eventio.VATRateVersion{}, &eventio.VersionRejectedError{Reason: "invalid vat rate: " + err.Error()}
+	}
+	version, err := s.addPricingVersion("vat-rate", func(tx *sql.Tx) (int64, error) {
+		var version int64
+		err := tx.QueryRow(`
+			insert into vat_rate_versions (
+				code, valid_from, rate, created_by
+			) values (
+				$1, $2, $3, $4
+			) returning version
+		`, vr.Code, vr.ValidFrom, vr.Rate, createdBy).Scan(&version)
+		return version, err
+	})
+	if err != nil {
+		return eventio.VATRateVersion{}, err
+	}
+	versions, err := s.getVATRateVersions(`version = $1`, version)
+	if err != nil {
+		return eventio.VATRateVersion{}, err
+	}
+	if len(versions) != 1 {
+		return eventio.VATRateVersion{}, errors.New("vat rate version was not recorded")
+	}
+	return versions[0], nil
+}
+
+// AddCurrencyRateVersion records a currency rate that applies from
+// valid_from, or replaces the rate if there is already one from that date
+func (s *EventStore) AddCurrencyRateVersion(cr eventio.CurrencyRate, createdBy string) (eventio.CurrencyRateVersion, error) {
+	if err := eventio.ValidateCurrencyCode(cr.Code); err != nil {
+		return eventio.CurrencyRateVersion{}, &eventio.VersionRejectedError{Reason: "invalid currency rate: " + err.Error()}
+	}
+	version, err := s.addPricingVersion("currency-rate", func(tx *sql.Tx) (int64, error) {
+		var version int64
+		err := tx.QueryRow(`
+			insert into currency_rate_versions (
+				code, valid_from, rate, created_by
+			) values (
+				$1, $2, $3, $4
+			) returning version
+		`, cr.Code, cr.ValidFrom, cr.Rate, createdBy).Scan(&version)
+		return version, err
+	})
+	if err != nil {
+		return eventio.CurrencyRateVersion{}, err
+	}
+	versions, err := s.getCurrencyRateVersions(`version = $1`, version)
+	if err != nil {
+		return eventio.CurrencyRateVersion{}, err
+	}
+	if len(versions) != 1 {
+		return eventio.CurrencyRateVersion{}, errors.New("currency rate version was not recorded")
+	}
+	return versions[0], nil
+}
+
+// AddOrgTaxTreatmentVersion records how an org is taxed from valid_from, or
+// replaces the treatment if there is already one from that date. An empty
+// VATCode returns the org to being taxed using the VAT code of each
+// component.
+func (s *EventStore) AddOrgTaxTreatmentVersion(ot eventio.OrgTaxTreatment, createdBy string) (eventio.OrgTaxTreatmentVersion, error) {
+	if ot.VATCode != "" {
+		if err := eventio.ValidateVATCode(ot.VATCode); err != nil {
+			return eventio.OrgTaxTreatmentVersion{}, &eventio.VersionRejectedError{Reason: "invalid org tax treatment: " + err.Error()}
+		}
+	}
+	version, err := s.addPricingVersion("org-tax-treatment", func(tx *sql.Tx) (int64, error) {
+		var version int64
+		err := tx.QueryRow(`
+			insert into org_tax_treatment_versions (
+				org_guid, valid_from, vat_code, created_by
+			) values (
+				$1, $2, nullif($3, ''), $4
+			) returning version
+		`, ot.OrgGUID, ot.ValidFrom, ot.VATCode, createdBy).Scan(&version)
+		return version, err
+	})
+	if err != nil {
+		return eventio.OrgTaxTreatmentVersion{}, err
+	}
+	versions, err := s.getOrgTaxTreatmentVersions(`version = $1`, version)
+	if err != nil {
+		return eventio.OrgTaxTreatmentVersion{}, err
+	}
+	if len(versions) != 1 {
+		return eventio.OrgTaxTreatmentVersion{}, errors.New("org tax treatment version was not recorded")
+	}
+	return versions[0], nil
+}
+
+func (s *EventStore) GetPricingPlanVersions(planGUID string) ([]eventio.PricingPlanVersion, error) {
+	if planGUID == "" {
+		return s.getPricingPlanVersions(`true`)
+	}
+	return s.getPricingPlanVersions(`v.plan_guid::text = lower($1)`, planGUID)
+}
+
+func (s *EventStore) GetVATRateVersions(code string) ([]eventio.VATRateVersion, error) {
+	if code == "" {
+		return s.getVATRateVersions(`true`)
+	}
+	return s.getVATRateVersions(`code::text = $1`, code)
+}
+
+func (s *EventStore) GetCurrencyRateVersions(code string) ([]eventio.CurrencyRateVersion, error) {
+	if code == "" {
+		return s.getCurrencyRateVersions(`true`)
+	}
+	return s.getCurrencyRateVersions(`code::text = $1`, code)
+}
+
+func (s *EventStore) GetOrgTaxTreatmentVersions(orgGUID string) ([]eventio.OrgTaxTreatmentVersion, error) {
+	if orgGUID == "" {
+		return s.getOrgTaxTreatmentVersions(`true`)
+	}
+	return s.getOrgTaxTreatmentVersions(`org_guid::text = lower($1)`, orgGUID)
+}
+
+func (s *EventStore) getPricingPlanVersions(where string, args ...interface{}) ([]eventio.PricingPlanVersion, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	rows, err := queryJSON(tx, `
+		select
+			v.version,
+			v.plan_guid,
+			v.valid_from,
+			v.name,
+			v.memory_in_mb,
+			v.number_of_nodes,
+			v.storage_in_mb,
+			v.disk_in_mb,
+			v.created_at,
+			v.created_by,
+			json_agg(json_build_object(
+				'name', ppc.name,
+				'formula', ppc.formula,
+				'vat_code', ppc.vat_code,
+				'currency_code', ppc.currency_code
+			) order by ppc.name) as components
+		from
+			pricing_plan_versions v
+		join
+			pricing_plan_component_versions ppc on ppc.version = v.version
+		where
+			`+where+`
+		group by
+			v.version
+		order by
+			v.version
+	`, args...)
+	if err != nil {
+		return nil, wrapPqError(err, "get-pricing-plan-versions")
+	}
+	defer rows.Close()
+	versions := []eventio.PricingPlanVersion{}
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var version eventio.PricingPlanVersion
+		if err := json.Unmarshal(b, &version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+func (s *EventStore) getVATRateVersions(where string, args ...interface{}) ([]eventio.VATRateVersion, error) {
+	versions := []eventio.VATRateVersion{}
+	err := s.queryRateVersions("vat_rate_versions", where, args, func(b []byte) error {
+		var version eventio.VATRateVersion
+		if err := json.Unmarshal(b, &version); err != nil {
+			return err
+		}
+		versions = append(versions, version)
+		return nil
+	})
+	return versions, err
+}
+
+func (s *EventStore) getCurrencyRateVersions(where string, args ...interface{}) ([]eventio.CurrencyRateVersion, error) {
+	versions := []eventio.CurrencyRateVersion{}
+	err := s.queryRateVersions("currency_rate_versions", where, args, func(b []byte) error {
+		var version eventio.CurrencyRateVersion
+		if err := json.Unmarshal(b, &version); err != nil {
+			return err
+		}
+		versions = append(versions, version)
+		return nil
+	})
+	return versions, err
+}
+
+func (s *EventStore) getOrgTaxTreatmentVersions(where string, args ...interface{}) ([]eventio.OrgTaxTreatmentVersion, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	rows, err := queryJSON(tx, `
+		select
+			version,
+			org_guid,
+			valid_from,
+			coalesce(vat_code, '') as vat_code,
+			created_at,
+			created_by
+		from
+			org_tax_treatment_versions
+		where
+			`+where+`
+		order by
+			version
+	`, args...)
+	if err != nil {
+		return nil, wrapPqError(err, "get-org-tax-treatment-versions")
+	}
+	defer rows.Close()
+	versions := []eventio.OrgTaxTreatmentVersion{}
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var version eventio.OrgTaxTreatmentVersion
+		if err := json.Unmarshal(b, &version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// queryRateVersions calls fn with each matching row of one of the rate
+// version tables as json, oldest first
+func (s *EventStore) queryRateVersions(table string, where string, args []interface{}, fn func([]byte) error) error {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	rows, err := queryJSON(tx, `
+		select
+			version,
+			code,
+			valid_from,
+			rate,
+			created_at,
+			created_by
+		from
+			`+table+`
+		where
+			`+where+`
+		order by
+			version
+	`, args...)
+	if err != nil {
+		return wrapPqError(err, "get-"+table)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return err
+		}
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,240 @@
+package eventstore_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PricingVersions", func() {
+
+	var (
+		db  *testenv.TempDB
+		cfg eventstore.Config
+	)
+
+	var computePlan = func(validFrom string, formula string) eventio.PricingPlan {
+		return eventio.PricingPlan{
+			PlanGUID:  eventstore.ComputePlanGUID,
+			ValidFrom: validFrom,
+			Name:      "PLAN1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "compute",
+					Formula:      formula,
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		cfg.AddPlan(computePlan("2001-01-01", "$time_in_seconds * 0.01"))
+
+		var err error
+		db, err = testenv.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("should record the config as the first version of each plan and rate", func() {
+		Expect(
+			db.Query(`select version, plan_guid, name, created_by from pricing_plan_versions`),
+		).To(MatchJSON(testenv.Rows{
+			{"version": 1, "plan_guid": eventstore.ComputePlanGUID, "name": "PLAN1", "created_by": "config.json"},
+		}))
+		Expect(
+			db.Query(`select code, rate, created_by from vat_rate_versions`),
+		).To(MatchJSON(testenv.Rows{
+			{"code": "Standard", "rate": 0.2, "created_by": "config.json"},
+		}))
+		Expect(
+			db.Query(`select code, rate, created_by from currency_rate_versions`),
+		).To(MatchJSON(testenv.Rows{
+			{"code": "GBP", "rate": 1, "created_by": "config.json"},
+		}))
+	})
+
+	It("should not replace existing versions with the config when initialised again", func() {
+		_, err := db.Schema.AddPricingPlanVersion(computePlan("2001-01-01", "$time_in_seconds * 0.02"), "jeff")
+		Expect(err).ToNot(HaveOccurred())
+
+		cfg.AddPlan(computePlan("2002-01-01", "$time_in_seconds * 0.03"))
+		store := eventstore.New(context.Background(), db.Conn, lager.NewLogger("test"), cfg)
+		Expect(store.Init()).To(Succeed())
+
+		Expect(
+			db.Query(`select valid_from, formula from pricing_plan_components order by valid_from`),
+		).To(MatchJSON(testenv.Rows{
+			{"valid_from": "2001-01-01T00:00:00+00:00", "formula": "$time_in_seconds * 0.02"},
+			{"valid_from": "2002-01-01T00:00:00+00:00", "formula": "$time_in_seconds * 0.03"},
+		}))
+		Expect(
+			db.Query(`select version, created_by from pricing_plan_versions order by version`),
+		).To(MatchJSON(testenv.Rows{
+			{"version": 1, "created_by": "config.json"},
+			{"version": 2, "created_by": "jeff"},
+			{"version": 3, "created_by": "config.json"},
+		}))
+	})
+
+	It("should fail to initialise if a plan has been edited in the config since it was imported", func() {
+		cfg.PricingPlans = []eventio.PricingPlan{
+			computePlan("2001-01-01", "$time_in_seconds * 0.02"),
+		}
+		store := eventstore.New(context.Background(), db.Conn, lager.NewLogger("test"), cfg)
+		err := store.Init()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("pricing plan PLAN1 (" + eventstore.ComputePlanGUID + ") from 2001-01-01 (version 1)"))
+
+		Expect(
+			db.Query(`select formula from pricing_plan_components`),
+		).To(MatchJSON(testenv.Rows{
+			{"formula": "$time_in_seconds * 0.01"},
+		}))
+	})
+
+	It("should fail to initialise if a rate has been edited in the config since it was imported", func() {
+		cfg.VATRates = []eventio.VATRate{
+			{Code: "Standard", Rate: eventio.MustParseDecimalNumber("0.25"), ValidFrom: "epoch"},
+		}
+		store := eventstore.New(context.Background(), db.Conn, lager.NewLogger("test"), cfg)
+		err := store.Init()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("VAT rate Standard from"))
+	})
+
+	It("should replace a plan with a new version and keep the history", func() {
+		version, err := db.Schema.AddPricingPlanVersion(computePlan("2001-01-01", "$time_in_seconds * 0.02"), "jeff")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version.Version).To(Equal(int64(2)))
+		Expect(version.CreatedBy).To(Equal("jeff"))
+		Expect(version.Components[0].Formula).To(Equal("$time_in_seconds * 0.02"))
+
+		plans, err := db.Schema.GetPricingPlans(eventio.TimeRangeFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plans).To(HaveLen(1))
+		Expect(plans[0].Components[0].Formula).To(Equal("$time_in_seconds * 0.02"))
+
+		versions, err := db.Schema.GetPricingPlanVersions(eventstore.ComputePlanGUID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions).To(HaveLen(2))
+		Expect(versions[0].CreatedBy).To(Equal("config.json"))
+		Expect(versions[0].Components[0].Formula).To(Equal("$time_in_seconds * 0.01"))
+		Expect(versions[1].CreatedBy).To(Equal("jeff"))
+	})
+
+	It("should add a currency rate from a given month", func() {
+		version, err := db.Schema.AddCurrencyRateVersion(eventio.CurrencyRate{
+			Code:      "GBP",
+			ValidFrom: "2001-03-01",
+			Rate:      eventio.MustParseDecimalNumber("0.9"),
+		}, "jeff")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version.ValidFrom).To(Equal("2001-03-01T00:00:00+00:00"))
+
+		Expect(
+			db.Query(`select valid_from, rate from currency_rates order by valid_from`),
+		).To(MatchJSON(testenv.Rows{
+			{"valid_from": "1970-01-01T00:00:00+00:00", "rate": 1},
+			{"valid_from": "2001-03-01T00:00:00+00:00", "rate": 0.9},
+		}))
+
+		versions, err := db.Schema.GetCurrencyRateVersions("GBP")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions).To(HaveLen(2))
+		Expect(versions[1].CreatedBy).To(Equal("jeff"))
+	})
+
+	It("should clear the watermarks so that the next refresh reprices every event", func() {
+		Expect(db.Get(`select count(*) from event_watermarks`)).ToNot(BeEquivalentTo(0))
+
+		_, err := db.Schema.AddVATRateVersion(eventio.VATRate{
+			Code:      "Standard",
+			ValidFrom: "2001-01-01",
+			Rate:      eventio.MustParseDecimalNumber("0.25"),
+		}, "jeff")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(db.Get(`select count(*) from event_watermarks`)).To(BeEquivalentTo(0))
+	})
+
+	It("should reject a rate that does not start at the beginning of a month", func() {
+		_, err := db.Schema.AddVATRateVersion(eventio.VATRate{
+			Code:      "Standard",
+			ValidFrom: "2001-01-02",
+			Rate:      eventio.MustParseDecimalNumber("0.25"),
+		}, "jeff")
+		Expect(err).To(BeAssignableToTypeOf(&eventio.VersionRejectedError{}))
+		Expect(err).To(MatchError(ContainSubstring(`violates check constraint "valid_from_start_of_month"`)))
+
+		Expect(db.Get(`select count(*) from vat_rate_versions`)).To(BeEquivalentTo(1))
+	})
+
+	It("should reject a plan that uses a VAT code without a rate", func() {
+		plan := computePlan("2001-02-01", "$time_in_seconds * 0.02")
+		plan.Components[0].VATCode = "Zero"
+		_, err := db.Schema.AddPricingPlanVersion(plan, "jeff")
+		Expect(err).To(BeAssignableToTypeOf(&eventio.VersionRejectedError{}))
+		Expect(err).To(MatchError(ContainSubstring(`missing vat_rate for 'Zero'`)))
+
+		Expect(db.Get(`select count(*) from pricing_plan_versions`)).To(BeEquivalentTo(1))
+	})
+
+	It("should add an org tax treatment and reject one using a VAT code without a rate", func() {
+		_, err := db.Schema.AddOrgTaxTreatmentVersion(eventio.OrgTaxTreatment{
+			OrgGUID:   "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+			ValidFrom: "2001-02-01",
+			VATCode:   "Exempt",
+		}, "jeff")
+		Expect(err).To(BeAssignableToTypeOf(&eventio.VersionRejectedError{}))
+		Expect(err).To(MatchError(ContainSubstring(`missing vat_rate for 'Exempt'`)))
+
+		_, err = db.Schema.AddVATRateVersion(eventio.VATRate{
+			Code:      "Exempt",
+			ValidFrom: "2001-01-01",
+			Rate:      eventio.MustParseDecimalNumber("0"),
+		}, "jeff")
+		Expect(err).ToNot(HaveOccurred())
+
+		version, err := db.Schema.AddOrgTaxTreatmentVersion(eventio.OrgTaxTreatment{
+			OrgGUID:   "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+			ValidFrom: "2001-02-01",
+			VATCode:   "Exempt",
+		}, "jeff")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version.VATCode).To(Equal("Exempt"))
+
+		Expect(
+			db.Query(`select org_guid, valid_from, vat_code from org_tax_treatments`),
+		).To(MatchJSON(testenv.Rows{
+			{"org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "valid_from": "2001-02-01T00:00:00+00:00", "vat_code": "Exempt"},
+		}))
+	})
+
+	It("should reject a plan with an invalid formula", func() {
+		_, err := db.Schema.AddPricingPlanVersion(computePlan("2001-02-01", "1 +"), "jeff")
+		Expect(err).To(BeAssignableToTypeOf(&eventio.VersionRejectedError{}))
+	})
+
+	It("should not allow versions to be changed", func() {
+		_, err := db.Conn.Exec(`update vat_rate_versions set rate = 0.5`)
+		Expect(err).To(MatchError(ContainSubstring(`vat_rate_versions is append only`)))
+		_, err = db.Conn.Exec(`delete from pricing_plan_versions`)
+		Expect(err).To(MatchError(ContainSubstring(`pricing_plan_versions is append only`)))
+	})
+})
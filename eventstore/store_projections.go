@@ -0,0 +1,355 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/lib/pq"
+)
+
+var _ eventio.ProjectionReader = &EventStore{}
+
+// GetMonthEndProjections projects what each space of the orgs in the filter
+// will be billed for the billing period of its org that contains the last
+// refresh. A resource is running if its event was still open ended when the
+// watermarks of the last refresh were taken, as in selectRefreshResources.
+// Running resources are priced until the end of their org's period with the
+// same components as every other event. Nothing is written, the projected
+// events are added to temporary tables that are discarded afterwards.
+func (s *EventStore) GetMonthEndProjections(filter eventio.ProjectionFilter) ([]eventio.Projection, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var asOf time.Time
+	if err := tx.QueryRow(`select coalesce(min(processed_at), now()) from event_watermarks`).Scan(&asOf); err != nil {
+		return nil, err
+	}
+	asOf = asOf.UTC()
+	periods, err := s.projectionPeriods(filter, asOf)
+	if err != nil {
+		return nil, err
+	}
+	for _, period := range periods {
+		if err := checkCurrencyRateFrom(tx, filter.Currency, period.filter.RangeStart); err != nil {
+			return nil, err
+		}
+	}
+
+	groupBy := []eventio.SummaryGroup{eventio.GroupByOrg, eventio.GroupBySpace}
+	summarise := func() ([][]eventio.BillableSummary, error) {
+		summaries := make([][]eventio.BillableSummary, len(periods))
+		for i, period := range periods {
+			var err error
+			summaries[i], err = s.getBillableSummary(tx, groupBy, period.filter, false)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return summaries, nil
+	}
+	periodToDate, err := summarise()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.projectRunningEvents(tx, filter.OrgGUIDs, periods, asOf); err != nil {
+		return nil, err
+	}
+	projected, err := summarise()
+	if err != nil {
+		return nil, err
+	}
+
+	currencyCode := filter.Currency
+	if currencyCode == "" {
+		currencyCode = eventio.BaseCurrency
+	}
+	projections := []eventio.Projection{}
+	for i, period := range periods {
+		actuals := map[string]eventio.BillableSummary{}
+		for _, summary := range periodToDate[i] {
+			actuals[summary.OrgGUID+"/"+summary.SpaceGUID] = summary
+		}
+		for _, summary := range projected[i] {
+			if period.excludes(summary.OrgGUID) {
+				continue
+			}
+			actual := actuals[summary.OrgGUID+"/"+summary.SpaceGUID]
+			projections = append(projections, eventio.Projection{
+				OrgGUID:           summary.OrgGUID,
+				OrgName:           summary.OrgName,
+				SpaceGUID:         summary.SpaceGUID,
+				SpaceName:         summary.SpaceName,
+				PeriodStart:       period.start,
+				PeriodStop:        period.stop,
+				AsOf:              asOf.Format(time.RFC3339Nano),
+				CurrencyCode:      currencyCode,
+				MonthToDateExVAT:  actual.ExVAT,
+				MonthToDateIncVAT: actual.IncVAT,
+				ProjectedExVAT:    summary.ExVAT,
+				ProjectedIncVAT:   summary.IncVAT,
+			})
+		}
+	}
+	sort.SliceStable(projections, func(i, j int) bool {
+		a, b := projections[i], projections[j]
+		if a.OrgName != b.OrgName {
+			return a.OrgName < b.OrgName
+		}
+		if a.OrgGUID != b.OrgGUID {
+			return a.OrgGUID < b.OrgGUID
+		}
+		if a.SpaceName != b.SpaceName {
+			return a.SpaceName < b.SpaceName
+		}
+		return a.SpaceGUID < b.SpaceGUID
+	})
+	return projections, nil
+}
+
+// projectionPeriod is the billing period containing the last refresh that
+// some of the orgs being projected share. A filter without OrgGUIDs is for
+// every org apart from excludeOrgGUIDs, which have periods of their own.
+// start and stop are the dates that the period starts and stops on in the
+// time zone of its orgs.
+type projectionPeriod struct {
+	filter          eventio.EventFilter
+	excludeOrgGUIDs []string
+	start           string
+	stop            string
+}
+
+func (p projectionPeriod) excludes(orgGUID string) bool {
+	for _, excluded := range p.excludeOrgGUIDs {
+		if strings.EqualFold(excluded, orgGUID) {
+			return true
+		}
+	}
+	return false
+}
+
+// projectionPeriods groups the orgs of the filter by their billing period
+// containing asOf, as EvaluateBudgets does, so that each group can be
+// summarised at once. Without any orgs in the filter, every org that does
+// not have a billing period of its own is projected for the default period.
+func (s *EventStore) projectionPeriods(filter eventio.ProjectionFilter, asOf time.Time) ([]projectionPeriod, error) {
+	periods := []projectionPeriod{}
+	orgGUIDs := filter.OrgGUIDs
+	if len(orgGUIDs) == 0 {
+		orgGUIDs = []string{}
+		for orgGUID := range s.orgBillingPeriods() {
+			orgGUIDs = append(orgGUIDs, orgGUID)
+		}
+		sort.Strings(orgGUIDs)
+		period, err := newProjectionPeriod(eventio.DefaultBillingPeriod, asOf)
+		if err != nil {
+			return nil, err
+		}
+		period.excludeOrgGUIDs = orgGUIDs
+		periods = append(periods, period)
+	}
+	seen := map[string]int{}
+	for _, orgGUID := range orgGUIDs {
+		billingPeriod, err := s.GetBillingPeriod([]string{orgGUID})
+		if err != nil {
+			return nil, err
+		}
+		period, err := newProjectionPeriod(billingPeriod, asOf)
+		if err != nil {
+			return nil, err
+		}
+		key := period.filter.RangeStart + "/" + period.filter.RangeStop
+		i, ok := seen[key]
+		if !ok {
+			periods = append(periods, period)
+			i = len(periods) - 1
+			seen[key] = i
+		}
+		periods[i].filter.OrgGUIDs = append(periods[i].filter.OrgGUIDs, orgGUID)
+	}
+	for i := range periods {
+		periods[i].filter.Currency = filter.Currency
+	}
+	return periods, nil
+}
+
+// newProjectionPeriod returns the period of a billing period containing
+// asOf, without any orgs
+func newProjectionPeriod(billingPeriod eventio.BillingPeriod, asOf time.Time) (projectionPeriod, error) {
+	filter, err := billingPeriod.PeriodContaining(asOf)
+	if err != nil {
+		return projectionPeriod{}, err
+	}
+	loc, err := time.LoadLocation(billingPeriod.TimeZone)
+	if err != nil {
+		return projectionPeriod{}, err
+	}
+	start, err := eventio.ParseRangeTime(filter.RangeStart)
+	if err != nil {
+		return projectionPeriod{}, err
+	}
+	stop, err := eventio.ParseRangeTime(filter.RangeStop)
+	if err != nil {
+		return projectionPeriod{}, err
+	}
+	return projectionPeriod{
+		filter: filter,
+		start:  start.In(loc).Format("2006-01-02"),
+		stop:   stop.In(loc).Format("2006-01-02"),
+	}, nil
+}
+
+// projectionTables are the tables that running resources are projected
+// into. While projecting they are shadowed by temporary copies of the rows
+// that are needed so that the real events are neither changed nor locked.
+var projectionTables = []string{
+	"events",
+	"billable_event_components",
+}
+
+// projectRunningEvents shadows the projectionTables with copies of the rows
+// of the periods and adds an event to them for each resource of the orgs of
+// each period that was running at asOf, from the end of its current event
+// until the end of the period, and generates its billable components. The
+// projected events are given guids derived from the events they follow on
+// from.
+func (s *EventStore) projectRunningEvents(tx *sql.Tx, orgGUIDs []string, periods []projectionPeriod, asOf time.Time) error {
+	var rangeStart, rangeStop time.Time
+	for i, period := range periods {
+		start, err := eventio.ParseRangeTime(period.filter.RangeStart)
+		if err != nil {
+			return err
+		}
+		stop, err := eventio.ParseRangeTime(period.filter.RangeStop)
+		if err != nil {
+			return err
+		}
+		if i == 0 || start.Before(rangeStart) {
+			rangeStart = start
+		}
+		if i == 0 || stop.After(rangeStop) {
+			rangeStop = stop
+		}
+	}
+	args := []interface{}{rangeStart, rangeStop}
+	orgQuery := ""
+	if len(orgGUIDs) > 0 {
+		args = append(args, pq.Array(orgGUIDs))
+		orgQuery = "and org_guid = any($3::uuid[])"
+	}
+
+	startTime := time.Now()
+	// the copies are made before the temporary table exists so that the
+	// select reads from the real table
+	for _, table := range projectionTables {
+		if _, err := tx.Exec(fmt.Sprintf(`
+			create temporary table %s on commit drop as
+			select * from %s
+			where duration && tstzrange($1::timestamptz, $2::timestamptz)
+			%s
+		`, table, table, orgQuery), args...); err != nil {
+			return wrapPqError(err, "create-projection-tables")
+		}
+	}
+
+	eventGUIDs := []string{}
+	resourceGUIDs := []string{}
+	for _, period := range periods {
+		stop, err := eventio.ParseRangeTime(period.filter.RangeStop)
+		if err != nil {
+			return err
+		}
+		periodOrgQuery, periodOrgGUIDs := "org_guid = any($3::uuid[])", period.filter.OrgGUIDs
+		if len(periodOrgGUIDs) == 0 {
+			periodOrgQuery, periodOrgGUIDs = "org_guid <> all($3::uuid[])", period.excludeOrgGUIDs
+		}
+		projectedEventGUIDs, projectedResourceGUIDs, err := projectPeriodRunningEvents(tx, asOf, stop, periodOrgQuery, periodOrgGUIDs)
+		if err != nil {
+			return err
+		}
+		eventGUIDs = append(eventGUIDs, projectedEventGUIDs...)
+		resourceGUIDs = append(resourceGUIDs, projectedResourceGUIDs...)
+	}
+
+	// generate_billable_event_components reads events unqualified, so it
+	// sees the projected events in the temporary table
+	if _, err := tx.Exec(`
+		insert into pg_temp.billable_event_components (
+			select * from generate_billable_event_components($1::uuid[])
+			where event_guid = any($2::uuid[])
+		)
+	`, pq.Array(resourceGUIDs), pq.Array(eventGUIDs)); err != nil {
+		return wrapPqError(err, "generate-projected-billable-event-components")
+	}
+	s.logger.Info("project-running-events", lager.Data{
+		"events":  len(eventGUIDs),
+		"periods": len(periods),
+		"as_of":   asOf,
+		"elapsed": int64(time.Since(startTime)),
+	})
+	return nil
+}
+
+// projectPeriodRunningEvents adds the projected events of the orgs matching
+// orgQuery that were running at asOf until stop, returning their guids and
+// the guids of their resources
+func projectPeriodRunningEvents(tx *sql.Tx, asOf time.Time, stop time.Time, orgQuery string, orgGUIDs []string) ([]string, []string, error) {
+	rows, err := tx.Query(fmt.Sprintf(`
+		insert into pg_temp.events (
+			event_guid,
+			resource_guid, resource_name, resource_type,
+			org_guid, org_name, space_guid, space_name,
+			duration,
+			plan_guid, plan_name,
+			service_guid, service_name,
+			number_of_nodes, memory_in_mb, storage_in_mb, disk_in_mb
+		)
+		select
+			md5(event_guid::text || ':projected')::uuid,
+			resource_guid, resource_name, resource_type,
+			org_guid, org_name, space_guid, space_name,
+			tstzrange(upper(duration), $2::timestamptz),
+			plan_guid, plan_name,
+			service_guid, service_name,
+			number_of_nodes, memory_in_mb, storage_in_mb, disk_in_mb
+		from
+			pg_temp.events
+		where
+			upper(duration) >= $1::timestamptz
+			and upper(duration) < $2::timestamptz
+			and %s
+		returning
+			event_guid, resource_guid
+	`, orgQuery), asOf, stop, pq.Array(orgGUIDs))
+	if err != nil {
+		return nil, nil, wrapPqError(err, "project-running-events")
+	}
+	defer rows.Close()
+	eventGUIDs := []string{}
+	resourceGUIDs := []string{}
+	for rows.Next() {
+		var eventGUID, resourceGUID string
+		if err := rows.Scan(&eventGUID, &resourceGUID); err != nil {
+			return nil, nil, err
+		}
+		eventGUIDs = append(eventGUIDs, eventGUID)
+		resourceGUIDs = append(resourceGUIDs, resourceGUID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return eventGUIDs, resourceGUIDs, nil
+}
@@ -0,0 +1,171 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetMonthEndProjections", func() {
+
+	var (
+		cfg eventstore.Config
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+	})
+
+	/*-----------------------------------------------------------------------------------*
+	     2001-01-01            start of this month       last refresh      end of month  .
+	         |                         |                       |                 |       .
+	 .   .   [=========================|=======APP1============]~~~~projected~~~~|   .   .
+	 .   .   [=====APP2=====]  .   .   |   .   .   .   .   .   .   .   .   .   . |   .   .
+	 .   .   .   .   .   .   .   .   . |______ month to date __|   .   .   .   . |   .   .
+	*-----------------------------------------------------------------------------------*/
+	It("should project the running resources to the end of the month", func() {
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  eventstore.ComputePlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "APP_PLAN_1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "compute",
+					Formula:      "$time_in_seconds * $number_of_nodes * 0.01",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+
+		db, err := testenv.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		store := db.Schema
+
+		orgGUID := "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		spaceGUID := "276f4886-ac40-492d-a8cd-b2646637ba76"
+		appEvent := func(guid string, appGUID string, state string, createdAt time.Time) eventio.RawEvent {
+			return eventio.RawEvent{
+				GUID:       guid,
+				Kind:       "app",
+				CreatedAt:  createdAt,
+				RawMessage: json.RawMessage(`{"state": "` + state + `", "app_guid": "` + appGUID + `", "app_name": "APP", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "ORG1-SPACE1", "process_type": "web", "instance_count": 2, "previous_state": "STOPPED", "memory_in_mb_per_instance": 1000}`),
+			}
+		}
+		Expect(store.StoreEvents([]eventio.RawEvent{
+			appEvent("ae28a572-f485-48e1-87d0-98b7b8b66dfa", "c85e98f0-6d1b-4f45-9368-ea58263165a0", "STARTED", time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)),
+			appEvent("ae28a573-f485-48e1-87d0-98b7b8b66dfa", "d85e98f0-6d1b-4f45-9368-ea58263165a0", "STARTED", time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)),
+			appEvent("ae28a574-f485-48e1-87d0-98b7b8b66dfa", "d85e98f0-6d1b-4f45-9368-ea58263165a0", "STOPPED", time.Date(2001, 1, 2, 0, 0, 0, 0, time.UTC)),
+		})).To(Succeed())
+		Expect(store.Refresh()).To(Succeed())
+		componentCount := db.Get(`select count(*) from billable_event_components`)
+
+		projections, err := store.GetMonthEndProjections(eventio.ProjectionFilter{
+			OrgGUIDs: []string{orgGUID},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(projections).To(HaveLen(1))
+
+		projection := projections[0]
+		asOf, err := time.Parse(time.RFC3339Nano, projection.AsOf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(asOf).To(BeTemporally("~", time.Now(), time.Minute))
+		monthStart := time.Date(asOf.Year(), asOf.Month(), 1, 0, 0, 0, 0, time.UTC)
+		secondsInMonth := int64(monthStart.AddDate(0, 1, 0).Sub(monthStart) / time.Second)
+
+		Expect(projection.OrgGUID).To(Equal(orgGUID))
+		Expect(projection.SpaceGUID).To(Equal(spaceGUID))
+		Expect(projection.PeriodStart).To(Equal(monthStart.Format("2006-01-02")))
+		Expect(projection.PeriodStop).To(Equal(monthStart.AddDate(0, 1, 0).Format("2006-01-02")))
+		Expect(projection.CurrencyCode).To(Equal("GBP"))
+		Expect(projection.MonthToDateExVAT.Sign()).To(Equal(1))
+		Expect(projection.MonthToDateExVAT.Cmp(projection.ProjectedExVAT)).To(Equal(-1))
+		expected := eventio.NewDecimalFromInt(secondsInMonth * 2).Mul(eventio.MustParseDecimal("0.01"))
+		Expect(projection.ProjectedExVAT.Equal(expected)).To(BeTrue(), "expected %s to be %s", projection.ProjectedExVAT, expected)
+
+		realEvents, err := store.GetUsageEvents(eventio.EventFilter{
+			RangeStart: monthStart.Format("2006-01-02"),
+			RangeStop:  monthStart.AddDate(0, 1, 0).Format("2006-01-02"),
+			OrgGUIDs:   []string{orgGUID},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(realEvents).To(HaveLen(1), "did not expect the projected events to be stored")
+		Expect(db.Get(`select count(*) from billable_event_components`)).To(Equal(componentCount))
+	})
+
+	It("should project each org to the end of its own billing period", func() {
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  eventstore.ComputePlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "APP_PLAN_1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "compute",
+					Formula:      "$time_in_seconds * $number_of_nodes * 0.01",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+		defaultOrgGUID := "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		quarterlyOrgGUID := "61ba75ef-edc0-47ad-a633-a8f6e8770944"
+		cfg.AddOrgBillingPeriod(eventio.BillingPeriod{OrgGUID: quarterlyOrgGUID, StartDay: 15, Months: 3})
+
+		db, err := testenv.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		store := db.Schema
+
+		appEvent := func(guid string, orgGUID string, appGUID string) eventio.RawEvent {
+			return eventio.RawEvent{
+				GUID:       guid,
+				Kind:       "app",
+				CreatedAt:  time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+				RawMessage: json.RawMessage(`{"state": "STARTED", "app_guid": "` + appGUID + `", "app_name": "APP", "org_guid": "` + orgGUID + `", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "SPACE", "process_type": "web", "instance_count": 1, "previous_state": "STOPPED", "memory_in_mb_per_instance": 1000}`),
+			}
+		}
+		Expect(store.StoreEvents([]eventio.RawEvent{
+			appEvent("ae28a572-f485-48e1-87d0-98b7b8b66dfa", defaultOrgGUID, "c85e98f0-6d1b-4f45-9368-ea58263165a0"),
+			appEvent("ae28a573-f485-48e1-87d0-98b7b8b66dfa", quarterlyOrgGUID, "d85e98f0-6d1b-4f45-9368-ea58263165a0"),
+		})).To(Succeed())
+		Expect(store.Refresh()).To(Succeed())
+
+		projections, err := store.GetMonthEndProjections(eventio.ProjectionFilter{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(projections).To(HaveLen(2))
+
+		for _, projection := range projections {
+			asOf, err := time.Parse(time.RFC3339Nano, projection.AsOf)
+			Expect(err).ToNot(HaveOccurred())
+			period := eventio.DefaultBillingPeriod
+			if projection.OrgGUID == quarterlyOrgGUID {
+				period = eventio.BillingPeriod{StartDay: 15, Months: 3}
+			}
+			filter, err := period.PeriodContaining(asOf)
+			Expect(err).ToNot(HaveOccurred())
+			periodStart, err := eventio.ParseRangeTime(filter.RangeStart)
+			Expect(err).ToNot(HaveOccurred())
+			periodStop, err := eventio.ParseRangeTime(filter.RangeStop)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(projection.PeriodStart).To(Equal(periodStart.Format("2006-01-02")), projection.OrgGUID)
+			Expect(projection.PeriodStop).To(Equal(periodStop.Format("2006-01-02")), projection.OrgGUID)
+			expected := eventio.NewDecimalFromInt(int64(periodStop.Sub(periodStart) / time.Second)).Mul(eventio.MustParseDecimal("0.01"))
+			Expect(projection.ProjectedExVAT.Equal(expected)).To(BeTrue(), "expected %s to be %s for %s", projection.ProjectedExVAT, expected, projection.OrgGUID)
+		}
+
+		projections, err = store.GetMonthEndProjections(eventio.ProjectionFilter{
+			OrgGUIDs: []string{quarterlyOrgGUID},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(projections).To(HaveLen(1))
+		Expect(projections[0].OrgGUID).To(Equal(quarterlyOrgGUID))
+		Expect(projections[0].PeriodStart).ToNot(HaveSuffix("-01"))
+	})
+})
@@ -0,0 +1,341 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// rawEventTables are the tables that the normalized events are derived from,
+// each has a serial id column which is used to track how far processing has got
+var rawEventTables = []string{
+	AppUsageTableName,
+	ServiceUsageTableName,
+	ComposeAuditTableName,
+}
+
+// watermarks record how much of the raw event data has been processed into
+// the events and billable_event_components tables.
+type watermarks struct {
+	// sequences is the highest processed id for each raw event table
+	sequences map[string]int64
+	// processedAt is the database time at which the sequences were captured,
+	// any event still running at that point ends at or after this time
+	processedAt time.Time
+}
+
+// Refresh brings the normalized view of the event data and the billable
+// components up to date with the raw events collected since the last
+// Refresh. Only resources with new raw events, or that were still running at
+// the time of the last Refresh, are re-derived. If there is no record of a
+// previous run then it falls back to a full Rebuild.
+func (s *EventStore) Refresh() error {
+	return s.refreshEvents()
+}
+
+// Rebuild triggers regeneration of the entire normalized view of the event
+// data from every raw event ever collected and rebuilds all the billable
+// components. This is slow and is kept for recovery, for example when the
+// historic org, space or plan names have been corrected.
+func (s *EventStore) Rebuild() error {
+	return s.regenerateEvents()
+}
+
+func (s *EventStore) regenerateEvents() error {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultRefreshTimeout)
+	defer cancel()
+
+	next, err := s.captureWatermarks(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.runSQLFilesInTransaction(
+		ctx,
+		"create_events.sql",
+	); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if s.cfg.IgnoreMissingPlans {
+		if err := s.generateMissingPlans(tx); err != nil {
+			return err
+		}
+	}
+
+	if err := checkPlanConsistency(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := s.runSQLFilesInTransaction(
+		ctx,
+		"create_billable_event_components.sql",
+	); err != nil {
+		return err
+	}
+
+	tx, err = s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := saveWatermarks(tx, next); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *EventStore) refreshEvents() error {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultRefreshTimeout)
+	defer cancel()
+
+	next, err := s.captureWatermarks(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	prev, ok, err := getWatermarks(tx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.logger.Info("refresh-without-watermarks", lager.Data{
+			"message": "no previous refresh recorded, falling back to a full rebuild",
+		})
+		tx.Rollback()
+		return s.regenerateEvents()
+	}
+
+	startTime := time.Now()
+	count, err := selectRefreshResources(tx, prev)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("refresh-events", lager.Data{
+		"resources":    count,
+		"processed_at": prev.processedAt,
+		"sequences":    prev.sequences,
+	})
+
+	if _, err := tx.Exec(`
+		delete from events
+		where resource_guid in (select resource_guid from refresh_resources)
+	`); err != nil {
+		return wrapPqError(err, "delete-refreshed-events")
+	}
+	if _, err := tx.Exec(`
+		insert into events (
+			select * from generate_events(array(select resource_guid from refresh_resources))
+		)
+	`); err != nil {
+		return wrapPqError(err, "generate-refreshed-events")
+	}
+
+	if s.cfg.IgnoreMissingPlans {
+		if err := s.generateMissingPlans(tx); err != nil {
+			return err
+		}
+	}
+
+	if err := checkPlanConsistency(tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`
+		delete from billable_event_components
+		where resource_guid in (select resource_guid from refresh_resources)
+	`); err != nil {
+		return wrapPqError(err, "delete-refreshed-billable-event-components")
+	}
+	if _, err := tx.Exec(`
+		insert into billable_event_components (
+			select * from generate_billable_event_components(array(select resource_guid from refresh_resources))
+		)
+	`); err != nil {
+		return wrapPqError(err, "generate-refreshed-billable-event-components")
+	}
+
+	if err := saveWatermarks(tx, next); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.logger.Info("refreshed-events", lager.Data{
+		"resources": count,
+		"elapsed":   int64(time.Since(startTime)),
+	})
+	return nil
+}
+
+// selectRefreshResources populates a temporary refresh_resources table with
+// the guids of every resource that needs its events re-deriving: those with
+// raw events newer than the given watermarks and those that were still
+// running (and so had an open ended duration) when the watermarks were taken.
+func selectRefreshResources(tx *sql.Tx, prev watermarks) (int64, error) {
+	_, err := tx.Exec(`
+		create temporary table refresh_resources on commit drop as
+		select distinct
+			guid::uuid as resource_guid
+		from (
+			(
+				select
+					unnest(array[
+						raw_message->>'app_guid',
+						raw_message->>'task_guid',
+						raw_message->>'parent_app_guid'
+					]) as guid
+				from
+					app_usage_events
+				where
+					id > $1
+			) union all (
+				select
+					raw_message->>'service_instance_guid' as guid
+				from
+					service_usage_events
+				where
+					id > $2
+			) union all (
+				select
+					substring(
+						raw_message->'data'->>'deployment'
+						from '[a-zA-Z0-9]{8}-[a-zA-Z0-9]{4}-[a-zA-Z0-9]{4}-[a-zA-Z0-9]{4}-[a-zA-Z0-9]{12}$'
+					) as guid
+				from
+					compose_audit_events
+				where
+					id > $3
+			) union all (
+				select
+					resource_guid::text as guid
+				from
+					events
+				where
+					upper(duration) >= $4
+			)
+		) as touched
+		where
+			guid is not null
+	`,
+		prev.sequences[AppUsageTableName],
+		prev.sequences[ServiceUsageTableName],
+		prev.sequences[ComposeAuditTableName],
+		prev.processedAt,
+	)
+	if err != nil {
+		return 0, wrapPqError(err, "select-refresh-resources")
+	}
+	var count int64
+	if err := tx.QueryRow(`select count(*) from refresh_resources`).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// captureWatermarks returns the highest id committed to each of the raw event
+// tables. A SHARE lock is briefly held on the tables so that any inserts
+// already in flight are committed before the ids are read, otherwise an event
+// could be committed later with an id below the watermark and never be
+// processed. The lock is released as soon as the ids have been read.
+func (s *EventStore) captureWatermarks(ctx context.Context) (watermarks, error) {
+	w := watermarks{
+		sequences: map[string]int64{},
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return w, err
+	}
+	defer tx.Rollback()
+	for _, table := range rawEventTables {
+		if _, err := tx.Exec(fmt.Sprintf(`lock table %s in share mode`, table)); err != nil {
+			return w, wrapPqError(err, "capture-watermarks")
+		}
+	}
+	if err := tx.QueryRow(`select now()`).Scan(&w.processedAt); err != nil {
+		return w, err
+	}
+	for _, table := range rawEventTables {
+		var seq int64
+		if err := tx.QueryRow(fmt.Sprintf(`select coalesce(max(id), 0) from %s`, table)).Scan(&seq); err != nil {
+			return w, wrapPqError(err, "capture-watermarks")
+		}
+		w.sequences[table] = seq
+	}
+	return w, tx.Commit()
+}
+
+// getWatermarks returns the watermarks recorded by the last successful
+// Refresh or Rebuild. ok is false if no watermarks have been recorded.
+func getWatermarks(tx *sql.Tx) (w watermarks, ok bool, err error) {
+	w = watermarks{
+		sequences: map[string]int64{},
+	}
+	rows, err := tx.Query(`select source, last_sequence, processed_at from event_watermarks`)
+	if err != nil {
+		return w, false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var source string
+		var seq int64
+		var processedAt time.Time
+		if err := rows.Scan(&source, &seq, &processedAt); err != nil {
+			return w, false, err
+		}
+		w.sequences[source] = seq
+		if !ok || processedAt.Before(w.processedAt) {
+			w.processedAt = processedAt
+		}
+		ok = true
+	}
+	if err := rows.Err(); err != nil {
+		return w, false, err
+	}
+	for _, table := range rawEventTables {
+		if _, exists := w.sequences[table]; !exists {
+			return w, false, nil
+		}
+	}
+	return w, ok, nil
+}
+
+func saveWatermarks(tx *sql.Tx, w watermarks) error {
+	for _, table := range rawEventTables {
+		_, err := tx.Exec(`
+			insert into event_watermarks (
+				source, last_sequence, processed_at
+			) values (
+				$1, $2, $3
+			) on conflict (source) do update set
+				last_sequence = excluded.last_sequence,
+				processed_at = excluded.processed_at
+		`, table, w.sequences[table], w.processedAt)
+		if err != nil {
+			return wrapPqError(err, "save-watermarks")
+		}
+	}
+	return nil
+}
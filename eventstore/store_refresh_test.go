@@ -0,0 +1,124 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Refresh", func() {
+
+	var (
+		cfg eventstore.Config
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  eventstore.ComputePlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "APP_PLAN_1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "compute",
+					Formula:      "ceil($time_in_seconds/3600) * 0.01",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+	})
+
+	appEvent := func(guid, createdAt, state, appGUID, appName string) testenv.Row {
+		return testenv.Row{
+			"guid":        guid,
+			"created_at":  createdAt,
+			"raw_message": json.RawMessage(`{"state": "` + state + `", "app_guid": "` + appGUID + `", "app_name": "` + appName + `", "org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "ORG1-SPACE1", "process_type": "web", "instance_count": 1, "previous_state": "STARTED", "memory_in_mb_per_instance": 1024}`),
+		}
+	}
+
+	It("should record a watermark for each raw event table", func() {
+		db, err := testenv.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+
+		Expect(db.Insert("app_usage_events",
+			appEvent("ee28a570-f485-48e1-87d0-98b7b8b66dfa", "2001-01-01T00:00Z", "STARTED", "c85e98f0-6d1b-4f45-9368-ea58263165a0", "APP1"),
+			appEvent("8d9036c5-8367-497d-bb56-94bfcac6621a", "2001-01-01T01:00Z", "STOPPED", "c85e98f0-6d1b-4f45-9368-ea58263165a0", "APP1"),
+		)).To(Succeed())
+
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		Expect(
+			db.Query(`select source, last_sequence from event_watermarks order by source`),
+		).To(MatchJSON(testenv.Rows{
+			{"source": "app_usage_events", "last_sequence": 2},
+			{"source": "compose_audit_events", "last_sequence": 0},
+			{"source": "service_usage_events", "last_sequence": 0},
+		}))
+	})
+
+	It("should only re-derive the events of resources with new raw events", func() {
+		db, err := testenv.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+
+		Expect(db.Insert("app_usage_events",
+			appEvent("ee28a570-f485-48e1-87d0-98b7b8b66dfa", "2001-01-01T00:00Z", "STARTED", "c85e98f0-6d1b-4f45-9368-ea58263165a0", "APP1"),
+			appEvent("8d9036c5-8367-497d-bb56-94bfcac6621a", "2001-01-01T01:00Z", "STOPPED", "c85e98f0-6d1b-4f45-9368-ea58263165a0", "APP1"),
+		)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+		Expect(db.Get(`select count(*) from events`)).To(BeNumerically("==", 1))
+
+		// tamper with the derived data so we can tell if it gets regenerated
+		_, err = db.Conn.Exec(`update events set resource_name = 'UNTOUCHED'`)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(db.Insert("app_usage_events",
+			appEvent("3b1f4b2a-4a8e-4e64-9b0a-7d2c5e1f0a11", "2001-01-02T00:00Z", "STARTED", "1e7c3e64-9a5e-4d39-8c2b-4d5f6a7b8c9d", "APP2"),
+			appEvent("5c2e6d3b-5b9f-4f75-8c1b-8e3d6f2a1b22", "2001-01-02T02:00Z", "STOPPED", "1e7c3e64-9a5e-4d39-8c2b-4d5f6a7b8c9d", "APP2"),
+		)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		Expect(
+			db.Query(`select resource_name, duration from events order by lower(duration)`),
+		).To(MatchJSON(testenv.Rows{
+			{"resource_name": "UNTOUCHED", "duration": "[\"2001-01-01 00:00:00+00\",\"2001-01-01 01:00:00+00\")"},
+			{"resource_name": "APP2", "duration": "[\"2001-01-02 00:00:00+00\",\"2001-01-02 02:00:00+00\")"},
+		}))
+		Expect(db.Get(`select count(*) from billable_event_components`)).To(BeNumerically("==", 2))
+
+		By("regenerating everything on Rebuild")
+		Expect(db.Schema.Rebuild()).To(Succeed())
+
+		Expect(
+			db.Query(`select resource_name from events order by lower(duration)`),
+		).To(MatchJSON(testenv.Rows{
+			{"resource_name": "APP1"},
+			{"resource_name": "APP2"},
+		}))
+	})
+
+	It("should re-derive resources that are still running", func() {
+		db, err := testenv.Open(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+
+		Expect(db.Insert("app_usage_events",
+			appEvent("ee28a570-f485-48e1-87d0-98b7b8b66dfa", "2001-01-01T00:00Z", "STARTED", "c85e98f0-6d1b-4f45-9368-ea58263165a0", "APP1"),
+		)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+		firstUpper := db.Get(`select upper(duration) from events`)
+
+		Expect(db.Schema.Refresh()).To(Succeed())
+		secondUpper := db.Get(`select upper(duration) from events`)
+
+		Expect(secondUpper).ToNot(Equal(firstUpper))
+		Expect(db.Get(`select count(*) from billable_event_components`)).To(BeNumerically(">=", 1))
+	})
+})
@@ -139,6 +139,7 @@ var _ = Describe("Store", func() {
 				"space_guid":      "bd405d91-0b7c-4b8c-96ef-8b4c1e26e75d",
 				"space_name":      "bd405d91-0b7c-4b8c-96ef-8b4c1e26e75d",
 				"storage_in_mb":   nil,
+				"disk_in_mb":      nil,
 			},
 			{
 				"duration":        "[\"2001-01-01 00:00:00+00\",\"2001-01-01 01:00:00+00\")",
@@ -157,6 +158,7 @@ var _ = Describe("Store", func() {
 				"space_guid":      "276f4886-ac40-492d-a8cd-b2646637ba76",
 				"space_name":      "276f4886-ac40-492d-a8cd-b2646637ba76",
 				"storage_in_mb":   0,
+				"disk_in_mb":      0,
 			},
 		}))
 	})
@@ -524,7 +526,7 @@ var _ = Describe("Store", func() {
 					{
 						ValidFrom: timestamp,
 						Code:      "Standard",
-						Rate:      0,
+						Rate:      eventio.MustParseDecimalNumber("0"),
 					},
 				},
 			})
@@ -547,7 +549,7 @@ var _ = Describe("Store", func() {
 					{
 						ValidFrom: timestamp,
 						Code:      "USD",
-						Rate:      0.8,
+						Rate:      eventio.MustParseDecimalNumber("0.8"),
 					},
 				},
 			})
@@ -563,14 +565,14 @@ var _ = Describe("Store", func() {
 		Entry("not midnight (different timezone)", "2017-04-01T00:00:00+01:00"),
 	)
 
-	DescribeTable("allow whitelisted currency codes",
+	DescribeTable("allow ISO 4217 currency codes",
 		func(code string) {
 			db, err := testenv.Open(eventstore.Config{
 				CurrencyRates: []eventio.CurrencyRate{
 					{
 						ValidFrom: "2001-01-01",
 						Code:      code,
-						Rate:      0.8,
+						Rate:      eventio.MustParseDecimalNumber("0.8"),
 					},
 				},
 			})
@@ -582,6 +584,8 @@ var _ = Describe("Store", func() {
 		Entry("£ UK Sterling", "GBP"),
 		Entry("$ US Dollar", "USD"),
 		Entry("€ Euro", "EUR"),
+		Entry("¥ Japanese Yen", "JPY"),
+		Entry("Swiss Franc", "CHF"),
 	)
 
 	DescribeTable("reject unknown currency_codes",
@@ -591,14 +595,14 @@ var _ = Describe("Store", func() {
 					{
 						ValidFrom: "2001-01-01",
 						Code:      code,
-						Rate:      0.8,
+						Rate:      eventio.MustParseDecimalNumber("0.8"),
 					},
 				},
 			})
 			if err == nil {
 				db.Close()
 			}
-			Expect(err).To(MatchError(ContainSubstring(`invalid currency rate: invalid input value for enum currency_code`)))
+			Expect(err).To(MatchError(ContainSubstring(`invalid currency rate: unknown currency code`)))
 		},
 		Entry("no lowercase", "usd"),
 		Entry("no symbols", "$"),
@@ -606,14 +610,14 @@ var _ = Describe("Store", func() {
 		Entry("no unknown", "XXX"),
 	)
 
-	DescribeTable("allow whitelisted vat_rates",
+	DescribeTable("allow configured vat codes",
 		func(code string) {
 			db, err := testenv.Open(eventstore.Config{
 				VATRates: []eventio.VATRate{
 					{
 						ValidFrom: "2001-01-01",
 						Code:      code,
-						Rate:      0.1,
+						Rate:      eventio.MustParseDecimalNumber("0.1"),
 					},
 				},
 			})
@@ -625,27 +629,31 @@ var _ = Describe("Store", func() {
 		Entry("allow: Standard", "Standard"),
 		Entry("allow: Reduced", "Reduced"),
 		Entry("allow: Zero", "Zero"),
+		Entry("allow: Exempt", "Exempt"),
+		Entry("allow: ReverseCharge", "ReverseCharge"),
+		Entry("allow: lowercase", "standard"),
 	)
 
-	DescribeTable("reject unknown vat_rates",
+	DescribeTable("reject invalid vat codes",
 		func(code string) {
 			db, err := testenv.Open(eventstore.Config{
 				VATRates: []eventio.VATRate{
 					{
 						ValidFrom: "2001-01-01",
 						Code:      code,
-						Rate:      0.8,
+						Rate:      eventio.MustParseDecimalNumber("0.8"),
 					},
 				},
 			})
 			if err == nil {
 				db.Close()
 			}
-			Expect(err).To(MatchError(ContainSubstring(`invalid vat rate: invalid input value for enum vat_code`)))
+			Expect(err).To(MatchError(ContainSubstring(`invalid vat rate: invalid vat code`)))
 		},
-		Entry("no lowercase", "standard"),
-		Entry("no uppercase", "ZERO"),
-		Entry("no random codes", "myrate"),
+		Entry("no blank codes", ""),
+		Entry("no spaces", "Standard rate"),
+		Entry("no leading digits", "20pc"),
+		Entry("no symbols", "Zero%"),
 	)
 
 	DescribeTable("should store events of difference kinds",
@@ -106,11 +106,11 @@ var _ = Describe("TotalCostEvents", func() {
 		Expect(len(outputEvents)).To(Equal(2))
 		Expect(outputEvents[0]).To(Equal(eventio.TotalCost{
 			PlanGUID: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
-			Cost:     1417,
+			Cost:     eventio.MustParseDecimalNumber("1417"),
 		}))
 		Expect(outputEvents[1]).To(Equal(eventio.TotalCost{
 			PlanGUID: "f4d4b95a-f55e-4593-8d54-3364c25798c4",
-			Cost:     7.45,
+			Cost:     eventio.MustParseDecimalNumber("7.45"),
 		}))
 	})
 })
@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"strings"
 	"time"
 
 	"code.cloudfoundry.org/lager"
@@ -30,26 +29,14 @@ func (s *EventStore) GetUsageEventRows(filter eventio.EventFilter) (eventio.Usag
 	return rows, nil
 }
 
-func (s *EventStore) getUsageEventRows(tx *sql.Tx, filter eventio.EventFilter) (eventio.UsageEventRows, error) {
+func (s *EventStore) getUsageEventRows(tx *sql.Tx, filter eventio.EventFilter) (*UsageEventRows, error) {
 	if err := filter.Validate(); err != nil {
 		return nil, err
 	}
 	args := []interface{}{
 		fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop), // $1
 	}
-	filterConditions := []string{}
-	orgPlaceholders := []string{}
-	for _, orgGUID := range filter.OrgGUIDs {
-		args = append(args, orgGUID)
-		orgPlaceholders = append(orgPlaceholders, fmt.Sprintf("($%d::uuid)", len(args))) // $N
-	}
-	if len(orgPlaceholders) > 0 {
-		filterConditions = append(filterConditions, fmt.Sprintf("org_guid = any (values %s)", strings.Join(orgPlaceholders, ",")))
-	}
-	filterQuery := ""
-	if len(filterConditions) > 0 {
-		filterQuery = " and " + strings.Join(filterConditions, " and ")
-	}
+	filterQuery, args := eventFilterQuery(filter, "", args)
 
 	startTime := time.Now()
 	rows, err := queryJSON(tx, fmt.Sprintf(`
@@ -70,15 +57,15 @@ func (s *EventStore) getUsageEventRows(tx *sql.Tx, filter eventio.EventFilter) (
 			service_name,
 			number_of_nodes,
 			memory_in_mb,
-			storage_in_mb
+			storage_in_mb,
+			disk_in_mb
 		from
 			events
 		where
 			duration && $1::tstzrange
 			%s
-		order by
-			lower(duration), event_guid
-	`, filterQuery), args...)
+		%s
+	`, filterQuery, eventPageQuery(filter, "order by lower(duration), event_guid")), args...)
 	elapsed := time.Since(startTime)
 	if err != nil {
 		s.logger.Error("get-usage-event-rows-query", err, lager.Data{
@@ -345,7 +345,7 @@ var _ = Describe("GetUsageEvents", func() {
 	It("Should use the memory and storage values from compose scaling events if available", func() {
 		cfg.AddVATRate(eventio.VATRate{
 			Code:      "Zero",
-			Rate:      0,
+			Rate:      eventio.MustParseDecimalNumber("0"),
 			ValidFrom: "epoch",
 		})
 		plan := eventio.PricingPlan{
@@ -527,7 +527,7 @@ var _ = Describe("GetUsageEvents", func() {
 	It("should handle service UPDATE events that change the plan", func() {
 		cfg.AddVATRate(eventio.VATRate{
 			Code:      "Zero",
-			Rate:      0,
+			Rate:      eventio.MustParseDecimalNumber("0"),
 			ValidFrom: "epoch",
 		})
 		plan1 := eventio.PricingPlan{
@@ -693,7 +693,7 @@ var _ = Describe("GetUsageEvents", func() {
 	It("should use the compose event as the EventStart  ", func() {
 		cfg.AddVATRate(eventio.VATRate{
 			Code:      "Zero",
-			Rate:      0,
+			Rate:      eventio.MustParseDecimalNumber("0"),
 			ValidFrom: "epoch",
 		})
 		plan := eventio.PricingPlan{
@@ -964,7 +964,7 @@ var _ = Describe("GetUsageEvents", func() {
 	It("should populate service info (name, label, uuid, unique_id) if historic data is not available", func() {
 		cfg.AddVATRate(eventio.VATRate{
 			Code:      "Zero",
-			Rate:      0,
+			Rate:      eventio.MustParseDecimalNumber("0"),
 			ValidFrom: "epoch",
 		})
 
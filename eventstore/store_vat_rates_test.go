@@ -20,23 +20,23 @@ var _ = Describe("GetVATRates", func() {
 				{
 					Code:      "Zero",
 					ValidFrom: "1970-01-01T00:00:00+00:00",
-					Rate:      0.0,
+					Rate:      eventio.MustParseDecimalNumber("0.0"),
 				},
 				{
 					Code:      "Reduced",
 					ValidFrom: "1970-01-01T00:00:00+00:00",
-					Rate:      0.05,
+					Rate:      eventio.MustParseDecimalNumber("0.05"),
 				},
 				{
 					Code:      "Standard",
 					ValidFrom: "1970-01-01T00:00:00+00:00",
-					Rate:      0.2,
+					Rate:      eventio.MustParseDecimalNumber("0.2"),
 				},
 			},
 			CurrencyRates: []eventio.CurrencyRate{
 				{
 					Code:      "GBP",
-					Rate:      1,
+					Rate:      eventio.MustParseDecimalNumber("1"),
 					ValidFrom: "epoch",
 				},
 			},
@@ -0,0 +1,337 @@
+package eventstore
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/lib/pq"
+)
+
+var _ eventio.WebhookReader = &EventStore{}
+var _ eventio.WebhookWriter = &EventStore{}
+var _ eventio.WebhookEmitter = &EventStore{}
+var _ eventio.WebhookDeliverer = &EventStore{}
+
+// webhookDeliveryColumns are scanned by scanWebhookDelivery
+const webhookDeliveryColumns = `
+	d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+	coalesce(d.response_status, 0), coalesce(d.error, ''),
+	d.next_attempt_at, d.last_attempt_at, d.delivered_at, d.created_at
+`
+
+// GetWebhooks returns every webhook without its secret
+func (s *EventStore) GetWebhooks() ([]eventio.Webhook, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, `
+		select id, url, events, created_by, created_at
+		from webhooks
+		order by id
+	`)
+	if err != nil {
+		return nil, wrapPqError(err, "get-webhooks")
+	}
+	defer rows.Close()
+	webhooks := []eventio.Webhook{}
+	for rows.Next() {
+		var webhook eventio.Webhook
+		var events []string
+		if err := rows.Scan(&webhook.ID, &webhook.URL, pq.Array(&events), &webhook.CreatedBy, &webhook.CreatedAt); err != nil {
+			return nil, err
+		}
+		webhook.Events = []eventio.WebhookEventType{}
+		for _, eventType := range events {
+			webhook.Events = append(webhook.Events, eventio.WebhookEventType(eventType))
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// AddWebhook adds a webhook, generating its secret if it does not have one.
+// Only events emitted after it is added are sent to it.
+func (s *EventStore) AddWebhook(webhook eventio.Webhook, createdBy string) (eventio.Webhook, error) {
+	if err := webhook.Validate(); err != nil {
+		return eventio.Webhook{}, err
+	}
+	if webhook.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			return eventio.Webhook{}, err
+		}
+		webhook.Secret = secret
+	}
+	events := []string{}
+	for _, eventType := range webhook.Events {
+		events = append(events, string(eventType))
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	err := s.db.QueryRowContext(ctx, `
+		insert into webhooks (url, secret, events, created_by)
+		values ($1, $2, $3, $4)
+		returning id, created_by, created_at
+	`, webhook.URL, webhook.Secret, pq.Array(events), createdBy).Scan(&webhook.ID, &webhook.CreatedBy, &webhook.CreatedAt)
+	if err != nil {
+		return eventio.Webhook{}, wrapPqError(err, "add-webhook")
+	}
+	s.logger.Info("add-webhook", lager.Data{
+		"id":         webhook.ID,
+		"url":        webhook.URL,
+		"events":     webhook.Events,
+		"created_by": createdBy,
+	})
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook and the log of its deliveries
+func (s *EventStore) DeleteWebhook(id int64) error {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	result, err := s.db.ExecContext(ctx, `delete from webhooks where id = $1`, id)
+	if err != nil {
+		return wrapPqError(err, "delete-webhook")
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return eventio.ErrWebhookNotFound
+	}
+	s.logger.Info("delete-webhook", lager.Data{"id": id})
+	return nil
+}
+
+// GetWebhookDeliveries returns the most recent deliveries to a webhook,
+// newest first
+func (s *EventStore) GetWebhookDeliveries(filter eventio.WebhookDeliveryFilter) ([]eventio.WebhookDelivery, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`select exists (select 1 from webhooks where id = $1)`, filter.WebhookID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, eventio.ErrWebhookNotFound
+	}
+	rows, err := tx.Query(`
+		select `+webhookDeliveryColumns+`
+		from webhook_deliveries d
+		where d.webhook_id = $1
+		and ($2 = '' or d.status = $2)
+		order by d.id desc
+		limit $3
+	`, filter.WebhookID, string(filter.Status), filter.Limit)
+	if err != nil {
+		return nil, wrapPqError(err, "get-webhook-deliveries")
+	}
+	defer rows.Close()
+	deliveries := []eventio.WebhookDelivery{}
+	for rows.Next() {
+		var delivery eventio.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &delivery); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// RetryWebhookDelivery makes a delivery due now, with its attempts reset,
+// whether it was delivered, failed or is still being tried
+func (s *EventStore) RetryWebhookDelivery(webhookID int64, deliveryID int64) (eventio.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	var delivery eventio.WebhookDelivery
+	err := scanWebhookDelivery(s.db.QueryRowContext(ctx, `
+		update webhook_deliveries d set
+			status = 'pending',
+			attempts = 0,
+			next_attempt_at = now()
+		where d.webhook_id = $1 and d.id = $2
+		returning `+webhookDeliveryColumns,
+		webhookID, deliveryID,
+	), &delivery)
+	if err == sql.ErrNoRows {
+		return eventio.WebhookDelivery{}, eventio.ErrWebhookDeliveryNotFound
+	} else if err != nil {
+		return eventio.WebhookDelivery{}, wrapPqError(err, "retry-webhook-delivery")
+	}
+	s.logger.Info("retry-webhook-delivery", lager.Data{
+		"webhook_id": webhookID,
+		"id":         deliveryID,
+	})
+	return delivery, nil
+}
+
+// EmitWebhookEvent records a delivery of the event to each webhook that is
+// subscribed to it
+func (s *EventStore) EmitWebhookEvent(event eventio.WebhookEvent) error {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := s.emitWebhookEvent(tx, event); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// emitWebhookEvent records the deliveries of an event in tx, so that the
+// event is only sent if the change it is about is committed
+func (s *EventStore) emitWebhookEvent(tx *sql.Tx, event eventio.WebhookEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec(`
+		insert into webhook_deliveries (webhook_id, event_id, event_type, payload)
+		select id, $1, $2, $3
+		from webhooks
+		where cardinality(events) = 0 or $2 = any(events)
+		on conflict (webhook_id, event_id) do nothing
+	`, event.ID, string(event.Type), string(payload))
+	if err != nil {
+		return wrapPqError(err, "emit-webhook-event")
+	}
+	deliveries, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deliveries > 0 {
+		s.logger.Info("emit-webhook-event", lager.Data{
+			"id":         event.ID,
+			"type":       event.Type,
+			"deliveries": deliveries,
+		})
+	}
+	return nil
+}
+
+// GetDueWebhookDeliveries claims the pending deliveries that are due, oldest
+// first, by putting their next attempt back by lease. Deliveries claimed by
+// another caller are skipped rather than waited for.
+func (s *EventStore) GetDueWebhookDeliveries(limit int, lease time.Duration) ([]eventio.DueWebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		update webhook_deliveries d set
+			next_attempt_at = now() + $2 * interval '1 second'
+		from
+			(
+				select id
+				from webhook_deliveries
+				where status = 'pending' and next_attempt_at <= now()
+				order by next_attempt_at, id
+				limit $1
+				for update skip locked
+			) due,
+			webhooks w
+		where
+			d.id = due.id and w.id = d.webhook_id
+		returning `+webhookDeliveryColumns+`, w.url, w.secret
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, wrapPqError(err, "get-due-webhook-deliveries")
+	}
+	defer rows.Close()
+	deliveries := []eventio.DueWebhookDelivery{}
+	for rows.Next() {
+		var delivery eventio.DueWebhookDelivery
+		if err := scanWebhookDelivery(rows, &delivery.WebhookDelivery, &delivery.URL, &delivery.Secret); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	return deliveries, tx.Commit()
+}
+
+// RecordWebhookAttempt records the outcome of an attempt to send a delivery
+func (s *EventStore) RecordWebhookAttempt(id int64, attempt eventio.WebhookAttempt) error {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, `
+		update webhook_deliveries set
+			attempts = attempts + 1,
+			last_attempt_at = now(),
+			response_status = nullif($2, 0),
+			error = nullif($3, ''),
+			status = (case
+				when $4::boolean then 'delivered'
+				when $5::timestamptz is null then 'failed'
+				else 'pending'
+			end),
+			next_attempt_at = (case when $4::boolean then null else $5::timestamptz end),
+			delivered_at = (case when $4::boolean then now() end)
+		where id = $1
+	`, id, attempt.ResponseStatus, attempt.Error, attempt.Delivered, attempt.NextAttemptAt)
+	if err != nil {
+		return wrapPqError(err, "record-webhook-attempt")
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanWebhookDelivery scans the webhookDeliveryColumns into delivery,
+// followed by any extra columns
+func scanWebhookDelivery(row rowScanner, delivery *eventio.WebhookDelivery, extra ...interface{}) error {
+	var payload string
+	dest := append([]interface{}{
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.ResponseStatus,
+		&delivery.Error,
+		&delivery.NextAttemptAt,
+		&delivery.LastAttemptAt,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	delivery.Payload = json.RawMessage(payload)
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
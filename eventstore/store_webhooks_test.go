@@ -0,0 +1,157 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Webhooks", func() {
+
+	It("should add, list and delete webhooks", func() {
+		db, err := testenv.Open(testenv.BasicConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		store := db.Schema
+
+		webhook, err := store.AddWebhook(eventio.Webhook{
+			URL:    "https://ledger.example.com/billing",
+			Events: []eventio.WebhookEventType{eventio.MonthConsolidated},
+		}, "jeff@example.com")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(webhook.ID).ToNot(BeZero())
+		Expect(webhook.Secret).To(HaveLen(64), "expected a secret to be generated")
+		Expect(webhook.CreatedBy).To(Equal("jeff@example.com"))
+
+		webhooks, err := store.GetWebhooks()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(webhooks).To(HaveLen(1))
+		Expect(webhooks[0].ID).To(Equal(webhook.ID))
+		Expect(webhooks[0].URL).To(Equal("https://ledger.example.com/billing"))
+		Expect(webhooks[0].Events).To(Equal([]eventio.WebhookEventType{eventio.MonthConsolidated}))
+		Expect(webhooks[0].Secret).To(BeEmpty())
+
+		Expect(store.DeleteWebhook(webhook.ID)).To(Succeed())
+		Expect(store.DeleteWebhook(webhook.ID)).To(Equal(eventio.ErrWebhookNotFound))
+		Expect(store.GetWebhooks()).To(BeEmpty())
+	})
+
+	It("should deliver each event once to the webhooks subscribed to it and record each attempt", func() {
+		db, err := testenv.Open(testenv.BasicConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		store := db.Schema
+
+		all, err := store.AddWebhook(eventio.Webhook{URL: "https://all.example.com"}, "jeff@example.com")
+		Expect(err).ToNot(HaveOccurred())
+		refreshes, err := store.AddWebhook(eventio.Webhook{
+			URL:    "https://refreshes.example.com",
+			Secret: "0123456789abcdef",
+			Events: []eventio.WebhookEventType{eventio.RefreshFailed},
+		}, "jeff@example.com")
+		Expect(err).ToNot(HaveOccurred())
+
+		consolidated, err := eventio.NewWebhookEvent(eventio.MonthConsolidated, "month.consolidated:2001-01-01/2001-02-01", eventio.MonthConsolidatedData{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		failed, err := eventio.NewWebhookEvent(eventio.RefreshFailed, "refresh.failed:1", eventio.RefreshData{Error: "some-error"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(store.EmitWebhookEvent(consolidated)).To(Succeed())
+		Expect(store.EmitWebhookEvent(failed)).To(Succeed())
+		Expect(store.EmitWebhookEvent(failed)).To(Succeed())
+
+		due, err := store.GetDueWebhookDeliveries(10, time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(due).To(HaveLen(3))
+		again, err := store.GetDueWebhookDeliveries(10, time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(again).To(BeEmpty(), "expected the claimed deliveries to be leased")
+
+		var refreshDelivery eventio.DueWebhookDelivery
+		for _, delivery := range due {
+			if delivery.WebhookID == refreshes.ID {
+				refreshDelivery = delivery
+			}
+		}
+		Expect(refreshDelivery.EventID).To(Equal("refresh.failed:1"))
+		Expect(refreshDelivery.URL).To(Equal("https://refreshes.example.com"))
+		Expect(refreshDelivery.Secret).To(Equal("0123456789abcdef"))
+		var payload eventio.WebhookEvent
+		Expect(json.Unmarshal(refreshDelivery.Payload, &payload)).To(Succeed())
+		Expect(payload.Type).To(Equal(eventio.RefreshFailed))
+		Expect(payload.Data).To(MatchJSON(`{"full_rebuild": false, "error": "some-error"}`))
+
+		next := time.Now().Add(-time.Second)
+		Expect(store.RecordWebhookAttempt(refreshDelivery.ID, eventio.WebhookAttempt{
+			ResponseStatus: 502,
+			Error:          "webhook responded with 502 Bad Gateway",
+			NextAttemptAt:  &next,
+		})).To(Succeed())
+		due, err = store.GetDueWebhookDeliveries(10, time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(due).To(HaveLen(1))
+		Expect(due[0].ID).To(Equal(refreshDelivery.ID))
+		Expect(due[0].Attempts).To(Equal(1))
+		Expect(due[0].Error).To(Equal("webhook responded with 502 Bad Gateway"))
+
+		Expect(store.RecordWebhookAttempt(refreshDelivery.ID, eventio.WebhookAttempt{
+			Delivered:      true,
+			ResponseStatus: 200,
+		})).To(Succeed())
+		deliveries, err := store.GetWebhookDeliveries(eventio.WebhookDeliveryFilter{WebhookID: refreshes.ID})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deliveries).To(HaveLen(1))
+		Expect(deliveries[0].Status).To(Equal(eventio.WebhookDeliveryDelivered))
+		Expect(deliveries[0].Attempts).To(Equal(2))
+		Expect(deliveries[0].ResponseStatus).To(Equal(200))
+		Expect(deliveries[0].Error).To(BeEmpty())
+		Expect(deliveries[0].DeliveredAt).ToNot(BeNil())
+		Expect(deliveries[0].NextAttemptAt).To(BeNil())
+
+		deliveries, err = store.GetWebhookDeliveries(eventio.WebhookDeliveryFilter{WebhookID: all.ID})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deliveries).To(HaveLen(2))
+		Expect(store.RecordWebhookAttempt(deliveries[0].ID, eventio.WebhookAttempt{Error: "gave up"})).To(Succeed())
+		deliveries, err = store.GetWebhookDeliveries(eventio.WebhookDeliveryFilter{WebhookID: all.ID, Status: eventio.WebhookDeliveryFailed})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deliveries).To(HaveLen(1))
+
+		retried, err := store.RetryWebhookDelivery(all.ID, deliveries[0].ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(retried.Status).To(Equal(eventio.WebhookDeliveryPending))
+		Expect(retried.Attempts).To(Equal(0))
+		_, err = store.RetryWebhookDelivery(refreshes.ID, deliveries[0].ID)
+		Expect(err).To(Equal(eventio.ErrWebhookDeliveryNotFound))
+
+		_, err = store.GetWebhookDeliveries(eventio.WebhookDeliveryFilter{WebhookID: all.ID + refreshes.ID})
+		Expect(err).To(Equal(eventio.ErrWebhookNotFound))
+	})
+
+	It("should emit an event for each month consolidated", func() {
+		db, err := testenv.Open(testenv.BasicConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		store := db.Schema
+
+		webhook, err := store.AddWebhook(eventio.Webhook{
+			URL:    "https://ledger.example.com/billing",
+			Events: []eventio.WebhookEventType{eventio.MonthConsolidated},
+		}, "jeff@example.com")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(store.ConsolidateFullMonths("2001-01-01", "2001-03-01")).To(Succeed())
+		Expect(store.ConsolidateFullMonths("2001-01-01", "2001-03-01")).To(Succeed())
+
+		deliveries, err := store.GetWebhookDeliveries(eventio.WebhookDeliveryFilter{WebhookID: webhook.ID})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deliveries).To(HaveLen(2))
+		Expect(deliveries[0].EventID).To(Equal("month.consolidated:2001-02-01/2001-03-01"))
+		Expect(deliveries[1].EventID).To(Equal("month.consolidated:2001-01-01/2001-02-01"))
+	})
+})
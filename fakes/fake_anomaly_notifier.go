@@ -0,0 +1,114 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package fakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/notifier"
+)
+
+type FakeAnomalyNotifier struct {
+	NotifyAnomalyStub        func(context.Context, eventio.Anomaly) error
+	notifyAnomalyMutex       sync.RWMutex
+	notifyAnomalyArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.Anomaly
+	}
+	notifyAnomalyReturns struct {
+		result1 error
+	}
+	notifyAnomalyReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeAnomalyNotifier) NotifyAnomaly(arg1 context.Context, arg2 eventio.Anomaly) error {
+	fake.notifyAnomalyMutex.Lock()
+	ret, specificReturn := fake.notifyAnomalyReturnsOnCall[len(fake.notifyAnomalyArgsForCall)]
+	fake.notifyAnomalyArgsForCall = append(fake.notifyAnomalyArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.Anomaly
+	}{arg1, arg2})
+	fake.recordInvocation("NotifyAnomaly", []interface{}{arg1, arg2})
+	fake.notifyAnomalyMutex.Unlock()
+	if fake.NotifyAnomalyStub != nil {
+		return fake.NotifyAnomalyStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.notifyAnomalyReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeAnomalyNotifier) NotifyAnomalyCallCount() int {
+	fake.notifyAnomalyMutex.RLock()
+	defer fake.notifyAnomalyMutex.RUnlock()
+	return len(fake.notifyAnomalyArgsForCall)
+}
+
+func (fake *FakeAnomalyNotifier) NotifyAnomalyCalls(stub func(context.Context, eventio.Anomaly) error) {
+	fake.notifyAnomalyMutex.Lock()
+	defer fake.notifyAnomalyMutex.Unlock()
+	fake.NotifyAnomalyStub = stub
+}
+
+func (fake *FakeAnomalyNotifier) NotifyAnomalyArgsForCall(i int) (context.Context, eventio.Anomaly) {
+	fake.notifyAnomalyMutex.RLock()
+	defer fake.notifyAnomalyMutex.RUnlock()
+	argsForCall := fake.notifyAnomalyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeAnomalyNotifier) NotifyAnomalyReturns(result1 error) {
+	fake.notifyAnomalyMutex.Lock()
+	defer fake.notifyAnomalyMutex.Unlock()
+	fake.NotifyAnomalyStub = nil
+	fake.notifyAnomalyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAnomalyNotifier) NotifyAnomalyReturnsOnCall(i int, result1 error) {
+	fake.notifyAnomalyMutex.Lock()
+	defer fake.notifyAnomalyMutex.Unlock()
+	fake.NotifyAnomalyStub = nil
+	if fake.notifyAnomalyReturnsOnCall == nil {
+		fake.notifyAnomalyReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.notifyAnomalyReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAnomalyNotifier) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.notifyAnomalyMutex.RLock()
+	defer fake.notifyAnomalyMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeAnomalyNotifier) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ notifier.AnomalyNotifier = new(FakeAnomalyNotifier)
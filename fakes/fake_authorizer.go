@@ -10,8 +10,9 @@ import (
 type FakeAuthorizer struct {
 	AdminStub        func() (bool, error)
 	adminMutex       sync.RWMutex
-	adminArgsForCall []struct{}
-	adminReturns     struct {
+	adminArgsForCall []struct {
+	}
+	adminReturns struct {
 		result1 bool
 		result2 error
 	}
@@ -19,6 +20,18 @@ type FakeAuthorizer struct {
 		result1 bool
 		result2 error
 	}
+	FullAdminStub        func() (bool, error)
+	fullAdminMutex       sync.RWMutex
+	fullAdminArgsForCall []struct {
+	}
+	fullAdminReturns struct {
+		result1 bool
+		result2 error
+	}
+	fullAdminReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
 	HasBillingAccessStub        func([]string) (bool, error)
 	hasBillingAccessMutex       sync.RWMutex
 	hasBillingAccessArgsForCall []struct {
@@ -32,6 +45,18 @@ type FakeAuthorizer struct {
 		result1 bool
 		result2 error
 	}
+	UserStub        func() (string, error)
+	userMutex       sync.RWMutex
+	userArgsForCall []struct {
+	}
+	userReturns struct {
+		result1 string
+		result2 error
+	}
+	userReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -39,7 +64,8 @@ type FakeAuthorizer struct {
 func (fake *FakeAuthorizer) Admin() (bool, error) {
 	fake.adminMutex.Lock()
 	ret, specificReturn := fake.adminReturnsOnCall[len(fake.adminArgsForCall)]
-	fake.adminArgsForCall = append(fake.adminArgsForCall, struct{}{})
+	fake.adminArgsForCall = append(fake.adminArgsForCall, struct {
+	}{})
 	fake.recordInvocation("Admin", []interface{}{})
 	fake.adminMutex.Unlock()
 	if fake.AdminStub != nil {
@@ -48,7 +74,8 @@ func (fake *FakeAuthorizer) Admin() (bool, error) {
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.adminReturns.result1, fake.adminReturns.result2
+	fakeReturns := fake.adminReturns
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeAuthorizer) AdminCallCount() int {
@@ -57,7 +84,15 @@ func (fake *FakeAuthorizer) AdminCallCount() int {
 	return len(fake.adminArgsForCall)
 }
 
+func (fake *FakeAuthorizer) AdminCalls(stub func() (bool, error)) {
+	fake.adminMutex.Lock()
+	defer fake.adminMutex.Unlock()
+	fake.AdminStub = stub
+}
+
 func (fake *FakeAuthorizer) AdminReturns(result1 bool, result2 error) {
+	fake.adminMutex.Lock()
+	defer fake.adminMutex.Unlock()
 	fake.AdminStub = nil
 	fake.adminReturns = struct {
 		result1 bool
@@ -66,6 +101,8 @@ func (fake *FakeAuthorizer) AdminReturns(result1 bool, result2 error) {
 }
 
 func (fake *FakeAuthorizer) AdminReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.adminMutex.Lock()
+	defer fake.adminMutex.Unlock()
 	fake.AdminStub = nil
 	if fake.adminReturnsOnCall == nil {
 		fake.adminReturnsOnCall = make(map[int]struct {
@@ -79,6 +116,61 @@ func (fake *FakeAuthorizer) AdminReturnsOnCall(i int, result1 bool, result2 erro
 	}{result1, result2}
 }
 
+func (fake *FakeAuthorizer) FullAdmin() (bool, error) {
+	fake.fullAdminMutex.Lock()
+	ret, specificReturn := fake.fullAdminReturnsOnCall[len(fake.fullAdminArgsForCall)]
+	fake.fullAdminArgsForCall = append(fake.fullAdminArgsForCall, struct {
+	}{})
+	fake.recordInvocation("FullAdmin", []interface{}{})
+	fake.fullAdminMutex.Unlock()
+	if fake.FullAdminStub != nil {
+		return fake.FullAdminStub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.fullAdminReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeAuthorizer) FullAdminCallCount() int {
+	fake.fullAdminMutex.RLock()
+	defer fake.fullAdminMutex.RUnlock()
+	return len(fake.fullAdminArgsForCall)
+}
+
+func (fake *FakeAuthorizer) FullAdminCalls(stub func() (bool, error)) {
+	fake.fullAdminMutex.Lock()
+	defer fake.fullAdminMutex.Unlock()
+	fake.FullAdminStub = stub
+}
+
+func (fake *FakeAuthorizer) FullAdminReturns(result1 bool, result2 error) {
+	fake.fullAdminMutex.Lock()
+	defer fake.fullAdminMutex.Unlock()
+	fake.FullAdminStub = nil
+	fake.fullAdminReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAuthorizer) FullAdminReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.fullAdminMutex.Lock()
+	defer fake.fullAdminMutex.Unlock()
+	fake.FullAdminStub = nil
+	if fake.fullAdminReturnsOnCall == nil {
+		fake.fullAdminReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.fullAdminReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeAuthorizer) HasBillingAccess(arg1 []string) (bool, error) {
 	var arg1Copy []string
 	if arg1 != nil {
@@ -98,7 +190,8 @@ func (fake *FakeAuthorizer) HasBillingAccess(arg1 []string) (bool, error) {
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.hasBillingAccessReturns.result1, fake.hasBillingAccessReturns.result2
+	fakeReturns := fake.hasBillingAccessReturns
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeAuthorizer) HasBillingAccessCallCount() int {
@@ -107,13 +200,22 @@ func (fake *FakeAuthorizer) HasBillingAccessCallCount() int {
 	return len(fake.hasBillingAccessArgsForCall)
 }
 
+func (fake *FakeAuthorizer) HasBillingAccessCalls(stub func([]string) (bool, error)) {
+	fake.hasBillingAccessMutex.Lock()
+	defer fake.hasBillingAccessMutex.Unlock()
+	fake.HasBillingAccessStub = stub
+}
+
 func (fake *FakeAuthorizer) HasBillingAccessArgsForCall(i int) []string {
 	fake.hasBillingAccessMutex.RLock()
 	defer fake.hasBillingAccessMutex.RUnlock()
-	return fake.hasBillingAccessArgsForCall[i].arg1
+	argsForCall := fake.hasBillingAccessArgsForCall[i]
+	return argsForCall.arg1
 }
 
 func (fake *FakeAuthorizer) HasBillingAccessReturns(result1 bool, result2 error) {
+	fake.hasBillingAccessMutex.Lock()
+	defer fake.hasBillingAccessMutex.Unlock()
 	fake.HasBillingAccessStub = nil
 	fake.hasBillingAccessReturns = struct {
 		result1 bool
@@ -122,6 +224,8 @@ func (fake *FakeAuthorizer) HasBillingAccessReturns(result1 bool, result2 error)
 }
 
 func (fake *FakeAuthorizer) HasBillingAccessReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.hasBillingAccessMutex.Lock()
+	defer fake.hasBillingAccessMutex.Unlock()
 	fake.HasBillingAccessStub = nil
 	if fake.hasBillingAccessReturnsOnCall == nil {
 		fake.hasBillingAccessReturnsOnCall = make(map[int]struct {
@@ -135,13 +239,72 @@ func (fake *FakeAuthorizer) HasBillingAccessReturnsOnCall(i int, result1 bool, r
 	}{result1, result2}
 }
 
+func (fake *FakeAuthorizer) User() (string, error) {
+	fake.userMutex.Lock()
+	ret, specificReturn := fake.userReturnsOnCall[len(fake.userArgsForCall)]
+	fake.userArgsForCall = append(fake.userArgsForCall, struct {
+	}{})
+	fake.recordInvocation("User", []interface{}{})
+	fake.userMutex.Unlock()
+	if fake.UserStub != nil {
+		return fake.UserStub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.userReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeAuthorizer) UserCallCount() int {
+	fake.userMutex.RLock()
+	defer fake.userMutex.RUnlock()
+	return len(fake.userArgsForCall)
+}
+
+func (fake *FakeAuthorizer) UserCalls(stub func() (string, error)) {
+	fake.userMutex.Lock()
+	defer fake.userMutex.Unlock()
+	fake.UserStub = stub
+}
+
+func (fake *FakeAuthorizer) UserReturns(result1 string, result2 error) {
+	fake.userMutex.Lock()
+	defer fake.userMutex.Unlock()
+	fake.UserStub = nil
+	fake.userReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAuthorizer) UserReturnsOnCall(i int, result1 string, result2 error) {
+	fake.userMutex.Lock()
+	defer fake.userMutex.Unlock()
+	fake.UserStub = nil
+	if fake.userReturnsOnCall == nil {
+		fake.userReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.userReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeAuthorizer) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.adminMutex.RLock()
 	defer fake.adminMutex.RUnlock()
+	fake.fullAdminMutex.RLock()
+	defer fake.fullAdminMutex.RUnlock()
 	fake.hasBillingAccessMutex.RLock()
 	defer fake.hasBillingAccessMutex.RUnlock()
+	fake.userMutex.RLock()
+	defer fake.userMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
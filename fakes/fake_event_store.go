@@ -4,11 +4,82 @@ package fakes
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/alphagov/paas-billing/eventio"
 )
 
 type FakeEventStore struct {
+	AddCurrencyRateVersionStub        func(eventio.CurrencyRate, string) (eventio.CurrencyRateVersion, error)
+	addCurrencyRateVersionMutex       sync.RWMutex
+	addCurrencyRateVersionArgsForCall []struct {
+		arg1 eventio.CurrencyRate
+		arg2 string
+	}
+	addCurrencyRateVersionReturns struct {
+		result1 eventio.CurrencyRateVersion
+		result2 error
+	}
+	addCurrencyRateVersionReturnsOnCall map[int]struct {
+		result1 eventio.CurrencyRateVersion
+		result2 error
+	}
+	AddOrgTaxTreatmentVersionStub        func(eventio.OrgTaxTreatment, string) (eventio.OrgTaxTreatmentVersion, error)
+	addOrgTaxTreatmentVersionMutex       sync.RWMutex
+	addOrgTaxTreatmentVersionArgsForCall []struct {
+		arg1 eventio.OrgTaxTreatment
+		arg2 string
+	}
+	addOrgTaxTreatmentVersionReturns struct {
+		result1 eventio.OrgTaxTreatmentVersion
+		result2 error
+	}
+	addOrgTaxTreatmentVersionReturnsOnCall map[int]struct {
+		result1 eventio.OrgTaxTreatmentVersion
+		result2 error
+	}
+	AddPricingPlanVersionStub        func(eventio.PricingPlan, string) (eventio.PricingPlanVersion, error)
+	addPricingPlanVersionMutex       sync.RWMutex
+	addPricingPlanVersionArgsForCall []struct {
+		arg1 eventio.PricingPlan
+		arg2 string
+	}
+	addPricingPlanVersionReturns struct {
+		result1 eventio.PricingPlanVersion
+		result2 error
+	}
+	addPricingPlanVersionReturnsOnCall map[int]struct {
+		result1 eventio.PricingPlanVersion
+		result2 error
+	}
+	AddVATRateVersionStub        func(eventio.VATRate, string) (eventio.VATRateVersion, error)
+	addVATRateVersionMutex       sync.RWMutex
+	addVATRateVersionArgsForCall []struct {
+		arg1 eventio.VATRate
+		arg2 string
+	}
+	addVATRateVersionReturns struct {
+		result1 eventio.VATRateVersion
+		result2 error
+	}
+	addVATRateVersionReturnsOnCall map[int]struct {
+		result1 eventio.VATRateVersion
+		result2 error
+	}
+	AddWebhookStub        func(eventio.Webhook, string) (eventio.Webhook, error)
+	addWebhookMutex       sync.RWMutex
+	addWebhookArgsForCall []struct {
+		arg1 eventio.Webhook
+		arg2 string
+	}
+	addWebhookReturns struct {
+		result1 eventio.Webhook
+		result2 error
+	}
+	addWebhookReturnsOnCall map[int]struct {
+		result1 eventio.Webhook
+		result2 error
+	}
 	ConsolidateStub        func(eventio.EventFilter) error
 	consolidateMutex       sync.RWMutex
 	consolidateArgsForCall []struct {
@@ -42,6 +113,78 @@ type FakeEventStore struct {
 	consolidateFullMonthsReturnsOnCall map[int]struct {
 		result1 error
 	}
+	CreditInvoiceStub        func(int64, string) (eventio.Invoice, error)
+	creditInvoiceMutex       sync.RWMutex
+	creditInvoiceArgsForCall []struct {
+		arg1 int64
+		arg2 string
+	}
+	creditInvoiceReturns struct {
+		result1 eventio.Invoice
+		result2 error
+	}
+	creditInvoiceReturnsOnCall map[int]struct {
+		result1 eventio.Invoice
+		result2 error
+	}
+	DeleteBudgetStub        func(string) error
+	deleteBudgetMutex       sync.RWMutex
+	deleteBudgetArgsForCall []struct {
+		arg1 string
+	}
+	deleteBudgetReturns struct {
+		result1 error
+	}
+	deleteBudgetReturnsOnCall map[int]struct {
+		result1 error
+	}
+	DeleteWebhookStub        func(int64) error
+	deleteWebhookMutex       sync.RWMutex
+	deleteWebhookArgsForCall []struct {
+		arg1 int64
+	}
+	deleteWebhookReturns struct {
+		result1 error
+	}
+	deleteWebhookReturnsOnCall map[int]struct {
+		result1 error
+	}
+	DetectAnomaliesStub        func(eventio.AnomalyConfig) ([]eventio.Anomaly, error)
+	detectAnomaliesMutex       sync.RWMutex
+	detectAnomaliesArgsForCall []struct {
+		arg1 eventio.AnomalyConfig
+	}
+	detectAnomaliesReturns struct {
+		result1 []eventio.Anomaly
+		result2 error
+	}
+	detectAnomaliesReturnsOnCall map[int]struct {
+		result1 []eventio.Anomaly
+		result2 error
+	}
+	EmitWebhookEventStub        func(eventio.WebhookEvent) error
+	emitWebhookEventMutex       sync.RWMutex
+	emitWebhookEventArgsForCall []struct {
+		arg1 eventio.WebhookEvent
+	}
+	emitWebhookEventReturns struct {
+		result1 error
+	}
+	emitWebhookEventReturnsOnCall map[int]struct {
+		result1 error
+	}
+	EvaluateBudgetsStub        func() ([]eventio.BudgetAlert, error)
+	evaluateBudgetsMutex       sync.RWMutex
+	evaluateBudgetsArgsForCall []struct {
+	}
+	evaluateBudgetsReturns struct {
+		result1 []eventio.BudgetAlert
+		result2 error
+	}
+	evaluateBudgetsReturnsOnCall map[int]struct {
+		result1 []eventio.BudgetAlert
+		result2 error
+	}
 	ForecastBillableEventRowsStub        func(context.Context, []eventio.UsageEvent, eventio.EventFilter) (eventio.BillableEventRows, error)
 	forecastBillableEventRowsMutex       sync.RWMutex
 	forecastBillableEventRowsArgsForCall []struct {
@@ -71,6 +214,48 @@ type FakeEventStore struct {
 		result1 []eventio.BillableEvent
 		result2 error
 	}
+	ForecastScenarioBillableEventRowsStub        func(context.Context, eventio.Scenario, eventio.EventFilter) (eventio.BillableEventRows, error)
+	forecastScenarioBillableEventRowsMutex       sync.RWMutex
+	forecastScenarioBillableEventRowsArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.Scenario
+		arg3 eventio.EventFilter
+	}
+	forecastScenarioBillableEventRowsReturns struct {
+		result1 eventio.BillableEventRows
+		result2 error
+	}
+	forecastScenarioBillableEventRowsReturnsOnCall map[int]struct {
+		result1 eventio.BillableEventRows
+		result2 error
+	}
+	ForecastScenarioBillableEventsStub        func(eventio.Scenario, eventio.EventFilter) ([]eventio.BillableEvent, error)
+	forecastScenarioBillableEventsMutex       sync.RWMutex
+	forecastScenarioBillableEventsArgsForCall []struct {
+		arg1 eventio.Scenario
+		arg2 eventio.EventFilter
+	}
+	forecastScenarioBillableEventsReturns struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}
+	forecastScenarioBillableEventsReturnsOnCall map[int]struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}
+	GetAnomaliesStub        func(eventio.EventFilter) ([]eventio.Anomaly, error)
+	getAnomaliesMutex       sync.RWMutex
+	getAnomaliesArgsForCall []struct {
+		arg1 eventio.EventFilter
+	}
+	getAnomaliesReturns struct {
+		result1 []eventio.Anomaly
+		result2 error
+	}
+	getAnomaliesReturnsOnCall map[int]struct {
+		result1 []eventio.Anomaly
+		result2 error
+	}
 	GetBillableEventRowsStub        func(context.Context, eventio.EventFilter) (eventio.BillableEventRows, error)
 	getBillableEventRowsMutex       sync.RWMutex
 	getBillableEventRowsArgsForCall []struct {
@@ -98,6 +283,45 @@ type FakeEventStore struct {
 		result1 []eventio.BillableEvent
 		result2 error
 	}
+	GetBillableSummaryStub        func(eventio.BillableSummaryFilter) ([]eventio.BillableSummary, error)
+	getBillableSummaryMutex       sync.RWMutex
+	getBillableSummaryArgsForCall []struct {
+		arg1 eventio.BillableSummaryFilter
+	}
+	getBillableSummaryReturns struct {
+		result1 []eventio.BillableSummary
+		result2 error
+	}
+	getBillableSummaryReturnsOnCall map[int]struct {
+		result1 []eventio.BillableSummary
+		result2 error
+	}
+	GetBillingPeriodStub        func([]string) (eventio.BillingPeriod, error)
+	getBillingPeriodMutex       sync.RWMutex
+	getBillingPeriodArgsForCall []struct {
+		arg1 []string
+	}
+	getBillingPeriodReturns struct {
+		result1 eventio.BillingPeriod
+		result2 error
+	}
+	getBillingPeriodReturnsOnCall map[int]struct {
+		result1 eventio.BillingPeriod
+		result2 error
+	}
+	GetBudgetsStub        func([]string) ([]eventio.Budget, error)
+	getBudgetsMutex       sync.RWMutex
+	getBudgetsArgsForCall []struct {
+		arg1 []string
+	}
+	getBudgetsReturns struct {
+		result1 []eventio.Budget
+		result2 error
+	}
+	getBudgetsReturnsOnCall map[int]struct {
+		result1 []eventio.Budget
+		result2 error
+	}
 	GetConsolidatedBillableEventRowsStub        func(context.Context, eventio.EventFilter) (eventio.BillableEventRows, error)
 	getConsolidatedBillableEventRowsMutex       sync.RWMutex
 	getConsolidatedBillableEventRowsArgsForCall []struct {
@@ -125,6 +349,19 @@ type FakeEventStore struct {
 		result1 []eventio.BillableEvent
 		result2 error
 	}
+	GetCurrencyRateVersionsStub        func(string) ([]eventio.CurrencyRateVersion, error)
+	getCurrencyRateVersionsMutex       sync.RWMutex
+	getCurrencyRateVersionsArgsForCall []struct {
+		arg1 string
+	}
+	getCurrencyRateVersionsReturns struct {
+		result1 []eventio.CurrencyRateVersion
+		result2 error
+	}
+	getCurrencyRateVersionsReturnsOnCall map[int]struct {
+		result1 []eventio.CurrencyRateVersion
+		result2 error
+	}
 	GetCurrencyRatesStub        func(eventio.TimeRangeFilter) ([]eventio.CurrencyRate, error)
 	getCurrencyRatesMutex       sync.RWMutex
 	getCurrencyRatesArgsForCall []struct {
@@ -138,6 +375,20 @@ type FakeEventStore struct {
 		result1 []eventio.CurrencyRate
 		result2 error
 	}
+	GetDueWebhookDeliveriesStub        func(int, time.Duration) ([]eventio.DueWebhookDelivery, error)
+	getDueWebhookDeliveriesMutex       sync.RWMutex
+	getDueWebhookDeliveriesArgsForCall []struct {
+		arg1 int
+		arg2 time.Duration
+	}
+	getDueWebhookDeliveriesReturns struct {
+		result1 []eventio.DueWebhookDelivery
+		result2 error
+	}
+	getDueWebhookDeliveriesReturnsOnCall map[int]struct {
+		result1 []eventio.DueWebhookDelivery
+		result2 error
+	}
 	GetEventsStub        func(eventio.RawEventFilter) ([]eventio.RawEvent, error)
 	getEventsMutex       sync.RWMutex
 	getEventsArgsForCall []struct {
@@ -151,6 +402,71 @@ type FakeEventStore struct {
 		result1 []eventio.RawEvent
 		result2 error
 	}
+	GetInvoiceStub        func(int64) (eventio.Invoice, error)
+	getInvoiceMutex       sync.RWMutex
+	getInvoiceArgsForCall []struct {
+		arg1 int64
+	}
+	getInvoiceReturns struct {
+		result1 eventio.Invoice
+		result2 error
+	}
+	getInvoiceReturnsOnCall map[int]struct {
+		result1 eventio.Invoice
+		result2 error
+	}
+	GetInvoicesStub        func(eventio.EventFilter) ([]eventio.Invoice, error)
+	getInvoicesMutex       sync.RWMutex
+	getInvoicesArgsForCall []struct {
+		arg1 eventio.EventFilter
+	}
+	getInvoicesReturns struct {
+		result1 []eventio.Invoice
+		result2 error
+	}
+	getInvoicesReturnsOnCall map[int]struct {
+		result1 []eventio.Invoice
+		result2 error
+	}
+	GetMonthEndProjectionsStub        func(eventio.ProjectionFilter) ([]eventio.Projection, error)
+	getMonthEndProjectionsMutex       sync.RWMutex
+	getMonthEndProjectionsArgsForCall []struct {
+		arg1 eventio.ProjectionFilter
+	}
+	getMonthEndProjectionsReturns struct {
+		result1 []eventio.Projection
+		result2 error
+	}
+	getMonthEndProjectionsReturnsOnCall map[int]struct {
+		result1 []eventio.Projection
+		result2 error
+	}
+	GetOrgTaxTreatmentVersionsStub        func(string) ([]eventio.OrgTaxTreatmentVersion, error)
+	getOrgTaxTreatmentVersionsMutex       sync.RWMutex
+	getOrgTaxTreatmentVersionsArgsForCall []struct {
+		arg1 string
+	}
+	getOrgTaxTreatmentVersionsReturns struct {
+		result1 []eventio.OrgTaxTreatmentVersion
+		result2 error
+	}
+	getOrgTaxTreatmentVersionsReturnsOnCall map[int]struct {
+		result1 []eventio.OrgTaxTreatmentVersion
+		result2 error
+	}
+	GetPricingPlanVersionsStub        func(string) ([]eventio.PricingPlanVersion, error)
+	getPricingPlanVersionsMutex       sync.RWMutex
+	getPricingPlanVersionsArgsForCall []struct {
+		arg1 string
+	}
+	getPricingPlanVersionsReturns struct {
+		result1 []eventio.PricingPlanVersion
+		result2 error
+	}
+	getPricingPlanVersionsReturnsOnCall map[int]struct {
+		result1 []eventio.PricingPlanVersion
+		result2 error
+	}
 	GetPricingPlansStub        func(eventio.TimeRangeFilter) ([]eventio.PricingPlan, error)
 	getPricingPlansMutex       sync.RWMutex
 	getPricingPlansArgsForCall []struct {
@@ -176,6 +492,18 @@ type FakeEventStore struct {
 		result1 []eventio.TotalCost
 		result2 error
 	}
+	GetUnnotifiedAnomaliesStub        func() ([]eventio.Anomaly, error)
+	getUnnotifiedAnomaliesMutex       sync.RWMutex
+	getUnnotifiedAnomaliesArgsForCall []struct {
+	}
+	getUnnotifiedAnomaliesReturns struct {
+		result1 []eventio.Anomaly
+		result2 error
+	}
+	getUnnotifiedAnomaliesReturnsOnCall map[int]struct {
+		result1 []eventio.Anomaly
+		result2 error
+	}
 	GetUsageEventRowsStub        func(eventio.EventFilter) (eventio.UsageEventRows, error)
 	getUsageEventRowsMutex       sync.RWMutex
 	getUsageEventRowsArgsForCall []struct {
@@ -202,6 +530,19 @@ type FakeEventStore struct {
 		result1 []eventio.UsageEvent
 		result2 error
 	}
+	GetVATRateVersionsStub        func(string) ([]eventio.VATRateVersion, error)
+	getVATRateVersionsMutex       sync.RWMutex
+	getVATRateVersionsArgsForCall []struct {
+		arg1 string
+	}
+	getVATRateVersionsReturns struct {
+		result1 []eventio.VATRateVersion
+		result2 error
+	}
+	getVATRateVersionsReturnsOnCall map[int]struct {
+		result1 []eventio.VATRateVersion
+		result2 error
+	}
 	GetVATRatesStub        func(eventio.TimeRangeFilter) ([]eventio.VATRate, error)
 	getVATRatesMutex       sync.RWMutex
 	getVATRatesArgsForCall []struct {
@@ -215,6 +556,31 @@ type FakeEventStore struct {
 		result1 []eventio.VATRate
 		result2 error
 	}
+	GetWebhookDeliveriesStub        func(eventio.WebhookDeliveryFilter) ([]eventio.WebhookDelivery, error)
+	getWebhookDeliveriesMutex       sync.RWMutex
+	getWebhookDeliveriesArgsForCall []struct {
+		arg1 eventio.WebhookDeliveryFilter
+	}
+	getWebhookDeliveriesReturns struct {
+		result1 []eventio.WebhookDelivery
+		result2 error
+	}
+	getWebhookDeliveriesReturnsOnCall map[int]struct {
+		result1 []eventio.WebhookDelivery
+		result2 error
+	}
+	GetWebhooksStub        func() ([]eventio.Webhook, error)
+	getWebhooksMutex       sync.RWMutex
+	getWebhooksArgsForCall []struct {
+	}
+	getWebhooksReturns struct {
+		result1 []eventio.Webhook
+		result2 error
+	}
+	getWebhooksReturnsOnCall map[int]struct {
+		result1 []eventio.Webhook
+		result2 error
+	}
 	InitStub        func() error
 	initMutex       sync.RWMutex
 	initArgsForCall []struct {
@@ -238,6 +604,42 @@ type FakeEventStore struct {
 		result1 bool
 		result2 error
 	}
+	IssueInvoiceStub        func(int64, string) (eventio.Invoice, error)
+	issueInvoiceMutex       sync.RWMutex
+	issueInvoiceArgsForCall []struct {
+		arg1 int64
+		arg2 string
+	}
+	issueInvoiceReturns struct {
+		result1 eventio.Invoice
+		result2 error
+	}
+	issueInvoiceReturnsOnCall map[int]struct {
+		result1 eventio.Invoice
+		result2 error
+	}
+	RebuildStub        func() error
+	rebuildMutex       sync.RWMutex
+	rebuildArgsForCall []struct {
+	}
+	rebuildReturns struct {
+		result1 error
+	}
+	rebuildReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RecordWebhookAttemptStub        func(int64, eventio.WebhookAttempt) error
+	recordWebhookAttemptMutex       sync.RWMutex
+	recordWebhookAttemptArgsForCall []struct {
+		arg1 int64
+		arg2 eventio.WebhookAttempt
+	}
+	recordWebhookAttemptReturns struct {
+		result1 error
+	}
+	recordWebhookAttemptReturnsOnCall map[int]struct {
+		result1 error
+	}
 	RefreshStub        func() error
 	refreshMutex       sync.RWMutex
 	refreshArgsForCall []struct {
@@ -248,6 +650,55 @@ type FakeEventStore struct {
 	refreshReturnsOnCall map[int]struct {
 		result1 error
 	}
+	RetryWebhookDeliveryStub        func(int64, int64) (eventio.WebhookDelivery, error)
+	retryWebhookDeliveryMutex       sync.RWMutex
+	retryWebhookDeliveryArgsForCall []struct {
+		arg1 int64
+		arg2 int64
+	}
+	retryWebhookDeliveryReturns struct {
+		result1 eventio.WebhookDelivery
+		result2 error
+	}
+	retryWebhookDeliveryReturnsOnCall map[int]struct {
+		result1 eventio.WebhookDelivery
+		result2 error
+	}
+	SetAnomalyNotifiedStub        func(int64) error
+	setAnomalyNotifiedMutex       sync.RWMutex
+	setAnomalyNotifiedArgsForCall []struct {
+		arg1 int64
+	}
+	setAnomalyNotifiedReturns struct {
+		result1 error
+	}
+	setAnomalyNotifiedReturnsOnCall map[int]struct {
+		result1 error
+	}
+	SetBudgetStub        func(eventio.Budget) (eventio.Budget, error)
+	setBudgetMutex       sync.RWMutex
+	setBudgetArgsForCall []struct {
+		arg1 eventio.Budget
+	}
+	setBudgetReturns struct {
+		result1 eventio.Budget
+		result2 error
+	}
+	setBudgetReturnsOnCall map[int]struct {
+		result1 eventio.Budget
+		result2 error
+	}
+	SetBudgetAlertSentStub        func(int64) error
+	setBudgetAlertSentMutex       sync.RWMutex
+	setBudgetAlertSentArgsForCall []struct {
+		arg1 int64
+	}
+	setBudgetAlertSentReturns struct {
+		result1 error
+	}
+	setBudgetAlertSentReturnsOnCall map[int]struct {
+		result1 error
+	}
 	StoreEventsStub        func([]eventio.RawEvent) error
 	storeEventsMutex       sync.RWMutex
 	storeEventsArgsForCall []struct {
@@ -263,73 +714,393 @@ type FakeEventStore struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeEventStore) Consolidate(arg1 eventio.EventFilter) error {
-	fake.consolidateMutex.Lock()
-	ret, specificReturn := fake.consolidateReturnsOnCall[len(fake.consolidateArgsForCall)]
-	fake.consolidateArgsForCall = append(fake.consolidateArgsForCall, struct {
-		arg1 eventio.EventFilter
-	}{arg1})
-	fake.recordInvocation("Consolidate", []interface{}{arg1})
-	fake.consolidateMutex.Unlock()
-	if fake.ConsolidateStub != nil {
-		return fake.ConsolidateStub(arg1)
+func (fake *FakeEventStore) AddCurrencyRateVersion(arg1 eventio.CurrencyRate, arg2 string) (eventio.CurrencyRateVersion, error) {
+	fake.addCurrencyRateVersionMutex.Lock()
+	ret, specificReturn := fake.addCurrencyRateVersionReturnsOnCall[len(fake.addCurrencyRateVersionArgsForCall)]
+	fake.addCurrencyRateVersionArgsForCall = append(fake.addCurrencyRateVersionArgsForCall, struct {
+		arg1 eventio.CurrencyRate
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("AddCurrencyRateVersion", []interface{}{arg1, arg2})
+	fake.addCurrencyRateVersionMutex.Unlock()
+	if fake.AddCurrencyRateVersionStub != nil {
+		return fake.AddCurrencyRateVersionStub(arg1, arg2)
 	}
 	if specificReturn {
-		return ret.result1
+		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.consolidateReturns
-	return fakeReturns.result1
+	fakeReturns := fake.addCurrencyRateVersionReturns
+	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *FakeEventStore) ConsolidateCallCount() int {
-	fake.consolidateMutex.RLock()
-	defer fake.consolidateMutex.RUnlock()
-	return len(fake.consolidateArgsForCall)
+func (fake *FakeEventStore) AddCurrencyRateVersionCallCount() int {
+	fake.addCurrencyRateVersionMutex.RLock()
+	defer fake.addCurrencyRateVersionMutex.RUnlock()
+	return len(fake.addCurrencyRateVersionArgsForCall)
 }
 
-func (fake *FakeEventStore) ConsolidateCalls(stub func(eventio.EventFilter) error) {
-	fake.consolidateMutex.Lock()
-	defer fake.consolidateMutex.Unlock()
-	fake.ConsolidateStub = stub
+func (fake *FakeEventStore) AddCurrencyRateVersionCalls(stub func(eventio.CurrencyRate, string) (eventio.CurrencyRateVersion, error)) {
+	fake.addCurrencyRateVersionMutex.Lock()
+	defer fake.addCurrencyRateVersionMutex.Unlock()
+	fake.AddCurrencyRateVersionStub = stub
 }
 
-func (fake *FakeEventStore) ConsolidateArgsForCall(i int) eventio.EventFilter {
-	fake.consolidateMutex.RLock()
-	defer fake.consolidateMutex.RUnlock()
-	argsForCall := fake.consolidateArgsForCall[i]
-	return argsForCall.arg1
+func (fake *FakeEventStore) AddCurrencyRateVersionArgsForCall(i int) (eventio.CurrencyRate, string) {
+	fake.addCurrencyRateVersionMutex.RLock()
+	defer fake.addCurrencyRateVersionMutex.RUnlock()
+	argsForCall := fake.addCurrencyRateVersionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
 }
 
-func (fake *FakeEventStore) ConsolidateReturns(result1 error) {
-	fake.consolidateMutex.Lock()
-	defer fake.consolidateMutex.Unlock()
-	fake.ConsolidateStub = nil
-	fake.consolidateReturns = struct {
-		result1 error
-	}{result1}
+func (fake *FakeEventStore) AddCurrencyRateVersionReturns(result1 eventio.CurrencyRateVersion, result2 error) {
+	fake.addCurrencyRateVersionMutex.Lock()
+	defer fake.addCurrencyRateVersionMutex.Unlock()
+	fake.AddCurrencyRateVersionStub = nil
+	fake.addCurrencyRateVersionReturns = struct {
+		result1 eventio.CurrencyRateVersion
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakeEventStore) ConsolidateReturnsOnCall(i int, result1 error) {
-	fake.consolidateMutex.Lock()
-	defer fake.consolidateMutex.Unlock()
-	fake.ConsolidateStub = nil
-	if fake.consolidateReturnsOnCall == nil {
-		fake.consolidateReturnsOnCall = make(map[int]struct {
-			result1 error
+func (fake *FakeEventStore) AddCurrencyRateVersionReturnsOnCall(i int, result1 eventio.CurrencyRateVersion, result2 error) {
+	fake.addCurrencyRateVersionMutex.Lock()
+	defer fake.addCurrencyRateVersionMutex.Unlock()
+	fake.AddCurrencyRateVersionStub = nil
+	if fake.addCurrencyRateVersionReturnsOnCall == nil {
+		fake.addCurrencyRateVersionReturnsOnCall = make(map[int]struct {
+			result1 eventio.CurrencyRateVersion
+			result2 error
 		})
 	}
-	fake.consolidateReturnsOnCall[i] = struct {
-		result1 error
-	}{result1}
+	fake.addCurrencyRateVersionReturnsOnCall[i] = struct {
+		result1 eventio.CurrencyRateVersion
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakeEventStore) ConsolidateAll() error {
-	fake.consolidateAllMutex.Lock()
-	ret, specificReturn := fake.consolidateAllReturnsOnCall[len(fake.consolidateAllArgsForCall)]
-	fake.consolidateAllArgsForCall = append(fake.consolidateAllArgsForCall, struct {
-	}{})
-	fake.recordInvocation("ConsolidateAll", []interface{}{})
-	fake.consolidateAllMutex.Unlock()
+func (fake *FakeEventStore) AddOrgTaxTreatmentVersion(arg1 eventio.OrgTaxTreatment, arg2 string) (eventio.OrgTaxTreatmentVersion, error) {
+	fake.addOrgTaxTreatmentVersionMutex.Lock()
+	ret, specificReturn := fake.addOrgTaxTreatmentVersionReturnsOnCall[len(fake.addOrgTaxTreatmentVersionArgsForCall)]
+	fake.addOrgTaxTreatmentVersionArgsForCall = append(fake.addOrgTaxTreatmentVersionArgsForCall, struct {
+		arg1 eventio.OrgTaxTreatment
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("AddOrgTaxTreatmentVersion", []interface{}{arg1, arg2})
+	fake.addOrgTaxTreatmentVersionMutex.Unlock()
+	if fake.AddOrgTaxTreatmentVersionStub != nil {
+		return fake.AddOrgTaxTreatmentVersionStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.addOrgTaxTreatmentVersionReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) AddOrgTaxTreatmentVersionCallCount() int {
+	fake.addOrgTaxTreatmentVersionMutex.RLock()
+	defer fake.addOrgTaxTreatmentVersionMutex.RUnlock()
+	return len(fake.addOrgTaxTreatmentVersionArgsForCall)
+}
+
+func (fake *FakeEventStore) AddOrgTaxTreatmentVersionCalls(stub func(eventio.OrgTaxTreatment, string) (eventio.OrgTaxTreatmentVersion, error)) {
+	fake.addOrgTaxTreatmentVersionMutex.Lock()
+	defer fake.addOrgTaxTreatmentVersionMutex.Unlock()
+	fake.AddOrgTaxTreatmentVersionStub = stub
+}
+
+func (fake *FakeEventStore) AddOrgTaxTreatmentVersionArgsForCall(i int) (eventio.OrgTaxTreatment, string) {
+	fake.addOrgTaxTreatmentVersionMutex.RLock()
+	defer fake.addOrgTaxTreatmentVersionMutex.RUnlock()
+	argsForCall := fake.addOrgTaxTreatmentVersionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) AddOrgTaxTreatmentVersionReturns(result1 eventio.OrgTaxTreatmentVersion, result2 error) {
+	fake.addOrgTaxTreatmentVersionMutex.Lock()
+	defer fake.addOrgTaxTreatmentVersionMutex.Unlock()
+	fake.AddOrgTaxTreatmentVersionStub = nil
+	fake.addOrgTaxTreatmentVersionReturns = struct {
+		result1 eventio.OrgTaxTreatmentVersion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) AddOrgTaxTreatmentVersionReturnsOnCall(i int, result1 eventio.OrgTaxTreatmentVersion, result2 error) {
+	fake.addOrgTaxTreatmentVersionMutex.Lock()
+	defer fake.addOrgTaxTreatmentVersionMutex.Unlock()
+	fake.AddOrgTaxTreatmentVersionStub = nil
+	if fake.addOrgTaxTreatmentVersionReturnsOnCall == nil {
+		fake.addOrgTaxTreatmentVersionReturnsOnCall = make(map[int]struct {
+			result1 eventio.OrgTaxTreatmentVersion
+			result2 error
+		})
+	}
+	fake.addOrgTaxTreatmentVersionReturnsOnCall[i] = struct {
+		result1 eventio.OrgTaxTreatmentVersion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) AddPricingPlanVersion(arg1 eventio.PricingPlan, arg2 string) (eventio.PricingPlanVersion, error) {
+	fake.addPricingPlanVersionMutex.Lock()
+	ret, specificReturn := fake.addPricingPlanVersionReturnsOnCall[len(fake.addPricingPlanVersionArgsForCall)]
+	fake.addPricingPlanVersionArgsForCall = append(fake.addPricingPlanVersionArgsForCall, struct {
+		arg1 eventio.PricingPlan
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("AddPricingPlanVersion", []interface{}{arg1, arg2})
+	fake.addPricingPlanVersionMutex.Unlock()
+	if fake.AddPricingPlanVersionStub != nil {
+		return fake.AddPricingPlanVersionStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.addPricingPlanVersionReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) AddPricingPlanVersionCallCount() int {
+	fake.addPricingPlanVersionMutex.RLock()
+	defer fake.addPricingPlanVersionMutex.RUnlock()
+	return len(fake.addPricingPlanVersionArgsForCall)
+}
+
+func (fake *FakeEventStore) AddPricingPlanVersionCalls(stub func(eventio.PricingPlan, string) (eventio.PricingPlanVersion, error)) {
+	fake.addPricingPlanVersionMutex.Lock()
+	defer fake.addPricingPlanVersionMutex.Unlock()
+	fake.AddPricingPlanVersionStub = stub
+}
+
+func (fake *FakeEventStore) AddPricingPlanVersionArgsForCall(i int) (eventio.PricingPlan, string) {
+	fake.addPricingPlanVersionMutex.RLock()
+	defer fake.addPricingPlanVersionMutex.RUnlock()
+	argsForCall := fake.addPricingPlanVersionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) AddPricingPlanVersionReturns(result1 eventio.PricingPlanVersion, result2 error) {
+	fake.addPricingPlanVersionMutex.Lock()
+	defer fake.addPricingPlanVersionMutex.Unlock()
+	fake.AddPricingPlanVersionStub = nil
+	fake.addPricingPlanVersionReturns = struct {
+		result1 eventio.PricingPlanVersion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) AddPricingPlanVersionReturnsOnCall(i int, result1 eventio.PricingPlanVersion, result2 error) {
+	fake.addPricingPlanVersionMutex.Lock()
+	defer fake.addPricingPlanVersionMutex.Unlock()
+	fake.AddPricingPlanVersionStub = nil
+	if fake.addPricingPlanVersionReturnsOnCall == nil {
+		fake.addPricingPlanVersionReturnsOnCall = make(map[int]struct {
+			result1 eventio.PricingPlanVersion
+			result2 error
+		})
+	}
+	fake.addPricingPlanVersionReturnsOnCall[i] = struct {
+		result1 eventio.PricingPlanVersion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) AddVATRateVersion(arg1 eventio.VATRate, arg2 string) (eventio.VATRateVersion, error) {
+	fake.addVATRateVersionMutex.Lock()
+	ret, specificReturn := fake.addVATRateVersionReturnsOnCall[len(fake.addVATRateVersionArgsForCall)]
+	fake.addVATRateVersionArgsForCall = append(fake.addVATRateVersionArgsForCall, struct {
+		arg1 eventio.VATRate
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("AddVATRateVersion", []interface{}{arg1, arg2})
+	fake.addVATRateVersionMutex.Unlock()
+	if fake.AddVATRateVersionStub != nil {
+		return fake.AddVATRateVersionStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.addVATRateVersionReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) AddVATRateVersionCallCount() int {
+	fake.addVATRateVersionMutex.RLock()
+	defer fake.addVATRateVersionMutex.RUnlock()
+	return len(fake.addVATRateVersionArgsForCall)
+}
+
+func (fake *FakeEventStore) AddVATRateVersionCalls(stub func(eventio.VATRate, string) (eventio.VATRateVersion, error)) {
+	fake.addVATRateVersionMutex.Lock()
+	defer fake.addVATRateVersionMutex.Unlock()
+	fake.AddVATRateVersionStub = stub
+}
+
+func (fake *FakeEventStore) AddVATRateVersionArgsForCall(i int) (eventio.VATRate, string) {
+	fake.addVATRateVersionMutex.RLock()
+	defer fake.addVATRateVersionMutex.RUnlock()
+	argsForCall := fake.addVATRateVersionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) AddVATRateVersionReturns(result1 eventio.VATRateVersion, result2 error) {
+	fake.addVATRateVersionMutex.Lock()
+	defer fake.addVATRateVersionMutex.Unlock()
+	fake.AddVATRateVersionStub = nil
+	fake.addVATRateVersionReturns = struct {
+		result1 eventio.VATRateVersion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) AddVATRateVersionReturnsOnCall(i int, result1 eventio.VATRateVersion, result2 error) {
+	fake.addVATRateVersionMutex.Lock()
+	defer fake.addVATRateVersionMutex.Unlock()
+	fake.AddVATRateVersionStub = nil
+	if fake.addVATRateVersionReturnsOnCall == nil {
+		fake.addVATRateVersionReturnsOnCall = make(map[int]struct {
+			result1 eventio.VATRateVersion
+			result2 error
+		})
+	}
+	fake.addVATRateVersionReturnsOnCall[i] = struct {
+		result1 eventio.VATRateVersion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) AddWebhook(arg1 eventio.Webhook, arg2 string) (eventio.Webhook, error) {
+	fake.addWebhookMutex.Lock()
+	ret, specificReturn := fake.addWebhookReturnsOnCall[len(fake.addWebhookArgsForCall)]
+	fake.addWebhookArgsForCall = append(fake.addWebhookArgsForCall, struct {
+		arg1 eventio.Webhook
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("AddWebhook", []interface{}{arg1, arg2})
+	fake.addWebhookMutex.Unlock()
+	if fake.AddWebhookStub != nil {
+		return fake.AddWebhookStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.addWebhookReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) AddWebhookCallCount() int {
+	fake.addWebhookMutex.RLock()
+	defer fake.addWebhookMutex.RUnlock()
+	return len(fake.addWebhookArgsForCall)
+}
+
+func (fake *FakeEventStore) AddWebhookCalls(stub func(eventio.Webhook, string) (eventio.Webhook, error)) {
+	fake.addWebhookMutex.Lock()
+	defer fake.addWebhookMutex.Unlock()
+	fake.AddWebhookStub = stub
+}
+
+func (fake *FakeEventStore) AddWebhookArgsForCall(i int) (eventio.Webhook, string) {
+	fake.addWebhookMutex.RLock()
+	defer fake.addWebhookMutex.RUnlock()
+	argsForCall := fake.addWebhookArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) AddWebhookReturns(result1 eventio.Webhook, result2 error) {
+	fake.addWebhookMutex.Lock()
+	defer fake.addWebhookMutex.Unlock()
+	fake.AddWebhookStub = nil
+	fake.addWebhookReturns = struct {
+		result1 eventio.Webhook
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) AddWebhookReturnsOnCall(i int, result1 eventio.Webhook, result2 error) {
+	fake.addWebhookMutex.Lock()
+	defer fake.addWebhookMutex.Unlock()
+	fake.AddWebhookStub = nil
+	if fake.addWebhookReturnsOnCall == nil {
+		fake.addWebhookReturnsOnCall = make(map[int]struct {
+			result1 eventio.Webhook
+			result2 error
+		})
+	}
+	fake.addWebhookReturnsOnCall[i] = struct {
+		result1 eventio.Webhook
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) Consolidate(arg1 eventio.EventFilter) error {
+	fake.consolidateMutex.Lock()
+	ret, specificReturn := fake.consolidateReturnsOnCall[len(fake.consolidateArgsForCall)]
+	fake.consolidateArgsForCall = append(fake.consolidateArgsForCall, struct {
+		arg1 eventio.EventFilter
+	}{arg1})
+	fake.recordInvocation("Consolidate", []interface{}{arg1})
+	fake.consolidateMutex.Unlock()
+	if fake.ConsolidateStub != nil {
+		return fake.ConsolidateStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.consolidateReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeEventStore) ConsolidateCallCount() int {
+	fake.consolidateMutex.RLock()
+	defer fake.consolidateMutex.RUnlock()
+	return len(fake.consolidateArgsForCall)
+}
+
+func (fake *FakeEventStore) ConsolidateCalls(stub func(eventio.EventFilter) error) {
+	fake.consolidateMutex.Lock()
+	defer fake.consolidateMutex.Unlock()
+	fake.ConsolidateStub = stub
+}
+
+func (fake *FakeEventStore) ConsolidateArgsForCall(i int) eventio.EventFilter {
+	fake.consolidateMutex.RLock()
+	defer fake.consolidateMutex.RUnlock()
+	argsForCall := fake.consolidateArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) ConsolidateReturns(result1 error) {
+	fake.consolidateMutex.Lock()
+	defer fake.consolidateMutex.Unlock()
+	fake.ConsolidateStub = nil
+	fake.consolidateReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) ConsolidateReturnsOnCall(i int, result1 error) {
+	fake.consolidateMutex.Lock()
+	defer fake.consolidateMutex.Unlock()
+	fake.ConsolidateStub = nil
+	if fake.consolidateReturnsOnCall == nil {
+		fake.consolidateReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.consolidateReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) ConsolidateAll() error {
+	fake.consolidateAllMutex.Lock()
+	ret, specificReturn := fake.consolidateAllReturnsOnCall[len(fake.consolidateAllArgsForCall)]
+	fake.consolidateAllArgsForCall = append(fake.consolidateAllArgsForCall, struct {
+	}{})
+	fake.recordInvocation("ConsolidateAll", []interface{}{})
+	fake.consolidateAllMutex.Unlock()
 	if fake.ConsolidateAllStub != nil {
 		return fake.ConsolidateAllStub()
 	}
@@ -390,52 +1161,414 @@ func (fake *FakeEventStore) ConsolidateFullMonths(arg1 string, arg2 string) erro
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.consolidateFullMonthsReturns
+	fakeReturns := fake.consolidateFullMonthsReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeEventStore) ConsolidateFullMonthsCallCount() int {
+	fake.consolidateFullMonthsMutex.RLock()
+	defer fake.consolidateFullMonthsMutex.RUnlock()
+	return len(fake.consolidateFullMonthsArgsForCall)
+}
+
+func (fake *FakeEventStore) ConsolidateFullMonthsCalls(stub func(string, string) error) {
+	fake.consolidateFullMonthsMutex.Lock()
+	defer fake.consolidateFullMonthsMutex.Unlock()
+	fake.ConsolidateFullMonthsStub = stub
+}
+
+func (fake *FakeEventStore) ConsolidateFullMonthsArgsForCall(i int) (string, string) {
+	fake.consolidateFullMonthsMutex.RLock()
+	defer fake.consolidateFullMonthsMutex.RUnlock()
+	argsForCall := fake.consolidateFullMonthsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) ConsolidateFullMonthsReturns(result1 error) {
+	fake.consolidateFullMonthsMutex.Lock()
+	defer fake.consolidateFullMonthsMutex.Unlock()
+	fake.ConsolidateFullMonthsStub = nil
+	fake.consolidateFullMonthsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) ConsolidateFullMonthsReturnsOnCall(i int, result1 error) {
+	fake.consolidateFullMonthsMutex.Lock()
+	defer fake.consolidateFullMonthsMutex.Unlock()
+	fake.ConsolidateFullMonthsStub = nil
+	if fake.consolidateFullMonthsReturnsOnCall == nil {
+		fake.consolidateFullMonthsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.consolidateFullMonthsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) CreditInvoice(arg1 int64, arg2 string) (eventio.Invoice, error) {
+	fake.creditInvoiceMutex.Lock()
+	ret, specificReturn := fake.creditInvoiceReturnsOnCall[len(fake.creditInvoiceArgsForCall)]
+	fake.creditInvoiceArgsForCall = append(fake.creditInvoiceArgsForCall, struct {
+		arg1 int64
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("CreditInvoice", []interface{}{arg1, arg2})
+	fake.creditInvoiceMutex.Unlock()
+	if fake.CreditInvoiceStub != nil {
+		return fake.CreditInvoiceStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.creditInvoiceReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) CreditInvoiceCallCount() int {
+	fake.creditInvoiceMutex.RLock()
+	defer fake.creditInvoiceMutex.RUnlock()
+	return len(fake.creditInvoiceArgsForCall)
+}
+
+func (fake *FakeEventStore) CreditInvoiceCalls(stub func(int64, string) (eventio.Invoice, error)) {
+	fake.creditInvoiceMutex.Lock()
+	defer fake.creditInvoiceMutex.Unlock()
+	fake.CreditInvoiceStub = stub
+}
+
+func (fake *FakeEventStore) CreditInvoiceArgsForCall(i int) (int64, string) {
+	fake.creditInvoiceMutex.RLock()
+	defer fake.creditInvoiceMutex.RUnlock()
+	argsForCall := fake.creditInvoiceArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) CreditInvoiceReturns(result1 eventio.Invoice, result2 error) {
+	fake.creditInvoiceMutex.Lock()
+	defer fake.creditInvoiceMutex.Unlock()
+	fake.CreditInvoiceStub = nil
+	fake.creditInvoiceReturns = struct {
+		result1 eventio.Invoice
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) CreditInvoiceReturnsOnCall(i int, result1 eventio.Invoice, result2 error) {
+	fake.creditInvoiceMutex.Lock()
+	defer fake.creditInvoiceMutex.Unlock()
+	fake.CreditInvoiceStub = nil
+	if fake.creditInvoiceReturnsOnCall == nil {
+		fake.creditInvoiceReturnsOnCall = make(map[int]struct {
+			result1 eventio.Invoice
+			result2 error
+		})
+	}
+	fake.creditInvoiceReturnsOnCall[i] = struct {
+		result1 eventio.Invoice
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) DeleteBudget(arg1 string) error {
+	fake.deleteBudgetMutex.Lock()
+	ret, specificReturn := fake.deleteBudgetReturnsOnCall[len(fake.deleteBudgetArgsForCall)]
+	fake.deleteBudgetArgsForCall = append(fake.deleteBudgetArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("DeleteBudget", []interface{}{arg1})
+	fake.deleteBudgetMutex.Unlock()
+	if fake.DeleteBudgetStub != nil {
+		return fake.DeleteBudgetStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.deleteBudgetReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeEventStore) DeleteBudgetCallCount() int {
+	fake.deleteBudgetMutex.RLock()
+	defer fake.deleteBudgetMutex.RUnlock()
+	return len(fake.deleteBudgetArgsForCall)
+}
+
+func (fake *FakeEventStore) DeleteBudgetCalls(stub func(string) error) {
+	fake.deleteBudgetMutex.Lock()
+	defer fake.deleteBudgetMutex.Unlock()
+	fake.DeleteBudgetStub = stub
+}
+
+func (fake *FakeEventStore) DeleteBudgetArgsForCall(i int) string {
+	fake.deleteBudgetMutex.RLock()
+	defer fake.deleteBudgetMutex.RUnlock()
+	argsForCall := fake.deleteBudgetArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) DeleteBudgetReturns(result1 error) {
+	fake.deleteBudgetMutex.Lock()
+	defer fake.deleteBudgetMutex.Unlock()
+	fake.DeleteBudgetStub = nil
+	fake.deleteBudgetReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) DeleteBudgetReturnsOnCall(i int, result1 error) {
+	fake.deleteBudgetMutex.Lock()
+	defer fake.deleteBudgetMutex.Unlock()
+	fake.DeleteBudgetStub = nil
+	if fake.deleteBudgetReturnsOnCall == nil {
+		fake.deleteBudgetReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteBudgetReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) DeleteWebhook(arg1 int64) error {
+	fake.deleteWebhookMutex.Lock()
+	ret, specificReturn := fake.deleteWebhookReturnsOnCall[len(fake.deleteWebhookArgsForCall)]
+	fake.deleteWebhookArgsForCall = append(fake.deleteWebhookArgsForCall, struct {
+		arg1 int64
+	}{arg1})
+	fake.recordInvocation("DeleteWebhook", []interface{}{arg1})
+	fake.deleteWebhookMutex.Unlock()
+	if fake.DeleteWebhookStub != nil {
+		return fake.DeleteWebhookStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.deleteWebhookReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeEventStore) DeleteWebhookCallCount() int {
+	fake.deleteWebhookMutex.RLock()
+	defer fake.deleteWebhookMutex.RUnlock()
+	return len(fake.deleteWebhookArgsForCall)
+}
+
+func (fake *FakeEventStore) DeleteWebhookCalls(stub func(int64) error) {
+	fake.deleteWebhookMutex.Lock()
+	defer fake.deleteWebhookMutex.Unlock()
+	fake.DeleteWebhookStub = stub
+}
+
+func (fake *FakeEventStore) DeleteWebhookArgsForCall(i int) int64 {
+	fake.deleteWebhookMutex.RLock()
+	defer fake.deleteWebhookMutex.RUnlock()
+	argsForCall := fake.deleteWebhookArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) DeleteWebhookReturns(result1 error) {
+	fake.deleteWebhookMutex.Lock()
+	defer fake.deleteWebhookMutex.Unlock()
+	fake.DeleteWebhookStub = nil
+	fake.deleteWebhookReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) DeleteWebhookReturnsOnCall(i int, result1 error) {
+	fake.deleteWebhookMutex.Lock()
+	defer fake.deleteWebhookMutex.Unlock()
+	fake.DeleteWebhookStub = nil
+	if fake.deleteWebhookReturnsOnCall == nil {
+		fake.deleteWebhookReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteWebhookReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) DetectAnomalies(arg1 eventio.AnomalyConfig) ([]eventio.Anomaly, error) {
+	fake.detectAnomaliesMutex.Lock()
+	ret, specificReturn := fake.detectAnomaliesReturnsOnCall[len(fake.detectAnomaliesArgsForCall)]
+	fake.detectAnomaliesArgsForCall = append(fake.detectAnomaliesArgsForCall, struct {
+		arg1 eventio.AnomalyConfig
+	}{arg1})
+	fake.recordInvocation("DetectAnomalies", []interface{}{arg1})
+	fake.detectAnomaliesMutex.Unlock()
+	if fake.DetectAnomaliesStub != nil {
+		return fake.DetectAnomaliesStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.detectAnomaliesReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) DetectAnomaliesCallCount() int {
+	fake.detectAnomaliesMutex.RLock()
+	defer fake.detectAnomaliesMutex.RUnlock()
+	return len(fake.detectAnomaliesArgsForCall)
+}
+
+func (fake *FakeEventStore) DetectAnomaliesCalls(stub func(eventio.AnomalyConfig) ([]eventio.Anomaly, error)) {
+	fake.detectAnomaliesMutex.Lock()
+	defer fake.detectAnomaliesMutex.Unlock()
+	fake.DetectAnomaliesStub = stub
+}
+
+func (fake *FakeEventStore) DetectAnomaliesArgsForCall(i int) eventio.AnomalyConfig {
+	fake.detectAnomaliesMutex.RLock()
+	defer fake.detectAnomaliesMutex.RUnlock()
+	argsForCall := fake.detectAnomaliesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) DetectAnomaliesReturns(result1 []eventio.Anomaly, result2 error) {
+	fake.detectAnomaliesMutex.Lock()
+	defer fake.detectAnomaliesMutex.Unlock()
+	fake.DetectAnomaliesStub = nil
+	fake.detectAnomaliesReturns = struct {
+		result1 []eventio.Anomaly
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) DetectAnomaliesReturnsOnCall(i int, result1 []eventio.Anomaly, result2 error) {
+	fake.detectAnomaliesMutex.Lock()
+	defer fake.detectAnomaliesMutex.Unlock()
+	fake.DetectAnomaliesStub = nil
+	if fake.detectAnomaliesReturnsOnCall == nil {
+		fake.detectAnomaliesReturnsOnCall = make(map[int]struct {
+			result1 []eventio.Anomaly
+			result2 error
+		})
+	}
+	fake.detectAnomaliesReturnsOnCall[i] = struct {
+		result1 []eventio.Anomaly
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) EmitWebhookEvent(arg1 eventio.WebhookEvent) error {
+	fake.emitWebhookEventMutex.Lock()
+	ret, specificReturn := fake.emitWebhookEventReturnsOnCall[len(fake.emitWebhookEventArgsForCall)]
+	fake.emitWebhookEventArgsForCall = append(fake.emitWebhookEventArgsForCall, struct {
+		arg1 eventio.WebhookEvent
+	}{arg1})
+	fake.recordInvocation("EmitWebhookEvent", []interface{}{arg1})
+	fake.emitWebhookEventMutex.Unlock()
+	if fake.EmitWebhookEventStub != nil {
+		return fake.EmitWebhookEventStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.emitWebhookEventReturns
 	return fakeReturns.result1
 }
 
-func (fake *FakeEventStore) ConsolidateFullMonthsCallCount() int {
-	fake.consolidateFullMonthsMutex.RLock()
-	defer fake.consolidateFullMonthsMutex.RUnlock()
-	return len(fake.consolidateFullMonthsArgsForCall)
+func (fake *FakeEventStore) EmitWebhookEventCallCount() int {
+	fake.emitWebhookEventMutex.RLock()
+	defer fake.emitWebhookEventMutex.RUnlock()
+	return len(fake.emitWebhookEventArgsForCall)
 }
 
-func (fake *FakeEventStore) ConsolidateFullMonthsCalls(stub func(string, string) error) {
-	fake.consolidateFullMonthsMutex.Lock()
-	defer fake.consolidateFullMonthsMutex.Unlock()
-	fake.ConsolidateFullMonthsStub = stub
+func (fake *FakeEventStore) EmitWebhookEventCalls(stub func(eventio.WebhookEvent) error) {
+	fake.emitWebhookEventMutex.Lock()
+	defer fake.emitWebhookEventMutex.Unlock()
+	fake.EmitWebhookEventStub = stub
 }
 
-func (fake *FakeEventStore) ConsolidateFullMonthsArgsForCall(i int) (string, string) {
-	fake.consolidateFullMonthsMutex.RLock()
-	defer fake.consolidateFullMonthsMutex.RUnlock()
-	argsForCall := fake.consolidateFullMonthsArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+func (fake *FakeEventStore) EmitWebhookEventArgsForCall(i int) eventio.WebhookEvent {
+	fake.emitWebhookEventMutex.RLock()
+	defer fake.emitWebhookEventMutex.RUnlock()
+	argsForCall := fake.emitWebhookEventArgsForCall[i]
+	return argsForCall.arg1
 }
 
-func (fake *FakeEventStore) ConsolidateFullMonthsReturns(result1 error) {
-	fake.consolidateFullMonthsMutex.Lock()
-	defer fake.consolidateFullMonthsMutex.Unlock()
-	fake.ConsolidateFullMonthsStub = nil
-	fake.consolidateFullMonthsReturns = struct {
+func (fake *FakeEventStore) EmitWebhookEventReturns(result1 error) {
+	fake.emitWebhookEventMutex.Lock()
+	defer fake.emitWebhookEventMutex.Unlock()
+	fake.EmitWebhookEventStub = nil
+	fake.emitWebhookEventReturns = struct {
 		result1 error
 	}{result1}
 }
 
-func (fake *FakeEventStore) ConsolidateFullMonthsReturnsOnCall(i int, result1 error) {
-	fake.consolidateFullMonthsMutex.Lock()
-	defer fake.consolidateFullMonthsMutex.Unlock()
-	fake.ConsolidateFullMonthsStub = nil
-	if fake.consolidateFullMonthsReturnsOnCall == nil {
-		fake.consolidateFullMonthsReturnsOnCall = make(map[int]struct {
+func (fake *FakeEventStore) EmitWebhookEventReturnsOnCall(i int, result1 error) {
+	fake.emitWebhookEventMutex.Lock()
+	defer fake.emitWebhookEventMutex.Unlock()
+	fake.EmitWebhookEventStub = nil
+	if fake.emitWebhookEventReturnsOnCall == nil {
+		fake.emitWebhookEventReturnsOnCall = make(map[int]struct {
 			result1 error
 		})
 	}
-	fake.consolidateFullMonthsReturnsOnCall[i] = struct {
+	fake.emitWebhookEventReturnsOnCall[i] = struct {
 		result1 error
 	}{result1}
 }
 
+func (fake *FakeEventStore) EvaluateBudgets() ([]eventio.BudgetAlert, error) {
+	fake.evaluateBudgetsMutex.Lock()
+	ret, specificReturn := fake.evaluateBudgetsReturnsOnCall[len(fake.evaluateBudgetsArgsForCall)]
+	fake.evaluateBudgetsArgsForCall = append(fake.evaluateBudgetsArgsForCall, struct {
+	}{})
+	fake.recordInvocation("EvaluateBudgets", []interface{}{})
+	fake.evaluateBudgetsMutex.Unlock()
+	if fake.EvaluateBudgetsStub != nil {
+		return fake.EvaluateBudgetsStub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.evaluateBudgetsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) EvaluateBudgetsCallCount() int {
+	fake.evaluateBudgetsMutex.RLock()
+	defer fake.evaluateBudgetsMutex.RUnlock()
+	return len(fake.evaluateBudgetsArgsForCall)
+}
+
+func (fake *FakeEventStore) EvaluateBudgetsCalls(stub func() ([]eventio.BudgetAlert, error)) {
+	fake.evaluateBudgetsMutex.Lock()
+	defer fake.evaluateBudgetsMutex.Unlock()
+	fake.EvaluateBudgetsStub = stub
+}
+
+func (fake *FakeEventStore) EvaluateBudgetsReturns(result1 []eventio.BudgetAlert, result2 error) {
+	fake.evaluateBudgetsMutex.Lock()
+	defer fake.evaluateBudgetsMutex.Unlock()
+	fake.EvaluateBudgetsStub = nil
+	fake.evaluateBudgetsReturns = struct {
+		result1 []eventio.BudgetAlert
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) EvaluateBudgetsReturnsOnCall(i int, result1 []eventio.BudgetAlert, result2 error) {
+	fake.evaluateBudgetsMutex.Lock()
+	defer fake.evaluateBudgetsMutex.Unlock()
+	fake.EvaluateBudgetsStub = nil
+	if fake.evaluateBudgetsReturnsOnCall == nil {
+		fake.evaluateBudgetsReturnsOnCall = make(map[int]struct {
+			result1 []eventio.BudgetAlert
+			result2 error
+		})
+	}
+	fake.evaluateBudgetsReturnsOnCall[i] = struct {
+		result1 []eventio.BudgetAlert
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEventStore) ForecastBillableEventRows(arg1 context.Context, arg2 []eventio.UsageEvent, arg3 eventio.EventFilter) (eventio.BillableEventRows, error) {
 	var arg2Copy []eventio.UsageEvent
 	if arg2 != nil {
@@ -575,6 +1708,198 @@ func (fake *FakeEventStore) ForecastBillableEventsReturnsOnCall(i int, result1 [
 	}{result1, result2}
 }
 
+func (fake *FakeEventStore) ForecastScenarioBillableEventRows(arg1 context.Context, arg2 eventio.Scenario, arg3 eventio.EventFilter) (eventio.BillableEventRows, error) {
+	fake.forecastScenarioBillableEventRowsMutex.Lock()
+	ret, specificReturn := fake.forecastScenarioBillableEventRowsReturnsOnCall[len(fake.forecastScenarioBillableEventRowsArgsForCall)]
+	fake.forecastScenarioBillableEventRowsArgsForCall = append(fake.forecastScenarioBillableEventRowsArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.Scenario
+		arg3 eventio.EventFilter
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("ForecastScenarioBillableEventRows", []interface{}{arg1, arg2, arg3})
+	fake.forecastScenarioBillableEventRowsMutex.Unlock()
+	if fake.ForecastScenarioBillableEventRowsStub != nil {
+		return fake.ForecastScenarioBillableEventRowsStub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.forecastScenarioBillableEventRowsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) ForecastScenarioBillableEventRowsCallCount() int {
+	fake.forecastScenarioBillableEventRowsMutex.RLock()
+	defer fake.forecastScenarioBillableEventRowsMutex.RUnlock()
+	return len(fake.forecastScenarioBillableEventRowsArgsForCall)
+}
+
+func (fake *FakeEventStore) ForecastScenarioBillableEventRowsCalls(stub func(context.Context, eventio.Scenario, eventio.EventFilter) (eventio.BillableEventRows, error)) {
+	fake.forecastScenarioBillableEventRowsMutex.Lock()
+	defer fake.forecastScenarioBillableEventRowsMutex.Unlock()
+	fake.ForecastScenarioBillableEventRowsStub = stub
+}
+
+func (fake *FakeEventStore) ForecastScenarioBillableEventRowsArgsForCall(i int) (context.Context, eventio.Scenario, eventio.EventFilter) {
+	fake.forecastScenarioBillableEventRowsMutex.RLock()
+	defer fake.forecastScenarioBillableEventRowsMutex.RUnlock()
+	argsForCall := fake.forecastScenarioBillableEventRowsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeEventStore) ForecastScenarioBillableEventRowsReturns(result1 eventio.BillableEventRows, result2 error) {
+	fake.forecastScenarioBillableEventRowsMutex.Lock()
+	defer fake.forecastScenarioBillableEventRowsMutex.Unlock()
+	fake.ForecastScenarioBillableEventRowsStub = nil
+	fake.forecastScenarioBillableEventRowsReturns = struct {
+		result1 eventio.BillableEventRows
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) ForecastScenarioBillableEventRowsReturnsOnCall(i int, result1 eventio.BillableEventRows, result2 error) {
+	fake.forecastScenarioBillableEventRowsMutex.Lock()
+	defer fake.forecastScenarioBillableEventRowsMutex.Unlock()
+	fake.ForecastScenarioBillableEventRowsStub = nil
+	if fake.forecastScenarioBillableEventRowsReturnsOnCall == nil {
+		fake.forecastScenarioBillableEventRowsReturnsOnCall = make(map[int]struct {
+			result1 eventio.BillableEventRows
+			result2 error
+		})
+	}
+	fake.forecastScenarioBillableEventRowsReturnsOnCall[i] = struct {
+		result1 eventio.BillableEventRows
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) ForecastScenarioBillableEvents(arg1 eventio.Scenario, arg2 eventio.EventFilter) ([]eventio.BillableEvent, error) {
+	fake.forecastScenarioBillableEventsMutex.Lock()
+	ret, specificReturn := fake.forecastScenarioBillableEventsReturnsOnCall[len(fake.forecastScenarioBillableEventsArgsForCall)]
+	fake.forecastScenarioBillableEventsArgsForCall = append(fake.forecastScenarioBillableEventsArgsForCall, struct {
+		arg1 eventio.Scenario
+		arg2 eventio.EventFilter
+	}{arg1, arg2})
+	fake.recordInvocation("ForecastScenarioBillableEvents", []interface{}{arg1, arg2})
+	fake.forecastScenarioBillableEventsMutex.Unlock()
+	if fake.ForecastScenarioBillableEventsStub != nil {
+		return fake.ForecastScenarioBillableEventsStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.forecastScenarioBillableEventsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) ForecastScenarioBillableEventsCallCount() int {
+	fake.forecastScenarioBillableEventsMutex.RLock()
+	defer fake.forecastScenarioBillableEventsMutex.RUnlock()
+	return len(fake.forecastScenarioBillableEventsArgsForCall)
+}
+
+func (fake *FakeEventStore) ForecastScenarioBillableEventsCalls(stub func(eventio.Scenario, eventio.EventFilter) ([]eventio.BillableEvent, error)) {
+	fake.forecastScenarioBillableEventsMutex.Lock()
+	defer fake.forecastScenarioBillableEventsMutex.Unlock()
+	fake.ForecastScenarioBillableEventsStub = stub
+}
+
+func (fake *FakeEventStore) ForecastScenarioBillableEventsArgsForCall(i int) (eventio.Scenario, eventio.EventFilter) {
+	fake.forecastScenarioBillableEventsMutex.RLock()
+	defer fake.forecastScenarioBillableEventsMutex.RUnlock()
+	argsForCall := fake.forecastScenarioBillableEventsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) ForecastScenarioBillableEventsReturns(result1 []eventio.BillableEvent, result2 error) {
+	fake.forecastScenarioBillableEventsMutex.Lock()
+	defer fake.forecastScenarioBillableEventsMutex.Unlock()
+	fake.ForecastScenarioBillableEventsStub = nil
+	fake.forecastScenarioBillableEventsReturns = struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) ForecastScenarioBillableEventsReturnsOnCall(i int, result1 []eventio.BillableEvent, result2 error) {
+	fake.forecastScenarioBillableEventsMutex.Lock()
+	defer fake.forecastScenarioBillableEventsMutex.Unlock()
+	fake.ForecastScenarioBillableEventsStub = nil
+	if fake.forecastScenarioBillableEventsReturnsOnCall == nil {
+		fake.forecastScenarioBillableEventsReturnsOnCall = make(map[int]struct {
+			result1 []eventio.BillableEvent
+			result2 error
+		})
+	}
+	fake.forecastScenarioBillableEventsReturnsOnCall[i] = struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetAnomalies(arg1 eventio.EventFilter) ([]eventio.Anomaly, error) {
+	fake.getAnomaliesMutex.Lock()
+	ret, specificReturn := fake.getAnomaliesReturnsOnCall[len(fake.getAnomaliesArgsForCall)]
+	fake.getAnomaliesArgsForCall = append(fake.getAnomaliesArgsForCall, struct {
+		arg1 eventio.EventFilter
+	}{arg1})
+	fake.recordInvocation("GetAnomalies", []interface{}{arg1})
+	fake.getAnomaliesMutex.Unlock()
+	if fake.GetAnomaliesStub != nil {
+		return fake.GetAnomaliesStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getAnomaliesReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetAnomaliesCallCount() int {
+	fake.getAnomaliesMutex.RLock()
+	defer fake.getAnomaliesMutex.RUnlock()
+	return len(fake.getAnomaliesArgsForCall)
+}
+
+func (fake *FakeEventStore) GetAnomaliesCalls(stub func(eventio.EventFilter) ([]eventio.Anomaly, error)) {
+	fake.getAnomaliesMutex.Lock()
+	defer fake.getAnomaliesMutex.Unlock()
+	fake.GetAnomaliesStub = stub
+}
+
+func (fake *FakeEventStore) GetAnomaliesArgsForCall(i int) eventio.EventFilter {
+	fake.getAnomaliesMutex.RLock()
+	defer fake.getAnomaliesMutex.RUnlock()
+	argsForCall := fake.getAnomaliesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetAnomaliesReturns(result1 []eventio.Anomaly, result2 error) {
+	fake.getAnomaliesMutex.Lock()
+	defer fake.getAnomaliesMutex.Unlock()
+	fake.GetAnomaliesStub = nil
+	fake.getAnomaliesReturns = struct {
+		result1 []eventio.Anomaly
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetAnomaliesReturnsOnCall(i int, result1 []eventio.Anomaly, result2 error) {
+	fake.getAnomaliesMutex.Lock()
+	defer fake.getAnomaliesMutex.Unlock()
+	fake.GetAnomaliesStub = nil
+	if fake.getAnomaliesReturnsOnCall == nil {
+		fake.getAnomaliesReturnsOnCall = make(map[int]struct {
+			result1 []eventio.Anomaly
+			result2 error
+		})
+	}
+	fake.getAnomaliesReturnsOnCall[i] = struct {
+		result1 []eventio.Anomaly
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEventStore) GetBillableEventRows(arg1 context.Context, arg2 eventio.EventFilter) (eventio.BillableEventRows, error) {
 	fake.getBillableEventRowsMutex.Lock()
 	ret, specificReturn := fake.getBillableEventRowsReturnsOnCall[len(fake.getBillableEventRowsArgsForCall)]
@@ -633,71 +1958,270 @@ func (fake *FakeEventStore) GetBillableEventRowsReturnsOnCall(i int, result1 eve
 			result2 error
 		})
 	}
-	fake.getBillableEventRowsReturnsOnCall[i] = struct {
-		result1 eventio.BillableEventRows
+	fake.getBillableEventRowsReturnsOnCall[i] = struct {
+		result1 eventio.BillableEventRows
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetBillableEvents(arg1 eventio.EventFilter) ([]eventio.BillableEvent, error) {
+	fake.getBillableEventsMutex.Lock()
+	ret, specificReturn := fake.getBillableEventsReturnsOnCall[len(fake.getBillableEventsArgsForCall)]
+	fake.getBillableEventsArgsForCall = append(fake.getBillableEventsArgsForCall, struct {
+		arg1 eventio.EventFilter
+	}{arg1})
+	fake.recordInvocation("GetBillableEvents", []interface{}{arg1})
+	fake.getBillableEventsMutex.Unlock()
+	if fake.GetBillableEventsStub != nil {
+		return fake.GetBillableEventsStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getBillableEventsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetBillableEventsCallCount() int {
+	fake.getBillableEventsMutex.RLock()
+	defer fake.getBillableEventsMutex.RUnlock()
+	return len(fake.getBillableEventsArgsForCall)
+}
+
+func (fake *FakeEventStore) GetBillableEventsCalls(stub func(eventio.EventFilter) ([]eventio.BillableEvent, error)) {
+	fake.getBillableEventsMutex.Lock()
+	defer fake.getBillableEventsMutex.Unlock()
+	fake.GetBillableEventsStub = stub
+}
+
+func (fake *FakeEventStore) GetBillableEventsArgsForCall(i int) eventio.EventFilter {
+	fake.getBillableEventsMutex.RLock()
+	defer fake.getBillableEventsMutex.RUnlock()
+	argsForCall := fake.getBillableEventsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetBillableEventsReturns(result1 []eventio.BillableEvent, result2 error) {
+	fake.getBillableEventsMutex.Lock()
+	defer fake.getBillableEventsMutex.Unlock()
+	fake.GetBillableEventsStub = nil
+	fake.getBillableEventsReturns = struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetBillableEventsReturnsOnCall(i int, result1 []eventio.BillableEvent, result2 error) {
+	fake.getBillableEventsMutex.Lock()
+	defer fake.getBillableEventsMutex.Unlock()
+	fake.GetBillableEventsStub = nil
+	if fake.getBillableEventsReturnsOnCall == nil {
+		fake.getBillableEventsReturnsOnCall = make(map[int]struct {
+			result1 []eventio.BillableEvent
+			result2 error
+		})
+	}
+	fake.getBillableEventsReturnsOnCall[i] = struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetBillableSummary(arg1 eventio.BillableSummaryFilter) ([]eventio.BillableSummary, error) {
+	fake.getBillableSummaryMutex.Lock()
+	ret, specificReturn := fake.getBillableSummaryReturnsOnCall[len(fake.getBillableSummaryArgsForCall)]
+	fake.getBillableSummaryArgsForCall = append(fake.getBillableSummaryArgsForCall, struct {
+		arg1 eventio.BillableSummaryFilter
+	}{arg1})
+	fake.recordInvocation("GetBillableSummary", []interface{}{arg1})
+	fake.getBillableSummaryMutex.Unlock()
+	if fake.GetBillableSummaryStub != nil {
+		return fake.GetBillableSummaryStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getBillableSummaryReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetBillableSummaryCallCount() int {
+	fake.getBillableSummaryMutex.RLock()
+	defer fake.getBillableSummaryMutex.RUnlock()
+	return len(fake.getBillableSummaryArgsForCall)
+}
+
+func (fake *FakeEventStore) GetBillableSummaryCalls(stub func(eventio.BillableSummaryFilter) ([]eventio.BillableSummary, error)) {
+	fake.getBillableSummaryMutex.Lock()
+	defer fake.getBillableSummaryMutex.Unlock()
+	fake.GetBillableSummaryStub = stub
+}
+
+func (fake *FakeEventStore) GetBillableSummaryArgsForCall(i int) eventio.BillableSummaryFilter {
+	fake.getBillableSummaryMutex.RLock()
+	defer fake.getBillableSummaryMutex.RUnlock()
+	argsForCall := fake.getBillableSummaryArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetBillableSummaryReturns(result1 []eventio.BillableSummary, result2 error) {
+	fake.getBillableSummaryMutex.Lock()
+	defer fake.getBillableSummaryMutex.Unlock()
+	fake.GetBillableSummaryStub = nil
+	fake.getBillableSummaryReturns = struct {
+		result1 []eventio.BillableSummary
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetBillableSummaryReturnsOnCall(i int, result1 []eventio.BillableSummary, result2 error) {
+	fake.getBillableSummaryMutex.Lock()
+	defer fake.getBillableSummaryMutex.Unlock()
+	fake.GetBillableSummaryStub = nil
+	if fake.getBillableSummaryReturnsOnCall == nil {
+		fake.getBillableSummaryReturnsOnCall = make(map[int]struct {
+			result1 []eventio.BillableSummary
+			result2 error
+		})
+	}
+	fake.getBillableSummaryReturnsOnCall[i] = struct {
+		result1 []eventio.BillableSummary
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetBillingPeriod(arg1 []string) (eventio.BillingPeriod, error) {
+	var arg1Copy []string
+	if arg1 != nil {
+		arg1Copy = make([]string, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.getBillingPeriodMutex.Lock()
+	ret, specificReturn := fake.getBillingPeriodReturnsOnCall[len(fake.getBillingPeriodArgsForCall)]
+	fake.getBillingPeriodArgsForCall = append(fake.getBillingPeriodArgsForCall, struct {
+		arg1 []string
+	}{arg1Copy})
+	fake.recordInvocation("GetBillingPeriod", []interface{}{arg1Copy})
+	fake.getBillingPeriodMutex.Unlock()
+	if fake.GetBillingPeriodStub != nil {
+		return fake.GetBillingPeriodStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getBillingPeriodReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetBillingPeriodCallCount() int {
+	fake.getBillingPeriodMutex.RLock()
+	defer fake.getBillingPeriodMutex.RUnlock()
+	return len(fake.getBillingPeriodArgsForCall)
+}
+
+func (fake *FakeEventStore) GetBillingPeriodCalls(stub func([]string) (eventio.BillingPeriod, error)) {
+	fake.getBillingPeriodMutex.Lock()
+	defer fake.getBillingPeriodMutex.Unlock()
+	fake.GetBillingPeriodStub = stub
+}
+
+func (fake *FakeEventStore) GetBillingPeriodArgsForCall(i int) []string {
+	fake.getBillingPeriodMutex.RLock()
+	defer fake.getBillingPeriodMutex.RUnlock()
+	argsForCall := fake.getBillingPeriodArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetBillingPeriodReturns(result1 eventio.BillingPeriod, result2 error) {
+	fake.getBillingPeriodMutex.Lock()
+	defer fake.getBillingPeriodMutex.Unlock()
+	fake.GetBillingPeriodStub = nil
+	fake.getBillingPeriodReturns = struct {
+		result1 eventio.BillingPeriod
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetBillingPeriodReturnsOnCall(i int, result1 eventio.BillingPeriod, result2 error) {
+	fake.getBillingPeriodMutex.Lock()
+	defer fake.getBillingPeriodMutex.Unlock()
+	fake.GetBillingPeriodStub = nil
+	if fake.getBillingPeriodReturnsOnCall == nil {
+		fake.getBillingPeriodReturnsOnCall = make(map[int]struct {
+			result1 eventio.BillingPeriod
+			result2 error
+		})
+	}
+	fake.getBillingPeriodReturnsOnCall[i] = struct {
+		result1 eventio.BillingPeriod
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeEventStore) GetBillableEvents(arg1 eventio.EventFilter) ([]eventio.BillableEvent, error) {
-	fake.getBillableEventsMutex.Lock()
-	ret, specificReturn := fake.getBillableEventsReturnsOnCall[len(fake.getBillableEventsArgsForCall)]
-	fake.getBillableEventsArgsForCall = append(fake.getBillableEventsArgsForCall, struct {
-		arg1 eventio.EventFilter
-	}{arg1})
-	fake.recordInvocation("GetBillableEvents", []interface{}{arg1})
-	fake.getBillableEventsMutex.Unlock()
-	if fake.GetBillableEventsStub != nil {
-		return fake.GetBillableEventsStub(arg1)
+func (fake *FakeEventStore) GetBudgets(arg1 []string) ([]eventio.Budget, error) {
+	var arg1Copy []string
+	if arg1 != nil {
+		arg1Copy = make([]string, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.getBudgetsMutex.Lock()
+	ret, specificReturn := fake.getBudgetsReturnsOnCall[len(fake.getBudgetsArgsForCall)]
+	fake.getBudgetsArgsForCall = append(fake.getBudgetsArgsForCall, struct {
+		arg1 []string
+	}{arg1Copy})
+	fake.recordInvocation("GetBudgets", []interface{}{arg1Copy})
+	fake.getBudgetsMutex.Unlock()
+	if fake.GetBudgetsStub != nil {
+		return fake.GetBudgetsStub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getBillableEventsReturns
+	fakeReturns := fake.getBudgetsReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *FakeEventStore) GetBillableEventsCallCount() int {
-	fake.getBillableEventsMutex.RLock()
-	defer fake.getBillableEventsMutex.RUnlock()
-	return len(fake.getBillableEventsArgsForCall)
+func (fake *FakeEventStore) GetBudgetsCallCount() int {
+	fake.getBudgetsMutex.RLock()
+	defer fake.getBudgetsMutex.RUnlock()
+	return len(fake.getBudgetsArgsForCall)
 }
 
-func (fake *FakeEventStore) GetBillableEventsCalls(stub func(eventio.EventFilter) ([]eventio.BillableEvent, error)) {
-	fake.getBillableEventsMutex.Lock()
-	defer fake.getBillableEventsMutex.Unlock()
-	fake.GetBillableEventsStub = stub
+func (fake *FakeEventStore) GetBudgetsCalls(stub func([]string) ([]eventio.Budget, error)) {
+	fake.getBudgetsMutex.Lock()
+	defer fake.getBudgetsMutex.Unlock()
+	fake.GetBudgetsStub = stub
 }
 
-func (fake *FakeEventStore) GetBillableEventsArgsForCall(i int) eventio.EventFilter {
-	fake.getBillableEventsMutex.RLock()
-	defer fake.getBillableEventsMutex.RUnlock()
-	argsForCall := fake.getBillableEventsArgsForCall[i]
+func (fake *FakeEventStore) GetBudgetsArgsForCall(i int) []string {
+	fake.getBudgetsMutex.RLock()
+	defer fake.getBudgetsMutex.RUnlock()
+	argsForCall := fake.getBudgetsArgsForCall[i]
 	return argsForCall.arg1
 }
 
-func (fake *FakeEventStore) GetBillableEventsReturns(result1 []eventio.BillableEvent, result2 error) {
-	fake.getBillableEventsMutex.Lock()
-	defer fake.getBillableEventsMutex.Unlock()
-	fake.GetBillableEventsStub = nil
-	fake.getBillableEventsReturns = struct {
-		result1 []eventio.BillableEvent
+func (fake *FakeEventStore) GetBudgetsReturns(result1 []eventio.Budget, result2 error) {
+	fake.getBudgetsMutex.Lock()
+	defer fake.getBudgetsMutex.Unlock()
+	fake.GetBudgetsStub = nil
+	fake.getBudgetsReturns = struct {
+		result1 []eventio.Budget
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeEventStore) GetBillableEventsReturnsOnCall(i int, result1 []eventio.BillableEvent, result2 error) {
-	fake.getBillableEventsMutex.Lock()
-	defer fake.getBillableEventsMutex.Unlock()
-	fake.GetBillableEventsStub = nil
-	if fake.getBillableEventsReturnsOnCall == nil {
-		fake.getBillableEventsReturnsOnCall = make(map[int]struct {
-			result1 []eventio.BillableEvent
+func (fake *FakeEventStore) GetBudgetsReturnsOnCall(i int, result1 []eventio.Budget, result2 error) {
+	fake.getBudgetsMutex.Lock()
+	defer fake.getBudgetsMutex.Unlock()
+	fake.GetBudgetsStub = nil
+	if fake.getBudgetsReturnsOnCall == nil {
+		fake.getBudgetsReturnsOnCall = make(map[int]struct {
+			result1 []eventio.Budget
 			result2 error
 		})
 	}
-	fake.getBillableEventsReturnsOnCall[i] = struct {
-		result1 []eventio.BillableEvent
+	fake.getBudgetsReturnsOnCall[i] = struct {
+		result1 []eventio.Budget
 		result2 error
 	}{result1, result2}
 }
@@ -829,6 +2353,69 @@ func (fake *FakeEventStore) GetConsolidatedBillableEventsReturnsOnCall(i int, re
 	}{result1, result2}
 }
 
+func (fake *FakeEventStore) GetCurrencyRateVersions(arg1 string) ([]eventio.CurrencyRateVersion, error) {
+	fake.getCurrencyRateVersionsMutex.Lock()
+	ret, specificReturn := fake.getCurrencyRateVersionsReturnsOnCall[len(fake.getCurrencyRateVersionsArgsForCall)]
+	fake.getCurrencyRateVersionsArgsForCall = append(fake.getCurrencyRateVersionsArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("GetCurrencyRateVersions", []interface{}{arg1})
+	fake.getCurrencyRateVersionsMutex.Unlock()
+	if fake.GetCurrencyRateVersionsStub != nil {
+		return fake.GetCurrencyRateVersionsStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getCurrencyRateVersionsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetCurrencyRateVersionsCallCount() int {
+	fake.getCurrencyRateVersionsMutex.RLock()
+	defer fake.getCurrencyRateVersionsMutex.RUnlock()
+	return len(fake.getCurrencyRateVersionsArgsForCall)
+}
+
+func (fake *FakeEventStore) GetCurrencyRateVersionsCalls(stub func(string) ([]eventio.CurrencyRateVersion, error)) {
+	fake.getCurrencyRateVersionsMutex.Lock()
+	defer fake.getCurrencyRateVersionsMutex.Unlock()
+	fake.GetCurrencyRateVersionsStub = stub
+}
+
+func (fake *FakeEventStore) GetCurrencyRateVersionsArgsForCall(i int) string {
+	fake.getCurrencyRateVersionsMutex.RLock()
+	defer fake.getCurrencyRateVersionsMutex.RUnlock()
+	argsForCall := fake.getCurrencyRateVersionsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetCurrencyRateVersionsReturns(result1 []eventio.CurrencyRateVersion, result2 error) {
+	fake.getCurrencyRateVersionsMutex.Lock()
+	defer fake.getCurrencyRateVersionsMutex.Unlock()
+	fake.GetCurrencyRateVersionsStub = nil
+	fake.getCurrencyRateVersionsReturns = struct {
+		result1 []eventio.CurrencyRateVersion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetCurrencyRateVersionsReturnsOnCall(i int, result1 []eventio.CurrencyRateVersion, result2 error) {
+	fake.getCurrencyRateVersionsMutex.Lock()
+	defer fake.getCurrencyRateVersionsMutex.Unlock()
+	fake.GetCurrencyRateVersionsStub = nil
+	if fake.getCurrencyRateVersionsReturnsOnCall == nil {
+		fake.getCurrencyRateVersionsReturnsOnCall = make(map[int]struct {
+			result1 []eventio.CurrencyRateVersion
+			result2 error
+		})
+	}
+	fake.getCurrencyRateVersionsReturnsOnCall[i] = struct {
+		result1 []eventio.CurrencyRateVersion
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEventStore) GetCurrencyRates(arg1 eventio.TimeRangeFilter) ([]eventio.CurrencyRate, error) {
 	fake.getCurrencyRatesMutex.Lock()
 	ret, specificReturn := fake.getCurrencyRatesReturnsOnCall[len(fake.getCurrencyRatesArgsForCall)]
@@ -892,6 +2479,70 @@ func (fake *FakeEventStore) GetCurrencyRatesReturnsOnCall(i int, result1 []event
 	}{result1, result2}
 }
 
+func (fake *FakeEventStore) GetDueWebhookDeliveries(arg1 int, arg2 time.Duration) ([]eventio.DueWebhookDelivery, error) {
+	fake.getDueWebhookDeliveriesMutex.Lock()
+	ret, specificReturn := fake.getDueWebhookDeliveriesReturnsOnCall[len(fake.getDueWebhookDeliveriesArgsForCall)]
+	fake.getDueWebhookDeliveriesArgsForCall = append(fake.getDueWebhookDeliveriesArgsForCall, struct {
+		arg1 int
+		arg2 time.Duration
+	}{arg1, arg2})
+	fake.recordInvocation("GetDueWebhookDeliveries", []interface{}{arg1, arg2})
+	fake.getDueWebhookDeliveriesMutex.Unlock()
+	if fake.GetDueWebhookDeliveriesStub != nil {
+		return fake.GetDueWebhookDeliveriesStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getDueWebhookDeliveriesReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetDueWebhookDeliveriesCallCount() int {
+	fake.getDueWebhookDeliveriesMutex.RLock()
+	defer fake.getDueWebhookDeliveriesMutex.RUnlock()
+	return len(fake.getDueWebhookDeliveriesArgsForCall)
+}
+
+func (fake *FakeEventStore) GetDueWebhookDeliveriesCalls(stub func(int, time.Duration) ([]eventio.DueWebhookDelivery, error)) {
+	fake.getDueWebhookDeliveriesMutex.Lock()
+	defer fake.getDueWebhookDeliveriesMutex.Unlock()
+	fake.GetDueWebhookDeliveriesStub = stub
+}
+
+func (fake *FakeEventStore) GetDueWebhookDeliveriesArgsForCall(i int) (int, time.Duration) {
+	fake.getDueWebhookDeliveriesMutex.RLock()
+	defer fake.getDueWebhookDeliveriesMutex.RUnlock()
+	argsForCall := fake.getDueWebhookDeliveriesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetDueWebhookDeliveriesReturns(result1 []eventio.DueWebhookDelivery, result2 error) {
+	fake.getDueWebhookDeliveriesMutex.Lock()
+	defer fake.getDueWebhookDeliveriesMutex.Unlock()
+	fake.GetDueWebhookDeliveriesStub = nil
+	fake.getDueWebhookDeliveriesReturns = struct {
+		result1 []eventio.DueWebhookDelivery
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetDueWebhookDeliveriesReturnsOnCall(i int, result1 []eventio.DueWebhookDelivery, result2 error) {
+	fake.getDueWebhookDeliveriesMutex.Lock()
+	defer fake.getDueWebhookDeliveriesMutex.Unlock()
+	fake.GetDueWebhookDeliveriesStub = nil
+	if fake.getDueWebhookDeliveriesReturnsOnCall == nil {
+		fake.getDueWebhookDeliveriesReturnsOnCall = make(map[int]struct {
+			result1 []eventio.DueWebhookDelivery
+			result2 error
+		})
+	}
+	fake.getDueWebhookDeliveriesReturnsOnCall[i] = struct {
+		result1 []eventio.DueWebhookDelivery
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEventStore) GetEvents(arg1 eventio.RawEventFilter) ([]eventio.RawEvent, error) {
 	fake.getEventsMutex.Lock()
 	ret, specificReturn := fake.getEventsReturnsOnCall[len(fake.getEventsArgsForCall)]
@@ -955,6 +2606,321 @@ func (fake *FakeEventStore) GetEventsReturnsOnCall(i int, result1 []eventio.RawE
 	}{result1, result2}
 }
 
+func (fake *FakeEventStore) GetInvoice(arg1 int64) (eventio.Invoice, error) {
+	fake.getInvoiceMutex.Lock()
+	ret, specificReturn := fake.getInvoiceReturnsOnCall[len(fake.getInvoiceArgsForCall)]
+	fake.getInvoiceArgsForCall = append(fake.getInvoiceArgsForCall, struct {
+		arg1 int64
+	}{arg1})
+	fake.recordInvocation("GetInvoice", []interface{}{arg1})
+	fake.getInvoiceMutex.Unlock()
+	if fake.GetInvoiceStub != nil {
+		return fake.GetInvoiceStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getInvoiceReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetInvoiceCallCount() int {
+	fake.getInvoiceMutex.RLock()
+	defer fake.getInvoiceMutex.RUnlock()
+	return len(fake.getInvoiceArgsForCall)
+}
+
+func (fake *FakeEventStore) GetInvoiceCalls(stub func(int64) (eventio.Invoice, error)) {
+	fake.getInvoiceMutex.Lock()
+	defer fake.getInvoiceMutex.Unlock()
+	fake.GetInvoiceStub = stub
+}
+
+func (fake *FakeEventStore) GetInvoiceArgsForCall(i int) int64 {
+	fake.getInvoiceMutex.RLock()
+	defer fake.getInvoiceMutex.RUnlock()
+	argsForCall := fake.getInvoiceArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetInvoiceReturns(result1 eventio.Invoice, result2 error) {
+	fake.getInvoiceMutex.Lock()
+	defer fake.getInvoiceMutex.Unlock()
+	fake.GetInvoiceStub = nil
+	fake.getInvoiceReturns = struct {
+		result1 eventio.Invoice
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetInvoiceReturnsOnCall(i int, result1 eventio.Invoice, result2 error) {
+	fake.getInvoiceMutex.Lock()
+	defer fake.getInvoiceMutex.Unlock()
+	fake.GetInvoiceStub = nil
+	if fake.getInvoiceReturnsOnCall == nil {
+		fake.getInvoiceReturnsOnCall = make(map[int]struct {
+			result1 eventio.Invoice
+			result2 error
+		})
+	}
+	fake.getInvoiceReturnsOnCall[i] = struct {
+		result1 eventio.Invoice
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetInvoices(arg1 eventio.EventFilter) ([]eventio.Invoice, error) {
+	fake.getInvoicesMutex.Lock()
+	ret, specificReturn := fake.getInvoicesReturnsOnCall[len(fake.getInvoicesArgsForCall)]
+	fake.getInvoicesArgsForCall = append(fake.getInvoicesArgsForCall, struct {
+		arg1 eventio.EventFilter
+	}{arg1})
+	fake.recordInvocation("GetInvoices", []interface{}{arg1})
+	fake.getInvoicesMutex.Unlock()
+	if fake.GetInvoicesStub != nil {
+		return fake.GetInvoicesStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getInvoicesReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetInvoicesCallCount() int {
+	fake.getInvoicesMutex.RLock()
+	defer fake.getInvoicesMutex.RUnlock()
+	return len(fake.getInvoicesArgsForCall)
+}
+
+func (fake *FakeEventStore) GetInvoicesCalls(stub func(eventio.EventFilter) ([]eventio.Invoice, error)) {
+	fake.getInvoicesMutex.Lock()
+	defer fake.getInvoicesMutex.Unlock()
+	fake.GetInvoicesStub = stub
+}
+
+func (fake *FakeEventStore) GetInvoicesArgsForCall(i int) eventio.EventFilter {
+	fake.getInvoicesMutex.RLock()
+	defer fake.getInvoicesMutex.RUnlock()
+	argsForCall := fake.getInvoicesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetInvoicesReturns(result1 []eventio.Invoice, result2 error) {
+	fake.getInvoicesMutex.Lock()
+	defer fake.getInvoicesMutex.Unlock()
+	fake.GetInvoicesStub = nil
+	fake.getInvoicesReturns = struct {
+		result1 []eventio.Invoice
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetInvoicesReturnsOnCall(i int, result1 []eventio.Invoice, result2 error) {
+	fake.getInvoicesMutex.Lock()
+	defer fake.getInvoicesMutex.Unlock()
+	fake.GetInvoicesStub = nil
+	if fake.getInvoicesReturnsOnCall == nil {
+		fake.getInvoicesReturnsOnCall = make(map[int]struct {
+			result1 []eventio.Invoice
+			result2 error
+		})
+	}
+	fake.getInvoicesReturnsOnCall[i] = struct {
+		result1 []eventio.Invoice
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetMonthEndProjections(arg1 eventio.ProjectionFilter) ([]eventio.Projection, error) {
+	fake.getMonthEndProjectionsMutex.Lock()
+	ret, specificReturn := fake.getMonthEndProjectionsReturnsOnCall[len(fake.getMonthEndProjectionsArgsForCall)]
+	fake.getMonthEndProjectionsArgsForCall = append(fake.getMonthEndProjectionsArgsForCall, struct {
+		arg1 eventio.ProjectionFilter
+	}{arg1})
+	fake.recordInvocation("GetMonthEndProjections", []interface{}{arg1})
+	fake.getMonthEndProjectionsMutex.Unlock()
+	if fake.GetMonthEndProjectionsStub != nil {
+		return fake.GetMonthEndProjectionsStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getMonthEndProjectionsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetMonthEndProjectionsCallCount() int {
+	fake.getMonthEndProjectionsMutex.RLock()
+	defer fake.getMonthEndProjectionsMutex.RUnlock()
+	return len(fake.getMonthEndProjectionsArgsForCall)
+}
+
+func (fake *FakeEventStore) GetMonthEndProjectionsCalls(stub func(eventio.ProjectionFilter) ([]eventio.Projection, error)) {
+	fake.getMonthEndProjectionsMutex.Lock()
+	defer fake.getMonthEndProjectionsMutex.Unlock()
+	fake.GetMonthEndProjectionsStub = stub
+}
+
+func (fake *FakeEventStore) GetMonthEndProjectionsArgsForCall(i int) eventio.ProjectionFilter {
+	fake.getMonthEndProjectionsMutex.RLock()
+	defer fake.getMonthEndProjectionsMutex.RUnlock()
+	argsForCall := fake.getMonthEndProjectionsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetMonthEndProjectionsReturns(result1 []eventio.Projection, result2 error) {
+	fake.getMonthEndProjectionsMutex.Lock()
+	defer fake.getMonthEndProjectionsMutex.Unlock()
+	fake.GetMonthEndProjectionsStub = nil
+	fake.getMonthEndProjectionsReturns = struct {
+		result1 []eventio.Projection
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetMonthEndProjectionsReturnsOnCall(i int, result1 []eventio.Projection, result2 error) {
+	fake.getMonthEndProjectionsMutex.Lock()
+	defer fake.getMonthEndProjectionsMutex.Unlock()
+	fake.GetMonthEndProjectionsStub = nil
+	if fake.getMonthEndProjectionsReturnsOnCall == nil {
+		fake.getMonthEndProjectionsReturnsOnCall = make(map[int]struct {
+			result1 []eventio.Projection
+			result2 error
+		})
+	}
+	fake.getMonthEndProjectionsReturnsOnCall[i] = struct {
+		result1 []eventio.Projection
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetOrgTaxTreatmentVersions(arg1 string) ([]eventio.OrgTaxTreatmentVersion, error) {
+	fake.getOrgTaxTreatmentVersionsMutex.Lock()
+	ret, specificReturn := fake.getOrgTaxTreatmentVersionsReturnsOnCall[len(fake.getOrgTaxTreatmentVersionsArgsForCall)]
+	fake.getOrgTaxTreatmentVersionsArgsForCall = append(fake.getOrgTaxTreatmentVersionsArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("GetOrgTaxTreatmentVersions", []interface{}{arg1})
+	fake.getOrgTaxTreatmentVersionsMutex.Unlock()
+	if fake.GetOrgTaxTreatmentVersionsStub != nil {
+		return fake.GetOrgTaxTreatmentVersionsStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getOrgTaxTreatmentVersionsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetOrgTaxTreatmentVersionsCallCount() int {
+	fake.getOrgTaxTreatmentVersionsMutex.RLock()
+	defer fake.getOrgTaxTreatmentVersionsMutex.RUnlock()
+	return len(fake.getOrgTaxTreatmentVersionsArgsForCall)
+}
+
+func (fake *FakeEventStore) GetOrgTaxTreatmentVersionsCalls(stub func(string) ([]eventio.OrgTaxTreatmentVersion, error)) {
+	fake.getOrgTaxTreatmentVersionsMutex.Lock()
+	defer fake.getOrgTaxTreatmentVersionsMutex.Unlock()
+	fake.GetOrgTaxTreatmentVersionsStub = stub
+}
+
+func (fake *FakeEventStore) GetOrgTaxTreatmentVersionsArgsForCall(i int) string {
+	fake.getOrgTaxTreatmentVersionsMutex.RLock()
+	defer fake.getOrgTaxTreatmentVersionsMutex.RUnlock()
+	argsForCall := fake.getOrgTaxTreatmentVersionsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetOrgTaxTreatmentVersionsReturns(result1 []eventio.OrgTaxTreatmentVersion, result2 error) {
+	fake.getOrgTaxTreatmentVersionsMutex.Lock()
+	defer fake.getOrgTaxTreatmentVersionsMutex.Unlock()
+	fake.GetOrgTaxTreatmentVersionsStub = nil
+	fake.getOrgTaxTreatmentVersionsReturns = struct {
+		result1 []eventio.OrgTaxTreatmentVersion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetOrgTaxTreatmentVersionsReturnsOnCall(i int, result1 []eventio.OrgTaxTreatmentVersion, result2 error) {
+	fake.getOrgTaxTreatmentVersionsMutex.Lock()
+	defer fake.getOrgTaxTreatmentVersionsMutex.Unlock()
+	fake.GetOrgTaxTreatmentVersionsStub = nil
+	if fake.getOrgTaxTreatmentVersionsReturnsOnCall == nil {
+		fake.getOrgTaxTreatmentVersionsReturnsOnCall = make(map[int]struct {
+			result1 []eventio.OrgTaxTreatmentVersion
+			result2 error
+		})
+	}
+	fake.getOrgTaxTreatmentVersionsReturnsOnCall[i] = struct {
+		result1 []eventio.OrgTaxTreatmentVersion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetPricingPlanVersions(arg1 string) ([]eventio.PricingPlanVersion, error) {
+	fake.getPricingPlanVersionsMutex.Lock()
+	ret, specificReturn := fake.getPricingPlanVersionsReturnsOnCall[len(fake.getPricingPlanVersionsArgsForCall)]
+	fake.getPricingPlanVersionsArgsForCall = append(fake.getPricingPlanVersionsArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("GetPricingPlanVersions", []interface{}{arg1})
+	fake.getPricingPlanVersionsMutex.Unlock()
+	if fake.GetPricingPlanVersionsStub != nil {
+		return fake.GetPricingPlanVersionsStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getPricingPlanVersionsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetPricingPlanVersionsCallCount() int {
+	fake.getPricingPlanVersionsMutex.RLock()
+	defer fake.getPricingPlanVersionsMutex.RUnlock()
+	return len(fake.getPricingPlanVersionsArgsForCall)
+}
+
+func (fake *FakeEventStore) GetPricingPlanVersionsCalls(stub func(string) ([]eventio.PricingPlanVersion, error)) {
+	fake.getPricingPlanVersionsMutex.Lock()
+	defer fake.getPricingPlanVersionsMutex.Unlock()
+	fake.GetPricingPlanVersionsStub = stub
+}
+
+func (fake *FakeEventStore) GetPricingPlanVersionsArgsForCall(i int) string {
+	fake.getPricingPlanVersionsMutex.RLock()
+	defer fake.getPricingPlanVersionsMutex.RUnlock()
+	argsForCall := fake.getPricingPlanVersionsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetPricingPlanVersionsReturns(result1 []eventio.PricingPlanVersion, result2 error) {
+	fake.getPricingPlanVersionsMutex.Lock()
+	defer fake.getPricingPlanVersionsMutex.Unlock()
+	fake.GetPricingPlanVersionsStub = nil
+	fake.getPricingPlanVersionsReturns = struct {
+		result1 []eventio.PricingPlanVersion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetPricingPlanVersionsReturnsOnCall(i int, result1 []eventio.PricingPlanVersion, result2 error) {
+	fake.getPricingPlanVersionsMutex.Lock()
+	defer fake.getPricingPlanVersionsMutex.Unlock()
+	fake.GetPricingPlanVersionsStub = nil
+	if fake.getPricingPlanVersionsReturnsOnCall == nil {
+		fake.getPricingPlanVersionsReturnsOnCall = make(map[int]struct {
+			result1 []eventio.PricingPlanVersion
+			result2 error
+		})
+	}
+	fake.getPricingPlanVersionsReturnsOnCall[i] = struct {
+		result1 []eventio.PricingPlanVersion
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEventStore) GetPricingPlans(arg1 eventio.TimeRangeFilter) ([]eventio.PricingPlan, error) {
 	fake.getPricingPlansMutex.Lock()
 	ret, specificReturn := fake.getPricingPlansReturnsOnCall[len(fake.getPricingPlansArgsForCall)]
@@ -1073,6 +3039,61 @@ func (fake *FakeEventStore) GetTotalCostReturnsOnCall(i int, result1 []eventio.T
 	}{result1, result2}
 }
 
+func (fake *FakeEventStore) GetUnnotifiedAnomalies() ([]eventio.Anomaly, error) {
+	fake.getUnnotifiedAnomaliesMutex.Lock()
+	ret, specificReturn := fake.getUnnotifiedAnomaliesReturnsOnCall[len(fake.getUnnotifiedAnomaliesArgsForCall)]
+	fake.getUnnotifiedAnomaliesArgsForCall = append(fake.getUnnotifiedAnomaliesArgsForCall, struct {
+	}{})
+	fake.recordInvocation("GetUnnotifiedAnomalies", []interface{}{})
+	fake.getUnnotifiedAnomaliesMutex.Unlock()
+	if fake.GetUnnotifiedAnomaliesStub != nil {
+		return fake.GetUnnotifiedAnomaliesStub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getUnnotifiedAnomaliesReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetUnnotifiedAnomaliesCallCount() int {
+	fake.getUnnotifiedAnomaliesMutex.RLock()
+	defer fake.getUnnotifiedAnomaliesMutex.RUnlock()
+	return len(fake.getUnnotifiedAnomaliesArgsForCall)
+}
+
+func (fake *FakeEventStore) GetUnnotifiedAnomaliesCalls(stub func() ([]eventio.Anomaly, error)) {
+	fake.getUnnotifiedAnomaliesMutex.Lock()
+	defer fake.getUnnotifiedAnomaliesMutex.Unlock()
+	fake.GetUnnotifiedAnomaliesStub = stub
+}
+
+func (fake *FakeEventStore) GetUnnotifiedAnomaliesReturns(result1 []eventio.Anomaly, result2 error) {
+	fake.getUnnotifiedAnomaliesMutex.Lock()
+	defer fake.getUnnotifiedAnomaliesMutex.Unlock()
+	fake.GetUnnotifiedAnomaliesStub = nil
+	fake.getUnnotifiedAnomaliesReturns = struct {
+		result1 []eventio.Anomaly
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetUnnotifiedAnomaliesReturnsOnCall(i int, result1 []eventio.Anomaly, result2 error) {
+	fake.getUnnotifiedAnomaliesMutex.Lock()
+	defer fake.getUnnotifiedAnomaliesMutex.Unlock()
+	fake.GetUnnotifiedAnomaliesStub = nil
+	if fake.getUnnotifiedAnomaliesReturnsOnCall == nil {
+		fake.getUnnotifiedAnomaliesReturnsOnCall = make(map[int]struct {
+			result1 []eventio.Anomaly
+			result2 error
+		})
+	}
+	fake.getUnnotifiedAnomaliesReturnsOnCall[i] = struct {
+		result1 []eventio.Anomaly
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEventStore) GetUsageEventRows(arg1 eventio.EventFilter) (eventio.UsageEventRows, error) {
 	fake.getUsageEventRowsMutex.Lock()
 	ret, specificReturn := fake.getUsageEventRowsReturnsOnCall[len(fake.getUsageEventRowsArgsForCall)]
@@ -1199,6 +3220,69 @@ func (fake *FakeEventStore) GetUsageEventsReturnsOnCall(i int, result1 []eventio
 	}{result1, result2}
 }
 
+func (fake *FakeEventStore) GetVATRateVersions(arg1 string) ([]eventio.VATRateVersion, error) {
+	fake.getVATRateVersionsMutex.Lock()
+	ret, specificReturn := fake.getVATRateVersionsReturnsOnCall[len(fake.getVATRateVersionsArgsForCall)]
+	fake.getVATRateVersionsArgsForCall = append(fake.getVATRateVersionsArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("GetVATRateVersions", []interface{}{arg1})
+	fake.getVATRateVersionsMutex.Unlock()
+	if fake.GetVATRateVersionsStub != nil {
+		return fake.GetVATRateVersionsStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getVATRateVersionsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetVATRateVersionsCallCount() int {
+	fake.getVATRateVersionsMutex.RLock()
+	defer fake.getVATRateVersionsMutex.RUnlock()
+	return len(fake.getVATRateVersionsArgsForCall)
+}
+
+func (fake *FakeEventStore) GetVATRateVersionsCalls(stub func(string) ([]eventio.VATRateVersion, error)) {
+	fake.getVATRateVersionsMutex.Lock()
+	defer fake.getVATRateVersionsMutex.Unlock()
+	fake.GetVATRateVersionsStub = stub
+}
+
+func (fake *FakeEventStore) GetVATRateVersionsArgsForCall(i int) string {
+	fake.getVATRateVersionsMutex.RLock()
+	defer fake.getVATRateVersionsMutex.RUnlock()
+	argsForCall := fake.getVATRateVersionsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetVATRateVersionsReturns(result1 []eventio.VATRateVersion, result2 error) {
+	fake.getVATRateVersionsMutex.Lock()
+	defer fake.getVATRateVersionsMutex.Unlock()
+	fake.GetVATRateVersionsStub = nil
+	fake.getVATRateVersionsReturns = struct {
+		result1 []eventio.VATRateVersion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetVATRateVersionsReturnsOnCall(i int, result1 []eventio.VATRateVersion, result2 error) {
+	fake.getVATRateVersionsMutex.Lock()
+	defer fake.getVATRateVersionsMutex.Unlock()
+	fake.GetVATRateVersionsStub = nil
+	if fake.getVATRateVersionsReturnsOnCall == nil {
+		fake.getVATRateVersionsReturnsOnCall = make(map[int]struct {
+			result1 []eventio.VATRateVersion
+			result2 error
+		})
+	}
+	fake.getVATRateVersionsReturnsOnCall[i] = struct {
+		result1 []eventio.VATRateVersion
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEventStore) GetVATRates(arg1 eventio.TimeRangeFilter) ([]eventio.VATRate, error) {
 	fake.getVATRatesMutex.Lock()
 	ret, specificReturn := fake.getVATRatesReturnsOnCall[len(fake.getVATRatesArgsForCall)]
@@ -1262,6 +3346,124 @@ func (fake *FakeEventStore) GetVATRatesReturnsOnCall(i int, result1 []eventio.VA
 	}{result1, result2}
 }
 
+func (fake *FakeEventStore) GetWebhookDeliveries(arg1 eventio.WebhookDeliveryFilter) ([]eventio.WebhookDelivery, error) {
+	fake.getWebhookDeliveriesMutex.Lock()
+	ret, specificReturn := fake.getWebhookDeliveriesReturnsOnCall[len(fake.getWebhookDeliveriesArgsForCall)]
+	fake.getWebhookDeliveriesArgsForCall = append(fake.getWebhookDeliveriesArgsForCall, struct {
+		arg1 eventio.WebhookDeliveryFilter
+	}{arg1})
+	fake.recordInvocation("GetWebhookDeliveries", []interface{}{arg1})
+	fake.getWebhookDeliveriesMutex.Unlock()
+	if fake.GetWebhookDeliveriesStub != nil {
+		return fake.GetWebhookDeliveriesStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getWebhookDeliveriesReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetWebhookDeliveriesCallCount() int {
+	fake.getWebhookDeliveriesMutex.RLock()
+	defer fake.getWebhookDeliveriesMutex.RUnlock()
+	return len(fake.getWebhookDeliveriesArgsForCall)
+}
+
+func (fake *FakeEventStore) GetWebhookDeliveriesCalls(stub func(eventio.WebhookDeliveryFilter) ([]eventio.WebhookDelivery, error)) {
+	fake.getWebhookDeliveriesMutex.Lock()
+	defer fake.getWebhookDeliveriesMutex.Unlock()
+	fake.GetWebhookDeliveriesStub = stub
+}
+
+func (fake *FakeEventStore) GetWebhookDeliveriesArgsForCall(i int) eventio.WebhookDeliveryFilter {
+	fake.getWebhookDeliveriesMutex.RLock()
+	defer fake.getWebhookDeliveriesMutex.RUnlock()
+	argsForCall := fake.getWebhookDeliveriesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetWebhookDeliveriesReturns(result1 []eventio.WebhookDelivery, result2 error) {
+	fake.getWebhookDeliveriesMutex.Lock()
+	defer fake.getWebhookDeliveriesMutex.Unlock()
+	fake.GetWebhookDeliveriesStub = nil
+	fake.getWebhookDeliveriesReturns = struct {
+		result1 []eventio.WebhookDelivery
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetWebhookDeliveriesReturnsOnCall(i int, result1 []eventio.WebhookDelivery, result2 error) {
+	fake.getWebhookDeliveriesMutex.Lock()
+	defer fake.getWebhookDeliveriesMutex.Unlock()
+	fake.GetWebhookDeliveriesStub = nil
+	if fake.getWebhookDeliveriesReturnsOnCall == nil {
+		fake.getWebhookDeliveriesReturnsOnCall = make(map[int]struct {
+			result1 []eventio.WebhookDelivery
+			result2 error
+		})
+	}
+	fake.getWebhookDeliveriesReturnsOnCall[i] = struct {
+		result1 []eventio.WebhookDelivery
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetWebhooks() ([]eventio.Webhook, error) {
+	fake.getWebhooksMutex.Lock()
+	ret, specificReturn := fake.getWebhooksReturnsOnCall[len(fake.getWebhooksArgsForCall)]
+	fake.getWebhooksArgsForCall = append(fake.getWebhooksArgsForCall, struct {
+	}{})
+	fake.recordInvocation("GetWebhooks", []interface{}{})
+	fake.getWebhooksMutex.Unlock()
+	if fake.GetWebhooksStub != nil {
+		return fake.GetWebhooksStub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getWebhooksReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetWebhooksCallCount() int {
+	fake.getWebhooksMutex.RLock()
+	defer fake.getWebhooksMutex.RUnlock()
+	return len(fake.getWebhooksArgsForCall)
+}
+
+func (fake *FakeEventStore) GetWebhooksCalls(stub func() ([]eventio.Webhook, error)) {
+	fake.getWebhooksMutex.Lock()
+	defer fake.getWebhooksMutex.Unlock()
+	fake.GetWebhooksStub = stub
+}
+
+func (fake *FakeEventStore) GetWebhooksReturns(result1 []eventio.Webhook, result2 error) {
+	fake.getWebhooksMutex.Lock()
+	defer fake.getWebhooksMutex.Unlock()
+	fake.GetWebhooksStub = nil
+	fake.getWebhooksReturns = struct {
+		result1 []eventio.Webhook
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetWebhooksReturnsOnCall(i int, result1 []eventio.Webhook, result2 error) {
+	fake.getWebhooksMutex.Lock()
+	defer fake.getWebhooksMutex.Unlock()
+	fake.GetWebhooksStub = nil
+	if fake.getWebhooksReturnsOnCall == nil {
+		fake.getWebhooksReturnsOnCall = make(map[int]struct {
+			result1 []eventio.Webhook
+			result2 error
+		})
+	}
+	fake.getWebhooksReturnsOnCall[i] = struct {
+		result1 []eventio.Webhook
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEventStore) Init() error {
 	fake.initMutex.Lock()
 	ret, specificReturn := fake.initReturnsOnCall[len(fake.initArgsForCall)]
@@ -1377,6 +3579,183 @@ func (fake *FakeEventStore) IsRangeConsolidatedReturnsOnCall(i int, result1 bool
 	}{result1, result2}
 }
 
+func (fake *FakeEventStore) IssueInvoice(arg1 int64, arg2 string) (eventio.Invoice, error) {
+	fake.issueInvoiceMutex.Lock()
+	ret, specificReturn := fake.issueInvoiceReturnsOnCall[len(fake.issueInvoiceArgsForCall)]
+	fake.issueInvoiceArgsForCall = append(fake.issueInvoiceArgsForCall, struct {
+		arg1 int64
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("IssueInvoice", []interface{}{arg1, arg2})
+	fake.issueInvoiceMutex.Unlock()
+	if fake.IssueInvoiceStub != nil {
+		return fake.IssueInvoiceStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.issueInvoiceReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) IssueInvoiceCallCount() int {
+	fake.issueInvoiceMutex.RLock()
+	defer fake.issueInvoiceMutex.RUnlock()
+	return len(fake.issueInvoiceArgsForCall)
+}
+
+func (fake *FakeEventStore) IssueInvoiceCalls(stub func(int64, string) (eventio.Invoice, error)) {
+	fake.issueInvoiceMutex.Lock()
+	defer fake.issueInvoiceMutex.Unlock()
+	fake.IssueInvoiceStub = stub
+}
+
+func (fake *FakeEventStore) IssueInvoiceArgsForCall(i int) (int64, string) {
+	fake.issueInvoiceMutex.RLock()
+	defer fake.issueInvoiceMutex.RUnlock()
+	argsForCall := fake.issueInvoiceArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) IssueInvoiceReturns(result1 eventio.Invoice, result2 error) {
+	fake.issueInvoiceMutex.Lock()
+	defer fake.issueInvoiceMutex.Unlock()
+	fake.IssueInvoiceStub = nil
+	fake.issueInvoiceReturns = struct {
+		result1 eventio.Invoice
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) IssueInvoiceReturnsOnCall(i int, result1 eventio.Invoice, result2 error) {
+	fake.issueInvoiceMutex.Lock()
+	defer fake.issueInvoiceMutex.Unlock()
+	fake.IssueInvoiceStub = nil
+	if fake.issueInvoiceReturnsOnCall == nil {
+		fake.issueInvoiceReturnsOnCall = make(map[int]struct {
+			result1 eventio.Invoice
+			result2 error
+		})
+	}
+	fake.issueInvoiceReturnsOnCall[i] = struct {
+		result1 eventio.Invoice
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) Rebuild() error {
+	fake.rebuildMutex.Lock()
+	ret, specificReturn := fake.rebuildReturnsOnCall[len(fake.rebuildArgsForCall)]
+	fake.rebuildArgsForCall = append(fake.rebuildArgsForCall, struct {
+	}{})
+	fake.recordInvocation("Rebuild", []interface{}{})
+	fake.rebuildMutex.Unlock()
+	if fake.RebuildStub != nil {
+		return fake.RebuildStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.rebuildReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeEventStore) RebuildCallCount() int {
+	fake.rebuildMutex.RLock()
+	defer fake.rebuildMutex.RUnlock()
+	return len(fake.rebuildArgsForCall)
+}
+
+func (fake *FakeEventStore) RebuildCalls(stub func() error) {
+	fake.rebuildMutex.Lock()
+	defer fake.rebuildMutex.Unlock()
+	fake.RebuildStub = stub
+}
+
+func (fake *FakeEventStore) RebuildReturns(result1 error) {
+	fake.rebuildMutex.Lock()
+	defer fake.rebuildMutex.Unlock()
+	fake.RebuildStub = nil
+	fake.rebuildReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) RebuildReturnsOnCall(i int, result1 error) {
+	fake.rebuildMutex.Lock()
+	defer fake.rebuildMutex.Unlock()
+	fake.RebuildStub = nil
+	if fake.rebuildReturnsOnCall == nil {
+		fake.rebuildReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.rebuildReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) RecordWebhookAttempt(arg1 int64, arg2 eventio.WebhookAttempt) error {
+	fake.recordWebhookAttemptMutex.Lock()
+	ret, specificReturn := fake.recordWebhookAttemptReturnsOnCall[len(fake.recordWebhookAttemptArgsForCall)]
+	fake.recordWebhookAttemptArgsForCall = append(fake.recordWebhookAttemptArgsForCall, struct {
+		arg1 int64
+		arg2 eventio.WebhookAttempt
+	}{arg1, arg2})
+	fake.recordInvocation("RecordWebhookAttempt", []interface{}{arg1, arg2})
+	fake.recordWebhookAttemptMutex.Unlock()
+	if fake.RecordWebhookAttemptStub != nil {
+		return fake.RecordWebhookAttemptStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.recordWebhookAttemptReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeEventStore) RecordWebhookAttemptCallCount() int {
+	fake.recordWebhookAttemptMutex.RLock()
+	defer fake.recordWebhookAttemptMutex.RUnlock()
+	return len(fake.recordWebhookAttemptArgsForCall)
+}
+
+func (fake *FakeEventStore) RecordWebhookAttemptCalls(stub func(int64, eventio.WebhookAttempt) error) {
+	fake.recordWebhookAttemptMutex.Lock()
+	defer fake.recordWebhookAttemptMutex.Unlock()
+	fake.RecordWebhookAttemptStub = stub
+}
+
+func (fake *FakeEventStore) RecordWebhookAttemptArgsForCall(i int) (int64, eventio.WebhookAttempt) {
+	fake.recordWebhookAttemptMutex.RLock()
+	defer fake.recordWebhookAttemptMutex.RUnlock()
+	argsForCall := fake.recordWebhookAttemptArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) RecordWebhookAttemptReturns(result1 error) {
+	fake.recordWebhookAttemptMutex.Lock()
+	defer fake.recordWebhookAttemptMutex.Unlock()
+	fake.RecordWebhookAttemptStub = nil
+	fake.recordWebhookAttemptReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) RecordWebhookAttemptReturnsOnCall(i int, result1 error) {
+	fake.recordWebhookAttemptMutex.Lock()
+	defer fake.recordWebhookAttemptMutex.Unlock()
+	fake.RecordWebhookAttemptStub = nil
+	if fake.recordWebhookAttemptReturnsOnCall == nil {
+		fake.recordWebhookAttemptReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.recordWebhookAttemptReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeEventStore) Refresh() error {
 	fake.refreshMutex.Lock()
 	ret, specificReturn := fake.refreshReturnsOnCall[len(fake.refreshArgsForCall)]
@@ -1429,6 +3808,253 @@ func (fake *FakeEventStore) RefreshReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeEventStore) RetryWebhookDelivery(arg1 int64, arg2 int64) (eventio.WebhookDelivery, error) {
+	fake.retryWebhookDeliveryMutex.Lock()
+	ret, specificReturn := fake.retryWebhookDeliveryReturnsOnCall[len(fake.retryWebhookDeliveryArgsForCall)]
+	fake.retryWebhookDeliveryArgsForCall = append(fake.retryWebhookDeliveryArgsForCall, struct {
+		arg1 int64
+		arg2 int64
+	}{arg1, arg2})
+	fake.recordInvocation("RetryWebhookDelivery", []interface{}{arg1, arg2})
+	fake.retryWebhookDeliveryMutex.Unlock()
+	if fake.RetryWebhookDeliveryStub != nil {
+		return fake.RetryWebhookDeliveryStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.retryWebhookDeliveryReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) RetryWebhookDeliveryCallCount() int {
+	fake.retryWebhookDeliveryMutex.RLock()
+	defer fake.retryWebhookDeliveryMutex.RUnlock()
+	return len(fake.retryWebhookDeliveryArgsForCall)
+}
+
+func (fake *FakeEventStore) RetryWebhookDeliveryCalls(stub func(int64, int64) (eventio.WebhookDelivery, error)) {
+	fake.retryWebhookDeliveryMutex.Lock()
+	defer fake.retryWebhookDeliveryMutex.Unlock()
+	fake.RetryWebhookDeliveryStub = stub
+}
+
+func (fake *FakeEventStore) RetryWebhookDeliveryArgsForCall(i int) (int64, int64) {
+	fake.retryWebhookDeliveryMutex.RLock()
+	defer fake.retryWebhookDeliveryMutex.RUnlock()
+	argsForCall := fake.retryWebhookDeliveryArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) RetryWebhookDeliveryReturns(result1 eventio.WebhookDelivery, result2 error) {
+	fake.retryWebhookDeliveryMutex.Lock()
+	defer fake.retryWebhookDeliveryMutex.Unlock()
+	fake.RetryWebhookDeliveryStub = nil
+	fake.retryWebhookDeliveryReturns = struct {
+		result1 eventio.WebhookDelivery
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) RetryWebhookDeliveryReturnsOnCall(i int, result1 eventio.WebhookDelivery, result2 error) {
+	fake.retryWebhookDeliveryMutex.Lock()
+	defer fake.retryWebhookDeliveryMutex.Unlock()
+	fake.RetryWebhookDeliveryStub = nil
+	if fake.retryWebhookDeliveryReturnsOnCall == nil {
+		fake.retryWebhookDeliveryReturnsOnCall = make(map[int]struct {
+			result1 eventio.WebhookDelivery
+			result2 error
+		})
+	}
+	fake.retryWebhookDeliveryReturnsOnCall[i] = struct {
+		result1 eventio.WebhookDelivery
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) SetAnomalyNotified(arg1 int64) error {
+	fake.setAnomalyNotifiedMutex.Lock()
+	ret, specificReturn := fake.setAnomalyNotifiedReturnsOnCall[len(fake.setAnomalyNotifiedArgsForCall)]
+	fake.setAnomalyNotifiedArgsForCall = append(fake.setAnomalyNotifiedArgsForCall, struct {
+		arg1 int64
+	}{arg1})
+	fake.recordInvocation("SetAnomalyNotified", []interface{}{arg1})
+	fake.setAnomalyNotifiedMutex.Unlock()
+	if fake.SetAnomalyNotifiedStub != nil {
+		return fake.SetAnomalyNotifiedStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.setAnomalyNotifiedReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeEventStore) SetAnomalyNotifiedCallCount() int {
+	fake.setAnomalyNotifiedMutex.RLock()
+	defer fake.setAnomalyNotifiedMutex.RUnlock()
+	return len(fake.setAnomalyNotifiedArgsForCall)
+}
+
+func (fake *FakeEventStore) SetAnomalyNotifiedCalls(stub func(int64) error) {
+	fake.setAnomalyNotifiedMutex.Lock()
+	defer fake.setAnomalyNotifiedMutex.Unlock()
+	fake.SetAnomalyNotifiedStub = stub
+}
+
+func (fake *FakeEventStore) SetAnomalyNotifiedArgsForCall(i int) int64 {
+	fake.setAnomalyNotifiedMutex.RLock()
+	defer fake.setAnomalyNotifiedMutex.RUnlock()
+	argsForCall := fake.setAnomalyNotifiedArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) SetAnomalyNotifiedReturns(result1 error) {
+	fake.setAnomalyNotifiedMutex.Lock()
+	defer fake.setAnomalyNotifiedMutex.Unlock()
+	fake.SetAnomalyNotifiedStub = nil
+	fake.setAnomalyNotifiedReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) SetAnomalyNotifiedReturnsOnCall(i int, result1 error) {
+	fake.setAnomalyNotifiedMutex.Lock()
+	defer fake.setAnomalyNotifiedMutex.Unlock()
+	fake.SetAnomalyNotifiedStub = nil
+	if fake.setAnomalyNotifiedReturnsOnCall == nil {
+		fake.setAnomalyNotifiedReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.setAnomalyNotifiedReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) SetBudget(arg1 eventio.Budget) (eventio.Budget, error) {
+	fake.setBudgetMutex.Lock()
+	ret, specificReturn := fake.setBudgetReturnsOnCall[len(fake.setBudgetArgsForCall)]
+	fake.setBudgetArgsForCall = append(fake.setBudgetArgsForCall, struct {
+		arg1 eventio.Budget
+	}{arg1})
+	fake.recordInvocation("SetBudget", []interface{}{arg1})
+	fake.setBudgetMutex.Unlock()
+	if fake.SetBudgetStub != nil {
+		return fake.SetBudgetStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.setBudgetReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) SetBudgetCallCount() int {
+	fake.setBudgetMutex.RLock()
+	defer fake.setBudgetMutex.RUnlock()
+	return len(fake.setBudgetArgsForCall)
+}
+
+func (fake *FakeEventStore) SetBudgetCalls(stub func(eventio.Budget) (eventio.Budget, error)) {
+	fake.setBudgetMutex.Lock()
+	defer fake.setBudgetMutex.Unlock()
+	fake.SetBudgetStub = stub
+}
+
+func (fake *FakeEventStore) SetBudgetArgsForCall(i int) eventio.Budget {
+	fake.setBudgetMutex.RLock()
+	defer fake.setBudgetMutex.RUnlock()
+	argsForCall := fake.setBudgetArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) SetBudgetReturns(result1 eventio.Budget, result2 error) {
+	fake.setBudgetMutex.Lock()
+	defer fake.setBudgetMutex.Unlock()
+	fake.SetBudgetStub = nil
+	fake.setBudgetReturns = struct {
+		result1 eventio.Budget
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) SetBudgetReturnsOnCall(i int, result1 eventio.Budget, result2 error) {
+	fake.setBudgetMutex.Lock()
+	defer fake.setBudgetMutex.Unlock()
+	fake.SetBudgetStub = nil
+	if fake.setBudgetReturnsOnCall == nil {
+		fake.setBudgetReturnsOnCall = make(map[int]struct {
+			result1 eventio.Budget
+			result2 error
+		})
+	}
+	fake.setBudgetReturnsOnCall[i] = struct {
+		result1 eventio.Budget
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) SetBudgetAlertSent(arg1 int64) error {
+	fake.setBudgetAlertSentMutex.Lock()
+	ret, specificReturn := fake.setBudgetAlertSentReturnsOnCall[len(fake.setBudgetAlertSentArgsForCall)]
+	fake.setBudgetAlertSentArgsForCall = append(fake.setBudgetAlertSentArgsForCall, struct {
+		arg1 int64
+	}{arg1})
+	fake.recordInvocation("SetBudgetAlertSent", []interface{}{arg1})
+	fake.setBudgetAlertSentMutex.Unlock()
+	if fake.SetBudgetAlertSentStub != nil {
+		return fake.SetBudgetAlertSentStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.setBudgetAlertSentReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeEventStore) SetBudgetAlertSentCallCount() int {
+	fake.setBudgetAlertSentMutex.RLock()
+	defer fake.setBudgetAlertSentMutex.RUnlock()
+	return len(fake.setBudgetAlertSentArgsForCall)
+}
+
+func (fake *FakeEventStore) SetBudgetAlertSentCalls(stub func(int64) error) {
+	fake.setBudgetAlertSentMutex.Lock()
+	defer fake.setBudgetAlertSentMutex.Unlock()
+	fake.SetBudgetAlertSentStub = stub
+}
+
+func (fake *FakeEventStore) SetBudgetAlertSentArgsForCall(i int) int64 {
+	fake.setBudgetAlertSentMutex.RLock()
+	defer fake.setBudgetAlertSentMutex.RUnlock()
+	argsForCall := fake.setBudgetAlertSentArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) SetBudgetAlertSentReturns(result1 error) {
+	fake.setBudgetAlertSentMutex.Lock()
+	defer fake.setBudgetAlertSentMutex.Unlock()
+	fake.SetBudgetAlertSentStub = nil
+	fake.setBudgetAlertSentReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) SetBudgetAlertSentReturnsOnCall(i int, result1 error) {
+	fake.setBudgetAlertSentMutex.Lock()
+	defer fake.setBudgetAlertSentMutex.Unlock()
+	fake.SetBudgetAlertSentStub = nil
+	if fake.setBudgetAlertSentReturnsOnCall == nil {
+		fake.setBudgetAlertSentReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.setBudgetAlertSentReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeEventStore) StoreEvents(arg1 []eventio.RawEvent) error {
 	var arg1Copy []eventio.RawEvent
 	if arg1 != nil {
@@ -1497,44 +4123,114 @@ func (fake *FakeEventStore) StoreEventsReturnsOnCall(i int, result1 error) {
 func (fake *FakeEventStore) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.addCurrencyRateVersionMutex.RLock()
+	defer fake.addCurrencyRateVersionMutex.RUnlock()
+	fake.addOrgTaxTreatmentVersionMutex.RLock()
+	defer fake.addOrgTaxTreatmentVersionMutex.RUnlock()
+	fake.addPricingPlanVersionMutex.RLock()
+	defer fake.addPricingPlanVersionMutex.RUnlock()
+	fake.addVATRateVersionMutex.RLock()
+	defer fake.addVATRateVersionMutex.RUnlock()
+	fake.addWebhookMutex.RLock()
+	defer fake.addWebhookMutex.RUnlock()
 	fake.consolidateMutex.RLock()
 	defer fake.consolidateMutex.RUnlock()
 	fake.consolidateAllMutex.RLock()
 	defer fake.consolidateAllMutex.RUnlock()
 	fake.consolidateFullMonthsMutex.RLock()
 	defer fake.consolidateFullMonthsMutex.RUnlock()
+	fake.creditInvoiceMutex.RLock()
+	defer fake.creditInvoiceMutex.RUnlock()
+	fake.deleteBudgetMutex.RLock()
+	defer fake.deleteBudgetMutex.RUnlock()
+	fake.deleteWebhookMutex.RLock()
+	defer fake.deleteWebhookMutex.RUnlock()
+	fake.detectAnomaliesMutex.RLock()
+	defer fake.detectAnomaliesMutex.RUnlock()
+	fake.emitWebhookEventMutex.RLock()
+	defer fake.emitWebhookEventMutex.RUnlock()
+	fake.evaluateBudgetsMutex.RLock()
+	defer fake.evaluateBudgetsMutex.RUnlock()
 	fake.forecastBillableEventRowsMutex.RLock()
 	defer fake.forecastBillableEventRowsMutex.RUnlock()
 	fake.forecastBillableEventsMutex.RLock()
 	defer fake.forecastBillableEventsMutex.RUnlock()
+	fake.forecastScenarioBillableEventRowsMutex.RLock()
+	defer fake.forecastScenarioBillableEventRowsMutex.RUnlock()
+	fake.forecastScenarioBillableEventsMutex.RLock()
+	defer fake.forecastScenarioBillableEventsMutex.RUnlock()
+	fake.getAnomaliesMutex.RLock()
+	defer fake.getAnomaliesMutex.RUnlock()
 	fake.getBillableEventRowsMutex.RLock()
 	defer fake.getBillableEventRowsMutex.RUnlock()
 	fake.getBillableEventsMutex.RLock()
 	defer fake.getBillableEventsMutex.RUnlock()
+	fake.getBillableSummaryMutex.RLock()
+	defer fake.getBillableSummaryMutex.RUnlock()
+	fake.getBillingPeriodMutex.RLock()
+	defer fake.getBillingPeriodMutex.RUnlock()
+	fake.getBudgetsMutex.RLock()
+	defer fake.getBudgetsMutex.RUnlock()
 	fake.getConsolidatedBillableEventRowsMutex.RLock()
 	defer fake.getConsolidatedBillableEventRowsMutex.RUnlock()
 	fake.getConsolidatedBillableEventsMutex.RLock()
 	defer fake.getConsolidatedBillableEventsMutex.RUnlock()
+	fake.getCurrencyRateVersionsMutex.RLock()
+	defer fake.getCurrencyRateVersionsMutex.RUnlock()
 	fake.getCurrencyRatesMutex.RLock()
 	defer fake.getCurrencyRatesMutex.RUnlock()
+	fake.getDueWebhookDeliveriesMutex.RLock()
+	defer fake.getDueWebhookDeliveriesMutex.RUnlock()
 	fake.getEventsMutex.RLock()
 	defer fake.getEventsMutex.RUnlock()
+	fake.getInvoiceMutex.RLock()
+	defer fake.getInvoiceMutex.RUnlock()
+	fake.getInvoicesMutex.RLock()
+	defer fake.getInvoicesMutex.RUnlock()
+	fake.getMonthEndProjectionsMutex.RLock()
+	defer fake.getMonthEndProjectionsMutex.RUnlock()
+	fake.getOrgTaxTreatmentVersionsMutex.RLock()
+	defer fake.getOrgTaxTreatmentVersionsMutex.RUnlock()
+	fake.getPricingPlanVersionsMutex.RLock()
+	defer fake.getPricingPlanVersionsMutex.RUnlock()
 	fake.getPricingPlansMutex.RLock()
 	defer fake.getPricingPlansMutex.RUnlock()
 	fake.getTotalCostMutex.RLock()
 	defer fake.getTotalCostMutex.RUnlock()
+	fake.getUnnotifiedAnomaliesMutex.RLock()
+	defer fake.getUnnotifiedAnomaliesMutex.RUnlock()
 	fake.getUsageEventRowsMutex.RLock()
 	defer fake.getUsageEventRowsMutex.RUnlock()
 	fake.getUsageEventsMutex.RLock()
 	defer fake.getUsageEventsMutex.RUnlock()
+	fake.getVATRateVersionsMutex.RLock()
+	defer fake.getVATRateVersionsMutex.RUnlock()
 	fake.getVATRatesMutex.RLock()
 	defer fake.getVATRatesMutex.RUnlock()
+	fake.getWebhookDeliveriesMutex.RLock()
+	defer fake.getWebhookDeliveriesMutex.RUnlock()
+	fake.getWebhooksMutex.RLock()
+	defer fake.getWebhooksMutex.RUnlock()
 	fake.initMutex.RLock()
 	defer fake.initMutex.RUnlock()
 	fake.isRangeConsolidatedMutex.RLock()
 	defer fake.isRangeConsolidatedMutex.RUnlock()
+	fake.issueInvoiceMutex.RLock()
+	defer fake.issueInvoiceMutex.RUnlock()
+	fake.rebuildMutex.RLock()
+	defer fake.rebuildMutex.RUnlock()
+	fake.recordWebhookAttemptMutex.RLock()
+	defer fake.recordWebhookAttemptMutex.RUnlock()
 	fake.refreshMutex.RLock()
 	defer fake.refreshMutex.RUnlock()
+	fake.retryWebhookDeliveryMutex.RLock()
+	defer fake.retryWebhookDeliveryMutex.RUnlock()
+	fake.setAnomalyNotifiedMutex.RLock()
+	defer fake.setAnomalyNotifiedMutex.RUnlock()
+	fake.setBudgetMutex.RLock()
+	defer fake.setBudgetMutex.RUnlock()
+	fake.setBudgetAlertSentMutex.RLock()
+	defer fake.setBudgetAlertSentMutex.RUnlock()
 	fake.storeEventsMutex.RLock()
 	defer fake.storeEventsMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
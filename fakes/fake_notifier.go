@@ -0,0 +1,114 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package fakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/notifier"
+)
+
+type FakeNotifier struct {
+	NotifyStub        func(context.Context, eventio.BudgetAlert) error
+	notifyMutex       sync.RWMutex
+	notifyArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.BudgetAlert
+	}
+	notifyReturns struct {
+		result1 error
+	}
+	notifyReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeNotifier) Notify(arg1 context.Context, arg2 eventio.BudgetAlert) error {
+	fake.notifyMutex.Lock()
+	ret, specificReturn := fake.notifyReturnsOnCall[len(fake.notifyArgsForCall)]
+	fake.notifyArgsForCall = append(fake.notifyArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.BudgetAlert
+	}{arg1, arg2})
+	fake.recordInvocation("Notify", []interface{}{arg1, arg2})
+	fake.notifyMutex.Unlock()
+	if fake.NotifyStub != nil {
+		return fake.NotifyStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.notifyReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeNotifier) NotifyCallCount() int {
+	fake.notifyMutex.RLock()
+	defer fake.notifyMutex.RUnlock()
+	return len(fake.notifyArgsForCall)
+}
+
+func (fake *FakeNotifier) NotifyCalls(stub func(context.Context, eventio.BudgetAlert) error) {
+	fake.notifyMutex.Lock()
+	defer fake.notifyMutex.Unlock()
+	fake.NotifyStub = stub
+}
+
+func (fake *FakeNotifier) NotifyArgsForCall(i int) (context.Context, eventio.BudgetAlert) {
+	fake.notifyMutex.RLock()
+	defer fake.notifyMutex.RUnlock()
+	argsForCall := fake.notifyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeNotifier) NotifyReturns(result1 error) {
+	fake.notifyMutex.Lock()
+	defer fake.notifyMutex.Unlock()
+	fake.NotifyStub = nil
+	fake.notifyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeNotifier) NotifyReturnsOnCall(i int, result1 error) {
+	fake.notifyMutex.Lock()
+	defer fake.notifyMutex.Unlock()
+	fake.NotifyStub = nil
+	if fake.notifyReturnsOnCall == nil {
+		fake.notifyReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.notifyReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeNotifier) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.notifyMutex.RLock()
+	defer fake.notifyMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeNotifier) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ notifier.Notifier = new(FakeNotifier)
@@ -2,13 +2,20 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/signal"
 	"syscall"
+	"text/tabwriter"
 
 	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
 )
 
 func Main(ctx context.Context, logger lager.Logger) error {
@@ -19,14 +26,23 @@ func Main(ctx context.Context, logger lager.Logger) error {
 	}
 	cfg.Logger = logger
 
+	if len(os.Args) < 2 {
+		return errors.New("Please provide a command to run [api | collector | validate-config | preview-config]")
+	}
+	// the config tools do not need the CF API so are run before the app is
+	// created
+	switch os.Args[1] {
+	case "validate-config":
+		return validateConfig(os.Stdout, cfg, os.Args[2:])
+	case "preview-config":
+		return previewConfig(ctx, os.Stdout, cfg, os.Args[2:])
+	}
+
 	app, err := New(ctx, cfg)
 	if err != nil {
 		return err
 	}
 
-	if len(os.Args) < 2 {
-		return errors.New("Please provide a command to run [api | collector]")
-	}
 	switch command := os.Args[1]; command {
 	case "collector":
 		return startCollector(app, cfg)
@@ -55,6 +71,9 @@ func startCollector(app *App, cfg Config) error {
 	if err := app.StartHistoricDataCollector(); err != nil {
 		return err
 	}
+	if err := app.StartWebhookDispatcher(); err != nil {
+		return err
+	}
 
 	cfg.Logger.Info("started collector")
 	return app.Wait()
@@ -68,6 +87,98 @@ func startAPI(app *App, cfg Config) error {
 	return app.Wait()
 }
 
+// validateConfig checks the pricing config, either the file given in args
+// or config.json in the app root, and writes every problem found to w
+func validateConfig(w io.Writer, cfg Config, args []string) error {
+	var filename string
+	switch len(args) {
+	case 0:
+		f, err := cfg.ConfigFile()
+		if err != nil {
+			return err
+		}
+		filename = f
+	case 1:
+		filename = args[0]
+	default:
+		return errors.New("usage: validate-config [path/to/config.json]")
+	}
+	problems, err := eventstore.ValidateConfigFile(filename)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(w, "%s: %s\n", filename, problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s: found %d problem(s)", filename, len(problems))
+	}
+	fmt.Fprintf(w, "%s: ok\n", filename)
+	return nil
+}
+
+// previewConfig prints the change in cost of each org and plan if the
+// proposed config were to replace the latest stored pricing versions, or the
+// config given by -current, using the events stored in the database for the
+// given range
+func previewConfig(ctx context.Context, w io.Writer, cfg Config, args []string) error {
+	usage := errors.New("usage: preview-config [-current current.json] <proposed.json> <range_start> <range_stop> [org_guid...]")
+	flags := flag.NewFlagSet("preview-config", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	currentFile := flags.String("current", "", "")
+	if err := flags.Parse(args); err != nil {
+		return usage
+	}
+	args = flags.Args()
+	if len(args) < 3 {
+		return usage
+	}
+	var current *eventstore.Config
+	if *currentFile != "" {
+		c, err := eventstore.LoadConfig(*currentFile)
+		if err != nil {
+			return err
+		}
+		current = &c
+	}
+	proposed, err := eventstore.LoadConfig(args[0])
+	if err != nil {
+		return err
+	}
+	filter := eventio.EventFilter{
+		RangeStart: args[1],
+		RangeStop:  args[2],
+		OrgGUIDs:   args[3:],
+	}
+
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	store := eventstore.New(ctx, db, cfg.Logger.Session("store"), proposed)
+	preview, err := store.PreviewConfig(current, proposed, filter)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, section := range []struct {
+		title   string
+		changes []eventstore.PriceChange
+	}{
+		{"ORG", preview.Orgs},
+		{"PLAN", preview.Plans},
+	} {
+		fmt.Fprintf(tw, "%s GUID\t%s NAME\tCURRENT\tPROPOSED\tDIFFERENCE\t\n", section.title, section.title)
+		for _, c := range section.changes {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n", c.GUID, c.Name, c.Current, c.Proposed, c.Difference)
+		}
+		fmt.Fprintln(tw, "\t\t\t\t\t")
+	}
+	return tw.Flush()
+}
+
 func main() {
 	ctx, shutdown := context.WithCancel(context.Background())
 
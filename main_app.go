@@ -16,6 +16,8 @@ import (
 	"github.com/alphagov/paas-billing/eventfetchers/cffetcher"
 	"github.com/alphagov/paas-billing/eventio"
 	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/notifier"
+	"github.com/alphagov/paas-billing/webhooks"
 	"github.com/cloudfoundry-community/go-cfclient"
 	"github.com/pkg/errors"
 )
@@ -106,35 +108,172 @@ func (app *App) StartEventProcessor() error {
 	name := "processor"
 	logger := app.logger.Session(name)
 	return app.start(name, logger, func() error {
-		runRefreshAndConsolidateLoop(app.ctx, logger, app.cfg.Processor.Schedule, app.store)
+		runRefreshAndConsolidateLoop(app.ctx, logger, app.cfg.Processor, app.store)
 		return nil
 	})
 }
 
-func runRefreshAndConsolidateLoop(ctx context.Context, logger lager.Logger, schedule time.Duration, store eventio.EventStore) {
+func runRefreshAndConsolidateLoop(ctx context.Context, logger lager.Logger, cfg ProcessorConfig, store eventio.EventStore) {
 	logger.Info("started")
 	defer logger.Info("stopping")
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-time.After(schedule):
-			logger.Info("processing")
-			if err := store.Refresh(); err != nil {
+		case <-time.After(cfg.Schedule):
+			logger.Info("processing", lager.Data{
+				"full_rebuild": cfg.FullRebuild,
+			})
+			refresh := store.Refresh
+			if cfg.FullRebuild {
+				refresh = store.Rebuild
+			}
+			if err := refresh(); err != nil {
 				logger.Error("refresh-error", err)
+				emitRefreshEvent(logger, store, cfg.FullRebuild, err)
 				continue
 			}
+			emitRefreshEvent(logger, store, cfg.FullRebuild, nil)
+			if cfg.Notifier != nil {
+				sendBudgetAlerts(ctx, logger, store, cfg.Notifier)
+			}
+			detectAnomalies(ctx, logger, store, cfg.Anomalies, cfg.AnomalyNotifier)
 			if err := store.ConsolidateAll(); err != nil {
 				logger.Error("consolidate-error", err)
 				continue
 			}
 		}
 		logger.Info("processed", lager.Data{
-			"next_processing_in": schedule.String(),
+			"next_processing_in": cfg.Schedule.String(),
 		})
 	}
 }
 
+// sendBudgetAlerts raises the alerts for any org that has reached a
+// threshold of its budget and sends the alerts that have not been sent.
+// Alerts that fail to send are tried again after the next refresh.
+func sendBudgetAlerts(ctx context.Context, logger lager.Logger, store eventio.BudgetAlerter, n notifier.Notifier) {
+	alerts, err := store.EvaluateBudgets()
+	if err != nil {
+		logger.Error("evaluate-budgets-error", err)
+		return
+	}
+	for _, alert := range alerts {
+		data := lager.Data{
+			"id":           alert.ID,
+			"org_guid":     alert.OrgGUID,
+			"period_start": alert.PeriodStart,
+			"threshold":    alert.Threshold,
+		}
+		if err := n.Notify(ctx, alert); err != nil {
+			logger.Error("send-budget-alert-error", err, data)
+			continue
+		}
+		if err := store.SetBudgetAlertSent(alert.ID); err != nil {
+			logger.Error("send-budget-alert-error", err, data)
+			continue
+		}
+		logger.Info("sent-budget-alert", data)
+	}
+}
+
+// detectAnomalies records the unusual costs of the last day and, if there is
+// an AnomalyNotifier, sends every anomaly that has not been sent. Anomalies
+// that fail to send are tried again after the next refresh.
+func detectAnomalies(ctx context.Context, logger lager.Logger, store eventio.AnomalyDetector, cfg eventio.AnomalyConfig, n notifier.AnomalyNotifier) {
+	anomalies, err := store.DetectAnomalies(cfg)
+	if err != nil {
+		logger.Error("detect-anomalies-error", err)
+		return
+	}
+	for _, anomaly := range anomalies {
+		logger.Info("anomaly", anomalyLogData(anomaly))
+	}
+	if n == nil {
+		return
+	}
+	unnotified, err := store.GetUnnotifiedAnomalies()
+	if err != nil {
+		logger.Error("send-anomaly-error", err)
+		return
+	}
+	for _, anomaly := range unnotified {
+		data := anomalyLogData(anomaly)
+		if err := n.NotifyAnomaly(ctx, anomaly); err != nil {
+			logger.Error("send-anomaly-error", err, data)
+			continue
+		}
+		if err := store.SetAnomalyNotified(anomaly.ID); err != nil {
+			logger.Error("send-anomaly-error", err, data)
+			continue
+		}
+		logger.Info("sent-anomaly", data)
+	}
+}
+
+func anomalyLogData(anomaly eventio.Anomaly) lager.Data {
+	return lager.Data{
+		"id":       anomaly.ID,
+		"day":      anomaly.Day,
+		"level":    anomaly.Level,
+		"org_guid": anomaly.OrgGUID,
+		"cost":     anomaly.Cost,
+		"baseline": anomaly.Baseline,
+	}
+}
+
+// emitRefreshEvent emits a RefreshCompleted event, or a RefreshFailed event
+// if err is not nil. A refresh that failed because of a plan without pricing
+// also emits a PlanDetected event for the plan. Failures are only logged.
+func emitRefreshEvent(logger lager.Logger, store eventio.WebhookEmitter, fullRebuild bool, err error) {
+	events := []eventio.WebhookEvent{}
+	eventType := eventio.RefreshCompleted
+	data := eventio.RefreshData{FullRebuild: fullRebuild}
+	if err != nil {
+		eventType = eventio.RefreshFailed
+		data.Error = err.Error()
+		if missing, ok := errors.Cause(err).(*eventstore.MissingPlanError); ok {
+			event, err := eventstore.NewPlanDetectedEvent(eventio.PlanDetectedData{
+				PlanGUID:     missing.PlanGUID,
+				PlanName:     missing.PlanName,
+				ResourceType: missing.ResourceType,
+			})
+			if err != nil {
+				logger.Error("emit-webhook-event-error", err)
+				return
+			}
+			events = append(events, event)
+		}
+	}
+	now := time.Now().UTC()
+	event, err := eventio.NewWebhookEvent(eventType, fmt.Sprintf("%s:%s", eventType, now.Format(time.RFC3339Nano)), data)
+	if err != nil {
+		logger.Error("emit-webhook-event-error", err)
+		return
+	}
+	events = append([]eventio.WebhookEvent{event}, events...)
+	for _, event := range events {
+		if err := store.EmitWebhookEvent(event); err != nil {
+			logger.Error("emit-webhook-event-error", err, lager.Data{
+				"id":   event.ID,
+				"type": event.Type,
+			})
+		}
+	}
+}
+
+func (app *App) StartWebhookDispatcher() error {
+	name := "webhook-dispatcher"
+	logger := app.logger.Session(name)
+	cfg := app.cfg.Webhooks
+	cfg.Logger = logger
+	cfg.Store = app.store
+	dispatcher := webhooks.New(cfg)
+	return app.start(name, logger, func() error {
+		return dispatcher.Run(app.ctx)
+	})
+}
+
 func (app *App) StartHistoricDataCollector() error {
 	name := "historic-data-collector"
 	logger := app.logger.Session(name)
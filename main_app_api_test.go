@@ -180,8 +180,8 @@ var _ = It("Should perform a smoke test against a real environment", func() {
 			Expect(ev.ResourceType).ToNot(BeEmpty())
 			Expect(ev.ResourceName).ToNot(BeEmpty())
 			Expect(ev.PlanGUID).ToNot(BeEmpty())
-			Expect(ev.Price.IncVAT).ToNot(BeEmpty())
-			Expect(ev.Price.ExVAT).ToNot(BeEmpty())
+			Expect(ev.Price.IncVAT.String()).ToNot(BeEmpty())
+			Expect(ev.Price.ExVAT.String()).ToNot(BeEmpty())
 			Expect(len(ev.Price.Details)).To(BeNumerically(">", 0))
 		}
 	})
@@ -227,7 +227,7 @@ var _ = It("Should perform a smoke test against a real environment", func() {
 			Expect(ev.EventGUID).ToNot(BeEmpty())
 			Expect(ev.EventStart).To(Equal("2001-01-01T00:00:00+00:00"))
 			Expect(ev.EventStop).To(Equal("2001-02-01T00:00:00+00:00"))
-			Expect(ev.Price.ExVAT).To(Equal("0.01"))
+			Expect(ev.Price.ExVAT).To(Equal(eventio.MustParseDecimal("0.01")))
 		}
 	})
 
@@ -10,6 +10,8 @@ import (
 	"sync"
 
 	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
 	"github.com/alphagov/paas-billing/fakes"
 	"github.com/alphagov/paas-billing/testenv"
 	. "github.com/onsi/ginkgo"
@@ -125,7 +127,7 @@ var _ = Describe("runRefreshAndConsolidateLoop", func() {
 
 		go func() {
 			wg.Add(1)
-			runRefreshAndConsolidateLoop(ctx, logger, 1*time.Nanosecond, fakeStore)
+			runRefreshAndConsolidateLoop(ctx, logger, ProcessorConfig{Schedule: 1 * time.Nanosecond}, fakeStore)
 			wg.Done()
 		}()
 
@@ -149,7 +151,7 @@ var _ = Describe("runRefreshAndConsolidateLoop", func() {
 
 		go func() {
 			wg.Add(1)
-			runRefreshAndConsolidateLoop(ctx, logger, 1*time.Nanosecond, fakeStore)
+			runRefreshAndConsolidateLoop(ctx, logger, ProcessorConfig{Schedule: 1 * time.Nanosecond}, fakeStore)
 			wg.Done()
 		}()
 
@@ -161,4 +163,198 @@ var _ = Describe("runRefreshAndConsolidateLoop", func() {
 			return fakeStore.ConsolidateAllCallCount()
 		}).Should(BeNumerically("==", 0))
 	})
+
+	It("should call Rebuild instead of Refresh when FullRebuild is set", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+		wg := sync.WaitGroup{}
+		defer wg.Wait()
+		defer cancel()
+
+		wg.Add(1)
+		go func() {
+			runRefreshAndConsolidateLoop(ctx, logger, ProcessorConfig{Schedule: 1 * time.Nanosecond, FullRebuild: true}, fakeStore)
+			wg.Done()
+		}()
+
+		Eventually(func() int {
+			return fakeStore.RebuildCallCount()
+		}).Should(BeNumerically(">=", 1))
+
+		Eventually(func() int {
+			return fakeStore.ConsolidateAllCallCount()
+		}).Should(BeNumerically(">=", 1))
+
+		Expect(fakeStore.RefreshCallCount()).To(Equal(0))
+	})
+
+	It("should evaluate budgets after each refresh when there is a Notifier", func() {
+		fakeNotifier := &fakes.FakeNotifier{}
+		fakeStore.EvaluateBudgetsReturns([]eventio.BudgetAlert{{ID: 1}}, nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+		wg := sync.WaitGroup{}
+		defer wg.Wait()
+		defer cancel()
+
+		wg.Add(1)
+		go func() {
+			runRefreshAndConsolidateLoop(ctx, logger, ProcessorConfig{Schedule: 1 * time.Nanosecond, Notifier: fakeNotifier}, fakeStore)
+			wg.Done()
+		}()
+
+		Eventually(func() int {
+			return fakeNotifier.NotifyCallCount()
+		}).Should(BeNumerically(">=", 1))
+
+		Expect(fakeStore.RefreshCallCount()).To(BeNumerically(">=", fakeStore.EvaluateBudgetsCallCount()))
+	})
+
+	It("should detect anomalies after each refresh", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+		wg := sync.WaitGroup{}
+		defer wg.Wait()
+		defer cancel()
+
+		wg.Add(1)
+		go func() {
+			runRefreshAndConsolidateLoop(ctx, logger, ProcessorConfig{Schedule: 1 * time.Nanosecond, Anomalies: eventio.DefaultAnomalyConfig}, fakeStore)
+			wg.Done()
+		}()
+
+		Eventually(func() int {
+			return fakeStore.DetectAnomaliesCallCount()
+		}).Should(BeNumerically(">=", 1))
+		Expect(fakeStore.DetectAnomaliesArgsForCall(0)).To(Equal(eventio.DefaultAnomalyConfig))
+	})
+
+	It("should emit a webhook event for each refresh", func() {
+		fakeStore.RefreshReturnsOnCall(0, fmt.Errorf("some-error"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+		wg := sync.WaitGroup{}
+		defer wg.Wait()
+		defer cancel()
+
+		wg.Add(1)
+		go func() {
+			runRefreshAndConsolidateLoop(ctx, logger, ProcessorConfig{Schedule: 1 * time.Nanosecond}, fakeStore)
+			wg.Done()
+		}()
+
+		Eventually(func() int {
+			return fakeStore.EmitWebhookEventCallCount()
+		}).Should(BeNumerically(">=", 2))
+
+		failed := fakeStore.EmitWebhookEventArgsForCall(0)
+		Expect(failed.Type).To(Equal(eventio.RefreshFailed))
+		Expect(failed.Data).To(MatchJSON(`{"full_rebuild": false, "error": "some-error"}`))
+		completed := fakeStore.EmitWebhookEventArgsForCall(1)
+		Expect(completed.Type).To(Equal(eventio.RefreshCompleted))
+		Expect(completed.Data).To(MatchJSON(`{"full_rebuild": false}`))
+	})
+
+	It("should not evaluate budgets without a Notifier", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+		wg := sync.WaitGroup{}
+		defer wg.Wait()
+		defer cancel()
+
+		wg.Add(1)
+		go func() {
+			runRefreshAndConsolidateLoop(ctx, logger, ProcessorConfig{Schedule: 1 * time.Nanosecond}, fakeStore)
+			wg.Done()
+		}()
+
+		Eventually(func() int {
+			return fakeStore.ConsolidateAllCallCount()
+		}).Should(BeNumerically(">=", 1))
+
+		Expect(fakeStore.EvaluateBudgetsCallCount()).To(Equal(0))
+	})
+})
+
+var _ = Describe("sendBudgetAlerts", func() {
+	It("should only mark the alerts that were sent as sent", func() {
+		fakeStore := &fakes.FakeEventStore{}
+		fakeNotifier := &fakes.FakeNotifier{}
+		fakeNotifier.NotifyReturnsOnCall(0, fmt.Errorf("some-error"))
+		fakeStore.EvaluateBudgetsReturns([]eventio.BudgetAlert{{ID: 1}, {ID: 2}}, nil)
+
+		sendBudgetAlerts(context.Background(), lager.NewLogger("test"), fakeStore, fakeNotifier)
+
+		Expect(fakeNotifier.NotifyCallCount()).To(Equal(2))
+		Expect(fakeStore.SetBudgetAlertSentCallCount()).To(Equal(1))
+		Expect(fakeStore.SetBudgetAlertSentArgsForCall(0)).To(Equal(int64(2)))
+	})
+})
+
+var _ = Describe("detectAnomalies", func() {
+	It("should send every unsent anomaly and only mark those that were sent", func() {
+		fakeStore := &fakes.FakeEventStore{}
+		fakeNotifier := &fakes.FakeAnomalyNotifier{}
+		fakeNotifier.NotifyAnomalyReturnsOnCall(0, fmt.Errorf("some-error"))
+		fakeStore.DetectAnomaliesReturns([]eventio.Anomaly{{ID: 2}}, nil)
+		fakeStore.GetUnnotifiedAnomaliesReturns([]eventio.Anomaly{{ID: 1}, {ID: 2}}, nil)
+
+		detectAnomalies(context.Background(), lager.NewLogger("test"), fakeStore, eventio.DefaultAnomalyConfig, fakeNotifier)
+
+		Expect(fakeStore.DetectAnomaliesArgsForCall(0)).To(Equal(eventio.DefaultAnomalyConfig))
+		Expect(fakeNotifier.NotifyAnomalyCallCount()).To(Equal(2))
+		_, anomaly := fakeNotifier.NotifyAnomalyArgsForCall(1)
+		Expect(anomaly.ID).To(Equal(int64(2)))
+		Expect(fakeStore.SetAnomalyNotifiedCallCount()).To(Equal(1))
+		Expect(fakeStore.SetAnomalyNotifiedArgsForCall(0)).To(Equal(int64(2)))
+	})
+
+	It("should only record the anomalies without a notifier", func() {
+		fakeStore := &fakes.FakeEventStore{}
+		fakeStore.DetectAnomaliesReturns([]eventio.Anomaly{{ID: 1}}, nil)
+
+		detectAnomalies(context.Background(), lager.NewLogger("test"), fakeStore, eventio.DefaultAnomalyConfig, nil)
+
+		Expect(fakeStore.DetectAnomaliesCallCount()).To(Equal(1))
+		Expect(fakeStore.GetUnnotifiedAnomaliesCallCount()).To(Equal(0))
+		Expect(fakeStore.SetAnomalyNotifiedCallCount()).To(Equal(0))
+	})
+})
+
+var _ = Describe("emitRefreshEvent", func() {
+	It("should emit a PlanDetected event when a refresh fails for a plan without pricing", func() {
+		fakeStore := &fakes.FakeEventStore{}
+		err := &eventstore.MissingPlanError{
+			PlanGUID:     "c6221308-b7bb-46d2-9d79-a357f5a3837b",
+			PlanName:     "AWESOME_SERVICE_PLAN_NAME",
+			ResourceType: "service",
+		}
+
+		emitRefreshEvent(lager.NewLogger("test"), fakeStore, true, err)
+
+		Expect(fakeStore.EmitWebhookEventCallCount()).To(Equal(2))
+		failed := fakeStore.EmitWebhookEventArgsForCall(0)
+		Expect(failed.Type).To(Equal(eventio.RefreshFailed))
+		Expect(failed.Data).To(MatchJSON(`{"full_rebuild": true, "error": "missing 'service' pricing plan configuration for 'AWESOME_SERVICE_PLAN_NAME' (c6221308-b7bb-46d2-9d79-a357f5a3837b)"}`))
+		detected := fakeStore.EmitWebhookEventArgsForCall(1)
+		Expect(detected.Type).To(Equal(eventio.PlanDetected))
+		Expect(detected.ID).To(Equal("plan.detected:c6221308-b7bb-46d2-9d79-a357f5a3837b"))
+		Expect(detected.Data).To(MatchJSON(`{
+			"plan_guid": "c6221308-b7bb-46d2-9d79-a357f5a3837b",
+			"plan_name": "AWESOME_SERVICE_PLAN_NAME",
+			"resource_type": "service",
+			"placeholder": false
+		}`))
+	})
+
+	It("should only log failures to emit", func() {
+		fakeStore := &fakes.FakeEventStore{}
+		fakeStore.EmitWebhookEventReturns(fmt.Errorf("some-error"))
+
+		emitRefreshEvent(lager.NewLogger("test"), fakeStore, false, nil)
+
+		Expect(fakeStore.EmitWebhookEventCallCount()).To(Equal(1))
+	})
 })
@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"net"
 	"net/http"
+	"net/smtp"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -13,6 +15,8 @@ import (
 	"github.com/alphagov/paas-billing/eventcollector"
 	"github.com/alphagov/paas-billing/eventfetchers/cffetcher"
 	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/notifier"
+	"github.com/alphagov/paas-billing/webhooks"
 	cfclient "github.com/cloudfoundry-community/go-cfclient"
 	"github.com/pkg/errors"
 
@@ -29,6 +33,7 @@ type Config struct {
 	ServerPort            int
 	Processor             ProcessorConfig
 	HistoricDataCollector cfstore.Config
+	Webhooks              webhooks.Config
 }
 
 func (cfg Config) ConfigFile() (string, error) {
@@ -42,6 +47,18 @@ func (cfg Config) ConfigFile() (string, error) {
 
 type ProcessorConfig struct {
 	Schedule time.Duration
+	// FullRebuild causes every run to regenerate all events from scratch
+	// rather than only processing the events collected since the last run
+	FullRebuild bool
+	// Notifier sends the budget alerts raised after each refresh, budgets
+	// are not evaluated if it is nil
+	Notifier notifier.Notifier
+	// Anomalies is how unusual a day's cost must be to be recorded as an
+	// anomaly after each refresh
+	Anomalies eventio.AnomalyConfig
+	// AnomalyNotifier sends the anomalies found after each refresh, they are
+	// only recorded if it is nil
+	AnomalyNotifier notifier.AnomalyNotifier
 }
 
 func NewConfigFromEnv() (cfg Config, err error) {
@@ -98,13 +115,76 @@ func NewConfigFromEnv() (cfg Config, err error) {
 			FetchLimit:   getEnvWithDefaultInt("CF_FETCH_LIMIT", 50),
 		},
 		Processor: ProcessorConfig{
-			Schedule: getEnvWithDefaultDuration("PROCESSOR_SCHEDULE", 30*time.Minute),
+			Schedule:    getEnvWithDefaultDuration("PROCESSOR_SCHEDULE", 30*time.Minute),
+			FullRebuild: os.Getenv("PROCESSOR_FULL_REBUILD") == "true",
+			Notifier:    newNotifierFromEnv(),
+			Anomalies: eventio.AnomalyConfig{
+				BaselineDays:   getEnvWithDefaultInt("ANOMALY_BASELINE_DAYS", eventio.DefaultAnomalyConfig.BaselineDays),
+				MinHistoryDays: getEnvWithDefaultInt("ANOMALY_MIN_HISTORY_DAYS", eventio.DefaultAnomalyConfig.MinHistoryDays),
+				Factor:         getEnvWithDefaultDecimal("ANOMALY_FACTOR", eventio.DefaultAnomalyConfig.Factor),
+				MinIncrease:    getEnvWithDefaultDecimal("ANOMALY_MIN_INCREASE", eventio.DefaultAnomalyConfig.MinIncrease),
+			},
 		},
 		ServerPort: getEnvWithDefaultInt("PORT", 8881),
+		Webhooks: webhooks.Config{
+			Schedule:    getEnvWithDefaultDuration("WEBHOOK_SCHEDULE", webhooks.DefaultSchedule),
+			MaxAttempts: getEnvWithDefaultInt("WEBHOOK_MAX_ATTEMPTS", webhooks.DefaultMaxAttempts),
+			Client: &http.Client{
+				Timeout: 30 * time.Second,
+			},
+		},
+	}
+	if err := cfg.Processor.Anomalies.Validate(); err != nil {
+		return cfg, err
+	}
+	if cfg.Webhooks.MaxAttempts < 1 {
+		return cfg, fmt.Errorf("WEBHOOK_MAX_ATTEMPTS must be at least 1 - got %d", cfg.Webhooks.MaxAttempts)
+	}
+	if url := os.Getenv("ANOMALY_WEBHOOK_URL"); url != "" {
+		cfg.Processor.AnomalyNotifier = &notifier.Webhook{
+			URL: url,
+			Client: &http.Client{
+				Timeout: 30 * time.Second,
+			},
+		}
 	}
 	return cfg, nil
 }
 
+// newNotifierFromEnv returns a notifier for each way of sending budget alerts
+// that is configured, or nil if there are none
+func newNotifierFromEnv() notifier.Notifier {
+	notifiers := notifier.Multi{}
+	if url := os.Getenv("BUDGET_ALERT_WEBHOOK_URL"); url != "" {
+		notifiers = append(notifiers, &notifier.Webhook{
+			URL: url,
+			Client: &http.Client{
+				Timeout: 30 * time.Second,
+			},
+		})
+	}
+	if addr := os.Getenv("BUDGET_ALERT_SMTP_ADDR"); addr != "" {
+		var smtpAuth smtp.Auth
+		if username := os.Getenv("BUDGET_ALERT_SMTP_USERNAME"); username != "" {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				panic(fmt.Sprintf("BUDGET_ALERT_SMTP_ADDR must be a host:port - got %s", addr))
+			}
+			smtpAuth = smtp.PlainAuth("", username, os.Getenv("BUDGET_ALERT_SMTP_PASSWORD"), host)
+		}
+		notifiers = append(notifiers, &notifier.SMTP{
+			Addr: addr,
+			From: getEnvString("BUDGET_ALERT_SMTP_FROM"),
+			To:   strings.Split(getEnvString("BUDGET_ALERT_SMTP_TO"), ","),
+			Auth: smtpAuth,
+		})
+	}
+	if len(notifiers) == 0 {
+		return nil
+	}
+	return notifiers
+}
+
 func getEnvWithDefaultDuration(k string, def time.Duration) time.Duration {
 	v := getEnvWithDefaultString(k, "")
 	if v == "" {
@@ -129,6 +209,18 @@ func getEnvWithDefaultInt(k string, def int) int {
 	return n
 }
 
+func getEnvWithDefaultDecimal(k string, def eventio.Decimal) eventio.Decimal {
+	v := getEnvWithDefaultString(k, "")
+	if v == "" {
+		return def
+	}
+	d, err := eventio.ParseDecimal(v)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
 func getEnvWithDefaultString(k string, def string) string {
 	v := os.Getenv(k)
 	if v == "" {
@@ -4,6 +4,8 @@ import (
 	"os"
 	"time"
 
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/notifier"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
@@ -27,9 +29,26 @@ var _ = Describe("Config", func() {
 		os.Unsetenv("CF_TOKEN")
 		os.Unsetenv("CF_USER_AGENT")
 		os.Unsetenv("PROCESSOR_SCHEDULE")
+		os.Unsetenv("PROCESSOR_FULL_REBUILD")
 		os.Unsetenv("PORT")
 	})
 
+	AfterEach(func() {
+		// the other commands would fail to load an incomplete budget alert
+		// config
+		os.Unsetenv("BUDGET_ALERT_WEBHOOK_URL")
+		os.Unsetenv("BUDGET_ALERT_SMTP_ADDR")
+		os.Unsetenv("BUDGET_ALERT_SMTP_FROM")
+		os.Unsetenv("BUDGET_ALERT_SMTP_TO")
+		os.Unsetenv("ANOMALY_BASELINE_DAYS")
+		os.Unsetenv("ANOMALY_MIN_HISTORY_DAYS")
+		os.Unsetenv("ANOMALY_FACTOR")
+		os.Unsetenv("ANOMALY_MIN_INCREASE")
+		os.Unsetenv("ANOMALY_WEBHOOK_URL")
+		os.Unsetenv("WEBHOOK_SCHEDULE")
+		os.Unsetenv("WEBHOOK_MAX_ATTEMPTS")
+	})
+
 	It("should set sensible defaults for the config when no environment variables set", func() {
 		cfg, err := NewConfigFromEnv()
 		Expect(err).ToNot(HaveOccurred())
@@ -41,7 +60,13 @@ var _ = Describe("Config", func() {
 		Expect(cfg.CFFetcher.RecordMinAge).To(Equal(10 * time.Minute))
 		Expect(cfg.CFFetcher.FetchLimit).To(Equal(50))
 		Expect(cfg.Processor.Schedule).To(Equal(30 * time.Minute))
+		Expect(cfg.Processor.FullRebuild).To(BeFalse())
+		Expect(cfg.Processor.Notifier).To(BeNil())
+		Expect(cfg.Processor.Anomalies).To(Equal(eventio.DefaultAnomalyConfig))
+		Expect(cfg.Processor.AnomalyNotifier).To(BeNil())
 		Expect(cfg.ServerPort).To(Equal(8881))
+		Expect(cfg.Webhooks.Schedule).To(Equal(1 * time.Minute))
+		Expect(cfg.Webhooks.MaxAttempts).To(Equal(10))
 	})
 
 	DescribeTable("should return error when failing to parse durations",
@@ -163,4 +188,67 @@ var _ = Describe("Config", func() {
 		Expect(cfg.Processor.Schedule).To(Equal(12 * time.Hour))
 	})
 
+	It("should set Processor.FullRebuild from PROCESSOR_FULL_REBUILD", func() {
+		os.Setenv("PROCESSOR_FULL_REBUILD", "true")
+		cfg, err := NewConfigFromEnv()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cfg.Processor.FullRebuild).To(BeTrue())
+	})
+
+	It("should send budget alerts to BUDGET_ALERT_WEBHOOK_URL and by email through BUDGET_ALERT_SMTP_ADDR", func() {
+		os.Setenv("BUDGET_ALERT_WEBHOOK_URL", "https://example.com/alerts")
+		os.Setenv("BUDGET_ALERT_SMTP_ADDR", "localhost:25")
+		os.Setenv("BUDGET_ALERT_SMTP_FROM", "billing@example.com")
+		os.Setenv("BUDGET_ALERT_SMTP_TO", "finance@example.com,ops@example.com")
+		cfg, err := NewConfigFromEnv()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cfg.Processor.Notifier).To(HaveLen(2))
+		notifiers := cfg.Processor.Notifier.(notifier.Multi)
+		Expect(notifiers[0].(*notifier.Webhook).URL).To(Equal("https://example.com/alerts"))
+		Expect(notifiers[1].(*notifier.SMTP).To).To(Equal([]string{"finance@example.com", "ops@example.com"}))
+	})
+
+	It("should require BUDGET_ALERT_SMTP_TO when BUDGET_ALERT_SMTP_ADDR is set", func() {
+		os.Setenv("BUDGET_ALERT_SMTP_ADDR", "localhost:25")
+		os.Setenv("BUDGET_ALERT_SMTP_FROM", "billing@example.com")
+		_, err := NewConfigFromEnv()
+		Expect(err).To(MatchError("environment variable BUDGET_ALERT_SMTP_TO is required"))
+	})
+
+	It("should set Processor.Anomalies from ANOMALY_BASELINE_DAYS, ANOMALY_MIN_HISTORY_DAYS, ANOMALY_FACTOR and ANOMALY_MIN_INCREASE", func() {
+		os.Setenv("ANOMALY_BASELINE_DAYS", "7")
+		os.Setenv("ANOMALY_MIN_HISTORY_DAYS", "3")
+		os.Setenv("ANOMALY_FACTOR", "2.5")
+		os.Setenv("ANOMALY_MIN_INCREASE", "100")
+		os.Setenv("ANOMALY_WEBHOOK_URL", "https://example.com/anomalies")
+		cfg, err := NewConfigFromEnv()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cfg.Processor.Anomalies.BaselineDays).To(Equal(7))
+		Expect(cfg.Processor.Anomalies.MinHistoryDays).To(Equal(3))
+		Expect(cfg.Processor.Anomalies.Factor).To(Equal(eventio.MustParseDecimal("2.5")))
+		Expect(cfg.Processor.Anomalies.MinIncrease).To(Equal(eventio.MustParseDecimal("100")))
+		Expect(cfg.Processor.AnomalyNotifier.(*notifier.Webhook).URL).To(Equal("https://example.com/anomalies"))
+	})
+
+	It("should reject an anomaly config that would report every cost", func() {
+		os.Setenv("ANOMALY_FACTOR", "0.5")
+		_, err := NewConfigFromEnv()
+		Expect(err).To(MatchError("the anomaly factor must be greater than 1 - got 0.5"))
+	})
+
+	It("should set Webhooks from WEBHOOK_SCHEDULE and WEBHOOK_MAX_ATTEMPTS", func() {
+		os.Setenv("WEBHOOK_SCHEDULE", "10s")
+		os.Setenv("WEBHOOK_MAX_ATTEMPTS", "3")
+		cfg, err := NewConfigFromEnv()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cfg.Webhooks.Schedule).To(Equal(10 * time.Second))
+		Expect(cfg.Webhooks.MaxAttempts).To(Equal(3))
+	})
+
+	It("should require at least one attempt to send each webhook", func() {
+		os.Setenv("WEBHOOK_MAX_ATTEMPTS", "-1")
+		_, err := NewConfigFromEnv()
+		Expect(err).To(MatchError("WEBHOOK_MAX_ATTEMPTS must be at least 1 - got -1"))
+	})
+
 })
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("preview-config", func() {
+
+	var run = func(args ...string) *Session {
+		cmd := exec.Command(BinaryPath, append([]string{"preview-config"}, args...)...)
+		cmd.Env = os.Environ()
+		session, err := Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).ToNot(HaveOccurred())
+		return session
+	}
+
+	It("should show the usage if not given enough arguments", func() {
+		session := run("proposed.json", "2001-01-01")
+		Eventually(session).Should(Exit(1))
+		Expect(session.Out).To(Say(`usage: preview-config`))
+
+		session = run("-unknown", "proposed.json", "2001-01-01", "2001-02-01")
+		Eventually(session).Should(Exit(1))
+		Expect(session.Out).To(Say(`usage: preview-config`))
+	})
+
+	It("should fail if a config can not be loaded", func() {
+		session := run("does-not-exist.json", "2001-01-01", "2001-02-01")
+		Eventually(session).Should(Exit(1))
+		Expect(session.Out).To(Say(`does-not-exist.json: no such file or directory`))
+
+		session = run("-current", "current-does-not-exist.json", "proposed.json", "2001-01-01", "2001-02-01")
+		Eventually(session).Should(Exit(1))
+		Expect(session.Out).To(Say(`current-does-not-exist.json: no such file or directory`))
+	})
+})
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("validate-config", func() {
+
+	var (
+		appRoot string
+	)
+
+	BeforeEach(func() {
+		var err error
+		appRoot, err = ioutil.TempDir("", "validate-config")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(appRoot)
+	})
+
+	var run = func(args ...string) *Session {
+		cmd := exec.Command(BinaryPath, append([]string{"validate-config"}, args...)...)
+		cmd.Env = append(os.Environ(), "APP_ROOT="+appRoot)
+		session, err := Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).ToNot(HaveOccurred())
+		return session
+	}
+
+	var writeConfig = func(filename string, config string) string {
+		p := filepath.Join(appRoot, filename)
+		Expect(ioutil.WriteFile(p, []byte(config), 0644)).To(Succeed())
+		return p
+	}
+
+	It("should succeed for a valid config.json in APP_ROOT", func() {
+		p := writeConfig("config.json", `{
+			"vat_rates": [{"code": "Standard", "rate": 0.2, "valid_from": "epoch"}],
+			"currency_rates": [{"code": "GBP", "rate": 1, "valid_from": "epoch"}],
+			"pricing_plans": [{
+				"name": "PLAN1",
+				"plan_guid": "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+				"valid_from": "2001-01-01",
+				"components": [{"name": "compute", "formula": "$time_in_seconds * 0.01", "vat_code": "Standard", "currency_code": "GBP"}]
+			}]
+		}`)
+
+		session := run()
+		Eventually(session).Should(Exit(0))
+		Expect(session.Out).To(Say(p + ": ok"))
+	})
+
+	It("should report every problem in the given file and exit non-zero", func() {
+		p := writeConfig("other.json", `{
+			"vat_rates": [{"code": "Standard", "rate": 0.2, "valid_from": "epoch"}],
+			"currency_rates": [{"code": "GBP", "rate": 1, "valid_from": "epoch"}],
+			"pricing_plans": [{
+				"name": "PLAN1",
+				"plan_guid": "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+				"valid_from": "2001-01-02",
+				"components": [{"name": "compute", "formula": "1 +", "vat_code": "Zero", "currency_code": "GBP"}]
+			}]
+		}`)
+
+		session := run(p)
+		Eventually(session).Should(Exit(1))
+		Expect(session.Out).To(Say(p + `: pricing_plans\[0\].valid_from: '2001-01-02' must be the start of a month`))
+		Expect(session.Out).To(Say(p + `: pricing_plans\[0\].components\[0\].formula: line 1, column 4: `))
+		Expect(session.Out).To(Say(p + `: pricing_plans\[0\].components\[0\].vat_code: missing vat_rate for 'Zero'`))
+		Expect(session.Out).To(Say(`found 3 problem\(s\)`))
+	})
+
+	It("should fail if there is no config file", func() {
+		session := run()
+		Eventually(session).Should(Exit(1))
+		Expect(session.Out).To(Say("config.json does not exist"))
+	})
+})
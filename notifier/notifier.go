@@ -0,0 +1,88 @@
+// Package notifier delivers budget alerts and cost anomalies to the people
+// who need to act on them, such as by email or to a webhook.
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+// Notifier sends a budget alert. Notify returns an error if the alert could
+// not be delivered, so that it can be tried again later.
+type Notifier interface {
+	Notify(ctx context.Context, alert eventio.BudgetAlert) error
+}
+
+// AnomalyNotifier sends a cost anomaly
+type AnomalyNotifier interface {
+	NotifyAnomaly(ctx context.Context, anomaly eventio.Anomaly) error
+}
+
+// Multi sends each alert with every one of its notifiers. Every notifier is
+// tried even if an earlier one fails, and an error is returned if any fail.
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, alert eventio.BudgetAlert) error {
+	failures := []string{}
+	for _, n := range m {
+		if err := n.Notify(ctx, alert); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to send budget alert %d: %s", alert.ID, strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// Subject is a one line summary of the alert
+func Subject(alert eventio.BudgetAlert) string {
+	return fmt.Sprintf("%s has spent %d%% of its budget for the billing period starting %s", alert.OrgName, alert.Threshold, alert.PeriodStart)
+}
+
+// Message is the alert as plain text
+func Message(alert eventio.BudgetAlert) string {
+	return fmt.Sprintf(
+		"The org %s (%s) has spent %s %s ex VAT in the billing period starting %s, which is %d%% or more of its budget of %s %s for the period.\n",
+		alert.OrgName,
+		alert.OrgGUID,
+		alert.Spent.RoundMoney(),
+		alert.CurrencyCode,
+		alert.PeriodStart,
+		alert.Threshold,
+		alert.Amount.RoundMoney(),
+		alert.CurrencyCode,
+	)
+}
+
+// AnomalySubject is a one line summary of the anomaly
+func AnomalySubject(anomaly eventio.Anomaly) string {
+	return fmt.Sprintf("Unusual cost for %s on %s", anomalyName(anomaly), anomaly.Day)
+}
+
+// AnomalyMessage is the anomaly as plain text
+func AnomalyMessage(anomaly eventio.Anomaly) string {
+	return fmt.Sprintf(
+		"The cost of %s on %s was %s %s ex VAT, compared to a daily average of %s %s before.\n",
+		anomalyName(anomaly),
+		anomaly.Day,
+		anomaly.Cost.RoundMoney(),
+		anomaly.CurrencyCode,
+		anomaly.Baseline.RoundMoney(),
+		anomaly.CurrencyCode,
+	)
+}
+
+func anomalyName(anomaly eventio.Anomaly) string {
+	switch anomaly.Level {
+	case eventio.AnomalyResource:
+		return fmt.Sprintf("the %s %s in %s/%s", anomaly.ResourceType, anomaly.ResourceName, anomaly.OrgName, anomaly.SpaceName)
+	case eventio.AnomalySpace:
+		return fmt.Sprintf("the space %s/%s", anomaly.OrgName, anomaly.SpaceName)
+	default:
+		return fmt.Sprintf("the org %s", anomaly.OrgName)
+	}
+}
@@ -0,0 +1,229 @@
+package notifier_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/fakes"
+	"github.com/alphagov/paas-billing/notifier"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var alert = eventio.BudgetAlert{
+	ID:           1,
+	OrgGUID:      "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+	OrgName:      "ORG1",
+	PeriodStart:  "2001-01-01",
+	Threshold:    80,
+	Amount:       eventio.MustParseDecimal("100"),
+	Spent:        eventio.MustParseDecimal("81.234"),
+	CurrencyCode: "GBP",
+	CreatedAt:    time.Date(2001, 1, 20, 0, 0, 0, 0, time.UTC),
+}
+
+var _ = Describe("Message", func() {
+	It("should describe the alert", func() {
+		Expect(notifier.Subject(alert)).To(Equal("ORG1 has spent 80% of its budget for the billing period starting 2001-01-01"))
+		Expect(notifier.Message(alert)).To(Equal(
+			"The org ORG1 (51ba75ef-edc0-47ad-a633-a8f6e8770944) has spent 81.23 GBP ex VAT in the billing period starting 2001-01-01, which is 80% or more of its budget of 100.00 GBP for the period.\n",
+		))
+	})
+})
+
+var _ = Describe("AnomalyMessage", func() {
+	It("should name the resource, space or org with the unusual cost", func() {
+		anomaly := eventio.Anomaly{
+			Day:          "2001-01-10",
+			Level:        eventio.AnomalyResource,
+			OrgName:      "ORG1",
+			SpaceName:    "SPACE1",
+			ResourceName: "my-app",
+			ResourceType: "app",
+			Cost:         eventio.MustParseDecimal("120"),
+			Baseline:     eventio.MustParseDecimal("9.999"),
+			CurrencyCode: "GBP",
+		}
+		Expect(notifier.AnomalySubject(anomaly)).To(Equal("Unusual cost for the app my-app in ORG1/SPACE1 on 2001-01-10"))
+		Expect(notifier.AnomalyMessage(anomaly)).To(Equal(
+			"The cost of the app my-app in ORG1/SPACE1 on 2001-01-10 was 120.00 GBP ex VAT, compared to a daily average of 10.00 GBP before.\n",
+		))
+
+		anomaly.Level = eventio.AnomalyOrg
+		Expect(notifier.AnomalySubject(anomaly)).To(Equal("Unusual cost for the org ORG1 on 2001-01-10"))
+	})
+})
+
+var _ = Describe("Multi", func() {
+	It("should try every notifier and report the failures", func() {
+		first := &fakes.FakeNotifier{}
+		first.NotifyReturns(errors.New("first-failed"))
+		second := &fakes.FakeNotifier{}
+
+		err := notifier.Multi{first, second}.Notify(context.Background(), alert)
+
+		Expect(err).To(MatchError("failed to send budget alert 1: first-failed"))
+		Expect(first.NotifyCallCount()).To(Equal(1))
+		Expect(second.NotifyCallCount()).To(Equal(1))
+		_, sent := second.NotifyArgsForCall(0)
+		Expect(sent).To(Equal(alert))
+	})
+})
+
+var _ = Describe("Webhook", func() {
+	It("should post the alert as JSON", func() {
+		var payload notifier.WebhookPayload
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.Method).To(Equal(http.MethodPost))
+			Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+			body, err := ioutil.ReadAll(r.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(json.Unmarshal(body, &payload)).To(Succeed())
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		webhook := &notifier.Webhook{URL: server.URL}
+		Expect(webhook.Notify(context.Background(), alert)).To(Succeed())
+
+		Expect(payload.Subject).To(Equal(notifier.Subject(alert)))
+		Expect(payload.Message).To(Equal(notifier.Message(alert)))
+		Expect(payload.Alert.OrgGUID).To(Equal(alert.OrgGUID))
+		Expect(payload.Alert.Spent.Equal(alert.Spent)).To(BeTrue())
+	})
+
+	It("should post an anomaly as JSON", func() {
+		var payload notifier.AnomalyWebhookPayload
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			body, err := ioutil.ReadAll(r.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(json.Unmarshal(body, &payload)).To(Succeed())
+		}))
+		defer server.Close()
+
+		anomaly := eventio.Anomaly{ID: 7, Day: "2001-01-10", Level: eventio.AnomalyOrg, OrgName: "ORG1"}
+		webhook := &notifier.Webhook{URL: server.URL}
+		Expect(webhook.NotifyAnomaly(context.Background(), anomaly)).To(Succeed())
+
+		Expect(payload.Subject).To(Equal(notifier.AnomalySubject(anomaly)))
+		Expect(payload.Anomaly.ID).To(Equal(int64(7)))
+	})
+
+	It("should fail if the webhook does not respond with a 2xx status", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		webhook := &notifier.Webhook{URL: server.URL}
+		Expect(webhook.Notify(context.Background(), alert)).To(MatchError("webhook responded with 502 Bad Gateway"))
+	})
+})
+
+var _ = Describe("SMTP", func() {
+	var (
+		listener net.Listener
+		received chan string
+	)
+
+	BeforeEach(func() {
+		var err error
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		received = make(chan string, 1)
+		go serveSMTP(listener, received)
+	})
+
+	AfterEach(func() {
+		listener.Close()
+	})
+
+	It("should email the alert to the recipients", func() {
+		n := &notifier.SMTP{
+			Addr: listener.Addr().String(),
+			From: "billing@example.com",
+			To:   []string{"finance@example.com", "ops@example.com"},
+		}
+		Expect(n.Notify(context.Background(), alert)).To(Succeed())
+
+		var transcript string
+		Eventually(received).Should(Receive(&transcript))
+		Expect(transcript).To(ContainSubstring("MAIL FROM:<billing@example.com>"))
+		Expect(transcript).To(ContainSubstring("RCPT TO:<finance@example.com>"))
+		Expect(transcript).To(ContainSubstring("RCPT TO:<ops@example.com>"))
+		Expect(transcript).To(ContainSubstring("Subject: ORG1 has spent 80% of its budget for the billing period starting 2001-01-01"))
+		Expect(transcript).To(ContainSubstring("has spent 81.23 GBP ex VAT in the billing period starting 2001-01-01"))
+	})
+
+	It("should encode the org name in the subject and not let it add headers", func() {
+		n := &notifier.SMTP{
+			Addr: listener.Addr().String(),
+			From: "billing@example.com",
+			To:   []string{"finance@example.com"},
+		}
+		named := alert
+		named.OrgName = "Ørg\r\nBcc: someone@example.com"
+		Expect(n.Notify(context.Background(), named)).To(Succeed())
+
+		var transcript string
+		Eventually(received).Should(Receive(&transcript))
+		Expect(transcript).To(ContainSubstring("Subject: =?utf-8?q?=C3=98rgBcc:_someone@example.com_has_spent"))
+		headers := strings.SplitN(transcript, "\n\n", 2)[0]
+		Expect(headers).ToNot(MatchRegexp(`(?m)^Bcc:`))
+	})
+
+	It("should fail without any recipients", func() {
+		n := &notifier.SMTP{Addr: listener.Addr().String(), From: "billing@example.com"}
+		Expect(n.Notify(context.Background(), alert)).To(MatchError("no recipients for budget alert emails"))
+	})
+})
+
+// serveSMTP is a stand in mail server that accepts a single message and
+// sends the commands and data it was given to received
+func serveSMTP(listener net.Listener, received chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	transcript := []string{}
+	text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		transcript = append(transcript, line)
+		switch command := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); command {
+		case "EHLO", "HELO":
+			text.PrintfLine("250 localhost")
+		case "DATA":
+			text.PrintfLine("354 go ahead")
+			data, err := ioutil.ReadAll(bufio.NewReader(text.DotReader()))
+			if err != nil {
+				return
+			}
+			transcript = append(transcript, string(data))
+			text.PrintfLine("250 queued")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			received <- strings.Join(transcript, "\n")
+			return
+		default:
+			text.PrintfLine("250 OK")
+		}
+	}
+}
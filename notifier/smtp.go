@@ -0,0 +1,62 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+// SMTP emails each alert to the To addresses through the mail server at
+// Addr, a host:port. Auth is optional, net/smtp only sends credentials over
+// TLS or to localhost.
+type SMTP struct {
+	Addr string
+	From string
+	To   []string
+	Auth smtp.Auth
+}
+
+func (n *SMTP) Notify(ctx context.Context, alert eventio.BudgetAlert) error {
+	if len(n.To) == 0 {
+		return fmt.Errorf("no recipients for budget alert emails")
+	}
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(msg, "Subject: %s\r\n", headerText(Subject(alert)))
+	fmt.Fprintf(msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(msg, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprintf(msg, "\r\n")
+	fmt.Fprintf(msg, "%s", strings.Replace(Message(alert), "\n", "\r\n", -1))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(n.Addr, n.Auth, n.From, n.To, msg.Bytes())
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// headerText makes s safe to use as the text of a header. Control characters
+// are removed, so that a CR or LF can not start another header, and it is
+// RFC 2047 encoded if it is not plain ASCII.
+func headerText(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+	return mime.QEncoding.Encode("utf-8", s)
+}
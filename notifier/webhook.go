@@ -0,0 +1,78 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+// Webhook posts each alert or anomaly as JSON to URL. It is delivered if
+// the response has a 2xx status.
+type Webhook struct {
+	URL string
+	// Client is the client used to post, http.DefaultClient is used if it is
+	// nil
+	Client *http.Client
+}
+
+// WebhookPayload is the body posted for each alert
+type WebhookPayload struct {
+	Subject string              `json:"subject"`
+	Message string              `json:"message"`
+	Alert   eventio.BudgetAlert `json:"alert"`
+}
+
+// AnomalyWebhookPayload is the body posted for each anomaly
+type AnomalyWebhookPayload struct {
+	Subject string          `json:"subject"`
+	Message string          `json:"message"`
+	Anomaly eventio.Anomaly `json:"anomaly"`
+}
+
+func (w *Webhook) Notify(ctx context.Context, alert eventio.BudgetAlert) error {
+	return w.post(ctx, WebhookPayload{
+		Subject: Subject(alert),
+		Message: Message(alert),
+		Alert:   alert,
+	})
+}
+
+func (w *Webhook) NotifyAnomaly(ctx context.Context, anomaly eventio.Anomaly) error {
+	return w.post(ctx, AnomalyWebhookPayload{
+		Subject: AnomalySubject(anomaly),
+		Message: AnomalyMessage(anomaly),
+		Anomaly: anomaly,
+	})
+}
+
+func (w *Webhook) post(ctx context.Context, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
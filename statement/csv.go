@@ -0,0 +1,99 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// CSVHeader is the first row of a CSV statement
+var CSVHeader = []string{
+	"row_type",
+	"space_guid",
+	"space_name",
+	"resource_guid",
+	"resource_name",
+	"resource_type",
+	"plan_guid",
+	"plan_name",
+	"component",
+	"vat_code",
+	"vat_rate",
+	"ex_vat",
+	"inc_vat",
+}
+
+// WriteCSV writes s as CSV with a "component" row for each component of each
+// resource, then a "vat_total" row for each VAT code and rate and a final
+// "total" row. Amounts are exact and are not rounded. Cells that a spreadsheet
+// would read as a formula are prefixed with a single quote.
+func WriteCSV(w io.Writer, s Statement) error {
+	cw := &escapingWriter{csv.NewWriter(w)}
+	if err := cw.Write(CSVHeader); err != nil {
+		return err
+	}
+	for _, space := range s.Spaces {
+		for _, resource := range space.Resources {
+			for _, component := range resource.Components {
+				err := cw.Write([]string{
+					"component",
+					space.SpaceGUID,
+					space.SpaceName,
+					resource.ResourceGUID,
+					resource.ResourceName,
+					resource.ResourceType,
+					resource.PlanGUID,
+					resource.PlanName,
+					component.Name,
+					component.VATCode,
+					component.VATRate.String(),
+					component.ExVAT.String(),
+					component.IncVAT.String(),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, vatTotal := range s.VATTotals {
+		err := cw.Write([]string{
+			"vat_total", "", "", "", "", "", "", "", "",
+			vatTotal.VATCode,
+			vatTotal.VATRate.String(),
+			vatTotal.ExVAT.String(),
+			vatTotal.IncVAT.String(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err := cw.Write([]string{
+		"total", "", "", "", "", "", "", "", "", "", "",
+		s.ExVAT.String(),
+		s.IncVAT.String(),
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// escapingWriter is a csv.Writer that prefixes each cell starting with =, +,
+// - or @ with a single quote, so that names chosen by tenants cannot run
+// formulas when the statement is opened in a spreadsheet.
+type escapingWriter struct {
+	*csv.Writer
+}
+
+func (w *escapingWriter) Write(record []string) error {
+	escaped := make([]string, len(record))
+	for i, cell := range record {
+		if cell != "" && strings.ContainsRune("=+-@", rune(cell[0])) {
+			cell = "'" + cell
+		}
+		escaped[i] = cell
+	}
+	return w.Writer.Write(escaped)
+}
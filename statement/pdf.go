@@ -0,0 +1,250 @@
+package statement
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+// Page layout of a PDF statement in points, for A4 paper
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	marginLeft   = 50
+	marginTop    = 60
+	marginBottom = 60
+	lineHeight   = 13
+	tableSize    = 8.5
+	// courierWidth is the width of every character of the Courier fonts as
+	// a fraction of the font size
+	courierWidth = 0.6
+)
+
+// The fonts are standard PDF fonts, which every PDF reader has, so that
+// nothing needs to be embedded
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+	fontTable   = "F3"
+	fontTableB  = "F4"
+)
+
+var fontNames = []string{"Helvetica", "Helvetica-Bold", "Courier", "Courier-Bold"}
+
+// table columns, in characters of fontTable
+const (
+	colName   = 46
+	colVAT    = 12
+	colAmount = 14
+)
+
+// WritePDF renders s as a PDF. Amounts are rounded to eventio.MoneyPlaces,
+// the totals are calculated before rounding.
+func WritePDF(w io.Writer, s Statement) error {
+	doc := &pdfDocument{title: fmt.Sprintf("Statement for %s from %s to %s", orgName(s), s.PeriodStart, s.PeriodStop)}
+
+	doc.newPage()
+	doc.text(fontBold, 16, marginLeft, doc.y, "Statement")
+	doc.y -= lineHeight * 2
+	doc.text(fontRegular, 10, marginLeft, doc.y, "Organisation: "+orgName(s))
+	doc.y -= lineHeight
+	doc.text(fontRegular, 10, marginLeft, doc.y, "Organisation GUID: "+s.OrgGUID)
+	doc.y -= lineHeight
+	doc.text(fontRegular, 10, marginLeft, doc.y, fmt.Sprintf("Period: %s to %s", s.PeriodStart, s.PeriodStop))
+	doc.y -= lineHeight
+	doc.text(fontRegular, 10, marginLeft, doc.y, fmt.Sprintf("Amounts in %s, rounded to %d decimal places", eventio.BaseCurrency, eventio.MoneyPlaces))
+	doc.y -= lineHeight * 2
+
+	tableHeader := func() {
+		doc.row(fontTableB, "Space / resource / component", "VAT code", "Ex VAT", "Inc VAT")
+		doc.rule()
+	}
+	doc.onNewPage = tableHeader
+	tableHeader()
+	if len(s.Spaces) == 0 {
+		doc.row(fontTable, "No billable usage in this period", "", "", "")
+	}
+	for _, space := range s.Spaces {
+		doc.row(fontTableB, "Space: "+nameOrGUID(space.SpaceName, space.SpaceGUID), "", money(space.ExVAT), money(space.IncVAT))
+		for _, resource := range space.Resources {
+			doc.row(fontTable, fmt.Sprintf("  %s (%s) %s", nameOrGUID(resource.ResourceName, resource.ResourceGUID), resource.ResourceType, resource.PlanName), "", money(resource.ExVAT), money(resource.IncVAT))
+			for _, component := range resource.Components {
+				doc.row(fontTable, "    "+component.Name, component.VATCode, money(component.ExVAT), money(component.IncVAT))
+			}
+		}
+		doc.y -= lineHeight / 2.0
+	}
+
+	doc.onNewPage = nil
+	doc.y -= lineHeight
+	doc.row(fontTableB, "VAT summary", "VAT", "Ex VAT", "Inc VAT")
+	doc.rule()
+	for _, vatTotal := range s.VATTotals {
+		doc.row(fontTable, fmt.Sprintf("%s at %s%%", vatTotal.VATCode, percent(vatTotal.VATRate)), money(vatTotal.VAT), money(vatTotal.ExVAT), money(vatTotal.IncVAT))
+	}
+	doc.rule()
+	doc.row(fontTableB, "Total", money(s.VAT()), money(s.ExVAT), money(s.IncVAT))
+
+	for i, page := range doc.pages {
+		footer := fmt.Sprintf("%s - page %d of %d", doc.title, i+1, len(doc.pages))
+		writeText(page, fontRegular, 8, marginLeft, marginBottom/2, footer)
+	}
+	return doc.write(w)
+}
+
+func orgName(s Statement) string {
+	return nameOrGUID(s.OrgName, s.OrgGUID)
+}
+
+func nameOrGUID(name string, guid string) string {
+	if name == "" {
+		return guid
+	}
+	return name
+}
+
+// percent formats a rate such as 0.20 as 20
+func percent(rate eventio.Decimal) string {
+	s := rate.Mul(eventio.NewDecimalFromInt(100)).String()
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+func money(d eventio.Decimal) string {
+	return d.RoundMoney().String()
+}
+
+// pdfDocument lays out lines of text on A4 pages and writes them as a
+// minimal PDF 1.4 file
+type pdfDocument struct {
+	title     string
+	pages     []*bytes.Buffer
+	y         float64
+	onNewPage func()
+}
+
+func (d *pdfDocument) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pageHeight - marginTop
+}
+
+// row writes a table row, starting a new page if there is no room for it
+func (d *pdfDocument) row(font string, name string, vat string, exVAT string, incVAT string) {
+	if d.y < marginBottom+lineHeight {
+		d.newPage()
+		if d.onNewPage != nil {
+			d.onNewPage()
+		}
+	}
+	line := fmt.Sprintf("%-*s %-*s %*s %*s",
+		colName, truncate(name, colName),
+		colVAT, truncate(vat, colVAT),
+		colAmount, truncate(exVAT, colAmount),
+		colAmount, truncate(incVAT, colAmount),
+	)
+	d.text(font, tableSize, marginLeft, d.y, line)
+	d.y -= lineHeight
+}
+
+// rule draws a line under the previous row the width of the table
+func (d *pdfDocument) rule() {
+	width := float64(colName+colVAT+colAmount*2+3) * tableSize * courierWidth
+	y := d.y + lineHeight - 3
+	fmt.Fprintf(d.pages[len(d.pages)-1], "0.5 w %d %.2f m %.2f %.2f l S\n", marginLeft, y, marginLeft+width, y)
+	d.y -= 3
+}
+
+func (d *pdfDocument) text(font string, size float64, x float64, y float64, s string) {
+	writeText(d.pages[len(d.pages)-1], font, size, x, y, s)
+}
+
+func writeText(page *bytes.Buffer, font string, size float64, x float64, y float64, s string) {
+	fmt.Fprintf(page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, escapeText(s))
+}
+
+// truncate shortens s to at most n characters, marking that it has been
+// shortened with a trailing '~'
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "~"
+}
+
+// escapeText encodes s for a PDF string using the fonts' WinAnsiEncoding,
+// which matches Latin-1 for the characters it has. Other characters are
+// replaced with '?'.
+func escapeText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// write writes the document. The objects are the catalog, the page tree,
+// the info dictionary and the fonts, followed by each page and its content.
+func (d *pdfDocument) write(w io.Writer) error {
+	buf := &bytes.Buffer{}
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	firstPage := 4 + len(fontNames)
+	kids := []string{}
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", firstPage+i*2))
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object(fmt.Sprintf("<< /Title (%s) /Producer (paas-billing) >>", escapeText(d.title)))
+	fonts := []string{}
+	for i, name := range fontNames {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+		fonts = append(fonts, fmt.Sprintf("/F%d %d 0 R", i+1, 4+i))
+	}
+	for i, page := range d.pages {
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, strings.Join(fonts, " "), firstPage+i*2+1,
+		))
+		compressed := &bytes.Buffer{}
+		zw := zlib.NewWriter(compressed)
+		if _, err := zw.Write(page.Bytes()); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.Bytes()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	_, err := buf.WriteTo(w)
+	return err
+}
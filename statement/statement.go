@@ -0,0 +1,193 @@
+// Package statement summarises an org's billable events for a month into a
+// statement that can be downloaded as a PDF or CSV file.
+package statement
+
+import (
+	"sort"
+
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+// Statement is an org's costs for one month grouped by space, resource and
+// plan. Amounts are exact, they are only rounded when the PDF is rendered.
+type Statement struct {
+	OrgGUID     string
+	OrgName     string
+	PeriodStart string
+	PeriodStop  string
+	Spaces      []Space
+	VATTotals   []VATTotal
+	ExVAT       eventio.Decimal
+	IncVAT      eventio.Decimal
+}
+
+type Space struct {
+	SpaceGUID string
+	SpaceName string
+	Resources []Resource
+	ExVAT     eventio.Decimal
+	IncVAT    eventio.Decimal
+}
+
+// Resource is the cost of a resource on one plan. A resource that changed
+// plan during the month appears once for each plan.
+type Resource struct {
+	ResourceGUID string
+	ResourceName string
+	ResourceType string
+	PlanGUID     string
+	PlanName     string
+	Components   []Component
+	ExVAT        eventio.Decimal
+	IncVAT       eventio.Decimal
+}
+
+// Component is the total of one pricing component of a resource's plan
+type Component struct {
+	Name    string
+	VATCode string
+	VATRate eventio.Decimal
+	ExVAT   eventio.Decimal
+	IncVAT  eventio.Decimal
+}
+
+// VATTotal is the total of all the components with one VAT code and rate
+type VATTotal struct {
+	VATCode string
+	VATRate eventio.Decimal
+	ExVAT   eventio.Decimal
+	VAT     eventio.Decimal
+	IncVAT  eventio.Decimal
+}
+
+// New builds the statement for orgGUID from the billable events for the
+// period from periodStart to periodStop. Events for other orgs are ignored.
+func New(orgGUID string, periodStart string, periodStop string, events []eventio.BillableEvent) Statement {
+	s := Statement{
+		OrgGUID:     orgGUID,
+		PeriodStart: periodStart,
+		PeriodStop:  periodStop,
+		Spaces:      []Space{},
+		VATTotals:   []VATTotal{},
+	}
+	spaces := map[string]*Space{}
+	resources := map[string]map[string]*Resource{}
+	components := map[*Resource]map[string]*Component{}
+	vatTotals := map[string]*VATTotal{}
+
+	for _, event := range events {
+		if event.OrgGUID != orgGUID {
+			continue
+		}
+		s.OrgName = event.OrgName
+
+		space, ok := spaces[event.SpaceGUID]
+		if !ok {
+			space = &Space{SpaceGUID: event.SpaceGUID}
+			spaces[event.SpaceGUID] = space
+			resources[event.SpaceGUID] = map[string]*Resource{}
+		}
+		space.SpaceName = event.SpaceName
+
+		resourceKey := event.ResourceGUID + "/" + event.PlanGUID
+		resource, ok := resources[event.SpaceGUID][resourceKey]
+		if !ok {
+			resource = &Resource{
+				ResourceGUID: event.ResourceGUID,
+				ResourceType: event.ResourceType,
+				PlanGUID:     event.PlanGUID,
+			}
+			resources[event.SpaceGUID][resourceKey] = resource
+			components[resource] = map[string]*Component{}
+		}
+		resource.ResourceName = event.ResourceName
+
+		for _, detail := range event.Price.Details {
+			if detail.PlanName != "" {
+				resource.PlanName = detail.PlanName
+			}
+			componentKey := detail.Name + "/" + detail.VatCode + "/" + detail.VatRate.String()
+			component, ok := components[resource][componentKey]
+			if !ok {
+				component = &Component{
+					Name:    detail.Name,
+					VATCode: detail.VatCode,
+					VATRate: detail.VatRate,
+				}
+				components[resource][componentKey] = component
+			}
+			component.ExVAT = component.ExVAT.Add(detail.ExVAT)
+			component.IncVAT = component.IncVAT.Add(detail.IncVAT)
+
+			vatKey := detail.VatCode + "/" + detail.VatRate.String()
+			vatTotal, ok := vatTotals[vatKey]
+			if !ok {
+				vatTotal = &VATTotal{VATCode: detail.VatCode, VATRate: detail.VatRate}
+				vatTotals[vatKey] = vatTotal
+			}
+			vatTotal.ExVAT = vatTotal.ExVAT.Add(detail.ExVAT)
+			vatTotal.IncVAT = vatTotal.IncVAT.Add(detail.IncVAT)
+		}
+	}
+
+	for _, space := range spaces {
+		for _, resource := range resources[space.SpaceGUID] {
+			for _, component := range components[resource] {
+				resource.Components = append(resource.Components, *component)
+				resource.ExVAT = resource.ExVAT.Add(component.ExVAT)
+				resource.IncVAT = resource.IncVAT.Add(component.IncVAT)
+			}
+			sort.Slice(resource.Components, func(i, j int) bool {
+				a, b := resource.Components[i], resource.Components[j]
+				if a.Name != b.Name {
+					return a.Name < b.Name
+				}
+				if a.VATCode != b.VATCode {
+					return a.VATCode < b.VATCode
+				}
+				return a.VATRate.Cmp(b.VATRate) < 0
+			})
+			space.Resources = append(space.Resources, *resource)
+			space.ExVAT = space.ExVAT.Add(resource.ExVAT)
+			space.IncVAT = space.IncVAT.Add(resource.IncVAT)
+		}
+		sort.Slice(space.Resources, func(i, j int) bool {
+			a, b := space.Resources[i], space.Resources[j]
+			if a.ResourceName != b.ResourceName {
+				return a.ResourceName < b.ResourceName
+			}
+			if a.ResourceGUID != b.ResourceGUID {
+				return a.ResourceGUID < b.ResourceGUID
+			}
+			return a.PlanName < b.PlanName
+		})
+		s.Spaces = append(s.Spaces, *space)
+		s.ExVAT = s.ExVAT.Add(space.ExVAT)
+		s.IncVAT = s.IncVAT.Add(space.IncVAT)
+	}
+	sort.Slice(s.Spaces, func(i, j int) bool {
+		a, b := s.Spaces[i], s.Spaces[j]
+		if a.SpaceName != b.SpaceName {
+			return a.SpaceName < b.SpaceName
+		}
+		return a.SpaceGUID < b.SpaceGUID
+	})
+
+	for _, vatTotal := range vatTotals {
+		vatTotal.VAT = vatTotal.IncVAT.Sub(vatTotal.ExVAT)
+		s.VATTotals = append(s.VATTotals, *vatTotal)
+	}
+	sort.Slice(s.VATTotals, func(i, j int) bool {
+		a, b := s.VATTotals[i], s.VATTotals[j]
+		if a.VATCode != b.VATCode {
+			return a.VATCode < b.VATCode
+		}
+		return a.VATRate.Cmp(b.VATRate) < 0
+	})
+	return s
+}
+
+// VAT is the total VAT of the statement
+func (s Statement) VAT() eventio.Decimal {
+	return s.IncVAT.Sub(s.ExVAT)
+}
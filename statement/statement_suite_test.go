@@ -0,0 +1,13 @@
+package statement_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestStatement(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Statement")
+}
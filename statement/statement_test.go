@@ -0,0 +1,220 @@
+package statement_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+
+	"github.com/alphagov/paas-billing/eventio"
+	. "github.com/alphagov/paas-billing/statement"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Statement", func() {
+	var (
+		orgGUID = "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		events  []eventio.BillableEvent
+	)
+
+	var component = func(name string, exVAT string, incVAT string) eventio.PriceComponent {
+		return eventio.PriceComponent{
+			Name:         name,
+			PlanName:     "PLAN1",
+			VatCode:      "Standard",
+			VatRate:      eventio.MustParseDecimal("0.2"),
+			CurrencyCode: "GBP",
+			ExVAT:        eventio.MustParseDecimal(exVAT),
+			IncVAT:       eventio.MustParseDecimal(incVAT),
+		}
+	}
+
+	BeforeEach(func() {
+		events = []eventio.BillableEvent{
+			{
+				EventGUID:    "aa30fa3c-725d-4272-9052-c7186d4968a6",
+				ResourceGUID: "c85e98f0-6d1b-4f45-9368-ea58263165a0",
+				ResourceName: "APP1",
+				ResourceType: "app",
+				OrgGUID:      orgGUID,
+				OrgName:      "my-org",
+				SpaceGUID:    "276f4886-ac40-492d-a8cd-b2646637ba76",
+				SpaceName:    "space-b",
+				PlanGUID:     "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+				Price: eventio.Price{
+					ExVAT:   eventio.MustParseDecimal("0.015"),
+					IncVAT:  eventio.MustParseDecimal("0.018"),
+					Details: []eventio.PriceComponent{component("compute", "0.015", "0.018")},
+				},
+			},
+			{
+				EventGUID:    "8a7e0c65-ac1e-4a3c-8c3d-61a0e7e1fb46",
+				ResourceGUID: "c85e98f0-6d1b-4f45-9368-ea58263165a0",
+				ResourceName: "APP1",
+				ResourceType: "app",
+				OrgGUID:      orgGUID,
+				OrgName:      "my-org",
+				SpaceGUID:    "276f4886-ac40-492d-a8cd-b2646637ba76",
+				SpaceName:    "space-b",
+				PlanGUID:     "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+				Price: eventio.Price{
+					ExVAT:   eventio.MustParseDecimal("0.01"),
+					IncVAT:  eventio.MustParseDecimal("0.012"),
+					Details: []eventio.PriceComponent{component("compute", "0.01", "0.012")},
+				},
+			},
+			{
+				EventGUID:    "1a1f1c0c-8d6e-4a58-9d32-1e5ffb7ed0f1",
+				ResourceGUID: "0f3f3c8e-9e5b-4c47-b2a2-1c7b2c3c0a11",
+				ResourceName: "DB1",
+				ResourceType: "postgres",
+				OrgGUID:      orgGUID,
+				OrgName:      "my-org",
+				SpaceGUID:    "9f5a5d6a-93c2-4f6b-a8b4-5ad3c2e2b6f4",
+				SpaceName:    "space-a",
+				PlanGUID:     "2f1ad4a6-3a39-4c6e-a6f4-0d0b2b6f7f5e",
+				Price: eventio.Price{
+					ExVAT:  eventio.MustParseDecimal("3"),
+					IncVAT: eventio.MustParseDecimal("3.6"),
+					Details: []eventio.PriceComponent{
+						component("storage", "1", "1.2"),
+						component("instance", "2", "2.4"),
+					},
+				},
+			},
+			{
+				EventGUID: "3f0c5b0e-4a7e-4a8b-9b7a-0d1e5f2a3b4c",
+				OrgGUID:   "00000000-0000-0000-0000-000000000000",
+				OrgName:   "other-org",
+				Price: eventio.Price{
+					Details: []eventio.PriceComponent{component("compute", "100", "120")},
+				},
+			},
+		}
+	})
+
+	It("should group the org's events by space, resource and plan", func() {
+		s := New(orgGUID, "2001-01-01", "2001-02-01", events)
+
+		Expect(s.OrgName).To(Equal("my-org"))
+		Expect(s.Spaces).To(HaveLen(2))
+		Expect(s.Spaces[0].SpaceName).To(Equal("space-a"))
+		Expect(s.Spaces[0].Resources).To(HaveLen(1))
+		Expect(s.Spaces[0].Resources[0].Components).To(Equal([]Component{
+			{Name: "instance", VATCode: "Standard", VATRate: eventio.MustParseDecimal("0.2"), ExVAT: eventio.MustParseDecimal("2"), IncVAT: eventio.MustParseDecimal("2.4")},
+			{Name: "storage", VATCode: "Standard", VATRate: eventio.MustParseDecimal("0.2"), ExVAT: eventio.MustParseDecimal("1"), IncVAT: eventio.MustParseDecimal("1.2")},
+		}))
+		Expect(s.Spaces[1].SpaceName).To(Equal("space-b"))
+		Expect(s.Spaces[1].Resources[0].PlanName).To(Equal("PLAN1"))
+		Expect(s.Spaces[1].Resources[0].Components).To(HaveLen(1))
+		Expect(s.Spaces[1].ExVAT.String()).To(Equal("0.025"))
+
+		Expect(s.VATTotals).To(Equal([]VATTotal{
+			{
+				VATCode: "Standard",
+				VATRate: eventio.MustParseDecimal("0.2"),
+				ExVAT:   eventio.MustParseDecimal("3.025"),
+				VAT:     eventio.MustParseDecimal("0.605"),
+				IncVAT:  eventio.MustParseDecimal("3.630"),
+			},
+		}))
+		Expect(s.ExVAT.String()).To(Equal("3.025"))
+		Expect(s.IncVAT.String()).To(Equal("3.630"))
+	})
+
+	It("should write a CSV statement with exact amounts", func() {
+		buf := &bytes.Buffer{}
+		Expect(WriteCSV(buf, New(orgGUID, "2001-01-01", "2001-02-01", events))).To(Succeed())
+
+		Expect(buf.String()).To(Equal(`row_type,space_guid,space_name,resource_guid,resource_name,resource_type,plan_guid,plan_name,component,vat_code,vat_rate,ex_vat,inc_vat
+component,9f5a5d6a-93c2-4f6b-a8b4-5ad3c2e2b6f4,space-a,0f3f3c8e-9e5b-4c47-b2a2-1c7b2c3c0a11,DB1,postgres,2f1ad4a6-3a39-4c6e-a6f4-0d0b2b6f7f5e,PLAN1,instance,Standard,0.2,2,2.4
+component,9f5a5d6a-93c2-4f6b-a8b4-5ad3c2e2b6f4,space-a,0f3f3c8e-9e5b-4c47-b2a2-1c7b2c3c0a11,DB1,postgres,2f1ad4a6-3a39-4c6e-a6f4-0d0b2b6f7f5e,PLAN1,storage,Standard,0.2,1,1.2
+component,276f4886-ac40-492d-a8cd-b2646637ba76,space-b,c85e98f0-6d1b-4f45-9368-ea58263165a0,APP1,app,f4d4b95a-f55e-4593-8d54-3364c25798c4,PLAN1,compute,Standard,0.2,0.025,0.030
+vat_total,,,,,,,,,Standard,0.2,3.025,3.630
+total,,,,,,,,,,,3.025,3.630
+`))
+	})
+
+	It("should escape names that a spreadsheet would read as a formula", func() {
+		events = events[:1]
+		events[0].SpaceName = "=HYPERLINK(\"http://example.com\")"
+		events[0].ResourceName = "@SUM(A1)"
+
+		buf := &bytes.Buffer{}
+		Expect(WriteCSV(buf, New(orgGUID, "2001-01-01", "2001-02-01", events))).To(Succeed())
+
+		Expect(buf.String()).To(ContainSubstring(`,"'=HYPERLINK(""http://example.com"")",c85e98f0-6d1b-4f45-9368-ea58263165a0,'@SUM(A1),app,`))
+	})
+
+	Describe("PDF", func() {
+		var render = func(s Statement) []byte {
+			buf := &bytes.Buffer{}
+			Expect(WritePDF(buf, s)).To(Succeed())
+			return buf.Bytes()
+		}
+
+		// pageText returns the uncompressed content of each page
+		var pageText = func(pdf []byte) string {
+			text := ""
+			streams := regexp.MustCompile(`(?s)/Length (\d+) /Filter /FlateDecode >>\nstream\n`)
+			for _, loc := range streams.FindAllSubmatchIndex(pdf, -1) {
+				length, err := strconv.Atoi(string(pdf[loc[2]:loc[3]]))
+				Expect(err).ToNot(HaveOccurred())
+				zr, err := zlib.NewReader(bytes.NewReader(pdf[loc[1] : loc[1]+length]))
+				Expect(err).ToNot(HaveOccurred())
+				b, err := ioutil.ReadAll(zr)
+				Expect(err).ToNot(HaveOccurred())
+				text += string(b)
+			}
+			return text
+		}
+
+		It("should render a valid PDF with rounded amounts", func() {
+			pdf := render(New(orgGUID, "2001-01-01", "2001-02-01", events))
+
+			Expect(string(pdf[:9])).To(Equal("%PDF-1.4\n"))
+			Expect(string(pdf)).To(HaveSuffix("%%EOF\n"))
+
+			By("having an xref table that points at each object")
+			startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+			Expect(startxref).ToNot(BeNil())
+			xref, _ := strconv.Atoi(string(startxref[1]))
+			Expect(string(pdf[xref : xref+4])).To(Equal("xref"))
+			offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf, -1)
+			Expect(offsets).To(HaveLen(9))
+			for i, offset := range offsets {
+				n, _ := strconv.Atoi(string(offset[1]))
+				Expect(string(pdf[n:])).To(HavePrefix(strconv.Itoa(i+1) + " 0 obj\n"))
+			}
+
+			text := pageText(pdf)
+			Expect(text).To(ContainSubstring("(Organisation: my-org)"))
+			Expect(text).To(ContainSubstring("Space: space-a"))
+			Expect(text).To(MatchRegexp(`instance +Standard +2\.00 +2\.40`))
+			Expect(text).To(MatchRegexp(`Standard at 20% +0\.61 +3\.03 +3\.63`))
+			Expect(text).To(ContainSubstring("page 1 of 1"))
+		})
+
+		It("should start new pages when the statement is long", func() {
+			for i := 0; i < 100; i++ {
+				ev := events[0]
+				ev.ResourceGUID = strconv.Itoa(i)
+				events = append(events, ev)
+			}
+			pdf := render(New(orgGUID, "2001-01-01", "2001-02-01", events))
+
+			Expect(string(pdf)).To(ContainSubstring("/Count 5"))
+			Expect(pageText(pdf)).To(ContainSubstring("page 5 of 5"))
+		})
+
+		It("should escape text", func() {
+			events[0].SpaceName = `(bracketed) \ £`
+			events[1].SpaceName = events[0].SpaceName
+			text := pageText(render(New(orgGUID, "2001-01-01", "2001-02-01", events)))
+
+			Expect(text).To(ContainSubstring(`Space: \(bracketed\) \\ \243`))
+		})
+	})
+})
@@ -9,14 +9,14 @@ var BasicConfig = eventstore.Config{
 	VATRates: []eventio.VATRate{
 		{
 			Code:      "Standard",
-			Rate:      0.2,
+			Rate:      eventio.MustParseDecimalNumber("0.2"),
 			ValidFrom: "epoch",
 		},
 	},
 	CurrencyRates: []eventio.CurrencyRate{
 		{
 			Code:      "GBP",
-			Rate:      1,
+			Rate:      eventio.MustParseDecimalNumber("1"),
 			ValidFrom: "epoch",
 		},
 	},
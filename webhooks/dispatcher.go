@@ -0,0 +1,200 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+const (
+	DefaultSchedule    = 1 * time.Minute
+	DefaultMaxAttempts = 10
+	DefaultBatchSize   = 50
+	// DefaultLease is how long a claimed delivery is left before it is tried
+	// again, in case the dispatcher sending it stopped part way through. It
+	// must be longer than the client takes to time out.
+	DefaultLease = 5 * time.Minute
+	// DefaultTimeout is how long the default client waits for a webhook to
+	// respond, unless a batch of posts that all time out would outlast the
+	// lease, when it waits for the lease divided by the batch size
+	DefaultTimeout = 10 * time.Second
+	// MaxBackoff is the longest wait between attempts
+	MaxBackoff = 6 * time.Hour
+)
+
+// Dispatcher periodically sends the deliveries that are due. A delivery that
+// fails is tried again after a backoff that doubles with each attempt, until
+// it has been attempted MaxAttempts times.
+type Dispatcher struct {
+	schedule    time.Duration
+	maxAttempts int
+	batchSize   int
+	lease       time.Duration
+	logger      lager.Logger
+	client      *http.Client
+	store       eventio.WebhookDeliverer
+}
+
+type Config struct {
+	Schedule    time.Duration
+	MaxAttempts int
+	BatchSize   int
+	Lease       time.Duration
+	Logger      lager.Logger
+	// Client is the client used to post, its timeout must be short enough
+	// for a batch to be sent within the lease. A client with a timeout of
+	// DefaultTimeout, or shorter if the lease needs it, is used if it is nil.
+	Client *http.Client
+	Store  eventio.WebhookDeliverer
+}
+
+func New(cfg Config) *Dispatcher {
+	if cfg.Logger == nil {
+		cfg.Logger = lager.NewLogger("webhook-dispatcher")
+	}
+	if cfg.Schedule == 0 {
+		cfg.Schedule = DefaultSchedule
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.Lease == 0 {
+		cfg.Lease = DefaultLease
+	}
+	if cfg.Client == nil {
+		timeout := DefaultTimeout
+		if batchTimeout := cfg.Lease / time.Duration(cfg.BatchSize); batchTimeout < timeout {
+			timeout = batchTimeout
+		}
+		cfg.Client = &http.Client{Timeout: timeout}
+	}
+	return &Dispatcher{
+		schedule:    cfg.Schedule,
+		maxAttempts: cfg.MaxAttempts,
+		batchSize:   cfg.BatchSize,
+		lease:       cfg.Lease,
+		logger:      cfg.Logger,
+		client:      cfg.Client,
+		store:       cfg.Store,
+	}
+}
+
+// Run sends the due deliveries every Schedule until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) error {
+	d.logger.Info("started")
+	defer d.logger.Info("stopping")
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(d.schedule):
+			if _, err := d.DeliverDue(ctx); err != nil {
+				d.logger.Error("deliver-error", err)
+			}
+		}
+	}
+}
+
+// DeliverDue sends the deliveries that are due, a batch at a time until
+// there are none left, and returns how many were attempted
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	attempted := 0
+	for {
+		deliveries, err := d.store.GetDueWebhookDeliveries(d.batchSize, d.lease)
+		if err != nil {
+			return attempted, err
+		}
+		for _, delivery := range deliveries {
+			attempt := d.send(ctx, delivery)
+			if err := d.store.RecordWebhookAttempt(delivery.ID, attempt); err != nil {
+				return attempted, err
+			}
+			attempted++
+			data := lager.Data{
+				"id":              delivery.ID,
+				"webhook_id":      delivery.WebhookID,
+				"event_id":        delivery.EventID,
+				"attempt":         delivery.Attempts + 1,
+				"response_status": attempt.ResponseStatus,
+			}
+			if attempt.Delivered {
+				d.logger.Info("delivered", data)
+			} else {
+				data["next_attempt_at"] = attempt.NextAttemptAt
+				d.logger.Error("delivery-error", fmt.Errorf("%s", attempt.Error), data)
+			}
+		}
+		if len(deliveries) < d.batchSize {
+			return attempted, nil
+		}
+	}
+}
+
+// send posts a delivery, it is delivered if the response has a 2xx status
+func (d *Dispatcher) send(ctx context.Context, delivery eventio.DueWebhookDelivery) eventio.WebhookAttempt {
+	attempt := eventio.WebhookAttempt{}
+	statusCode, err := d.post(ctx, delivery)
+	attempt.ResponseStatus = statusCode
+	if err == nil {
+		attempt.Delivered = true
+		return attempt
+	}
+	attempt.Error = err.Error()
+	if attempts := delivery.Attempts + 1; attempts < d.maxAttempts {
+		next := time.Now().Add(Backoff(attempts))
+		attempt.NextAttemptAt = &next
+	}
+	return attempt
+}
+
+func (d *Dispatcher) post(ctx context.Context, delivery eventio.DueWebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(delivery.EventType))
+	req.Header.Set(EventIDHeader, delivery.EventID)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, timestamp, body))
+	resp, err := d.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Backoff is how long to wait after a delivery has failed attempts times,
+// one minute after the first and doubling each time up to MaxBackoff
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	backoff := time.Minute
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= MaxBackoff {
+			return MaxBackoff
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,209 @@
+package webhooks_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/fakes"
+	"github.com/alphagov/paas-billing/webhooks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sign", func() {
+	It("should sign the timestamp and body with the secret", func() {
+		signature := webhooks.Sign("0123456789abcdef", 1520000000, []byte(`{"id":"1"}`))
+		Expect(signature).To(HavePrefix("sha256="))
+		Expect(signature).To(HaveLen(len("sha256=") + 64))
+		Expect(webhooks.Verify("0123456789abcdef", 1520000000, []byte(`{"id":"1"}`), signature)).To(BeTrue())
+
+		Expect(webhooks.Verify("0123456789abcdeX", 1520000000, []byte(`{"id":"1"}`), signature)).To(BeFalse())
+		Expect(webhooks.Verify("0123456789abcdef", 1520000001, []byte(`{"id":"1"}`), signature)).To(BeFalse())
+		Expect(webhooks.Verify("0123456789abcdef", 1520000000, []byte(`{"id":"2"}`), signature)).To(BeFalse())
+	})
+})
+
+var _ = Describe("Backoff", func() {
+	It("should double the wait after each attempt up to the maximum", func() {
+		Expect(webhooks.Backoff(1)).To(Equal(1 * time.Minute))
+		Expect(webhooks.Backoff(2)).To(Equal(2 * time.Minute))
+		Expect(webhooks.Backoff(5)).To(Equal(16 * time.Minute))
+		Expect(webhooks.Backoff(9)).To(Equal(256 * time.Minute))
+		Expect(webhooks.Backoff(10)).To(Equal(webhooks.MaxBackoff))
+		Expect(webhooks.Backoff(100)).To(Equal(webhooks.MaxBackoff))
+	})
+})
+
+var _ = Describe("Dispatcher", func() {
+	var (
+		store      *fakes.FakeEventStore
+		server     *httptest.Server
+		status     int
+		wait       time.Duration
+		requests   []*http.Request
+		bodies     []string
+		dispatcher *webhooks.Dispatcher
+		delivery   eventio.DueWebhookDelivery
+	)
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		wait = 0
+		requests = nil
+		bodies = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			requests = append(requests, r)
+			bodies = append(bodies, string(body))
+			time.Sleep(wait)
+			w.WriteHeader(status)
+		}))
+		store = &fakes.FakeEventStore{}
+		delivery = eventio.DueWebhookDelivery{
+			WebhookDelivery: eventio.WebhookDelivery{
+				ID:        7,
+				WebhookID: 3,
+				EventID:   "month.consolidated:2018-01-01/2018-02-01",
+				EventType: eventio.MonthConsolidated,
+				Payload:   []byte(`{"id":"month.consolidated:2018-01-01/2018-02-01","type":"month.consolidated"}`),
+				Status:    eventio.WebhookDeliveryPending,
+			},
+			URL:    server.URL,
+			Secret: "0123456789abcdef",
+		}
+		store.GetDueWebhookDeliveriesReturnsOnCall(0, []eventio.DueWebhookDelivery{delivery}, nil)
+		dispatcher = webhooks.New(webhooks.Config{
+			Store:       store,
+			MaxAttempts: 3,
+			BatchSize:   10,
+			Lease:       time.Minute,
+		})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should post the signed payload and record that it was delivered", func() {
+		n, err := dispatcher.DeliverDue(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(1))
+
+		Expect(store.GetDueWebhookDeliveriesCallCount()).To(Equal(1))
+		limit, lease := store.GetDueWebhookDeliveriesArgsForCall(0)
+		Expect(limit).To(Equal(10))
+		Expect(lease).To(Equal(time.Minute))
+
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Method).To(Equal("POST"))
+		Expect(requests[0].Header.Get("Content-Type")).To(Equal("application/json"))
+		Expect(requests[0].Header.Get(webhooks.EventHeader)).To(Equal("month.consolidated"))
+		Expect(requests[0].Header.Get(webhooks.EventIDHeader)).To(Equal("month.consolidated:2018-01-01/2018-02-01"))
+		Expect(requests[0].Header.Get(webhooks.DeliveryHeader)).To(Equal("7"))
+		Expect(bodies[0]).To(Equal(string(delivery.Payload)))
+		timestamp, err := strconv.ParseInt(requests[0].Header.Get(webhooks.TimestampHeader), 10, 64)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(time.Unix(timestamp, 0)).To(BeTemporally("~", time.Now(), time.Minute))
+		Expect(webhooks.Verify("0123456789abcdef", timestamp, []byte(bodies[0]), requests[0].Header.Get(webhooks.SignatureHeader))).To(BeTrue())
+
+		Expect(store.RecordWebhookAttemptCallCount()).To(Equal(1))
+		id, attempt := store.RecordWebhookAttemptArgsForCall(0)
+		Expect(id).To(Equal(int64(7)))
+		Expect(attempt).To(Equal(eventio.WebhookAttempt{
+			Delivered:      true,
+			ResponseStatus: http.StatusOK,
+		}))
+	})
+
+	It("should schedule another attempt with a backoff if the webhook fails", func() {
+		status = http.StatusBadGateway
+		delivery.Attempts = 1
+		store.GetDueWebhookDeliveriesReturnsOnCall(0, []eventio.DueWebhookDelivery{delivery}, nil)
+
+		_, err := dispatcher.DeliverDue(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(store.RecordWebhookAttemptCallCount()).To(Equal(1))
+		_, attempt := store.RecordWebhookAttemptArgsForCall(0)
+		Expect(attempt.Delivered).To(BeFalse())
+		Expect(attempt.ResponseStatus).To(Equal(http.StatusBadGateway))
+		Expect(attempt.Error).To(Equal("webhook responded with 502 Bad Gateway"))
+		Expect(attempt.NextAttemptAt).ToNot(BeNil())
+		Expect(*attempt.NextAttemptAt).To(BeTemporally("~", time.Now().Add(2*time.Minute), 10*time.Second))
+	})
+
+	It("should give up once the delivery has been attempted the most times", func() {
+		status = http.StatusInternalServerError
+		delivery.Attempts = 2
+		store.GetDueWebhookDeliveriesReturnsOnCall(0, []eventio.DueWebhookDelivery{delivery}, nil)
+
+		_, err := dispatcher.DeliverDue(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		_, attempt := store.RecordWebhookAttemptArgsForCall(0)
+		Expect(attempt.Delivered).To(BeFalse())
+		Expect(attempt.NextAttemptAt).To(BeNil())
+	})
+
+	It("should record an attempt that could not connect", func() {
+		server.Close()
+
+		_, err := dispatcher.DeliverDue(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		_, attempt := store.RecordWebhookAttemptArgsForCall(0)
+		Expect(attempt.Delivered).To(BeFalse())
+		Expect(attempt.ResponseStatus).To(Equal(0))
+		Expect(attempt.Error).ToNot(BeEmpty())
+		Expect(attempt.NextAttemptAt).ToNot(BeNil())
+	})
+
+	It("should give up on a webhook that takes too long for the batch to be sent within the lease", func() {
+		wait = 500 * time.Millisecond
+		dispatcher = webhooks.New(webhooks.Config{
+			Store:     store,
+			BatchSize: 10,
+			Lease:     time.Second,
+		})
+
+		startTime := time.Now()
+		_, err := dispatcher.DeliverDue(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(time.Since(startTime)).To(BeNumerically("<", wait))
+
+		_, attempt := store.RecordWebhookAttemptArgsForCall(0)
+		Expect(attempt.Delivered).To(BeFalse())
+		Expect(attempt.Error).To(ContainSubstring("Timeout"))
+		Expect(attempt.NextAttemptAt).ToNot(BeNil())
+	})
+
+	It("should keep claiming deliveries while there are full batches", func() {
+		dispatcher = webhooks.New(webhooks.Config{
+			Store:     store,
+			BatchSize: 1,
+		})
+		store.GetDueWebhookDeliveriesReturnsOnCall(1, []eventio.DueWebhookDelivery{delivery}, nil)
+		store.GetDueWebhookDeliveriesReturnsOnCall(2, []eventio.DueWebhookDelivery{}, nil)
+
+		n, err := dispatcher.DeliverDue(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(2))
+		Expect(store.GetDueWebhookDeliveriesCallCount()).To(Equal(3))
+		_, lease := store.GetDueWebhookDeliveriesArgsForCall(0)
+		Expect(lease).To(Equal(webhooks.DefaultLease))
+	})
+
+	It("should stop if the deliveries can not be claimed", func() {
+		store.GetDueWebhookDeliveriesReturnsOnCall(0, nil, errors.New("database unavailable"))
+
+		_, err := dispatcher.DeliverDue(context.Background())
+		Expect(err).To(MatchError("database unavailable"))
+		Expect(requests).To(BeEmpty())
+	})
+})
@@ -0,0 +1,42 @@
+// Package webhooks sends the billing lifecycle events recorded by the store
+// to the webhooks subscribed to them, signed so that the receiver can check
+// that they came from paas-billing.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+const (
+	// SignatureHeader is the signature of the timestamp and body, from Sign
+	SignatureHeader = "X-Billing-Signature"
+	// TimestampHeader is the unix time in seconds that the request was
+	// signed at, receivers should reject requests signed long ago
+	TimestampHeader = "X-Billing-Timestamp"
+	// EventHeader is the type of the event
+	EventHeader = "X-Billing-Event"
+	// EventIDHeader is the ID of the event, which may be sent more than once
+	EventIDHeader = "X-Billing-Event-Id"
+	// DeliveryHeader is the ID of the delivery in the delivery log
+	DeliveryHeader = "X-Billing-Delivery"
+)
+
+// Sign returns the signature of a request body sent at timestamp, a hex
+// encoded HMAC-SHA256 of the timestamp, a dot and the body, keyed with the
+// webhook's secret, in the form sha256=<hex>
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that signature is the signature of the body sent at
+// timestamp, for receivers written in Go
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}